@@ -0,0 +1,143 @@
+// Package bindings re-exports the contract bindings of the ETH Relay (Testimonium) and Ethash contracts, so services
+// consuming relay events (e.g., indexers or bridges) can import them from a stable path instead of duplicating the
+// abigen output. The types are aliases of the bindings used by the testimonium package, values can be passed between
+// both packages.
+package bindings
+
+import (
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+	"github.com/pantos-io/go-ethrelay/testimonium"
+)
+
+// ABIs of the contracts, e.g., for abi.JSON or bind.NewBoundContract
+const (
+	TestimoniumABI = testimonium.TestimoniumABI
+	EthashABI      = ethash.EthashABI
+)
+
+// bindings of the ETH Relay contract
+type (
+	Testimonium                  = testimonium.Testimonium
+	TestimoniumCaller            = testimonium.TestimoniumCaller
+	TestimoniumTransactor        = testimonium.TestimoniumTransactor
+	TestimoniumFilterer          = testimonium.TestimoniumFilterer
+	TestimoniumSession           = testimonium.TestimoniumSession
+	TestimoniumCallerSession     = testimonium.TestimoniumCallerSession
+	TestimoniumTransactorSession = testimonium.TestimoniumTransactorSession
+	TestimoniumRaw               = testimonium.TestimoniumRaw
+	TestimoniumCallerRaw         = testimonium.TestimoniumCallerRaw
+	TestimoniumTransactorRaw     = testimonium.TestimoniumTransactorRaw
+)
+
+// events of the ETH Relay contract and their iterators
+type (
+	TestimoniumDisputeBlock                = testimonium.TestimoniumDisputeBlock
+	TestimoniumDisputeBlockIterator        = testimonium.TestimoniumDisputeBlockIterator
+	TestimoniumPoWValidationResult         = testimonium.TestimoniumPoWValidationResult
+	TestimoniumPoWValidationResultIterator = testimonium.TestimoniumPoWValidationResultIterator
+	TestimoniumRemoveBranch                = testimonium.TestimoniumRemoveBranch
+	TestimoniumRemoveBranchIterator        = testimonium.TestimoniumRemoveBranchIterator
+	TestimoniumSubmitBlock                 = testimonium.TestimoniumSubmitBlock
+	TestimoniumSubmitBlockIterator         = testimonium.TestimoniumSubmitBlockIterator
+	TestimoniumVerifyReceipt               = testimonium.TestimoniumVerifyReceipt
+	TestimoniumVerifyReceiptIterator       = testimonium.TestimoniumVerifyReceiptIterator
+	TestimoniumVerifyState                 = testimonium.TestimoniumVerifyState
+	TestimoniumVerifyStateIterator         = testimonium.TestimoniumVerifyStateIterator
+	TestimoniumVerifyTransaction           = testimonium.TestimoniumVerifyTransaction
+	TestimoniumVerifyTransactionIterator   = testimonium.TestimoniumVerifyTransactionIterator
+	TestimoniumWithdrawStake               = testimonium.TestimoniumWithdrawStake
+	TestimoniumWithdrawStakeIterator       = testimonium.TestimoniumWithdrawStakeIterator
+)
+
+// bindings of the Ethash contract
+type (
+	Ethash                  = ethash.Ethash
+	EthashCaller            = ethash.EthashCaller
+	EthashTransactor        = ethash.EthashTransactor
+	EthashFilterer          = ethash.EthashFilterer
+	EthashSession           = ethash.EthashSession
+	EthashCallerSession     = ethash.EthashCallerSession
+	EthashTransactorSession = ethash.EthashTransactorSession
+	EthashRaw               = ethash.EthashRaw
+	EthashCallerRaw         = ethash.EthashCallerRaw
+	EthashTransactorRaw     = ethash.EthashTransactorRaw
+)
+
+// events of the Ethash contract and their iterators
+type (
+	EthashSetEpochData         = ethash.EthashSetEpochData
+	EthashSetEpochDataIterator = ethash.EthashSetEpochDataIterator
+)
+
+// NewTestimonium binds the ETH Relay contract deployed at the address.
+func NewTestimonium(address common.Address, backend bind.ContractBackend) (*Testimonium, error) {
+	return testimonium.NewTestimonium(address, backend)
+}
+
+// NewTestimoniumCaller binds the read-only methods of the ETH Relay contract deployed at the address.
+func NewTestimoniumCaller(address common.Address, caller bind.ContractCaller) (*TestimoniumCaller, error) {
+	return testimonium.NewTestimoniumCaller(address, caller)
+}
+
+// NewTestimoniumTransactor binds the write methods of the ETH Relay contract deployed at the address.
+func NewTestimoniumTransactor(address common.Address, transactor bind.ContractTransactor) (*TestimoniumTransactor, error) {
+	return testimonium.NewTestimoniumTransactor(address, transactor)
+}
+
+// NewTestimoniumFilterer binds the events of the ETH Relay contract deployed at the address.
+func NewTestimoniumFilterer(address common.Address, filterer bind.ContractFilterer) (*TestimoniumFilterer, error) {
+	return testimonium.NewTestimoniumFilterer(address, filterer)
+}
+
+// NewEthash binds the Ethash contract deployed at the address.
+func NewEthash(address common.Address, backend bind.ContractBackend) (*Ethash, error) {
+	return ethash.NewEthash(address, backend)
+}
+
+// NewEthashCaller binds the read-only methods of the Ethash contract deployed at the address.
+func NewEthashCaller(address common.Address, caller bind.ContractCaller) (*EthashCaller, error) {
+	return ethash.NewEthashCaller(address, caller)
+}
+
+// NewEthashTransactor binds the write methods of the Ethash contract deployed at the address.
+func NewEthashTransactor(address common.Address, transactor bind.ContractTransactor) (*EthashTransactor, error) {
+	return ethash.NewEthashTransactor(address, transactor)
+}
+
+// NewEthashFilterer binds the events of the Ethash contract deployed at the address.
+func NewEthashFilterer(address common.Address, filterer bind.ContractFilterer) (*EthashFilterer, error) {
+	return ethash.NewEthashFilterer(address, filterer)
+}
+
+// DialTestimonium connects to the node at the URL (HTTP, WebSocket or IPC; watching events requires WebSocket or IPC)
+// and binds the ETH Relay contract deployed at the address. The connection is returned to be closed by the caller.
+func DialTestimonium(rawurl string, address common.Address) (*Testimonium, *ethclient.Client, error) {
+	client, err := ethclient.Dial(rawurl)
+	if err != nil {
+		return nil, nil, err
+	}
+	contract, err := NewTestimonium(address, client)
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+	return contract, client, nil
+}
+
+// DialEthash connects to the node at the URL like DialTestimonium and binds the Ethash contract deployed at the
+// address.
+func DialEthash(rawurl string, address common.Address) (*Ethash, *ethclient.Client, error) {
+	client, err := ethclient.Dial(rawurl)
+	if err != nil {
+		return nil, nil, err
+	}
+	contract, err := NewEthash(address, client)
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+	return contract, client, nil
+}
@@ -0,0 +1,146 @@
+// This file contains the decoding of raw logs of the contracts into their typed events, e.g., for logs received from
+// eth_getLogs or a log subscription covering several events.
+
+package bindings
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// names of the events of the ETH Relay contract
+const (
+	EVENT_DISPUTE_BLOCK         = "DisputeBlock"
+	EVENT_POW_VALIDATION_RESULT = "PoWValidationResult"
+	EVENT_REMOVE_BRANCH         = "RemoveBranch"
+	EVENT_SUBMIT_BLOCK          = "SubmitBlock"
+	EVENT_VERIFY_RECEIPT        = "VerifyReceipt"
+	EVENT_VERIFY_STATE          = "VerifyState"
+	EVENT_VERIFY_TRANSACTION    = "VerifyTransaction"
+	EVENT_WITHDRAW_STAKE        = "WithdrawStake"
+)
+
+// names of the events of the Ethash contract
+const (
+	EVENT_SET_EPOCH_DATA = "SetEpochData"
+)
+
+var (
+	abiOnce        sync.Once
+	testimoniumAbi abi.ABI
+	ethashAbi      abi.ABI
+	abiErr         error
+)
+
+func parsedABIs() (abi.ABI, abi.ABI, error) {
+	abiOnce.Do(func() {
+		if testimoniumAbi, abiErr = abi.JSON(strings.NewReader(TestimoniumABI)); abiErr != nil {
+			return
+		}
+		ethashAbi, abiErr = abi.JSON(strings.NewReader(EthashABI))
+	})
+	return testimoniumAbi, ethashAbi, abiErr
+}
+
+// eventName returns the name of the event of the contract the log was emitted as
+func eventName(contractAbi abi.ABI, log types.Log) (string, error) {
+	if len(log.Topics) == 0 {
+		return "", fmt.Errorf("anonymous log of %s in tx %s", log.Address.Hex(), log.TxHash.Hex())
+	}
+	for name, event := range contractAbi.Events {
+		if event.ID() == log.Topics[0] {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unknown event %s of %s in tx %s", log.Topics[0].Hex(), log.Address.Hex(), log.TxHash.Hex())
+}
+
+// TestimoniumEventTopic returns the topic (event id) of the ETH Relay event with the specified name, e.g.,
+// EVENT_SUBMIT_BLOCK, to filter logs by event.
+func TestimoniumEventTopic(name string) (common.Hash, error) {
+	contractAbi, _, err := parsedABIs()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	event, exists := contractAbi.Events[name]
+	if !exists {
+		return common.Hash{}, fmt.Errorf("unknown event %s", name)
+	}
+	return event.ID(), nil
+}
+
+// ParseTestimoniumEvent decodes a log emitted by the ETH Relay contract into its typed event, e.g.,
+// *TestimoniumSubmitBlock. The name of the event is returned as well.
+func ParseTestimoniumEvent(log types.Log) (string, interface{}, error) {
+	contractAbi, _, err := parsedABIs()
+	if err != nil {
+		return "", nil, err
+	}
+	name, err := eventName(contractAbi, log)
+	if err != nil {
+		return "", nil, err
+	}
+	filterer, err := NewTestimoniumFilterer(log.Address, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var event interface{}
+	switch name {
+	case EVENT_DISPUTE_BLOCK:
+		event, err = filterer.ParseDisputeBlock(log)
+	case EVENT_POW_VALIDATION_RESULT:
+		event, err = filterer.ParsePoWValidationResult(log)
+	case EVENT_REMOVE_BRANCH:
+		event, err = filterer.ParseRemoveBranch(log)
+	case EVENT_SUBMIT_BLOCK:
+		event, err = filterer.ParseSubmitBlock(log)
+	case EVENT_VERIFY_RECEIPT:
+		event, err = filterer.ParseVerifyReceipt(log)
+	case EVENT_VERIFY_STATE:
+		event, err = filterer.ParseVerifyState(log)
+	case EVENT_VERIFY_TRANSACTION:
+		event, err = filterer.ParseVerifyTransaction(log)
+	case EVENT_WITHDRAW_STAKE:
+		event, err = filterer.ParseWithdrawStake(log)
+	default:
+		return name, nil, fmt.Errorf("event %s is not supported by the bindings", name)
+	}
+	if err != nil {
+		return name, nil, err
+	}
+	return name, event, nil
+}
+
+// ParseEthashEvent decodes a log emitted by the Ethash contract into its typed event, e.g., *EthashSetEpochData. The
+// name of the event is returned as well.
+func ParseEthashEvent(log types.Log) (string, interface{}, error) {
+	_, contractAbi, err := parsedABIs()
+	if err != nil {
+		return "", nil, err
+	}
+	name, err := eventName(contractAbi, log)
+	if err != nil {
+		return "", nil, err
+	}
+	filterer, err := NewEthashFilterer(log.Address, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch name {
+	case EVENT_SET_EPOCH_DATA:
+		event, err := filterer.ParseSetEpochData(log)
+		if err != nil {
+			return name, nil, err
+		}
+		return name, event, nil
+	default:
+		return name, nil, fmt.Errorf("event %s is not supported by the bindings", name)
+	}
+}
@@ -5,7 +5,11 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"log"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/testimonium"
 	"github.com/spf13/cobra"
 )
 
@@ -13,13 +17,31 @@ import (
 var accountCmd = &cobra.Command{
 	Use:   "account",
 	Short: "Prints the address of the current account",
-	Long: `Prints the address of the current account`,
+	Long: `Prints the address of the current account and the accounts of the operator roles (submitter, disputer,
+verifier) with keys of their own (see the 'operators' section of the config file)`,
 	Run: func(cmd *cobra.Command, args []string) {
 		testimoniumClient = createTestimoniumClient()
-		fmt.Println(testimoniumClient.Account())
+		if !testimoniumClient.HasAccount() {
+			log.Fatal("No private key configured")
+		}
+		printResult(accountResult{Account: testimoniumClient.Account(), Operators: testimoniumClient.Operators()})
 	},
 }
 
+type accountResult struct {
+	Account   string                                      `json:"account"`
+	Operators map[testimonium.OperatorRole]common.Address `json:"operators,omitempty"`
+}
+
+func (result accountResult) renderText(w io.Writer) {
+	fmt.Fprintln(w, result.Account)
+	for _, role := range []testimonium.OperatorRole{testimonium.OPERATOR_SUBMITTER, testimonium.OPERATOR_DISPUTER, testimonium.OPERATOR_VERIFIER} {
+		if account, exists := result.Operators[role]; exists {
+			fmt.Fprintf(w, "%s: %s\n", role, account.Hex())
+		}
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(accountCmd)
 
@@ -0,0 +1,71 @@
+// This file contains logic executed if the command "account allowance" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+// accountAllowanceCmd represents the command 'account allowance'
+var accountAllowanceCmd = &cobra.Command{
+	Use:   "allowance",
+	Short: "Shows the fee token balance and allowance of the current account",
+	Long: `Shows the verification fee of the ETH Relay contract on the specified chain and, if the fee is paid in an
+ERC-20 token, the tokens held by the current account and the tokens the contract may collect from it (the allowance).
+Verifications approve the contract to collect the fees of the next 10 verifications when the allowance does not cover
+the fee, use 'account approve' to set the allowance yourself.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient()
+
+		fee, err := testimoniumClient.VerificationFee(accountFlagChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+		result := accountAllowanceResult{Account: testimoniumClient.Account(), Fee: fee}
+		if fee.IsToken() {
+			if result.Balance, err = testimoniumClient.FeeTokenBalance(accountFlagChain); err != nil {
+				log.Fatal(err)
+			}
+			if result.Allowance, err = testimoniumClient.FeeTokenAllowance(accountFlagChain); err != nil {
+				log.Fatal(err)
+			}
+		}
+		printResult(result)
+	},
+}
+
+type accountAllowanceResult struct {
+	Account   string                      `json:"account"`
+	Fee       testimonium.VerificationFee `json:"fee"`
+	Balance   *big.Int                    `json:"balance,omitempty"`   // fee tokens of the account
+	Allowance *big.Int                    `json:"allowance,omitempty"` // fee tokens the contract may collect
+}
+
+func (result accountAllowanceResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Account: %s\n", result.Account)
+	fmt.Fprintf(w, "Verification fee: %s\n", result.Fee)
+	if !result.Fee.IsToken() {
+		fmt.Fprintln(w, "The fee is paid in ether, no allowance is required")
+		return
+	}
+	fmt.Fprintf(w, "Fee token: %s\n", result.Fee.Token.Hex())
+	fmt.Fprintf(w, "Balance: %s %s\n", testimonium.FormatTokenAmount(result.Balance, result.Fee.Decimals), result.Fee.Symbol)
+	fmt.Fprintf(w, "Allowance: %s %s", testimonium.FormatTokenAmount(result.Allowance, result.Fee.Decimals), result.Fee.Symbol)
+	if result.Fee.Amount.Sign() > 0 {
+		fmt.Fprintf(w, " (%s verifications)", new(big.Int).Quo(result.Allowance, result.Fee.Amount))
+	}
+	fmt.Fprintln(w)
+}
+
+func init() {
+	accountCmd.AddCommand(accountAllowanceCmd)
+
+	accountAllowanceCmd.Flags().Uint8VarP(&accountFlagChain, "chain", "c", 1, "chain")
+}
@@ -0,0 +1,53 @@
+// This file contains logic executed if the command "account approve" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var accountApproveFlagVerifications int64
+
+// accountApproveCmd represents the command 'account approve'
+var accountApproveCmd = &cobra.Command{
+	Use:   "approve [amount]",
+	Short: "Approves the ETH Relay contract to collect verification fees in its fee token",
+	Long: `Sets the allowance of the ETH Relay contract on the specified chain, i.e., the fee tokens the contract may
+collect from the current account for verifications. The amount is given in the smallest unit of the token, without
+amount the fees of --verifications verifications are approved. The approval replaces the current allowance, an amount
+of 0 revokes it.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient()
+
+		fee, err := testimoniumClient.VerificationFee(accountFlagChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !fee.IsToken() {
+			log.Fatalf("The verification fee on chain %d is paid in ether", accountFlagChain)
+		}
+
+		amount := new(big.Int).Mul(fee.Amount, big.NewInt(accountApproveFlagVerifications))
+		if len(args) > 0 {
+			amount = parseAdminAmount(args[0], "amount")
+		}
+		result, err := testimoniumClient.ApproveFeeToken(accountFlagChain, amount)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printResult(txResult{Message: fmt.Sprintf("Approved %s %s", testimonium.FormatTokenAmount(amount, fee.Decimals), fee.Symbol), TxResult: result})
+	},
+}
+
+func init() {
+	accountCmd.AddCommand(accountApproveCmd)
+
+	accountApproveCmd.Flags().Uint8VarP(&accountFlagChain, "chain", "c", 1, "chain")
+	accountApproveCmd.Flags().Int64Var(&accountApproveFlagVerifications, "verifications", testimonium.FEE_TOKEN_APPROVAL_VERIFICATIONS, "number of verifications to approve the fees of if no amount is given")
+}
@@ -0,0 +1,77 @@
+// This file contains logic executed if the command "account audit" is typed in.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+// accountAuditCmd represents the command 'account audit'
+var accountAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Checks the configured chains for replay hazards of the account's transactions",
+	Long: `Checks the chain ids, contract addresses and nonces of the current account on all configured chains for ways a
+transaction sent to one chain could be included on another chain:
+
+- transactions are not replay protected (EIP-155), since the chain id of a destination chain is unknown or disabled
+- two different chains (i.e., with different genesis blocks) share a chain id
+- the account's nonce on the other chain has not passed the nonces of the sent transactions
+
+Hazards are reported as "danger" if sent transactions are valid on another chain now and as "warning" otherwise. The
+command fails if there is a danger.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient()
+
+		audit, err := testimoniumClient.AuditReplayProtection()
+		if errors.Is(err, testimonium.ErrNoAccount) {
+			log.Fatal("No private key configured")
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		printResult(accountAuditResult{audit})
+
+		for _, hazard := range audit.Hazards {
+			if hazard.Severity == testimonium.REPLAY_DANGER {
+				log.Fatal("Replay audit FAILED: sent transactions are valid on other chains")
+			}
+		}
+	},
+}
+
+type accountAuditResult struct {
+	*testimonium.ReplayAudit
+}
+
+func (result accountAuditResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Account %s\n", result.Account.Hex())
+	for _, chain := range result.Chains {
+		chainId := "not replay protected"
+		if chain.ChainId != nil {
+			chainId = fmt.Sprintf("chain id %s", chain.ChainId)
+		}
+		fmt.Fprintf(w, "Chain %d (%s, %s, %s): nonce %d, pending nonce %d\n", chain.Chain, chain.Role, chainId,
+			chain.ChainIdSource, chain.Nonce, chain.PendingNonce)
+		for _, err := range chain.Errors {
+			fmt.Fprintf(w, "   cannot query %s\n", err)
+		}
+	}
+	if len(result.Hazards) == 0 {
+		fmt.Fprintln(w, "No replay hazards")
+		return
+	}
+	for _, hazard := range result.Hazards {
+		fmt.Fprintf(w, "%s: %s\n", hazard.Severity, hazard.Description)
+	}
+}
+
+func init() {
+	accountCmd.AddCommand(accountAuditCmd)
+}
@@ -0,0 +1,74 @@
+// This file contains logic executed if the command "account repair" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+// accountRepairCmd represents the command 'account repair'
+var accountRepairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Reconciles the nonces and the transaction log of the accounts with the chain",
+	Long: `Reconciles the nonce state of the current account and the operator accounts on the specified chain with the
+chain's confirmed and pending nonces, e.g., after a crash or after transactions were sent through other wallets.
+
+The unresolved transactions of the transaction log (txlog-<chain>.jsonl in --datadir) are compared with the chain:
+mined transactions and transactions whose nonce was used by another transaction are resolved, transactions still
+pending in the node's mempool are kept. Unlike the reconciliation when the client starts, transactions the node does
+not know are cleared instead of broadcast again, so the next transaction uses their nonce. Reserved nonces are dropped.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient(testimonium.WithoutTxLogReconciliation())
+		if !testimoniumClient.HasAccount() {
+			log.Fatal("No private key configured")
+		}
+
+		repair, err := testimoniumClient.RepairAccount(accountFlagChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printResult(accountRepairResult{repair})
+	},
+}
+
+type accountRepairResult struct {
+	*testimonium.AccountRepair
+}
+
+func (result accountRepairResult) renderText(w io.Writer) {
+	for _, account := range result.Accounts {
+		role := account.Role
+		if role == "" {
+			role = "account"
+		}
+		fmt.Fprintf(w, "%s %s: nonce %d (latest block), %d (pending)", role, account.Account.Hex(), account.Nonce, account.PendingNonce)
+		if account.ReservedNonce != nil {
+			fmt.Fprintf(w, ", reservation of nonce %d dropped", *account.ReservedNonce)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(result.Txs) == 0 {
+		fmt.Fprintln(w, "No unresolved transactions in the transaction log")
+		return
+	}
+	for _, tx := range result.Txs {
+		fmt.Fprintf(w, "%d: %s (%s) %s -> %s\n", tx.Nonce, tx.Hash.Hex(), tx.Intent, tx.PreviousState, tx.State)
+		if tx.Error != "" {
+			fmt.Fprintf(w, "   %s\n", tx.Error)
+		}
+	}
+	fmt.Fprintf(w, "%d transactions cleared\n", len(result.Cleared()))
+}
+
+func init() {
+	accountCmd.AddCommand(accountRepairCmd)
+
+	accountRepairCmd.Flags().Uint8VarP(&accountFlagChain, "chain", "c", 1, "chain")
+}
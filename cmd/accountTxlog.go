@@ -0,0 +1,56 @@
+// This file contains logic executed if the command "account txlog" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+// accountTxlogCmd represents the command 'account txlog'
+var accountTxlogCmd = &cobra.Command{
+	Use:   "txlog",
+	Short: "Lists the transactions of the current account in the transaction log",
+	Long: `Lists the transactions in the transaction log of the specified chain (txlog-<chain>.jsonl in --datadir).
+
+Every transaction is written to the log before it is broadcast. When the client starts, transactions left unresolved
+by a previous run (e.g., since the process crashed before their receipts arrived) are compared with the chain: mined
+transactions and transactions whose nonce was used by another transaction are resolved, transactions the node does not
+know are broadcast again, so their nonces do not block later transactions.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient()
+
+		entries, err := testimoniumClient.TxLogEntries(accountFlagChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printResult(accountTxLogResult(entries))
+	},
+}
+
+type accountTxLogResult []testimonium.TxLogEntry
+
+func (result accountTxLogResult) renderText(w io.Writer) {
+	if len(result) == 0 {
+		fmt.Fprintln(w, "No transactions in the transaction log")
+		return
+	}
+	for _, entry := range result {
+		fmt.Fprintf(w, "%d: %s (%s, %s, %s)\n", entry.Nonce, entry.Hash.Hex(), entry.Intent, entry.State,
+			entry.Time.Local().Format("2006-01-02 15:04:05"))
+		if entry.Error != "" {
+			fmt.Fprintf(w, "   %s\n", entry.Error)
+		}
+	}
+}
+
+func init() {
+	accountCmd.AddCommand(accountTxlogCmd)
+
+	accountTxlogCmd.Flags().Uint8VarP(&accountFlagChain, "chain", "c", 1, "chain")
+}
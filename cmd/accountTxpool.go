@@ -0,0 +1,70 @@
+// This file contains logic executed if the command "account txpool" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var accountFlagChain uint8
+
+// accountTxpoolCmd represents the command 'account txpool'
+var accountTxpoolCmd = &cobra.Command{
+	Use:   "txpool",
+	Short: "Lists the pending and queued transactions of the current account",
+	Long: `Lists the nonces and the pending and queued transactions of the current account on the specified chain.
+Queued transactions wait for a transaction with a lower nonce, pending transactions with a low gas price may
+not be included at all. The node has to expose the txpool API.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient()
+
+		pool, err := testimoniumClient.AccountTxPool(accountFlagChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		printResult(accountTxPoolResult{Account: testimoniumClient.Account(), AccountTxPool: pool})
+	},
+}
+
+type accountTxPoolResult struct {
+	Account string
+	testimonium.AccountTxPool
+}
+
+func (result accountTxPoolResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Account: %s\n", result.Account)
+	fmt.Fprintf(w, "Nonce (latest block): %d\n", result.Nonce)
+	fmt.Fprintf(w, "Nonce (pending): %d\n", result.PendingNonce)
+
+	fmt.Fprintf(w, "\nPending transactions: %d\n", len(result.Pending))
+	printPoolTransactions(w, result.Pending)
+
+	fmt.Fprintf(w, "\nQueued transactions: %d\n", len(result.Queued))
+	printPoolTransactions(w, result.Queued)
+	if len(result.Queued) > 0 && result.Queued[0].Nonce > result.PendingNonce {
+		fmt.Fprintf(w, "\nQueued transactions wait for the missing nonce %d\n", result.PendingNonce)
+	}
+}
+
+func init() {
+	accountCmd.AddCommand(accountTxpoolCmd)
+
+	accountTxpoolCmd.Flags().Uint8VarP(&accountFlagChain, "chain", "c", 1, "chain")
+}
+
+func printPoolTransactions(w io.Writer, txs []testimonium.PoolTransaction) {
+	for _, tx := range txs {
+		method := tx.Method
+		if method == "" {
+			method = "-"
+		}
+		fmt.Fprintf(w, "%d: %s (gas price: %s wei, gas: %d, method: %s)\n", tx.Nonce, tx.Hash.String(), tx.GasPrice, tx.Gas, method)
+	}
+}
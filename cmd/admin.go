@@ -0,0 +1,100 @@
+// This file contains logic executed if the command "admin" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var adminFlagChain uint8
+
+// adminCmd represents the admin command
+var adminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Administrates the ETH Relay contract on the specified chain",
+	Long: `Administrates the ETH Relay contract on the specified chain (ownership, verification fee, lock period).
+
+Without a subcommand, the admin functions exposed by the deployed contract are listed.
+Subcommands of functions the contract does not expose fail without sending a transaction.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient()
+		functions, err := testimoniumClient.AdminFunctions(adminFlagChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		result := adminInfoResult{Functions: functions}
+		for _, function := range functions {
+			switch function {
+			case "owner":
+				owner, err := testimoniumClient.Owner(adminFlagChain)
+				if err != nil {
+					log.Fatal(err)
+				}
+				result.Owner = &owner
+			case "getLockPeriod":
+				result.LockPeriod, err = testimoniumClient.LockPeriod(adminFlagChain)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+		}
+		result.fee, err = testimoniumClient.VerificationFee(adminFlagChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+		result.VerificationFee = result.fee.Amount
+		if result.fee.IsToken() {
+			result.FeeToken = &result.fee.Token
+		}
+
+		printResult(result)
+	},
+}
+
+type adminInfoResult struct {
+	Functions       []string        `json:"functions"`
+	Owner           *common.Address `json:"owner,omitempty"`
+	VerificationFee *big.Int        `json:"verificationFee"`      // in wei or in the smallest unit of the fee token
+	FeeToken        *common.Address `json:"feeToken,omitempty"`   // ERC-20 token the fee is paid in
+	LockPeriod      *big.Int        `json:"lockPeriod,omitempty"` // in seconds
+	fee             testimonium.VerificationFee
+}
+
+func (result adminInfoResult) renderText(w io.Writer) {
+	if len(result.Functions) == 0 {
+		fmt.Fprintln(w, "The contract does not expose admin functions")
+	} else {
+		fmt.Fprintf(w, "Admin functions: %s\n", strings.Join(result.Functions, ", "))
+	}
+	if result.Owner != nil {
+		fmt.Fprintf(w, "Owner: %s\n", result.Owner.Hex())
+	}
+	fmt.Fprintf(w, "Verification fee: %s\n", result.fee)
+	if result.LockPeriod != nil {
+		fmt.Fprintf(w, "Lock period: %s seconds\n", result.LockPeriod)
+	}
+}
+
+// parseAdminAmount parses the amount argument of an admin command
+func parseAdminAmount(arg string, name string) *big.Int {
+	amount, ok := new(big.Int).SetString(arg, 10)
+	if !ok || amount.Sign() < 0 {
+		log.Fatalf("Can not parse %s parameter", name)
+	}
+	return amount
+}
+
+func init() {
+	rootCmd.AddCommand(adminCmd)
+
+	adminCmd.PersistentFlags().Uint8Var(&adminFlagChain, "chain", 1, "chain")
+}
@@ -0,0 +1,39 @@
+// This file contains logic executed if the command "admin set-fee" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// adminSetFeeCmd represents the admin set-fee command
+var adminSetFeeCmd = &cobra.Command{
+	Use:   "set-fee [fee]",
+	Short: "Sets the verification fee of the ETH Relay contract",
+	Long: `Sets the fee that has to be paid for each verification, in wei or in the smallest unit of the fee token if
+the contract collects its fee in an ERC-20 token (see 'account allowance')`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		feeInWei := parseAdminAmount(args[0], "fee")
+
+		testimoniumClient = createTestimoniumClient()
+		fee, err := testimoniumClient.VerificationFee(adminFlagChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+		result, err := testimoniumClient.SetVerificationFee(adminFlagChain, feeInWei)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fee.Amount = feeInWei
+		printResult(txResult{Message: fmt.Sprintf("Set verification fee to %s", fee), TxResult: result})
+	},
+}
+
+func init() {
+	adminCmd.AddCommand(adminSetFeeCmd)
+}
@@ -0,0 +1,33 @@
+// This file contains logic executed if the command "admin set-lock-period" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// adminSetLockPeriodCmd represents the admin set-lock-period command
+var adminSetLockPeriodCmd = &cobra.Command{
+	Use:   "set-lock-period [seconds]",
+	Short: "Sets the lock period of the ETH Relay contract",
+	Long:  `Sets the period (in seconds) the stake of a submitted header is locked and the header can be disputed`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		lockPeriod := parseAdminAmount(args[0], "seconds")
+
+		testimoniumClient = createTestimoniumClient()
+		result, err := testimoniumClient.SetLockPeriod(adminFlagChain, lockPeriod)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		printResult(txResult{Message: fmt.Sprintf("Set lock period to %s seconds", lockPeriod), TxResult: result})
+	},
+}
+
+func init() {
+	adminCmd.AddCommand(adminSetLockPeriodCmd)
+}
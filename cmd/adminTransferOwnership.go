@@ -0,0 +1,37 @@
+// This file contains logic executed if the command "admin transfer-ownership" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+)
+
+// adminTransferOwnershipCmd represents the admin transfer-ownership command
+var adminTransferOwnershipCmd = &cobra.Command{
+	Use:   "transfer-ownership [newOwner]",
+	Short: "Transfers the ownership of the ETH Relay contract",
+	Long:  `Transfers the ownership of the ETH Relay contract to the specified address (only the current owner can do so)`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !common.IsHexAddress(args[0]) {
+			log.Fatalf("Illegal address '%s'", args[0])
+		}
+		newOwner := common.HexToAddress(args[0])
+
+		testimoniumClient = createTestimoniumClient()
+		result, err := testimoniumClient.TransferOwnership(adminFlagChain, newOwner)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		printResult(txResult{Message: fmt.Sprintf("Transferred ownership to %s", newOwner.Hex()), TxResult: result})
+	},
+}
+
+func init() {
+	adminCmd.AddCommand(adminTransferOwnershipCmd)
+}
@@ -0,0 +1,88 @@
+// This file contains logic executed if the command "audit" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var auditFlagChain uint8
+var auditFlagSrcChain uint8
+var auditFlagMaxHeaders uint64
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Compares the longest chain of the ETH Relay contract with the source chain",
+	Long: `Compares the longest chain stored in the ETH Relay contract on the verifying chain (--chain) with the source
+chain (--src), e.g., to investigate suspicious disputes.
+
+The stored headers are walked from the endpoint of the longest chain back to the genesis block of the contract (or
+--max-headers headers). A stored header is divergent if the source chain has a different block at its height, the
+block of the source chain is missing if it is not stored in any branch of the contract. The parents of divergent
+headers are read from their submissions, which is faster with an up-to-date event index (see 'index update').
+
+The command fails if a divergent or missing header was found.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient()
+
+		audit, err := testimoniumClient.AuditBranch(auditFlagChain, auditFlagSrcChain, auditFlagMaxHeaders)
+		if err != nil {
+			log.Fatal("Failed to audit the longest chain: " + err.Error())
+		}
+		printResult(auditResult{audit})
+		if !audit.Consistent() {
+			log.Fatalf("The longest chain diverges from source chain %d", auditFlagSrcChain)
+		}
+	},
+}
+
+type auditResult struct {
+	*testimonium.BranchAudit
+}
+
+func (result auditResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Longest chain of chain %d: %s (No. %d)\n", result.DestinationChain, result.Endpoint.Hex(), result.EndpointNumber)
+	fmt.Fprintf(w, "Source chain %d: latest block %d\n", result.SourceChain, result.SourceHead)
+	if result.Complete {
+		fmt.Fprintf(w, "Headers checked: %d (down to the genesis block %s)\n", result.Checked, result.Genesis.Hex())
+	} else {
+		fmt.Fprintf(w, "Headers checked: %d (the genesis block was not reached)\n", result.Checked)
+	}
+	if result.Consistent() {
+		fmt.Fprintln(w, "All checked headers match the source chain")
+		return
+	}
+
+	fmt.Fprintf(w, "Divergent headers: %d, missing headers: %d\n", result.Count(testimonium.AUDIT_DIVERGENT), result.Count(testimonium.AUDIT_MISSING))
+	if result.ForkPoint != nil {
+		fmt.Fprintf(w, "The longest chain forks from the source chain after block %d\n", *result.ForkPoint)
+	}
+	fmt.Fprintf(w, "%-10s %-10s %-66s %s\n", "Block", "State", "Hash", "Note")
+	for _, finding := range result.Findings {
+		note := ""
+		switch {
+		case finding.State == testimonium.AUDIT_MISSING:
+			note = "not stored in the contract"
+		case finding.KnownBlock:
+			note = "known to the source chain, not canonical"
+		default:
+			note = "unknown to the source chain"
+		}
+		fmt.Fprintf(w, "%-10d %-10s %-66s %s\n", finding.BlockNumber, finding.State, finding.BlockHash.Hex(), note)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().Uint8VarP(&auditFlagChain, "chain", "c", 1, "the verifying chain of the ETH Relay contract")
+	auditCmd.Flags().Uint8Var(&auditFlagSrcChain, "src", 0, "the source chain of the stored headers")
+	auditCmd.Flags().Uint64Var(&auditFlagMaxHeaders, "max-headers", 0, "maximum number of headers to check (0: all)")
+}
@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"math/big"
@@ -35,30 +36,64 @@ var balanceCmd = &cobra.Command{
 			if err != nil {
 				log.Fatal(err)
 			}
-			fmt.Printf("%.4f ETH\n", getDecimal(balance, 18))
+			printResult(balanceResult{Total: balance})
 			return
 		}
 
 		if detailFlag {
-			totalBalance := big.NewInt(0)
-			for _, chainId := range testimoniumClient.Chains() {
-				balance, err := testimoniumClient.Balance(uint8(chainId))
-				if err != nil {
-					log.Fatal(err)
+			result := balanceResult{Total: big.NewInt(0)}
+			for _, balance := range testimoniumClient.Balances() {
+				chainBalance := chainBalanceResult{Chain: balance.Chain, Balance: balance.Balance}
+				if balance.Err != nil {
+					chainBalance.Error = balance.Err.Error()
+				} else {
+					result.Total = result.Total.Add(result.Total, balance.Balance)
 				}
-				fmt.Printf("Chain %d: %.4f ETH\n", chainId, getDecimal(balance, 18))
-				totalBalance = totalBalance.Add(totalBalance, balance)
+				result.Chains = append(result.Chains, chainBalance)
 			}
-			fmt.Printf("Total  : ")
+			printResult(result)
+			return
 		}
 		balance, err := testimoniumClient.TotalBalance()
+		result := balanceResult{Total: balance}
 		if err != nil {
-			log.Fatal(err)
+			// the balance of the reachable chains is still printed
+			result.Warning = err.Error()
 		}
-		fmt.Printf("%.4f ETH\n", getDecimal(balance, 18))
+		printResult(result)
 	},
 }
 
+type chainBalanceResult struct {
+	Chain   uint8    `json:"chain"`
+	Balance *big.Int `json:"balance"` // in wei, nil if unavailable
+	Error   string   `json:"error,omitempty"`
+}
+
+type balanceResult struct {
+	Chains  []chainBalanceResult `json:"chains,omitempty"` // only set with --detail
+	Total   *big.Int             `json:"total"`            // in wei
+	Warning string               `json:"warning,omitempty"`
+}
+
+func (result balanceResult) renderText(w io.Writer) {
+	if result.Warning != "" {
+		fmt.Fprintf(w, "WARNING: %s\n", result.Warning)
+	}
+	if result.Chains == nil {
+		fmt.Fprintf(w, "%.4f ETH\n", getDecimal(result.Total, 18))
+		return
+	}
+	for _, balance := range result.Chains {
+		if balance.Error != "" {
+			fmt.Fprintf(w, "Chain %d: unavailable (%s)\n", balance.Chain, balance.Error)
+			continue
+		}
+		fmt.Fprintf(w, "Chain %d: %.4f ETH\n", balance.Chain, getDecimal(balance.Balance, 18))
+	}
+	fmt.Fprintf(w, "Total  : %.4f ETH\n", getDecimal(result.Total, 18))
+}
+
 func getDecimal(absolute *big.Int, decimals int) *big.Float {
 	decimal := new(big.Float)
 	decimal.SetString(absolute.String())
@@ -0,0 +1,361 @@
+// This file contains logic executed if the command "batch" is typed in.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var batchFlagSrcChain uint8
+var batchFlagDestChain uint8
+var batchFlagConfirmations uint8
+var batchFlagWait time.Duration
+var batchFlagMaxHeaders int
+var batchFlagStopOnError bool
+
+// batchCmd represents the batch command
+var batchCmd = &cobra.Command{
+	Use:   "batch [file or -]",
+	Short: "Executes the operations listed in a file or read from stdin",
+	Long: `Executes the operations listed in the file (or read from stdin with '-') one after another with a single client,
+so the chains are dialed once instead of for every invocation of the binary, and prints one report of all operations.
+
+The operations are a JSON list or one JSON object per line (empty lines and lines starting with # are skipped):
+
+    {"op": "submit", "block": "12345"}                  submits the header of a block (number or hash) of --src,
+                                                        "ancestors": n submits up to n missing ancestors first
+    {"op": "verify", "txHash": "0x...", "type": "receipt", "confirmations": 6}
+                                                        verifies a transaction (default) or receipt of --src, waits up
+                                                        to "wait" (default: --wait) until its blocks are relayed, or
+                                                        submits them itself with "backfill": true (at most
+                                                        --max-headers)
+    {"op": "dispute", "blockHash": "0x..."}             disputes a submitted header
+    {"op": "deposit", "amount": "1000000000000000000"}  deposits stake (in wei)
+    {"op": "withdraw", "amount": "1000000000000000000"} withdraws stake (in wei)
+
+Every operation accepts "src" and "chain" to override --src and --chain. The nonces of the account are reserved by
+the client, so the transactions of consecutive operations get consecutive nonces. Failed operations are reported and
+the batch continues unless --stop-on-error is set; the command fails if any operation failed.`,
+	Example: `  echo '{"op": "submit", "block": "12345"}' | go-ethrelay batch -`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		operations, err := readBatchOperations(args[0])
+		if err != nil {
+			log.Fatalf("Cannot read operations: %s", err)
+		}
+
+		testimoniumClient = createTestimoniumClient(testimonium.WithNonceReservation())
+
+		result := batchResult{Operations: make([]batchOperationResult, 0, len(operations))}
+		for i, operation := range operations {
+			fmt.Fprintf(progressOutput(), "Operation %d of %d: %s\n", i+1, len(operations), operation.Op)
+			entry := operation.execute()
+			entry.Index = i + 1
+			switch {
+			case entry.Error != "":
+				result.Failed++
+			case entry.Skipped:
+				result.Skipped++
+			default:
+				result.Succeeded++
+			}
+			result.Operations = append(result.Operations, entry)
+			if entry.Error != "" && batchFlagStopOnError {
+				break
+			}
+		}
+
+		printResult(result)
+		if result.Failed > 0 {
+			log.Fatalf("%d of %d operations failed", result.Failed, len(operations))
+		}
+	},
+}
+
+// batchOperation is an operation of a batch, the fields besides op depend on the operation
+type batchOperation struct {
+	Op            string `json:"op"`
+	Src           *uint8 `json:"src"`
+	Chain         *uint8 `json:"chain"`
+	Block         string `json:"block"`     // submit: number or hash of the block
+	Ancestors     int    `json:"ancestors"` // submit: missing ancestors submitted first
+	TxHash        string `json:"txHash"`    // verify
+	Type          string `json:"type"`      // verify: transaction or receipt
+	Confirmations *uint8 `json:"confirmations"`
+	Backfill      bool   `json:"backfill"`  // verify: submit missing headers instead of waiting
+	Wait          string `json:"wait"`      // verify: duration, e.g., 10m
+	BlockHash     string `json:"blockHash"` // dispute
+	Amount        string `json:"amount"`    // deposit and withdraw: amount in wei
+}
+
+type batchOperationResult struct {
+	Index        int                          `json:"index"`
+	Op           string                       `json:"op"`
+	Skipped      bool                         `json:"skipped,omitempty"` // nothing to do, e.g., the block is already stored
+	Message      string                       `json:"message,omitempty"`
+	Transactions []*testimonium.TxResult      `json:"transactions,omitempty"`
+	Verification *testimonium.VerificationJob `json:"verification,omitempty"`
+	Error        string                       `json:"error,omitempty"`
+}
+
+type batchResult struct {
+	Operations []batchOperationResult `json:"operations"`
+	Succeeded  int                    `json:"succeeded"`
+	Skipped    int                    `json:"skipped"`
+	Failed     int                    `json:"failed"`
+}
+
+func (result batchResult) renderText(w io.Writer) {
+	for _, operation := range result.Operations {
+		switch {
+		case operation.Error != "":
+			fmt.Fprintf(w, "%d. %s failed: %s\n", operation.Index, operation.Op, operation.Error)
+		case operation.Message != "":
+			fmt.Fprintf(w, "%d. %s: %s\n", operation.Index, operation.Op, operation.Message)
+		default:
+			fmt.Fprintf(w, "%d. %s succeeded\n", operation.Index, operation.Op)
+		}
+		for _, tx := range operation.Transactions {
+			txResult{TxResult: tx}.renderText(w)
+		}
+		if operation.Verification != nil && operation.Verification.Verification != nil {
+			txResult{TxResult: operation.Verification.Verification}.renderText(w)
+		}
+	}
+	fmt.Fprintf(w, "%d succeeded, %d skipped, %d failed\n", result.Succeeded, result.Skipped, result.Failed)
+}
+
+// execute runs the operation, its failure is reported in the result
+func (operation batchOperation) execute() batchOperationResult {
+	result := batchOperationResult{Op: operation.Op}
+	var err error
+	switch operation.Op {
+	case "submit":
+		err = operation.submit(&result)
+	case "verify":
+		err = operation.verify(&result)
+	case "dispute":
+		var tx *testimonium.TxResult
+		tx, err = testimoniumClient.DisputeBlock(common.HexToHash(operation.BlockHash), operation.destinationChain())
+		result.Transactions = append(result.Transactions, tx)
+	case "deposit", "withdraw":
+		err = operation.moveStake(&result)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.Transactions = nonNilResults(result.Transactions)
+	}
+	return result
+}
+
+func (operation batchOperation) submit(result *batchOperationResult) error {
+	var header *types.Header
+	var err error
+	if strings.HasPrefix(operation.Block, "0x") {
+		header, err = testimoniumClient.HeaderByHash(common.HexToHash(operation.Block), operation.sourceChain())
+	} else {
+		number, _ := new(big.Int).SetString(operation.Block, 10)
+		header, err = testimoniumClient.HeaderByNumber(number, operation.sourceChain())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to retrieve header: %s", err)
+	}
+
+	if operation.Ancestors > 0 {
+		result.Transactions, err = testimoniumClient.SubmitHeaderWithAncestors(header, operation.destinationChain(),
+			operation.sourceChain(), operation.Ancestors)
+	} else if err = testimoniumClient.ValidateHeader(header, operation.sourceChain()); err == nil {
+		var tx *testimonium.TxResult
+		tx, err = testimoniumClient.SubmitHeader(header, operation.destinationChain())
+		result.Transactions = append(result.Transactions, tx)
+	}
+	if errors.Is(err, testimonium.ErrHeaderAlreadyStored) {
+		// also if another relayer submitted it first
+		result.Skipped = true
+		result.Message = fmt.Sprintf("block %s is already stored", header.Hash().Hex())
+		result.Transactions = nonNilResults(result.Transactions)
+		return nil
+	}
+	return err
+}
+
+func (operation batchOperation) verify(result *batchOperationResult) error {
+	valueType := testimonium.VALUE_TYPE_TRANSACTION
+	if operation.Type != "" {
+		valueType, _ = testimonium.ParseTrieValueType(operation.Type)
+	}
+	confirmations := batchFlagConfirmations
+	if operation.Confirmations != nil {
+		confirmations = *operation.Confirmations
+	}
+
+	var err error
+	if operation.Backfill {
+		result.Verification, err = testimoniumClient.VerifyWithBackfill(common.HexToHash(operation.TxHash), valueType,
+			confirmations, operation.sourceChain(), operation.destinationChain(), batchFlagMaxHeaders, nil)
+		return err
+	}
+	wait := batchFlagWait
+	if operation.Wait != "" {
+		wait, _ = time.ParseDuration(operation.Wait)
+	}
+	result.Verification, err = testimoniumClient.VerifyAfterRelay(common.HexToHash(operation.TxHash), valueType,
+		confirmations, operation.sourceChain(), operation.destinationChain(), wait, nil)
+	return err
+}
+
+func (operation batchOperation) moveStake(result *batchOperationResult) error {
+	amountInWei, _ := new(big.Int).SetString(operation.Amount, 10)
+	var tx *testimonium.TxResult
+	var err error
+	if operation.Op == "deposit" {
+		tx, err = testimoniumClient.DepositStake(operation.destinationChain(), amountInWei)
+	} else {
+		tx, err = testimoniumClient.WithdrawStake(operation.destinationChain(), amountInWei)
+	}
+	result.Transactions = append(result.Transactions, tx)
+	if err == nil && operation.Op == "deposit" {
+		result.Message = fmt.Sprintf("deposited %s ETH", weiToEth(amountInWei))
+	} else if err == nil {
+		result.Message = fmt.Sprintf("withdrew %s ETH", weiToEth(amountInWei))
+	}
+	return err
+}
+
+func (operation batchOperation) sourceChain() uint8 {
+	if operation.Src != nil {
+		return *operation.Src
+	}
+	return batchFlagSrcChain
+}
+
+func (operation batchOperation) destinationChain() uint8 {
+	if operation.Chain != nil {
+		return *operation.Chain
+	}
+	return batchFlagDestChain
+}
+
+// validate checks the arguments of the operation before any operation of the batch is executed
+func (operation batchOperation) validate() error {
+	isHash := func(value string) bool {
+		return strings.HasPrefix(value, "0x") && len(common.FromHex(value)) == common.HashLength
+	}
+	switch operation.Op {
+	case "submit":
+		if _, ok := new(big.Int).SetString(operation.Block, 10); !ok && !isHash(operation.Block) {
+			return fmt.Errorf("illegal block '%s' (number or hash)", operation.Block)
+		}
+	case "verify":
+		if !isHash(operation.TxHash) {
+			return fmt.Errorf("illegal transaction hash '%s'", operation.TxHash)
+		}
+		if operation.Type != "" {
+			if _, err := testimonium.ParseTrieValueType(operation.Type); err != nil {
+				return err
+			}
+		}
+		if operation.Wait != "" {
+			if _, err := time.ParseDuration(operation.Wait); err != nil {
+				return fmt.Errorf("illegal wait '%s': %s", operation.Wait, err)
+			}
+		}
+	case "dispute":
+		if !isHash(operation.BlockHash) {
+			return fmt.Errorf("illegal block hash '%s'", operation.BlockHash)
+		}
+	case "deposit", "withdraw":
+		if amount, ok := new(big.Int).SetString(operation.Amount, 10); !ok || amount.Sign() <= 0 {
+			return fmt.Errorf("illegal amount '%s' (in wei)", operation.Amount)
+		}
+	default:
+		return fmt.Errorf("unknown operation '%s' (submit, verify, dispute, deposit or withdraw)", operation.Op)
+	}
+	return nil
+}
+
+// readBatchOperations reads the operations from the file or from stdin ("-"), a JSON list or one JSON object per line
+func readBatchOperations(path string) ([]batchOperation, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []batchOperation
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &operations); err != nil {
+			return nil, err
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			var operation batchOperation
+			if err := json.Unmarshal([]byte(text), &operation); err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err)
+			}
+			operations = append(operations, operation)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(operations) == 0 {
+		return nil, fmt.Errorf("no operations")
+	}
+	for i, operation := range operations {
+		if err := operation.validate(); err != nil {
+			return nil, fmt.Errorf("operation %d: %s", i+1, err)
+		}
+	}
+	return operations, nil
+}
+
+// nonNilResults removes the results of transactions that were not sent
+func nonNilResults(results []*testimonium.TxResult) []*testimonium.TxResult {
+	var sent []*testimonium.TxResult
+	for _, result := range results {
+		if result != nil {
+			sent = append(sent, result)
+		}
+	}
+	return sent
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+
+	batchCmd.Flags().Uint8Var(&batchFlagSrcChain, "src", 0, "source chain of operations without \"src\"")
+	batchCmd.Flags().Uint8Var(&batchFlagDestChain, "chain", 1, "verifying chain of operations without \"chain\"")
+	batchCmd.Flags().Uint8VarP(&batchFlagConfirmations, "confirmations", "c", 4, "number of block confirmations of verifications without \"confirmations\"")
+	batchCmd.Flags().DurationVar(&batchFlagWait, "wait", 0, "time verifications without \"wait\" wait until their blocks are relayed")
+	batchCmd.Flags().IntVar(&batchFlagMaxHeaders, "max-headers", 256, "maximum number of headers submitted by verifications with \"backfill\"")
+	batchCmd.Flags().BoolVar(&batchFlagStopOnError, "stop-on-error", false, "skip the remaining operations after an operation failed")
+}
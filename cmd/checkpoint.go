@@ -0,0 +1,73 @@
+// This file contains logic executed if the command "checkpoint" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var checkpointFlagTargetChain uint8
+var checkpointFlagSigners []string
+var checkpointFlagThreshold int
+var checkpointFlagMinProviders int
+var checkpointFlagUnsigned bool
+
+// checkpointCmd represents the checkpoint command
+var checkpointCmd = &cobra.Command{
+	Use:   "checkpoint",
+	Short: "Creates, signs and verifies trusted checkpoints of the target chain",
+	Long: `Creates, signs and verifies checkpoint files. A checkpoint is a recent block of the target chain (header, total
+difficulty, chain id and genesis block) the ETH Relay contract can be deployed with ('deploy ethrelay --checkpoint'),
+instead of relaying all headers from an old genesis block. The parties agreeing on the checkpoint sign the file, and
+before it is used, the signatures of the trusted signers (--signers, --threshold) are checked and the checkpoint is
+compared with the node of the target chain and the providers of its "crosscheck" entry (--min-providers).`,
+}
+
+// checkpointPolicy returns the policy of the checkpoint flags
+func checkpointPolicy() testimonium.CheckpointPolicy {
+	if len(checkpointFlagSigners) == 0 && !checkpointFlagUnsigned {
+		log.Fatal("No trusted signers, set --signers (or --unsigned to rely on the providers only)")
+	}
+	policy := testimonium.CheckpointPolicy{Threshold: checkpointFlagThreshold, MinProviders: checkpointFlagMinProviders}
+	for _, signer := range checkpointFlagSigners {
+		if !common.IsHexAddress(signer) {
+			log.Fatalf("Illegal signer address '%s'", signer)
+		}
+		policy.TrustedSigners = append(policy.TrustedSigners, common.HexToAddress(signer))
+	}
+	return policy
+}
+
+// addCheckpointPolicyFlags adds the flags of the checkpoint policy to the command
+func addCheckpointPolicyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&checkpointFlagSigners, "signers", nil, "addresses of the trusted signers of the checkpoint")
+	cmd.Flags().IntVar(&checkpointFlagThreshold, "threshold", 0, "number of trusted signers that have to sign the checkpoint (default: all)")
+	cmd.Flags().IntVar(&checkpointFlagMinProviders, "min-providers", testimonium.CHECKPOINT_MIN_PROVIDERS, "number of providers of the target chain that have to agree with the checkpoint")
+	cmd.Flags().BoolVar(&checkpointFlagUnsigned, "unsigned", false, "accept checkpoints without trusted signers if the providers agree")
+}
+
+type checkpointResult struct {
+	*testimonium.Checkpoint
+	File string `json:"file"`
+}
+
+func (result checkpointResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Checkpoint block %d (%s), total difficulty %s, chain id %s\n", result.BlockNumber, result.BlockHash.Hex(),
+		result.TotalDifficulty, result.ChainId)
+	for _, signer := range result.Signers() {
+		fmt.Fprintf(w, "Signed by %s\n", signer.Hex())
+	}
+	fmt.Fprintf(w, "Written to %s\n", result.File)
+}
+
+func init() {
+	rootCmd.AddCommand(checkpointCmd)
+
+	checkpointCmd.PersistentFlags().Uint8VarP(&checkpointFlagTargetChain, "target", "t", 0, "target chain of the checkpoint")
+}
@@ -0,0 +1,49 @@
+// This file contains logic executed if the command "checkpoint create" is typed in.
+
+package cmd
+
+import (
+	"log"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var checkpointCreateFlagBlock uint64
+var checkpointCreateFlagOut string
+var checkpointCreateFlagSign bool
+
+// checkpointCreateCmd represents the command 'checkpoint create'
+var checkpointCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Writes the checkpoint of a block of the target chain to a file",
+	Long: `Writes the checkpoint of the block (--block) of the target chain to a file (--out), signed by the current account
+with --sign. The other signers add their signatures with 'checkpoint sign'.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient()
+
+		checkpoint, err := testimoniumClient.CreateCheckpoint(checkpointCreateFlagBlock, checkpointFlagTargetChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if checkpointCreateFlagSign {
+			if err := testimoniumClient.SignCheckpoint(checkpoint); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err := testimonium.WriteCheckpoint(checkpointCreateFlagOut, checkpoint); err != nil {
+			log.Fatal(err)
+		}
+		printResult(checkpointResult{Checkpoint: checkpoint, File: checkpointCreateFlagOut})
+	},
+}
+
+func init() {
+	checkpointCmd.AddCommand(checkpointCreateCmd)
+
+	checkpointCreateCmd.Flags().Uint64Var(&checkpointCreateFlagBlock, "block", 0, "number of the checkpoint block")
+	checkpointCreateCmd.Flags().StringVar(&checkpointCreateFlagOut, "out", "checkpoint.json", "file the checkpoint is written to")
+	checkpointCreateCmd.Flags().BoolVar(&checkpointCreateFlagSign, "sign", false, "sign the checkpoint with the current account")
+	checkpointCreateCmd.MarkFlagRequired("block")
+}
@@ -0,0 +1,38 @@
+// This file contains logic executed if the command "checkpoint sign" is typed in.
+
+package cmd
+
+import (
+	"log"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+// checkpointSignCmd represents the command 'checkpoint sign'
+var checkpointSignCmd = &cobra.Command{
+	Use:   "sign [file]",
+	Short: "Adds the signature of the current account to a checkpoint file",
+	Long: `Adds the signature of the current account to the checkpoint file. Sign a checkpoint only after comparing its block
+hash with a source you trust, e.g., 'checkpoint verify' with your own providers.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkpoint, err := testimonium.ReadCheckpoint(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		testimoniumClient = createTestimoniumClient()
+		if err := testimoniumClient.SignCheckpoint(checkpoint); err != nil {
+			log.Fatal(err)
+		}
+		if err := testimonium.WriteCheckpoint(args[0], checkpoint); err != nil {
+			log.Fatal(err)
+		}
+		printResult(checkpointResult{Checkpoint: checkpoint, File: args[0]})
+	},
+}
+
+func init() {
+	checkpointCmd.AddCommand(checkpointSignCmd)
+}
@@ -0,0 +1,69 @@
+// This file contains logic executed if the command "checkpoint verify" is typed in.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+// checkpointVerifyCmd represents the command 'checkpoint verify'
+var checkpointVerifyCmd = &cobra.Command{
+	Use:   "verify [file]",
+	Short: "Checks a checkpoint file against the trusted signers and the providers of the target chain",
+	Long: `Checks that the checkpoint file is signed by enough trusted signers (--signers, --threshold) and that the node of the
+target chain and the providers of its "crosscheck" entry agree on the chain id, the genesis block, the checkpoint block
+and its total difficulty (at least --min-providers). Providers that cannot be reached are reported, a disagreeing
+provider fails the check.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkpoint, err := testimonium.ReadCheckpoint(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		policy := checkpointPolicy()
+
+		testimoniumClient = createTestimoniumClient()
+		validation, err := testimoniumClient.ValidateCheckpoint(checkpoint, checkpointFlagTargetChain, policy)
+		if validation == nil {
+			log.Fatal(err)
+		}
+		printResult(checkpointValidationResult{validation})
+		if errors.Is(err, testimonium.ErrUntrustedCheckpoint) {
+			log.Fatal("Checkpoint verification FAILED")
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+type checkpointValidationResult struct {
+	*testimonium.CheckpointValidation
+}
+
+func (result checkpointValidationResult) renderText(w io.Writer) {
+	for _, signer := range result.Signers {
+		fmt.Fprintf(w, "Signed by trusted signer %s\n", signer.Hex())
+	}
+	for _, provider := range result.Agreeing {
+		fmt.Fprintf(w, "Confirmed by %s\n", provider)
+	}
+	for _, problem := range result.Problems {
+		fmt.Fprintf(w, "WARNING: %s\n", problem)
+	}
+	if result.Trusted {
+		fmt.Fprintln(w, "Checkpoint trusted")
+	}
+}
+
+func init() {
+	checkpointCmd.AddCommand(checkpointVerifyCmd)
+
+	addCheckpointPolicyFlags(checkpointVerifyCmd)
+}
@@ -0,0 +1,78 @@
+// This file contains logic executed if the command "clock" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var clockFlagChain uint8
+var clockFlagSrcChain uint8
+var clockFlagTolerance time.Duration
+
+// clockCmd represents the clock command
+var clockCmd = &cobra.Command{
+	Use:   "clock",
+	Short: "Compares the local clock with the timestamps of the source and the verifying chain",
+	Long: `Compares the local clock with the timestamps of the latest blocks of the source chain (--src) and the verifying
+chain (--chain).
+
+The ETH Relay contract measures lock periods (how long the stake of a submitted header is locked and the header can
+be disputed) with the timestamps of the verifying chain's blocks, while the client waits for them with the local
+clock. The command warns if the head of a chain is ahead of the local clock or older than the chain's block interval
+by more than --tolerance, if the timestamps of the chains differ by more than that, and how far lock periods measured
+locally are off relative to the contract's lock period. The live mode runs the same check when it starts and every
+10 minutes.
+
+The command fails if a skew was found.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient()
+
+		check, err := testimoniumClient.CheckClocks(clockFlagSrcChain, clockFlagChain, clockFlagTolerance)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printResult(clockResult{check})
+		if check.Skewed() {
+			log.Fatal("The clocks are skewed")
+		}
+	},
+}
+
+type clockResult struct {
+	testimonium.ClockCheck
+}
+
+func (result clockResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Local clock: %s\n", result.LocalTime.Format(time.RFC3339))
+	for _, chain := range []testimonium.ChainClock{result.Source, result.Destination} {
+		fmt.Fprintf(w, "Chain %d: block %d at %s (%s ago, block interval %s)\n", chain.Chain, chain.HeadNumber,
+			chain.HeadTime.Format(time.RFC3339), chain.HeadAge.Round(time.Second), chain.BlockInterval.Round(time.Second))
+	}
+	fmt.Fprintf(w, "Skew between the chains: %s\n", result.ChainSkew.Round(time.Second))
+	if result.LockPeriod > 0 {
+		fmt.Fprintf(w, "Lock period: %s\n", result.LockPeriod)
+	}
+	if !result.Skewed() {
+		fmt.Fprintf(w, "No skew beyond the tolerance of %s\n", result.Tolerance)
+		return
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(w, "WARNING: %s\n", warning)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(clockCmd)
+
+	clockCmd.Flags().Uint8VarP(&clockFlagChain, "chain", "c", 1, "the verifying chain of the ETH Relay contract")
+	clockCmd.Flags().Uint8Var(&clockFlagSrcChain, "src", 0, "the source chain")
+	clockCmd.Flags().DurationVar(&clockFlagTolerance, "tolerance", testimonium.CLOCK_SKEW_TOLERANCE, "skew tolerated in addition to the block interval of the chains")
+}
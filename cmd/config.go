@@ -0,0 +1,18 @@
+// This file contains logic executed if the command "config" is typed in.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Maintains the config file",
+	Long:  `Commands maintaining the config file (testimonium.yml or the file specified with --config)`,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+}
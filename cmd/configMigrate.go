@@ -0,0 +1,244 @@
+// This file contains logic executed if the command "config migrate" is typed in.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+var configMigrateFlagDryRun bool
+
+// keys of config entries holding hex strings, which YAML would read as numbers if they are short enough
+var configHexKeys = []string{"privatekey", "ethrelayaddress", "ethashaddress", "multicalladdress", "create2deployer"}
+
+// configMigrateCmd represents the migrate command
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrades the config file to the current layout",
+	Long: `Upgrades the config file to the current layout, in which the connection of a chain is configured with a single url
+entry containing the scheme and the port (e.g., 'url: http://localhost:7545') instead of the separate type, url and
+port entries. Chain ids are written as plain integer keys and hex values (private key, contract addresses) as strings.
+
+The previous config file is kept with the suffix .bak. Comments are preserved, the comments of the removed type and
+port entries are moved to the url entry. With --dry-run, the migrated config is printed instead of written.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path := cfgFile
+		if path == "" {
+			if err := viper.ReadInConfig(); err != nil {
+				log.Fatal("Can't read config file: ", err)
+			}
+			path = viper.ConfigFileUsed()
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		migrated, changes, err := migrateConfig(data)
+		if err != nil {
+			log.Fatalf("Cannot migrate %s: %s", path, err)
+		}
+
+		result := configMigrateResult{File: path, Changes: changes, DryRun: configMigrateFlagDryRun}
+		if configMigrateFlagDryRun {
+			result.Config = string(migrated)
+		} else if len(changes) > 0 {
+			result.Backup = path + ".bak"
+			if err := ioutil.WriteFile(result.Backup, data, 0600); err != nil {
+				log.Fatal(err)
+			}
+			if err := ioutil.WriteFile(path, migrated, 0600); err != nil {
+				log.Fatal(err)
+			}
+		}
+		printResult(result)
+	},
+}
+
+// configMigrateResult is the result of the config migrate command.
+type configMigrateResult struct {
+	File    string   `json:"file"`
+	Backup  string   `json:"backup,omitempty"`
+	Changes []string `json:"changes"`
+	DryRun  bool     `json:"dryRun"`
+	Config  string   `json:"config,omitempty"`
+}
+
+func (result configMigrateResult) renderText(w io.Writer) {
+	if len(result.Changes) == 0 {
+		fmt.Fprintf(w, "%s already has the current layout\n", result.File)
+		return
+	}
+	for _, change := range result.Changes {
+		fmt.Fprintf(w, "%s\n", change)
+	}
+	if result.DryRun {
+		fmt.Fprintf(w, "\n%s", result.Config)
+		return
+	}
+	fmt.Fprintf(w, "Migrated %s (previous config: %s)\n", result.File, result.Backup)
+}
+
+// migrateConfig converts the config to the current layout and returns it together with a description of the changes
+func migrateConfig(data []byte) ([]byte, []string, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, nil, err
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("config is not a map")
+	}
+	root := document.Content[0]
+
+	var changes []string
+	changes = append(changes, quoteHexValues(root, "")...)
+
+	_, chains := configEntry(root, "chains")
+	if chains == nil || chains.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("no chains configured")
+	}
+	for i := 0; i < len(chains.Content); i += 2 {
+		key, chain := chains.Content[i], chains.Content[i+1]
+		chainId, err := strconv.ParseUint(strings.TrimSpace(key.Value), 10, 8)
+		if err != nil {
+			return nil, nil, fmt.Errorf("chain key '%s' is not a chain id (0-255)", key.Value)
+		}
+		if key.Tag != "!!int" || key.Style != 0 || key.Value != strconv.FormatUint(chainId, 10) {
+			key.Value, key.Tag, key.Style = strconv.FormatUint(chainId, 10), "!!int", 0
+			changes = append(changes, fmt.Sprintf("chain %d: id written as integer key", chainId))
+		}
+		if chain.Kind != yaml.MappingNode {
+			return nil, nil, fmt.Errorf("config of chain %d is not a map", chainId)
+		}
+
+		urlChange, err := mergeConnectionUrl(chain)
+		if err != nil {
+			return nil, nil, fmt.Errorf("chain %d: %s", chainId, err)
+		}
+		if urlChange != "" {
+			changes = append(changes, fmt.Sprintf("chain %d: %s", chainId, urlChange))
+		}
+		changes = append(changes, quoteHexValues(chain, fmt.Sprintf("chain %d: ", chainId))...)
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(4)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, err
+	}
+	return buffer.Bytes(), changes, nil
+}
+
+// mergeConnectionUrl replaces the type, url and port entries of the chain config with a single url entry
+func mergeConnectionUrl(chain *yaml.Node) (string, error) {
+	typeKey, typeValue := configEntry(chain, "type")
+	urlKey, urlValue := configEntry(chain, "url")
+	portKey, portValue := configEntry(chain, "port")
+	if urlValue == nil {
+		return "", fmt.Errorf("no url specified")
+	}
+
+	url := urlValue.Value
+	hasScheme := strings.Contains(url, "://")
+	if typeValue == nil && portValue == nil && hasScheme {
+		return "", nil
+	}
+	if typeValue != nil {
+		if hasScheme {
+			return "", fmt.Errorf("type %s specified for url %s containing a scheme", typeValue.Value, url)
+		}
+		url = typeValue.Value + "://" + url
+	} else if !hasScheme {
+		// the scheme has defaulted to https
+		url = "https://" + url
+	}
+	if portValue != nil {
+		port, err := strconv.ParseUint(portValue.Value, 10, 16)
+		if err != nil {
+			return "", fmt.Errorf("illegal port: %s", portValue.Value)
+		}
+		// the port follows the host, not the path
+		hostEnd := strings.Index(url, "://") + 3
+		if slash := strings.Index(url[hostEnd:], "/"); slash >= 0 {
+			hostEnd += slash
+		} else {
+			hostEnd = len(url)
+		}
+		url = fmt.Sprintf("%s:%d%s", url[:hostEnd], port, url[hostEnd:])
+	}
+
+	urlValue.Value, urlValue.Tag, urlValue.Style = url, "!!str", 0
+	for _, removed := range []*yaml.Node{typeKey, typeValue, portKey, portValue} {
+		if removed != nil {
+			urlKey.HeadComment = joinComments(urlKey.HeadComment, removed.HeadComment, removed.LineComment, removed.FootComment)
+		}
+	}
+	removeConfigEntry(chain, typeKey)
+	removeConfigEntry(chain, portKey)
+	return "type, url and port merged into url " + url, nil
+}
+
+// quoteHexValues marks the hex values of the config map as strings
+func quoteHexValues(config *yaml.Node, prefix string) []string {
+	var changes []string
+	for _, name := range configHexKeys {
+		key, value := configEntry(config, name)
+		if value == nil || value.Kind != yaml.ScalarNode || value.Style != 0 {
+			continue
+		}
+		value.Tag, value.Style = "!!str", yaml.DoubleQuotedStyle
+		changes = append(changes, fmt.Sprintf("%s%s written as string", prefix, key.Value))
+	}
+	return changes
+}
+
+// configEntry returns the key and value nodes of the entry of a config map, keys are matched case-insensitively (like
+// viper does)
+func configEntry(config *yaml.Node, name string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(config.Content); i += 2 {
+		if strings.EqualFold(config.Content[i].Value, name) {
+			return config.Content[i], config.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+func removeConfigEntry(config *yaml.Node, key *yaml.Node) {
+	for i := 0; i+1 < len(config.Content); i += 2 {
+		if config.Content[i] == key {
+			config.Content = append(config.Content[:i], config.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+func joinComments(comments ...string) string {
+	var nonEmpty []string
+	for _, comment := range comments {
+		if comment != "" {
+			nonEmpty = append(nonEmpty, comment)
+		}
+	}
+	return strings.Join(nonEmpty, "\n")
+}
+
+func init() {
+	configCmd.AddCommand(configMigrateCmd)
+
+	configMigrateCmd.Flags().BoolVar(&configMigrateFlagDryRun, "dry-run", false, "print the migrated config instead of writing it")
+}
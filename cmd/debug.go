@@ -0,0 +1,87 @@
+// This file contains the opt-in debug server (--debug-addr) exposing pprof, goroutine dumps and the internal state
+// of long running commands, e.g., to diagnose stalls of the live mode or the memory usage of DAG computations.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rpprof "runtime/pprof"
+	"time"
+
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+	"github.com/pantos-io/go-ethrelay/testimonium"
+)
+
+var debugAddr string
+
+// liveMonitor is set by commands running the live mode, its state is exposed by the debug server
+var liveMonitor *testimonium.LiveMonitor
+
+var debugStarted = time.Now()
+
+type debugRuntime struct {
+	Uptime       string                    `json:"uptime"`
+	Goroutines   int                       `json:"goroutines"`
+	HeapAlloc    uint64                    `json:"heapAlloc"` // bytes of allocated heap objects
+	HeapInuse    uint64                    `json:"heapInuse"` // bytes in in-use spans
+	Sys          uint64                    `json:"sys"`       // bytes obtained from the OS
+	NumGC        uint32                    `json:"numGC"`
+	PauseTotalNs uint64                    `json:"pauseTotalNs"`
+	Live         *testimonium.LiveSnapshot `json:"live,omitempty"` // state of the live mode, e.g., the stake queue
+	DAGCache     ethash.DAGCacheStats      `json:"dagCache"`       // the DAG cache shared by disputes and epoch data
+}
+
+// startDebugServer serves the debug endpoints on debugAddr in the background
+func startDebugServer() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	mux.HandleFunc("/debug/goroutines", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		rpprof.Lookup("goroutine").WriteTo(w, 2)
+	})
+
+	mux.HandleFunc("/debug/runtime", func(w http.ResponseWriter, r *http.Request) {
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+
+		state := debugRuntime{
+			Uptime:       time.Since(debugStarted).Round(time.Second).String(),
+			Goroutines:   runtime.NumGoroutine(),
+			HeapAlloc:    memStats.HeapAlloc,
+			HeapInuse:    memStats.HeapInuse,
+			Sys:          memStats.Sys,
+			NumGC:        memStats.NumGC,
+			PauseTotalNs: memStats.PauseTotalNs,
+			DAGCache:     ethash.SharedDAGCache().Stats(),
+		}
+		if liveMonitor != nil {
+			snapshot := liveMonitor.Snapshot()
+			state.Live = &snapshot
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(state)
+	})
+
+	go func() {
+		if err := http.ListenAndServe(debugAddr, mux); err != nil {
+			fmt.Fprintf(progressOutput(), "WARNING: Debug server stopped: %s\n", err)
+		}
+	}()
+	fmt.Fprintf(progressOutput(), "Debug server listening on %s (/debug/pprof/, /debug/goroutines, /debug/runtime)\n", debugAddr)
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&debugAddr, "debug-addr", "", "serve pprof, goroutine dumps and runtime diagnostics on this address (e.g., localhost:6060)")
+}
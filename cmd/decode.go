@@ -0,0 +1,18 @@
+// This file contains logic executed if the command "decode" is typed in.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// decodeCmd represents the decode command
+var decodeCmd = &cobra.Command{
+	Use:   "decode",
+	Short: "Decodes RLP encoded data",
+	Long:  `Decodes RLP encoded data (e.g., block headers) and prints its contents`,
+}
+
+func init() {
+	rootCmd.AddCommand(decodeCmd)
+}
@@ -0,0 +1,96 @@
+// This file contains logic executed if the command "decode header" is typed in.
+
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+// decodeHeaderCmd represents the command 'decode header'
+var decodeHeaderCmd = &cobra.Command{
+	Use:   "header [hex or file]",
+	Short: "Decodes and prints an RLP encoded block header",
+	Long: `Decodes the RLP encoded block header given as hex string or file (hex or binary) and prints all fields,
+the hashes with and without nonce, the fork of the block and fields the ETH Relay contract cannot handle`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		rlpHeader, err := readRlpInput(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		decoded, err := testimonium.DecodeRLPHeader(rlpHeader)
+		if err != nil {
+			log.Fatal("Failed to decode header: " + err.Error())
+		}
+
+		printResult(decodedHeaderResult{decoded})
+	},
+}
+
+type decodedHeaderResult struct {
+	*testimonium.DecodedHeader
+}
+
+func (result decodedHeaderResult) renderText(w io.Writer) {
+	header := result.Header
+	fmt.Fprintf(w, "Hash: %s\n", result.Hash.String())
+	fmt.Fprintf(w, "Hash without nonce: %s\n", result.HashWithoutNonce.String())
+	fmt.Fprintf(w, "ParentHash: %s\n", header.ParentHash.String())
+	fmt.Fprintf(w, "UncleHash: %s\n", header.UncleHash.String())
+	fmt.Fprintf(w, "Coinbase: %s\n", header.Coinbase.Hex())
+	fmt.Fprintf(w, "StateRoot: %s\n", header.Root.String())
+	fmt.Fprintf(w, "TxHash: %s\n", header.TxHash.String())
+	fmt.Fprintf(w, "ReceiptHash: %s\n", header.ReceiptHash.String())
+	fmt.Fprintf(w, "Bloom: %x\n", header.Bloom.Bytes())
+	fmt.Fprintf(w, "Difficulty: %s\n", header.Difficulty.String())
+	fmt.Fprintf(w, "Number: %s (%s)\n", header.Number.String(), result.Fork)
+	fmt.Fprintf(w, "GasLimit: %d\n", header.GasLimit)
+	fmt.Fprintf(w, "GasUsed: %d\n", header.GasUsed)
+	fmt.Fprintf(w, "Time: %d\n", header.Time)
+	fmt.Fprintf(w, "Extra: 0x%x (%q)\n", header.Extra, header.Extra)
+	fmt.Fprintf(w, "MixDigest: %s\n", header.MixDigest.String())
+	fmt.Fprintf(w, "Nonce: %d\n", header.Nonce.Uint64())
+	fmt.Fprintf(w, "Fields: %d\n", result.FieldCount)
+
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(w, "WARNING: %s\n", warning)
+	}
+}
+
+func init() {
+	decodeCmd.AddCommand(decodeHeaderCmd)
+}
+
+// readRlpInput reads RLP encoded data given as hex string or as file containing a hex string or the raw bytes
+func readRlpInput(input string) ([]byte, error) {
+	if _, err := os.Stat(input); err == nil {
+		data, err := ioutil.ReadFile(input)
+		if err != nil {
+			return nil, err
+		}
+		if decoded, err := decodeHex(string(data)); err == nil {
+			return decoded, nil
+		}
+		return data, nil
+	}
+
+	decoded, err := decodeHex(input)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' is neither a file nor a hex string", input)
+	}
+	return decoded, nil
+}
+
+func decodeHex(input string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(input), "0x"))
+}
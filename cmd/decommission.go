@@ -0,0 +1,101 @@
+// This file contains logic executed if the command "decommission" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var decommissionFlagChain uint8
+var decommissionFlagLockPeriod time.Duration
+var decommissionFlagPollInterval time.Duration
+var decommissionFlagNoWait bool
+var decommissionFlagCancel bool
+
+// decommissionCmd represents the decommission command
+var decommissionCmd = &cobra.Command{
+	Use:   "decommission",
+	Short: "Stops relaying to a chain and withdraws the whole stake",
+	Long: `Decommissions the current account on the verifying chain (--chain):
+
+1. Header submissions of the account to the chain are stopped. A marker is written to the data directory (--datadir),
+   'submit block' and the live mode using the directory fail while it exists.
+2. The command waits until the lock period of the last header of the account that is still locked (according to the
+   event index, see 'index update' and 'status --locks') passed on the chain. Headers removed by a dispute lock no
+   stake. The lock period is read from the contract or set with --lock-period.
+3. The whole stake is withdrawn, stake still locked (e.g., by a pending dispute) is retried every --poll-interval.
+4. A final accounting of the account's relay activity (headers, disputes, verification fees, gas) is printed.
+
+As waiting takes the lock period, the command can be interrupted and run again later. With --no-wait, it fails
+instead of waiting. The marker is kept after the decommissioning, --cancel removes it so the account can relay again.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if decommissionFlagCancel {
+			if err := testimonium.CancelDecommission(dataDir, decommissionFlagChain); err != nil {
+				log.Fatal(err)
+			}
+			printResult(txResult{Message: fmt.Sprintf("Decommissioning of chain %d canceled, headers are submitted again", decommissionFlagChain)})
+			return
+		}
+
+		testimoniumClient = createTestimoniumClient()
+		report, err := testimoniumClient.Decommission(dataDir, decommissionFlagChain, testimonium.DecommissionConfig{
+			LockPeriod:   decommissionFlagLockPeriod,
+			PollInterval: decommissionFlagPollInterval,
+			NoWait:       decommissionFlagNoWait,
+		})
+		if err != nil {
+			log.Fatal("Decommissioning failed: " + err.Error())
+		}
+		printResult(decommissionResult{report})
+	},
+}
+
+type decommissionResult struct {
+	*testimonium.DecommissionReport
+}
+
+func (result decommissionResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Account %s decommissioned on chain %d\n", result.Account.Hex(), result.Chain)
+	fmt.Fprintf(w, "Started: %s, completed: %s\n", result.Started.Format(time.RFC3339), result.Completed.Format(time.RFC3339))
+	if result.LastSubmission != nil {
+		fmt.Fprintf(w, "Last submission: %s, unlocked at %s (lock period %s)\n", result.LastSubmission.Format(time.RFC3339),
+			result.UnlockedAt.Format(time.RFC3339), result.LockPeriod)
+	} else {
+		fmt.Fprintln(w, "No headers submitted")
+	}
+	for _, withdrawal := range result.Withdrawals {
+		fmt.Fprintf(w, "Withdrawal: %s\n", withdrawal.TxHash.Hex())
+	}
+	fmt.Fprintf(w, "Stake withdrawn: %s ETH (of %s ETH)\n", weiToEth(result.StakeWithdrawn), weiToEth(result.StakeBefore))
+	if result.StakeRemaining.Sign() > 0 {
+		fmt.Fprintf(w, "Stake remaining: %s ETH\n", weiToEth(result.StakeRemaining))
+	}
+	fmt.Fprintf(w, "Balance: %s ETH\n", weiToEth(result.Balance))
+
+	activity := result.Activity
+	fmt.Fprintf(w, "Headers submitted: %d\n", activity.HeadersSubmitted)
+	fmt.Fprintf(w, "Disputes won: %d, lost: %d\n", activity.DisputesWon, activity.DisputesLost)
+	fmt.Fprintf(w, "Verifications served: %d, fees earned: %s ETH\n", activity.VerificationsServed, formatEther(activity.FeesEarned))
+	for token, fees := range activity.TokenFeesEarned {
+		fmt.Fprintf(w, "Fees paid in token %s: %s (smallest unit)\n", token.Hex(), fees)
+	}
+	fmt.Fprintf(w, "Gas spent: %s ETH\n", formatEther(activity.GasSpent))
+	fmt.Fprintf(w, "Profit: %s ETH\n", formatEther(result.Profit))
+}
+
+func init() {
+	rootCmd.AddCommand(decommissionCmd)
+
+	decommissionCmd.Flags().Uint8VarP(&decommissionFlagChain, "chain", "c", 1, "the verifying chain of the ETH Relay contract")
+	decommissionCmd.Flags().DurationVar(&decommissionFlagLockPeriod, "lock-period", 0, "lock period of submitted headers (default: read from the contract)")
+	decommissionCmd.Flags().DurationVar(&decommissionFlagPollInterval, "poll-interval", testimonium.DECOMMISSION_POLL_INTERVAL, "interval the lock period and the stake are checked in")
+	decommissionCmd.Flags().BoolVar(&decommissionFlagNoWait, "no-wait", false, "fail instead of waiting for locked stake")
+	decommissionCmd.Flags().BoolVar(&decommissionFlagCancel, "cancel", false, "remove the decommission marker, so the account submits headers again")
+}
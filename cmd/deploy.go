@@ -15,21 +15,36 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"log"
 	"strconv"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/testimonium"
 	"github.com/spf13/viper"
 
 	"github.com/spf13/cobra"
 )
 
 var deployFlagVerifyingChain uint8
+var deployFlagSalt string
+var deployFlagForce bool
+var deployFlagMetadata map[string]string
 
 // deployCmd represents the deploy command
 var deployCmd = &cobra.Command{
 	Use:   "deploy",
 	Short: "Deploys a smart contract (Ethash or ETH Relay) on the specified blockchain",
-	Long:  `Deploys a smart contract (Ethash or ETH Relay) on the specified blockchain`,
+	Long: `Deploys a smart contract (Ethash or ETH Relay) on the specified blockchain
+
+Deployments are recorded in the registry of the blockchain in the data directory. A contract that is already recorded
+is not deployed again unless --force is specified, as a new contract does not contain the headers and stakes of the
+previous one.
+
+Deployments are tagged with metadata, e.g., who deployed the contract (deployer), the release of the setup (version)
+and its purpose, from the 'deployment' section of the config file and --metadata (which takes precedence). If the
+config of the blockchain contains a 'deploymentregistry' entry, every deployment is also announced to that contract.`,
 }
 
 func init() {
@@ -40,12 +55,25 @@ func init() {
 	// Cobra supports Persistent Flags which will work for this command
 	// and all subcommands, e.g.:
 	deployCmd.PersistentFlags().Uint8VarP(&deployFlagVerifyingChain, "verifying", "v", 1, "The blockchain to which the smart contract is deployed")
+	deployCmd.PersistentFlags().BoolVar(&deployFlagForce, "force", false, "Deploys the contract even if the registry contains a deployment on the blockchain")
+	deployCmd.PersistentFlags().StringToStringVar(&deployFlagMetadata, "metadata", nil, "Tags the deployment with metadata, e.g., deployer=team-a,version=1.2,purpose=staging (merged with the 'deployment' section of the config file)")
+	deployCmd.PersistentFlags().StringVar(&deployFlagSalt, "salt", "", "Deploys the contract with the CREATE2 deployer using this salt (32 byte hex or any string, which is hashed), so it lands at the same address on every chain")
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// deployCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
+// deployResult is the result of the deploy commands.
+type deployResult struct {
+	Chain   uint8          `json:"chain"`
+	Address common.Address `json:"address"`
+}
+
+func (result deployResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Contract has been deployed at address: %s\n", result.Address.String())
+}
+
 func updateChainsConfig(deployedAddress common.Address, chainId uint8, key string) {
 	chainsConfig := viper.Get("chains").(map[string]interface{})
 	deployChainConfig := chainsConfig[strconv.FormatUint(uint64(chainId), 10)].(map[string]interface{})
@@ -56,3 +84,34 @@ func updateChainsConfig(deployedAddress common.Address, chainId uint8, key strin
 
 	_ = viper.WriteConfig()
 }
+
+// checkRedeployment exits if the registry of the chain already contains a deployment of the contract, unless --force
+// is specified or the deployment uses the same CREATE2 salt (which results in the same contract).
+func checkRedeployment(chain uint8, contract string) {
+	registry, err := testimonium.OpenChainRegistry(dataDir, chain)
+	if err != nil {
+		log.Fatal(err)
+	}
+	deployment, exists := registry.Deployment(contract)
+	if !exists || deployFlagForce {
+		return
+	}
+	if deployFlagSalt != "" && deployment.Salt != nil && *deployment.Salt == testimonium.ParseSalt(deployFlagSalt) {
+		return
+	}
+	log.Fatalf("Contract %s already deployed on chain %d at %s (tx %s, %s), use --force to deploy a new one",
+		contract, chain, deployment.Address.Hex(), deployment.TxHash.Hex(), deployment.DeployedAt.Format("2006-01-02 15:04:05 MST"))
+}
+
+// deploymentMetadataOption returns the option tagging deployments with the metadata of the 'deployment' section of the
+// config file and of --metadata
+func deploymentMetadataOption() testimonium.ClientOption {
+	metadata := make(map[string]string)
+	for key, value := range viper.GetStringMap("deployment") {
+		metadata[key] = fmt.Sprint(value)
+	}
+	for key, value := range deployFlagMetadata {
+		metadata[key] = value
+	}
+	return testimonium.WithDeploymentMetadata(metadata)
+}
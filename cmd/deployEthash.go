@@ -15,6 +15,10 @@
 package cmd
 
 import (
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/testimonium"
 	"github.com/spf13/cobra"
 )
 
@@ -24,10 +28,22 @@ var ethashCmd = &cobra.Command{
 	Short: "Deploys the Ethash smart contract on the specified blockchain",
 	Long: `Deploys the Ethash smart contract on the specified blockchain`,
 	Run: func(cmd *cobra.Command, args []string) {
-		testimoniumClient = createTestimoniumClient()
-		deployedAddress := testimoniumClient.DeployEthash(deployFlagVerifyingChain)
+		checkRedeployment(deployFlagVerifyingChain, testimonium.REGISTRY_ETHASH)
+
+		testimoniumClient = createTestimoniumClient(deploymentMetadataOption())
+		var deployedAddress common.Address
+		var err error
+		if deployFlagSalt != "" {
+			deployedAddress, err = testimoniumClient.DeployEthashCreate2(deployFlagVerifyingChain, testimonium.ParseSalt(deployFlagSalt))
+		} else {
+			deployedAddress, err = testimoniumClient.DeployEthash(deployFlagVerifyingChain)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
 
 		updateChainsConfig(deployedAddress, deployFlagVerifyingChain, "ethashAddress")
+		printResult(deployResult{Chain: deployFlagVerifyingChain, Address: deployedAddress})
 	},
 }
 
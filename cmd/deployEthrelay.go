@@ -15,22 +15,62 @@
 package cmd
 
 import (
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/testimonium"
 	"github.com/spf13/cobra"
 )
 
 var deployFlagTargetChain uint8
 var deployFlagGenesisNumber uint64
+var deployFlagCheckpoint string
 
 // ethrelayCmd represents the ethrelay command
 var ethrelayCmd = &cobra.Command{
 	Use:   "ethrelay",
 	Short: "Deploys the ETH Relay smart contract on the specified blockchain",
-	Long:  `Deploys the ETH Relay smart contract on the specified blockchain`,
+	Long: `Deploys the ETH Relay smart contract on the specified blockchain
+
+With --checkpoint, the contract starts at the block of a checkpoint file (see 'checkpoint') instead of --genesis. The
+checkpoint is only used if it is signed by the trusted signers (--signers, --threshold) and enough providers of the
+target chain agree with it (--min-providers).`,
 	Run: func(cmd *cobra.Command, args []string) {
-		testimoniumClient = createTestimoniumClient()
-		deployedAddress := testimoniumClient.DeployTestimonium(deployFlagVerifyingChain, deployFlagTargetChain, deployFlagGenesisNumber)
+		checkRedeployment(deployFlagVerifyingChain, testimonium.REGISTRY_ETHRELAY)
+
+		var checkpoint *testimonium.Checkpoint
+		var policy testimonium.CheckpointPolicy
+		if deployFlagCheckpoint != "" {
+			var err error
+			if checkpoint, err = testimonium.ReadCheckpoint(deployFlagCheckpoint); err != nil {
+				log.Fatal(err)
+			}
+			policy = checkpointPolicy()
+		}
+
+		testimoniumClient = createTestimoniumClient(deploymentMetadataOption())
+		var deployedAddress common.Address
+		var err error
+		if checkpoint != nil {
+			var salt *common.Hash
+			if deployFlagSalt != "" {
+				parsed := testimonium.ParseSalt(deployFlagSalt)
+				salt = &parsed
+			}
+			deployedAddress, err = testimoniumClient.DeployTestimoniumFromCheckpoint(deployFlagVerifyingChain, deployFlagTargetChain,
+				checkpoint, policy, salt)
+		} else if deployFlagSalt != "" {
+			deployedAddress, err = testimoniumClient.DeployTestimoniumCreate2(deployFlagVerifyingChain, deployFlagTargetChain,
+				deployFlagGenesisNumber, testimonium.ParseSalt(deployFlagSalt))
+		} else {
+			deployedAddress, err = testimoniumClient.DeployTestimonium(deployFlagVerifyingChain, deployFlagTargetChain, deployFlagGenesisNumber)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
 
 		updateChainsConfig(deployedAddress, deployFlagVerifyingChain, "ethrelayAddress")
+		printResult(deployResult{Chain: deployFlagVerifyingChain, Address: deployedAddress})
 	},
 }
 
@@ -44,6 +84,8 @@ func init() {
 	// ethrelayCmd.PersistentFlags().String("foo", "", "A help for foo")
 	ethrelayCmd.Flags().Uint8VarP(&deployFlagTargetChain, "target", "t", 0, "The 'target' chain containing the specified genesis block")
 	ethrelayCmd.Flags().Uint64VarP(&deployFlagGenesisNumber, "genesis", "g", 1, "The number of the block (of the target chain) that should be used as genesis block")
+	ethrelayCmd.Flags().StringVar(&deployFlagCheckpoint, "checkpoint", "", "checkpoint file whose block is used as genesis block (instead of --genesis)")
+	addCheckpointPolicyFlags(ethrelayCmd)
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
@@ -4,29 +4,100 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"runtime"
+
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+	"github.com/pantos-io/go-ethrelay/testimonium"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var disputeFlagChain uint8
+var disputeFlagDryRun bool
+var disputeFlagWorkers int
+var disputeFlagDagMemory uint64
+var disputeFlagDagDisk uint64
+var disputeFlagPublic bool
+var disputeFlagWitnessCache string
+var disputeFlagWitnessCacheUrl string
+var disputeFlagArchive string
+var disputeFlagNoArchive bool
 
 // disputeCmd represents the dispute command
 var disputeCmd = &cobra.Command{
-	Use:   "dispute [blockHash]",
-	Short: "Disputes a submitted block header",
-	Long: `Disputes the submitted block header with the specified hash ('blockHash')`,
-	Args: cobra.ExactArgs(1),
+	Use:   "dispute [blockHash]...",
+	Short: "Disputes submitted block headers",
+	Long: `Disputes the submitted block headers with the specified hashes ('blockHash')
+
+If several block headers are disputed, their witnesses are generated concurrently before the first dispute
+is sent, so all disputes can be filed within the lock period. DAGs that fit into the memory specified by
+--dag-memory are read from disk only once and shared between the blocks of the same epoch. With --dag-disk, the least
+recently used DAG files are deleted when a new DAG is generated and the DAG files would exceed the limit. Both limits
+can also be set with the entries 'memory' and 'disk' of the 'dag' entry of the config file.
+
+If a private relay is configured for the disputed chain (entry 'privaterelay'), the disputes are sent to it instead of
+the public mempool, so the submitter of a block cannot front-run its dispute. Use --public to bypass the relay.
+
+Witnesses are stored in the directory --witness-cache and shared with other relayer instances via the server at
+--witness-cache-url (see 'ethash witness-server'), so the DAGs are only generated for blocks no one disputed before.
+
+The evidence of every dispute (both RLP headers, the DAG lookups and their witnesses, the transactions and the emitted
+events) is archived as JSON file in the directory --archive (default: 'disputes' in the data directory) for later
+auditing or publishing. Use --no-archive to disable the archive.`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		blockHash := common.HexToHash(args[0])
+		blockHashes := make([]common.Hash, len(args))
+		for i, arg := range args {
+			blockHashes[i] = common.HexToHash(arg)
+		}
+
+		configureDAGCache(cmd)
+		testimoniumClient = createTestimoniumClient(disputeClientOptions()...)
 
-		// copy dynamic byte array to fixed length byte array
-		var blockHashBytes32 [32]byte
-		blockHashBytes := blockHash.Bytes()
-		copy(blockHashBytes32[:], blockHashBytes)
+		if disputeFlagDryRun {
+			var predictions disputePredictionResults
+			for _, blockHash := range blockHashes {
+				prediction, err := testimoniumClient.PredictDispute(blockHash, disputeFlagChain)
+				if err != nil {
+					log.Fatal("Failed to predict dispute: " + err.Error())
+				}
+				predictions = append(predictions, newDisputePredictionResult(prediction))
+			}
+			printResult(predictions)
+			return
+		}
 
-		// call disputeBlock in the testimonium client library
-		testimoniumClient = createTestimoniumClient()
-		testimoniumClient.DisputeBlock(blockHash, disputeFlagChain)
+		if len(blockHashes) == 1 {
+			// call disputeBlock in the testimonium client library
+			result, err := testimoniumClient.DisputeBlock(blockHashes[0], disputeFlagChain)
+			if err != nil {
+				log.Fatal(err)
+			}
+			printResult(txResult{TxResult: result})
+			return
+		}
+
+		fmt.Fprintf(progressOutput(), "Generating witnesses for %d blocks ...\n", len(blockHashes))
+		witnesses, err := testimoniumClient.GenerateDisputeWitnesses(blockHashes, disputeFlagChain)
+		if err != nil {
+			log.Fatal("Failed to generate witnesses: " + err.Error())
+		}
+
+		var results txResults
+		for _, witness := range witnesses {
+			fmt.Fprintf(progressOutput(), "Disputing block %s ...\n", witness.BlockHash.String())
+			result, err := testimoniumClient.DisputeBlockWithWitness(witness, disputeFlagChain)
+			if err != nil {
+				log.Fatal(err)
+			}
+			results = append(results, txResult{TxResult: result})
+		}
+		printResult(results)
 	},
 }
 
@@ -43,4 +114,106 @@ func init() {
 	// is called directly, e.g.:
 	// disputeCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	disputeCmd.Flags().Uint8VarP(&disputeFlagChain, "chain", "c", 1, "the disputed chain ID")
+	disputeCmd.Flags().BoolVar(&disputeFlagDryRun, "dry-run", false, "only predict the outcome of the dispute, no transaction is sent")
+	disputeCmd.Flags().IntVar(&disputeFlagWorkers, "workers", runtime.NumCPU(), "number of witnesses generated concurrently")
+	disputeCmd.Flags().Uint64Var(&disputeFlagDagMemory, "dag-memory", 4096, "memory in MB used to share DAGs between concurrently disputed blocks")
+	disputeCmd.Flags().Uint64Var(&disputeFlagDagDisk, "dag-disk", 0, "disk space in MB of the DAG files kept in the Ethash directory (0: no limit)")
+	disputeCmd.Flags().BoolVar(&disputeFlagPublic, "public", false, "send the disputes to the public mempool even if a private relay is configured")
+	disputeCmd.Flags().StringVar(&disputeFlagWitnessCache, "witness-cache", "", "directory caching the generated witnesses")
+	disputeCmd.Flags().StringVar(&disputeFlagWitnessCacheUrl, "witness-cache-url", "", "URL of a witness server shared with other relayers (see 'ethash witness-server')")
+	disputeCmd.Flags().StringVar(&disputeFlagArchive, "archive", "", "directory archiving the evidence of the disputes (default: <datadir>/disputes)")
+	disputeCmd.Flags().BoolVar(&disputeFlagNoArchive, "no-archive", false, "do not archive the evidence of the disputes")
+}
+
+// disputeClientOptions returns the options generating the witnesses, archiving the evidence and sending the disputes
+// through the private relay configured for the chain
+func disputeClientOptions() []testimonium.ClientOption {
+	if disputeFlagDryRun {
+		return nil
+	}
+	options := []testimonium.ClientOption{testimonium.WithWitnessProvider(disputeWitnessProvider())}
+	if !disputeFlagNoArchive {
+		archive := disputeFlagArchive
+		if archive == "" {
+			archive = filepath.Join(dataDir, "disputes")
+		}
+		options = append(options, testimonium.WithDisputeArchive(archive))
+	}
+	if disputeFlagPublic {
+		return options
+	}
+
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatal("Can't read config file: ", err)
+	}
+	relayConfig := viper.GetStringMap(fmt.Sprintf("chains.%d.privaterelay", disputeFlagChain))
+	if len(relayConfig) == 0 {
+		return options
+	}
+
+	relay, err := testimonium.NewPrivateRelayFromConfig(relayConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return append(options, testimonium.WithPrivateRelay(disputeFlagChain, relay))
+}
+
+// disputeWitnessProvider returns the provider computing the witnesses, behind the witness server and the local cache
+// if configured
+func disputeWitnessProvider() ethash.WitnessProvider {
+	var provider ethash.WitnessProvider = ethash.NewComputedWitnessProvider(ethash.SharedDAGCache(), disputeFlagWorkers)
+	if disputeFlagWitnessCacheUrl != "" {
+		provider = ethash.NewRemoteWitnessCache(disputeFlagWitnessCacheUrl, provider, nil)
+	}
+	if disputeFlagWitnessCache != "" {
+		cache, err := ethash.NewDiskWitnessCache(disputeFlagWitnessCache, provider)
+		if err != nil {
+			log.Fatal(err)
+		}
+		provider = cache
+	}
+	return provider
+}
+
+type disputePredictionResult struct {
+	testimonium.DisputePrediction
+	MixDigestErr string `json:"MixDigestErr,omitempty"` // the error itself is not serializable
+	Succeeds     bool
+}
+
+func newDisputePredictionResult(prediction testimonium.DisputePrediction) disputePredictionResult {
+	result := disputePredictionResult{DisputePrediction: prediction, Succeeds: prediction.Succeeds()}
+	if prediction.MixDigestErr != nil {
+		result.MixDigestErr = prediction.MixDigestErr.Error()
+	}
+	return result
+}
+
+type disputePredictionResults []disputePredictionResult
+
+func (results disputePredictionResults) renderText(w io.Writer) {
+	for _, result := range results {
+		printDisputePrediction(w, result.DisputePrediction)
+	}
+}
+
+func printDisputePrediction(w io.Writer, prediction testimonium.DisputePrediction) {
+	fmt.Fprintf(w, "Block: %s (No. %d)\n", prediction.BlockHash.String(), prediction.BlockNumber)
+	if prediction.PoW.Target != nil {
+		fmt.Fprintf(w, "PoW result: %x\n", prediction.PoW.Result)
+		fmt.Fprintf(w, "PoW target: %x\n", prediction.PoW.Target)
+	}
+	if prediction.MixDigestErr != nil {
+		fmt.Fprintf(w, "Note: %s (not checked by the contract)\n", prediction.MixDigestErr)
+	}
+	fmt.Fprintf(w, "Predicted PoWValidationResult: { returnCode: %d, errorInfo: %s }\n", prediction.ReturnCode, prediction.ErrorInfo.String())
+
+	switch prediction.ReturnCode {
+	case testimonium.POW_VALID:
+		fmt.Fprintln(w, "The dispute will FAIL, the proof-of-work of the block is valid")
+	case testimonium.POW_EPOCH_DATA_NOT_SET:
+		fmt.Fprintf(w, "The epoch data of epoch %s is not set, the contract cannot validate the proof-of-work (use 'submit epoch' first)\n", prediction.ErrorInfo.String())
+	default:
+		fmt.Fprintln(w, "The dispute will SUCCEED, the proof-of-work of the block is invalid")
+	}
 }
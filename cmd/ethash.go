@@ -0,0 +1,41 @@
+// This file contains logic executed if the command "ethash" is typed in.
+
+package cmd
+
+import (
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ethashUtilCmd represents the ethash command (not to be confused with the command "deploy ethash")
+var ethashUtilCmd = &cobra.Command{
+	Use:   "ethash",
+	Short: "Local Ethash utilities",
+	Long:  `Utilities working with the local Ethash implementation, no transactions are sent to any chain`,
+}
+
+// configureDAGCache sets the limits of the DAG cache shared by all DAG computations of the command (witnesses of
+// disputes, epoch data) in MB. The flags --dag-memory and --dag-disk of the command take precedence over the entries
+// "memory" and "disk" of the "dag" entry of the config file, which take precedence over the defaults of the flags.
+// Without flags and entries, no DAGs are held in memory and the DAG files on disk are not limited.
+func configureDAGCache(cmd *cobra.Command) {
+	viper.ReadInConfig()
+
+	limit := func(name string, key string) uint64 {
+		flag := cmd.Flags().Lookup(name)
+		if flag != nil && flag.Changed || !viper.IsSet(key) {
+			if flag == nil {
+				return 0
+			}
+			value, _ := cmd.Flags().GetUint64(name)
+			return value
+		}
+		return viper.GetUint64(key)
+	}
+	ethash.SharedDAGCache().SetLimits(limit("dag-memory", "dag.memory")*1024*1024, limit("dag-disk", "dag.disk")*1024*1024)
+}
+
+func init() {
+	rootCmd.AddCommand(ethashUtilCmd)
+}
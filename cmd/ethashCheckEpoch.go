@@ -0,0 +1,125 @@
+// This file contains logic executed if the command "ethash check-epoch" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+// number of mismatches printed per epoch, all of them are contained in the JSON output
+const maxPrintedMismatches = 10
+
+var checkEpochFlagChain uint8
+var checkEpochFlagFrom uint64
+
+// ethashCheckEpochCmd represents the command 'ethash check-epoch'
+var ethashCheckEpochCmd = &cobra.Command{
+	Use:   "check-epoch [epoch]...",
+	Short: "Checks the epoch data stored in the Ethash contract",
+	Long: `Compares the epoch data of the specified epochs stored in the Ethash contract of the verifying chain (--chain)
+with the epoch data computed locally, node by node. Corrupted or maliciously set epoch data makes disputes of
+illegitimate headers fail, as the contract rejects the witnesses of the correct DAG.
+
+The contract offers no getter for the epoch data, so the stored values are read from the calldata of the setEpochData
+transactions, which are searched from block --from on (default: the deployment block of the Ethash contract in the
+registry). The DAG of each epoch is generated if necessary (see the 'dag' entry of the config file). No transactions
+are sent.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		epochs := make([]uint64, len(args))
+		for i, arg := range args {
+			epoch, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				log.Fatalf("Illegal epoch number '%s'", arg)
+			}
+			epochs[i] = epoch
+		}
+		if failed := failedSelfTestChecks(ethash.SelfTest()); len(failed) > 0 {
+			log.Fatalf("Ethash self test failed (%s), run 'ethash selftest' for details", strings.Join(failed, ", "))
+		}
+
+		fromBlock := checkEpochFlagFrom
+		if !cmd.Flags().Changed("from") {
+			registry, err := testimonium.OpenChainRegistry(dataDir, checkEpochFlagChain)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if deployment, exists := registry.Deployment(testimonium.REGISTRY_ETHASH); exists {
+				fromBlock = deployment.BlockNumber
+			}
+		}
+
+		configureDAGCache(cmd)
+		testimoniumClient = createTestimoniumClient()
+
+		var results epochDataCheckResults
+		for _, epoch := range epochs {
+			check, err := testimoniumClient.CheckEpochData(ethash.GenerateEpochData(epoch), checkEpochFlagChain, fromBlock)
+			if err != nil {
+				log.Fatalf("Failed to check epoch %d: %s", epoch, err)
+			}
+			results = append(results, epochDataCheckResult{EpochDataCheck: check, Valid: check.Valid()})
+		}
+		printResult(results)
+
+		for _, result := range results {
+			if !result.Valid {
+				log.Fatal("Epoch data is INVALID")
+			}
+		}
+	},
+}
+
+type epochDataCheckResult struct {
+	*testimonium.EpochDataCheck
+	Valid bool `json:"valid"`
+}
+
+type epochDataCheckResults []epochDataCheckResult
+
+func (results epochDataCheckResults) renderText(w io.Writer) {
+	for _, result := range results {
+		fmt.Fprintf(w, "Epoch %d: %s\n", result.Epoch, result.EpochDataCheck)
+		for _, tx := range result.Transactions {
+			status := "ok"
+			if tx.Error != 0 {
+				status = fmt.Sprintf("rejected with error %d", tx.Error)
+			}
+			fmt.Fprintf(w, "  Tx %s (block %d, sender %s): nodes %d to %d, %s\n", tx.TxHash.Hex(), tx.BlockNumber,
+				tx.Sender.Hex(), tx.Start, tx.Start+tx.NumElems-1, status)
+		}
+		for _, missing := range result.Missing {
+			fmt.Fprintf(w, "  Missing nodes %d to %d\n", missing.From, missing.To)
+		}
+		for i, mismatch := range result.Mismatches {
+			if i == maxPrintedMismatches {
+				fmt.Fprintf(w, "  ... %d more wrong values (see --output json)\n", len(result.Mismatches)-i)
+				break
+			}
+			field := mismatch.Field
+			if field == "merkleNodes" {
+				field = fmt.Sprintf("node %d", mismatch.Index)
+			}
+			fmt.Fprintf(w, "  Wrong %s: stored %s, expected %s (tx %s)\n", field, mismatch.Stored, mismatch.Expected,
+				mismatch.TxHash.Hex())
+		}
+		for _, txHash := range result.Undecodable {
+			fmt.Fprintf(w, "  Undecodable tx %s\n", txHash.Hex())
+		}
+	}
+}
+
+func init() {
+	ethashUtilCmd.AddCommand(ethashCheckEpochCmd)
+
+	ethashCheckEpochCmd.Flags().Uint8VarP(&checkEpochFlagChain, "chain", "c", 1, "verifying chain")
+	ethashCheckEpochCmd.Flags().Uint64Var(&checkEpochFlagFrom, "from", 0, "block the setEpochData transactions are searched from")
+}
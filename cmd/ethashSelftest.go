@@ -0,0 +1,76 @@
+// This file contains logic executed if the command "ethash selftest" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+	"github.com/spf13/cobra"
+)
+
+var ethashFlagEpochs []uint
+
+// ethashSelftestCmd represents the command 'ethash selftest'
+var ethashSelftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Checks the local Ethash implementation against known-good data",
+	Long: `Checks the local Ethash implementation against known-good data before any epoch data or proof is submitted.
+Caches, datasets, hashimoto results and Merkle trees of small test vectors are compared with golden values, as are
+the seed hash, sizes and branch depth of several epochs. The checks take less than a second.
+
+With --epoch, the DAG of the epoch is generated (if necessary) and the epoch data "submit epoch" would send is
+compared with a Merkle tree computed independently from the DAG file. This takes as long as generating the epoch data.
+The DAGs of epochs 0 and 110 are also checked against the seals of mainnet blocks 1 and 3311058, and the Merkle roots
+of epochs 0, 110 and 269 against the known roots.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		configureDAGCache(cmd)
+		checks := ethash.SelfTest()
+		for _, epoch := range ethashFlagEpochs {
+			checks = append(checks, ethash.SelfTestEpoch(uint64(epoch))...)
+		}
+
+		printResult(selftestResult(checks))
+
+		if failed := failedSelfTestChecks(checks); len(failed) > 0 {
+			log.Fatal("Ethash self test FAILED: " + strings.Join(failed, ", "))
+		}
+	},
+}
+
+type selftestResult []ethash.SelfTestCheck
+
+func (result selftestResult) renderText(w io.Writer) {
+	for _, check := range result {
+		status := "ok"
+		if !check.Passed {
+			status = "FAILED"
+		}
+		if check.Detail != "" {
+			fmt.Fprintf(w, "%-6s %s (%s)\n", status, check.Name, check.Detail)
+		} else {
+			fmt.Fprintf(w, "%-6s %s\n", status, check.Name)
+		}
+	}
+}
+
+// failedSelfTestChecks returns the names of the failed checks
+func failedSelfTestChecks(checks []ethash.SelfTestCheck) []string {
+	var failed []string
+	for _, check := range checks {
+		if !check.Passed {
+			failed = append(failed, check.Name)
+		}
+	}
+	return failed
+}
+
+func init() {
+	ethashUtilCmd.AddCommand(ethashSelftestCmd)
+
+	ethashSelftestCmd.Flags().UintSliceVar(&ethashFlagEpochs, "epoch", nil, "additionally check the full epoch data of the epochs (generates their DAGs)")
+}
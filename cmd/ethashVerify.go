@@ -0,0 +1,68 @@
+// This file contains logic executed if the command "ethash verify" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var ethashFlagBlock uint64
+var ethashFlagChain uint8
+
+// ethashVerifyCmd represents the command 'ethash verify'
+var ethashVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verifies the proof-of-work of a block locally",
+	Long: `Verifies the proof-of-work of the specified block of the target chain with the local Ethash implementation.
+The verification cache of the block's epoch is generated on first use and reused by subsequent verifications.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient()
+
+		verification, err := testimoniumClient.VerifyPoWOfBlock(new(big.Int).SetUint64(ethashFlagBlock), ethashFlagChain)
+		if verification.Target == nil {
+			// the verification could not be performed at all
+			log.Fatal("Failed to verify proof-of-work: " + err.Error())
+		}
+
+		printResult(powVerificationResult{PoWVerification: verification, Valid: err == nil})
+
+		if err != nil {
+			log.Fatal("Proof-of-work is INVALID: " + err.Error())
+		}
+	},
+}
+
+type powVerificationResult struct {
+	testimonium.PoWVerification
+	Valid bool
+}
+
+func (result powVerificationResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Block: %d (epoch %d)\n", result.BlockNumber, result.Epoch)
+	fmt.Fprintf(w, "Hash without nonce: %s\n", result.HashWithoutNonce.String())
+	fmt.Fprintf(w, "Nonce: %d\n", result.Nonce)
+	fmt.Fprintf(w, "MixDigest (header): %s\n", result.MixDigest.String())
+	fmt.Fprintf(w, "MixDigest (computed): %s\n", result.ComputedMixDigest.String())
+	fmt.Fprintf(w, "Result: %x\n", result.Result)
+	fmt.Fprintf(w, "Target: %x\n", result.Target)
+
+	if result.Valid {
+		fmt.Fprintln(w, "Proof-of-work is valid")
+	}
+}
+
+func init() {
+	ethashUtilCmd.AddCommand(ethashVerifyCmd)
+
+	ethashVerifyCmd.Flags().Uint64Var(&ethashFlagBlock, "block", 0, "block number")
+	ethashVerifyCmd.Flags().Uint8VarP(&ethashFlagChain, "chain", "c", 0, "target chain")
+	markChainRole(ethashVerifyCmd.Flags(), "chain", testimonium.ROLE_SOURCE)
+	ethashVerifyCmd.MarkFlagRequired("block")
+}
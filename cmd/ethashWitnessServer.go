@@ -0,0 +1,43 @@
+// This file contains logic executed if the command "ethash witness-server" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+	"github.com/spf13/cobra"
+)
+
+var ethashWitnessServerFlagDir string
+var ethashWitnessServerFlagAddr string
+
+// ethashWitnessServerCmd represents the command 'ethash witness-server'
+var ethashWitnessServerCmd = &cobra.Command{
+	Use:   "witness-server",
+	Short: "Shares dispute witnesses between relayer instances",
+	Long: `Serves the witnesses (DAG elements and proofs) stored in a directory over HTTP, so several relayer instances
+computing disputes share them instead of each generating the DAGs.
+
+The relayers use the server with 'dispute --witness-cache-url http://<addr>': witnesses are fetched with GET and the
+ones computed by a relayer are uploaded with PUT. Uploaded witnesses are not verified, so the server should only be
+reachable by trusted relayers.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cache, err := ethash.NewDiskWitnessCache(ethashWitnessServerFlagDir, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(progressOutput(), "Serving witnesses of %s on %s\n", ethashWitnessServerFlagDir, ethashWitnessServerFlagAddr)
+		log.Fatal(http.ListenAndServe(ethashWitnessServerFlagAddr, ethash.NewWitnessCacheHandler(cache)))
+	},
+}
+
+func init() {
+	ethashUtilCmd.AddCommand(ethashWitnessServerCmd)
+
+	ethashWitnessServerCmd.Flags().StringVar(&ethashWitnessServerFlagDir, "dir", "witnesses", "directory the witnesses are stored in")
+	ethashWitnessServerCmd.Flags().StringVar(&ethashWitnessServerFlagAddr, "addr", "localhost:8081", "address the server listens on")
+}
@@ -0,0 +1,22 @@
+// This file contains logic executed if the command "events" is typed in.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var eventsFlagChain uint8
+
+// eventsCmd represents the events command
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Retrieves events emitted by the ETH Relay contract",
+	Long:  `Retrieves events emitted by the ETH Relay contract on the verifying chain`,
+}
+
+func init() {
+	rootCmd.AddCommand(eventsCmd)
+
+	eventsCmd.PersistentFlags().Uint8VarP(&eventsFlagChain, "chain", "c", 1, "verifying chain")
+}
@@ -0,0 +1,128 @@
+// This file contains logic executed if the command "events export" is typed in.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var eventsFlagFromBlock uint64
+var eventsFlagToBlock uint64
+var eventsFlagOut string
+var eventsFlagTimeout time.Duration
+
+// eventsExportCmd represents the command 'events export'
+var eventsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports all events of the ETH Relay contract to a CSV or JSON file",
+	Long: `Exports all events (SubmitBlock, RemoveBranch, PoWValidationResult, Verify*, WithdrawStake, ...) emitted by the
+ETH Relay contract within the specified block range to a file. The format is determined by the extension of the
+output file (.csv or .json).
+
+The export can be stopped with Ctrl-C or after --timeout. The events of the blocks scanned so far are written to the
+file and the block range the export can be resumed with is printed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format := strings.ToLower(strings.TrimPrefix(filepath.Ext(eventsFlagOut), "."))
+		if format != "csv" && format != "json" {
+			log.Fatalf("Unsupported output format '%s', use a .csv or .json file", format)
+		}
+
+		var toBlock *big.Int
+		if eventsFlagToBlock != 0 {
+			toBlock = new(big.Int).SetUint64(eventsFlagToBlock)
+		}
+
+		testimoniumClient = createTestimoniumClient()
+		ctx, cancel := interruptibleContext(eventsFlagTimeout)
+		defer cancel()
+		events, err := testimoniumClient.EventsContext(ctx, eventsFlagChain, eventsFlagFromBlock, toBlock)
+		var interrupted *testimonium.ScanInterruptedError
+		if err != nil && !errors.As(err, &interrupted) {
+			log.Fatal("Failed to retrieve events: " + err.Error())
+		}
+
+		f, err := os.Create(eventsFlagOut)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+
+		if format == "json" {
+			err = writeEventsAsJson(f, events)
+		} else {
+			err = writeEventsAsCsv(f, events)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if interrupted != nil {
+			// the events of the scanned blocks are kept, the export of the remaining blocks can be resumed
+			printResult(txResult{Message: fmt.Sprintf("Wrote %d events to %s, resume the export with --from-block %d --to-block %d",
+				len(events), eventsFlagOut, interrupted.Cursor.NextBlock, interrupted.Cursor.LastBlock)})
+			log.Fatal("Export interrupted: " + interrupted.Err.Error())
+		}
+		printResult(txResult{Message: fmt.Sprintf("Wrote %d events to %s", len(events), eventsFlagOut)})
+	},
+}
+
+func init() {
+	eventsCmd.AddCommand(eventsExportCmd)
+
+	eventsExportCmd.Flags().Uint64Var(&eventsFlagFromBlock, "from-block", 0, "first block of the exported range")
+	eventsExportCmd.Flags().Uint64Var(&eventsFlagToBlock, "to-block", 0, "last block of the exported range (default: latest block)")
+	eventsExportCmd.Flags().StringVarP(&eventsFlagOut, "out", "o", "events.csv", "output file (.csv or .json)")
+	eventsExportCmd.Flags().DurationVar(&eventsFlagTimeout, "timeout", 0, "stop the export after this time and write the events found so far (0: no timeout)")
+}
+
+func writeEventsAsJson(f *os.File, events []testimonium.RelayEvent) error {
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(events)
+}
+
+func writeEventsAsCsv(f *os.File, events []testimonium.RelayEvent) error {
+	w := csv.NewWriter(f)
+	err := w.Write([]string{"blockNumber", "blockHash", "txHash", "logIndex", "event", "fields"})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		fields := make([]string, 0, len(event.Fields))
+		for k, v := range event.Fields {
+			fields = append(fields, k+"="+v)
+		}
+		sort.Strings(fields)
+
+		err = w.Write([]string{
+			strconv.FormatUint(event.BlockNumber, 10),
+			event.BlockHash.String(),
+			event.TxHash.String(),
+			strconv.FormatUint(uint64(event.LogIndex), 10),
+			event.Name,
+			strings.Join(fields, ";"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
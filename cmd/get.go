@@ -4,6 +4,7 @@
 package cmd
 
 import (
+	"github.com/pantos-io/go-ethrelay/testimonium"
 	"github.com/spf13/cobra"
 )
 
@@ -25,6 +26,7 @@ func init() {
 	// and all subcommands, e.g.:
 	// getCmd.PersistentFlags().String("foo", "", "A help for foo")
 	getCmd.PersistentFlags().Uint8VarP(&getFlagChain, "chain", "c", 0, "chain")
+	markChainRole(getCmd.PersistentFlags(), "chain", testimonium.ROLE_SOURCE)
 
 
 	// Cobra supports local flags which will only run when this command
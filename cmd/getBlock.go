@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/spf13/cobra"
@@ -32,7 +33,7 @@ var getBlockCmd = &cobra.Command{
 				log.Fatal("Failed to retrieve header: " + err.Error())
 			}
 
-			printHeader(header)
+			printResult(headerResult{header})
 		} else {
 			// else the full header will be printed
 
@@ -41,11 +42,7 @@ var getBlockCmd = &cobra.Command{
 				log.Fatal("Failed to retrieve block: " + err.Error())
 			}
 
-			printBlock(block)
-
-			if detailFlag {
-				printTransactions(block)
-			}
+			printResult(newBlockResult(block, detailFlag))
 		}
 	},
 }
@@ -65,25 +62,54 @@ func init() {
 	getBlockCmd.Flags().BoolVarP(&detailFlag, "detail", "d", false, "Show transaction details of block")
 }
 
-func printHeader(header *types.Header) {
-	fmt.Printf("Hash: %s\n", header.Hash().String())
-	fmt.Printf("Number: %s\n", header.Number.String())
-	fmt.Printf("Nonce: %d\n", header.Nonce.Uint64())
-	fmt.Printf("StateRoot: %s\n", header.Root.String())
-	fmt.Printf("TxHash: %s\n", header.TxHash.String())
-	fmt.Printf("ReceiptHash: %s\n", header.ReceiptHash.String())
+// headerResult is the result of 'get block --header', the header is rendered with its JSON fields in JSON output.
+type headerResult struct {
+	*types.Header
+}
+
+func (result headerResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Hash: %s\n", result.Hash().String())
+	fmt.Fprintf(w, "Number: %s\n", result.Number.String())
+	fmt.Fprintf(w, "Nonce: %d\n", result.Nonce.Uint64())
+	fmt.Fprintf(w, "StateRoot: %s\n", result.Root.String())
+	fmt.Fprintf(w, "TxHash: %s\n", result.TxHash.String())
+	fmt.Fprintf(w, "ReceiptHash: %s\n", result.ReceiptHash.String())
 }
 
-func printBlock(block *types.Block) {
-	fmt.Printf("Hash: %s\n", block.Hash().String())
-	fmt.Printf("Number: %d\n", block.Number())
-	fmt.Printf("Nonce: %d\n", block.Nonce())
-	fmt.Printf("Transaction Count: %d\n", len(block.Transactions()))
+type blockResult struct {
+	Hash             common.Hash   `json:"hash"`
+	Number           uint64        `json:"number"`
+	Nonce            uint64        `json:"nonce"`
+	TransactionCount int           `json:"transactionCount"`
+	Transactions     []common.Hash `json:"transactions,omitempty"` // only set with --detail
 }
 
-func printTransactions(block *types.Block) {
-	fmt.Printf("Transactions:\n")
-	for index, tx := range(block.Transactions()) {
-		fmt.Printf("%d: %s\n", index, tx.Hash().String())
+func newBlockResult(block *types.Block, withTransactions bool) blockResult {
+	result := blockResult{
+		Hash:             block.Hash(),
+		Number:           block.NumberU64(),
+		Nonce:            block.Nonce(),
+		TransactionCount: len(block.Transactions()),
+	}
+	if withTransactions {
+		result.Transactions = make([]common.Hash, 0, len(block.Transactions()))
+		for _, tx := range block.Transactions() {
+			result.Transactions = append(result.Transactions, tx.Hash())
+		}
+	}
+	return result
+}
+
+func (result blockResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Hash: %s\n", result.Hash.String())
+	fmt.Fprintf(w, "Number: %d\n", result.Number)
+	fmt.Fprintf(w, "Nonce: %d\n", result.Nonce)
+	fmt.Fprintf(w, "Transaction Count: %d\n", result.TransactionCount)
+
+	if result.Transactions != nil {
+		fmt.Fprintf(w, "Transactions:\n")
+		for index, txHash := range result.Transactions {
+			fmt.Fprintf(w, "%d: %s\n", index, txHash.String())
+		}
 	}
 }
@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"strconv"
 
@@ -28,10 +29,18 @@ var getLongestChainEndpointCmd = &cobra.Command{
 			log.Fatal("Failed to retrieve longest chain blockHash from chain " + strconv.Itoa(int(testimoniumContractChain)) + ":" + err.Error())
 		}
 
-		fmt.Printf("LongestChainEndpointBlockHash: %s\n", common.BytesToHash(blockHash[:]).String())
+		printResult(longestChainEndpointResult{BlockHash: common.BytesToHash(blockHash[:])})
 	},
 }
 
+type longestChainEndpointResult struct {
+	BlockHash common.Hash `json:"blockHash"`
+}
+
+func (result longestChainEndpointResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "LongestChainEndpointBlockHash: %s\n", result.BlockHash.String())
+}
+
 func init() {
 	getCmd.AddCommand(getLongestChainEndpointCmd)
 
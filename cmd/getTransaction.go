@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/spf13/cobra"
@@ -30,7 +31,7 @@ var getTransactionCmd = &cobra.Command{
 			if err != nil {
 				log.Fatal("Failed to retrieve transaction receipt: " + err.Error())
 			}
-			printTransactionReceipt(txReceipt)
+			printResult(receiptResult{txReceipt})
 			return
 		}
 
@@ -38,7 +39,7 @@ var getTransactionCmd = &cobra.Command{
 		if err != nil {
 			log.Fatal("Failed to retrieve transaction: " + err.Error())
 		}
-		printTransaction(tx)
+		printResult(transactionResult{tx})
 	},
 }
 
@@ -57,22 +58,31 @@ func init() {
 	getTransactionCmd.Flags().BoolVarP(&receiptFlag, "receipt", "r", false, "Get the receipt of the transaction")
 }
 
-func printTransaction(tx *types.Transaction) {
-	fmt.Printf("Hash: %s\n", tx.Hash().String())
-	fmt.Printf("To: %s\n", tx.To().String())
-	fmt.Printf("Nonce: %d\n", tx.Nonce())
-	fmt.Printf("Value: %d\n", tx.Value())
-	fmt.Printf("GasPrice: %d\n", tx.GasPrice())
-	fmt.Printf("Gas: %d\n", tx.Gas())
+// transactionResult is the result of 'get transaction', the transaction is rendered with its JSON fields in JSON output.
+type transactionResult struct {
+	*types.Transaction
 }
 
-func printTransactionReceipt(receipt *types.Receipt) {
-	fmt.Printf("TxHash: %s\n", receipt.TxHash.String())
-	fmt.Printf("BlockHash: %s\n", receipt.BlockHash.String())
-	fmt.Printf("Status: %d\n", receipt.Status)
-	fmt.Printf("BlockNumber: %d\n", receipt.BlockNumber)
-	fmt.Printf("GasUsed: %d\n", receipt.GasUsed)
-	fmt.Printf("CumulativeGasUsed: %d\n", receipt.CumulativeGasUsed)
-	fmt.Printf("TransactionIndex: %d\n", receipt.TransactionIndex)
-	fmt.Printf("ContractAddress: %s\n", receipt.ContractAddress.String())
+func (result transactionResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Hash: %s\n", result.Hash().String())
+	fmt.Fprintf(w, "To: %s\n", result.To().String())
+	fmt.Fprintf(w, "Nonce: %d\n", result.Nonce())
+	fmt.Fprintf(w, "Value: %d\n", result.Value())
+	fmt.Fprintf(w, "GasPrice: %d\n", result.GasPrice())
+	fmt.Fprintf(w, "Gas: %d\n", result.Gas())
+}
+
+type receiptResult struct {
+	*types.Receipt
+}
+
+func (result receiptResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "TxHash: %s\n", result.TxHash.String())
+	fmt.Fprintf(w, "BlockHash: %s\n", result.BlockHash.String())
+	fmt.Fprintf(w, "Status: %d\n", result.Status)
+	fmt.Fprintf(w, "BlockNumber: %d\n", result.BlockNumber)
+	fmt.Fprintf(w, "GasUsed: %d\n", result.GasUsed)
+	fmt.Fprintf(w, "CumulativeGasUsed: %d\n", result.CumulativeGasUsed)
+	fmt.Fprintf(w, "TransactionIndex: %d\n", result.TransactionIndex)
+	fmt.Fprintf(w, "ContractAddress: %s\n", result.ContractAddress.String())
 }
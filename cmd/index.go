@@ -0,0 +1,23 @@
+// This file contains logic executed if the command "index" is typed in.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var indexFlagChain uint8
+
+// indexCmd represents the index command
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manages the local index of submitted block headers",
+	Long: `Manages the local index of the block headers submitted to the ETH Relay contract. The index is built from the
+SubmitBlock events and used to look up submitted headers (e.g., for disputes) without scanning all events again.`,
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+
+	indexCmd.PersistentFlags().Uint8VarP(&indexFlagChain, "chain", "c", 1, "verifying chain")
+}
@@ -0,0 +1,51 @@
+// This file contains logic executed if the command "index lookup" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+// indexLookupCmd represents the command 'index lookup'
+var indexLookupCmd = &cobra.Command{
+	Use:   "lookup [blockHash]",
+	Short: "Prints the submission of a block header from the local index",
+	Long:  `Prints the submit transaction, the submitter and the RLP encoded header of the block with the specified hash`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient()
+
+		index, err := testimoniumClient.EventIndex(dataDir, indexFlagChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		record, exists := index.Lookup(common.HexToHash(args[0]))
+		if !exists {
+			log.Fatalf("Block %s is not in the index (scanned up to block %d), run 'index update' first", args[0], index.LastScannedBlock)
+		}
+
+		printResult(submitRecordResult{record})
+	},
+}
+
+type submitRecordResult struct {
+	*testimonium.SubmitRecord
+}
+
+func (result submitRecordResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Block: %s\n", result.BlockHash.String())
+	fmt.Fprintf(w, "Submit Tx: %s (block %d)\n", result.TxHash.String(), result.SubmitBlockNumber)
+	fmt.Fprintf(w, "Submitter: %s\n", result.Submitter.Hex())
+	fmt.Fprintf(w, "RLP Header: %s\n", result.RlpHeader.String())
+}
+
+func init() {
+	indexCmd.AddCommand(indexLookupCmd)
+}
@@ -0,0 +1,91 @@
+// This file contains logic executed if the command "index update" is typed in.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var indexUpdateFlagTimeout time.Duration
+
+// indexUpdateCmd represents the command 'index update'
+var indexUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Builds or updates the local index of submitted block headers",
+	Long: `Scans the SubmitBlock events emitted since the last update and adds the submitted headers to the local index.
+An interrupted update continues where it stopped.
+
+The update can be stopped with Ctrl-C or after --timeout. The index is saved up to the last completely scanned batch
+of blocks, the result contains the block the next update continues at.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient()
+
+		ctx, cancel := interruptibleContext(indexUpdateFlagTimeout)
+		defer cancel()
+		index, added, err := testimoniumClient.UpdateEventIndexContext(ctx, dataDir, indexFlagChain)
+		var interrupted *testimonium.ScanInterruptedError
+		if err != nil && !errors.As(err, &interrupted) {
+			if index != nil {
+				fmt.Fprintf(progressOutput(), "Indexed %d new headers up to block %d before the update failed\n", added, index.LastScannedBlock)
+			}
+			log.Fatal("Failed to update index: " + err.Error())
+		}
+
+		result := indexUpdateResult{
+			Added:            added,
+			Total:            len(index.Records),
+			LastScannedBlock: index.LastScannedBlock,
+			Submissions:      make(map[string]int),
+		}
+		if interrupted != nil {
+			result.Resume = &interrupted.Cursor
+		}
+		for _, record := range index.Records {
+			result.Submissions[record.Submitter.Hex()]++
+		}
+		printResult(result)
+		if interrupted != nil {
+			log.Fatal("Update interrupted: " + interrupted.Err.Error())
+		}
+	},
+}
+
+type indexUpdateResult struct {
+	Added            int            `json:"added"`
+	Total            int            `json:"total"`
+	LastScannedBlock uint64         `json:"lastScannedBlock"`
+	Submissions      map[string]int `json:"submissions"` // number of headers per submitter
+	// set if the update was interrupted, the next update continues at its block
+	Resume *testimonium.ScanCursor `json:"resume,omitempty"`
+}
+
+func (result indexUpdateResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Indexed %d new headers, %d headers in total (scanned up to block %d)\n", result.Added, result.Total, result.LastScannedBlock)
+	if result.Resume != nil {
+		fmt.Fprintf(w, "The update was interrupted, the next update continues at block %d of %d\n", result.Resume.NextBlock, result.Resume.LastBlock)
+	}
+
+	submitters := make([]string, 0, len(result.Submissions))
+	for submitter := range result.Submissions {
+		submitters = append(submitters, submitter)
+	}
+	sort.Strings(submitters)
+	for _, submitter := range submitters {
+		fmt.Fprintf(w, "%s: %d headers\n", submitter, result.Submissions[submitter])
+	}
+}
+
+func init() {
+	indexCmd.AddCommand(indexUpdateCmd)
+
+	indexUpdateCmd.Flags().DurationVar(&indexUpdateFlagTimeout, "timeout", 0, "stop the update after this time (0: no timeout)")
+}
@@ -17,86 +17,353 @@ package cmd
 import (
 	"bufio"
 	"fmt"
-	"io/ioutil"
+	"log"
 	"os"
-
-	"github.com/pantos-io/go-ethrelay/testimonium"
-	"github.com/spf13/viper"
-
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pantos-io/go-ethrelay/testimonium"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
+var initFlagTestnet string
+
 // initCmd represents the init command
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initializes the ETH Relay client",
 	Long: `This command initializes the ETH Relay client. 
-This command sets up the testimonium.yml file in the current directory.
+This command sets up the testimonium.yml file in the current directory (or the file specified with --config).
 The file contains connection configurations for the different blockchains, e.g.,
 private key, url, port, etc.
 
-The default testimonium.yml file looks like this:
+The command is a wizard asking for
+  - a testnet profile (` + strings.Join(testimonium.TestnetProfileNames(), ", ") + `) or custom chains,
+  - the URLs of the target chain (0) and the verifying chain (1),
+  - the private key of the account (0x...) or the path of a keystore file, whose password is read from
+    $` + testimonium.KEYSTORE_PASSWORD_ENV + ` or asked for by the other commands (the key is not written to the config),
+  - whether to deploy the Ethash and ETH Relay contracts if the verifying chain has no known deployment.
+Defaults are shown in brackets and taken by entering nothing. Before the config is written, the connections to
+both chains are checked (see 'status'), a config failing the checks is only written if confirmed.
+
+Without a testnet profile, the defaults look like this:
 
     chains:
         0:
-			type: wss
-            url: mainnet.infura.io/ws/v3/1e835672adba4b9b930a12a3ec58ebad
+            role: source
+            url: wss://mainnet.infura.io/ws/v3/1e835672adba4b9b930a12a3ec58ebad
         1:
-			port: 7545
-            type: http
-            url: localhost
-	privateKey: <YOUR PRIVATE KEY>
+            role: destination
+            url: http://localhost:7545
+    privateKey: <YOUR PRIVATE KEY>
 
 Websocket-Connection is required for submitting blocks in live mode.
 Chain ID 0 contains connection configuration for the target chain, which defaults to the main Ethereum chain (via Infura).
-Chain ID 1 contains connection configuration for the verifying chain, which defaults to a local chain (e.g., run via Ganache).`,
+Chain ID 1 contains connection configuration for the verifying chain, which defaults to a local chain (e.g., run via Ganache).
+
+With --testnet, the chains are configured with the public endpoints of the testnet profile without asking for it,
+including known contract deployments.`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Setting up testimonium.yml...")
-		reader := bufio.NewReader(os.Stdin)
-		fmt.Print("Enter the private key of your account (the account will be used on all chains, input this in the format starting with '0x...'): ")
-		privateKey, _ := reader.ReadString('\n')
+		wizard := initWizard{reader: bufio.NewReader(os.Stdin)}
 
-		if !strings.HasPrefix(privateKey, "0x") {
-			fmt.Println("Entered private key is not starting with '0x'.")
-			return
+		path := cfgFile
+		if path == "" {
+			path = "./testimonium.yml"
+		}
+		if _, err := os.Stat(path); err == nil {
+			if !wizard.confirm(fmt.Sprintf("File %s already exists. Overwrite?", path), false) {
+				return
+			}
+		}
+
+		profileName := initFlagTestnet
+		if profileName == "" {
+			profileName = wizard.ask(fmt.Sprintf("Testnet profile (%s), empty for custom chains", strings.Join(testimonium.TestnetProfileNames(), ", ")), "")
+		}
+		var profile *testimonium.TestnetProfile
+		if profileName != "" {
+			testnetProfile, err := testimonium.TestnetProfileByName(profileName)
+			if err != nil {
+				log.Fatal(err)
+			}
+			profile = &testnetProfile
 		}
 
-		viper.Set("privateKey", privateKey[:len(privateKey)-1])
+		chainsConfig := wizard.askChains(profile)
 
-		chainsConfig := make(map[uint8]interface{})
+		privateKey, keystorePath := wizard.askAccount()
+		if keystorePath != "" {
+			viper.Set("keystore", keystorePath)
+		} else {
+			viper.Set("privateKey", privateKey)
+		}
 
-		mainnetConfig := testimonium.CreateChainConfig("wss", "mainnet.infura.io/ws/v3/1e835672adba4b9b930a12a3ec58ebad", 0)
-		chainsConfig[0] = mainnetConfig
+		healthy := wizard.checkChains(privateKey, chainsConfig)
+		if !healthy && !wizard.confirm("Write the config anyway?", false) {
+			return
+		}
 
-		ganacheConfig := testimonium.CreateChainConfig("http", "localhost", 7545)
-		chainsConfig[1] = ganacheConfig
+		// contracts are only offered to be deployed if both chains can be used
+		if _, deployed := chainsConfig["1"].(map[string]interface{})["ethrelayaddress"]; healthy && !deployed {
+			genesis := uint64(1)
+			if profile != nil && profile.SuggestedGenesis != 0 {
+				genesis = profile.SuggestedGenesis
+			}
+			if wizard.confirm("No ETH Relay contract is configured for the verifying chain (1). Deploy the Ethash and ETH Relay contracts now (requires funds)?", false) {
+				genesis = wizard.askUint("Genesis block (number of the target chain's block the relay starts at)", genesis)
+				wizard.deploy(privateKey, chainsConfig, genesis)
+			}
+		}
 
 		viper.Set("chains", chainsConfig)
+		if err := viper.WriteConfigAs(path); err != nil {
+			log.Fatalf("Unable to write %s: %s", path, err)
+		}
+		fmt.Printf("Created %s.\n", path)
+
+		if profile != nil {
+			printTestnetHints(*profile)
+		}
+	},
+}
+
+// initWizard asks the questions of the init command on stdin
+type initWizard struct {
+	reader *bufio.Reader
+}
+
+// ask prints the question and returns the answer, or the default value if nothing is entered
+func (w initWizard) ask(question string, defaultValue string) string {
+	if defaultValue != "" {
+		fmt.Printf("%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+	answer, err := w.reader.ReadString('\n')
+	if err != nil && answer == "" {
+		log.Fatal("No answer: ", err)
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return defaultValue
+	}
+	return answer
+}
+
+func (w initWizard) confirm(question string, defaultValue bool) bool {
+	options := "y/N"
+	if defaultValue {
+		options = "Y/n"
+	}
+	for {
+		switch strings.ToLower(w.ask(fmt.Sprintf("%s (%s)", question, options), "")) {
+		case "":
+			return defaultValue
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+	}
+}
+
+func (w initWizard) askUint(question string, defaultValue uint64) uint64 {
+	for {
+		answer := w.ask(question, strconv.FormatUint(defaultValue, 10))
+		value, err := strconv.ParseUint(answer, 10, 64)
+		if err == nil {
+			return value
+		}
+		fmt.Printf("'%s' is not a number.\n", answer)
+	}
+}
+
+// askChains asks for the URLs of the target and the verifying chain, the chains of the testnet profile are kept if
+// their URLs are not changed
+func (w initWizard) askChains(profile *testimonium.TestnetProfile) map[string]interface{} {
+	defaults := map[uint8]interface{}{
+		0: testimonium.CreateChainConfig("", "wss://mainnet.infura.io/ws/v3/1e835672adba4b9b930a12a3ec58ebad", 0),
+		1: testimonium.CreateChainConfig("", "http://localhost:7545", 0),
+	}
+	defaults[0].(map[string]interface{})["role"] = testimonium.ROLE_SOURCE.String()
+	defaults[1].(map[string]interface{})["role"] = testimonium.ROLE_DESTINATION.String()
+	if profile != nil {
+		defaults = profile.ChainsConfig()
+	}
+
+	chainsConfig := make(map[string]interface{})
+	for _, chain := range []struct {
+		id   uint8
+		name string
+	}{{0, "target chain (0)"}, {1, "verifying chain (1)"}} {
+		defaultConfig := defaults[chain.id].(map[string]interface{})
+		defaultUrl := fmt.Sprint(defaultConfig["url"])
+		if connectionType, ok := defaultConfig["type"].(string); ok {
+			defaultUrl = connectionType + "://" + defaultUrl
+		}
 
-		err := viper.SafeWriteConfig()
+		for {
+			url := w.ask("URL of the "+chain.name+" (http, https, ws or wss)", defaultUrl)
+			if url == defaultUrl {
+				chainsConfig[strconv.Itoa(int(chain.id))] = defaultConfig
+				break
+			}
+			if !strings.Contains(url, "://") {
+				fmt.Println("The URL has to contain the scheme, e.g., wss://...")
+				continue
+			}
+			chainConfig := testimonium.CreateChainConfig("", url, 0)
+			chainConfig["role"] = defaultConfig["role"]
+			chainsConfig[strconv.Itoa(int(chain.id))] = chainConfig
+			break
+		}
+	}
+	return chainsConfig
+}
+
+// askAccount asks for the private key or the keystore file of the account. The private key is returned in both cases,
+// the path of the keystore file only if one was entered.
+func (w initWizard) askAccount() (string, string) {
+	for {
+		answer := w.ask("Private key of your account (0x..., used on all chains) or path of a keystore file", "")
+		if strings.HasPrefix(answer, "0x") {
+			if _, err := crypto.HexToECDSA(answer[2:]); err != nil {
+				fmt.Printf("Invalid private key: %s\n", err)
+				continue
+			}
+			return answer, ""
+		}
+		if answer == "" {
+			fmt.Println("An account is required to send transactions.")
+			continue
+		}
+
+		keystorePath, err := filepath.Abs(answer)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := os.Stat(keystorePath); err != nil {
+			fmt.Printf("Neither a private key starting with '0x' nor a keystore file: %s\n", err)
+			continue
+		}
+		password, err := readPassword(w.reader, "Password of the keystore: ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		privateKey, err := testimonium.PrivateKeyFromKeystore(keystorePath, password)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Printf("The password has to be entered when the client is used or set in $%s.\n", testimonium.KEYSTORE_PASSWORD_ENV)
+		return privateKey, keystorePath
+	}
+}
+
+// checkChains connects to the chains and prints the result of probing them. It reports whether all chains are healthy.
+func (w initWizard) checkChains(privateKey string, chainsConfig map[string]interface{}) bool {
+	fmt.Println("Checking the connections...")
+	client, err := testimonium.NewClient(privateKey, chainsConfig, testimonium.WithProgressOutput(progressOutput()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Account: %s\n", client.Account())
+	report := client.Connect(0, 1)
+
+	healthy := true
+	for _, chain := range []uint8{0, 1} {
+		probe, err := client.Probe(chain)
 		if err != nil {
-			if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-				err := ioutil.WriteFile("./testimonium.yml", []byte(""), 0644)
-				if err != nil {
-					fmt.Printf("Unable to write file: %v", err)
-					return
-				}
-			} else {
-				// File already exists
-				fmt.Print("File testimonium.yml already exists. Overwrite? (n/Y):")
-				response, _ := reader.ReadString('\n')
-				if response == "Y\n" {
-					fmt.Println("Overwriting...")
-				} else {
-					return
-				}
+			fmt.Printf("  chain %d: not connected\n", chain)
+			for _, problem := range report.ChainProblems(chain) {
+				fmt.Printf("    %s\n", problem)
 			}
-			_ = viper.WriteConfig()
+			healthy = false
+			continue
 		}
-		fmt.Println("Created testimonium.yml.")
-	},
+		if !probe.Healthy() {
+			healthy = false
+		}
+		fmt.Printf("  chain %d: block %d, latency %s", chain, probe.CurrentBlock, probe.Latency.Round(time.Millisecond))
+		if probe.Balance != nil {
+			fmt.Printf(", balance %s wei", probe.Balance)
+		}
+		if probe.Syncing {
+			fmt.Printf(", syncing (highest block %d)", probe.HighestBlock)
+		}
+		if !probe.Subscriptions {
+			fmt.Print(", no subscriptions (the live mode polls new heads)")
+		}
+		fmt.Println()
+		fmt.Printf("    capabilities: %s\n", probe.Capabilities)
+		for _, problem := range probe.Errors {
+			fmt.Printf("    %s\n", problem)
+		}
+		for _, problem := range report.ChainProblems(chain) {
+			fmt.Printf("    %s\n", problem)
+		}
+	}
+	return healthy
+}
+
+// deploy deploys the Ethash and the ETH Relay contract to the verifying chain and adds their addresses to its config
+func (w initWizard) deploy(privateKey string, chainsConfig map[string]interface{}, genesis uint64) {
+	verifyingConfig := chainsConfig["1"].(map[string]interface{})
+	opts := []testimonium.ClientOption{testimonium.WithRegistry(dataDir), testimonium.WithProgressOutput(progressOutput())}
+
+	if _, deployed := verifyingConfig["ethashaddress"]; !deployed {
+		fmt.Println("Deploying the Ethash contract...")
+		client, err := testimonium.NewClient(privateKey, chainsConfig, opts...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		address, err := client.DeployEthash(1)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Ethash contract deployed at %s\n", address.Hex())
+		verifyingConfig["ethashaddress"] = address.Hex()
+	}
+
+	// the ETH Relay contract is deployed with a client bound to the new Ethash contract
+	fmt.Println("Deploying the ETH Relay contract...")
+	client, err := testimonium.NewClient(privateKey, chainsConfig, opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	address, err := client.DeployTestimonium(1, 0, genesis)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("ETH Relay contract deployed at %s\n", address.Hex())
+	verifyingConfig["ethrelayaddress"] = address.Hex()
+	fmt.Printf("Submit the epoch data of the genesis block to the Ethash contract before relaying blocks, e.g., 'submit epoch %d'.\n", genesis/30000)
+}
+
+// printTestnetHints prints how to get started with the chains of the testnet profile
+func printTestnetHints(profile testimonium.TestnetProfile) {
+	fmt.Printf("\nTestnet profile %s: %s\n", profile.Name, profile.Description)
+	fmt.Printf("Source chain (0): %s\n", profile.Source.Name)
+	fmt.Printf("Verifying chain (1): %s\n", profile.Verifying.Name)
+
+	if len(profile.Verifying.Faucets) > 0 {
+		fmt.Printf("\nFund your account on %s with one of the faucets:\n", profile.Verifying.Name)
+		for _, faucet := range profile.Verifying.Faucets {
+			fmt.Printf("  %s\n", faucet)
+		}
+	}
+
+	if profile.Verifying.EthashAddress == "" || profile.Verifying.EthrelayAddress == "" {
+		fmt.Printf("\nThere are no known public deployments on %s, deploy the contracts with 'deploy ethash' and 'deploy ethrelay'.\n", profile.Verifying.Name)
+	}
+	for _, hint := range profile.Hints {
+		fmt.Printf("\n%s\n", hint)
+	}
 }
 
 func init() {
@@ -111,4 +378,5 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// initCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	initCmd.Flags().StringVar(&initFlagTestnet, "testnet", "", "configures the chains of a testnet profile without asking for it ("+strings.Join(testimonium.TestnetProfileNames(), ", ")+")")
 }
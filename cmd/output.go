@@ -0,0 +1,100 @@
+// This file contains the rendering of command results. Commands build a result value and pass it to printResult,
+// which renders it in the format selected with --output, so new formats only have to be added here.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+)
+
+const (
+	OUTPUT_TEXT  = "text"
+	OUTPUT_JSON  = "json"
+	OUTPUT_QUIET = "quiet"
+)
+
+var outputFormat string
+
+// textRenderer is implemented by results that define their text representation, all other results are printed with
+// their default format (e.g., their String method).
+type textRenderer interface {
+	renderText(w io.Writer)
+}
+
+// printResult renders the result of a command to stdout.
+func printResult(result interface{}) {
+	switch outputFormat {
+	case OUTPUT_QUIET:
+		return
+	case OUTPUT_JSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		if renderer, ok := result.(textRenderer); ok {
+			renderer.renderText(os.Stdout)
+			return
+		}
+		fmt.Println(result)
+	}
+}
+
+// progressOutput returns the writer for progress messages of the client. If the result is rendered as JSON, stdout
+// only contains the result.
+func progressOutput() io.Writer {
+	switch outputFormat {
+	case OUTPUT_JSON:
+		return os.Stderr
+	case OUTPUT_QUIET:
+		return ioutil.Discard
+	default:
+		return os.Stdout
+	}
+}
+
+func validateOutputFormat() error {
+	switch outputFormat {
+	case OUTPUT_TEXT, OUTPUT_JSON, OUTPUT_QUIET:
+		return nil
+	default:
+		return fmt.Errorf("unknown output format '%s' (use %s, %s or %s)", outputFormat, OUTPUT_TEXT, OUTPUT_JSON, OUTPUT_QUIET)
+	}
+}
+
+// txResult is the result of a command sending a single transaction.
+type txResult struct {
+	Message string `json:"message,omitempty"`
+	*testimonium.TxResult
+}
+
+func (result txResult) renderText(w io.Writer) {
+	if result.TxResult != nil {
+		for _, event := range result.Events {
+			fmt.Fprintf(w, "Tx successful: %s\n", event)
+		}
+		if result.Verification != nil {
+			fmt.Fprintf(w, "Tx successful: %s\n", result.Verification.String())
+		}
+	}
+	if result.Message != "" {
+		fmt.Fprintln(w, result.Message)
+	}
+}
+
+// txResults is the result of a command sending several transactions.
+type txResults []txResult
+
+func (results txResults) renderText(w io.Writer) {
+	for _, result := range results {
+		result.renderText(w)
+	}
+}
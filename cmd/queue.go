@@ -0,0 +1,58 @@
+// This file contains logic executed if the command "queue" is typed in.
+
+package cmd
+
+import (
+	"log"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var queueFlagUrl string
+var queueFlagSrcChain uint8
+var queueFlagDestChain uint8
+
+// queueCmd represents the queue command
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Shares submissions and verifications between relay workers through a job queue",
+	Long: `Pushes submissions and verifications to a job queue and processes them with workers ('queue worker'). Several
+workers on different hosts share the jobs of a queue backed by Redis or NATS JetStream, and the jobs of a crashed worker
+are handed to the others.
+
+The queue is specified by --queue or by the "queue" entry of the config file:
+
+    redis://[:password@]host:6379[/db][?key=ethrelay:jobs]        (rediss:// for TLS)
+    nats://[user:password@]host:4222[?stream=ETHRELAY_JOBS&subject=ethrelay.jobs&consumer=ethrelay-workers]  (tls:// for TLS)
+
+Jobs are delivered at least once, i.e., a job whose worker crashed before it acknowledged the job is processed again.`,
+}
+
+// openQueue opens the queue of --queue or of the config file
+func openQueue(worker string) testimonium.JobQueue {
+	queueUrl := queueFlagUrl
+	if queueUrl == "" {
+		if err := viper.ReadInConfig(); err == nil {
+			queueUrl = viper.GetString("queue")
+		}
+	}
+	if queueUrl == "" {
+		log.Fatal("No queue configured, set --queue")
+	}
+	queue, err := testimonium.OpenJobQueue(queueUrl, worker)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, inMemory := queue.(*testimonium.MemoryJobQueue); inMemory {
+		log.Fatal("An in-memory queue is not shared between commands, use Redis or NATS")
+	}
+	return queue
+}
+
+func init() {
+	rootCmd.AddCommand(queueCmd)
+
+	queueCmd.PersistentFlags().StringVar(&queueFlagUrl, "queue", "", "URL of the job queue (default: \"queue\" entry of the config file)")
+}
@@ -0,0 +1,76 @@
+// This file contains logic executed if the command "queue submit" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var queueSubmitFlagMaxHeaders int
+
+// queueSubmitCmd represents the command 'queue submit'
+var queueSubmitCmd = &cobra.Command{
+	Use:   "submit [blockNumber...]",
+	Short: "Pushes submissions of blocks of the target chain to the job queue",
+	Long: `Pushes a job for every block number to the job queue. The worker processing the job submits the header of the block
+of the target chain to the verifying chain, including missing ancestors (at most --max-headers). Blocks that are already
+stored count as submitted.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var jobs []testimonium.QueuedJob
+		for _, arg := range args {
+			blockNumber, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				log.Fatalf("Illegal block number '%s'", arg)
+			}
+			jobs = append(jobs, testimonium.QueuedJob{
+				Kind:             testimonium.JOB_SUBMIT,
+				SourceChain:      queueFlagSrcChain,
+				DestinationChain: queueFlagDestChain,
+				BlockNumber:      blockNumber,
+				MaxHeaders:       queueSubmitFlagMaxHeaders,
+			})
+		}
+		pushJobs(jobs)
+	},
+}
+
+// pushJobs pushes the jobs to the queue and prints their ids
+func pushJobs(jobs []testimonium.QueuedJob) {
+	queue := openQueue("")
+	defer queue.Close()
+
+	var result queuePushResult
+	for _, job := range jobs {
+		id, err := queue.Push(job)
+		if err != nil {
+			log.Fatal(err)
+		}
+		result.Jobs = append(result.Jobs, id)
+	}
+	printResult(result)
+}
+
+type queuePushResult struct {
+	Jobs []string `json:"jobs"`
+}
+
+func (result queuePushResult) renderText(w io.Writer) {
+	for _, id := range result.Jobs {
+		fmt.Fprintf(w, "Queued job %s\n", id)
+	}
+}
+
+func init() {
+	queueCmd.AddCommand(queueSubmitCmd)
+
+	queueSubmitCmd.Flags().Uint8Var(&queueFlagSrcChain, "src", 0, "target chain")
+	queueSubmitCmd.Flags().Uint8Var(&queueFlagDestChain, "dest", 1, "verifying chain")
+	queueSubmitCmd.Flags().IntVar(&queueSubmitFlagMaxHeaders, "max-headers", 256, "maximum number of missing ancestors submitted")
+}
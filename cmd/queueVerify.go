@@ -0,0 +1,69 @@
+// This file contains logic executed if the command "queue verify" is typed in.
+
+package cmd
+
+import (
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var queueVerifyFlagType string
+var queueVerifyFlagConfirmations uint8
+var queueVerifyFlagBackfill bool
+var queueVerifyFlagMaxHeaders int
+var queueVerifyFlagWait time.Duration
+var queueVerifyFlagCallback string
+
+// queueVerifyCmd represents the command 'queue verify'
+var queueVerifyCmd = &cobra.Command{
+	Use:   "verify [txHash...]",
+	Short: "Pushes verifications of transactions or receipts of the target chain to the job queue",
+	Long: `Pushes a job for every transaction hash to the job queue. The worker processing the job verifies the transaction or
+receipt (--type) like 'verify transaction': it waits until the block and its confirmation blocks are relayed (at most
+--wait) or submits them itself with --backfill. With --callback, the result is posted to the URL when the job ends
+(see 'verify transaction').`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		valueType, err := testimonium.ParseTrieValueType(queueVerifyFlagType)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var jobs []testimonium.QueuedJob
+		for _, arg := range args {
+			if len(common.FromHex(arg)) != common.HashLength {
+				log.Fatalf("Illegal transaction hash '%s'", arg)
+			}
+			jobs = append(jobs, testimonium.QueuedJob{
+				Kind:             testimonium.JOB_VERIFY,
+				SourceChain:      queueFlagSrcChain,
+				DestinationChain: queueFlagDestChain,
+				TxHash:           common.HexToHash(arg),
+				ValueType:        valueType,
+				Confirmations:    queueVerifyFlagConfirmations,
+				Backfill:         queueVerifyFlagBackfill,
+				MaxHeaders:       queueVerifyFlagMaxHeaders,
+				Wait:             queueVerifyFlagWait,
+				Callback:         queueVerifyFlagCallback,
+			})
+		}
+		pushJobs(jobs)
+	},
+}
+
+func init() {
+	queueCmd.AddCommand(queueVerifyCmd)
+
+	queueVerifyCmd.Flags().Uint8Var(&queueFlagSrcChain, "src", 0, "target chain")
+	queueVerifyCmd.Flags().Uint8Var(&queueFlagDestChain, "dest", 1, "verifying chain")
+	queueVerifyCmd.Flags().StringVar(&queueVerifyFlagType, "type", "transaction", "verified value (transaction or receipt)")
+	queueVerifyCmd.Flags().Uint8VarP(&queueVerifyFlagConfirmations, "confirmations", "c", 4, "Number of block confirmations")
+	queueVerifyCmd.Flags().BoolVar(&queueVerifyFlagBackfill, "backfill", false, "submit the missing headers of the block and its confirmation blocks before the verification")
+	queueVerifyCmd.Flags().IntVar(&queueVerifyFlagMaxHeaders, "max-headers", 256, "maximum number of headers submitted with --backfill")
+	queueVerifyCmd.Flags().DurationVar(&queueVerifyFlagWait, "wait", 30*time.Minute, "maximum time to wait until the block and its confirmation blocks are relayed (without --backfill)")
+	queueVerifyCmd.Flags().StringVar(&queueVerifyFlagCallback, "callback", "", "URL the result of the verification is posted to once the job ends")
+}
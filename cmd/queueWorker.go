@@ -0,0 +1,85 @@
+// This file contains logic executed if the command "queue worker" is typed in.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var queueWorkerFlagId string
+var queueWorkerFlagCallbackSecret string
+
+// queueWorkerCmd represents the command 'queue worker'
+var queueWorkerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Processes the jobs of the job queue",
+	Long: `Processes the submissions and verifications of the job queue one after the other until it is interrupted. Failing
+jobs are retried up to 3 times before they are moved to the failed jobs ("<key>:failed" in Redis, "<subject>.failed"
+in NATS).
+
+Give every worker its own account, workers sharing an account send conflicting nonces. With
+Redis, a worker that is restarted with the same --worker id immediately returns the jobs it leased before, otherwise
+they are returned once its lease expired (1m).`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		worker := queueWorkerFlagId
+		if worker == "" {
+			hostname, _ := os.Hostname()
+			worker = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+		}
+		secret := queueWorkerFlagCallbackSecret
+		if secret == "" {
+			secret = os.Getenv("ETHRELAY_CALLBACK_SECRET")
+		}
+
+		queue := openQueue(worker)
+		defer queue.Close()
+
+		ctx, cancel := interruptibleContext(0)
+		defer cancel()
+
+		testimoniumClient = createTestimoniumClient()
+		connectChains()
+		err := testimoniumClient.ProcessJobs(ctx, queue, []byte(secret), func(job testimonium.QueuedJob, err error) {
+			result := queueJobResult{QueuedJob: job}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			printResult(result)
+		})
+		if err != nil && err != context.Canceled {
+			log.Fatal(err)
+		}
+	},
+}
+
+type queueJobResult struct {
+	testimonium.QueuedJob
+	Error string `json:"error,omitempty"`
+}
+
+func (result queueJobResult) renderText(w io.Writer) {
+	subject := fmt.Sprintf("block %d", result.BlockNumber)
+	if result.Kind == testimonium.JOB_VERIFY {
+		subject = fmt.Sprintf("%s %s", result.ValueType, result.TxHash.Hex())
+	}
+	if result.Error != "" {
+		fmt.Fprintf(w, "Job %s (%s %s) failed: %s\n", result.Id, result.Kind, subject, result.Error)
+		return
+	}
+	fmt.Fprintf(w, "Job %s (%s %s) done\n", result.Id, result.Kind, subject)
+}
+
+func init() {
+	queueCmd.AddCommand(queueWorkerCmd)
+
+	queueWorkerCmd.Flags().StringVar(&queueWorkerFlagId, "worker", "", "id of the worker in the queue (default: <hostname>-<pid>)")
+	queueWorkerCmd.Flags().StringVar(&queueWorkerFlagCallbackSecret, "callback-secret", "", "key the callback payloads are signed with (default $ETHRELAY_CALLBACK_SECRET)")
+}
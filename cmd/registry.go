@@ -0,0 +1,88 @@
+// This file contains logic executed if the command "registry" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var registryFlagChain uint8
+
+// registryCmd represents the registry command
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Shows the contracts deployed on the specified blockchain",
+	Long: `Shows the contracts recorded in the registry of the specified blockchain: their addresses, deployment transactions,
+versions and the metadata they were tagged with, and the genesis block of the ETH Relay contract. The registry is kept
+in the data directory and updated by the deploy commands.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		registry, err := testimonium.OpenChainRegistry(dataDir, registryFlagChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printResult(registryResult{registry})
+	},
+}
+
+type registryResult struct {
+	*testimonium.ChainRegistry
+}
+
+func (result registryResult) renderText(w io.Writer) {
+	if len(result.Contracts) == 0 {
+		fmt.Fprintf(w, "No contracts recorded for chain %d\n", result.Chain)
+		return
+	}
+
+	contracts := make([]string, 0, len(result.Contracts))
+	for contract := range result.Contracts {
+		contracts = append(contracts, contract)
+	}
+	sort.Strings(contracts)
+
+	for _, contract := range contracts {
+		deployment := result.Contracts[contract]
+		fmt.Fprintf(w, "%s: %s\n", contract, deployment.Address.Hex())
+		fmt.Fprintf(w, "  Tx: %s (block %d)\n", deployment.TxHash.Hex(), deployment.BlockNumber)
+		fmt.Fprintf(w, "  Deployer: %s\n", deployment.Deployer.Hex())
+		if deployment.Salt != nil {
+			fmt.Fprintf(w, "  Salt: %s\n", deployment.Salt.Hex())
+		}
+		fmt.Fprintf(w, "  Version: %s\n", deployment.Version.Hex())
+		if deployment.Version != testimonium.ContractVersion(contract) {
+			fmt.Fprintf(w, "  (deployed with other contract bindings than this client's)\n")
+		}
+		fmt.Fprintf(w, "  Deployed at: %s\n", deployment.DeployedAt.Format("2006-01-02 15:04:05 MST"))
+		if len(deployment.Metadata) > 0 {
+			keys := make([]string, 0, len(deployment.Metadata))
+			for key := range deployment.Metadata {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			fmt.Fprintf(w, "  Metadata:\n")
+			for _, key := range keys {
+				fmt.Fprintf(w, "    %s: %s\n", key, deployment.Metadata[key])
+			}
+		}
+		if deployment.RegistrationTxHash != nil {
+			fmt.Fprintf(w, "  Announced to the deployment registry: %s\n", deployment.RegistrationTxHash.Hex())
+		}
+	}
+	if result.Genesis != nil {
+		fmt.Fprintf(w, "Genesis: block %d (%s) of chain %d, total difficulty %s\n", result.Genesis.BlockNumber,
+			result.Genesis.BlockHash.Hex(), result.Genesis.SourceChain, result.Genesis.TotalDifficulty)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(registryCmd)
+
+	registryCmd.Flags().Uint8VarP(&registryFlagChain, "chain", "c", 1, "the blockchain whose registry is shown")
+}
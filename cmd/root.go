@@ -4,15 +4,28 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"github.com/pantos-io/go-ethrelay/testimonium"
+	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var cfgFile string
+var recordFile string
+var replayFile string
+var dataDir string
+var relayInstance string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -22,6 +35,22 @@ var rootCmd = &cobra.Command{
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	//	Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutputFormat(); err != nil {
+			return err
+		}
+		if err := applyChainContext(cmd); err != nil {
+			return err
+		}
+		if debugAddr != "" {
+			startDebugServer()
+		}
+		return startTracing(cmd, args)
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		stopAnalytics()
+		stopTracing()
+	},
 }
 
 var testimoniumClient *testimonium.Client
@@ -43,6 +72,11 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/testimonium.yml)")
+	rootCmd.PersistentFlags().StringVar(&recordFile, "record", "", "record all RPC requests and responses to the specified file")
+	rootCmd.PersistentFlags().StringVar(&replayFile, "replay", "", "replay RPC responses from the specified recording instead of connecting to the chains")
+	rootCmd.PersistentFlags().StringVar(&dataDir, "datadir", ".ethrelay", "directory for local data (e.g., event indexes)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", OUTPUT_TEXT, "output format of results: text, json or quiet")
+	rootCmd.PersistentFlags().StringVar(&relayInstance, "instance", "", "use the ETH Relay instance with this name of the chains' ethrelays entries instead of the default contract")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -67,14 +101,157 @@ func initConfig() {
 
 }
 
-func createTestimoniumClient() (*testimonium.Client) {
+func createTestimoniumClient(extraOpts ...testimonium.ClientOption) (*testimonium.Client) {
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err != nil {
-		fmt.Println("Can't read config file:", err)
+		fmt.Fprintln(progressOutput(), "Can't read config file:", err)
 	}
 
 	chainsConfig := viper.Get("chains").(map[string]interface{})
-	privateKey := viper.Get("privateKey").(string)
+	// the private key is only required to send transactions, read-only usage (e.g., of source chains) works without it
+	privateKey := configuredPrivateKey()
+
+	opts := []testimonium.ClientOption{testimonium.WithEventIndex(dataDir), testimonium.WithRegistry(dataDir), testimonium.WithTxLog(dataDir),
+		testimonium.WithBodyArchive(dataDir), testimonium.WithProgressOutput(progressOutput()),
+		testimonium.WithContext(interruptContext())}
+	if recordFile != "" {
+		opts = append(opts, testimonium.WithRPCRecording(recordFile))
+	}
+	if replayFile != "" {
+		opts = append(opts, testimonium.WithRPCReplay(replayFile))
+	}
+	if tracer != nil {
+		opts = append(opts, testimonium.WithTracer(tracer), testimonium.WithTraceContext(commandContext))
+	}
+	if relayInstance != "" {
+		opts = append(opts, testimonium.WithRelayInstance(relayInstance))
+	}
+	opts = append(opts, analyticsOptions()...)
+	opts = append(opts, operatorOptions()...)
+	opts = append(opts, extraOpts...)
+
+	client, err := testimonium.NewClient(privateKey, chainsConfig, opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return client
+}
+
+// connectChains connects the client to all configured chains upfront. Long-running commands use the chains
+// concurrently, the other commands dial the chains they use on first use.
+func connectChains() {
+	printClientReport(testimoniumClient.Connect())
+}
+
+// printClientReport prints the problems found while connecting to the chains grouped by chain
+func printClientReport(report testimonium.ClientReport) {
+	if len(report.Problems) == 0 {
+		return
+	}
+	w := progressOutput()
+	fmt.Fprintln(w, "Problems connecting to the chains:")
+	for i, problem := range report.Problems {
+		if i == 0 || report.Problems[i-1].Chain != problem.Chain {
+			status := "connected"
+			for _, chainProblem := range report.ChainProblems(problem.Chain) {
+				if chainProblem.Unusable {
+					status = "not connected"
+				}
+			}
+			fmt.Fprintf(w, "  chain %d (%s):\n", problem.Chain, status)
+		}
+		fmt.Fprintf(w, "    - %s\n", problem)
+	}
+}
+
 
-	return testimonium.NewClient(privateKey, chainsConfig)
+// configuredPrivateKey returns the private key of the config file or, if a keystore file is configured instead, the
+// decrypted key of the keystore. Its password is read from $ETHRELAY_KEYSTORE_PASSWORD or asked for.
+func configuredPrivateKey() string {
+	privateKey := viper.GetString("privateKey")
+	keystorePath := viper.GetString("keystore")
+	if privateKey != "" || keystorePath == "" {
+		return privateKey
+	}
+
+	password, exists := os.LookupEnv(testimonium.KEYSTORE_PASSWORD_ENV)
+	if !exists {
+		var err error
+		if password, err = readPassword(bufio.NewReader(os.Stdin), fmt.Sprintf("Password of keystore %s: ", keystorePath)); err != nil {
+			log.Fatal(err)
+		}
+	}
+	privateKey, err := testimonium.PrivateKeyFromKeystore(keystorePath, password)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return privateKey
+}
+
+// readPassword asks for a password without echoing it if stdin is a terminal, otherwise the password is read from the
+// reader of stdin
+func readPassword(reader *bufio.Reader, prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	password, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return string(password), err
+}
+
+// interrupted is canceled on the first Ctrl-C, see interruptContext
+var interrupted context.Context
+var interruptOnce sync.Once
+
+// interruptContext returns the context of the command, it is canceled on the first Ctrl-C (SIGINT), so waits for
+// receipts, scans and workers stop. A second Ctrl-C terminates the process.
+func interruptContext() context.Context {
+	interruptOnce.Do(func() {
+		var cancel context.CancelFunc
+		interrupted, cancel = context.WithCancel(context.Background())
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		go func() {
+			<-interrupt
+			signal.Stop(interrupt)
+			fmt.Fprintln(progressOutput(), "Interrupted, stopping...")
+			cancel()
+		}()
+	})
+	return interrupted
+}
+
+// interruptibleContext returns a context that is canceled on Ctrl-C (SIGINT) or after the timeout (if not 0), so long
+// scans can stop and report their partial results
+func interruptibleContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(interruptContext(), timeout)
+	}
+	return context.WithCancel(interruptContext())
+}
+
+// operatorOptions returns the keys of the operator roles configured in the "operators" section of the config file
+func operatorOptions() []testimonium.ClientOption {
+	var opts []testimonium.ClientOption
+	for name, entry := range viper.GetStringMap("operators") {
+		role, err := testimonium.ParseOperatorRole(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		operatorConfig, ok := entry.(map[string]interface{})
+		if !ok {
+			log.Fatalf("Illegal config of the %s", role)
+		}
+		config, err := testimonium.OperatorConfigFromConfig(operatorConfig)
+		if err != nil {
+			log.Fatalf("Illegal config of the %s: %s", role, err)
+		}
+		opts = append(opts, testimonium.WithOperator(role, config))
+	}
+	return opts
 }
@@ -6,8 +6,8 @@ package cmd
 import (
 	"fmt"
 	"github.com/spf13/cobra"
+	"io"
 	"log"
-	"math"
 	"math/big"
 )
 
@@ -25,14 +25,18 @@ var stakeCmd = &cobra.Command{
 			log.Fatal(err)
 		}
 
-		var stakeInEth = new(big.Float)
-		stakeInEth = stakeInEth.SetInt(stakeInWei)
-		stakeInEth = new(big.Float).Quo(stakeInEth, big.NewFloat(math.Pow10(18)))
-
-		fmt.Printf("Stake balance: %s ETH\n", stakeInEth.String())
+		printResult(stakeResult{Stake: stakeInWei})
 	},
 }
 
+type stakeResult struct {
+	Stake *big.Int `json:"stake"` // in wei
+}
+
+func (result stakeResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Stake balance: %s ETH\n", weiToEth(result.Stake))
+}
+
 func init() {
 	rootCmd.AddCommand(stakeCmd)
 
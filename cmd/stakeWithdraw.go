@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"github.com/spf13/cobra"
 	"log"
-	"math"
 	"math/big"
 )
 
@@ -25,16 +24,12 @@ var stakeWithdrawCmd = &cobra.Command{
 			log.Fatal("Can not parse amountInWei parameter")
 		}
 
-		err := testimoniumClient.WithdrawStake(stakeFlagChain, amountInWei)
+		result, err := testimoniumClient.WithdrawStake(stakeFlagChain, amountInWei)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		var stakeInEth = new(big.Float)
-		stakeInEth = stakeInEth.SetInt(amountInWei)
-		stakeInEth = new(big.Float).Quo(stakeInEth, big.NewFloat(math.Pow10(18)))
-
-		fmt.Printf("Successfully withdrew stake: %s ETH\n", stakeInEth.String())
+		printResult(txResult{Message: fmt.Sprintf("Successfully withdrew stake: %s ETH", weiToEth(amountInWei)), TxResult: result})
 	},
 }
 
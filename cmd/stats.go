@@ -0,0 +1,114 @@
+// This file contains logic executed if the command "stats" is typed in.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var statsFlagChains []uint
+var statsFlagAccount string
+var statsFlagDays int
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarizes the relay activity of the account per day",
+	Long: `Summarizes the relay activity of the account (--account, default: the current account) per chain and day (UTC):
+headers submitted, disputes won and lost, verifications served and the fees paid for them, gas spent and the average
+latency between the mining of a submitted block on the source chain and its submission.
+
+Disputes are won by successful own disputes and failed disputes of own blocks, and lost by failed own disputes and
+disputes removing own blocks. Verifications are served if they verify a value of a block submitted by the account.
+
+The statistics are computed from the event index (see 'index update'), which is updated first. By default, all
+chains with an ETH Relay contract are summarized.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient()
+
+		account := common.HexToAddress(testimoniumClient.Account())
+		if statsFlagAccount != "" {
+			if !common.IsHexAddress(statsFlagAccount) {
+				log.Fatalf("Illegal account '%s'", statsFlagAccount)
+			}
+			account = common.HexToAddress(statsFlagAccount)
+		}
+
+		chains := statsFlagChains
+		if len(chains) == 0 {
+			for _, chain := range testimoniumClient.DestinationChains() {
+				chains = append(chains, uint(chain))
+			}
+		}
+
+		var result statsResult
+		for _, chain := range chains {
+			stats, err := testimoniumClient.RelayStats(dataDir, uint8(chain), account)
+			if errors.Is(err, testimonium.ErrNoTestimoniumContract) && len(statsFlagChains) == 0 {
+				continue
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			if statsFlagDays > 0 && len(stats.Days) > statsFlagDays {
+				stats.Days = stats.Days[len(stats.Days)-statsFlagDays:]
+			}
+			result = append(result, stats)
+		}
+		printResult(result)
+	},
+}
+
+type statsResult []*testimonium.RelayStats
+
+func (result statsResult) renderText(w io.Writer) {
+	if len(result) == 0 {
+		fmt.Fprintln(w, "No chain with an ETH Relay contract")
+	}
+	for i, stats := range result {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Chain %d, account %s\n", stats.Chain, stats.Account.Hex())
+		fmt.Fprintf(w, "%-10s %8s %6s %6s %13s %14s %14s %8s\n", "Day", "Headers", "Won", "Lost", "Verifications", "Fees (ETH)", "Gas (ETH)", "Latency")
+		for _, day := range append(stats.Days, stats.Total) {
+			name := day.Day
+			if name == "" {
+				name = "Total"
+			}
+			latency := "-"
+			if day.AverageLatency > 0 {
+				latency = day.AverageLatency.String()
+			}
+			fmt.Fprintf(w, "%-10s %8d %6d %6d %13d %14s %14s %8s\n", name, day.HeadersSubmitted, day.DisputesWon,
+				day.DisputesLost, day.VerificationsServed, formatEther(day.FeesEarned), formatEther(day.GasSpent), latency)
+		}
+		for token, fees := range stats.Total.TokenFeesEarned {
+			fmt.Fprintf(w, "Fees paid in token %s: %s (smallest unit)\n", token.Hex(), fees)
+		}
+	}
+}
+
+// formatEther formats an amount of wei in ether with 6 decimals
+func formatEther(wei *big.Int) string {
+	ether := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(params.Ether))
+	return ether.Text('f', 6)
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().UintSliceVarP(&statsFlagChains, "chain", "c", nil, "chains to summarize (default: all chains with an ETH Relay contract)")
+	statsCmd.Flags().StringVar(&statsFlagAccount, "account", "", "account to summarize (default: the current account)")
+	statsCmd.Flags().IntVar(&statsFlagDays, "days", 0, "only show the most recent days with activity (the total covers all days)")
+}
@@ -0,0 +1,108 @@
+// This file contains logic executed if the command "stats simulate" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var statsSimulateFlagChain uint8
+var statsSimulateFlagIntervals []uint
+var statsSimulateFlagDays int
+var statsSimulateFlagDelay time.Duration
+var statsSimulateFlagGasPrice float64
+var statsSimulateFlagSubmitGas uint64
+
+// statsSimulateCmd represents the stats simulate command
+var statsSimulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Estimates the profitability of relaying every block or every N-th block",
+	Long: `Replays the submissions and verifications of the event index (see 'index update', which is updated first)
+with the live mode policies 'all' and 'every:N' (--intervals) and estimates for each policy the verification fees the
+account (--account, default: the current account) would have earned and the gas its submissions would have cost.
+
+The account submits a block if no other relayer submitted it before the account would have, i.e., within --delay
+after the mining of the next block the policy relays. The delay defaults to the median delay of the account's
+submissions. The gas price of a submission is the price paid by the indexed transactions at that time (or
+--gas-price), its gas the average of the indexed submissions (or --submit-gas). The fees of the verifications of a
+block are earned by its submitter; fees paid in an ERC-20 token are not included.
+
+The most profitable policy is recommended for 'submit block --live --policy'.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient()
+
+		account := common.HexToAddress(testimoniumClient.Account())
+		if statsFlagAccount != "" {
+			if !common.IsHexAddress(statsFlagAccount) {
+				log.Fatalf("Illegal account '%s'", statsFlagAccount)
+			}
+			account = common.HexToAddress(statsFlagAccount)
+		}
+
+		config := testimonium.EconomicsConfig{
+			Account:   account,
+			Window:    time.Duration(statsSimulateFlagDays) * 24 * time.Hour,
+			Delay:     statsSimulateFlagDelay,
+			SubmitGas: statsSimulateFlagSubmitGas,
+		}
+		for _, interval := range statsSimulateFlagIntervals {
+			config.Intervals = append(config.Intervals, uint64(interval))
+		}
+		if statsSimulateFlagGasPrice > 0 {
+			config.GasPrice, _ = new(big.Float).Mul(big.NewFloat(statsSimulateFlagGasPrice), big.NewFloat(params.GWei)).Int(nil)
+		}
+
+		simulation, err := testimoniumClient.SimulateEconomics(dataDir, statsSimulateFlagChain, config)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printResult(statsSimulateResult{simulation})
+	},
+}
+
+type statsSimulateResult struct {
+	*testimonium.EconomicsSimulation
+}
+
+func (result statsSimulateResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Chain %d, account %s\n", result.Chain, result.Account.Hex())
+	fmt.Fprintf(w, "Blocks %d to %d (%s to %s), %d verifications\n", result.FirstBlock, result.LastBlock,
+		result.From.UTC().Format("2006-01-02 15:04"), result.To.UTC().Format("2006-01-02 15:04"), result.Verifications)
+	fmt.Fprintf(w, "Submission delay: %s, gas per submission: %d\n", result.Delay, result.SubmitGas)
+	if result.TokenFees > 0 {
+		fmt.Fprintf(w, "Note: %d verifications paid in an ERC-20 token are not included\n", result.TokenFees)
+	}
+
+	fmt.Fprintf(w, "%-10s %8s %13s %14s %14s %14s %10s\n", "Policy", "Headers", "Verifications", "Fees (ETH)", "Gas (ETH)", "Profit (ETH)", "Latency")
+	for _, policy := range result.Policies {
+		fmt.Fprintf(w, "%-10s %8d %13d %14s %14s %14s %10s\n", policy.Policy, policy.HeadersSubmitted, policy.VerificationsServed,
+			formatEther(policy.FeesEarned), formatEther(policy.GasCost), formatEther(policy.Profit), policy.AverageLatency.Round(time.Second))
+	}
+	if result.Recommended == "" {
+		fmt.Fprintln(w, "No policy is profitable, relay blocks only when verifications need them (e.g., 'verify --backfill')")
+		return
+	}
+	fmt.Fprintf(w, "Recommended: submit block --live --policy %s\n", result.Recommended)
+}
+
+func init() {
+	statsCmd.AddCommand(statsSimulateCmd)
+
+	statsSimulateCmd.Flags().Uint8VarP(&statsSimulateFlagChain, "chain", "c", 1, "the verifying chain of the ETH Relay contract")
+	statsSimulateCmd.Flags().StringVar(&statsFlagAccount, "account", "", "account to simulate (default: the current account)")
+	statsSimulateCmd.Flags().UintSliceVar(&statsSimulateFlagIntervals, "intervals", []uint{1, 2, 4, 8, 16}, "relay every N-th block")
+	statsSimulateCmd.Flags().IntVar(&statsSimulateFlagDays, "days", 30, "simulated days before the latest indexed block (0: the whole index)")
+	statsSimulateCmd.Flags().DurationVar(&statsSimulateFlagDelay, "delay", 0, "time between the mining and the submission of a block by the account (default: measured)")
+	statsSimulateCmd.Flags().Float64Var(&statsSimulateFlagGasPrice, "gas-price", 0, "gas price of the submissions in gwei (default: historical)")
+	statsSimulateCmd.Flags().Uint64Var(&statsSimulateFlagSubmitGas, "submit-gas", 0, "gas of a submission (default: average of the indexed submissions)")
+}
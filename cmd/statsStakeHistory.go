@@ -0,0 +1,114 @@
+// This file contains logic executed if the command "stats stake-history" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var statsStakeHistoryFlagChain uint8
+var statsStakeHistoryFlagAt string
+
+// statsStakeHistoryCmd represents the stats stake-history command
+var statsStakeHistoryCmd = &cobra.Command{
+	Use:   "stake-history",
+	Short: "Shows the changes of the stake of the account over time",
+	Long: `Shows every change of the stake the account (--account, default: the current account) deposited in the ETH
+Relay contract with its cause (deposit, withdrawal, slashed, dispute) and the stake after it, e.g., for accounting or
+the post-mortem of a dispute. With --at, the stake at a past time (e.g., 2006-01-02 or 2006-01-02T15:04:05Z) or after
+a block of the verifying chain is printed as well.
+
+The withdrawals and disputes are taken from the event index (see 'index update'), which is updated first. The contract
+emits no event for deposits, so they are found by reading the stake at past blocks. This requires the state of these
+blocks: if the node of the chain has pruned it, the archive node of the chain config ("archive") is used.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		testimoniumClient = createTestimoniumClient()
+
+		account := common.HexToAddress(testimoniumClient.Account())
+		if statsFlagAccount != "" {
+			if !common.IsHexAddress(statsFlagAccount) {
+				log.Fatalf("Illegal account '%s'", statsFlagAccount)
+			}
+			account = common.HexToAddress(statsFlagAccount)
+		}
+
+		history, err := testimoniumClient.StakeHistory(dataDir, statsStakeHistoryFlagChain, account)
+		if err != nil {
+			log.Fatal(err)
+		}
+		result := statsStakeHistoryResult{StakeHistory: history, KindTotals: history.Totals()}
+		if statsStakeHistoryFlagAt != "" {
+			if blockNumber, err := strconv.ParseUint(statsStakeHistoryFlagAt, 10, 64); err == nil {
+				result.BalanceAt = history.BalanceAtBlock(blockNumber)
+			} else {
+				at, err := parseStakeHistoryTime(statsStakeHistoryFlagAt)
+				if err != nil {
+					log.Fatalf("Illegal time or block '%s'", statsStakeHistoryFlagAt)
+				}
+				result.BalanceAt = history.BalanceAt(at)
+			}
+			result.At = statsStakeHistoryFlagAt
+		}
+		printResult(result)
+	},
+}
+
+// parseStakeHistoryTime parses a time in RFC 3339 or a day (UTC)
+func parseStakeHistoryTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+type statsStakeHistoryResult struct {
+	*testimonium.StakeHistory
+	KindTotals map[testimonium.StakeChangeKind]*big.Int `json:"totals"`
+	At         string                                   `json:"at,omitempty"`
+	BalanceAt  *big.Int                                 `json:"balanceAt,omitempty"`
+}
+
+func (result statsStakeHistoryResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Chain %d, account %s (up to block %d)\n", result.Chain, result.Account.Hex(), result.LastBlock)
+	if len(result.Changes) == 0 {
+		fmt.Fprintln(w, "The stake of the account never changed")
+	} else {
+		fmt.Fprintf(w, "%10s %-16s %-10s %14s %14s\n", "Block", "Time (UTC)", "Kind", "Amount (ETH)", "Stake (ETH)")
+		for _, change := range result.Changes {
+			fmt.Fprintf(w, "%10d %-16s %-10s %14s %14s\n", change.BlockNumber, change.Time.Format("2006-01-02 15:04"),
+				change.Kind, formatEther(change.Amount), formatEther(change.Balance))
+		}
+
+		kinds := make([]string, 0, len(result.KindTotals))
+		for kind := range result.KindTotals {
+			kinds = append(kinds, string(kind))
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			fmt.Fprintf(w, "Total %s: %s ETH\n", kind, formatEther(result.KindTotals[testimonium.StakeChangeKind(kind)]))
+		}
+	}
+	fmt.Fprintf(w, "Stake: %s ETH\n", formatEther(result.Balance()))
+	if result.BalanceAt != nil {
+		fmt.Fprintf(w, "Stake at %s: %s ETH\n", result.At, formatEther(result.BalanceAt))
+	}
+}
+
+func init() {
+	statsCmd.AddCommand(statsStakeHistoryCmd)
+
+	statsStakeHistoryCmd.Flags().Uint8VarP(&statsStakeHistoryFlagChain, "chain", "c", 1, "the verifying chain of the ETH Relay contract")
+	statsStakeHistoryCmd.Flags().StringVar(&statsFlagAccount, "account", "", "account whose stake is shown (default: the current account)")
+	statsStakeHistoryCmd.Flags().StringVar(&statsStakeHistoryFlagAt, "at", "", "also print the stake at this time (e.g., 2006-01-02T15:04:05Z) or after this block")
+}
@@ -0,0 +1,192 @@
+// This file contains logic executed if the command "status" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var statusFlagChain uint8
+var statusFlagLocks bool
+var statusFlagLockPeriod time.Duration
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status [blockHash]...",
+	Short: "Shows the status of the ETH Relay contract on the specified chain",
+	Long: `Shows the balance and stake of the current account, the required stake per block, the verification fee
+and the longest chain endpoint of the ETH Relay contract on the specified chain (default: the destination chain selected
+with 'use'). For every specified block hash ('blockHash'), it is shown whether the header is stored in the contract.
+
+If a Multicall3 contract is deployed on the chain, all view calls are sent with a single request.
+The status also contains the health probe of the chain's node (latency, sync status, age of the latest block,
+reachability of the contracts and whether subscriptions are supported) and the capabilities of the node probed when
+the chain was connected (eth_getProof, eth_getBlockReceipts, the txpool API, subscriptions and the blocks whose state
+the node keeps).
+
+With --locks, the status contains the lock calendar of the account: the headers it submitted that can still be
+disputed, when their lock periods pass and how much stake is freed then. The calendar is derived from the event index
+(see 'index update'), which is updated first. The lock period is read from the contract or set with --lock-period
+(required for contracts that do not expose it, like the bundled one).
+
+If the chain config names further ETH Relay contracts in its ethrelays entry, they are listed; the global --instance
+flag shows the status of one of them instead of the default contract.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		blockHashes := make([]common.Hash, len(args))
+		for i, arg := range args {
+			blockHashes[i] = common.HexToHash(arg)
+		}
+
+		testimoniumClient = createTestimoniumClient()
+		status, err := testimoniumClient.Status(statusFlagChain, blockHashes...)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		probe, err := testimoniumClient.Probe(statusFlagChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fee, err := testimoniumClient.VerificationFee(statusFlagChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+		instances, err := testimoniumClient.RelayInstances(statusFlagChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+		instance, err := testimoniumClient.RelayInstance(statusFlagChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+		result := statusResult{Account: testimoniumClient.Account(), ChainStatus: status, Probe: probe, blockHashes: blockHashes, fee: fee,
+			Instance: instance, Instances: instances}
+		if fee.IsToken() {
+			result.FeeToken = &fee.Token
+		}
+		if context, err := currentChainContext(); err == nil && context.isSet() {
+			result.Context = &context
+		}
+		if statusFlagLocks {
+			result.Locks, err = testimoniumClient.LockCalendar(dataDir, statusFlagChain, statusFlagLockPeriod)
+			if err != nil {
+				log.Fatal("Failed to read the lock calendar: " + err.Error())
+			}
+		}
+
+		printResult(result)
+	},
+}
+
+type statusResult struct {
+	Account string `json:"account"`
+	testimonium.ChainStatus
+	FeeToken    *common.Address           `json:"feeToken,omitempty"` // ERC-20 token the verification fee is paid in
+	Context     *chainContext             `json:"context,omitempty"`  // chain pair selected with the use command or the config
+	Probe       testimonium.ChainProbe    `json:"probe"`
+	Locks       *testimonium.LockCalendar `json:"locks,omitempty"`     // set with --locks
+	Instance    string                    `json:"instance,omitempty"`  // ETH Relay instance selected with --instance
+	Instances   map[string]common.Address `json:"instances,omitempty"` // named ETH Relay instances of the chain
+	blockHashes []common.Hash
+	fee         testimonium.VerificationFee
+}
+
+func (result statusResult) renderText(w io.Writer) {
+	if result.Context != nil {
+		fmt.Fprintf(w, "Chain context: %s\n", result.Context)
+	}
+	if result.Instance != "" {
+		fmt.Fprintf(w, "ETH Relay instance: %s (%s)\n", result.Instance, result.Instances[result.Instance].Hex())
+	}
+	fmt.Fprintf(w, "Account: %s\n", result.Account)
+	fmt.Fprintf(w, "Balance: %s ETH\n", weiToEth(result.Balance))
+	fmt.Fprintf(w, "Stake: %s ETH\n", weiToEth(result.Stake))
+	fmt.Fprintf(w, "Required stake per block: %s ETH\n", weiToEth(result.RequiredStakePerBlock))
+	fmt.Fprintf(w, "Verification fee: %s\n", result.fee)
+	fmt.Fprintf(w, "Genesis block: %s\n", result.GenesisBlockHash.String())
+	fmt.Fprintf(w, "Longest chain endpoint: %s\n", result.LongestChainEndpoint.String())
+
+	for _, blockHash := range result.blockHashes {
+		fmt.Fprintf(w, "Header %s stored: %t\n", blockHash.String(), result.HeadersStored[blockHash])
+	}
+
+	if !result.Multicall {
+		fmt.Fprintln(w, "(no Multicall3 contract found, view calls were sent one by one)")
+	}
+
+	if len(result.Instances) > 0 {
+		names := make([]string, 0, len(result.Instances))
+		for name := range result.Instances {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintln(w, "ETH Relay instances:")
+		for _, name := range names {
+			fmt.Fprintf(w, "  %s: %s\n", name, result.Instances[name].Hex())
+		}
+	}
+
+	renderProbe(w, result.Probe)
+	if result.Locks != nil {
+		renderLocks(w, result.Locks, result.Stake)
+	}
+}
+
+func renderLocks(w io.Writer, locks *testimonium.LockCalendar, stake *big.Int) {
+	locked := locks.LockedStake()
+	free := new(big.Int).Sub(stake, locked)
+	if free.Sign() < 0 {
+		free.SetInt64(0)
+	}
+	fmt.Fprintf(w, "Locked headers: %d (lock period %s), %s ETH locked, %s ETH free\n", len(locks.Headers), locks.LockPeriod,
+		weiToEth(locked), weiToEth(free))
+	for i, header := range locks.Headers {
+		freed := new(big.Int).Add(free, new(big.Int).Mul(locks.StakePerHeader, big.NewInt(int64(i+1))))
+		fmt.Fprintf(w, "  #%d %s incontestable at %s (in %s), %s ETH free then\n", header.BlockNumber, header.BlockHash.Hex(),
+			header.Incontestable.Format(time.RFC3339), header.Incontestable.Sub(locks.ChainTime).Round(time.Second), weiToEth(freed))
+	}
+}
+
+func renderProbe(w io.Writer, probe testimonium.ChainProbe) {
+	fmt.Fprintf(w, "Node latency: %s\n", probe.Latency.Round(time.Millisecond))
+	if probe.Syncing {
+		fmt.Fprintf(w, "Node syncing: block %d of %d\n", probe.CurrentBlock, probe.HighestBlock)
+	} else {
+		fmt.Fprintf(w, "Latest block: %d (%s old)\n", probe.CurrentBlock, probe.HeadAge)
+	}
+	fmt.Fprintf(w, "Subscriptions supported: %t\n", probe.Subscriptions)
+	fmt.Fprintf(w, "Node capabilities: %s\n", probe.Capabilities)
+	if probe.Role != testimonium.ROLE_ANY {
+		fmt.Fprintf(w, "Chain role: %s\n", probe.Role)
+	}
+	if !probe.TransactionsUsable && probe.Role != testimonium.ROLE_SOURCE {
+		fmt.Fprintln(w, "Chain ids do not match the config, no transactions are sent")
+	}
+	for _, err := range probe.Errors {
+		fmt.Fprintf(w, "Probe failed: %s\n", err)
+	}
+}
+
+func weiToEth(wei *big.Int) string {
+	eth := new(big.Float).SetInt(wei)
+	return new(big.Float).Quo(eth, big.NewFloat(math.Pow10(18))).String()
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().Uint8VarP(&statusFlagChain, "chain", "c", 1, "chain")
+	statusCmd.Flags().BoolVar(&statusFlagLocks, "locks", false, "show the lock calendar of the headers submitted by the account")
+	statusCmd.Flags().DurationVar(&statusFlagLockPeriod, "lock-period", 0, "lock period of submitted headers for --locks (default: read from the contract)")
+}
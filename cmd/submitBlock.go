@@ -4,21 +4,34 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/pantos-io/go-ethrelay/testimonium"
 	"github.com/spf13/cobra"
 )
 
 var submitFlagSrcChain uint8
 var submitFlagRandomize bool
+var submitFlagIllegitimate string
 var submitFlagParent string
 var submitFlagLiveMode bool
+var submitFlagAncestors int
+var submitFlagValidate string
+var submitFlagPolicy string
+var submitFlagHealthAddr string
+var submitFlagStallTimeout time.Duration
+var submitFlagMaxGasPrice float64
+var submitFlagResumeGasPrice float64
+var submitFlagGasCheckInterval time.Duration
 
 // submitCmd represents the submit command
 var submitBlockCmd = &cobra.Command{
@@ -28,18 +41,64 @@ var submitBlockCmd = &cobra.Command{
 	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 
+		validationLevel, err := testimonium.ParseValidationLevel(submitFlagValidate)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var mutators []testimonium.HeaderMutator
+		if submitFlagIllegitimate != "" {
+			if mutators, err = testimonium.ParseHeaderMutators(submitFlagIllegitimate); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if submitFlagRandomize {
+			mutators = append(mutators, testimonium.MUTATE_SWAP_ROOTS)
+		}
+
+		// modified headers are submitted on purpose (e.g., to test disputes), they would never pass the validation
+		if len(mutators) > 0 || len(submitFlagParent) > 0 {
+			validationLevel = testimonium.VALIDATION_NONE
+		}
+
 		if submitFlagLiveMode {
-			testimoniumClient = createTestimoniumClient()
+			policy, err := testimonium.ParseRelayPolicy(submitFlagPolicy, submitFlagDestChain)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			liveMonitor = testimonium.NewLiveMonitor()
+			opts := []testimonium.ClientOption{testimonium.WithHeaderValidation(validationLevel), testimonium.WithRelayPolicy(policy),
+				testimonium.WithLiveMonitor(liveMonitor)}
+			if submitFlagMaxGasPrice > 0 {
+				ceiling := testimonium.GasCeiling{MaxGasPrice: gweiToWei(submitFlagMaxGasPrice), CheckInterval: submitFlagGasCheckInterval}
+				if submitFlagResumeGasPrice > 0 {
+					ceiling.ResumeGasPrice = gweiToWei(submitFlagResumeGasPrice)
+				}
+				opts = append(opts, testimonium.WithGasCeiling(ceiling))
+			}
+			testimoniumClient = createTestimoniumClient(opts...)
+			connectChains()
+
+			if submitFlagHealthAddr != "" {
+				pair := testimonium.RelayPair{SourceChain: submitFlagSrcChain, DestinationChain: submitFlagDestChain}
+				health := testimonium.NewHealthHandler(*testimoniumClient, liveMonitor, submitFlagStallTimeout, pair)
+				go func() {
+					log.Fatal(http.ListenAndServe(submitFlagHealthAddr, health))
+				}()
+			}
 			// TODO: live mode should be variable, outsource this to terminal
-			testimoniumClient.SubmitHeaderLive(submitFlagDestChain, submitFlagSrcChain, 5*time.Minute)
+			err = testimoniumClient.SubmitHeaderLive(submitFlagDestChain, submitFlagSrcChain, 5*time.Minute)
+			if err != nil {
+				log.Fatal(err)
+			}
 
 			return
 		}
 
 		var header *types.Header = nil
-		var err error
 
-		testimoniumClient = createTestimoniumClient()
+		testimoniumClient = createTestimoniumClient(testimonium.WithHeaderValidation(validationLevel))
 
 		if len(args) > 0 {
 			if strings.HasPrefix(args[0], "0x") {
@@ -64,26 +123,66 @@ var submitBlockCmd = &cobra.Command{
 		}
 
 		if len(submitFlagParent) > 0 {
-			fmt.Printf("Modifying parent...\n")
+			fmt.Fprintf(progressOutput(), "Modifying parent...\n")
 			header.ParentHash = common.HexToHash(submitFlagParent)
 		}
 
-		if submitFlagRandomize {
-			fmt.Printf("Randomizing header...\n")
-			header = testimoniumClient.RandomizeHeader(header, submitFlagSrcChain)
+		for _, mutator := range mutators {
+			fmt.Fprintf(progressOutput(), "Mutating header: %s\n", mutator)
+			header = mutator.Mutate(header)
 		}
 
-		fmt.Printf("Submitting block %s of chain %d to chain %d...\n", header.Number.String(), submitFlagSrcChain, submitFlagDestChain)
+		fmt.Fprintf(progressOutput(), "Submitting block %s of chain %d to chain %d...\n", header.Number.String(), submitFlagSrcChain, submitFlagDestChain)
 
 		//header.Nonce = types.EncodeNonce(header.Nonce.Uint64() + 1)  // can be used for testing PoW validation
 
-		err = testimoniumClient.SubmitHeader(header, submitFlagDestChain)
+		var results []*testimonium.TxResult
+		if submitFlagAncestors > 0 {
+			results, err = testimoniumClient.SubmitHeaderWithAncestors(header, submitFlagDestChain, submitFlagSrcChain, submitFlagAncestors)
+		} else {
+			err = testimoniumClient.ValidateHeader(header, submitFlagSrcChain)
+			if err == nil {
+				var result *testimonium.TxResult
+				result, err = testimoniumClient.SubmitHeader(header, submitFlagDestChain)
+				results = append(results, result)
+			}
+		}
+		if errors.Is(err, testimonium.ErrLostRace) {
+			printResult(txResult{Message: fmt.Sprintf("Block %s was submitted by another relayer first: %s", header.Hash().String(), err)})
+			return
+		}
+		if errors.Is(err, testimonium.ErrHeaderAlreadyStored) {
+			printResult(txResult{Message: fmt.Sprintf("Block %s is already stored on chain %d, nothing to submit", header.Hash().String(), submitFlagDestChain)})
+			return
+		}
+		if errors.Is(err, testimonium.ErrInvalidHeader) {
+			log.Fatalf("Header did not pass %s validation: %s (use --validate none to submit it anyway)", validationLevel, err)
+		}
+		if errors.Is(err, testimonium.ErrParentNotStored) {
+			log.Fatalf("Failed to submit header: %s (use --ancestors to submit missing ancestors first)", err)
+		}
 		if err != nil {
 			log.Fatal("Failed to submit header: " + err.Error())
 		}
+
+		submitted := make(txResults, len(results))
+		for i, result := range results {
+			submitted[i] = txResult{TxResult: result}
+		}
+		if len(mutators) > 0 && len(submitted) > 0 {
+			// all mutations invalidate the proof-of-work, so the last one determines the expected dispute result
+			submitted[len(submitted)-1].Message = fmt.Sprintf("Illegitimate block %s submitted, a dispute is expected to emit return code %d",
+				header.Hash().String(), mutators[len(mutators)-1].ReturnCode)
+		}
+		printResult(submitted)
 	},
 }
 
+func gweiToWei(gwei float64) *big.Int {
+	wei, _ := new(big.Float).Mul(big.NewFloat(gwei), big.NewFloat(params.GWei)).Int(nil)
+	return wei
+}
+
 func init() {
 	submitCmd.AddCommand(submitBlockCmd)
 
@@ -97,6 +196,15 @@ func init() {
 	// is called directly, e.g.:
 	submitBlockCmd.Flags().BoolVarP(&submitFlagLiveMode, "live", "l", false, "live mode (continuously submits most recent block headers)")
 	submitBlockCmd.Flags().Uint8Var(&submitFlagSrcChain, "target", 0, "target chain")
-	submitBlockCmd.Flags().BoolVarP(&submitFlagRandomize, "randomize", "r", false, "randomize block")
+	submitBlockCmd.Flags().BoolVarP(&submitFlagRandomize, "randomize", "r", false, "randomize block (same as --illegitimate swap-roots)")
+	submitBlockCmd.Flags().StringVar(&submitFlagIllegitimate, "illegitimate", "", "submit an illegitimate header with these mutations (state-root, receipts-root, timestamp, nonce, difficulty, swap-roots)")
 	submitBlockCmd.Flags().StringVarP(&submitFlagParent, "parent", "p", "", "set parent explicitly")
+	submitBlockCmd.Flags().StringVar(&submitFlagValidate, "validate", "basic", "validation of headers before submission (none, basic, strict)")
+	submitBlockCmd.Flags().StringVar(&submitFlagPolicy, "policy", "all", "blocks relayed in live mode (all, every:N, to:ADDRESS,..., adaptive:low=GWEI,max=GWEI,interval=N,maxskip=N)")
+	submitBlockCmd.Flags().StringVar(&submitFlagHealthAddr, "health-addr", "", "address serving /healthz and /readyz in live mode (e.g., :8080)")
+	submitBlockCmd.Flags().DurationVar(&submitFlagStallTimeout, "stall-timeout", 10*time.Minute, "/healthz fails if no header was processed for this duration in live mode")
+	submitBlockCmd.Flags().Float64Var(&submitFlagMaxGasPrice, "max-gas-price", 0, "live mode pauses submissions while the gas price of the verifying chain exceeds this price in gwei (0 for no limit)")
+	submitBlockCmd.Flags().Float64Var(&submitFlagResumeGasPrice, "resume-gas-price", 0, "paused submissions resume at or below this gas price in gwei (default: --max-gas-price)")
+	submitBlockCmd.Flags().DurationVar(&submitFlagGasCheckInterval, "gas-check-interval", testimonium.GAS_CEILING_CHECK_INTERVAL, "interval the gas price is checked at while submissions are paused")
+	submitBlockCmd.Flags().IntVar(&submitFlagAncestors, "ancestors", 0, "max. number of missing ancestors that are submitted before the block")
 }
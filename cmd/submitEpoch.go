@@ -9,6 +9,7 @@ import (
 	"github.com/pantos-io/go-ethrelay/typedefs"
 	"math/big"
 	"os"
+	"strings"
 
 	"log"
 
@@ -32,15 +33,31 @@ var submitEpochCmd = &cobra.Command{
 			log.Fatalf("Illegal epoch number '%s'", args[0])
 		}
 
+		// wrong epoch data is only noticed when the first proof is rejected, after the gas has been spent
+		if failed := failedSelfTestChecks(ethash.SelfTest()); len(failed) > 0 {
+			log.Fatalf("Ethash self test failed (%s), run 'ethash selftest' for details", strings.Join(failed, ", "))
+		}
+
+		configureDAGCache(cmd)
 		epochData := ethash.GenerateEpochData(epoch.Uint64())
 
 		if jsonFlag {
 			writeEpochAsJson(epochData, epoch)
-			fmt.Printf("Wrote epoch data to %s.json\n", epoch.String())
+			printResult(txResult{Message: fmt.Sprintf("Wrote epoch data to %s.json", epoch.String())})
 			return
 		}
 		testimoniumClient = createTestimoniumClient()
-		testimoniumClient.SetEpochData(epochData, submitFlagDestChain)
+		results, err := testimoniumClient.SetEpochData(epochData, submitFlagDestChain)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		output := make(txResults, len(results))
+		for i, result := range results {
+			output[i] = txResult{TxResult: result}
+		}
+		output = append(output, txResult{Message: fmt.Sprintf("Epoch data of epoch %s set", epoch.String())})
+		printResult(output)
 	},
 }
 
@@ -0,0 +1,62 @@
+// This file contains the opt-in export of traces (--otlp-endpoint) to an OpenTelemetry collector. Each command is
+// traced as a whole, submissions, proof generations, verifications and RPC requests become its child spans.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var otlpEndpoint string
+
+// tracer is set if traces are exported, it is passed to the client by createTestimoniumClient
+var tracer *testimonium.Tracer
+var commandSpan *testimonium.Span
+
+// commandContext carries the span of the command, the operations of the client become its children
+var commandContext = context.Background()
+
+// startTracing creates the tracer and starts the span of the command if an OTLP endpoint is configured. The endpoint
+// and service name can also be set with the standard OpenTelemetry environment variables.
+func startTracing(cmd *cobra.Command, args []string) error {
+	endpoint := otlpEndpoint
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	}
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if endpoint == "" {
+		return nil
+	}
+
+	var err error
+	tracer, err = testimonium.NewTracer(endpoint, os.Getenv("OTEL_SERVICE_NAME"))
+	if err != nil {
+		return err
+	}
+	commandContext, commandSpan = tracer.Start(context.Background(), cmd.CommandPath())
+	commandSpan.SetAttribute("ethrelay.args", strings.Join(args, " "))
+	return nil
+}
+
+// stopTracing ends the span of the command and exports the remaining spans
+func stopTracing() {
+	if tracer == nil {
+		return
+	}
+	commandSpan.End(nil)
+	if err := tracer.Shutdown(); err != nil {
+		fmt.Fprintln(progressOutput(), "WARNING:", err)
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&otlpEndpoint, "otlp-endpoint", "", "export traces to this OTLP/HTTP endpoint (e.g., http://localhost:4318), defaults to $OTEL_EXPORTER_OTLP_ENDPOINT")
+}
@@ -0,0 +1,236 @@
+// This file contains logic executed if the command "tree" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+const (
+	TREE_FORMAT_ASCII = "ascii"
+	TREE_FORMAT_DOT   = "dot"
+	TREE_FORMAT_JSON  = "json"
+)
+
+var treeFlagChain uint8
+var treeFlagFormat string
+var treeFlagFrom uint64
+var treeFlagAll bool
+
+// treeCmd represents the tree command
+var treeCmd = &cobra.Command{
+	Use:   "tree",
+	Short: "Renders the fork tree of the headers stored in the ETH Relay contract",
+	Long: `Renders the fork tree of all headers submitted to the ETH Relay contract on the chain (--chain): their
+hashes, numbers and submitters, whether they are confirmed (the lock period elapsed), still locked or removed by a
+dispute, and whether they were disputed. Headers of the longest chain are marked with '*'.
+
+The tree is rendered as ASCII art (--format ascii), as Graphviz DOT (--format dot, e.g., 'ethrelay tree --format dot
+| dot -Tsvg > tree.svg') or as JSON (--format json or --output json). In ASCII art, runs of stored headers without
+forks or disputes are collapsed unless --all is set. --from omits the headers below a block number.
+
+The tree is built from the event index (see 'index update'), which is updated first.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		switch treeFlagFormat {
+		case TREE_FORMAT_ASCII, TREE_FORMAT_DOT:
+		case TREE_FORMAT_JSON:
+			outputFormat = OUTPUT_JSON
+		default:
+			log.Fatalf("Unknown tree format '%s' (use %s, %s or %s)", treeFlagFormat, TREE_FORMAT_ASCII, TREE_FORMAT_DOT, TREE_FORMAT_JSON)
+		}
+
+		testimoniumClient = createTestimoniumClient()
+
+		tree, err := testimoniumClient.BranchTree(dataDir, treeFlagChain)
+		if err != nil {
+			log.Fatal("Failed to build the fork tree: " + err.Error())
+		}
+		result := treeResult{BranchTree: tree, from: treeFlagFrom, all: treeFlagAll}
+		if treeFlagFormat == TREE_FORMAT_DOT {
+			printResult(treeDotResult(result))
+			return
+		}
+		printResult(result)
+	},
+}
+
+type treeResult struct {
+	*testimonium.BranchTree
+	from uint64
+	all  bool
+}
+
+// roots returns the nodes the rendering starts at: the roots of the tree and the nodes at --from
+func (result treeResult) roots() []*testimonium.TreeNode {
+	var roots []*testimonium.TreeNode
+	for _, node := range result.Nodes {
+		if node.BlockNumber < result.from {
+			continue
+		}
+		if parent, exists := result.Node(node.ParentHash); !exists || parent.BlockNumber < result.from {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+func (result treeResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Fork tree of chain %d (contract %s): %d headers, %d fork points\n", result.Chain, result.Contract.Hex(),
+		len(result.Nodes), result.Forks())
+	if result.LockPeriod > 0 {
+		fmt.Fprintf(w, "Lock period: %s\n", result.LockPeriod)
+	} else {
+		fmt.Fprintln(w, "Lock period: unknown (the contract does not expose it)")
+	}
+	fmt.Fprintf(w, "Longest chain: %s (* = header of the longest chain)\n", result.Endpoint.Hex())
+	for _, root := range result.roots() {
+		result.renderBranch(w, root, "", "")
+	}
+}
+
+// renderBranch renders the run of headers from node up to the next fork or tip, followed by the branches of the
+// fork. The first line is prefixed with first, all further lines with rest.
+func (result treeResult) renderBranch(w io.Writer, node *testimonium.TreeNode, first string, rest string) {
+	run := []*testimonium.TreeNode{node}
+	for len(node.Children) == 1 {
+		node, _ = result.Node(node.Children[0])
+		run = append(run, node)
+	}
+
+	collapsed := 0
+	for i, runNode := range run {
+		if !result.all && i > 0 && i < len(run)-1 && runNode.Stored && !runNode.Disputed {
+			collapsed++
+			continue
+		}
+		if collapsed > 0 {
+			fmt.Fprintf(w, "%s... %d headers\n", rest, collapsed)
+			collapsed = 0
+		}
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		fmt.Fprintf(w, "%s%s\n", prefix, treeNodeLabel(runNode))
+	}
+
+	for i, childHash := range node.Children {
+		child, _ := result.Node(childHash)
+		if i == len(node.Children)-1 {
+			result.renderBranch(w, child, rest+"`-- ", rest+"    ")
+		} else {
+			result.renderBranch(w, child, rest+"|-- ", rest+"|   ")
+		}
+	}
+}
+
+// treeNodeLabel returns the line of a header in ASCII art
+func treeNodeLabel(node *testimonium.TreeNode) string {
+	label := fmt.Sprintf("#%d %s", node.BlockNumber, node.BlockHash.Hex())
+	if node.Submitter == nil {
+		label += " genesis"
+	} else {
+		label += " by " + node.Submitter.Hex()
+	}
+	label += " [" + strings.Join(treeNodeStates(node), ", ") + "]"
+	if node.LongestChain {
+		label += " *"
+	}
+	return label
+}
+
+// treeNodeStates returns the states of a header, e.g., "confirmed" and "disputed"
+func treeNodeStates(node *testimonium.TreeNode) []string {
+	var states []string
+	switch {
+	case !node.Stored:
+		states = append(states, "removed")
+	case node.Confirmed == nil:
+		states = append(states, "stored")
+	case *node.Confirmed:
+		states = append(states, "confirmed")
+	default:
+		states = append(states, "unconfirmed")
+	}
+	if node.Disputed {
+		states = append(states, "disputed")
+	}
+	return states
+}
+
+// treeDotResult renders the fork tree as Graphviz DOT
+type treeDotResult treeResult
+
+func (result treeDotResult) renderText(w io.Writer) {
+	tree := treeResult(result)
+	included := func(node *testimonium.TreeNode) bool {
+		return node.BlockNumber >= tree.from
+	}
+
+	fmt.Fprintf(w, "digraph \"chain %d\" {\n", tree.Chain)
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, "  node [shape=box, fontname=\"monospace\"];")
+	for _, node := range tree.Nodes {
+		if !included(node) {
+			continue
+		}
+		hash := node.BlockHash.Hex()
+		label := fmt.Sprintf("#%d\\n%s..%s", node.BlockNumber, hash[:10], hash[len(hash)-4:])
+		if node.Submitter != nil {
+			submitter := node.Submitter.Hex()
+			label += fmt.Sprintf("\\nby %s..%s", submitter[:8], submitter[len(submitter)-4:])
+		}
+		label += "\\n" + strings.Join(treeNodeStates(node), ", ")
+
+		var attributes []string
+		if node.LongestChain {
+			attributes = append(attributes, "penwidth=2")
+		}
+		if !node.Stored {
+			attributes = append(attributes, "style=dashed", "color=gray", "fontcolor=gray")
+		} else if node.Disputed {
+			attributes = append(attributes, "color=red")
+		}
+		if node.Confirmed != nil && !*node.Confirmed && node.Stored {
+			attributes = append(attributes, "style=filled", "fillcolor=lightyellow")
+		}
+		fmt.Fprintf(w, "  \"%s\" [label=\"%s\"%s];\n", hash, label, dotAttributes(attributes))
+	}
+	for _, node := range tree.Nodes {
+		if !included(node) {
+			continue
+		}
+		for _, childHash := range node.Children {
+			child, _ := tree.Node(childHash)
+			style := ""
+			if child.LongestChain {
+				style = " [penwidth=2]"
+			}
+			fmt.Fprintf(w, "  \"%s\" -> \"%s\"%s;\n", node.BlockHash.Hex(), childHash.Hex(), style)
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+func dotAttributes(attributes []string) string {
+	if len(attributes) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(attributes, ", ")
+}
+
+func init() {
+	rootCmd.AddCommand(treeCmd)
+
+	treeCmd.Flags().Uint8VarP(&treeFlagChain, "chain", "c", 1, "the verifying chain of the ETH Relay contract")
+	treeCmd.Flags().StringVar(&treeFlagFormat, "format", TREE_FORMAT_ASCII, "format of the tree (ascii, dot or json)")
+	treeCmd.Flags().Uint64Var(&treeFlagFrom, "from", 0, "omit the headers below this block number")
+	treeCmd.Flags().BoolVar(&treeFlagAll, "all", false, "render every header instead of collapsing runs without forks (ascii)")
+}
@@ -0,0 +1,244 @@
+// This file contains logic executed if the command "use" is typed in.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// chainRoleAnnotation marks flags whose name does not tell the role of the chain they select
+const chainRoleAnnotation = "chain-role"
+
+// chainFlagRoles are the roles of the chains selected by flags of these names
+var chainFlagRoles = map[string]testimonium.ChainRole{
+	"target":      testimonium.ROLE_SOURCE,
+	"src":         testimonium.ROLE_SOURCE,
+	"chain":       testimonium.ROLE_DESTINATION,
+	"dest":        testimonium.ROLE_DESTINATION,
+	"destination": testimonium.ROLE_DESTINATION,
+	"verifying":   testimonium.ROLE_DESTINATION,
+}
+
+var useFlagClear bool
+
+// useCmd represents the use command
+var useCmd = &cobra.Command{
+	Use:   "use [sourceChain] [destinationChain]",
+	Short: "Sets the chain pair used by default",
+	Long: `Sets the source chain (whose headers are relayed) and the destination chain (the verifying chain with the ETH
+Relay contract) used by all following commands, so flags like --target, --src, --chain or --dest can be omitted.
+Explicitly specified flags take precedence. The selection is stored in the data directory (--datadir), without
+arguments the current selection is shown, --clear removes it.
+
+Without a selection, the 'source' and 'destination' entries of the config file are used, e.g.:
+
+    source: 0
+    destination: 1`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 && len(args) != 2 {
+			return fmt.Errorf("requires no or two args, received %d", len(args))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if useFlagClear {
+			if err := os.Remove(chainContextPath()); err != nil && !os.IsNotExist(err) {
+				log.Fatal(err)
+			}
+		}
+
+		if len(args) == 2 {
+			source, err := parseChainArg(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			destination, err := parseChainArg(args[1])
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := saveChainContext(chainContext{Source: &source, Destination: &destination}); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		context, err := currentChainContext()
+		if err != nil {
+			log.Fatal(err)
+		}
+		printResult(useResult{context})
+	},
+}
+
+// chainContext is the chain pair used if no chain flags are specified. Origin is "use" if the pair was selected with
+// the use command, "config" if it was read from the config file.
+type chainContext struct {
+	Source      *uint8 `json:"source,omitempty"`
+	Destination *uint8 `json:"destination,omitempty"`
+	Origin      string `json:"origin,omitempty"`
+}
+
+func (context chainContext) isSet() bool {
+	return context.Source != nil || context.Destination != nil
+}
+
+func (context chainContext) chain(role testimonium.ChainRole) *uint8 {
+	if role == testimonium.ROLE_SOURCE {
+		return context.Source
+	}
+	return context.Destination
+}
+
+func (context chainContext) String() string {
+	if !context.isSet() {
+		return "none"
+	}
+	format := func(chain *uint8) string {
+		if chain == nil {
+			return "default"
+		}
+		return strconv.Itoa(int(*chain))
+	}
+	return fmt.Sprintf("source chain %s, destination chain %s (from %s)", format(context.Source), format(context.Destination), context.Origin)
+}
+
+type useResult struct {
+	chainContext
+}
+
+func (result useResult) renderText(w io.Writer) {
+	if !result.isSet() {
+		fmt.Fprintln(w, "No chain pair selected, commands use the defaults of their flags")
+		return
+	}
+	fmt.Fprintf(w, "Using %s\n", result.chainContext)
+}
+
+func chainContextPath() string {
+	return filepath.Join(dataDir, "context.json")
+}
+
+func parseChainArg(arg string) (uint8, error) {
+	chain, err := strconv.ParseUint(arg, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("illegal chain '%s'", arg)
+	}
+	if err := viper.ReadInConfig(); err == nil && !viper.IsSet(fmt.Sprintf("chains.%d", chain)) {
+		return 0, fmt.Errorf("chain %d is not configured", chain)
+	}
+	return uint8(chain), nil
+}
+
+func saveChainContext(context chainContext) error {
+	context.Origin = ""
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(context, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(chainContextPath(), data, 0644)
+}
+
+// currentChainContext returns the chain pair selected with the use command or, if none is selected, the one of the
+// config file.
+func currentChainContext() (chainContext, error) {
+	var context chainContext
+	data, err := ioutil.ReadFile(chainContextPath())
+	if err != nil && !os.IsNotExist(err) {
+		return context, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &context); err != nil {
+			return context, fmt.Errorf("corrupt chain context %s: %s", chainContextPath(), err)
+		}
+		context.Origin = "use"
+		return context, nil
+	}
+
+	if err := viper.ReadInConfig(); err != nil {
+		return context, nil
+	}
+	for _, role := range []testimonium.ChainRole{testimonium.ROLE_SOURCE, testimonium.ROLE_DESTINATION} {
+		if !viper.IsSet(role.String()) {
+			continue
+		}
+		chain := uint8(viper.GetUint(role.String()))
+		if role == testimonium.ROLE_SOURCE {
+			context.Source = &chain
+		} else {
+			context.Destination = &chain
+		}
+		context.Origin = "config"
+	}
+	return context, nil
+}
+
+// markChainRole sets the role of the chain selected by the flag, if its name does not tell it (e.g., a --chain flag
+// selecting a source chain).
+func markChainRole(flags *pflag.FlagSet, name string, role testimonium.ChainRole) {
+	if err := flags.SetAnnotation(name, chainRoleAnnotation, []string{role.String()}); err != nil {
+		panic(err)
+	}
+}
+
+// applyChainContext sets the chain flags of the command that were not specified to the chains of the current context.
+// Flags of a role are left alone if any flag of the role was specified, as several flags may select the same chain
+// (e.g., --target and --src).
+func applyChainContext(cmd *cobra.Command) error {
+	flagsByRole := make(map[testimonium.ChainRole][]*pflag.Flag)
+	specified := make(map[testimonium.ChainRole]bool)
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Value.Type() != "uint8" {
+			return
+		}
+		role, exists := chainFlagRoles[flag.Name]
+		if annotation := flag.Annotations[chainRoleAnnotation]; len(annotation) > 0 {
+			role, _ = testimonium.ParseChainRole(annotation[0])
+			exists = true
+		}
+		if !exists {
+			return
+		}
+		flagsByRole[role] = append(flagsByRole[role], flag)
+		specified[role] = specified[role] || flag.Changed
+	})
+	if len(flagsByRole) == 0 {
+		return nil
+	}
+
+	context, err := currentChainContext()
+	if err != nil {
+		return err
+	}
+	for role, flags := range flagsByRole {
+		chain := context.chain(role)
+		if chain == nil || specified[role] {
+			continue
+		}
+		for _, flag := range flags {
+			if err := flag.Value.Set(strconv.Itoa(int(*chain))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(useCmd)
+
+	useCmd.Flags().BoolVar(&useFlagClear, "clear", false, "remove the selected chain pair")
+}
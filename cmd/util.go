@@ -0,0 +1,18 @@
+// This file contains logic executed if the command "util" is typed in.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// utilCmd represents the util command
+var utilCmd = &cobra.Command{
+	Use:   "util",
+	Short: "Utilities for tooling built around ETH Relay",
+	Long:  `Utilities computing values used by ETH Relay (e.g., for disputes), no transactions are sent to any chain`,
+}
+
+func init() {
+	rootCmd.AddCommand(utilCmd)
+}
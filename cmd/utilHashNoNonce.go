@@ -0,0 +1,100 @@
+// This file contains logic executed if the command "util hash-no-nonce" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var utilFlagChain uint8
+var utilFlagBlock int64
+var utilFlagHash string
+
+// utilHashNoNonceCmd represents the command 'util hash-no-nonce'
+var utilHashNoNonceCmd = &cobra.Command{
+	Use:   "hash-no-nonce",
+	Short: "Computes the hash without nonce of a block",
+	Long: `Computes the hash of the block header without the fields MixDigest and Nonce (--block or --hash).
+
+This hash is the input of the Ethash proof of work and is stored by the Ethash contract when a header is disputed.
+The RLP encoding it is computed from is printed as well.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if (utilFlagBlock < 0) == (utilFlagHash == "") {
+			log.Fatal("Specify either --block or --hash")
+		}
+
+		testimoniumClient = createTestimoniumClient()
+
+		var header *types.Header
+		var err error
+		if utilFlagHash != "" {
+			header, err = testimoniumClient.HeaderByHash(common.HexToHash(utilFlagHash), utilFlagChain)
+		} else {
+			header, err = testimoniumClient.HeaderByNumber(big.NewInt(utilFlagBlock), utilFlagChain)
+		}
+		if err != nil {
+			log.Fatal("Failed to retrieve header: " + err.Error())
+		}
+
+		result, err := newHashNoNonceResult(header)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printResult(result)
+	},
+}
+
+type hashNoNonceResult struct {
+	BlockNumber      uint64
+	BlockHash        common.Hash
+	HashWithoutNonce common.Hash
+	RlpWithoutNonce  hexutil.Bytes
+	MixDigest        common.Hash
+	Nonce            uint64
+}
+
+func newHashNoNonceResult(header *types.Header) (hashNoNonceResult, error) {
+	rlpWithoutNonce, err := testimonium.EncodeHeaderWithoutNonce(header)
+	if err != nil {
+		return hashNoNonceResult{}, err
+	}
+	hashWithoutNonce, err := testimonium.HeaderHashWithoutNonce(header)
+	if err != nil {
+		return hashNoNonceResult{}, err
+	}
+	return hashNoNonceResult{
+		BlockNumber:      header.Number.Uint64(),
+		BlockHash:        header.Hash(),
+		HashWithoutNonce: hashWithoutNonce,
+		RlpWithoutNonce:  rlpWithoutNonce,
+		MixDigest:        header.MixDigest,
+		Nonce:            header.Nonce.Uint64(),
+	}, nil
+}
+
+func (result hashNoNonceResult) renderText(w io.Writer) {
+	fmt.Fprintf(w, "Block: %d (%s)\n", result.BlockNumber, result.BlockHash.Hex())
+	fmt.Fprintf(w, "HashWithoutNonce: %s\n", result.HashWithoutNonce.Hex())
+	fmt.Fprintf(w, "MixDigest: %s\n", result.MixDigest.Hex())
+	fmt.Fprintf(w, "Nonce: %d\n", result.Nonce)
+	fmt.Fprintf(w, "RlpWithoutNonce: %s\n", result.RlpWithoutNonce.String())
+}
+
+func init() {
+	utilCmd.AddCommand(utilHashNoNonceCmd)
+
+	utilHashNoNonceCmd.Flags().Uint8VarP(&utilFlagChain, "chain", "c", 0, "the chain of the block")
+	markChainRole(utilHashNoNonceCmd.Flags(), "chain", testimonium.ROLE_SOURCE)
+	utilHashNoNonceCmd.Flags().Int64Var(&utilFlagBlock, "block", -1, "the number of the block")
+	utilHashNoNonceCmd.Flags().StringVar(&utilFlagHash, "hash", "", "the hash of the block")
+}
@@ -4,11 +4,30 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/proofs"
+	"github.com/pantos-io/go-ethrelay/testimonium"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var verifyFlagSrcChain uint8
 var verifyFlagDestChain uint8
+var verifyFlagRoot string
+var verifyFlagRelay bool
+var verifyFlagBackfill bool
+var verifyFlagMaxHeaders int
+var verifyFlagWait time.Duration
+var verifyFlagCallback string
+var verifyFlagCallbackSecret string
+var verifyFlagLight bool
+var verifyFlagBranch string
 
 // verifyCmd represents the verify command
 var verifyCmd = &cobra.Command{
@@ -27,9 +46,167 @@ func init() {
 	// verifyCmd.PersistentFlags().String("foo", "", "A help for foo")
 
 	verifyCmd.PersistentFlags().Uint8Var(&verifyFlagSrcChain, "target", 0, "target chain")
+	verifyCmd.PersistentFlags().Uint8Var(&verifyFlagSrcChain, "src", 0, "target chain (same as --target)")
 	verifyCmd.PersistentFlags().Uint8Var(&verifyFlagDestChain, "chain", 1, "verifying chain")
+	verifyCmd.PersistentFlags().Uint8Var(&verifyFlagDestChain, "dest", 1, "verifying chain (same as --chain)")
+	verifyCmd.PersistentFlags().BoolVar(&verifyFlagRelay, "relay", false, "send the verification through the relayer configured for the verifying chain")
+	verifyCmd.PersistentFlags().StringVar(&verifyFlagCallback, "callback", "", "URL the result of the verification is posted to once it completes or fails")
+	verifyCmd.PersistentFlags().BoolVar(&verifyFlagLight, "light", false, "build the proofs without downloading block bodies (for light clients and bandwidth-constrained providers)")
+	verifyCmd.PersistentFlags().StringVar(&verifyFlagBranch, "branch", "", "only verify if the block is part of the branch ending at this stored header (endpoint hash)")
+	verifyCmd.PersistentFlags().StringVar(&verifyFlagCallbackSecret, "callback-secret", "", "key the callback payloads are signed with (default $ETHRELAY_CALLBACK_SECRET)")
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// verifyCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
+
+// verifyClientOptions returns the client options of the verify commands, i.e., the relayer if --relay is set
+func verifyClientOptions() []testimonium.ClientOption {
+	var options []testimonium.ClientOption
+	if verifyFlagLight {
+		options = append(options, testimonium.WithLightProofs(verifyFlagSrcChain))
+	}
+	if !verifyFlagRelay {
+		return options
+	}
+
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatal("Can't read config file: ", err)
+	}
+	relayerConfig := viper.GetStringMap(fmt.Sprintf("chains.%d.relayer", verifyFlagDestChain))
+	if len(relayerConfig) == 0 {
+		log.Fatalf("No relayer configured for chain %d", verifyFlagDestChain)
+	}
+
+	relayer, err := testimonium.NewRelayerFromConfig(relayerConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return append(options, testimonium.WithRelayer(verifyFlagDestChain, relayer))
+}
+
+// verifyTracker returns the tracking function of verification jobs, which posts the result to --callback, nil if
+// there is no callback
+func verifyTracker() func(job testimonium.VerificationJob) {
+	if verifyFlagCallback == "" {
+		return nil
+	}
+	secret := verifyFlagCallbackSecret
+	if secret == "" {
+		secret = os.Getenv("ETHRELAY_CALLBACK_SECRET")
+	}
+	if secret == "" {
+		fmt.Fprintln(progressOutput(), "WARNING: The callback payloads are not signed, set --callback-secret")
+	}
+	hook := testimonium.VerificationWebhook{URL: verifyFlagCallback, Secret: []byte(secret)}
+	return testimoniumClient.TrackWithWebhook(hook, verifyFlagSrcChain, verifyFlagDestChain, nil)
+}
+
+// verifyOptions returns the options of the verifications, i.e., the branch if --branch is set
+func verifyOptions() []testimonium.VerifyOption {
+	if verifyFlagBranch == "" {
+		return nil
+	}
+	return []testimonium.VerifyOption{testimonium.OnBranch(common.HexToHash(verifyFlagBranch))}
+}
+
+// verifyAgainstRoot verifies the proof against the trusted root specified by --root instead of a submitted header
+func verifyAgainstRoot(trieValueType testimonium.TrieValueType, proof proofs.Proof) {
+	feesInWei, err := testimoniumClient.GetRequiredVerificationFee(verifyFlagDestChain)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	result, err := testimoniumClient.VerifyAgainstRoot(feesInWei, common.HexToHash(verifyFlagRoot), trieValueType, proof, verifyFlagDestChain)
+	if err != nil {
+		log.Fatal(err)
+	}
+	printResult(txResult{TxResult: result})
+}
+
+// verifyWithBackfill verifies the transaction or receipt after submitting the missing headers of its block and of the
+// confirmation blocks
+func verifyWithBackfill(txHash common.Hash, trieValueType testimonium.TrieValueType) {
+	job, err := testimoniumClient.VerifyWithBackfill(txHash, trieValueType, noOfConfirmations, verifyFlagSrcChain,
+		verifyFlagDestChain, verifyFlagMaxHeaders, verifyTracker(), verifyOptions()...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	printResult(verificationJobResult{job})
+}
+
+// verifyAfterRelay verifies the transaction or receipt after waiting until its block and the confirmation blocks are
+// relayed by others
+func verifyAfterRelay(txHash common.Hash, trieValueType testimonium.TrieValueType) {
+	job, err := testimoniumClient.VerifyAfterRelay(txHash, trieValueType, noOfConfirmations, verifyFlagSrcChain,
+		verifyFlagDestChain, verifyFlagWait, verifyTracker(), verifyOptions()...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	printResult(verificationJobResult{job})
+}
+
+// verifyTransactionWithMeta verifies the transaction and its receipt in one job, reporting whether the transaction
+// succeeded and the gas it used
+func verifyTransactionWithMeta(txHash common.Hash) {
+	maxHeaders := 0
+	if verifyFlagBackfill {
+		maxHeaders = verifyFlagMaxHeaders
+	}
+	var track func(job testimonium.MetaVerificationJob)
+	if tracker := verifyTracker(); tracker != nil {
+		track = func(job testimonium.MetaVerificationJob) {
+			tracker(*job.Transaction)
+			tracker(*job.Receipt)
+		}
+	}
+	job, err := testimoniumClient.VerifyTransactionWithMeta(txHash, noOfConfirmations, verifyFlagSrcChain,
+		verifyFlagDestChain, maxHeaders, verifyFlagWait, track, verifyOptions()...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	printResult(metaVerificationJobResult{job})
+}
+
+type metaVerificationJobResult struct {
+	*testimonium.MetaVerificationJob
+}
+
+func (result metaVerificationJobResult) renderText(w io.Writer) {
+	fmt.Fprintln(w, "Transaction:")
+	verificationJobResult{result.Transaction}.renderText(w)
+	fmt.Fprintln(w, "Receipt:")
+	verificationJobResult{result.Receipt}.renderText(w)
+	if meta := result.Meta; meta != nil {
+		status := "succeeded"
+		if !meta.Succeeded() {
+			status = "failed"
+		}
+		fmt.Fprintf(w, "Transaction %s %s on the target chain, gas used %d (cumulative %d)\n", result.Transaction.TxHash.Hex(),
+			status, meta.GasUsed, meta.CumulativeGasUsed)
+	}
+}
+
+type verificationJobResult struct {
+	*testimonium.VerificationJob
+}
+
+func (result verificationJobResult) renderText(w io.Writer) {
+	if result.TxStatus != nil && *result.TxStatus == 0 {
+		fmt.Fprintf(w, "Transaction %s failed on the target chain (it is verified nevertheless)\n", result.TxHash.Hex())
+	}
+	fmt.Fprintf(w, "Block %d (%s), %d confirmations\n", result.BlockNumber, result.BlockHash.Hex(), result.Confirmations)
+	if len(result.Submitted) > 0 {
+		fmt.Fprintf(w, "Submitted %d headers up to block %d\n", len(result.Submitted), result.BlockNumber+uint64(result.Confirmations))
+	}
+	txResult{TxResult: result.Verification}.renderText(w)
+	if latency := result.Latency; latency != nil {
+		relayLag := latency.RelayLag.String()
+		if latency.Stored.IsZero() {
+			relayLag = "unknown"
+		}
+		fmt.Fprintf(w, "Latency %s: confirmation wait %s, relay lag %s, proof generation %s, tx inclusion %s, other %s\n",
+			latency.Total, latency.ConfirmationWait, relayLag, latency.ProofGeneration.Round(time.Millisecond),
+			latency.TxInclusion.Round(time.Second), latency.Other.Round(time.Second))
+	}
+}
@@ -0,0 +1,186 @@
+// This file contains logic executed if the command "verify batch" is typed in.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var verifyBatchFlagManifest string
+var verifyBatchFlagReport string
+var verifyBatchFlagConfirmations uint8
+var verifyBatchFlagWorkers int
+
+// verifyBatchCmd represents the command 'verify batch'
+var verifyBatchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Verifies the transactions and receipts listed in a manifest",
+	Long: `Verifies all transactions and receipts listed in the manifest on the verifying chain, e.g., to work off a backlog.
+
+The manifest is a JSON list of entries
+
+    [{"txHash": "0x...", "type": "receipt", "confirmations": 6}, ...]
+
+or a CSV file with the columns txHash, type and confirmations (an optional header line and lines starting with # are
+skipped). The type (transaction or receipt) defaults to transaction, the confirmations default to --confirmations.
+
+The proofs are generated concurrently (--workers), then the verifications are sent with consecutive nonces without
+waiting for each other. The outcome of every entry is written to the report (default: <manifest>.report.json).
+The blocks of the transactions have to be stored in the relay with the required confirmations.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		batch, err := readBatchManifest(verifyBatchFlagManifest, verifyBatchFlagConfirmations)
+		if err != nil {
+			log.Fatalf("Cannot read manifest %s: %s", verifyBatchFlagManifest, err)
+		}
+
+		reportPath := verifyBatchFlagReport
+		if reportPath == "" {
+			reportPath = strings.TrimSuffix(verifyBatchFlagManifest, filepath.Ext(verifyBatchFlagManifest)) + ".report.json"
+		}
+
+		testimoniumClient = createTestimoniumClient(verifyClientOptions()...)
+		if err := testimoniumClient.VerifyBatch(batch, verifyFlagSrcChain, verifyFlagDestChain, verifyBatchFlagWorkers); err != nil {
+			log.Fatal(err)
+		}
+
+		result := verifyBatchResult{Report: reportPath}
+		for _, verification := range batch {
+			entry := batchEntryResult{BatchVerification: verification}
+			if verification.Err != nil {
+				entry.Error = verification.Err.Error()
+			} else {
+				result.Verified++
+			}
+			result.Entries = append(result.Entries, entry)
+		}
+
+		report, err := json.MarshalIndent(result.Entries, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := ioutil.WriteFile(reportPath, report, 0644); err != nil {
+			log.Fatal(err)
+		}
+		printResult(result)
+	},
+}
+
+type batchEntryResult struct {
+	testimonium.BatchVerification
+	Error string `json:"error,omitempty"`
+}
+
+type verifyBatchResult struct {
+	Entries  []batchEntryResult `json:"entries"`
+	Verified int                `json:"verified"`
+	Report   string             `json:"report"`
+}
+
+func (result verifyBatchResult) renderText(w io.Writer) {
+	for _, entry := range result.Entries {
+		if entry.Error != "" {
+			fmt.Fprintf(w, "Verification of %s %s failed: %s\n", entry.ValueType, entry.TxHash.Hex(), entry.Error)
+			continue
+		}
+		fmt.Fprintf(w, "Verified %s %s (block %d)\n", entry.ValueType, entry.TxHash.Hex(), entry.BlockNumber)
+		txResult{TxResult: entry.Result}.renderText(w)
+	}
+	fmt.Fprintf(w, "%d of %d verifications succeeded, report written to %s\n", result.Verified, len(result.Entries), result.Report)
+}
+
+// batchManifestEntry is an entry of a JSON manifest
+type batchManifestEntry struct {
+	TxHash        string `json:"txHash"`
+	Type          string `json:"type"`
+	Confirmations *uint8 `json:"confirmations"`
+}
+
+// readBatchManifest reads the JSON or CSV manifest at the path, entries without confirmations get the default ones
+func readBatchManifest(path string, defaultConfirmations uint8) ([]testimonium.BatchVerification, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []batchManifestEntry
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, err
+		}
+	} else {
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.Comment = '#'
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for i, record := range records {
+			if i == 0 && strings.EqualFold(record[0], "txHash") {
+				continue
+			}
+			entry := batchManifestEntry{TxHash: record[0]}
+			if len(record) > 1 {
+				entry.Type = record[1]
+			}
+			if len(record) > 2 && record[2] != "" {
+				confirmations, err := strconv.ParseUint(record[2], 10, 8)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: illegal confirmations '%s'", i+1, record[2])
+				}
+				value := uint8(confirmations)
+				entry.Confirmations = &value
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no entries")
+	}
+	batch := make([]testimonium.BatchVerification, len(entries))
+	for i, entry := range entries {
+		if len(common.FromHex(entry.TxHash)) != common.HashLength {
+			return nil, fmt.Errorf("entry %d: illegal transaction hash '%s'", i+1, entry.TxHash)
+		}
+		batch[i] = testimonium.BatchVerification{
+			TxHash:        common.HexToHash(entry.TxHash),
+			ValueType:     testimonium.VALUE_TYPE_TRANSACTION,
+			Confirmations: defaultConfirmations,
+		}
+		if entry.Type != "" {
+			if batch[i].ValueType, err = testimonium.ParseTrieValueType(entry.Type); err != nil {
+				return nil, fmt.Errorf("entry %d: %s", i+1, err)
+			}
+		}
+		if entry.Confirmations != nil {
+			batch[i].Confirmations = *entry.Confirmations
+		}
+	}
+	return batch, nil
+}
+
+func init() {
+	verifyCmd.AddCommand(verifyBatchCmd)
+
+	verifyBatchCmd.Flags().StringVar(&verifyBatchFlagManifest, "manifest", "", "JSON or CSV file listing the verified transactions")
+	verifyBatchCmd.Flags().StringVar(&verifyBatchFlagReport, "report", "", "file the results are written to (default: <manifest>.report.json)")
+	verifyBatchCmd.Flags().Uint8VarP(&verifyBatchFlagConfirmations, "confirmations", "c", 4, "Number of block confirmations of entries without confirmations")
+	verifyBatchCmd.Flags().IntVar(&verifyBatchFlagWorkers, "workers", testimonium.BATCH_PROOF_WORKERS, "number of proofs generated concurrently")
+	verifyBatchCmd.MarkFlagRequired("manifest")
+}
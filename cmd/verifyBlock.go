@@ -18,6 +18,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"log"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -39,7 +40,7 @@ var verifyBlockCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		blockHash := common.HexToHash(args[0])
 
-		testimoniumClient = createTestimoniumClient()
+		testimoniumClient = createTestimoniumClient(verifyClientOptions()...)
 
 		headerExists, err := testimoniumClient.BlockHeaderExists(blockHash, verifyFlagDestChain)
 		if err != nil {
@@ -47,7 +48,7 @@ var verifyBlockCmd = &cobra.Command{
 		}
 
 		if !headerExists {
-			fmt.Printf("No header stored for block %s on verifying chain\n", ShortHexString(args[0]))
+			printResult(blockVerificationResult{BlockHash: blockHash})
 			return
 		}
 
@@ -56,10 +57,23 @@ var verifyBlockCmd = &cobra.Command{
 			log.Fatal("Could not get original block on source chain: " + err.Error())
 		}
 
-		fmt.Printf("Block %s is valid\n", ShortHexString(args[0]))
+		printResult(blockVerificationResult{BlockHash: blockHash, Valid: true})
 	},
 }
 
+type blockVerificationResult struct {
+	BlockHash common.Hash `json:"blockHash"`
+	Valid     bool        `json:"valid"`
+}
+
+func (result blockVerificationResult) renderText(w io.Writer) {
+	if !result.Valid {
+		fmt.Fprintf(w, "No header stored for block %s on verifying chain\n", ShortHexString(result.BlockHash.Hex()))
+		return
+	}
+	fmt.Fprintf(w, "Block %s is valid\n", ShortHexString(result.BlockHash.Hex()))
+}
+
 func init() {
 	verifyCmd.AddCommand(verifyBlockCmd)
 
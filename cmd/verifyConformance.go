@@ -0,0 +1,92 @@
+// This file contains logic executed if the command "verify conformance" is typed in.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var verifyFlagSeed int64
+var verifyFlagVectors int
+
+// verifyConformanceCmd represents the command 'verify conformance'
+var verifyConformanceCmd = &cobra.Command{
+	Use:   "conformance",
+	Short: "Checks that locally generated proofs are accepted and rejected like by the contract",
+	Long: `Generates the transaction, receipt and state tries of random blocks (and of tries of fixed sizes), builds
+proofs of their values locally and mutates them (tampered values and nodes, the path of another value, missing and
+superfluous nodes). Every proof is verified with the pure-Go verification and by the bundled ETH Relay contract
+deployed on a simulated backend; both have to accept and reject the same proofs. No chain is connected.
+
+Every difference fails the check, including the known defects of the bundled contract's verification (it rejects
+valid proofs through extension nodes and accepts proofs ending before the value), which are named with the failed
+checks. The tries are generated from --seed, so a failure can be reproduced with the same seed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		checks, err := testimonium.RunConformanceSuite(verifyFlagSeed, verifyFlagVectors)
+		if err != nil {
+			log.Fatal(err)
+		}
+		result := conformanceResult{Seed: verifyFlagSeed, Checks: checks}
+		printResult(result)
+
+		if failed := result.failed(); failed > 0 {
+			log.Fatalf("Proof conformance FAILED: %d of %d checks", failed, len(checks))
+		}
+	},
+}
+
+type conformanceResult struct {
+	Seed   int64                          `json:"seed"`
+	Checks []testimonium.ConformanceCheck `json:"checks"`
+}
+
+func (result conformanceResult) failed() int {
+	failed := 0
+	for _, check := range result.Checks {
+		if !check.Passed {
+			failed++
+		}
+	}
+	return failed
+}
+
+func (result conformanceResult) renderText(w io.Writer) {
+	defects := make(map[string]int)
+	for _, check := range result.Checks {
+		if check.Passed {
+			continue
+		}
+		if check.ContractDefect != "" {
+			defects[check.ContractDefect]++
+			continue
+		}
+		fmt.Fprintf(w, "FAILED %s (locally accepted: %t, contract accepted: %t, return code %d)\n", check.Name,
+			check.LocalAccepted, check.ContractAccepted, check.ReturnCode)
+		if check.Detail != "" {
+			fmt.Fprintf(w, "       %s\n", check.Detail)
+		}
+	}
+	names := make([]string, 0, len(defects))
+	for defect := range defects {
+		names = append(names, defect)
+	}
+	sort.Strings(names)
+	for _, defect := range names {
+		fmt.Fprintf(w, "FAILED %d checks: %s\n", defects[defect], defect)
+	}
+	fmt.Fprintf(w, "%d checks, %d failed (seed %d)\n", len(result.Checks), result.failed(), result.Seed)
+}
+
+func init() {
+	verifyCmd.AddCommand(verifyConformanceCmd)
+
+	verifyConformanceCmd.Flags().Int64Var(&verifyFlagSeed, "seed", 1, "seed the tries are generated from")
+	verifyConformanceCmd.Flags().IntVar(&verifyFlagVectors, "vectors", testimonium.CONFORMANCE_VECTORS, "number of random blocks whose tries are generated")
+}
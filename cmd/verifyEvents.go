@@ -0,0 +1,94 @@
+// This file contains logic executed if the command "verify events" is typed in.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pantos-io/go-ethrelay/testimonium"
+	"github.com/spf13/cobra"
+)
+
+var verifyEventsFlagContract string
+var verifyEventsFlagEvent string
+var verifyEventsFlagFromBlock uint64
+var verifyEventsFlagConfirmations uint8
+var verifyEventsFlagPollInterval time.Duration
+
+// verifyEventsCmd represents the command 'verify events'
+var verifyEventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Continuously verifies the events of a contract",
+	Long: `Watches the specified contract on the source chain for events and verifies the receipt of every transaction
+emitting such an event on the verifying chain, as soon as the event's block is stored in the relay with the required
+number of confirmations. Blocks of the source chain are only scanned once they have the same number of
+confirmations; events whose transaction moves to another block in a reorganisation wait for the new block. The
+command runs until it is interrupted.
+
+Events are selected by their signature, e.g., --event "Transfer(address,address,uint256)". Without --event, all
+events of the contract are verified.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !common.IsHexAddress(verifyEventsFlagContract) {
+			log.Fatalf("Illegal contract address '%s'", verifyEventsFlagContract)
+		}
+
+		watch := testimonium.EventWatch{
+			Contract:      common.HexToAddress(verifyEventsFlagContract),
+			FromBlock:     verifyEventsFlagFromBlock,
+			Confirmations: verifyEventsFlagConfirmations,
+			PollInterval:  verifyEventsFlagPollInterval,
+		}
+		if verifyEventsFlagEvent != "" {
+			watch.Topics = [][]common.Hash{{crypto.Keccak256Hash([]byte(verifyEventsFlagEvent))}}
+		}
+
+		ctx, cancel := interruptibleContext(0)
+		defer cancel()
+
+		testimoniumClient = createTestimoniumClient(verifyClientOptions()...)
+		connectChains()
+		err := testimoniumClient.WatchAndVerifyEvents(ctx, watch, verifyFlagSrcChain, verifyFlagDestChain,
+			func(event testimonium.VerifiedEvent, err error) {
+				result := eventVerificationResult{VerifiedEvent: event}
+				if err != nil {
+					result.Error = err.Error()
+				}
+				printResult(result)
+			})
+		if err != nil && err != context.Canceled {
+			log.Fatal(err)
+		}
+	},
+}
+
+type eventVerificationResult struct {
+	testimonium.VerifiedEvent
+	Error string `json:"error,omitempty"`
+}
+
+func (result eventVerificationResult) renderText(w io.Writer) {
+	if result.Error != "" {
+		fmt.Fprintf(w, "Verification of tx %s (block %d) failed: %s\n", result.TxHash.Hex(), result.BlockNumber, result.Error)
+		return
+	}
+	fmt.Fprintf(w, "Verified tx %s (block %d, %d events)\n", result.TxHash.Hex(), result.BlockNumber, len(result.LogIndexes))
+	txResult{TxResult: result.Result}.renderText(w)
+}
+
+func init() {
+	verifyCmd.AddCommand(verifyEventsCmd)
+
+	verifyEventsCmd.Flags().StringVar(&verifyEventsFlagContract, "contract", "", "address of the watched contract on the target chain")
+	verifyEventsCmd.Flags().StringVar(&verifyEventsFlagEvent, "event", "", "signature of the verified events (default: all events)")
+	verifyEventsCmd.Flags().Uint64Var(&verifyEventsFlagFromBlock, "from-block", 0, "first scanned block (default: latest block)")
+	verifyEventsCmd.Flags().Uint8VarP(&verifyEventsFlagConfirmations, "confirmations", "c", 4, "Number of block confirmations")
+	verifyEventsCmd.Flags().DurationVar(&verifyEventsFlagPollInterval, "poll-interval", 15*time.Second, "interval between two scans of the target chain")
+	verifyEventsCmd.MarkFlagRequired("contract")
+}
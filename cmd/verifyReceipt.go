@@ -5,12 +5,17 @@ package cmd
 
 import (
 	"log"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/pantos-io/go-ethrelay/testimonium"
 	"github.com/spf13/cobra"
 )
 
+var verifyReceiptFlagContract string
+var verifyReceiptFlagEvent string
+
 // verifyReceiptCmd represents the receipt command
 var verifyReceiptCmd = &cobra.Command{
 	Use:   "receipt [txHash]",
@@ -19,26 +24,52 @@ var verifyReceiptCmd = &cobra.Command{
 
 Behind the scene, the command queries the receipt with the specified hash ('txHash') from the source chain.
 It then generates a Merkle Proof contesting the existence of the receipt within a specific block.
-This information gets sent to the verifying chain, where not only the existence of the block but also the Merkle Proof are verified`,
-	Aliases: []string{"tx"},
-	Args:    cobra.ExactArgs(1),
+This information gets sent to the verifying chain, where not only the existence of the block but also the Merkle Proof are verified
+
+Like 'verify transaction', the command waits until the block and its confirmation blocks are relayed (at most --wait,
+or submits them itself with --backfill) before the verification is sent.
+
+If the verification is supposed to prove an event, --contract and/or --event (the event signature, e.g.,
+"Transfer(address,address,uint256)") select it. The logs blooms of the block and the receipt are checked first and the
+command fails fast if the event cannot be in the block, before any proof is built or fee is paid.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		txHash := common.HexToHash(args[0])
 
-		testimoniumClient = createTestimoniumClient()
+		testimoniumClient = createTestimoniumClient(verifyClientOptions()...)
+
+		if verifyReceiptFlagContract != "" || verifyReceiptFlagEvent != "" {
+			var filter testimonium.EventFilter
+			if verifyReceiptFlagContract != "" {
+				if !common.IsHexAddress(verifyReceiptFlagContract) {
+					log.Fatalf("Illegal contract address '%s'", verifyReceiptFlagContract)
+				}
+				contract := common.HexToAddress(verifyReceiptFlagContract)
+				filter.Contract = &contract
+			}
+			if verifyReceiptFlagEvent != "" {
+				filter.Topics = []common.Hash{crypto.Keccak256Hash([]byte(verifyReceiptFlagEvent))}
+			}
+			if err := testimoniumClient.CheckEventBloom(txHash, filter, verifyFlagSrcChain); err != nil {
+				log.Fatal(err)
+			}
+		}
 
-		rlpHeader, rlpEncodedReceipt, path, rlpEncodedProofNodes, err := testimoniumClient.GenerateMerkleProofForReceipt(txHash, verifyFlagSrcChain)
-		if err != nil {
-			log.Fatal("Failed to generate Merkle Proof: " + err.Error())
+		if verifyFlagBackfill {
+			verifyWithBackfill(txHash, testimonium.VALUE_TYPE_RECEIPT)
+			return
 		}
 
-		feesInWei, err := testimoniumClient.GetRequiredVerificationFee(verifyFlagDestChain)
-		if err != nil {
-			log.Fatal(err)
+		if verifyFlagRoot != "" {
+			proof, _, err := testimoniumClient.BuildReceiptProof(txHash, verifyFlagSrcChain)
+			if err != nil {
+				log.Fatal("Failed to generate Merkle Proof: " + err.Error())
+			}
+			verifyAgainstRoot(testimonium.VALUE_TYPE_RECEIPT, proof)
+			return
 		}
 
-		testimoniumClient.VerifyMerkleProof(feesInWei, rlpHeader, testimonium.VALUE_TYPE_RECEIPT, rlpEncodedReceipt, path,
-			rlpEncodedProofNodes, noOfConfirmations, verifyFlagDestChain)
+		verifyAfterRelay(txHash, testimonium.VALUE_TYPE_RECEIPT)
 	},
 }
 
@@ -54,4 +85,10 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	verifyReceiptCmd.Flags().Uint8VarP(&noOfConfirmations, "confirmations", "c", 4, "Number of block confirmations")
+	verifyReceiptCmd.Flags().BoolVar(&verifyFlagBackfill, "backfill", false, "submit the missing headers of the block and its confirmation blocks before the verification")
+	verifyReceiptCmd.Flags().IntVar(&verifyFlagMaxHeaders, "max-headers", 256, "maximum number of headers submitted with --backfill")
+	verifyReceiptCmd.Flags().DurationVar(&verifyFlagWait, "wait", 30*time.Minute, "maximum time to wait until the block and its confirmation blocks are relayed (without --backfill)")
+	verifyReceiptCmd.Flags().StringVar(&verifyReceiptFlagContract, "contract", "", "contract the event to prove was emitted by, checked against the logs bloom before the verification")
+	verifyReceiptCmd.Flags().StringVar(&verifyReceiptFlagEvent, "event", "", "signature of the event to prove, checked against the logs bloom before the verification")
+	verifyReceiptCmd.Flags().StringVar(&verifyFlagRoot, "root", "", "verify against this trusted root stored in the contract instead of a submitted block header")
 }
@@ -11,9 +11,11 @@ import (
 	"github.com/spf13/cobra"
 	"log"
 	"os"
+	"time"
 )
 
 var noOfConfirmations uint8
+var verifyFlagMeta bool
 
 // verifyTransactionCmd represents the transaction command
 var verifyTransactionCmd = &cobra.Command{
@@ -23,40 +25,58 @@ var verifyTransactionCmd = &cobra.Command{
 
 Behind the scene, the command queries the transaction with the specified hash ('txHash') from the target chain.
 It then generates a Merkle Proof contesting the existence of the transaction within a specific block.
-This information gets sent to the verifying chain, where not only the existence of the block but also the Merkle Proof are verified`,
+This information gets sent to the verifying chain, where not only the existence of the block but also the Merkle Proof are verified
+
+The command performs the whole workflow: it checks that the transaction is mined, waits until its block has the
+requested confirmations on the target chain and until the block and its confirmation blocks are relayed to the
+verifying chain (at most --wait, or submits them itself with --backfill), pays the verification fee and reports the
+result, e.g., 'verify tx 0x... --src 0 --dest 1'.
+
+With --meta, the receipt of the transaction is verified in the same job, so the result also tells whether the
+transaction succeeded and the gas it used.`,
 	Aliases: []string{"tx"},
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		txHash := common.HexToHash(args[0])
 
-		testimoniumClient = createTestimoniumClient()
-
-		rlpHeader, rlpEncodedTx, path, rlpEncodedProofNodes, err := testimoniumClient.GenerateMerkleProofForTx(txHash, verifyFlagSrcChain)
-		if err != nil {
-			log.Fatal("Failed to generate Merkle Proof: " + err.Error())
-		}
+		testimoniumClient = createTestimoniumClient(verifyClientOptions()...)
 
-		// TODO: this produces a merkle proof for the transaction and does not verify the transaction
-		//  maybe it is better to introduce a new command for this behaviour as it is quite confusing to
-		//  call verifyTransaction and no transaction is verified
 		if jsonFlag {
+			// only the Merkle proof is written to a file, no transaction is verified
+			rlpHeader, rlpEncodedTx, path, rlpEncodedProofNodes, err := testimoniumClient.GenerateMerkleProofForTx(txHash, verifyFlagSrcChain)
+			if err != nil {
+				log.Fatal("Failed to generate Merkle Proof: " + err.Error())
+			}
+
 			hexEncodedTxHash := make([]byte, hex.EncodedLen(len(txHash)))
 			hex.Encode(hexEncodedTxHash, txHash[:])
 
 			writeMerkleProofAsJson(hexEncodedTxHash, rlpHeader, rlpEncodedTx, path, rlpEncodedProofNodes)
 
-			fmt.Printf("Wrote merkle proof to 0x%s.json\n", hexEncodedTxHash)
+			printResult(txResult{Message: fmt.Sprintf("Wrote merkle proof to 0x%s.json", hexEncodedTxHash)})
+			return
+		}
 
+		if verifyFlagMeta {
+			verifyTransactionWithMeta(txHash)
 			return
 		}
 
-		feesInWei, err := testimoniumClient.GetRequiredVerificationFee(verifyFlagDestChain)
-		if err != nil {
-			log.Fatal(err)
+		if verifyFlagBackfill {
+			verifyWithBackfill(txHash, testimonium.VALUE_TYPE_TRANSACTION)
+			return
+		}
+
+		if verifyFlagRoot != "" {
+			proof, _, err := testimoniumClient.BuildTxProof(txHash, verifyFlagSrcChain)
+			if err != nil {
+				log.Fatal("Failed to generate Merkle Proof: " + err.Error())
+			}
+			verifyAgainstRoot(testimonium.VALUE_TYPE_TRANSACTION, proof)
+			return
 		}
 
-		testimoniumClient.VerifyMerkleProof(feesInWei, rlpHeader, testimonium.VALUE_TYPE_TRANSACTION, rlpEncodedTx, path,
-			rlpEncodedProofNodes, noOfConfirmations, verifyFlagDestChain)
+		verifyAfterRelay(txHash, testimonium.VALUE_TYPE_TRANSACTION)
 	},
 }
 
@@ -105,5 +125,10 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	verifyTransactionCmd.Flags().Uint8VarP(&noOfConfirmations, "confirmations", "c", 4, "Number of block confirmations")
+	verifyTransactionCmd.Flags().BoolVar(&verifyFlagBackfill, "backfill", false, "submit the missing headers of the block and its confirmation blocks before the verification")
+	verifyTransactionCmd.Flags().IntVar(&verifyFlagMaxHeaders, "max-headers", 256, "maximum number of headers submitted with --backfill")
+	verifyTransactionCmd.Flags().DurationVar(&verifyFlagWait, "wait", 30*time.Minute, "maximum time to wait until the block and its confirmation blocks are relayed (without --backfill)")
+	verifyTransactionCmd.Flags().StringVar(&verifyFlagRoot, "root", "", "verify against this trusted root stored in the contract instead of a submitted block header")
+	verifyTransactionCmd.Flags().BoolVar(&verifyFlagMeta, "meta", false, "also verify the receipt of the transaction to report its status and gas used")
 	verifyTransactionCmd.Flags().BoolVar(&jsonFlag, "json", false, "save merkle proof to a json file")
 }
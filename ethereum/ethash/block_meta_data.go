@@ -67,7 +67,7 @@ func ProcessAllDuringRead(
 		}
 		fmt.Println("before write")
 		for _, metaData := range metaDataArray {
-			metaData.DagTree.Insert(typedefs.Word(buf), i)
+			metaData.DagTree.Insert(littleEndianWord(buf), i)
 		}
 		fmt.Println("after write")
 		if err != nil && err != io.EOF {
@@ -113,7 +113,7 @@ func ProcessDuringRead(
 		if n != 128 {
 			log.Fatal("Malformed dataset")
 		}
-		mt.Insert(typedefs.Word(buf), i)
+		mt.Insert(littleEndianWord(buf), i)
 		if err != nil && err != io.EOF {
 			log.Fatal(err)
 		}
@@ -202,7 +202,34 @@ func BuildDagTrees(metaDataArray []*BlockMetaData) {
 	fmt.Println("step 6")
 }
 
+// buildDagTree builds the DAG tree with the DAG of the shared cache, the DAG file is streamed if the cache fails
 func (s *BlockMetaData) buildDagTree() {
+	if err := s.buildDagTreeWithCache(SharedDAGCache()); err != nil {
+		fmt.Printf("DAG cache failed (%s), reading the DAG file...\n", err)
+		MakeDAG(s.blockNumber, DefaultDir)
+		s.streamDagTree()
+	}
+}
+
+// buildDagTreeWithCache builds the DAG tree from the dataset of the cache or, if it does not fit into the cache, from
+// the DAG file
+func (s *BlockMetaData) buildDagTreeWithCache(cache *DAGCache) error {
+	dataset, release, err := cache.Acquire(s.blockNumber)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if dataset != nil {
+		s.buildDagTreeFromDataset(dataset)
+	} else {
+		// the dataset does not fit into memory, stream it from disk
+		s.streamDagTree()
+	}
+	return nil
+}
+
+// streamDagTree builds the DAG tree while reading the DAG file, which has to exist
+func (s *BlockMetaData) streamDagTree() {
 	indices := Instance.GetVerificationIndices(
 		s.blockNumber,
 		s.hashNoNonce,
@@ -211,7 +238,6 @@ func (s *BlockMetaData) buildDagTree() {
 	fmt.Printf("indices: %v\n", indices)
 	s.DagTree = mtree.NewDagTree()
 	s.DagTree.RegisterIndex(indices...)
-	MakeDAG(s.blockNumber, DefaultDir)
 	fullSize := DAGSize(s.blockNumber)
 	fullSizeIn128Resolution := fullSize / 128
 	branchDepth := len(fmt.Sprintf("%b", fullSizeIn128Resolution-1))
@@ -0,0 +1,383 @@
+// This file contains a memory and disk bounded cache of DAG datasets shared by concurrent DAG tree constructions,
+// e.g., of the witnesses of disputes and of the epoch data.
+
+package ethash
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pantos-io/go-ethrelay/mtree"
+)
+
+// size of the magic number at the beginning of a DAG file
+const dagMagicSize = 8
+
+// DAGCache keeps the datasets of recently used epochs in memory, so the DAG trees of several blocks of the same epoch
+// can be built concurrently without reading the DAG file once per block. The total size of the cached datasets never
+// exceeds the configured number of bytes; datasets that do not fit are read from disk instead. Optionally, the DAG
+// files on disk are limited as well, the least recently used files of epochs not in use are deleted after a DAG was
+// generated.
+type DAGCache struct {
+	dir string
+
+	lock         sync.Mutex
+	maxBytes     uint64
+	maxDiskBytes uint64 // 0 for no limit
+	used         uint64
+	entries      map[uint64]*dagEntry
+	order        []uint64       // epochs of the cached datasets, least recently used first
+	inUse        map[uint64]int // acquisitions of the epochs that were not released yet
+	making       map[uint64]*sync.Mutex
+	stats        DAGCacheStats
+}
+
+type dagEntry struct {
+	data  []byte
+	size  uint64
+	refs  int
+	ready chan struct{}
+	err   error
+}
+
+// DAGCacheStats are the metrics of a DAG cache.
+type DAGCacheStats struct {
+	MemoryLimit     uint64 `json:"memoryLimit"` // bytes
+	MemoryUsed      uint64 `json:"memoryUsed"`
+	DiskLimit       uint64 `json:"diskLimit"` // bytes, 0 for no limit
+	Datasets        int    `json:"datasets"`  // datasets held in memory
+	Hits            uint64 `json:"hits"`      // acquisitions served by a dataset in memory
+	Misses          uint64 `json:"misses"`    // acquisitions loading the dataset into memory
+	Streamed        uint64 `json:"streamed"`  // acquisitions of datasets not fitting into memory
+	Generated       uint64 `json:"generated"` // DAG files generated
+	MemoryEvictions uint64 `json:"memoryEvictions"`
+	DiskEvictions   uint64 `json:"diskEvictions"` // DAG files deleted
+}
+
+var sharedDAGCache = NewDAGCache(0)
+
+// SharedDAGCache returns the cache shared by all DAG computations of the process that are not given a cache
+// explicitly. It holds no datasets in memory until its limits are set with SetLimits.
+func SharedDAGCache() *DAGCache {
+	return sharedDAGCache
+}
+
+// NewDAGCache creates a cache holding at most maxBytes of datasets in memory. A cache with maxBytes 0 only makes sure
+// that the DAG of each epoch is generated once.
+func NewDAGCache(maxBytes uint64) *DAGCache {
+	return &DAGCache{
+		dir:      DefaultDir,
+		maxBytes: maxBytes,
+		entries:  make(map[uint64]*dagEntry),
+		inUse:    make(map[uint64]int),
+		making:   make(map[uint64]*sync.Mutex),
+	}
+}
+
+// SetLimits changes the bytes of datasets held in memory and the bytes of the DAG files kept on disk (0 for no
+// limit). Unused datasets exceeding the memory limit are dropped right away, the DAG files when the next DAG is
+// generated.
+func (c *DAGCache) SetLimits(maxBytes uint64, maxDiskBytes uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.maxBytes = maxBytes
+	c.maxDiskBytes = maxDiskBytes
+	c.evict(0)
+}
+
+// Stats returns the current metrics of the cache.
+func (c *DAGCache) Stats() DAGCacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	stats := c.stats
+	stats.MemoryLimit = c.maxBytes
+	stats.MemoryUsed = c.used
+	stats.DiskLimit = c.maxDiskBytes
+	stats.Datasets = len(c.entries)
+	return stats
+}
+
+// Acquire generates the DAG of the block's epoch if necessary and returns its dataset (without the magic number).
+// If the dataset does not fit into the cache, nil is returned and the caller has to read the DAG file itself.
+// The returned function has to be called once the dataset (or the DAG file) is no longer used.
+func (c *DAGCache) Acquire(blockNumber uint64) ([]byte, func(), error) {
+	epoch := blockNumber / epochLength
+
+	c.lock.Lock()
+	c.inUse[epoch]++
+	c.lock.Unlock()
+	var once sync.Once
+	done := func() {
+		once.Do(func() {
+			c.lock.Lock()
+			c.inUse[epoch]--
+			if c.inUse[epoch] == 0 {
+				delete(c.inUse, epoch)
+			}
+			c.lock.Unlock()
+		})
+	}
+
+	if err := c.makeDAG(blockNumber); err != nil {
+		done()
+		return nil, func() {}, err
+	}
+
+	size := DAGSize(blockNumber)
+
+	c.lock.Lock()
+	entry, exists := c.entries[epoch]
+	if !exists {
+		if !c.reserve(size) {
+			c.stats.Streamed++
+			c.lock.Unlock()
+			return nil, done, nil
+		}
+		c.stats.Misses++
+		entry = &dagEntry{size: size, ready: make(chan struct{})}
+		c.entries[epoch] = entry
+		go c.load(epoch, entry)
+	} else {
+		c.stats.Hits++
+	}
+	entry.refs++
+	c.touch(epoch)
+	c.lock.Unlock()
+
+	<-entry.ready
+
+	release := func() {
+		c.lock.Lock()
+		entry.refs--
+		c.lock.Unlock()
+		done()
+	}
+	if entry.err != nil {
+		release()
+		c.lock.Lock()
+		if c.entries[epoch] == entry {
+			c.remove(epoch)
+		}
+		c.lock.Unlock()
+		return nil, func() {}, entry.err
+	}
+	return entry.data, release, nil
+}
+
+// makeDAG generates the DAG of the block's epoch unless its file exists, concurrent acquisitions of the epoch wait
+// for a single generation. The access time of the file is updated for the eviction of DAG files.
+func (c *DAGCache) makeDAG(blockNumber uint64) error {
+	epoch := blockNumber / epochLength
+
+	c.lock.Lock()
+	making, exists := c.making[epoch]
+	if !exists {
+		making = new(sync.Mutex)
+		c.making[epoch] = making
+	}
+	c.lock.Unlock()
+
+	making.Lock()
+	defer making.Unlock()
+
+	path := PathToDAG(epoch, c.dir)
+	if _, err := os.Stat(path); err == nil {
+		now := time.Now()
+		os.Chtimes(path, now, now)
+		return nil
+	}
+	MakeDAG(blockNumber, c.dir)
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("DAG of epoch %d was not generated: %s", epoch, err)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.stats.Generated++
+	return c.trimDisk()
+}
+
+// trimDisk deletes the least recently used DAG files of epochs that are not in use until the disk limit is met, it
+// has to be called with the lock held
+func (c *DAGCache) trimDisk() error {
+	if c.maxDiskBytes == 0 {
+		return nil
+	}
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	inUse := make(map[string]bool)
+	for epoch := range c.inUse {
+		inUse[filepath.Base(PathToDAG(epoch, c.dir))] = true
+	}
+
+	var dags []os.FileInfo
+	var total uint64
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), fmt.Sprintf("full-R%d-", algorithmRevision)) {
+			continue
+		}
+		dags = append(dags, file)
+		total += uint64(file.Size())
+	}
+	sort.Slice(dags, func(i, j int) bool { return dags[i].ModTime().Before(dags[j].ModTime()) })
+	for _, file := range dags {
+		if total <= c.maxDiskBytes {
+			break
+		}
+		if inUse[file.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, file.Name())); err != nil {
+			return err
+		}
+		total -= uint64(file.Size())
+		c.stats.DiskEvictions++
+	}
+	return nil
+}
+
+// reserve evicts unused datasets until size bytes are available, it has to be called with the lock held
+func (c *DAGCache) reserve(size uint64) bool {
+	if size > c.maxBytes {
+		return false
+	}
+	c.evict(size)
+	if c.used+size > c.maxBytes {
+		return false
+	}
+	c.used += size
+	return true
+}
+
+// evict drops unused datasets, least recently used first, until size bytes are available or no unused dataset is
+// left, it has to be called with the lock held
+func (c *DAGCache) evict(size uint64) {
+	for i := 0; c.used+size > c.maxBytes && i < len(c.order); {
+		if c.entries[c.order[i]].refs > 0 {
+			i++
+			continue
+		}
+		c.remove(c.order[i])
+		c.stats.MemoryEvictions++
+	}
+}
+
+// touch marks the epoch as most recently used, it has to be called with the lock held
+func (c *DAGCache) touch(epoch uint64) {
+	for i, e := range c.order {
+		if e == epoch {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+	c.order = append(c.order, epoch)
+}
+
+// remove drops the dataset of the epoch, it has to be called with the lock held
+func (c *DAGCache) remove(epoch uint64) {
+	entry, exists := c.entries[epoch]
+	if !exists {
+		return
+	}
+	delete(c.entries, epoch)
+	c.used -= entry.size
+	for i, e := range c.order {
+		if e == epoch {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (c *DAGCache) load(epoch uint64, entry *dagEntry) {
+	defer close(entry.ready)
+
+	f, err := os.Open(PathToDAG(epoch, c.dir))
+	if err != nil {
+		entry.err = err
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(dagMagicSize, io.SeekStart); err != nil {
+		entry.err = err
+		return
+	}
+	entry.data = make([]byte, entry.size)
+	if _, err := io.ReadFull(f, entry.data); err != nil {
+		entry.data = nil
+		entry.err = fmt.Errorf("malformed DAG file of epoch %d: %s", epoch, err)
+	}
+}
+
+// buildDagTreeFromDataset builds the DAG tree from a dataset held in memory
+func (s *BlockMetaData) buildDagTreeFromDataset(dataset []byte) {
+	indices := Instance.GetVerificationIndices(
+		s.blockNumber,
+		s.hashNoNonce,
+		s.nonce,
+	)
+	s.DagTree = mtree.NewDagTree()
+	s.DagTree.RegisterIndex(indices...)
+	fullSizeIn128Resolution := uint64(len(dataset)) / 128
+	branchDepth := len(fmt.Sprintf("%b", fullSizeIn128Resolution-1))
+	s.DagTree.RegisterStoredLevel(uint32(branchDepth), uint32(10))
+
+	buf := [128]byte{}
+	for i := uint64(0); i < fullSizeIn128Resolution; i++ {
+		copy(buf[:], dataset[i*128:(i+1)*128])
+		s.DagTree.Insert(littleEndianWord(buf), uint32(i))
+	}
+	s.DagTree.Finalize()
+}
+
+// BuildDagTreesParallel builds the DAG trees of blocks of arbitrary epochs using at most workers goroutines. The DAG of
+// each epoch is generated once and shared via the cache, so afterwards DAGElementArray and DAGProofArray of all blocks
+// return immediately. The first error stops the construction of the remaining trees.
+func BuildDagTreesParallel(metaDataArray []*BlockMetaData, cache *DAGCache, workers int) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan *BlockMetaData)
+	errs := make(chan error, len(metaDataArray))
+	var wg sync.WaitGroup
+	var failed sync.Once
+	done := make(chan struct{})
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range jobs {
+				if err := s.buildDagTreeWithCache(cache); err != nil {
+					errs <- fmt.Errorf("block %d: %s", s.blockNumber, err)
+					failed.Do(func() { close(done) })
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, s := range metaDataArray {
+		select {
+		case jobs <- s:
+		case <-done:
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
@@ -96,11 +96,12 @@ func memoryMapFile(file *os.File, write bool) (mmap.MMap, []uint32, error) {
 		return nil, nil, err
 	}
 	// Yay, we managed to memory map the file, here be dragons
-	header := *(*reflect.SliceHeader)(unsafe.Pointer(&mem))
-	header.Len /= 4
-	header.Cap /= 4
-
-	return mem, *(*[]uint32)(unsafe.Pointer(&header)), nil
+	var view []uint32
+	header := (*reflect.SliceHeader)(unsafe.Pointer(&view))
+	header.Data = (*reflect.SliceHeader)(unsafe.Pointer(&mem)).Data
+	header.Cap = len(mem) / 4
+	header.Len = header.Cap
+	return mem, view, nil
 }
 
 // memoryMapAndGenerate tries to memory map a temporary file of uint32s for write
@@ -324,7 +325,7 @@ func MakeCache(block uint64, dir string) {
 
 // MakeDataset generates a new ethash dataset and optionally stores it to disk.
 func MakeDataset(block uint64, dir string) {
-	d := dataset{epoch: block/epochLength + 1}
+	d := dataset{epoch: block / epochLength}
 	d.generate(dir, math.MaxInt32, false)
 	d.release()
 }
@@ -608,8 +609,7 @@ func SeedHash(block uint64) []byte {
 }
 
 func GenerateEpochData(epoch uint64) typedefs.EpochData {
-	fmt.Println("Checking DAG file. Generate if needed...\n")
-	MakeDAG(uint64(epoch*30000), DefaultDir)
+	fmt.Println("Checking DAG file. Generate if needed...")
 	fullSize := DAGSize(uint64(epoch * 30000))
 	fullSizeIn128Resolution := fullSize / 128
 	path := PathToDAG(uint64(epoch), DefaultDir)
@@ -617,7 +617,21 @@ func GenerateEpochData(epoch uint64) typedefs.EpochData {
 	mt := mtree.NewDagTree()
 	// TODO: 10 is just an experimental level
 	mt.RegisterStoredLevel(uint32(branchDepth), 10)
-	ProcessDuringRead(path, mt)
+	dataset, release, err := SharedDAGCache().Acquire(uint64(epoch * 30000))
+	if err != nil {
+		fmt.Printf("DAG cache failed (%s), reading the DAG file...\n", err)
+		MakeDAG(uint64(epoch*30000), DefaultDir)
+	}
+	if dataset != nil {
+		buf := [128]byte{}
+		for i := uint64(0); i < fullSizeIn128Resolution; i++ {
+			copy(buf[:], dataset[i*128:(i+1)*128])
+			mt.Insert(littleEndianWord(buf), uint32(i))
+		}
+	} else {
+		ProcessDuringRead(path, mt)
+	}
+	release()
 	mt.Finalize()
 
 	fmt.Printf("Done.\n")
@@ -91,7 +91,7 @@ func hashimotoIndices(hash []byte, nonce uint64, size uint64, lookup func(index
 }
 
 func MakeDAG(block uint64, dir string) {
-	MakeDataset(block-30000, dir)
+	MakeDataset(block, dir)
 }
 
 func PathToDAG(epoch uint64, dir string) string {
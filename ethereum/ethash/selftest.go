@@ -0,0 +1,544 @@
+// This file contains the self test of the local Ethash implementation. Generated caches, datasets and Merkle data are
+// compared with known-good values before epoch data or proofs are submitted, so a broken build (e.g., on a big endian
+// system) does not spend gas on data the contract rejects.
+
+package ethash
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"reflect"
+	"unsafe"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pantos-io/go-ethrelay/mtree"
+	"github.com/pantos-io/go-ethrelay/typedefs"
+)
+
+// number of Merkle tree levels whose nodes are stored in the contract (see GenerateEpochData)
+const storedLevels = 10
+
+// SelfTestCheck is the outcome of a single check of the self test.
+type SelfTestCheck struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// The cache, dataset and hashimoto vectors are the ones of go-ethereum's consensus/ethash tests (cache of 1024 bytes,
+// dataset of 32 KiB), the cache and dataset are given as Keccak-256 hash of their little endian encoding.
+var (
+	testCacheSize   = uint64(1024)
+	testDatasetSize = uint64(32 * 1024)
+
+	testCacheHashes = map[uint64]string{
+		0: "92591c70a0fb6058340313346356b789f333ae1e1eb20ae12e005ad5e922a2ac",
+		1: "3fd786490f65c89065edf3d2843b38ad674ceaeffa6952605ff1684c2c428c3a",
+	}
+	testDatasetHash = "29929d8f6b3dfd631e20b924c42ec55b818977b40a4a85146a6a920a5d51dcec"
+
+	testHashimotoHash   = "c9149cc0386e689d789a1c2f3d5d169a61a6218ed30e74414dc736e442ef3d1f"
+	testHashimotoDigest = "e4073cffaef931d37117cefd9afd27ea0f1cad6a981dd2605c4a1ac97c519800"
+	testHashimotoResult = "d3539235ee2e6f8db665c0a72169f55b7f6c605712330b778ec3944f0eb5a557"
+
+	// Merkle root of the test dataset, a regression value of the Merkle tree implementation
+	testDatasetMerkleRoot = "fe82100e5850b398e8bbf711fd502740"
+)
+
+// byteOrderWords is a DAG element whose little endian encoding (the byte order of the Merkle tree and the contract)
+// is byteOrderElement, byteOrderUint256 are the first two of its uint256 values as passed to the contract
+var (
+	byteOrderWords = [hashWords * 2]uint32{
+		0x03020100, 0x07060504, 0x0b0a0908, 0x0f0e0d0c, 0x13121110, 0x17161514, 0x1b1a1918, 0x1f1e1d1c,
+		0x23222120, 0x27262524, 0x2b2a2928, 0x2f2e2d2c, 0x33323130, 0x37363534, 0x3b3a3938, 0x3f3e3d3c,
+		0x43424140, 0x47464544, 0x4b4a4948, 0x4f4e4d4c, 0x53525150, 0x57565554, 0x5b5a5958, 0x5f5e5d5c,
+		0x63626160, 0x67666564, 0x6b6a6968, 0x6f6e6d6c, 0x73727170, 0x77767574, 0x7b7a7978, 0x7f7e7d7c,
+	}
+	byteOrderElement = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f" +
+		"202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f" +
+		"404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f" +
+		"606162636465666768696a6b6c6d6e6f707172737475767778797a7b7c7d7e7f"
+	byteOrderUint256 = []string{
+		"1f1e1d1c1b1a191817161514131211100f0e0d0c0b0a09080706050403020100",
+		"3f3e3d3c3b3a393837363534333231302f2e2d2c2b2a29282726252423222120",
+	}
+)
+
+// mainnetSeal is the seal of a mainnet block, its mix digest is only reproduced with the correct dataset of its epoch
+type mainnetSeal struct {
+	block     uint64
+	hash      string // hash of the block
+	sealHash  string // Keccak-256 hash of the header without mix digest and nonce
+	nonce     uint64
+	mixDigest string
+	result    string
+}
+
+// mainnetSeals are the seals of mainnet block 1 and of block 3311058 (the mainnet block of go-ethereum's ethash tests)
+var mainnetSeals = []mainnetSeal{
+	{
+		block:     1,
+		hash:      "88e96d4537bea4d9c05d12549907b32561d3bf31f45aae734cdc119f13406cb6",
+		sealHash:  "85913a3057ea8bec78cd916871ca73802e77724e014dda65add3405d02240eb7",
+		nonce:     0x539bd4979fef1ec4,
+		mixDigest: "969b900de27b6ac6a67742365dd65f55a0526c41fd18e1b16f1a1215c2e66f59",
+		result:    "000000002bc095dd4de049873e6302c3f14a7f2e5b5a1f60cdf1f1798164d610",
+	},
+	{
+		block:     3311058,
+		hash:      "ae174c5cf816f820b17ae13d120fff057406ce12f448422f0d522459d4ae646b",
+		sealHash:  "543e8c0c744afd5ca511d4080dc71bc8206c9e34d991bc06462b938e37cda38c",
+		nonce:     0xf400cd0006070c49,
+		mixDigest: "3e140b0784516af5e5ec6730f2fb20cca22f32be399b9e4ad77d32541f798cd0",
+		result:    "00000000000052bc7b8a4779368d8ff3ca84dcc99ac94d9863ae01ffcd52f11a",
+	},
+}
+
+// epochGolden contains the parameters of an epoch as taken from go-ethereum's size tables and seed hashes and the
+// Merkle root of its dataset if known.
+type epochGolden struct {
+	epoch                   uint64
+	seedHash                string
+	cacheSize               uint64
+	datasetSize             uint64
+	fullSizeIn128Resolution uint64
+	branchDepth             uint64
+	merkleRoot              string
+}
+
+var epochGoldens = []epochGolden{
+	// the dataset of epoch 0 (and thereby its Merkle root) reproduces the mix digest of mainnet block 1
+	{0, "0000000000000000000000000000000000000000000000000000000000000000", 16776896, 1073739904, 8388593, 13, "f34cafdb92d8626451414882183e1211"},
+	{1, "290decd9548b62a8d60345a988386fc84ba6bc95484008f6362f93160ef3e563", 16907456, 1082130304, 8454143, 14, ""},
+	{2, "510e4e770828ddbf7f7b00ab00a9f6adaf81c0dc9cc85f1f8249c256942d61d9", 17039296, 1090514816, 8519647, 14, ""},
+	{100, "71a56feffb6f10ea9d76e1a9464eb0abd86e4349ae98fb794923a65b650282a3", 29882816, 1912601216, 14942197, 14, ""},
+	// the dataset of epoch 110 reproduces the mix digest of mainnet block 3311058
+	{110, "1dca8a85e74aa76301699f50d3f66d17034fceb6c458d62fb48a2248dd8790b0", 31195072, 1996487552, 15597559, 14, "82f19ba287773720273e8a5eb12419b7"},
+	{200, "8308d376eeb469b7ff84bd59c51988d9618b208dc3b951d1dc1918fa08306723", 42991552, 2751462016, 21495797, 15, ""},
+	// the Merkle branches of the witnesses of mainnet blocks 8084510 to 8084611 in epoch-data.json lead to this root
+	{269, "955f33cf01218e21b8282ab6c8953e4e753331201bb84de43f8fe89e053f9cef", 52035136, 3330273152, 26017759, 15, "e06008fe459ec13887c84680187d4d6a"},
+	{300, "ca4e0bf72f4f439ec403a495c3da58229837edf966a9ca94db534de054eba34b", 56097856, 3590324096, 28049407, 15, ""},
+	{400, "312b6007e9a1bb08b78dad2afdce476501adb039bc29ea6c27ede523fe87d3d4", 69205568, 4429182848, 34602991, 16, ""},
+	{517, "71215a27714dd13bb1ad6861ba67c9607110f6dc3597f7d221b4b795f6b5d11d", 84540608, 5410650496, 42270707, 16, ""},
+}
+
+// SelfTest checks the local Ethash implementation with small test vectors and the parameters of several epochs. It
+// takes less than a second and needs no DAG file.
+func SelfTest() []SelfTestCheck {
+	var checks []SelfTestCheck
+
+	checks = append(checks, checkByteOrder(littleEndianWord))
+
+	for _, golden := range epochGoldens {
+		checks = append(checks, checkEpochParameters(golden))
+	}
+
+	var cache []uint32
+	for _, epoch := range []uint64{0, 1} {
+		cache = make([]uint32, testCacheSize/4)
+		generateCache(cache, epoch, seedHash(epoch*epochLength+1))
+		checks = append(checks, compareHash(fmt.Sprintf("cache of epoch %d", epoch),
+			crypto.Keccak256(littleEndianBytes(cache)), testCacheHashes[epoch]))
+	}
+
+	// the dataset and the hashimoto vectors are generated from the cache of epoch 0
+	generateCache(cache, 0, seedHash(1))
+	dataset := make([]uint32, testDatasetSize/4)
+	generateDataset(dataset, 0, cache)
+	checks = append(checks, compareHash("dataset", crypto.Keccak256(littleEndianBytes(dataset)), testDatasetHash))
+
+	hash := common.FromHex(testHashimotoHash)
+	digest, result := hashimotoLight(testDatasetSize, cache, hash, 0)
+	checks = append(checks, compareHash("hashimoto light digest", digest, testHashimotoDigest))
+	checks = append(checks, compareHash("hashimoto light result", result, testHashimotoResult))
+	digest, result = hashimotoFull(dataset, hash, 0)
+	checks = append(checks, compareHash("hashimoto full digest", digest, testHashimotoDigest))
+	checks = append(checks, compareHash("hashimoto full result", result, testHashimotoResult))
+
+	return append(checks, checkDatasetMerkleTree(dataset, hash)...)
+}
+
+// SelfTestEpoch generates the DAG of the epoch if necessary and checks the epoch data that would be submitted with
+// "submit epoch" against a Merkle tree computed independently from the DAG file. This takes as long as submitting the
+// epoch data without sending any transaction.
+func SelfTestEpoch(epoch uint64) []SelfTestCheck {
+	var checks []SelfTestCheck
+	name := fmt.Sprintf("epoch %d", epoch)
+
+	epochData := GenerateEpochData(epoch)
+	fullSizeIn128Resolution := DAGSize(epoch*epochLength) / mixBytes
+	depth := uint64(len(fmt.Sprintf("%b", fullSizeIn128Resolution-1)))
+
+	merkleRoot := ""
+	for _, golden := range epochGoldens {
+		if golden.epoch == epoch {
+			fullSizeIn128Resolution = golden.fullSizeIn128Resolution
+			depth = golden.branchDepth + storedLevels
+			merkleRoot = golden.merkleRoot
+		}
+	}
+	checks = append(checks,
+		compareUint(name+" full size in 128 resolution", epochData.FullSizeIn128Resolution.Uint64(), fullSizeIn128Resolution),
+		compareUint(name+" branch depth", epochData.BranchDepth.Uint64(), depth-storedLevels))
+
+	path := PathToDAG(epoch, DefaultDir)
+	for _, seal := range mainnetSeals {
+		if seal.block/epochLength == epoch {
+			checks = append(checks, checkMainnetSeal(path, seal))
+		}
+	}
+	nodes, err := dagMerkleNodes(path, fullSizeIn128Resolution, depth-storedLevels)
+	if err != nil {
+		return append(checks, SelfTestCheck{Name: name + " Merkle nodes", Detail: err.Error()})
+	}
+	check := compareMerkleNodes(name+" Merkle nodes", epochData.MerkleNodes, nodes)
+	root := merkleReduce(nodes, storedLevels)[0]
+	if check.Passed {
+		check.Detail = fmt.Sprintf("%d nodes, root %x", len(nodes), root)
+	}
+	checks = append(checks, check)
+	if merkleRoot != "" {
+		checks = append(checks, compareHash(name+" Merkle root (golden)", root[:], merkleRoot))
+	}
+	return checks
+}
+
+// checkMainnetSeal computes the seal of the mainnet block with the dataset of the DAG file of its epoch
+func checkMainnetSeal(path string, seal mainnetSeal) SelfTestCheck {
+	name := fmt.Sprintf("epoch %d seal of mainnet block %d", seal.block/epochLength, seal.block)
+	f, err := os.Open(path)
+	if err != nil {
+		return SelfTestCheck{Name: name, Detail: err.Error()}
+	}
+	defer f.Close()
+
+	// the DAG file is in machine byte order
+	order := binary.ByteOrder(binary.LittleEndian)
+	if !isLittleEndian() {
+		order = binary.BigEndian
+	}
+	item := make([]byte, hashBytes)
+	var readErr error
+	lookup := func(index uint32) []uint32 {
+		words := make([]uint32, hashWords)
+		if _, err := f.ReadAt(item, dagMagicSize+int64(index)*hashBytes); err != nil {
+			readErr = err
+		}
+		for i := range words {
+			words[i] = order.Uint32(item[4*i:])
+		}
+		return words
+	}
+	digest, result := hashimoto(common.FromHex(seal.sealHash), seal.nonce, datasetSize(seal.block), lookup)
+	if readErr != nil {
+		return SelfTestCheck{Name: name, Detail: fmt.Sprintf("malformed DAG file %s: %s", path, readErr)}
+	}
+	check := compareHash(name, digest, seal.mixDigest)
+	if check.Passed {
+		check = compareHash(name, result, seal.result)
+	}
+	return check
+}
+
+// checkByteOrder reads byteOrderWords in machine byte order like from a DAG file and converts it with wordOf (see
+// littleEndianWord), the word has to be the little endian encoding. The cache words are read back from it.
+func checkByteOrder(wordOf func([128]byte) typedefs.Word) SelfTestCheck {
+	check := SelfTestCheck{Name: "byte order"}
+	var element [128]byte
+	copy(element[:], machineBytes(byteOrderWords[:]))
+	word := wordOf(element)
+	if have := common.Bytes2Hex(word[:]); have != byteOrderElement {
+		check.Detail = fmt.Sprintf("DAG element read as %s... instead of %s... (little endian: %t)", have[:16],
+			byteOrderElement[:16], isLittleEndian())
+		return check
+	}
+	for i, value := range word.ToUint256Array()[:len(byteOrderUint256)] {
+		if have := common.Bytes2Hex(common.LeftPadBytes(value.Bytes(), 32)); have != byteOrderUint256[i] {
+			check.Detail = fmt.Sprintf("uint256 %d of the element is %s instead of %s", i, have, byteOrderUint256[i])
+			return check
+		}
+	}
+	var words [hashWords * 2]uint32
+	prepare(words[:], common.FromHex(byteOrderElement))
+	if words != byteOrderWords {
+		check.Detail = fmt.Sprintf("words read as %x instead of %x", words[:2], byteOrderWords[:2])
+		return check
+	}
+	check.Passed = true
+	check.Detail = fmt.Sprintf("little endian: %t", isLittleEndian())
+	return check
+}
+
+// littleEndianWord returns a word of a DAG file in the little endian byte order the Merkle tree and the contract
+// expect, DAG files of big endian systems (suffix .be) are in machine byte order.
+func littleEndianWord(buf [128]byte) typedefs.Word {
+	if !isLittleEndian() {
+		swap(buf[:])
+	}
+	return typedefs.Word(buf)
+}
+
+func checkEpochParameters(golden epochGolden) SelfTestCheck {
+	block := golden.epoch * epochLength
+	fullSizeIn128Resolution := DAGSize(block) / mixBytes
+	branchDepth := uint64(len(fmt.Sprintf("%b", fullSizeIn128Resolution-1))) - storedLevels
+
+	check := SelfTestCheck{Name: fmt.Sprintf("parameters of epoch %d", golden.epoch), Passed: true}
+	mismatch := func(field string, have, want interface{}) {
+		check.Passed = false
+		check.Detail += fmt.Sprintf("%s is %v instead of %v; ", field, have, want)
+	}
+	if seed := common.Bytes2Hex(seedHash(block + 1)); seed != golden.seedHash {
+		mismatch("seed hash", seed, golden.seedHash)
+	}
+	if size := cacheSize(block); size != golden.cacheSize {
+		mismatch("cache size", size, golden.cacheSize)
+	}
+	if size := DAGSize(block); size != golden.datasetSize {
+		mismatch("dataset size", size, golden.datasetSize)
+	}
+	if fullSizeIn128Resolution != golden.fullSizeIn128Resolution {
+		mismatch("full size in 128 resolution", fullSizeIn128Resolution, golden.fullSizeIn128Resolution)
+	}
+	if branchDepth != golden.branchDepth {
+		mismatch("branch depth", branchDepth, golden.branchDepth)
+	}
+	if check.Passed {
+		check.Detail = fmt.Sprintf("branch depth %d", branchDepth)
+	}
+	return check
+}
+
+// checkDatasetMerkleTree builds the Merkle tree of the test dataset the way proofs of blocks are built and compares
+// its root, stored nodes, branches and elements with an independent computation
+func checkDatasetMerkleTree(dataset []uint32, hash []byte) []SelfTestCheck {
+	var checks []SelfTestCheck
+
+	// the words are read in machine byte order like from a DAG file
+	raw := machineBytes(dataset)
+	elements := uint64(len(raw)) / mixBytes
+	words := make([]typedefs.Word, elements)
+	leaves := make([][16]byte, elements)
+	for i := range words {
+		var buf [128]byte
+		copy(buf[:], raw[i*mixBytes:])
+		words[i] = littleEndianWord(buf)
+		leaves[i] = merkleLeaf(words[i])
+	}
+
+	lookup := func(index uint32) []uint32 {
+		offset := index * hashWords
+		return dataset[offset : offset+hashWords]
+	}
+	indices := hashimotoIndices(hash, 0, testDatasetSize, lookup)
+
+	// with 256 elements, the branches of the two stored levels have 6 nodes
+	depth := uint64(len(fmt.Sprintf("%b", elements-1)))
+	storedLevel := uint64(2)
+	tree := mtree.NewDagTree()
+	tree.RegisterIndex(indices...)
+	tree.RegisterStoredLevel(uint32(depth), uint32(storedLevel))
+	for i, word := range words {
+		tree.Insert(word, uint32(i))
+	}
+	tree.Finalize()
+
+	root := merkleReduce(leaves, depth)[0]
+	rootHash := tree.RootHash()
+	checks = append(checks, compareHash("dataset Merkle root", rootHash[:], common.Bytes2Hex(root[:])))
+	checks = append(checks, compareHash("dataset Merkle root (golden)", root[:], testDatasetMerkleRoot))
+
+	nodes := merkleReduce(leaves, depth-storedLevel)
+	checks = append(checks, compareMerkleNodes("dataset Merkle nodes", tree.MerkleNodes(), nodes))
+	checks = append(checks, checkBranches(tree, indices, words, nodes, depth-storedLevel))
+
+	// a dataset whose size is not a power of two is padded with the last nodes of each level
+	padded := mtree.NewDagTree()
+	for i, word := range words[:elements*3/4] {
+		padded.Insert(word, uint32(i))
+	}
+	padded.Finalize()
+	root = merkleReduce(leaves[:elements*3/4], depth)[0]
+	rootHash = padded.RootHash()
+	checks = append(checks, compareHash("padded dataset Merkle root", rootHash[:], common.Bytes2Hex(root[:])))
+
+	return checks
+}
+
+// checkBranches verifies the branches of the indices (as submitted with a block) against the stored Merkle nodes
+func checkBranches(tree *mtree.DagTree, indices []uint32, words []typedefs.Word, nodes [][16]byte, branchDepth uint64) SelfTestCheck {
+	check := SelfTestCheck{Name: "dataset Merkle branches", Passed: true}
+	fail := func(format string, args ...interface{}) SelfTestCheck {
+		check.Passed = false
+		check.Detail = fmt.Sprintf(format, args...)
+		return check
+	}
+
+	elements := tree.AllDAGElements()
+	branches := tree.AllBranchesArray()
+	elementsPerBranch := int(branchDepth+1) / 2
+	if len(elements) != len(indices) || len(branches) != len(indices)*elementsPerBranch {
+		return fail("%d elements and %d branch elements for %d indices", len(elements), len(branches), len(indices))
+	}
+
+	for i, index := range indices {
+		if elements[i] != words[index] {
+			return fail("element of index %d differs from the dataset", index)
+		}
+
+		// each branch element contains two nodes, the lower one in the second half
+		var siblings [][16]byte
+		for _, element := range branches[i*elementsPerBranch : (i+1)*elementsPerBranch] {
+			var lower, upper [16]byte
+			copy(lower[:], element[16:])
+			copy(upper[:], element[:16])
+			siblings = append(siblings, lower, upper)
+		}
+
+		node := merkleLeaf(words[index])
+		for level := uint64(0); level < branchDepth; level++ {
+			if index>>level&1 == 0 {
+				node = merkleNode(node, siblings[level])
+			} else {
+				node = merkleNode(siblings[level], node)
+			}
+		}
+		if node != nodes[index>>branchDepth] {
+			return fail("branch of index %d does not lead to stored node %d", index, index>>branchDepth)
+		}
+	}
+	check.Detail = fmt.Sprintf("%d branches", len(indices))
+	return check
+}
+
+// dagMerkleNodes computes the stored Merkle nodes from the DAG file, each node is the root of a subtree with
+// 2^branchDepth elements
+func dagMerkleNodes(path string, fullSizeIn128Resolution uint64, branchDepth uint64) ([][16]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	if _, err := r.Discard(dagMagicSize); err != nil {
+		return nil, err
+	}
+
+	var nodes [][16]byte
+	subtree := make([][16]byte, 0, 1<<branchDepth)
+	buf := [128]byte{}
+	for i := uint64(0); i < fullSizeIn128Resolution; i++ {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, fmt.Errorf("malformed DAG file %s: %s", path, err)
+		}
+		subtree = append(subtree, merkleLeaf(littleEndianWord(buf)))
+		if uint64(len(subtree)) == 1<<branchDepth || i == fullSizeIn128Resolution-1 {
+			nodes = append(nodes, merkleReduce(subtree, branchDepth)[0])
+			subtree = subtree[:0]
+		}
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		return nil, fmt.Errorf("DAG file %s is larger than the dataset", path)
+	}
+	return nodes, nil
+}
+
+// compareMerkleNodes compares the Merkle nodes as passed to the contract (two nodes per element, the lower one in
+// the second half) with the expected nodes
+func compareMerkleNodes(name string, packed []*big.Int, nodes [][16]byte) SelfTestCheck {
+	check := SelfTestCheck{Name: name}
+	if len(packed) != (len(nodes)+1)/2 {
+		check.Detail = fmt.Sprintf("%d elements instead of %d", len(packed), (len(nodes)+1)/2)
+		return check
+	}
+	for i, element := range packed {
+		b := common.LeftPadBytes(element.Bytes(), 32)
+		if !bytes.Equal(b[16:], nodes[2*i][:]) || (2*i+1 < len(nodes) && !bytes.Equal(b[:16], nodes[2*i+1][:])) {
+			check.Detail = fmt.Sprintf("element %d is %x", i, b)
+			return check
+		}
+	}
+	check.Passed = true
+	check.Detail = fmt.Sprintf("%d nodes", len(nodes))
+	return check
+}
+
+// merkleReduce hashes the nodes of the given number of levels. Like the Merkle tree, the last node of a level with
+// an odd number of nodes is paired with itself.
+func merkleReduce(nodes [][16]byte, levels uint64) [][16]byte {
+	level := append([][16]byte{}, nodes...)
+	for l := uint64(0); l < levels; l++ {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([][16]byte, len(level)/2)
+		for i := range next {
+			next[i] = merkleNode(level[2*i], level[2*i+1])
+		}
+		level = next
+	}
+	return level
+}
+
+// merkleLeaf hashes an element like the contract, each 32 bytes of the word are reversed
+func merkleLeaf(word typedefs.Word) [16]byte {
+	data := make([]byte, 0, len(word))
+	for i := 0; i < len(word); i += 32 {
+		for j := i + 31; j >= i; j-- {
+			data = append(data, word[j])
+		}
+	}
+	var node [16]byte
+	copy(node[:], crypto.Keccak256(data)[16:])
+	return node
+}
+
+// merkleNode hashes two nodes like the contract, each node is padded to 32 bytes
+func merkleNode(left, right [16]byte) [16]byte {
+	data := make([]byte, 64)
+	copy(data[16:32], left[:])
+	copy(data[48:], right[:])
+	var node [16]byte
+	copy(node[:], crypto.Keccak256(data)[16:])
+	return node
+}
+
+func compareHash(name string, have []byte, want string) SelfTestCheck {
+	if common.Bytes2Hex(have) != want {
+		return SelfTestCheck{Name: name, Detail: fmt.Sprintf("%x instead of %s", have, want)}
+	}
+	return SelfTestCheck{Name: name, Passed: true}
+}
+
+func compareUint(name string, have, want uint64) SelfTestCheck {
+	if have != want {
+		return SelfTestCheck{Name: name, Detail: fmt.Sprintf("%d instead of %d", have, want)}
+	}
+	return SelfTestCheck{Name: name, Passed: true, Detail: fmt.Sprintf("%d", have)}
+}
+
+func littleEndianBytes(data []uint32) []byte {
+	b := make([]byte, len(data)*4)
+	for i, word := range data {
+		binary.LittleEndian.PutUint32(b[i*4:], word)
+	}
+	return b
+}
+
+// machineBytes returns the data in machine byte order, as it is written to cache and DAG files
+func machineBytes(data []uint32) []byte {
+	var b []byte
+	bHdr := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	dataHdr := (*reflect.SliceHeader)(unsafe.Pointer(&data))
+	bHdr.Data = dataHdr.Data
+	bHdr.Len = dataHdr.Len * 4
+	bHdr.Cap = dataHdr.Cap * 4
+	return b
+}
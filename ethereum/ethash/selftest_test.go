@@ -0,0 +1,148 @@
+package ethash
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/pantos-io/go-ethrelay/typedefs"
+)
+
+func TestSelfTest(t *testing.T) {
+	for _, check := range SelfTest() {
+		if !check.Passed {
+			t.Errorf("%s: %s", check.Name, check.Detail)
+		}
+	}
+}
+
+// TestByteOrderCheck fails the byte order check with a conversion of DAG elements that swaps the bytes of the
+// wrong byte order
+func TestByteOrderCheck(t *testing.T) {
+	if check := checkByteOrder(littleEndianWord); !check.Passed {
+		t.Errorf("byte order: %s", check.Detail)
+	}
+	swapped := func(buf [128]byte) typedefs.Word {
+		if isLittleEndian() {
+			swap(buf[:])
+		}
+		return typedefs.Word(buf)
+	}
+	if check := checkByteOrder(swapped); check.Passed {
+		t.Error("swapped DAG element accepted")
+	}
+}
+
+// mainnetHeaders are the headers of the blocks of mainnetSeals
+var mainnetHeaders = map[uint64]*types.Header{
+	1: {
+		ParentHash:  common.HexToHash("0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3"),
+		UncleHash:   types.EmptyUncleHash,
+		Coinbase:    common.HexToAddress("0x05a56e2d52c817161883f50c441c3228cfe54d9f"),
+		Root:        common.HexToHash("0xd67e4d450343046425ae4271474353857ab860dbc0a1dde64b41b5cd3a532bf3"),
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: types.EmptyRootHash,
+		Difficulty:  big.NewInt(17171480576),
+		Number:      big.NewInt(1),
+		GasLimit:    5000,
+		Time:        1438269988,
+		Extra:       common.FromHex("0x476574682f76312e302e302f6c696e75782f676f312e342e32"),
+		MixDigest:   common.HexToHash("0x969b900de27b6ac6a67742365dd65f55a0526c41fd18e1b16f1a1215c2e66f59"),
+		Nonce:       types.EncodeNonce(0x539bd4979fef1ec4),
+	},
+	3311058: {
+		ParentHash:  common.HexToHash("0xd783efa4d392943503f28438ad5830b2d5964696ffc285f338585e9fe0a37a05"),
+		UncleHash:   types.EmptyUncleHash,
+		Coinbase:    common.HexToAddress("0xc0ea08a2d404d3172d2add29a45be56da40e2949"),
+		Root:        common.HexToHash("0x77d14e10470b5850332524f8cd6f69ad21f070ce92dca33ab2858300242ef2f1"),
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: types.EmptyRootHash,
+		Difficulty:  big.NewInt(167925187834220),
+		Number:      big.NewInt(3311058),
+		GasLimit:    4015682,
+		Time:        1488928920,
+		Extra:       []byte("www.bw.com"),
+		MixDigest:   common.HexToHash("0x3e140b0784516af5e5ec6730f2fb20cca22f32be399b9e4ad77d32541f798cd0"),
+		Nonce:       types.EncodeNonce(0xf400cd0006070c49),
+	},
+}
+
+// TestMainnetSeals checks the seals of the mainnet blocks against their headers and verifies them with the caches of
+// their epochs
+func TestMainnetSeals(t *testing.T) {
+	for _, seal := range mainnetSeals {
+		header := mainnetHeaders[seal.block]
+		if hash := header.Hash(); common.Bytes2Hex(hash[:]) != seal.hash {
+			t.Fatalf("hash of mainnet block %d: %s", seal.block, hash.Hex())
+		}
+		if common.Bytes2Hex(header.MixDigest[:]) != seal.mixDigest || header.Nonce.Uint64() != seal.nonce {
+			t.Fatalf("mix digest or nonce of mainnet block %d differ from the header", seal.block)
+		}
+		sealed, err := rlp.EncodeToBytes([]interface{}{header.ParentHash, header.UncleHash, header.Coinbase, header.Root,
+			header.TxHash, header.ReceiptHash, header.Bloom, header.Difficulty, header.Number, header.GasLimit, header.GasUsed,
+			header.Time, header.Extra})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sealHash := crypto.Keccak256(sealed); common.Bytes2Hex(sealHash) != seal.sealHash {
+			t.Fatalf("seal hash %x of mainnet block %d instead of %s", sealHash, seal.block, seal.sealHash)
+		}
+
+		cache := make([]uint32, cacheSize(seal.block)/4)
+		generateCache(cache, seal.block/epochLength, seedHash(seal.block))
+		digest, result := hashimotoLight(datasetSize(seal.block), cache, common.FromHex(seal.sealHash), seal.nonce)
+		if common.Bytes2Hex(digest) != seal.mixDigest {
+			t.Errorf("mix digest %x of mainnet block %d instead of %s", digest, seal.block, seal.mixDigest)
+		}
+		if common.Bytes2Hex(result) != seal.result {
+			t.Errorf("result %x of mainnet block %d instead of %s", result, seal.block, seal.result)
+		}
+		target := new(big.Int).Div(new(big.Int).Lsh(big.NewInt(1), 256), header.Difficulty)
+		if new(big.Int).SetBytes(result).Cmp(target) > 0 {
+			t.Errorf("result %x above the target of difficulty %s", result, header.Difficulty)
+		}
+	}
+}
+
+// TestSelfTestEpoch0 compares the epoch data of epoch 0 with the golden Merkle root. It needs the DAG of epoch 0
+// (generated by "ethash selftest --epoch 0") and takes one to two minutes.
+func TestSelfTestEpoch0(t *testing.T) {
+	if _, err := os.Stat(PathToDAG(0, DefaultDir)); err != nil {
+		t.Skipf("no DAG of epoch 0: %s", err)
+	}
+	checks := SelfTestEpoch(0)
+	names := make(map[string]bool)
+	for _, check := range checks {
+		names[check.Name] = true
+		if !check.Passed {
+			t.Errorf("%s: %s", check.Name, check.Detail)
+		}
+	}
+	for _, name := range []string{"epoch 0 seal of mainnet block 1", "epoch 0 Merkle root (golden)"} {
+		if !names[name] {
+			t.Errorf("check %s missing", name)
+		}
+	}
+}
+
+func TestCompareMerkleNodes(t *testing.T) {
+	nodes := [][16]byte{{1}, {2}, {3}}
+	packed := []*big.Int{
+		new(big.Int).SetBytes(append(append([]byte{}, nodes[1][:]...), nodes[0][:]...)),
+		new(big.Int).SetBytes(nodes[2][:]),
+	}
+	if check := compareMerkleNodes("nodes", packed, nodes); !check.Passed {
+		t.Errorf("packed nodes rejected: %s", check.Detail)
+	}
+	if check := compareMerkleNodes("nodes", packed[:1], nodes); check.Passed {
+		t.Error("missing element accepted")
+	}
+	swapped := []*big.Int{new(big.Int).SetBytes(append(append([]byte{}, nodes[0][:]...), nodes[1][:]...)), packed[1]}
+	if check := compareMerkleNodes("nodes", swapped, nodes); check.Passed {
+		t.Error("nodes in the wrong halves accepted")
+	}
+}
@@ -0,0 +1,51 @@
+// This file contains the local verification of the proof-of-work of a block header.
+
+package ethash
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrInvalidMixDigest is returned if the mix digest of a header does not match the computed one.
+	ErrInvalidMixDigest = errors.New("invalid mix digest")
+	// ErrInvalidPoW is returned if the proof-of-work of a header does not satisfy the difficulty.
+	ErrInvalidPoW = errors.New("invalid proof-of-work")
+)
+
+// ComputeSeal computes the mix digest and the proof-of-work result of a block locally, i.e., without any on-chain
+// interaction. Only the verification cache of the block's epoch is needed, which is generated on first use and stored
+// in DefaultDir, so subsequent calls for the same epoch (also of other processes) reuse it.
+func ComputeSeal(blockNumber uint64, hashWithoutNonce common.Hash, nonce uint64) (common.Hash, *big.Int) {
+	cache := Instance.cache(blockNumber)
+	size := datasetSize(blockNumber)
+	digest, result := hashimotoLight(size, cache, hashWithoutNonce.Bytes(), nonce)
+
+	return common.BytesToHash(digest), new(big.Int).SetBytes(result)
+}
+
+// Target returns the maximum proof-of-work result that satisfies the difficulty.
+func Target(difficulty *big.Int) *big.Int {
+	return new(big.Int).Div(maxUint256, difficulty)
+}
+
+// VerifySeal verifies the proof-of-work of a block locally (see ComputeSeal).
+func VerifySeal(blockNumber uint64, hashWithoutNonce common.Hash, nonce uint64, mixDigest common.Hash, difficulty *big.Int) error {
+	if difficulty.Sign() <= 0 {
+		return ErrInvalidPoW
+	}
+
+	digest, result := ComputeSeal(blockNumber, hashWithoutNonce, nonce)
+
+	if !bytes.Equal(mixDigest.Bytes(), digest.Bytes()) {
+		return ErrInvalidMixDigest
+	}
+	if result.Cmp(Target(difficulty)) > 0 {
+		return ErrInvalidPoW
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+// This file contains the providers of the DAG elements and Merkle proofs (the witness) needed to verify the
+// proof-of-work of a block in the contract. Computing a witness requires the DAG of the block's epoch, which takes
+// gigabytes of memory and minutes of CPU time, so witnesses can be cached on disk and shared between relayer instances
+// via HTTP.
+
+package ethash
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// WitnessRequest identifies the proof-of-work of a block.
+type WitnessRequest struct {
+	BlockNumber uint64
+	Nonce       uint64
+	HashNoNonce common.Hash
+}
+
+// Key returns the name of the request's witness in caches.
+func (request WitnessRequest) Key() string {
+	return fmt.Sprintf("%d-%x-%016x", request.BlockNumber, request.HashNoNonce, request.Nonce)
+}
+
+// Witness contains the DAG elements accessed by the proof-of-work computation of a block and their Merkle proofs.
+type Witness struct {
+	DataSetLookup    []*big.Int `json:"dataSetLookup"`
+	WitnessForLookup []*big.Int `json:"witnessForLookup"`
+}
+
+// WitnessProvider provides the witnesses of blocks.
+type WitnessProvider interface {
+	// Witnesses returns the witnesses of the requests in their order.
+	Witnesses(requests []WitnessRequest) ([]Witness, error)
+}
+
+// ComputedWitnesses computes the witnesses from the DAGs of the blocks.
+type ComputedWitnesses struct {
+	cache   *DAGCache
+	workers int
+}
+
+// NewComputedWitnessProvider returns a provider building the DAG trees of the requested blocks with at most workers
+// goroutines, the DAGs are shared via the cache.
+func NewComputedWitnessProvider(cache *DAGCache, workers int) *ComputedWitnesses {
+	return &ComputedWitnesses{cache: cache, workers: workers}
+}
+
+func (p *ComputedWitnesses) Witnesses(requests []WitnessRequest) ([]Witness, error) {
+	metaDataArray := make([]*BlockMetaData, len(requests))
+	for i, request := range requests {
+		metaDataArray[i] = NewBlockMetaData(request.BlockNumber, request.Nonce, request.HashNoNonce)
+	}
+	if err := BuildDagTreesParallel(metaDataArray, p.cache, p.workers); err != nil {
+		return nil, err
+	}
+
+	witnesses := make([]Witness, len(requests))
+	for i, metaData := range metaDataArray {
+		witnesses[i] = Witness{
+			DataSetLookup:    metaData.DAGElementArray(),
+			WitnessForLookup: metaData.DAGProofArray(),
+		}
+	}
+	return witnesses, nil
+}
+
+// witnessStore is a cache of witnesses in front of a provider
+type witnessStore interface {
+	load(request WitnessRequest) (*Witness, error) // nil if the witness is not cached
+	store(request WitnessRequest, witness Witness) error
+}
+
+// cachedWitnesses returns the cached witnesses and requests the others from the source in one go, they are stored
+// afterwards. Failures of the store are ignored, the witnesses are computed instead.
+func cachedWitnesses(store witnessStore, source WitnessProvider, requests []WitnessRequest) ([]Witness, error) {
+	witnesses := make([]Witness, len(requests))
+	var missing []WitnessRequest
+	var missingIndexes []int
+	for i, request := range requests {
+		witness, err := store.load(request)
+		if err != nil || witness == nil {
+			missing = append(missing, request)
+			missingIndexes = append(missingIndexes, i)
+			continue
+		}
+		witnesses[i] = *witness
+	}
+	if len(missing) == 0 {
+		return witnesses, nil
+	}
+
+	sourced, err := source.Witnesses(missing)
+	if err != nil {
+		return nil, err
+	}
+	for i, witness := range sourced {
+		witnesses[missingIndexes[i]] = witness
+		store.store(missing[i], witness)
+	}
+	return witnesses, nil
+}
+
+// DiskWitnessCache stores the witnesses of a source provider as JSON files in a directory.
+type DiskWitnessCache struct {
+	dir    string
+	source WitnessProvider
+}
+
+// NewDiskWitnessCache returns a provider caching the witnesses of the source in the directory.
+func NewDiskWitnessCache(dir string, source WitnessProvider) (*DiskWitnessCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DiskWitnessCache{dir: dir, source: source}, nil
+}
+
+func (p *DiskWitnessCache) Witnesses(requests []WitnessRequest) ([]Witness, error) {
+	return cachedWitnesses(p, p.source, requests)
+}
+
+func (p *DiskWitnessCache) path(key string) string {
+	return filepath.Join(p.dir, key+".json")
+}
+
+func (p *DiskWitnessCache) load(request WitnessRequest) (*Witness, error) {
+	return p.loadKey(request.Key())
+}
+
+func (p *DiskWitnessCache) loadKey(key string) (*Witness, error) {
+	data, err := ioutil.ReadFile(p.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var witness Witness
+	if err := json.Unmarshal(data, &witness); err != nil {
+		return nil, fmt.Errorf("malformed witness %s: %s", key, err)
+	}
+	return &witness, nil
+}
+
+func (p *DiskWitnessCache) store(request WitnessRequest, witness Witness) error {
+	return p.storeKey(request.Key(), witness)
+}
+
+func (p *DiskWitnessCache) storeKey(key string, witness Witness) error {
+	data, err := json.Marshal(witness)
+	if err != nil {
+		return err
+	}
+	// written to a temporary file first, so concurrent readers never see a partial witness
+	tmp, err := ioutil.TempFile(p.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p.path(key))
+}
+
+// RemoteWitnessCache fetches the witnesses from an HTTP server (GET <url>/<key>) and uploads the witnesses of the
+// source provider to it (PUT <url>/<key>), so relayer instances can share witnesses. Witnesses the server does not
+// provide, e.g., because it is not reachable, are requested from the source.
+type RemoteWitnessCache struct {
+	url    string
+	source WitnessProvider
+	client *http.Client
+}
+
+// NewRemoteWitnessCache returns a provider caching the witnesses of the source on the server at the URL. The HTTP
+// client is http.DefaultClient if nil.
+func NewRemoteWitnessCache(url string, source WitnessProvider, client *http.Client) *RemoteWitnessCache {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &RemoteWitnessCache{url: strings.TrimSuffix(url, "/"), source: source, client: client}
+}
+
+func (p *RemoteWitnessCache) Witnesses(requests []WitnessRequest) ([]Witness, error) {
+	return cachedWitnesses(p, p.source, requests)
+}
+
+func (p *RemoteWitnessCache) load(request WitnessRequest) (*Witness, error) {
+	response, err := p.client.Get(p.url + "/" + request.Key())
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("witness cache %s: %s", p.url, response.Status)
+	}
+	var witness Witness
+	if err := json.NewDecoder(response.Body).Decode(&witness); err != nil {
+		return nil, fmt.Errorf("malformed witness %s: %s", request.Key(), err)
+	}
+	return &witness, nil
+}
+
+func (p *RemoteWitnessCache) store(request WitnessRequest, witness Witness) error {
+	data, err := json.Marshal(witness)
+	if err != nil {
+		return err
+	}
+	httpRequest, err := http.NewRequest(http.MethodPut, p.url+"/"+request.Key(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	response, err := p.client.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("witness cache %s: %s", p.url, response.Status)
+	}
+	return nil
+}
+
+// keys of witnesses, see WitnessRequest.Key
+var witnessKeyPattern = regexp.MustCompile("^[0-9]+-[0-9a-f]{64}-[0-9a-f]{16}$")
+
+// NewWitnessCacheHandler returns an HTTP handler serving the witnesses of the disk cache to remote caches (GET) and
+// storing the witnesses uploaded by them (PUT). Uploaded witnesses are not verified, so the handler should only be
+// reachable by trusted relayers: a wrong witness lets disputes fail.
+func NewWitnessCacheHandler(cache *DiskWitnessCache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		if !witnessKeyPattern.MatchString(key) {
+			http.Error(w, "illegal witness key", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			witness, err := cache.loadKey(key)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if witness == nil {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(witness)
+		case http.MethodPut:
+			var witness Witness
+			if err := json.NewDecoder(r.Body).Decode(&witness); err != nil {
+				http.Error(w, "malformed witness: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := cache.storeKey(key, witness); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
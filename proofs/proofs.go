@@ -0,0 +1,123 @@
+// Package proofs contains the construction and verification of Merkle Patricia proofs for transactions, receipts
+// and accounts. The proofs are in the format expected by the verify functions of the ETH Relay contract.
+
+package proofs
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Proof is a Merkle Patricia proof for a single value of a trie.
+type Proof struct {
+	Root  common.Hash // root of the trie the proof was built for
+	Path  []byte      // key of the value within the trie
+	Value []byte      // RLP encoded value
+	Nodes [][]byte    // RLP encoded trie nodes from the root to the leaf
+}
+
+// EncodedNodes returns the RLP encoded list of proof nodes as expected by the ETH Relay contract.
+func (p Proof) EncodedNodes() ([]byte, error) {
+	return rlp.EncodeToBytes(p.Nodes)
+}
+
+// BuildTxProof builds the proof for the transaction at the specified index of the transactions trie.
+func BuildTxProof(txs types.Transactions, index uint) (Proof, error) {
+	return buildProof(txs, index)
+}
+
+// BuildReceiptProof builds the proof for the receipt at the specified index of the receipts trie.
+// The receipts have to contain all receipts of the block in the order of the transactions.
+func BuildReceiptProof(receipts types.Receipts, index uint) (Proof, error) {
+	return buildProof(receipts, index)
+}
+
+// BuildAccountProof builds the proof for the account with the specified address from the account proof nodes
+// returned by eth_getProof. The value of the proof is the RLP encoded account read from the nodes.
+func BuildAccountProof(stateRoot common.Hash, address common.Address, accountProof [][]byte) (Proof, error) {
+	proof := Proof{
+		Root:  stateRoot,
+		Path:  crypto.Keccak256(address.Bytes()),
+		Nodes: accountProof,
+	}
+
+	value, err := verifyNodes(proof.Root, proof.Path, proof.Nodes)
+	if err != nil {
+		return Proof{}, err
+	}
+	if value == nil {
+		return Proof{}, fmt.Errorf("account %s does not exist in state %s", address.String(), stateRoot.String())
+	}
+	proof.Value = value
+
+	return proof, nil
+}
+
+// VerifyProof checks that the proof nodes lead from the proof's root to the proof's value.
+func VerifyProof(proof Proof) error {
+	value, err := verifyNodes(proof.Root, proof.Path, proof.Nodes)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(value, proof.Value) {
+		return fmt.Errorf("proof value does not match value stored in trie with root %s", proof.Root.String())
+	}
+	return nil
+}
+
+func buildProof(list types.DerivableList, index uint) (Proof, error) {
+	if int(index) >= list.Len() {
+		return Proof{}, fmt.Errorf("index %d out of range, trie only contains %d values", index, list.Len())
+	}
+
+	// create trie, the keys are the RLP encoded indices of the values
+	buffer := new(bytes.Buffer)
+	merkleTrie := new(trie.Trie)
+	for i := 0; i < list.Len(); i++ {
+		buffer.Reset()
+		rlp.Encode(buffer, uint(i))
+		merkleTrie.Update(buffer.Bytes(), list.GetRlp(i))
+	}
+
+	path, err := rlp.EncodeToBytes(index)
+	if err != nil {
+		return Proof{}, err
+	}
+
+	// create Merkle proof
+	var proofNodes [][]byte
+	merkleIterator := merkleTrie.NodeIterator(nil)
+	for merkleIterator.Next(true) {
+		if merkleIterator.Leaf() && bytes.Equal(merkleIterator.LeafKey(), path) {
+			// leaf node representing the value has been found --> create Merkle proof
+			proofNodes = merkleIterator.LeafProof()
+			break
+		}
+	}
+
+	return Proof{
+		Root:  merkleTrie.Hash(),
+		Path:  path,
+		Value: list.GetRlp(int(index)),
+		Nodes: proofNodes,
+	}, nil
+}
+
+func verifyNodes(root common.Hash, path []byte, nodes [][]byte) ([]byte, error) {
+	proofDb := memorydb.New()
+	for _, node := range nodes {
+		if err := proofDb.Put(crypto.Keccak256(node), node); err != nil {
+			return nil, err
+		}
+	}
+
+	value, _, err := trie.VerifyProof(root, path, proofDb)
+	return value, err
+}
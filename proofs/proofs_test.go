@@ -0,0 +1,283 @@
+package proofs
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// mainnetBlock46147 contains the first transaction of the mainnet (0x5c504ed4...) and the transactions root of its
+// block 46147
+var mainnetBlock46147 = struct {
+	txHash common.Hash
+	txRoot common.Hash
+	tx     *types.Transaction
+}{
+	txHash: common.HexToHash("0x5c504ed432cb51138bcf09aa5e8a410dd4a1e204ef84bfed1be16dfba1b22060"),
+	txRoot: common.HexToHash("0x4513310fcb9f6f616972a3b948dc5d547f280849a87ebb5af0191f98b87be598"),
+	tx: signedTx(types.NewTransaction(0, common.HexToAddress("0x5df9b87991262f6ba471f09758cde1c0fc1de734"),
+		big.NewInt(31337), 21000, big.NewInt(50000000000000), nil),
+		"88ff6cf0fefd94db46111149ae4bfc179e9b94721fffd821d38d16464b3f71d0"+
+			"45e0aff800961cfce805daef7016b9b675c137a6a41a548f7b60a3484c06a33a01"),
+}
+
+func signedTx(tx *types.Transaction, signature string) *types.Transaction {
+	signed, err := tx.WithSignature(types.HomesteadSigner{}, common.FromHex(signature))
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+// manyTxs returns transactions of the mainnet transaction's sender with increasing nonces, so the tries of more than
+// 128 values contain extension nodes
+func manyTxs(n int) types.Transactions {
+	txs := types.Transactions{mainnetBlock46147.tx}
+	for i := 1; i < n; i++ {
+		txs = append(txs, types.NewTransaction(uint64(i), common.HexToAddress("0x5df9b87991262f6ba471f09758cde1c0fc1de734"),
+			big.NewInt(int64(i)), 21000, big.NewInt(50000000000000), nil))
+	}
+	return txs
+}
+
+// manyReceipts returns receipts in the format since Byzantium (status instead of state root), every second one with a
+// log. Unlike the transactions, they are not anchored to a mainnet root: the receipts root of block 46147 commits to
+// the intermediate state root of the pre-Byzantium receipt.
+func manyReceipts(n int) types.Receipts {
+	var receipts types.Receipts
+	for i := 0; i < n; i++ {
+		receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: uint64(i+1) * 21000}
+		if i%2 == 1 {
+			receipt.Logs = []*types.Log{{Address: common.HexToAddress("0x5df9b87991262f6ba471f09758cde1c0fc1de734"),
+				Topics: []common.Hash{mainnetBlock46147.txHash}, Data: []byte{byte(i)}}}
+		}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		receipts = append(receipts, receipt)
+	}
+	return receipts
+}
+
+func TestMainnetTransaction(t *testing.T) {
+	if hash := mainnetBlock46147.tx.Hash(); hash != mainnetBlock46147.txHash {
+		t.Fatalf("hash of the mainnet transaction: got %s, want %s", hash.Hex(), mainnetBlock46147.txHash.Hex())
+	}
+}
+
+func TestBuildTxProof(t *testing.T) {
+	tests := []struct {
+		name  string
+		txs   types.Transactions
+		index uint
+		root  common.Hash // expected root, derived from the transactions if empty
+	}{
+		{"mainnet block 46147", types.Transactions{mainnetBlock46147.tx}, 0, mainnetBlock46147.txRoot},
+		{"first of two", manyTxs(2), 0, common.Hash{}},
+		{"last of two", manyTxs(2), 1, common.Hash{}},
+		{"last of 17", manyTxs(17), 16, common.Hash{}},
+		{"one byte index", manyTxs(130), 127, common.Hash{}},
+		{"through an extension node", manyTxs(130), 128, common.Hash{}},
+		{"last of 300", manyTxs(300), 299, common.Hash{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			proof, err := BuildTxProof(test.txs, test.index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			root := test.root
+			if root == (common.Hash{}) {
+				root = types.DeriveSha(test.txs)
+			}
+			if proof.Root != root {
+				t.Errorf("root: got %s, want %s", proof.Root.Hex(), root.Hex())
+			}
+			value, _ := rlp.EncodeToBytes(test.txs[test.index])
+			if !bytes.Equal(proof.Value, value) {
+				t.Errorf("value is not the encoded transaction")
+			}
+			if path, _ := rlp.EncodeToBytes(test.index); !bytes.Equal(proof.Path, path) {
+				t.Errorf("path: got %x, want %x", proof.Path, path)
+			}
+			if err := VerifyProof(proof); err != nil {
+				t.Errorf("valid proof rejected: %s", err)
+			}
+		})
+	}
+
+	if _, err := BuildTxProof(manyTxs(2), 2); err == nil {
+		t.Error("proof of a missing transaction built")
+	}
+}
+
+func TestBuildReceiptProof(t *testing.T) {
+	tests := []struct {
+		name     string
+		receipts types.Receipts
+		index    uint
+	}{
+		{"single receipt", manyReceipts(1), 0},
+		{"receipt with logs", manyReceipts(2), 1},
+		{"one byte index", manyReceipts(130), 127},
+		{"through an extension node", manyReceipts(130), 129},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			proof, err := BuildReceiptProof(test.receipts, test.index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if root := types.DeriveSha(test.receipts); proof.Root != root {
+				t.Errorf("root: got %s, want %s", proof.Root.Hex(), root.Hex())
+			}
+			value, _ := rlp.EncodeToBytes(test.receipts[test.index])
+			if !bytes.Equal(proof.Value, value) {
+				t.Errorf("value is not the encoded receipt")
+			}
+			if err := VerifyProof(proof); err != nil {
+				t.Errorf("valid proof rejected: %s", err)
+			}
+		})
+	}
+}
+
+// mainnetGenesisState returns the state of the mainnet genesis block, its hash is checked against the mainnet's
+func mainnetGenesisState(t *testing.T) (*state.StateDB, common.Hash, []common.Address) {
+	db := rawdb.NewMemoryDatabase()
+	genesis := core.DefaultGenesisBlock()
+	block := genesis.ToBlock(db)
+	if block.Hash() != params.MainnetGenesisHash {
+		t.Fatalf("genesis hash: got %s, want %s", block.Hash().Hex(), params.MainnetGenesisHash.Hex())
+	}
+	statedb, err := state.New(block.Root(), state.NewDatabase(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addresses []common.Address
+	for address := range genesis.Alloc {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool { return bytes.Compare(addresses[i][:], addresses[j][:]) < 0 })
+	return statedb, block.Root(), addresses
+}
+
+func TestBuildAccountProof(t *testing.T) {
+	statedb, root, addresses := mainnetGenesisState(t)
+
+	for _, address := range []common.Address{addresses[0], addresses[len(addresses)/2], addresses[len(addresses)-1]} {
+		t.Run(address.Hex(), func(t *testing.T) {
+			nodes, err := statedb.GetProof(address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			proof, err := BuildAccountProof(root, address, nodes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var account state.Account
+			if err := rlp.DecodeBytes(proof.Value, &account); err != nil {
+				t.Fatalf("value is not an account: %s", err)
+			}
+			if account.Balance.Cmp(statedb.GetBalance(address)) != 0 {
+				t.Errorf("balance: got %s, want %s", account.Balance, statedb.GetBalance(address))
+			}
+			if err := VerifyProof(proof); err != nil {
+				t.Errorf("valid proof rejected: %s", err)
+			}
+		})
+	}
+
+	missing := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	nodes, err := statedb.GetProof(missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BuildAccountProof(root, missing, nodes); err == nil {
+		t.Error("proof of a missing account built")
+	}
+	nodes, err = statedb.GetProof(addresses[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BuildAccountProof(common.Hash{1}, addresses[0], nodes); err == nil {
+		t.Error("proof built for another state root")
+	}
+}
+
+func TestVerifyProofRejects(t *testing.T) {
+	statedb, root, addresses := mainnetGenesisState(t)
+	nodes, err := statedb.GetProof(addresses[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	accountProof, err := BuildAccountProof(root, addresses[1], nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txProof, err := BuildTxProof(manyTxs(130), 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiptProof, err := BuildReceiptProof(manyReceipts(17), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPath, _ := rlp.EncodeToBytes(uint(5))
+
+	mutations := []struct {
+		name   string
+		mutate func(proof Proof) Proof
+	}{
+		{"tampered value", func(proof Proof) Proof {
+			proof.Value = flip(proof.Value)
+			return proof
+		}},
+		{"other root", func(proof Proof) Proof {
+			proof.Root = common.Hash{1}
+			return proof
+		}},
+		{"path of another value", func(proof Proof) Proof {
+			proof.Path = otherPath
+			return proof
+		}},
+		{"tampered leaf node", func(proof Proof) Proof {
+			proof.Nodes = append(append([][]byte(nil), proof.Nodes[:len(proof.Nodes)-1]...), flip(proof.Nodes[len(proof.Nodes)-1]))
+			return proof
+		}},
+		{"missing leaf node", func(proof Proof) Proof {
+			proof.Nodes = proof.Nodes[:len(proof.Nodes)-1]
+			return proof
+		}},
+		{"no nodes", func(proof Proof) Proof {
+			proof.Nodes = nil
+			return proof
+		}},
+	}
+	for _, valid := range []struct {
+		name  string
+		proof Proof
+	}{{"mainnet account", accountProof}, {"transaction", txProof}, {"receipt", receiptProof}} {
+		if err := VerifyProof(valid.proof); err != nil {
+			t.Fatalf("%s: valid proof rejected: %s", valid.name, err)
+		}
+		for _, mutation := range mutations {
+			if err := VerifyProof(mutation.mutate(valid.proof)); err == nil {
+				t.Errorf("%s: proof with %s accepted", valid.name, mutation.name)
+			}
+		}
+	}
+}
+
+func flip(data []byte) []byte {
+	flipped := append([]byte(nil), data...)
+	flipped[len(flipped)-1] ^= 0x01
+	return flipped
+}
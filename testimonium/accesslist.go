@@ -0,0 +1,205 @@
+// This file contains the attachment of EIP-2930 access lists to dispute and verification transactions. Their Ethash
+// lookups and Merkle proof checks read many storage slots, which are cheaper if they are declared in advance.
+
+package testimonium
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ACCESS_LIST_TX_TYPE is the EIP-2718 type of EIP-2930 transactions.
+const ACCESS_LIST_TX_TYPE = 0x01
+
+// AccessTuple is an address and the storage slots of it accessed by a transaction.
+type AccessTuple struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []common.Hash  `json:"storageKeys"`
+}
+
+// AccessList is the EIP-2930 access list of a transaction.
+type AccessList []AccessTuple
+
+// StorageKeys returns the number of storage slots in the access list.
+func (al AccessList) StorageKeys() int {
+	keys := 0
+	for _, tuple := range al {
+		keys += len(tuple.StorageKeys)
+	}
+	return keys
+}
+
+// WithoutAccessLists sends dispute and verification transactions without access lists.
+func WithoutAccessLists() ClientOption {
+	return func(client *Client) error {
+		client.noAccessLists = true
+		return nil
+	}
+}
+
+type accessListKey struct{}
+
+// withAccessList marks the transactions sent with the context to be sent with an access list if the chain supports it
+func withAccessList(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, accessListKey{}, true)
+}
+
+func isAccessListRequested(ctx context.Context) bool {
+	requested, _ := ctx.Value(accessListKey{}).(bool)
+	return requested
+}
+
+// accessListTransaction contains the fields of an EIP-2930 transaction in the order of their encoding
+type accessListTransaction struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasPrice   *big.Int
+	Gas        uint64
+	To         common.Address
+	Value      *big.Int
+	Data       []byte
+	AccessList AccessList
+	V, R, S    *big.Int
+}
+
+// signingHash returns the hash signed by the sender: keccak256(0x01 || rlp([chainId, ..., accessList]))
+func (tx *accessListTransaction) signingHash() (common.Hash, error) {
+	payload, err := rlp.EncodeToBytes([]interface{}{tx.ChainID, tx.Nonce, tx.GasPrice, tx.Gas, tx.To, tx.Value, tx.Data, tx.AccessList})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(append([]byte{ACCESS_LIST_TX_TYPE}, payload...)), nil
+}
+
+// sign sets the signature of the transaction and returns its encoding (0x01 || rlp(tx))
+func (tx *accessListTransaction) sign(privateKey *ecdsa.PrivateKey) ([]byte, error) {
+	hash, err := tx.signingHash()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := crypto.Sign(hash[:], privateKey)
+	if err != nil {
+		return nil, err
+	}
+	tx.R = new(big.Int).SetBytes(signature[:32])
+	tx.S = new(big.Int).SetBytes(signature[32:64])
+	tx.V = new(big.Int).SetUint64(uint64(signature[64])) // y parity
+
+	payload, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{ACCESS_LIST_TX_TYPE}, payload...), nil
+}
+
+// createAccessList requests the access list and the gas used with it of the call from the node (eth_createAccessList)
+func (b relayBackend) createAccessList(ctx context.Context, from common.Address, tx *types.Transaction) (AccessList, uint64, error) {
+	var result struct {
+		AccessList AccessList     `json:"accessList"`
+		GasUsed    hexutil.Uint64 `json:"gasUsed"`
+		Error      string         `json:"error"`
+	}
+	call := map[string]interface{}{
+		"from":     from,
+		"to":       tx.To(),
+		"gas":      hexutil.Uint64(tx.Gas()),
+		"gasPrice": (*hexutil.Big)(tx.GasPrice()),
+		"value":    (*hexutil.Big)(tx.Value()),
+		"data":     hexutil.Bytes(tx.Data()),
+	}
+	if err := b.chain.rpcClient.CallContext(ctx, &result, "eth_createAccessList", call, "pending"); err != nil {
+		return nil, 0, err
+	}
+	if result.Error != "" {
+		return nil, 0, fmt.Errorf("call fails: %s", result.Error)
+	}
+	return result.AccessList, uint64(result.GasUsed), nil
+}
+
+// signWithAccessList re-signs the transaction as EIP-2930 transaction with the access list created by the node. It
+// returns false if no access list can be attached, e.g., since the chain does not support EIP-2930 or the transaction
+// was not signed by the account or an operator (a TransactOptsModifier may have replaced the signer).
+func (b relayBackend) signWithAccessList(ctx context.Context, tx *types.Transaction) ([]byte, common.Hash, bool) {
+	if b.chain.rpcClient == nil || tx.To() == nil || atomic.LoadInt32(b.chain.noAccessLists) != 0 {
+		return nil, common.Hash{}, false
+	}
+
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, common.Hash{}, false
+	}
+	privateKey := b.signingKey(from)
+	if privateKey == nil {
+		return nil, common.Hash{}, false
+	}
+
+	accessList, gasUsed, err := b.createAccessList(ctx, from, tx)
+	if err != nil {
+		// not retried for the chain, nodes without EIP-2930 support reject every request
+		atomic.StoreInt32(b.chain.noAccessLists, 1)
+		b.progressf("WARNING: Sending transactions to chain %d without access lists: %s\n", b.chain.id, err)
+		return nil, common.Hash{}, false
+	}
+	if len(accessList) == 0 {
+		return nil, common.Hash{}, false
+	}
+
+	chainId := b.chain.signingChainId
+	if chainId == nil {
+		var err error
+		if chainId, err = b.Client.ChainID(ctx); err != nil {
+			return nil, common.Hash{}, false
+		}
+	}
+	// declaring the slots costs gas upfront, the gas limit covers both
+	gas := tx.Gas()
+	if gasUsed > gas {
+		gas = gasUsed
+	}
+	accessListTx := &accessListTransaction{
+		ChainID:    chainId,
+		Nonce:      tx.Nonce(),
+		GasPrice:   tx.GasPrice(),
+		Gas:        gas,
+		To:         *tx.To(),
+		Value:      tx.Value(),
+		Data:       tx.Data(),
+		AccessList: accessList,
+	}
+	rawTx, err := accessListTx.sign(privateKey)
+	if err != nil {
+		return nil, common.Hash{}, false
+	}
+
+	b.progressf("Attached access list (%d addresses, %d storage keys)\n", len(accessList), accessList.StorageKeys())
+	return rawTx, crypto.Keccak256Hash(rawTx), true
+}
+
+// sendRawTransaction sends an encoded transaction, which may have a type unknown to the bindings
+func (b relayBackend) sendRawTransaction(ctx context.Context, rawTx []byte) error {
+	return b.chain.rpcClient.CallContext(ctx, nil, "eth_sendRawTransaction", hexutil.Encode(rawTx))
+}
+
+// substitutedTxHash returns the hash of the transaction sent instead of the transaction created by the bindings
+func (chain *Chain) substitutedTxHash(txHash common.Hash) common.Hash {
+	if hash, substituted := chain.substitutedTxs.Load(txHash); substituted {
+		return hash.(common.Hash)
+	}
+	return txHash
+}
@@ -0,0 +1,186 @@
+// This file contains the adaptive relay policy, which adjusts the submission frequency of the live mode to the gas
+// price of the destination chain and to the blocks verifications are waiting for.
+
+package testimonium
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// AdaptiveConfig configures the adaptive relay policy. Gas prices are compared with the median of the recorded gas
+// prices, so single spikes do not change the submission frequency.
+type AdaptiveConfig struct {
+	DestinationChain uint8
+	LowGasPrice      *big.Int // every block is relayed up to this gas price, nil to always use the interval
+	MaxGasPrice      *big.Int // no blocks are relayed above this gas price unless demanded, nil for no limit
+	Interval         uint64   // every Interval-th block is relayed between the low and the maximum gas price
+	MaxSkippedBlocks uint64   // a block is relayed at the latest after this many skipped blocks, 0 for no limit
+	Samples          int      // number of recorded gas prices the median is taken of
+}
+
+// AdaptiveState is the input of the relay decision.
+type AdaptiveState struct {
+	GasPrice      *big.Int // median of the recorded gas prices
+	Demanded      bool     // a pending verification needs the block
+	SkippedBlocks uint64   // blocks skipped since the last relayed block
+}
+
+// Decide returns whether the block is relayed in the state and the reason for the decision.
+func (config AdaptiveConfig) Decide(state AdaptiveState) (bool, string) {
+	switch {
+	case state.Demanded:
+		return true, "requested by a pending verification"
+	case config.MaxSkippedBlocks > 0 && state.SkippedBlocks >= config.MaxSkippedBlocks:
+		return true, fmt.Sprintf("%d blocks skipped", state.SkippedBlocks)
+	case state.GasPrice == nil:
+		return true, "gas price unknown"
+	case config.MaxGasPrice != nil && state.GasPrice.Cmp(config.MaxGasPrice) > 0:
+		return false, fmt.Sprintf("gas price %s gwei above maximum", weiToGwei(state.GasPrice))
+	case config.LowGasPrice != nil && state.GasPrice.Cmp(config.LowGasPrice) <= 0:
+		return true, fmt.Sprintf("gas price %s gwei is low", weiToGwei(state.GasPrice))
+	case config.Interval <= 1 || state.SkippedBlocks+1 >= config.Interval:
+		return true, fmt.Sprintf("interval of %d blocks reached", config.Interval)
+	default:
+		return false, fmt.Sprintf("waiting for interval of %d blocks", config.Interval)
+	}
+}
+
+// AdaptivePolicy relays blocks according to the decision of its config. The gas price of the destination chain is
+// recorded whenever a decision is made, verification demand is passed with Request.
+type AdaptivePolicy struct {
+	config AdaptiveConfig
+	demand *OnDemandPolicy
+
+	mutex         sync.Mutex
+	gasPrices     []*big.Int // most recent last
+	skippedBlocks uint64
+}
+
+func NewAdaptivePolicy(config AdaptiveConfig) *AdaptivePolicy {
+	if config.Samples <= 0 {
+		config.Samples = 10
+	}
+	return &AdaptivePolicy{config: config, demand: NewOnDemandPolicy()}
+}
+
+// Request relays the block with the specified number regardless of the gas price, e.g., because a verification
+// needs it.
+func (p *AdaptivePolicy) Request(blockNumber uint64) {
+	p.demand.Request(blockNumber)
+}
+
+// RecordGasPrice adds a gas price of the destination chain to the recorded gas prices.
+func (p *AdaptivePolicy) RecordGasPrice(gasPrice *big.Int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.gasPrices = append(p.gasPrices, new(big.Int).Set(gasPrice))
+	if len(p.gasPrices) > p.config.Samples {
+		p.gasPrices = p.gasPrices[len(p.gasPrices)-p.config.Samples:]
+	}
+}
+
+// State returns the current input of the relay decision.
+func (p *AdaptivePolicy) State(demanded bool) AdaptiveState {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	state := AdaptiveState{Demanded: demanded, SkippedBlocks: p.skippedBlocks}
+	if len(p.gasPrices) > 0 {
+		sorted := make([]*big.Int, len(p.gasPrices))
+		copy(sorted, p.gasPrices)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+		state.GasPrice = sorted[len(sorted)/2]
+	}
+	return state
+}
+
+func (p *AdaptivePolicy) ShouldRelay(client Client, header *types.Header, sourceChain uint8) (bool, error) {
+	if err := client.checkChain(p.config.DestinationChain); err != nil {
+		return false, err
+	}
+	gasPrice, err := client.chain(p.config.DestinationChain).client.SuggestGasPrice(context.Background())
+	if err != nil {
+		return false, err
+	}
+	p.RecordGasPrice(gasPrice)
+
+	demanded, err := p.demand.ShouldRelay(client, header, sourceChain)
+	if err != nil {
+		return false, err
+	}
+
+	relay, reason := p.config.Decide(p.State(demanded))
+	p.mutex.Lock()
+	if relay {
+		p.skippedBlocks = 0
+	} else {
+		p.skippedBlocks++
+	}
+	p.mutex.Unlock()
+
+	if relay {
+		client.progressf("Relaying block %s: %s\n", header.Number, reason)
+	} else {
+		client.progressf("Skipping block %s: %s\n", header.Number, reason)
+	}
+	return relay, nil
+}
+
+// parseAdaptiveConfig parses the arguments "low=GWEI,max=GWEI,interval=N,maxskip=N,samples=N", all are optional
+func parseAdaptiveConfig(argument string, destinationChain uint8) (AdaptiveConfig, error) {
+	config := AdaptiveConfig{DestinationChain: destinationChain, Interval: 1}
+	if argument == "" {
+		return config, nil
+	}
+
+	for _, option := range strings.Split(argument, ",") {
+		keyValue := strings.SplitN(option, "=", 2)
+		if len(keyValue) != 2 {
+			return AdaptiveConfig{}, fmt.Errorf("illegal adaptive policy option '%s' (expected key=value)", option)
+		}
+		key, value := strings.ToLower(keyValue[0]), keyValue[1]
+
+		switch key {
+		case "low", "max":
+			gwei, ok := new(big.Float).SetString(value)
+			if !ok || gwei.Sign() < 0 {
+				return AdaptiveConfig{}, fmt.Errorf("illegal gas price '%s'", value)
+			}
+			wei, _ := new(big.Float).Mul(gwei, big.NewFloat(params.GWei)).Int(nil)
+			if key == "low" {
+				config.LowGasPrice = wei
+			} else {
+				config.MaxGasPrice = wei
+			}
+		case "interval", "maxskip", "samples":
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return AdaptiveConfig{}, fmt.Errorf("illegal %s '%s'", key, value)
+			}
+			switch key {
+			case "interval":
+				config.Interval = n
+			case "maxskip":
+				config.MaxSkippedBlocks = n
+			case "samples":
+				config.Samples = int(n)
+			}
+		default:
+			return AdaptiveConfig{}, fmt.Errorf("unknown adaptive policy option '%s' (low, max, interval, maxskip, samples)", key)
+		}
+	}
+	return config, nil
+}
+
+func weiToGwei(wei *big.Int) string {
+	return new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(params.GWei)).Text('f', 2)
+}
@@ -0,0 +1,160 @@
+// This file contains the administration of a deployed Testimonium contract (ownership, verification fee, lock
+// period). The generated binding does not contain admin functions, as the reference contract has none, so the
+// functions are called with the ABI of the conventional admin functions if the deployed contract exposes them.
+
+package testimonium
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrAdminFunctionNotSupported is returned if the deployed Testimonium contract does not expose an admin function.
+var ErrAdminFunctionNotSupported = errors.New("admin function not supported by the deployed contract")
+
+const testimoniumAdminABI = `[
+	{"inputs":[],"name":"owner","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function","constant":true},
+	{"inputs":[{"internalType":"address","name":"newOwner","type":"address"}],"name":"transferOwnership","outputs":[],"stateMutability":"nonpayable","type":"function"},
+	{"inputs":[{"internalType":"uint256","name":"feeInWei","type":"uint256"}],"name":"setVerificationFee","outputs":[],"stateMutability":"nonpayable","type":"function"},
+	{"inputs":[],"name":"getLockPeriod","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function","constant":true},
+	{"inputs":[{"internalType":"uint256","name":"lockPeriodInSeconds","type":"uint256"}],"name":"setLockPeriod","outputs":[],"stateMutability":"nonpayable","type":"function"}
+]`
+
+// AdminFunctions returns the names of the admin functions exposed by the Testimonium contract on the chain.
+func (c Client) AdminFunctions(chain uint8) ([]string, error) {
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, err
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(testimoniumAdminABI))
+	if err != nil {
+		return nil, err
+	}
+	code, err := c.chain(chain).client.CodeAt(c.context(), c.chain(chain).testimoniumContractAddress, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var functions []string
+	for _, name := range []string{"owner", "transferOwnership", "setVerificationFee", "getLockPeriod", "setLockPeriod"} {
+		if codeHasSelector(code, parsed.Methods[name].ID()) {
+			functions = append(functions, name)
+		}
+	}
+	return functions, nil
+}
+
+// Owner returns the owner of the Testimonium contract on the chain.
+func (c Client) Owner(chain uint8) (common.Address, error) {
+	var owner common.Address
+	err := c.callAdminFunction(chain, &owner, "owner")
+	return owner, err
+}
+
+// LockPeriod returns the lock period of submitted headers (in seconds) of the Testimonium contract on the chain.
+func (c Client) LockPeriod(chain uint8) (*big.Int, error) {
+	contract, err := c.adminContract(chain, "getLockPeriod")
+	if err != nil {
+		return nil, err
+	}
+	return c.chain(chain).cachedView("getLockPeriod", func(opts *bind.CallOpts) (*big.Int, error) {
+		lockPeriod := new(big.Int)
+		err := contract.Call(opts, &lockPeriod, "getLockPeriod")
+		return lockPeriod, err
+	})
+}
+
+// TransferOwnership makes newOwner the owner of the Testimonium contract on the chain. Only the current owner can
+// transfer the ownership.
+func (c Client) TransferOwnership(chain uint8, newOwner common.Address) (*TxResult, error) {
+	return c.sendAdminTransaction(chain, "transferOwnership", newOwner)
+}
+
+// SetVerificationFee sets the fee (in wei) that has to be paid for verifications.
+func (c Client) SetVerificationFee(chain uint8, feeInWei *big.Int) (*TxResult, error) {
+	return c.sendAdminTransaction(chain, "setVerificationFee", feeInWei)
+}
+
+// SetLockPeriod sets the period (in seconds) the stake of a submitted header is locked and the header can be
+// disputed.
+func (c Client) SetLockPeriod(chain uint8, lockPeriodInSeconds *big.Int) (*TxResult, error) {
+	return c.sendAdminTransaction(chain, "setLockPeriod", lockPeriodInSeconds)
+}
+
+// adminContract returns the admin binding of the Testimonium contract if it exposes the function
+func (c Client) adminContract(chain uint8, function string) (*bind.BoundContract, error) {
+	functions, err := c.AdminFunctions(chain)
+	if err != nil {
+		return nil, err
+	}
+	supported := false
+	for _, name := range functions {
+		supported = supported || name == function
+	}
+	if !supported {
+		return nil, fmt.Errorf("%w: %s (contract %s on chain %d)", ErrAdminFunctionNotSupported, function,
+			c.chain(chain).testimoniumContractAddress.Hex(), chain)
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(testimoniumAdminABI))
+	if err != nil {
+		return nil, err
+	}
+	// admin transactions are never relayed, the contract checks the sender
+	client := c.chain(chain).client
+	return bind.NewBoundContract(c.chain(chain).testimoniumContractAddress, parsed, client, client, client), nil
+}
+
+func (c Client) callAdminFunction(chain uint8, result interface{}, function string) error {
+	contract, err := c.adminContract(chain, function)
+	if err != nil {
+		return err
+	}
+	return contract.Call(nil, result, function)
+}
+
+func (c Client) sendAdminTransaction(chain uint8, function string, args ...interface{}) (*TxResult, error) {
+	contract, err := c.adminContract(chain, function)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := prepareTransaction(c.context(), c.account, c.privateKey, c.chain(chain), big.NewInt(0))
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := contract.Transact(auth, function, args...)
+	if err != nil {
+		return nil, err
+	}
+	c.progressf("Tx submitted: %s\n", tx.Hash().Hex())
+	// the transaction may change the parameters read with view calls
+	defer c.chain(chain).viewCache.invalidate()
+
+	receipt, err := awaitTxReceipt(c.context(), c.chain(chain), tx.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status == 0 {
+		// Transaction failed
+		reason := getFailureReason(c.chain(chain).client, c.account, tx, receipt.BlockNumber)
+		return nil, fmt.Errorf("tx failed: %s", reason)
+	}
+
+	return newTxResult(tx, receipt), nil
+}
+
+// codeHasSelector reports whether the function dispatcher of the runtime code compares the call data with the
+// selector, i.e., whether the code contains PUSH4 <selector>
+func codeHasSelector(code []byte, selector []byte) bool {
+	const PUSH4 = 0x63
+	return bytes.Contains(code, append([]byte{PUSH4}, selector...))
+}
@@ -0,0 +1,94 @@
+// This file contains the Kafka sink of the analytics export. Events are produced as JSON records to the partitions of
+// a topic with the kafka-go writer, keyed by the chain, so the events of a chain stay ordered within one partition. The
+// topic has to exist.
+
+package testimonium
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	kafkaClientId    = "go-ethrelay"
+	kafkaDefaultPort = "9092"
+)
+
+// kafkaWriter is the part of the kafka-go writer used by the sink
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
+type kafkaSink struct {
+	writer kafkaWriter
+}
+
+func openKafkaSink(sinkUrl *url.URL) (*kafkaSink, error) {
+	address := sinkUrl.Host
+	if sinkUrl.Port() == "" {
+		address = net.JoinHostPort(sinkUrl.Hostname(), kafkaDefaultPort)
+	}
+	topic := strings.Trim(sinkUrl.Path, "/")
+	if topic == "" {
+		return nil, fmt.Errorf("no Kafka topic in %s (kafka://host:port/topic)", redactedUrl(sinkUrl.String()))
+	}
+	transport := &kafka.Transport{ClientID: kafkaClientId, DialTimeout: ANALYTICS_EXPORT_TIMEOUT}
+	if sinkUrl.Scheme == "kafka+tls" {
+		transport.TLS = &tls.Config{ServerName: sinkUrl.Hostname()}
+	}
+
+	// the topic is checked right away, so misconfigurations are reported on startup
+	ctx, cancel := context.WithTimeout(context.Background(), ANALYTICS_EXPORT_TIMEOUT)
+	defer cancel()
+	client := &kafka.Client{Addr: kafka.TCP(address), Transport: transport, Timeout: ANALYTICS_EXPORT_TIMEOUT}
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Kafka broker %s: %s", address, err)
+	}
+	for _, metadataTopic := range metadata.Topics {
+		if metadataTopic.Error != nil {
+			return nil, fmt.Errorf("Kafka topic %s: %s", topic, metadataTopic.Error)
+		}
+	}
+
+	return &kafkaSink{writer: &kafka.Writer{
+		Addr:         kafka.TCP(address),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireOne,
+		BatchSize:    ANALYTICS_BATCH_SIZE,
+		BatchTimeout: ANALYTICS_EXPORT_INTERVAL / 100,
+		WriteTimeout: ANALYTICS_EXPORT_TIMEOUT,
+		Transport:    transport,
+	}}, nil
+}
+
+// Export produces the events, the records of a chain have the same key and are therefore produced to the same
+// partition in the order of the events
+func (s *kafkaSink) Export(events []AnalyticsEvent) error {
+	messages := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, kafka.Message{Key: []byte(strconv.Itoa(int(event.Chain))), Value: value})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ANALYTICS_EXPORT_TIMEOUT)
+	defer cancel()
+	return s.writer.WriteMessages(ctx, messages...)
+}
+
+func (s *kafkaSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,75 @@
+package testimonium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeKafkaWriter records the produced messages and fails with err
+type fakeKafkaWriter struct {
+	messages []kafka.Message
+	err      error
+	closed   bool
+}
+
+func (w *fakeKafkaWriter) WriteMessages(ctx context.Context, messages ...kafka.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.messages = append(w.messages, messages...)
+	return nil
+}
+
+func (w *fakeKafkaWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestKafkaSinkExport(t *testing.T) {
+	writer := new(fakeKafkaWriter)
+	sink := &kafkaSink{writer: writer}
+
+	var events []AnalyticsEvent
+	for _, chain := range []uint8{1, 2, 3, 1} {
+		events = append(events, AnalyticsEvent{Type: ANALYTICS_EVENT_SUBMISSION, Chain: chain, GasUsed: uint64(len(events))})
+	}
+	if err := sink.Export(events); err != nil {
+		t.Fatal(err)
+	}
+	if len(writer.messages) != len(events) {
+		t.Fatalf("%d messages instead of %d", len(writer.messages), len(events))
+	}
+	for i, message := range writer.messages {
+		var event AnalyticsEvent
+		if err := json.Unmarshal(message.Value, &event); err != nil {
+			t.Fatalf("value is not an event: %s", err)
+		}
+		// the events of a chain have the same key and are produced in order
+		if string(message.Key) != strconv.Itoa(int(events[i].Chain)) || event.Chain != events[i].Chain || event.GasUsed != uint64(i) {
+			t.Errorf("message %d: event of chain %d with key %s", i, event.Chain, message.Key)
+		}
+	}
+
+	writer.err = errors.New("not leader for partition")
+	if err := sink.Export(events); err != writer.err {
+		t.Errorf("failed export: %v", err)
+	}
+	sink.Close()
+	if !writer.closed {
+		t.Error("writer not closed")
+	}
+}
+
+func TestKafkaSinkWithoutTopic(t *testing.T) {
+	sinkUrl, _ := url.Parse("kafka://broker:9092")
+	if _, err := openKafkaSink(sinkUrl); err == nil || !strings.Contains(err.Error(), "no Kafka topic") {
+		t.Errorf("no topic: %v", err)
+	}
+}
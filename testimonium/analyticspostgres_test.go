@@ -0,0 +1,141 @@
+package testimonium
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakePostgresDB records the statements of the sink and fails them with err
+type fakePostgresDB struct {
+	statements []string
+	arguments  [][]interface{}
+	err        error
+	closed     bool
+}
+
+func (db *fakePostgresDB) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	db.statements = append(db.statements, sql)
+	db.arguments = append(db.arguments, arguments)
+	return pgconn.CommandTag{}, db.err
+}
+
+func (db *fakePostgresDB) Close() {
+	db.closed = true
+}
+
+func TestPostgresConfig(t *testing.T) {
+	parse := func(rawUrl string) *url.URL {
+		parsed, err := url.Parse(rawUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	config, table, err := postgresConfig(parse("postgres://relayer:secret@db/relay?table=analytics.events"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table != "analytics.events" {
+		t.Errorf("table %s", table)
+	}
+	conn := config.ConnConfig
+	if conn.User != "relayer" || conn.Password != "secret" || conn.Database != "relay" || conn.Port != 5432 {
+		t.Errorf("connection to %s@%s:%d/%s", conn.User, conn.Host, conn.Port, conn.Database)
+	}
+	if conn.TLSConfig == nil || len(conn.Fallbacks) != 0 {
+		t.Error("connection not encrypted by default")
+	}
+	if _, passed := conn.RuntimeParams["table"]; passed || conn.RuntimeParams["application_name"] != "go-ethrelay" {
+		t.Errorf("runtime parameters %v", conn.RuntimeParams)
+	}
+
+	if config, _, err = postgresConfig(parse("postgres://relayer@db/relay?sslmode=disable")); err != nil || config.ConnConfig.TLSConfig != nil {
+		t.Errorf("sslmode=disable: %v", err)
+	}
+	if _, _, err := postgresConfig(parse("postgres://db/relay")); err == nil || !strings.Contains(err.Error(), "no PostgreSQL user") {
+		t.Errorf("no user: %v", err)
+	}
+	if _, _, err := postgresConfig(parse("postgres://relayer:secret@db/relay?sslmode=sometimes")); err == nil ||
+		strings.Contains(err.Error(), "secret") {
+		t.Errorf("unsupported sslmode: %v", err)
+	}
+}
+
+func TestPostgresSinkExport(t *testing.T) {
+	db := new(fakePostgresDB)
+	sink, err := newPostgresSink(db, "analytics.events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.statements) != 1 || !strings.HasPrefix(db.statements[0], `CREATE TABLE IF NOT EXISTS "analytics"."events" (`) {
+		t.Fatalf("table not created: %v", db.statements)
+	}
+
+	blockHash := common.HexToHash("0x01")
+	broken := `it's \ broken'); DROP TABLE events; --`
+	events := []AnalyticsEvent{
+		{Type: ANALYTICS_EVENT_SUBMISSION, Time: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), Chain: 1,
+			BlockHash: &blockHash, GasUsed: 21000},
+		{Type: ANALYTICS_EVENT_VERIFICATION, Time: time.Date(2020, 1, 2, 3, 4, 6, 0, time.UTC), Chain: 2,
+			AmountInWei: big.NewInt(1000000000000000000), Error: broken},
+	}
+	if err := sink.Export(events); err != nil {
+		t.Fatal(err)
+	}
+	insert := db.statements[1]
+	if insert != `INSERT INTO "analytics"."events" (time, type, chain, account, block_hash, tx_hash, gas_used, amount_wei, error, event) VALUES `+
+		`($1, $2, $3, $4, $5, $6, $7, $8::numeric, $9, $10::jsonb), ($11, $12, $13, $14, $15, $16, $17, $18::numeric, $19, $20::jsonb)` {
+		t.Fatalf("insert %s", insert)
+	}
+
+	arguments := db.arguments[1]
+	if len(arguments) != 20 {
+		t.Fatalf("%d arguments", len(arguments))
+	}
+	if arguments[0] != events[0].Time || arguments[1] != "submission" || arguments[2] != int16(1) || arguments[6] != int64(21000) {
+		t.Errorf("arguments of the submission %v", arguments[:10])
+	}
+	if hash, _ := arguments[4].(*string); hash == nil || *hash != blockHash.Hex() {
+		t.Errorf("block hash %v", arguments[4])
+	}
+	for _, null := range []int{5, 7, 8} {
+		if value, _ := arguments[null].(*string); value != nil {
+			t.Errorf("argument %d is %s instead of NULL", null+1, *value)
+		}
+	}
+	if amount, _ := arguments[17].(*string); amount == nil || *amount != "1000000000000000000" {
+		t.Errorf("amount %v", arguments[17])
+	}
+	// the error is passed as it is
+	if eventError, _ := arguments[18].(*string); eventError == nil || *eventError != broken {
+		t.Errorf("error %v", arguments[18])
+	}
+	if event, _ := arguments[19].(string); !strings.Contains(event, `"type":"verification"`) {
+		t.Errorf("event %v", arguments[19])
+	}
+
+	db.err = errors.New("relation does not exist")
+	if err := sink.Export(events); err != db.err {
+		t.Errorf("failed insert: %v", err)
+	}
+	sink.Close()
+	if !db.closed {
+		t.Error("connection pool not closed")
+	}
+}
+
+func TestPostgresSinkTableNotCreated(t *testing.T) {
+	db := &fakePostgresDB{err: errors.New("permission denied for schema analytics")}
+	if _, err := newPostgresSink(db, "analytics.events"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("sink without table: %v", err)
+	}
+}
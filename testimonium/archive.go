@@ -0,0 +1,144 @@
+// This file contains the fallback for data of old blocks. Full nodes prune old state and, depending on the client and
+// provider, old block bodies and receipts, so proofs and disputes of old blocks would fail with "not found" errors deep
+// inside the proof generation. Such data is fetched from an archive node configured for the chain instead, headers are
+// also taken from the event indexes (the headers submitted to the relay).
+
+package testimonium
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrBlockDataUnavailable is returned if data of a block is neither provided by the node of a chain nor by its archive
+// node, e.g., because it was pruned.
+var ErrBlockDataUnavailable = errors.New("block data not available")
+
+// error messages of nodes that do not (or no longer) provide the requested data
+var prunedErrorHints = []string{"not found", "missing trie node", "pruned", "not available", "unknown block", "historical"}
+
+// dialArchive connects to the node of the "archive" entry of a chain config, which is a full URL, e.g.,
+//
+//	archive: https://eth-mainnet.g.alchemy.com/v2/<key>
+//
+// Nil clients are returned if no archive node is configured.
+func (c Client) dialArchive(chainConfig map[string]interface{}, retry RetryPolicies) (*ethclient.Client, *rpc.Client, error) {
+	if chainConfig["archive"] == nil {
+		return nil, nil, nil
+	}
+	url, ok := chainConfig["archive"].(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("illegal archive URL %v", chainConfig["archive"])
+	}
+	rpcClient, err := c.dial(url, nil, nil, retry)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot connect to archive node %s: %s", url, err)
+	}
+	return ethclient.NewClient(rpcClient), rpcClient, nil
+}
+
+// isPruned reports whether the error of a node indicates that it does not provide the requested data
+func isPruned(err error) bool {
+	if errors.Is(err, ethereum.NotFound) {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	for _, hint := range prunedErrorHints {
+		if strings.Contains(message, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// withArchiveFallback calls fetch with the node of the chain and, if the node does not provide the data, again with
+// the archive node of the chain. If neither provides the data, the returned error wraps ErrBlockDataUnavailable.
+func (c Client) withArchiveFallback(chain uint8, what string, fetch func(client *ethclient.Client, rpcClient *rpc.Client) error) error {
+	source := c.chain(chain)
+	return c.fallBackToArchive(chain, what,
+		func() error { return fetch(source.client, source.rpcClient) },
+		func() error { return fetch(source.archiveClient, source.archiveRpcClient) })
+}
+
+// withBlockSource calls fetch with the block source of the chain and, if the source does not provide the data, again
+// with the archive node of the chain. If neither provides the data, the returned error wraps ErrBlockDataUnavailable.
+func (c Client) withBlockSource(chain uint8, what string, fetch func(source BlockSource) error) error {
+	source := c.chain(chain)
+	return c.fallBackToArchive(chain, what,
+		func() error { return fetch(source.blockSource) },
+		func() error { return fetch(NewRpcBlockSource(source.archiveRpcClient)) })
+}
+
+// fallBackToArchive calls fetchArchive if fetch reports that the node does not provide the data
+func (c Client) fallBackToArchive(chain uint8, what string, fetch func() error, fetchArchive func() error) error {
+	source := c.chain(chain)
+	err := fetch()
+	if err == nil || !isPruned(err) {
+		return err
+	}
+	if source.archiveClient == nil {
+		return fmt.Errorf("%w: %s on chain %d: %s (no archive node configured)", ErrBlockDataUnavailable, what, chain, err)
+	}
+
+	c.progressf("%s not provided by the node of chain %d (%s), fetching it from the archive node\n", what, chain, err)
+	archiveErr := fetchArchive()
+	if archiveErr != nil && isPruned(archiveErr) {
+		return fmt.Errorf("%w: %s on chain %d: %s (archive node: %s)", ErrBlockDataUnavailable, what, chain, err, archiveErr)
+	}
+	return archiveErr
+}
+
+// headerByHash returns the header of the block from the node or the archive node of the chain. Headers that neither
+// provides are looked up in the event indexes, which contain the headers submitted to the relay contracts.
+func (c Client) headerByHash(blockHash common.Hash, chain uint8) (*types.Header, error) {
+	var header *types.Header
+	err := c.withBlockSource(chain, "header "+blockHash.Hex(), func(source BlockSource) error {
+		var err error
+		header, err = source.HeaderByHash(c.context(), blockHash)
+		return err
+	})
+	if errors.Is(err, ErrBlockDataUnavailable) {
+		if indexed, exists := c.indexedHeader(blockHash); exists {
+			return indexed, nil
+		}
+	}
+	return header, err
+}
+
+// indexedHeader looks up the header in the event indexes of all ETH Relay contracts, including the named instances. The
+// indexes are local, so the chains are not dialed.
+func (c Client) indexedHeader(blockHash common.Hash) (*types.Header, bool) {
+	if c.indexDir == "" {
+		return nil, false
+	}
+	for _, id := range c.ConfiguredChains() {
+		var addresses []common.Address
+		if addressHex, ok := c.dialer.configs[id]["ethrelayaddress"].(string); ok {
+			addresses = append(addresses, common.HexToAddress(addressHex))
+		}
+		instances, _ := relayInstancesFromConfig(c.dialer.configs[id])
+		for _, address := range instances {
+			addresses = append(addresses, address)
+		}
+		for _, address := range addresses {
+			index, err := OpenEventIndex(c.indexDir, id, address)
+			if err != nil {
+				continue
+			}
+			if record, exists := index.Lookup(blockHash); exists {
+				header, err := decodeHeaderFromRLP(record.RlpHeader)
+				if err == nil && header.Hash() == blockHash {
+					return header, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,205 @@
+// This file contains the audit of the branch structure stored in the Testimonium contract: the headers of the longest
+// chain are compared with the source chain, e.g., to investigate suspicious disputes.
+
+package testimonium
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// states of audited headers
+const (
+	AUDIT_DIVERGENT = "divergent" // the stored header is not the header of the source chain at its height
+	AUDIT_MISSING   = "missing"   // the header of the source chain at the height is not stored
+)
+
+// AUDIT_PROGRESS_INTERVAL is the number of headers after which the progress of an audit is reported.
+const AUDIT_PROGRESS_INTERVAL = 1000
+
+// AuditFinding is a header at a height at which the longest chain of the contract differs from the source chain.
+// At a height with a divergent header, the header of the source chain is additionally reported as missing unless it is
+// stored in another branch.
+type AuditFinding struct {
+	BlockNumber uint64      `json:"blockNumber"`
+	State       string      `json:"state"`
+	BlockHash   common.Hash `json:"blockHash"`            // the divergent or missing header
+	SourceHash  common.Hash `json:"sourceHash"`           // header of the source chain at the height
+	KnownBlock  bool        `json:"knownBlock,omitempty"` // the source chain knows the divergent block (e.g., reorged)
+}
+
+// BranchAudit is the result of comparing the longest chain of the contract with the source chain. The longest chain
+// is walked from its endpoint back to the genesis block or until MaxHeaders headers were compared.
+type BranchAudit struct {
+	SourceChain      uint8          `json:"sourceChain"`
+	DestinationChain uint8          `json:"destinationChain"`
+	Endpoint         common.Hash    `json:"endpoint"`
+	EndpointNumber   uint64         `json:"endpointNumber"`
+	Genesis          common.Hash    `json:"genesis"`
+	SourceHead       uint64         `json:"sourceHead"`          // latest block of the source chain
+	Checked          uint64         `json:"checked"`             // headers compared
+	Complete         bool           `json:"complete"`            // the genesis block was reached
+	ForkPoint        *uint64        `json:"forkPoint,omitempty"` // highest common block if the longest chain diverges
+	Findings         []AuditFinding `json:"findings"`
+}
+
+// Consistent returns whether no divergent or missing header was found.
+func (audit BranchAudit) Consistent() bool {
+	return len(audit.Findings) == 0
+}
+
+// Count returns the number of findings of the state.
+func (audit BranchAudit) Count(state string) int {
+	count := 0
+	for _, finding := range audit.Findings {
+		if finding.State == state {
+			count++
+		}
+	}
+	return count
+}
+
+// AuditBranch walks the longest chain stored in the Testimonium contract on the destination chain from its endpoint
+// back to the genesis block (at most maxHeaders headers, all if 0) and compares each header with the header of the
+// source chain at the same height. A stored header is divergent if it differs from the source chain, the header of
+// the source chain is missing if it is not stored in the contract at all. The parents of divergent headers are taken
+// from the submitted headers (the event index or the SubmitBlock events), the others from the source chain.
+func (c Client) AuditBranch(destinationChain uint8, sourceChain uint8, maxHeaders uint64) (*BranchAudit, error) {
+	if err := c.checkTestimonium(destinationChain); err != nil {
+		return nil, err
+	}
+	if err := c.checkChain(sourceChain); err != nil {
+		return nil, err
+	}
+
+	contract := c.chain(destinationChain).testimoniumContract
+	genesis, err := contract.GetGenesisBlockHash(nil)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := contract.GetLongestChainEndpoint(nil)
+	if err != nil {
+		return nil, err
+	}
+	sourceHead, err := c.HeaderByNumber(nil, sourceChain)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := &BranchAudit{
+		SourceChain:      sourceChain,
+		DestinationChain: destinationChain,
+		Endpoint:         endpoint,
+		Genesis:          genesis,
+		SourceHead:       sourceHead.Number.Uint64(),
+		Findings:         []AuditFinding{},
+	}
+
+	blockHash := common.Hash(endpoint)
+	for maxHeaders == 0 || audit.Checked < maxHeaders {
+		stored, err := contract.GetHeader(nil, blockHash)
+		if err != nil {
+			return nil, err
+		}
+		if stored.Hash != blockHash {
+			return nil, fmt.Errorf("block %s of the longest chain is not stored in the contract", blockHash.Hex())
+		}
+		blockNumber := stored.BlockNumber.Uint64()
+		if audit.Checked == 0 {
+			audit.EndpointNumber = blockNumber
+		}
+
+		sourceHeader, err := c.HeaderByNumber(stored.BlockNumber, sourceChain)
+		if err != nil {
+			return nil, fmt.Errorf("block %d of source chain %d: %s", blockNumber, sourceChain, err)
+		}
+		audit.Checked++
+		if audit.Checked%AUDIT_PROGRESS_INTERVAL == 0 {
+			c.progressf("Audited %d headers (block %d)\n", audit.Checked, blockNumber)
+		}
+
+		if sourceHeader.Hash() == blockHash {
+			if audit.ForkPoint == nil && len(audit.Findings) > 0 {
+				forkPoint := blockNumber
+				audit.ForkPoint = &forkPoint
+			}
+			if blockHash == genesis {
+				audit.Complete = true
+				break
+			}
+			blockHash = sourceHeader.ParentHash
+			continue
+		}
+
+		findings, err := c.auditFindings(blockNumber, blockHash, sourceHeader.Hash(), destinationChain, sourceChain)
+		if err != nil {
+			return nil, err
+		}
+		audit.Findings = append(audit.Findings, findings...)
+		if blockHash == genesis {
+			// the genesis block is trusted by the contract, there is nothing to compare below it
+			audit.Complete = true
+			break
+		}
+
+		parent, err := c.storedParentHash(blockHash, destinationChain, sourceChain, findings[0].KnownBlock)
+		if err != nil {
+			return nil, fmt.Errorf("parent of divergent block %s: %s", blockHash.Hex(), err)
+		}
+		blockHash = parent
+	}
+	return audit, nil
+}
+
+// auditFindings reports the divergent header of the longest chain and the header of the source chain at its height
+// if it is not stored
+func (c Client) auditFindings(blockNumber uint64, blockHash common.Hash, sourceHash common.Hash, destinationChain uint8, sourceChain uint8) ([]AuditFinding, error) {
+	divergent := AuditFinding{
+		BlockNumber: blockNumber,
+		State:       AUDIT_DIVERGENT,
+		BlockHash:   blockHash,
+		SourceHash:  sourceHash,
+	}
+	if _, err := c.HeaderByHash(blockHash, sourceChain); err == nil {
+		divergent.KnownBlock = true
+	}
+
+	stored, err := c.chain(destinationChain).testimoniumContract.IsHeaderStored(c.callOpts(), sourceHash)
+	if err != nil {
+		return nil, err
+	}
+	if stored {
+		return []AuditFinding{divergent}, nil
+	}
+	missing := AuditFinding{
+		BlockNumber: blockNumber,
+		State:       AUDIT_MISSING,
+		BlockHash:   sourceHash,
+		SourceHash:  sourceHash,
+	}
+	return []AuditFinding{divergent, missing}, nil
+}
+
+// storedParentHash returns the parent of a stored header, from the source chain if it knows the block, otherwise
+// from the submitted header
+func (c Client) storedParentHash(blockHash common.Hash, destinationChain uint8, sourceChain uint8, known bool) (common.Hash, error) {
+	if known {
+		header, err := c.HeaderByHash(blockHash, sourceChain)
+		if err == nil {
+			return header.ParentHash, nil
+		}
+	}
+	rlpHeader, err := c.submittedRlpHeader(blockHash, destinationChain)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	header, err := decodeHeaderFromRLP(rlpHeader)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if header.Hash() != blockHash {
+		return common.Hash{}, fmt.Errorf("submitted header %s does not match", header.Hash().Hex())
+	}
+	return header.ParentHash, nil
+}
@@ -0,0 +1,269 @@
+// This file contains verifications that first submit the headers the verification depends on, if they are not yet
+// relayed.
+
+package testimonium
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pantos-io/go-ethrelay/proofs"
+)
+
+// BackfillStage is the stage of a verification job.
+type BackfillStage string
+
+const (
+	BACKFILL_PROVING    BackfillStage = "proving"    // building the Merkle proof on the source chain
+	BACKFILL_WAITING    BackfillStage = "waiting"    // waiting for the confirmation blocks on the source chain
+	BACKFILL_SUBMITTING BackfillStage = "submitting" // submitting the missing headers
+	BACKFILL_RELAYING   BackfillStage = "relaying"   // waiting for others to submit the missing headers
+	BACKFILL_VERIFYING  BackfillStage = "verifying"  // sending the verification
+	BACKFILL_DONE       BackfillStage = "done"
+	BACKFILL_FAILED     BackfillStage = "failed"
+)
+
+// VerificationJob tracks a verification that makes sure the verified block and its confirmation blocks are stored
+// before the verification is sent.
+type VerificationJob struct {
+	TxHash        common.Hash          `json:"txHash"`
+	ValueType     TrieValueType        `json:"valueType"`
+	BlockHash     common.Hash          `json:"blockHash"`
+	BlockNumber   uint64               `json:"blockNumber"`
+	Confirmations uint8                `json:"confirmations"`
+	Stage         BackfillStage        `json:"stage"`
+	TxStatus      *uint64              `json:"txStatus,omitempty"`  // status of the transaction on the source chain (1 = success)
+	Submitted     []*TxResult          `json:"submitted,omitempty"` // submitted headers, oldest first
+	Verification  *TxResult            `json:"verification,omitempty"`
+	Latency       *VerificationLatency `json:"latency,omitempty"` // set once the job is done
+	Error         string               `json:"error,omitempty"`
+
+	receipt *types.Receipt // receipt of the transaction on the source chain
+	value   []byte         // RLP encoded value the proof was built for
+}
+
+// VerifyWithBackfill verifies the transaction or receipt (trieValueType) with the specified hash on the destination
+// chain. If the block containing it or the confirmation blocks on top of it are not yet stored in the contract, the
+// nearest stored ancestor is located and all headers up to the last confirmation block are submitted first (at most
+// maxHeaders). The job is passed to track whenever its stage changes, track may be nil. The options apply to the
+// verification (see VerifyMerkleProof).
+func (c Client) VerifyWithBackfill(txHash common.Hash, trieValueType TrieValueType, noOfConfirmations uint8,
+	sourceChain uint8, destinationChain uint8, maxHeaders int, track func(job VerificationJob), opts ...VerifyOption) (*VerificationJob, error) {
+	return c.runVerificationJob(txHash, trieValueType, noOfConfirmations, sourceChain, destinationChain, track, opts,
+		c.backfillHeaders(sourceChain, destinationChain, maxHeaders))
+}
+
+// VerifyAfterRelay verifies the transaction or receipt (trieValueType) with the specified hash on the destination
+// chain like VerifyWithBackfill, but instead of submitting missing headers, it waits up to timeout until the block and
+// its confirmation blocks are relayed by others (e.g., relayers running the live mode). The job is passed to track
+// whenever its stage changes, track may be nil. The options apply to the verification (see VerifyMerkleProof).
+func (c Client) VerifyAfterRelay(txHash common.Hash, trieValueType TrieValueType, noOfConfirmations uint8,
+	sourceChain uint8, destinationChain uint8, timeout time.Duration, track func(job VerificationJob), opts ...VerifyOption) (*VerificationJob, error) {
+	return c.runVerificationJob(txHash, trieValueType, noOfConfirmations, sourceChain, destinationChain, track, opts,
+		c.awaitRelayedHeaders(destinationChain, timeout))
+}
+
+// backfillHeaders returns the ensureHeaders step of verification jobs submitting the missing headers up to the last
+// confirmation block (at most maxHeaders)
+func (c Client) backfillHeaders(sourceChain uint8, destinationChain uint8, maxHeaders int) func(job *VerificationJob,
+	lastHeader *types.Header, setStage func(BackfillStage)) error {
+	return func(job *VerificationJob, lastHeader *types.Header, setStage func(BackfillStage)) error {
+		isLastHeaderStored, err := c.chain(destinationChain).testimoniumContract.IsHeaderStored(c.callOpts(), lastHeader.Hash())
+		if err != nil {
+			return err
+		}
+		if isLastHeaderStored {
+			return nil
+		}
+		setStage(BACKFILL_SUBMITTING)
+		results, err := c.SubmitHeaderWithAncestors(lastHeader, destinationChain, sourceChain, maxHeaders-1)
+		job.Submitted = results
+		if err != nil && !errors.Is(err, ErrHeaderAlreadyStored) {
+			return err
+		}
+		return nil
+	}
+}
+
+// awaitRelayedHeaders returns the ensureHeaders step of verification jobs waiting up to timeout until the last
+// confirmation block is relayed by others
+func (c Client) awaitRelayedHeaders(destinationChain uint8, timeout time.Duration) func(job *VerificationJob,
+	lastHeader *types.Header, setStage func(BackfillStage)) error {
+	return func(job *VerificationJob, lastHeader *types.Header, setStage func(BackfillStage)) error {
+		deadline := time.Now().Add(timeout)
+		for {
+			isLastHeaderStored, err := c.chain(destinationChain).testimoniumContract.IsHeaderStored(c.callOpts(), lastHeader.Hash())
+			if err != nil {
+				return err
+			}
+			if isLastHeaderStored {
+				return nil
+			}
+			if job.Stage != BACKFILL_RELAYING {
+				setStage(BACKFILL_RELAYING)
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("block %d was not relayed within %s", lastHeader.Number, timeout)
+			}
+			time.Sleep(5 * time.Second)
+		}
+	}
+}
+
+// runVerificationJob builds the proof, waits for the confirmation blocks on the source chain, lets ensureHeaders
+// make sure that the last confirmation block is stored in the contract and sends the verification
+func (c Client) runVerificationJob(txHash common.Hash, trieValueType TrieValueType, noOfConfirmations uint8,
+	sourceChain uint8, destinationChain uint8, track func(job VerificationJob), opts []VerifyOption,
+	ensureHeaders func(job *VerificationJob, lastHeader *types.Header, setStage func(BackfillStage)) error) (*VerificationJob, error) {
+	jobs, err := c.runVerificationJobs(txHash, []TrieValueType{trieValueType}, noOfConfirmations, sourceChain,
+		destinationChain, track, opts, ensureHeaders)
+	if len(jobs) == 0 {
+		return nil, err
+	}
+	return jobs[0], err
+}
+
+// runVerificationJobs runs the verification jobs of several values of the transaction (e.g., the transaction and its
+// receipt) together: the proofs are built against the same block, whose headers are ensured once, and the jobs pass the
+// stages together. The submitted headers are recorded in the first job.
+func (c Client) runVerificationJobs(txHash common.Hash, trieValueTypes []TrieValueType, noOfConfirmations uint8,
+	sourceChain uint8, destinationChain uint8, track func(job VerificationJob), opts []VerifyOption,
+	ensureHeaders func(job *VerificationJob, lastHeader *types.Header, setStage func(BackfillStage)) error) ([]*VerificationJob, error) {
+	if err := c.checkChain(sourceChain); err != nil {
+		return nil, err
+	}
+	if err := c.checkTestimonium(destinationChain); err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*VerificationJob, len(trieValueTypes))
+	for i, trieValueType := range trieValueTypes {
+		jobs[i] = &VerificationJob{
+			TxHash:        txHash,
+			ValueType:     trieValueType,
+			Confirmations: noOfConfirmations,
+		}
+	}
+	setStage := func(stage BackfillStage) {
+		c.progressf("Verification of %s: %s\n", txHash.Hex(), stage)
+		for _, job := range jobs {
+			job.Stage = stage
+			if track != nil {
+				track(*job)
+			}
+		}
+	}
+	fail := func(err error) ([]*VerificationJob, error) {
+		for _, job := range jobs {
+			job.Error = err.Error()
+		}
+		setStage(BACKFILL_FAILED)
+		return jobs, err
+	}
+
+	setStage(BACKFILL_PROVING)
+	// the receipt tells whether the transaction is mined at all, failed transactions can be verified as well
+	receipt, err := c.TransactionReceipt(txHash, sourceChain)
+	if err != nil {
+		return fail(fmt.Errorf("no receipt of transaction %s on chain %d: %s", txHash.Hex(), sourceChain, err))
+	}
+
+	valueProofs := make([]proofs.Proof, len(jobs))
+	var header *types.Header
+	proofStarted := time.Now()
+	for i, job := range jobs {
+		job.TxStatus = &receipt.Status
+		job.receipt = receipt
+		var valueHeader *types.Header
+		valueProofs[i], valueHeader, err = c.buildValueProof(txHash, job.ValueType, sourceChain)
+		if err != nil {
+			return fail(err)
+		}
+		if header != nil && valueHeader.Hash() != header.Hash() {
+			// the source chain was reorganised while the proofs were built
+			return fail(fmt.Errorf("proofs of transaction %s were built against blocks %s and %s", txHash.Hex(),
+				header.Hash().Hex(), valueHeader.Hash().Hex()))
+		}
+		header = valueHeader
+		job.BlockHash = header.Hash()
+		job.BlockNumber = header.Number.Uint64()
+		job.value = valueProofs[i].Value
+	}
+	proofGeneration := time.Since(proofStarted)
+
+	// the last confirmation block determines the headers that have to be stored
+	setStage(BACKFILL_WAITING)
+	lastHeader, err := c.awaitSourceHeader(new(big.Int).Add(header.Number, big.NewInt(int64(noOfConfirmations))), sourceChain)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := ensureHeaders(jobs[0], lastHeader, setStage); err != nil {
+		return fail(err)
+	}
+
+	// the source chain may have been reorganised since the proof was built
+	isHeaderStored, err := c.chain(destinationChain).testimoniumContract.IsHeaderStored(c.callOpts(), header.Hash())
+	if err != nil {
+		return fail(err)
+	}
+	if !isHeaderStored {
+		return fail(fmt.Errorf("block %s is not part of the chain of block %s anymore", header.Hash().Hex(), lastHeader.Hash().Hex()))
+	}
+
+	setStage(BACKFILL_VERIFYING)
+	feeInWei, err := c.chain(destinationChain).requiredVerificationFee()
+	if err != nil {
+		return fail(err)
+	}
+	for i, job := range jobs {
+		rlpHeader, rlpEncodedValue, path, rlpEncodedProofNodes, err := encodeProof(header, valueProofs[i])
+		if err != nil {
+			return fail(err)
+		}
+		sent := time.Now()
+		job.Verification, err = c.VerifyMerkleProof(feeInWei, rlpHeader, job.ValueType, rlpEncodedValue, path,
+			rlpEncodedProofNodes, noOfConfirmations, destinationChain, opts...)
+		if err != nil {
+			return fail(err)
+		}
+		job.Latency = c.verificationLatency(header, lastHeader, sent, job.Verification, proofGeneration, destinationChain)
+	}
+
+	setStage(BACKFILL_DONE)
+	return jobs, nil
+}
+
+// verificationLatency returns the latency of the completed verification, nil if the block times are not available
+func (c Client) verificationLatency(header *types.Header, lastHeader *types.Header, sent time.Time, verification *TxResult,
+	proofGeneration time.Duration, destinationChain uint8) *VerificationLatency {
+	verified, err := c.blockTime(verification.BlockNumber, destinationChain)
+	if err != nil {
+		c.progressf("WARNING: Latency of the verification unknown: %s\n", err)
+		return nil
+	}
+	stored, err := c.headerStoredTime(lastHeader.Hash(), destinationChain)
+	if err != nil {
+		c.progressf("WARNING: Relay lag of the verification unknown: %s\n", err)
+	}
+	return newVerificationLatency(time.Unix(int64(header.Time), 0), time.Unix(int64(lastHeader.Time), 0), stored, sent,
+		verified, proofGeneration)
+}
+
+// awaitSourceHeader waits until the source chain contains a block with the specified number
+func (c Client) awaitSourceHeader(blockNumber *big.Int, chain uint8) (*types.Header, error) {
+	for {
+		latest, err := c.HeaderByNumber(nil, chain)
+		if err != nil {
+			return nil, err
+		}
+		if latest.Number.Cmp(blockNumber) >= 0 {
+			return c.HeaderByNumber(blockNumber, chain)
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
@@ -0,0 +1,162 @@
+// This file contains the verification of many transactions or receipts in one go, e.g., to work off a backlog of a
+// bridge. The proofs are generated concurrently, the verifications are sent back to back with consecutive nonces
+// before their receipts are awaited.
+
+package testimonium
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// BATCH_PROOF_WORKERS is the default number of proofs generated at the same time by VerifyBatch.
+const BATCH_PROOF_WORKERS = 4
+
+// BatchVerification is a verification of a batch. TxHash, ValueType and Confirmations are set by the caller, the
+// other fields are filled in by VerifyBatch.
+type BatchVerification struct {
+	TxHash        common.Hash   `json:"txHash"`
+	ValueType     TrieValueType `json:"type"`
+	Confirmations uint8         `json:"confirmations"`
+	BlockHash     common.Hash   `json:"blockHash"`
+	BlockNumber   uint64        `json:"blockNumber,omitempty"`
+	Result        *TxResult     `json:"result,omitempty"`
+	Err           error         `json:"-"` // set if the verification failed
+}
+
+// batchProof is the encoded proof of a batch verification
+type batchProof struct {
+	rlpHeader            []byte
+	rlpEncodedValue      []byte
+	path                 []byte
+	rlpEncodedProofNodes []byte
+}
+
+// VerifyBatch verifies the transactions or receipts of the source chain on the destination chain. The proofs are
+// generated by the specified number of workers (BATCH_PROOF_WORKERS if not positive). Afterwards, the verifications
+// are checked (see CheckVerification) and sent in the order of the batch with consecutive nonces, without waiting for
+// each other. The outcome of every verification is stored in its entry, an error is only returned if the batch could
+// not be started.
+func (c Client) VerifyBatch(batch []BatchVerification, sourceChain uint8, destinationChain uint8, workers int) error {
+	c = c.as(OPERATOR_VERIFIER)
+	if err := c.checkChain(sourceChain); err != nil {
+		return err
+	}
+	if err := c.checkTestimonium(destinationChain); err != nil {
+		return err
+	}
+	if workers <= 0 {
+		workers = BATCH_PROOF_WORKERS
+	}
+
+	encodedProofs := c.generateBatchProofs(batch, sourceChain, workers)
+
+	feeInWei, err := c.chain(destinationChain).requiredVerificationFee()
+	if err != nil {
+		return err
+	}
+	// fee tokens are approved for all verifications before the first is sent
+	verifications := 0
+	for i := range batch {
+		if batch[i].Err == nil {
+			verifications++
+		}
+	}
+	value, err := c.verificationValue(feeInWei, verifications, destinationChain)
+	if err != nil {
+		return err
+	}
+	nonce, err := c.chain(destinationChain).client.PendingNonceAt(c.context(), c.account)
+	if err != nil {
+		return err
+	}
+
+	sent := make(map[int]*types.Transaction)
+	for i := range batch {
+		entry := &batch[i]
+		if entry.Err != nil {
+			continue
+		}
+		proof := encodedProofs[i]
+		if entry.Err = c.CheckVerification(feeInWei, proof.rlpHeader, entry.Confirmations, destinationChain); entry.Err != nil {
+			continue
+		}
+
+		auth, err := prepareTransaction(c.context(), c.account, c.privateKey, c.chain(destinationChain), value)
+		if err != nil {
+			entry.Err = err
+			continue
+		}
+		// the node may not count the transactions sent just before as pending yet
+		if auth.Nonce.Uint64() < nonce {
+			auth.Nonce = new(big.Int).SetUint64(nonce)
+		}
+
+		tx, err := c.sendVerification(auth, feeInWei, proof.rlpHeader, entry.ValueType, proof.rlpEncodedValue, proof.path,
+			proof.rlpEncodedProofNodes, entry.Confirmations, destinationChain)
+		if err != nil {
+			entry.Err = err
+			// the nonce was not used, continue with the one the node expects, so no gap is left
+			if pending, err := c.chain(destinationChain).client.PendingNonceAt(c.context(), c.account); err == nil {
+				nonce = pending
+			}
+			continue
+		}
+		nonce = auth.Nonce.Uint64() + 1
+		sent[i] = tx
+		c.progressf("Tx submitted: %s (verification of %s %s, nonce %d)\n", tx.Hash().Hex(), entry.ValueType,
+			entry.TxHash.Hex(), auth.Nonce.Uint64())
+	}
+
+	for i := range batch {
+		tx, exists := sent[i]
+		if !exists {
+			continue
+		}
+		batch[i].Result, batch[i].Err = c.awaitVerification(tx, batch[i].ValueType, destinationChain)
+	}
+	return nil
+}
+
+// generateBatchProofs generates the proofs of the batch concurrently, failures are stored in the batch entries
+func (c Client) generateBatchProofs(batch []BatchVerification, sourceChain uint8, workers int) []batchProof {
+	encodedProofs := make([]batchProof, len(batch))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	var done int
+	var mutex sync.Mutex
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				entry := &batch[i]
+				proof, header, err := c.buildValueProof(entry.TxHash, entry.ValueType, sourceChain)
+				if err == nil {
+					entry.BlockHash = header.Hash()
+					entry.BlockNumber = header.Number.Uint64()
+					encoded := &encodedProofs[i]
+					encoded.rlpHeader, encoded.rlpEncodedValue, encoded.path, encoded.rlpEncodedProofNodes, err =
+						encodeProof(header, proof)
+				}
+				entry.Err = err
+
+				mutex.Lock()
+				done++
+				c.progressf("Generated proof %d of %d\n", done, len(batch))
+				mutex.Unlock()
+			}
+		}()
+	}
+	for i := range batch {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return encodedProofs
+}
@@ -0,0 +1,439 @@
+// This file contains the sources of block headers, bodies and receipts. By default, they are fetched with the standard
+// JSON-RPC API of the chain's node. A relayer running next to its node can read them from the node's extended API
+// (Erigon) or directly from the node's freezer directory, which avoids most of the requests for old blocks.
+
+package testimonium
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/snappy"
+)
+
+// types of the "blocksource" entry of a chain config
+const (
+	BLOCK_SOURCE_RPC     = "rpc"
+	BLOCK_SOURCE_ERIGON  = "erigon"
+	BLOCK_SOURCE_FREEZER = "freezer"
+)
+
+// BlockSource provides the headers, blocks and receipts of a chain. Data a source does not provide is reported with
+// an error wrapping ethereum.NotFound, so it can be fetched elsewhere (e.g., from the archive node of the chain).
+type BlockSource interface {
+	Name() string
+	// HeaderByNumber returns the latest header if number is nil.
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+	// BlockByNumber returns the latest block if number is nil.
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error)
+	// BlockReceipts returns the receipts of all transactions of the block in the order of the transactions.
+	BlockReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error)
+}
+
+// WithBlockSource fetches the headers, blocks and receipts of the chain from the source instead of the standard
+// JSON-RPC API of the chain's node, overriding the "blocksource" entry of the chain config.
+func WithBlockSource(chain uint8, source BlockSource) ClientOption {
+	return func(client *Client) error {
+		if client.blockSources == nil {
+			client.blockSources = make(map[uint8]BlockSource)
+		}
+		client.blockSources[chain] = source
+		return nil
+	}
+}
+
+// blockSourceFromConfig creates the source of the "blocksource" entry of a chain config, e.g.,
+//
+//	blocksource:
+//	    type: freezer
+//	    dir: /data/geth/geth/chaindata/ancient
+//
+// The type is "rpc" (default), "erigon" or "freezer". Blocks that are not frozen yet are fetched with the RPC API.
+func blockSourceFromConfig(chainConfig map[string]interface{}, rpcClient *rpc.Client) (BlockSource, error) {
+	rpcSource := NewRpcBlockSource(rpcClient)
+	entry, ok := chainConfig["blocksource"].(map[string]interface{})
+	if !ok {
+		return rpcSource, nil
+	}
+
+	sourceType, _ := entry["type"].(string)
+	switch sourceType {
+	case "", BLOCK_SOURCE_RPC:
+		return rpcSource, nil
+	case BLOCK_SOURCE_ERIGON:
+		return NewErigonBlockSource(rpcClient), nil
+	case BLOCK_SOURCE_FREEZER:
+		dir, _ := entry["dir"].(string)
+		return NewFreezerBlockSource(dir, rpcSource)
+	default:
+		return rpcSource, fmt.Errorf("unknown block source type '%s' (%s, %s or %s)", sourceType, BLOCK_SOURCE_RPC,
+			BLOCK_SOURCE_ERIGON, BLOCK_SOURCE_FREEZER)
+	}
+}
+
+// rpcBlockSource fetches the data with the standard JSON-RPC API
+type rpcBlockSource struct {
+	client                *ethclient.Client
+	rpcClient             *rpc.Client
+	receiptsByTransaction bool // set if the node does not support eth_getBlockReceipts
+}
+
+// NewRpcBlockSource returns the source fetching the data with the standard JSON-RPC API of the node.
+func NewRpcBlockSource(rpcClient *rpc.Client) BlockSource {
+	return rpcBlockSource{client: ethclient.NewClient(rpcClient), rpcClient: rpcClient}
+}
+
+func (source rpcBlockSource) Name() string {
+	return BLOCK_SOURCE_RPC
+}
+
+func (source rpcBlockSource) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return source.client.HeaderByNumber(ctx, number)
+}
+
+func (source rpcBlockSource) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return source.client.HeaderByHash(ctx, hash)
+}
+
+func (source rpcBlockSource) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return source.client.BlockByNumber(ctx, number)
+}
+
+func (source rpcBlockSource) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return source.client.BlockByHash(ctx, hash)
+}
+
+// BlockReceipts fetches the receipts with a single eth_getBlockReceipts request, if the node does not support it they
+// are fetched one by one.
+func (source rpcBlockSource) BlockReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	var receipts types.Receipts
+	if !source.receiptsByTransaction {
+		err := source.rpcClient.CallContext(ctx, &receipts, "eth_getBlockReceipts", hash)
+		if err == nil && receipts != nil {
+			return receipts, nil
+		}
+	}
+
+	block, err := source.client.BlockByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	receipts = make(types.Receipts, block.Transactions().Len())
+	for i, tx := range block.Transactions() {
+		receipts[i], err = source.client.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return receipts, nil
+}
+
+// erigonBlockSource fetches headers and receipts with the erigon_ namespace, which returns headers without the
+// transactions of the block and all receipts of a block with a single request. Nodes without the namespace are
+// queried with the standard API.
+type erigonBlockSource struct {
+	rpcBlockSource
+}
+
+// NewErigonBlockSource returns the source fetching the data with the extended API of an Erigon node.
+func NewErigonBlockSource(rpcClient *rpc.Client) BlockSource {
+	return erigonBlockSource{rpcBlockSource{client: ethclient.NewClient(rpcClient), rpcClient: rpcClient}}
+}
+
+func (source erigonBlockSource) Name() string {
+	return BLOCK_SOURCE_ERIGON
+}
+
+func (source erigonBlockSource) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var header *types.Header
+	if err := source.rpcClient.CallContext(ctx, &header, "erigon_getHeaderByNumber", toBlockNumArg(number)); err != nil {
+		return source.rpcBlockSource.HeaderByNumber(ctx, number)
+	}
+	if header == nil {
+		return nil, ethereum.NotFound
+	}
+	return header, nil
+}
+
+func (source erigonBlockSource) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	var header *types.Header
+	if err := source.rpcClient.CallContext(ctx, &header, "erigon_getHeaderByHash", hash); err != nil {
+		return source.rpcBlockSource.HeaderByHash(ctx, hash)
+	}
+	if header == nil {
+		return nil, ethereum.NotFound
+	}
+	return header, nil
+}
+
+func (source erigonBlockSource) BlockReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	var receipts types.Receipts
+	err := source.rpcClient.CallContext(ctx, &receipts, "erigon_getBlockReceiptsByBlockHash", hash)
+	if err == nil && receipts != nil {
+		return receipts, nil
+	}
+	return source.rpcBlockSource.BlockReceipts(ctx, hash)
+}
+
+// names of the freezer tables and whether they are compressed
+var freezerTables = map[string]bool{
+	"headers":  true,
+	"hashes":   false,
+	"bodies":   true,
+	"receipts": true,
+}
+
+// size of an entry of the index file of a freezer table: number of the data file (2 bytes) and offset (4 bytes)
+const freezerIndexEntrySize = 6
+
+// freezerBlockSource reads the blocks frozen by a geth node (the "ancient" directory of its chaindata). The freezer
+// only contains canonical blocks older than about 90000 blocks, all other blocks are fetched from next. The freezer
+// is not indexed by hash, so next resolves the numbers of blocks requested by hash (a single header request), while
+// the bodies and receipts are read from disk.
+type freezerBlockSource struct {
+	dir  string
+	next BlockSource
+}
+
+// NewFreezerBlockSource returns the source reading the frozen blocks in the freezer directory of a geth node. The
+// directory is only read, so the node can keep running.
+func NewFreezerBlockSource(dir string, next BlockSource) (BlockSource, error) {
+	if dir == "" {
+		return nil, errors.New("no freezer directory configured")
+	}
+	for table, compressed := range freezerTables {
+		if _, err := os.Stat(freezerIndexPath(dir, table, compressed)); err != nil {
+			return nil, fmt.Errorf("no freezer in %s: %s", dir, err)
+		}
+	}
+	return freezerBlockSource{dir: dir, next: next}, nil
+}
+
+func (source freezerBlockSource) Name() string {
+	return fmt.Sprintf("%s %s, %s", BLOCK_SOURCE_FREEZER, source.dir, source.next.Name())
+}
+
+func (source freezerBlockSource) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		return source.next.HeaderByNumber(ctx, nil)
+	}
+	header, _, err := source.frozenHeader(number.Uint64())
+	if errors.Is(err, ethereum.NotFound) {
+		return source.next.HeaderByNumber(ctx, number)
+	}
+	return header, err
+}
+
+func (source freezerBlockSource) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return source.next.HeaderByHash(ctx, hash)
+}
+
+func (source freezerBlockSource) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	if number == nil {
+		return source.next.BlockByNumber(ctx, nil)
+	}
+	block, err := source.frozenBlock(number.Uint64())
+	if errors.Is(err, ethereum.NotFound) {
+		return source.next.BlockByNumber(ctx, number)
+	}
+	return block, err
+}
+
+func (source freezerBlockSource) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	number, err := source.frozenNumber(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return source.next.BlockByHash(ctx, hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return source.frozenBlock(number)
+}
+
+func (source freezerBlockSource) BlockReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	number, err := source.frozenNumber(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return source.next.BlockReceipts(ctx, hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	block, err := source.frozenBlock(number)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := source.retrieve("receipts", number)
+	if err != nil {
+		return nil, err
+	}
+	var stored []*types.ReceiptForStorage
+	if err := rlp.DecodeBytes(data, &stored); err != nil {
+		return nil, fmt.Errorf("corrupt receipts of block %d in the freezer: %s", number, err)
+	}
+	if len(stored) != block.Transactions().Len() {
+		return nil, fmt.Errorf("corrupt receipts of block %d in the freezer: %d receipts for %d transactions", number,
+			len(stored), block.Transactions().Len())
+	}
+
+	// the freezer only stores the consensus fields without the bloom filter, the other fields are derived from the block
+	receipts := make(types.Receipts, len(stored))
+	logIndex := uint(0)
+	for i, receipt := range stored {
+		r := (*types.Receipt)(receipt)
+		r.Bloom = types.CreateBloom(types.Receipts{r})
+		r.TxHash = block.Transactions()[i].Hash()
+		r.BlockHash = hash
+		r.BlockNumber = new(big.Int).SetUint64(number)
+		r.TransactionIndex = uint(i)
+		r.GasUsed = r.CumulativeGasUsed
+		if i > 0 {
+			r.GasUsed -= receipts[i-1].CumulativeGasUsed
+		}
+		for _, log := range r.Logs {
+			log.BlockNumber, log.BlockHash, log.TxHash, log.TxIndex, log.Index = number, hash, r.TxHash, uint(i), logIndex
+			logIndex++
+		}
+		receipts[i] = r
+	}
+	return receipts, nil
+}
+
+// frozenNumber returns the number of the block if it is frozen, an error wrapping ethereum.NotFound otherwise
+func (source freezerBlockSource) frozenNumber(ctx context.Context, hash common.Hash) (uint64, error) {
+	header, err := source.next.HeaderByHash(ctx, hash)
+	if err != nil {
+		return 0, err
+	}
+	data, err := source.retrieve("hashes", header.Number.Uint64())
+	if err != nil {
+		return 0, err
+	}
+	if common.BytesToHash(data) != hash {
+		// a block of a side chain
+		return 0, fmt.Errorf("block %s not frozen: %w", hash.Hex(), ethereum.NotFound)
+	}
+	return header.Number.Uint64(), nil
+}
+
+func (source freezerBlockSource) frozenHeader(number uint64) (*types.Header, common.Hash, error) {
+	data, err := source.retrieve("headers", number)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(data, header); err != nil {
+		return nil, common.Hash{}, fmt.Errorf("corrupt header of block %d in the freezer: %s", number, err)
+	}
+	hash, err := source.retrieve("hashes", number)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	if header.Hash() != common.BytesToHash(hash) {
+		return nil, common.Hash{}, fmt.Errorf("corrupt header of block %d in the freezer: hash %s, expected %s", number,
+			header.Hash().Hex(), common.BytesToHash(hash).Hex())
+	}
+	return header, header.Hash(), nil
+}
+
+func (source freezerBlockSource) frozenBlock(number uint64) (*types.Block, error) {
+	header, _, err := source.frozenHeader(number)
+	if err != nil {
+		return nil, err
+	}
+	data, err := source.retrieve("bodies", number)
+	if err != nil {
+		return nil, err
+	}
+	body := new(types.Body)
+	if err := rlp.DecodeBytes(data, body); err != nil {
+		return nil, fmt.Errorf("corrupt body of block %d in the freezer: %s", number, err)
+	}
+	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles), nil
+}
+
+func freezerIndexPath(dir string, table string, compressed bool) string {
+	if compressed {
+		return filepath.Join(dir, table+".cidx")
+	}
+	return filepath.Join(dir, table+".ridx")
+}
+
+// retrieve reads the item of the freezer table, an error wrapping ethereum.NotFound is returned if the item is not
+// (or no longer) in the table. The files are opened for every item, as the node appends to them.
+func (source freezerBlockSource) retrieve(table string, item uint64) ([]byte, error) {
+	compressed := freezerTables[table]
+	index, err := os.Open(freezerIndexPath(source.dir, table, compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer index.Close()
+	stat, err := index.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	readEntry := func(position uint64) (uint32, uint32, error) {
+		buffer := make([]byte, freezerIndexEntrySize)
+		if _, err := index.ReadAt(buffer, int64(position*freezerIndexEntrySize)); err != nil {
+			return 0, 0, err
+		}
+		return uint32(binary.BigEndian.Uint16(buffer[:2])), binary.BigEndian.Uint32(buffer[2:]), nil
+	}
+	// the first entry contains the number of items deleted from the tail of the table, each further entry points to
+	// the end of an item
+	deleted, _, err := readEntry(0)
+	if err != nil {
+		return nil, err
+	}
+	entries := uint64(stat.Size()) / freezerIndexEntrySize
+	if item < uint64(deleted) || entries == 0 || item >= uint64(deleted)+entries-1 {
+		return nil, fmt.Errorf("item %d of freezer table %s: %w", item, table, ethereum.NotFound)
+	}
+	position := item - uint64(deleted)
+	startFile, start, err := readEntry(position)
+	if err != nil {
+		return nil, err
+	}
+	endFile, end, err := readEntry(position + 1)
+	if err != nil {
+		return nil, err
+	}
+	if position == 0 || startFile != endFile {
+		// the first item starts at the beginning of its file, an item crossing data files is stored in one piece at
+		// the beginning of the second file
+		start = 0
+	}
+
+	extension := "rdat"
+	if compressed {
+		extension = "cdat"
+	}
+	data, err := os.Open(filepath.Join(source.dir, fmt.Sprintf("%s.%04d.%s", table, endFile, extension)))
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+	blob := make([]byte, end-start)
+	if _, err := data.ReadAt(blob, int64(start)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !compressed {
+		return blob, nil
+	}
+	return snappy.Decode(nil, blob)
+}
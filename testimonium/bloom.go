@@ -0,0 +1,77 @@
+// This file contains the pre-check of receipt verifications proving that an event was emitted. The logs bloom of the
+// block header rules out most blocks that do not contain the event, so building the receipt proof and paying the
+// verification fee can be skipped for them.
+
+package testimonium
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrEventNotInBlock is returned if the logs bloom rules out that the event was emitted in the block.
+var ErrEventNotInBlock = errors.New("event cannot be in this block")
+
+// EventFilter selects the event a receipt verification is supposed to prove. A log matches the filter if it was emitted
+// by the contract (any contract if nil) and contains all topics.
+type EventFilter struct {
+	Contract *common.Address
+	Topics   []common.Hash
+}
+
+func (filter EventFilter) String() string {
+	var parts []string
+	if filter.Contract != nil {
+		parts = append(parts, "contract "+filter.Contract.Hex())
+	}
+	for _, topic := range filter.Topics {
+		parts = append(parts, "topic "+topic.Hex())
+	}
+	if len(parts) == 0 {
+		return "any event"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// matchesBloom returns false if the bloom rules out that a log matching the filter was added to it. Blooms have false
+// positives, so true does not guarantee a match.
+func (filter EventFilter) matchesBloom(bloom types.Bloom) bool {
+	if filter.Contract != nil && !types.BloomLookup(bloom, filter.Contract) {
+		return false
+	}
+	for _, topic := range filter.Topics {
+		if !types.BloomLookup(bloom, topic) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckEventBloom checks the logs bloom of the block containing the transaction with the specified hash, as well as
+// the logs bloom of its receipt, for an event matching the filter. ErrEventNotInBlock is returned if the event cannot
+// have been emitted by the transaction, in that case verifying its receipt does not prove the event.
+func (c Client) CheckEventBloom(txHash common.Hash, filter EventFilter, chain uint8) error {
+	if err := c.checkChain(chain); err != nil {
+		return err
+	}
+
+	receipt, err := c.transactionReceipt(txHash, chain)
+	if err != nil {
+		return err
+	}
+	header, err := c.headerByHash(receipt.BlockHash, chain)
+	if err != nil {
+		return err
+	}
+	if !filter.matchesBloom(header.Bloom) {
+		return fmt.Errorf("%w: %s not in the logs bloom of block %d", ErrEventNotInBlock, filter, header.Number)
+	}
+	if !filter.matchesBloom(receipt.Bloom) {
+		return fmt.Errorf("%w: %s not in the logs bloom of transaction %s", ErrEventNotInBlock, filter, txHash.Hex())
+	}
+	return nil
+}
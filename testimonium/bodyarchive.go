@@ -0,0 +1,433 @@
+// This file contains the local archive of the bodies and receipts of relayed blocks. A relayer archiving the blocks it
+// submits keeps the data needed to verify transactions and receipts of these blocks and to dispute them, independent
+// of providers pruning old blocks. Archived blocks are pruned by age and height.
+
+package testimonium
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// BodyRetention determines how long archived blocks are kept, zero values do not limit the retention.
+type BodyRetention struct {
+	MaxAge    time.Duration // blocks archived longer ago are pruned
+	MaxBlocks uint64        // blocks this many blocks older than the newest archived block are pruned
+}
+
+// archivedBlock is the content of an archive file
+type archivedBlock struct {
+	Header       *types.Header        `json:"header"`
+	Transactions []*types.Transaction `json:"transactions"`
+	Uncles       []*types.Header      `json:"uncles"`
+	Receipts     types.Receipts       `json:"receipts"`
+}
+
+// archivedFile is an archived block known from its file name
+type archivedFile struct {
+	number   uint64
+	archived time.Time
+}
+
+// archivedTransactions is a line of the transaction index of the archive
+type archivedTransactions struct {
+	Block        common.Hash   `json:"block"`
+	Transactions []common.Hash `json:"transactions"`
+}
+
+// BodyArchive stores the bodies and receipts of the blocks of a chain in the data directory, one compressed file per
+// block.
+type BodyArchive struct {
+	dir   string
+	mutex sync.Mutex
+	// archived blocks and the blocks of the archived transactions, read on first use
+	blocks       map[common.Hash]archivedFile
+	transactions map[common.Hash]common.Hash
+}
+
+// BodyArchiveDir returns the directory of the archived blocks of the chain in the data directory.
+func BodyArchiveDir(dataDir string, chain uint8) string {
+	return filepath.Join(dataDir, fmt.Sprintf("bodies-%d", chain))
+}
+
+// OpenBodyArchive opens the archive of the blocks of the chain in the data directory, it is created with the first
+// archived block.
+func OpenBodyArchive(dataDir string, chain uint8) *BodyArchive {
+	return &BodyArchive{dir: BodyArchiveDir(dataDir, chain)}
+}
+
+// WithBodyArchive looks up the headers, bodies and receipts of blocks in the archives of the data directory before
+// fetching them from the chain (see WithBodyArchiving).
+func WithBodyArchive(dataDir string) ClientOption {
+	return func(client *Client) error {
+		client.bodyArchiveDir = dataDir
+		return nil
+	}
+}
+
+// WithBodyArchiving archives the bodies and receipts of the blocks of the source chain relayed by the client with the
+// retention, overriding the "bodyarchive" entry of the chain config. It requires WithBodyArchive.
+func WithBodyArchiving(chain uint8, retention BodyRetention) ClientOption {
+	return func(client *Client) error {
+		if client.bodyRetentions == nil {
+			client.bodyRetentions = make(map[uint8]BodyRetention)
+		}
+		client.bodyRetentions[chain] = retention
+		return nil
+	}
+}
+
+// bodyRetentionFromConfig reads the "bodyarchive" entry of a chain config, e.g.,
+//
+//	bodyarchive:
+//	    maxage: 720h
+//	    maxblocks: 100000
+//
+// Relayed blocks of the chain are only archived if the entry exists.
+func bodyRetentionFromConfig(chainConfig map[string]interface{}) (*BodyRetention, error) {
+	entry, ok := chainConfig["bodyarchive"].(map[string]interface{})
+	if !ok {
+		if chainConfig["bodyarchive"] != nil {
+			return nil, fmt.Errorf("illegal body archive config %v", chainConfig["bodyarchive"])
+		}
+		return nil, nil
+	}
+	retention := new(BodyRetention)
+	var err error
+	if maxAge, exists := entry["maxage"]; exists {
+		if retention.MaxAge, err = time.ParseDuration(fmt.Sprint(maxAge)); err != nil || retention.MaxAge < 0 {
+			return nil, fmt.Errorf("illegal maximum age of archived blocks: %v", maxAge)
+		}
+	}
+	if maxBlocks, exists := entry["maxblocks"]; exists {
+		if retention.MaxBlocks, err = strconv.ParseUint(fmt.Sprint(maxBlocks), 10, 64); err != nil {
+			return nil, fmt.Errorf("illegal maximum number of archived blocks: %v", maxBlocks)
+		}
+	}
+	return retention, nil
+}
+
+// Store archives the block with its receipts. Transactions and receipts that do not match the roots of the header are
+// rejected.
+func (a *BodyArchive) Store(block *types.Block, receipts types.Receipts) error {
+	if root := types.DeriveSha(block.Transactions()); root != block.TxHash() {
+		return fmt.Errorf("%w: transactions of block %s", ErrRootMismatch, block.Hash().Hex())
+	}
+	if root := types.DeriveSha(receipts); root != block.ReceiptHash() {
+		return fmt.Errorf("%w: receipts of block %s", ErrRootMismatch, block.Hash().Hex())
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if err := a.load(); err != nil {
+		return err
+	}
+	if _, exists := a.blocks[block.Hash()]; exists {
+		return nil
+	}
+	if err := os.MkdirAll(a.dir, 0700); err != nil {
+		return err
+	}
+
+	// the file is complete once it is renamed, an interrupted write leaves no partial block behind
+	path := a.path(block.Hash(), block.NumberU64())
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(file)
+	err = json.NewEncoder(writer).Encode(archivedBlock{
+		Header:       block.Header(),
+		Transactions: block.Transactions(),
+		Uncles:       block.Uncles(),
+		Receipts:     receipts,
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+
+	indexed := archivedTransactions{Block: block.Hash()}
+	for _, tx := range block.Transactions() {
+		indexed.Transactions = append(indexed.Transactions, tx.Hash())
+	}
+	if err := a.appendTransactions(indexed); err != nil {
+		return err
+	}
+	a.blocks[block.Hash()] = archivedFile{number: block.NumberU64(), archived: time.Now()}
+	for _, txHash := range indexed.Transactions {
+		a.transactions[txHash] = block.Hash()
+	}
+	return nil
+}
+
+// Block returns the archived block with its receipts, the error wraps ethereum.NotFound if the block is not archived.
+func (a *BodyArchive) Block(hash common.Hash) (*types.Block, types.Receipts, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if err := a.load(); err != nil {
+		return nil, nil, err
+	}
+	file, exists := a.blocks[hash]
+	if !exists {
+		return nil, nil, fmt.Errorf("block %s %w in the body archive", hash.Hex(), ethereum.NotFound)
+	}
+
+	reader, err := os.Open(a.path(hash, file.number))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reader.Close()
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	var archived archivedBlock
+	if err := json.NewDecoder(gzipReader).Decode(&archived); err != nil {
+		return nil, nil, fmt.Errorf("corrupt archived block %s: %s", hash.Hex(), err)
+	}
+	block := types.NewBlockWithHeader(archived.Header).WithBody(archived.Transactions, archived.Uncles)
+	if block.Hash() != hash {
+		return nil, nil, fmt.Errorf("corrupt archived block %s: its header has the hash %s", hash.Hex(), block.Hash().Hex())
+	}
+	return block, archived.Receipts, nil
+}
+
+// TransactionReceipt returns the receipt of the transaction if its block is archived, the error wraps
+// ethereum.NotFound otherwise.
+func (a *BodyArchive) TransactionReceipt(txHash common.Hash) (*types.Receipt, error) {
+	a.mutex.Lock()
+	if err := a.load(); err != nil {
+		a.mutex.Unlock()
+		return nil, err
+	}
+	blockHash, exists := a.transactions[txHash]
+	a.mutex.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("transaction %s %w in the body archive", txHash.Hex(), ethereum.NotFound)
+	}
+
+	_, receipts, err := a.Block(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	for _, receipt := range receipts {
+		if receipt.TxHash == txHash {
+			return receipt, nil
+		}
+	}
+	return nil, fmt.Errorf("transaction %s %w in the archived receipts of block %s", txHash.Hex(), ethereum.NotFound, blockHash.Hex())
+}
+
+// Prune removes the archived blocks exceeding the retention and returns their number.
+func (a *BodyArchive) Prune(retention BodyRetention) (int, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if err := a.load(); err != nil {
+		return 0, err
+	}
+
+	var newest uint64
+	for _, file := range a.blocks {
+		if file.number > newest {
+			newest = file.number
+		}
+	}
+	pruned := 0
+	for hash, file := range a.blocks {
+		tooOld := retention.MaxAge > 0 && time.Since(file.archived) > retention.MaxAge
+		tooLow := retention.MaxBlocks > 0 && file.number+retention.MaxBlocks < newest
+		if !tooOld && !tooLow {
+			continue
+		}
+		if err := os.Remove(a.path(hash, file.number)); err != nil && !os.IsNotExist(err) {
+			return pruned, err
+		}
+		delete(a.blocks, hash)
+		pruned++
+	}
+	if pruned == 0 {
+		return 0, nil
+	}
+
+	// the transactions of the pruned blocks are dropped from the index
+	var index strings.Builder
+	remaining := make(map[common.Hash][]common.Hash)
+	for txHash, blockHash := range a.transactions {
+		if _, exists := a.blocks[blockHash]; !exists {
+			delete(a.transactions, txHash)
+			continue
+		}
+		remaining[blockHash] = append(remaining[blockHash], txHash)
+	}
+	for blockHash, txHashes := range remaining {
+		line, err := json.Marshal(archivedTransactions{Block: blockHash, Transactions: txHashes})
+		if err != nil {
+			return pruned, err
+		}
+		index.Write(line)
+		index.WriteByte('\n')
+	}
+	indexPath := filepath.Join(a.dir, "transactions.jsonl")
+	if err := ioutil.WriteFile(indexPath+".tmp", []byte(index.String()), 0600); err != nil {
+		return pruned, err
+	}
+	return pruned, os.Rename(indexPath+".tmp", indexPath)
+}
+
+func (a *BodyArchive) path(hash common.Hash, number uint64) string {
+	return filepath.Join(a.dir, fmt.Sprintf("%d-%s.json.gz", number, hash.Hex()))
+}
+
+// load reads the archived blocks from the file names and the transaction index, the mutex has to be held
+func (a *BodyArchive) load() error {
+	if a.blocks != nil {
+		return nil
+	}
+	blocks := make(map[common.Hash]archivedFile)
+	transactions := make(map[common.Hash]common.Hash)
+	files, err := ioutil.ReadDir(a.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".json.gz")
+		separator := strings.Index(name, "-")
+		if name == file.Name() || separator < 0 {
+			continue
+		}
+		number, err := strconv.ParseUint(name[:separator], 10, 64)
+		hash := name[separator+1:]
+		if err != nil || len(hash) != 2+2*common.HashLength || !strings.HasPrefix(hash, "0x") {
+			continue
+		}
+		blocks[common.HexToHash(hash)] = archivedFile{number: number, archived: file.ModTime()}
+	}
+
+	index, err := os.Open(filepath.Join(a.dir, "transactions.jsonl"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		defer index.Close()
+		scanner := bufio.NewScanner(index)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var line archivedTransactions
+			// the last line is incomplete if the process crashed while writing it
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				continue
+			}
+			if _, exists := blocks[line.Block]; exists {
+				for _, txHash := range line.Transactions {
+					transactions[txHash] = line.Block
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+	a.blocks, a.transactions = blocks, transactions
+	return nil
+}
+
+// appendTransactions adds the transactions of an archived block to the index, the mutex has to be held
+func (a *BodyArchive) appendTransactions(indexed archivedTransactions) error {
+	line, err := json.Marshal(indexed)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(a.dir, "transactions.jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// archivedBlockSource provides archived blocks from the archive and all other data from the next source
+type archivedBlockSource struct {
+	archive *BodyArchive
+	next    BlockSource
+}
+
+func (source archivedBlockSource) Name() string {
+	return fmt.Sprintf("%s, archived blocks %s", source.next.Name(), source.archive.dir)
+}
+
+func (source archivedBlockSource) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return source.next.HeaderByNumber(ctx, number)
+}
+
+func (source archivedBlockSource) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	if block, _, err := source.archive.Block(hash); err == nil {
+		return block.Header(), nil
+	}
+	return source.next.HeaderByHash(ctx, hash)
+}
+
+func (source archivedBlockSource) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return source.next.BlockByNumber(ctx, number)
+}
+
+func (source archivedBlockSource) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	if block, _, err := source.archive.Block(hash); err == nil {
+		return block, nil
+	}
+	return source.next.BlockByHash(ctx, hash)
+}
+
+func (source archivedBlockSource) BlockReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	if _, receipts, err := source.archive.Block(hash); err == nil {
+		return receipts, nil
+	}
+	return source.next.BlockReceipts(ctx, hash)
+}
+
+// archiveBody archives the body and receipts of a relayed block if the source chain archives its blocks. Failures are
+// reported as warnings, they do not affect the relay.
+func (c Client) archiveBody(header *types.Header, sourceChain uint8) {
+	source := c.chain(sourceChain)
+	if source.bodyArchive == nil || source.bodyRetention == nil {
+		return
+	}
+	block, err := c.BlockByHash(header.Hash(), sourceChain)
+	if err == nil {
+		var receipts types.Receipts
+		if receipts, err = c.blockReceipts(header.Hash(), sourceChain); err == nil {
+			err = source.bodyArchive.Store(block, receipts)
+		}
+	}
+	if err != nil {
+		c.progressf("WARNING: Block %s of chain %d not archived: %s\n", header.Number, sourceChain, err)
+		return
+	}
+	if _, err := source.bodyArchive.Prune(*source.bodyRetention); err != nil {
+		c.progressf("WARNING: Archived blocks of chain %d not pruned: %s\n", sourceChain, err)
+	}
+}
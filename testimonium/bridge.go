@@ -0,0 +1,248 @@
+// This file contains the continuous verification of events emitted by a contract on the source chain, i.e., a
+// cross-chain event bridge built on the relay.
+
+package testimonium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// errEventReorganised is returned by verifyEventReceipt if the transaction of the event is not part of the event's
+// block anymore
+var errEventReorganised = errors.New("block of the event is not part of the source chain anymore")
+
+// EventWatch specifies the events that are verified by WatchAndVerifyEvents.
+type EventWatch struct {
+	Contract  common.Address
+	Topics    [][]common.Hash // topic filter as in eth_getLogs, the first position is the event signature
+	FromBlock uint64          // first scanned block of the source chain, the most recent block if zero
+	// number of blocks on top of the event's block, both on the source chain before the block is scanned and in the
+	// relay before the event is verified
+	Confirmations uint8
+	PollInterval  time.Duration
+}
+
+// VerifiedEvent is an event of a watched contract whose receipt was verified on the destination chain.
+type VerifiedEvent struct {
+	TxHash      common.Hash `json:"txHash"`
+	BlockHash   common.Hash `json:"blockHash"`
+	BlockNumber uint64      `json:"blockNumber"`
+	LogIndexes  []uint      `json:"logIndexes"` // indexes of the matching logs, all are covered by the receipt
+	Result      *TxResult   `json:"result,omitempty"`
+}
+
+// WatchAndVerifyEvents scans the source chain for events matching the watch and verifies the receipt of each
+// transaction emitting such events on the destination chain, as soon as its block is stored in the relay with the
+// required number of confirmations. Blocks are scanned once they have the required number of confirmations on the
+// source chain. Events whose transaction moved to another block in a reorganisation of the source chain are looked up
+// again by their transaction and stay pending until the new block is confirmed. The outcome of every verification is
+// passed to handle, failed verifications are not retried. The function returns if ctx is cancelled or the chains
+// cannot be queried anymore.
+func (c Client) WatchAndVerifyEvents(ctx context.Context, watch EventWatch, sourceChain uint8, destinationChain uint8,
+	handle func(event VerifiedEvent, err error)) error {
+	if err := c.checkChain(sourceChain); err != nil {
+		return err
+	}
+	if err := c.checkTestimonium(destinationChain); err != nil {
+		return err
+	}
+
+	pollInterval := watch.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = 15 * time.Second
+	}
+
+	nextBlock := watch.FromBlock
+	if nextBlock == 0 {
+		header, err := c.chain(sourceChain).client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return err
+		}
+		nextBlock = header.Number.Uint64()
+	}
+
+	// events whose blocks are not yet confirmed in the relay, in order of their appearance
+	var pending []*VerifiedEvent
+	pendingByTx := make(map[common.Hash]*VerifiedEvent)
+
+	for {
+		latest, err := c.chain(sourceChain).client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return err
+		}
+		// blocks without the required confirmations may still be reorganised, e.g., adding matching events
+		scanUntil := int64(latest.Number.Uint64()) - int64(watch.Confirmations)
+
+		for int64(nextBlock) <= scanUntil {
+			toBlock := nextBlock + eventScanBatchSize - 1
+			if toBlock > uint64(scanUntil) {
+				toBlock = uint64(scanUntil)
+			}
+
+			logs, err := c.chain(sourceChain).filterLogs(ctx, ethereum.FilterQuery{
+				FromBlock: new(big.Int).SetUint64(nextBlock),
+				ToBlock:   new(big.Int).SetUint64(toBlock),
+				Addresses: []common.Address{watch.Contract},
+				Topics:    watch.Topics,
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, vLog := range logs {
+				if vLog.Removed {
+					continue
+				}
+				// a single receipt proof covers all events of a transaction
+				if event, exists := pendingByTx[vLog.TxHash]; exists {
+					event.LogIndexes = append(event.LogIndexes, vLog.Index)
+					continue
+				}
+				event := &VerifiedEvent{
+					TxHash:      vLog.TxHash,
+					BlockHash:   vLog.BlockHash,
+					BlockNumber: vLog.BlockNumber,
+					LogIndexes:  []uint{vLog.Index},
+				}
+				pending = append(pending, event)
+				pendingByTx[vLog.TxHash] = event
+				c.progressf("Found event in tx %s (block %d), waiting for confirmations\n", vLog.TxHash.Hex(), vLog.BlockNumber)
+			}
+
+			nextBlock = toBlock + 1
+		}
+
+		confirmedUntil, err := c.confirmedBlockNumber(destinationChain, watch.Confirmations)
+		if err != nil {
+			return err
+		}
+
+		remaining := pending[:0]
+		for _, event := range pending {
+			if event.BlockNumber > confirmedUntil {
+				remaining = append(remaining, event)
+				continue
+			}
+
+			result, err := c.verifyEventReceipt(*event, sourceChain, destinationChain, watch.Confirmations)
+			if errors.Is(err, errEventReorganised) {
+				if err := c.resolveEvent(ctx, event, watch, sourceChain); err != nil {
+					c.progressf("Block %s of the event in tx %s was reorganised, tx not found yet: %s\n",
+						event.BlockHash.Hex(), event.TxHash.Hex(), err)
+				} else {
+					c.progressf("Event in tx %s moved to block %d, waiting for confirmations\n", event.TxHash.Hex(), event.BlockNumber)
+				}
+				remaining = append(remaining, event)
+				continue
+			}
+			delete(pendingByTx, event.TxHash)
+			event.Result = result
+			handle(*event, err)
+		}
+		pending = remaining
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// confirmedBlockNumber returns the number of the most recent block of the relay's longest chain that has the
+// specified number of confirmations
+func (c Client) confirmedBlockNumber(chain uint8, confirmations uint8) (uint64, error) {
+	endpoint, err := c.chain(chain).testimoniumContract.GetLongestChainEndpoint(c.callOpts())
+	if err != nil {
+		return 0, err
+	}
+	header, err := c.chain(chain).testimoniumContract.GetHeader(c.callOpts(), endpoint)
+	if err != nil {
+		return 0, err
+	}
+	if header.BlockNumber.Uint64() < uint64(confirmations) {
+		return 0, nil
+	}
+	return header.BlockNumber.Uint64() - uint64(confirmations), nil
+}
+
+func (c Client) verifyEventReceipt(event VerifiedEvent, sourceChain uint8, destinationChain uint8, confirmations uint8) (*TxResult, error) {
+	proof, header, err := c.BuildReceiptProof(event.TxHash, sourceChain)
+	if err != nil {
+		return nil, err
+	}
+	if header.Hash() != event.BlockHash {
+		// the transaction was included in another block after a reorganisation of the source chain
+		return nil, fmt.Errorf("%w: %s", errEventReorganised, event.BlockHash.Hex())
+	}
+
+	stored, err := c.chain(destinationChain).testimoniumContract.IsHeaderStored(c.callOpts(), event.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	if !stored {
+		return nil, fmt.Errorf("block %s of the event is not stored in the relay", event.BlockHash.Hex())
+	}
+
+	rlpHeader, rlpEncodedReceipt, path, rlpEncodedProofNodes, err := encodeProof(header, proof)
+	if err != nil {
+		return nil, err
+	}
+
+	feeInWei, err := c.chain(destinationChain).requiredVerificationFee()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.VerifyMerkleProof(feeInWei, rlpHeader, VALUE_TYPE_RECEIPT, rlpEncodedReceipt, path, rlpEncodedProofNodes,
+		confirmations, destinationChain)
+}
+
+// resolveEvent looks up the block and the matching logs of the event's transaction again after a reorganisation
+func (c Client) resolveEvent(ctx context.Context, event *VerifiedEvent, watch EventWatch, sourceChain uint8) error {
+	receipt, err := c.chain(sourceChain).client.TransactionReceipt(ctx, event.TxHash)
+	if err != nil {
+		return err
+	}
+	var logIndexes []uint
+	for _, vLog := range receipt.Logs {
+		if watch.matches(vLog) {
+			logIndexes = append(logIndexes, vLog.Index)
+		}
+	}
+	if len(logIndexes) == 0 {
+		return fmt.Errorf("no matching events in tx %s anymore", event.TxHash.Hex())
+	}
+	event.BlockHash = receipt.BlockHash
+	event.BlockNumber = receipt.BlockNumber.Uint64()
+	event.LogIndexes = logIndexes
+	return nil
+}
+
+// matches reports whether the log is emitted by the watched contract and matches the topic filter
+func (watch EventWatch) matches(vLog *types.Log) bool {
+	if vLog.Address != watch.Contract || len(vLog.Topics) < len(watch.Topics) {
+		return false
+	}
+	for i, alternatives := range watch.Topics {
+		if len(alternatives) == 0 {
+			continue
+		}
+		matched := false
+		for _, topic := range alternatives {
+			matched = matched || vLog.Topics[i] == topic
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,255 @@
+// This file contains the capability probing of the chains' nodes. Providers differ in the optional methods they
+// support (eth_getProof, eth_getBlockReceipts, the txpool API, subscriptions) and in how much state they keep, so the
+// methods are probed once when a chain is connected and operations select the code paths the node supports instead of
+// failing deep inside, e.g., after fetching all the data of a proof.
+
+package testimonium
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// CAPABILITY_PROBE_TIMEOUT limits the time spent probing the node of a chain when it is connected
+const CAPABILITY_PROBE_TIMEOUT = 15 * time.Second
+
+// HEAD_POLL_INTERVAL is the interval new heads are polled with if the node does not support subscriptions
+const HEAD_POLL_INTERVAL = 4 * time.Second
+
+// depths below the head the state is probed at if the node is no archive node, the largest first (geth keeps the state
+// of the latest 128 blocks)
+var stateProbeDepths = []uint64{100000, 10000, 1000, 127}
+
+// error messages of nodes and providers rejecting a method they do not support
+var unsupportedMethodHints = []string{"method not found", "does not exist", "not supported", "unsupported", "not available",
+	"not allowed", "disabled", "not whitelisted"}
+
+// Capabilities are the optional methods and the state the node of a chain provides. Nodes that are not probed (see
+// WithoutCapabilityProbing) are assumed to support everything.
+type Capabilities struct {
+	Probed        bool `json:"probed"`
+	GetProof      bool `json:"getProof"`      // eth_getProof, light account proofs are built with the archive node otherwise
+	BlockReceipts bool `json:"blockReceipts"` // eth_getBlockReceipts, receipts are fetched one by one otherwise
+	TxPool        bool `json:"txPool"`        // the txpool API, the pending transactions cannot be listed otherwise
+	Subscriptions bool `json:"subscriptions"` // new heads are polled if not supported
+	Archive       bool `json:"archive"`       // the node provides the state of all blocks
+	// StateDepth is the largest probed number of blocks below the head whose state the node provides (unless it is an
+	// archive node), state of older blocks is fetched from the archive node right away
+	StateDepth uint64 `json:"stateDepth"`
+	Head       uint64 `json:"head"` // the latest block when the node was probed
+}
+
+// allCapabilities are assumed for nodes that are not probed
+var allCapabilities = Capabilities{GetProof: true, BlockReceipts: true, TxPool: true, Subscriptions: true, Archive: true}
+
+// HasState returns whether the node provided the state of the block when it was probed.
+func (caps Capabilities) HasState(blockNumber uint64) bool {
+	return caps.Archive || blockNumber+caps.StateDepth >= caps.Head
+}
+
+// String returns the capability matrix in a single line, e.g., "eth_getProof yes, eth_getBlockReceipts no, ...".
+func (caps Capabilities) String() string {
+	if !caps.Probed {
+		return "not probed"
+	}
+	yesNo := func(supported bool) string {
+		if supported {
+			return "yes"
+		}
+		return "no"
+	}
+	state := "archive"
+	if !caps.Archive {
+		state = fmt.Sprintf("latest %d blocks", caps.StateDepth)
+	}
+	return fmt.Sprintf("eth_getProof %s, eth_getBlockReceipts %s, txpool %s, subscriptions %s, state %s",
+		yesNo(caps.GetProof), yesNo(caps.BlockReceipts), yesNo(caps.TxPool), yesNo(caps.Subscriptions), state)
+}
+
+// WithoutCapabilityProbing connects to the chains without probing the capabilities of their nodes, all optional
+// methods are assumed to be supported then. Recorded sessions are replayed without probing as well.
+func WithoutCapabilityProbing() ClientOption {
+	return func(client *Client) error {
+		client.noCapabilityProbing = true
+		return nil
+	}
+}
+
+// Capabilities returns the capabilities of the chain's node probed when the chain was connected.
+func (c Client) Capabilities(chain uint8) (Capabilities, error) {
+	if err := c.checkChain(chain); err != nil {
+		return Capabilities{}, err
+	}
+	return c.chain(chain).capabilities, nil
+}
+
+// probeCapabilities probes the optional methods and the state depth of the node. Checks that fail for other reasons
+// than an unsupported method (e.g., a timeout) keep the capability, so a slow node does not disable code paths.
+func probeCapabilities(ctx context.Context, client *ethclient.Client, rpcClient *rpc.Client) (Capabilities, error) {
+	ctx, cancel := context.WithTimeout(ctx, CAPABILITY_PROBE_TIMEOUT)
+	defer cancel()
+
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return allCapabilities, err
+	}
+	caps := Capabilities{Probed: true, Head: head.Number.Uint64()}
+
+	var proof interface{}
+	caps.GetProof = supportsMethod(rpcClient.CallContext(ctx, &proof, "eth_getProof", common.Address{}, []string{}, "latest"))
+
+	var receipts []interface{}
+	caps.BlockReceipts = supportsMethod(rpcClient.CallContext(ctx, &receipts, "eth_getBlockReceipts", head.Hash()))
+
+	var status interface{}
+	caps.TxPool = supportsMethod(rpcClient.CallContext(ctx, &status, "txpool_status"))
+
+	// subscriptions are only supported by websocket and IPC connections
+	heads := make(chan *types.Header)
+	if sub, err := client.SubscribeNewHead(ctx, heads); err == nil {
+		caps.Subscriptions = true
+		sub.Unsubscribe()
+	}
+
+	hasState := func(blockNumber uint64) bool {
+		_, err := client.BalanceAt(ctx, common.Address{}, new(big.Int).SetUint64(blockNumber))
+		return err == nil || !isPruned(err) && ctx.Err() == nil
+	}
+	if caps.Head <= stateProbeDepths[len(stateProbeDepths)-1] || hasState(1) {
+		caps.Archive = true
+		return caps, nil
+	}
+	for _, depth := range stateProbeDepths {
+		if depth < caps.Head && hasState(caps.Head-depth) {
+			caps.StateDepth = depth
+			break
+		}
+	}
+	return caps, nil
+}
+
+// supportsMethod returns false if the error of a request shows that the node does not support the method
+func supportsMethod(err error) bool {
+	if err == nil {
+		return true
+	}
+	if rpcErr, ok := err.(rpc.Error); ok && rpcErr.ErrorCode() == -32601 {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	for _, hint := range unsupportedMethodHints {
+		if strings.Contains(message, hint) {
+			return false
+		}
+	}
+	return true
+}
+
+// probeChainCapabilities probes the node of the chain being connected, unless probing is disabled or the session is
+// replayed, and selects the code paths of the chain accordingly
+func (c Client) probeChainCapabilities(chain *Chain) {
+	chain.capabilities = allCapabilities
+	if c.noCapabilityProbing || c.replay {
+		return
+	}
+	caps, err := probeCapabilities(c.context(), chain.client, chain.rpcClient)
+	if err != nil {
+		c.problemf("Could not probe the capabilities of the node of chain %d, assuming all are supported: %s", chain.id, err)
+		return
+	}
+	chain.capabilities = caps
+
+	if !caps.BlockReceipts {
+		chain.blockSource = withoutBlockReceipts(chain.blockSource)
+	}
+}
+
+// withoutBlockReceipts returns the block source fetching receipts one by one instead of with eth_getBlockReceipts,
+// sources other than the JSON-RPC API are returned unchanged
+func withoutBlockReceipts(source BlockSource) BlockSource {
+	switch source := source.(type) {
+	case rpcBlockSource:
+		source.receiptsByTransaction = true
+		return source
+	case erigonBlockSource:
+		source.receiptsByTransaction = true
+		return source
+	case freezerBlockSource:
+		source.next = withoutBlockReceipts(source.next)
+		return source
+	default:
+		return source
+	}
+}
+
+// withStateNode calls fetch with the node providing the state of the block: the chain's node if it supports
+// eth_getProof and still keeps the state of the block (falling back to the archive node, see withArchiveFallback),
+// otherwise the archive node right away.
+func (c Client) withStateNode(chain uint8, blockNumber uint64, what string, fetch func(client *ethclient.Client, rpcClient *rpc.Client) error) error {
+	source := c.chain(chain)
+	caps := source.capabilities
+	if caps.GetProof && caps.HasState(blockNumber) {
+		return c.withArchiveFallback(chain, what, fetch)
+	}
+	if source.archiveClient == nil {
+		if !caps.GetProof {
+			return fmt.Errorf("%s on chain %d: the node does not support eth_getProof (no archive node configured)", what, chain)
+		}
+		// the state depth is only probed at a few depths, the node may still provide it
+		return c.withArchiveFallback(chain, what, fetch)
+	}
+	return fetch(source.archiveClient, source.archiveRpcClient)
+}
+
+// subscribeNewHeads subscribes to the new heads of the chain, if its node does not support subscriptions the heads are
+// polled instead. All heads are sent in order, also if several blocks were mined between two polls.
+func (chain *Chain) subscribeNewHeads(ctx context.Context, heads chan<- *types.Header) (ethereum.Subscription, error) {
+	if chain.capabilities.Subscriptions {
+		return chain.client.SubscribeNewHead(ctx, heads)
+	}
+
+	latest, err := chain.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	next := new(big.Int).Add(latest.Number, common.Big1)
+	return event.NewSubscription(func(unsubscribed <-chan struct{}) error {
+		ticker := time.NewTicker(HEAD_POLL_INTERVAL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-unsubscribed:
+				return nil
+			case <-ticker.C:
+			}
+			latest, err := chain.client.HeaderByNumber(ctx, nil)
+			if err != nil {
+				// a failed poll is repeated with the next tick
+				continue
+			}
+			for ; next.Cmp(latest.Number) <= 0; next.Add(next, common.Big1) {
+				header := latest
+				if next.Cmp(latest.Number) < 0 {
+					if header, err = chain.client.HeaderByNumber(ctx, next); err != nil {
+						break
+					}
+				}
+				select {
+				case heads <- header:
+				case <-unsubscribed:
+					return nil
+				}
+			}
+		}
+	}), nil
+}
@@ -0,0 +1,82 @@
+// This file contains the verification of the chain and network ids reported by the nodes against the configuration.
+// Sending a transaction to the wrong chain (e.g., submitting main net headers to a test net contract) can be costly,
+// so transactions are refused for chains whose ids do not match the configured ones.
+
+package testimonium
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ErrChainIdMismatch is returned when a transaction should be sent to a chain whose node reports a chain id or
+// network id different from the configured one.
+var ErrChainIdMismatch = errors.New("chain id mismatch")
+
+// verifyChainIds compares the chain id (eth_chainId) and network id (net_version) reported by the node with the
+// values configured for the chain ("chainid" and "networkid"). Ids that are not configured are not compared.
+func (c Client) verifyChainIds(chain *Chain, chainConfig map[string]interface{}) error {
+	expectedChainId, err := configuredId(chainConfig, "chainid")
+	if err != nil {
+		return err
+	}
+	expectedNetworkId, err := configuredId(chainConfig, "networkid")
+	if err != nil {
+		return err
+	}
+
+	if expectedChainId != nil {
+		chainId, err := chain.client.ChainID(c.context())
+		if err != nil {
+			// eth_chainId is not supported by older nodes, the network id is still compared
+			c.problemf("Cannot verify chain id of %s: %s", chain.fullUrl, err)
+		} else if chainId.Cmp(expectedChainId) != 0 {
+			return fmt.Errorf("%w: node %s reports chain id %s, configured is %s", ErrChainIdMismatch, chain.fullUrl, chainId, expectedChainId)
+		}
+	}
+
+	if expectedNetworkId != nil {
+		networkId, err := chain.client.NetworkID(c.context())
+		if err != nil {
+			return fmt.Errorf("%w: cannot verify network id of %s: %s", ErrChainIdMismatch, chain.fullUrl, err)
+		}
+		if networkId.Cmp(expectedNetworkId) != 0 {
+			return fmt.Errorf("%w: node %s reports network id %s, configured is %s", ErrChainIdMismatch, chain.fullUrl, networkId, expectedNetworkId)
+		}
+	}
+
+	return nil
+}
+
+// configuredId reads an id of the chain config. Ids are uint256 values, ids beyond the int64 range have to be written
+// as strings (e.g., "0x..."), as they cannot be represented exactly as YAML or JSON numbers.
+func configuredId(chainConfig map[string]interface{}, key string) (*big.Int, error) {
+	var value *big.Int
+	switch id := chainConfig[key].(type) {
+	case nil:
+		return nil, nil
+	case int:
+		value = big.NewInt(int64(id))
+	case int64:
+		value = big.NewInt(id)
+	case uint64:
+		value = new(big.Int).SetUint64(id)
+	case float64:
+		if id != float64(int64(id)) {
+			return nil, fmt.Errorf("invalid %s '%v' (write large ids as strings)", key, id)
+		}
+		value = big.NewInt(int64(id))
+	case string:
+		var ok bool
+		if value, ok = new(big.Int).SetString(id, 0); !ok {
+			return nil, fmt.Errorf("invalid %s '%s'", key, id)
+		}
+	default:
+		return nil, fmt.Errorf("invalid %s '%v'", key, id)
+	}
+	if value.Sign() < 0 || value.Cmp(MAX_CHAIN_ID) > 0 {
+		return nil, fmt.Errorf("invalid %s '%s' (not a uint256)", key, value)
+	}
+	return value, nil
+}
@@ -0,0 +1,355 @@
+// This file contains trusted checkpoints of source chains. Instead of relaying thousands of headers from an old
+// genesis block, the ETH Relay contract is deployed with a recent checkpoint agreed on out-of-band: a file containing
+// the header, its total difficulty and the signatures of the parties vouching for it. Before the checkpoint is used,
+// the signatures are checked against the trusted signers and the checkpoint is compared with several RPC providers.
+
+package testimonium
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrUntrustedCheckpoint is returned if a checkpoint does not satisfy the checkpoint policy.
+var ErrUntrustedCheckpoint = errors.New("untrusted checkpoint")
+
+// CHECKPOINT_MIN_PROVIDERS is the default number of RPC providers that have to agree with a checkpoint.
+const CHECKPOINT_MIN_PROVIDERS = 2
+
+// Checkpoint is a block of a source chain the relay can start from.
+type Checkpoint struct {
+	ChainId         *big.Int              `json:"chainId"`     // of the source chain
+	GenesisHash     common.Hash           `json:"genesisHash"` // of the source chain
+	BlockNumber     uint64                `json:"blockNumber"`
+	BlockHash       common.Hash           `json:"blockHash"`
+	TotalDifficulty *big.Int              `json:"totalDifficulty"`
+	RlpHeader       hexutil.Bytes         `json:"rlpHeader"`
+	Signatures      []CheckpointSignature `json:"signatures,omitempty"`
+}
+
+// CheckpointSignature is the signature of a checkpoint (EIP-191 personal signature of Checkpoint.Digest).
+type CheckpointSignature struct {
+	Signer    common.Address `json:"signer"`
+	Signature hexutil.Bytes  `json:"signature"`
+}
+
+// CheckpointPolicy determines whether a checkpoint is trusted.
+type CheckpointPolicy struct {
+	TrustedSigners []common.Address
+	Threshold      int // signatures of trusted signers required, all trusted signers if not positive
+	MinProviders   int // providers that have to agree with the checkpoint, CHECKPOINT_MIN_PROVIDERS if not positive
+}
+
+// CheckpointValidation is the result of ValidateCheckpoint.
+type CheckpointValidation struct {
+	Signers  []common.Address `json:"signers"`  // trusted signers with a valid signature
+	Agreeing []string         `json:"agreeing"` // providers returning the checkpoint block
+	Problems []string         `json:"problems"` // disagreements and unreachable providers
+	Trusted  bool             `json:"trusted"`  // the checkpoint satisfies the policy
+}
+
+// Digest returns the hash signed by the signers of the checkpoint.
+func (cp Checkpoint) Digest() common.Hash {
+	number := make([]byte, 8)
+	binary.BigEndian.PutUint64(number, cp.BlockNumber)
+	chainId, totalDifficulty := new(big.Int), new(big.Int)
+	if cp.ChainId != nil {
+		chainId = cp.ChainId
+	}
+	if cp.TotalDifficulty != nil {
+		totalDifficulty = cp.TotalDifficulty
+	}
+	return crypto.Keccak256Hash([]byte("ethrelay checkpoint"), common.LeftPadBytes(chainId.Bytes(), 32),
+		cp.GenesisHash[:], number, cp.BlockHash[:], common.LeftPadBytes(totalDifficulty.Bytes(), 32))
+}
+
+// Header decodes the header of the checkpoint and checks that it is the checkpoint block.
+func (cp Checkpoint) Header() (*types.Header, error) {
+	header, err := decodeHeaderFromRLP(cp.RlpHeader)
+	if err != nil {
+		return nil, fmt.Errorf("illegal checkpoint header: %s", err)
+	}
+	if header.Hash() != cp.BlockHash || header.Number.Uint64() != cp.BlockNumber {
+		return nil, fmt.Errorf("checkpoint header is block %d (%s), not block %d (%s)", header.Number, header.Hash().Hex(),
+			cp.BlockNumber, cp.BlockHash.Hex())
+	}
+	return header, nil
+}
+
+// Signers returns the addresses of the valid signatures of the checkpoint.
+func (cp Checkpoint) Signers() []common.Address {
+	digest := cp.Digest()
+	var signers []common.Address
+	seen := make(map[common.Address]bool)
+	for _, signature := range cp.Signatures {
+		if len(signature.Signature) != 65 {
+			continue
+		}
+		sig := common.CopyBytes(signature.Signature)
+		if sig[64] >= 27 {
+			sig[64] -= 27
+		}
+		publicKey, err := crypto.SigToPub(accountsTextHash(digest.Bytes()), sig)
+		if err != nil {
+			continue
+		}
+		signer := crypto.PubkeyToAddress(*publicKey)
+		if signer != signature.Signer || seen[signer] {
+			continue
+		}
+		seen[signer] = true
+		signers = append(signers, signer)
+	}
+	return signers
+}
+
+// ReadCheckpoint reads a checkpoint file.
+func ReadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cp := new(Checkpoint)
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("illegal checkpoint file %s: %s", path, err)
+	}
+	return cp, nil
+}
+
+// WriteCheckpoint writes the checkpoint to a file.
+func WriteCheckpoint(path string, cp *Checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// CreateCheckpoint returns the unsigned checkpoint of the block of the source chain.
+func (c Client) CreateCheckpoint(blockNumber uint64, sourceChain uint8) (*Checkpoint, error) {
+	if err := c.checkChain(sourceChain); err != nil {
+		return nil, err
+	}
+	ctx := c.context()
+	chainId, err := c.chain(sourceChain).client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve chain id: %s", err)
+	}
+	genesis, err := c.HeaderByNumber(big.NewInt(0), sourceChain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve genesis block: %s", err)
+	}
+	header, err := c.HeaderByNumber(new(big.Int).SetUint64(blockNumber), sourceChain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve header from source chain: %s", err)
+	}
+	totalDifficulty, err := c.TotalDifficulty(header.Number, sourceChain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve total difficulty of block %d: %s", blockNumber, err)
+	}
+	rlpHeader, err := encodeHeaderToRLP(header)
+	if err != nil {
+		return nil, err
+	}
+	return &Checkpoint{
+		ChainId:         chainId,
+		GenesisHash:     genesis.Hash(),
+		BlockNumber:     blockNumber,
+		BlockHash:       header.Hash(),
+		TotalDifficulty: totalDifficulty,
+		RlpHeader:       rlpHeader,
+	}, nil
+}
+
+// SignCheckpoint adds the signature of the client's account to the checkpoint, replacing an earlier one.
+func (c Client) SignCheckpoint(cp *Checkpoint) error {
+	if c.privateKey == nil {
+		return ErrNoAccount
+	}
+	if _, err := cp.Header(); err != nil {
+		return err
+	}
+	signature, err := crypto.Sign(accountsTextHash(cp.Digest().Bytes()), c.privateKey)
+	if err != nil {
+		return err
+	}
+	signature[64] += 27
+
+	signatures := []CheckpointSignature{{Signer: c.account, Signature: signature}}
+	for _, other := range cp.Signatures {
+		if other.Signer != c.account {
+			signatures = append(signatures, other)
+		}
+	}
+	cp.Signatures = signatures
+	return nil
+}
+
+// ValidateCheckpoint checks the checkpoint of the source chain against the policy: the header has to match the
+// checkpoint, enough trusted signers have to have signed it, and the node of the source chain and its cross-check
+// providers have to agree on the chain id, the genesis block, the checkpoint block and its total difficulty. The
+// returned validation lists the agreeing providers and the problems, the error wraps ErrUntrustedCheckpoint if the
+// policy is not satisfied.
+func (c Client) ValidateCheckpoint(cp *Checkpoint, sourceChain uint8, policy CheckpointPolicy) (*CheckpointValidation, error) {
+	if err := c.checkChain(sourceChain); err != nil {
+		return nil, err
+	}
+	if _, err := cp.Header(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUntrustedCheckpoint, err)
+	}
+	if cp.ChainId == nil || cp.TotalDifficulty == nil {
+		return nil, fmt.Errorf("%w: chain id or total difficulty missing", ErrUntrustedCheckpoint)
+	}
+	validation := &CheckpointValidation{}
+
+	trusted := make(map[common.Address]bool)
+	for _, signer := range policy.TrustedSigners {
+		trusted[signer] = true
+	}
+	for _, signer := range cp.Signers() {
+		if trusted[signer] {
+			validation.Signers = append(validation.Signers, signer)
+		}
+	}
+	threshold := policy.Threshold
+	if threshold <= 0 {
+		threshold = len(trusted)
+	}
+	if len(validation.Signers) < threshold {
+		validation.Problems = append(validation.Problems, fmt.Sprintf("%d of %d required signatures of trusted signers",
+			len(validation.Signers), threshold))
+	}
+
+	chain := c.chain(sourceChain)
+	providers := []crossCheckSource{{url: chain.fullUrl, client: chain.client, rpc: chain.rpcClient}}
+	providers = append(providers, chain.crossCheckSources...)
+	totalDifficultyConfirmed, disagreement := false, false
+	for _, provider := range providers {
+		confirmed, err := checkpointAgreement(cp, provider)
+		if err != nil {
+			validation.Problems = append(validation.Problems, fmt.Sprintf("%s: %s", provider.url, err))
+			// providers that cannot be reached do not fail the validation if enough others agree
+			disagreement = disagreement || !errors.Is(err, errProviderUnavailable)
+			continue
+		}
+		validation.Agreeing = append(validation.Agreeing, provider.url)
+		totalDifficultyConfirmed = totalDifficultyConfirmed || confirmed
+	}
+	minProviders := policy.MinProviders
+	if minProviders <= 0 {
+		minProviders = CHECKPOINT_MIN_PROVIDERS
+	}
+	if len(validation.Agreeing) < minProviders {
+		validation.Problems = append(validation.Problems, fmt.Sprintf("%d of %d required providers agree (add providers to the crosscheck entry of chain %d)",
+			len(validation.Agreeing), minProviders, sourceChain))
+	}
+	if !totalDifficultyConfirmed {
+		validation.Problems = append(validation.Problems, "no provider reports the total difficulty")
+	}
+
+	validation.Trusted = len(validation.Signers) >= threshold && len(validation.Agreeing) >= minProviders &&
+		totalDifficultyConfirmed && !disagreement
+	if !validation.Trusted {
+		return validation, fmt.Errorf("%w: block %d (%s)", ErrUntrustedCheckpoint, cp.BlockNumber, cp.BlockHash.Hex())
+	}
+	return validation, nil
+}
+
+// errProviderUnavailable marks errors of providers that could not be queried, as opposed to disagreeing providers
+var errProviderUnavailable = errors.New("unavailable")
+
+// checkpointAgreement checks that the provider returns the checkpoint block. It reports whether the provider returned
+// the total difficulty of the block, which post-merge providers no longer do.
+func checkpointAgreement(cp *Checkpoint, provider crossCheckSource) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	chainId, err := provider.client.ChainID(ctx)
+	if err != nil {
+		return false, fmt.Errorf("%w: chain id: %s", errProviderUnavailable, err)
+	}
+	if chainId.Cmp(cp.ChainId) != 0 {
+		return false, fmt.Errorf("chain id %s instead of %s", chainId, cp.ChainId)
+	}
+	genesis, err := provider.client.HeaderByNumber(ctx, big.NewInt(0))
+	if err != nil {
+		return false, fmt.Errorf("%w: genesis block: %s", errProviderUnavailable, err)
+	}
+	if genesis.Hash() != cp.GenesisHash {
+		return false, fmt.Errorf("genesis block %s instead of %s", genesis.Hash().Hex(), cp.GenesisHash.Hex())
+	}
+	header, err := provider.client.HeaderByNumber(ctx, new(big.Int).SetUint64(cp.BlockNumber))
+	if err != nil {
+		return false, fmt.Errorf("%w: block %d: %s", errProviderUnavailable, cp.BlockNumber, err)
+	}
+	if header.Hash() != cp.BlockHash {
+		return false, fmt.Errorf("block %d is %s instead of %s", cp.BlockNumber, header.Hash().Hex(), cp.BlockHash.Hex())
+	}
+
+	if provider.rpc == nil {
+		return false, nil
+	}
+	var totalDifficulty *TotalDifficulty
+	if err := provider.rpc.CallContext(ctx, &totalDifficulty, "eth_getBlockByNumber", hexutil.EncodeUint64(cp.BlockNumber), false); err != nil {
+		return false, fmt.Errorf("%w: total difficulty: %s", errProviderUnavailable, err)
+	}
+	if totalDifficulty == nil || totalDifficulty.TotalDifficulty == "" {
+		return false, nil
+	}
+	reported, err := hexutil.DecodeBig(totalDifficulty.TotalDifficulty)
+	if err != nil {
+		return false, fmt.Errorf("illegal total difficulty: %s", err)
+	}
+	if reported.Cmp(cp.TotalDifficulty) != 0 {
+		return false, fmt.Errorf("total difficulty %s instead of %s", reported, cp.TotalDifficulty)
+	}
+	return true, nil
+}
+
+// DeployTestimoniumFromCheckpoint deploys the Testimonium contract on the destination chain with the checkpoint as
+// genesis block, after ValidateCheckpoint accepted it. With a salt, the contract is deployed with the CREATE2 deployer
+// (see DeployTestimoniumCreate2).
+func (c Client) DeployTestimoniumFromCheckpoint(destinationChain uint8, sourceChain uint8, cp *Checkpoint, policy CheckpointPolicy,
+	salt *common.Hash) (common.Address, error) {
+	if err := c.checkEthash(destinationChain); err != nil {
+		return common.Address{}, err
+	}
+	validation, err := c.ValidateCheckpoint(cp, sourceChain, policy)
+	if err != nil {
+		if validation != nil {
+			err = fmt.Errorf("%w: %s", err, strings.Join(validation.Problems, "; "))
+		}
+		return common.Address{}, err
+	}
+	for _, problem := range validation.Problems {
+		c.progressf("WARNING: Checkpoint: %s\n", problem)
+	}
+
+	header, err := cp.Header()
+	if err != nil {
+		return common.Address{}, err
+	}
+	genesis := &Genesis{
+		SourceChain:       sourceChain,
+		BlockNumber:       cp.BlockNumber,
+		BlockHash:         cp.BlockHash,
+		TotalDifficulty:   cp.TotalDifficulty,
+		CheckpointSigners: validation.Signers,
+	}
+	if salt != nil {
+		return c.deployTestimoniumCreate2(destinationChain, genesis, header, *salt)
+	}
+	return c.deployTestimonium(destinationChain, genesis, header)
+}
@@ -10,12 +10,12 @@ import (
 	"crypto/ecdsa"
 	"errors"
 	"fmt"
-	"github.com/ethereum/go-ethereum/accounts/abi"
-	"log"
+	"io"
 	"math/big"
-	"os"
+	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -27,28 +27,130 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
-	"github.com/ethereum/go-ethereum/trie"
 	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+	"github.com/pantos-io/go-ethrelay/proofs"
 	"github.com/pantos-io/go-ethrelay/typedefs"
 )
 
+// ErrHeaderAlreadyStored is returned when a header is submitted that is already stored in the Testimonium contract.
+// Such a submission would be reverted by the contract, so no transaction is sent.
+var ErrHeaderAlreadyStored = errors.New("header already stored")
+
+// ErrParentNotStored is returned when a header is submitted whose parent is not yet stored in the Testimonium contract.
+var ErrParentNotStored = errors.New("parent header not stored")
+
 type ChainConfig map[string]interface{}
 
 type ChainsConfig map[uint8]ChainConfig
 
+
+
 type Chain struct {
+	id                         uint8
 	client                     *ethclient.Client
+	rpcClient                  *rpc.Client
 	testimoniumContractAddress common.Address
 	testimoniumContract        *Testimonium
 	ethashContractAddress      common.Address
 	ethashContract             *ethash.Ethash
 	fullUrl                    string
+	idMismatch                 error // set if the node's chain or network id differs from the configured one
+	multicallAddress           common.Address
+	feeToken                   common.Address // ERC-20 token the verification fee is paid in if set (config entry "feetoken")
+	create2Deployer            common.Address
+	codec                      HeaderCodec        // encoding of the chain's headers
+	relayer                    Relayer            // state-changing calls are sent through the relayer if set
+	relayedTasks               *sync.Map          // hashes of the relayed (unsent) transactions to relay task ids
+	privateRelay               PrivateRelay       // disputes are sent through the private relay if set
+	substitutedTxs             *sync.Map          // hashes of transactions created by the bindings to the hashes of the sent ones
+	operators                  map[common.Address]*operator // operator roles signing with keys of their own by account
+	noAccessLists              *int32             // set (atomically) if the node cannot create access lists
+	crossCheckSources          []crossCheckSource // headers of the chain are compared with these providers before they are relayed
+	crossCheckErr              error              // set if the cross-check providers cannot be used, no headers are relayed
+	archiveClient              *ethclient.Client  // data of old blocks is fetched from this node if the chain's node does not provide it
+	archiveRpcClient           *rpc.Client
+	blockSource                BlockSource    // headers, blocks and receipts are fetched from this source
+	bodyArchive                *BodyArchive   // relayed blocks archived in the data directory, not used if nil
+	bodyRetention              *BodyRetention // relayed blocks are archived with this retention if set
+	lightProofs                bool        // proofs are built without downloading block bodies (see WithLightProofs)
+	gasLimits                  GasLimits   // gas limits of the transactions by operation, estimated if empty
+	role                       ChainRole // no transactions are sent to source chains
+	tdCache                    *tdCache  // total difficulties computed if the node does not provide them
+	txLog                      *TxLog    // transactions are persisted before they are broadcast if set
+	testimoniumABI             string    // ABI of the ETH Relay contract variant, the bundled ABI is used if empty
+	signingChainId             *big.Int  // transactions are signed according to EIP-155 with this chain id if set
+	chainIdSource              string    // origin of the signing chain id, e.g., CHAIN_ID_NODE
+	transactOptsModifiers      []TransactOptsModifier
+	retryPolicies              RetryPolicies // transient failures of operations on the chain are retried with these policies
+	capabilities               Capabilities  // optional methods supported by the node, probed when the chain is connected
+	viewCache                  *viewCache    // results of view calls of static contract parameters, not cached if nil
+	instances                  map[string]relayInstance // named ETH Relay contracts of the "ethrelays" entry
+	instance                   string                   // name of the instance used as ETH Relay contract, empty for the default one
+	deploymentRegistry         *common.Address          // deployments are announced to this contract if set
 }
 
+
 type Client struct {
-	chains     map[uint8]*Chain
+	chains     map[uint8]*Chain // connected chains (read with chain and eachChain), the configured chains are dialed on first use
+	dialer     *chainDialer
+	connecting *chainProblems // problems of the chain the copy connects to, printed as warnings if not set
 	account    common.Address
 	privateKey *ecdsa.PrivateKey
+	// the client's own account, account and privateKey are replaced in the copies signing for an operator role
+	defaultAccount    common.Address
+	defaultPrivateKey *ecdsa.PrivateKey
+	operators         map[OperatorRole]*operator // roles signing with keys of their own
+	reserveNonces     bool                       // the nonces of the client's own account are reserved if set
+	accountOperator   *operator                  // the client's own account if its nonces are reserved
+	transport  http.RoundTripper // used for HTTP connections if set
+	retryPolicies RetryPolicies // set with WithRetryPolicy, the defaults are used for the other operations
+	noCapabilityProbing bool // the nodes are assumed to support all optional methods if set
+	viewCacheTTL *time.Duration // set with WithViewCacheTTL, view calls are not cached if nil
+	relayInstance string // name of the ETH Relay instance used instead of the default contract if set
+	replay     bool
+	ctx        context.Context // parent of the contexts of requests, context.Background() if nil
+	// headers fetched from a source chain are validated with this level before they are submitted
+	validationLevel ValidationLevel
+	indexDir        string // data directory containing the event indexes, not used if empty
+	relayers        map[uint8]Relayer
+	privateRelays   map[uint8]PrivateRelay
+	noAccessLists   bool      // disputes and verifications are sent without EIP-2930 access lists if set
+	progress        io.Writer // progress messages are written to stdout if not set
+	relayPolicy     RelayPolicy
+	liveMonitor     *LiveMonitor           // progress of the live mode is reported to the monitor if set
+	gasCeiling      *GasCeiling            // the live mode pauses submissions above the gas price if set
+	tracer          *Tracer                // operations and RPC requests are traced if set
+	span            *Span                  // the current operation, operations and RPC requests of the client are its children
+	analytics       *AnalyticsExporter     // submissions, disputes, verifications and stake movements are exported if set
+	registryDir     string                 // data directory containing the registries of deployed contracts, not used if empty
+	deploymentMetadata map[string]string // deployed contracts are tagged with this metadata
+	chainRoles      map[uint8]ChainRole    // roles overriding the role entries of the chain configs
+	witnessProvider ethash.WitnessProvider // witnesses of disputes are computed without a DAG cache if not set
+	txLogDir        string                 // data directory containing the transaction logs, not used if empty
+	noTxLogReconciliation bool // unresolved transactions of previous runs are not reconciled when a chain is connected if set
+	testimoniumABIs map[uint8]string       // ABIs of ETH Relay contract variants overriding the "ethrelayabi" entries
+	// directory the evidence of disputes is archived in, not used if empty
+	disputeArchiveDir string
+	blockSources      map[uint8]BlockSource // sources overriding the "blocksource" entries of the chain configs
+	lightProofChains  map[uint8]bool        // chains whose proofs are built without block bodies
+	gasLimits         map[uint8]GasLimits   // gas limits overriding the "gaslimits" entries of the chain configs
+	bodyArchiveDir    string                // data directory containing the archived blocks, not used if empty
+	// retentions overriding the "bodyarchive" entries of the chain configs
+	bodyRetentions map[uint8]BodyRetention
+	// applied to the options of every transaction signed by the account
+	transactOptsModifiers []TransactOptsModifier
+}
+
+// ClientOption configures optional behaviour of the client. Options are applied before connecting to the chains.
+type ClientOption func(client *Client) error
+
+// WithContext sets the parent context of the client's requests. Canceling it (e.g., on Ctrl-C or when a daemon shuts
+// down) stops requests and waits for transaction receipts.
+func WithContext(ctx context.Context) ClientOption {
+	return func(client *Client) error {
+		client.ctx = ctx
+		return nil
+	}
 }
 
 type Header struct {
@@ -57,24 +159,8 @@ type Header struct {
 	TotalDifficulty           *big.Int
 }
 
-type FullHeader struct {
-	Parent                    [32]byte
-	UncleHash                 [32]byte
-	StateRoot                 [32]byte
-	TransactionsRoot          [32]byte
-	ReceiptsRoot              [32]byte
-	BlockNumber               *big.Int
-	GasLimit                  *big.Int
-	GasUsed                   *big.Int
-	RlpHeaderHashWithoutNonce [32]byte
-	Timestamp                 *big.Int
-	Nonce                     *big.Int
-	Difficulty                *big.Int
-	ExtraData                 *byte
-}
-
 type VerificationResult struct {
-	returnCode uint8
+	ReturnCode uint8 `json:"returnCode"`
 }
 
 type TrieValueType int
@@ -85,24 +171,41 @@ const (
 	VALUE_TYPE_STATE       TrieValueType = 2
 )
 
-func (header FullHeader) String() string {
-	return fmt.Sprintf(`BlockHeader: {
-Parent: %s,
-StateRoot: %s,
-TransactionsRoot: %s,
-ReceiptsRoot: %s,
-BlockNumber: %s,
-RlpHeaderHashWithoutNonce: %s,
-Nonce: %s,
-Difficulty: %s }`,
-		common.Bytes2Hex(header.Parent[:]),
-		common.Bytes2Hex(header.StateRoot[:]),
-		common.Bytes2Hex(header.TransactionsRoot[:]),
-		common.Bytes2Hex(header.ReceiptsRoot[:]),
-		header.BlockNumber.String(),
-		common.Bytes2Hex(header.RlpHeaderHashWithoutNonce[:]),
-		header.Nonce.String(),
-		header.Difficulty.String())
+func (t TrieValueType) String() string {
+	verifier, err := t.verifier()
+	if err != nil {
+		return fmt.Sprintf("unknown(%d)", int(t))
+	}
+	return verifier.Name
+}
+
+// MarshalText encodes the value type by its name, e.g., in JSON results.
+func (t TrieValueType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes the value type from its name (see ParseTrieValueType).
+func (t *TrieValueType) UnmarshalText(text []byte) error {
+	valueType, err := ParseTrieValueType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = valueType
+	return nil
+}
+
+// ParseTrieValueType parses the names of the registered value types, e.g., "transaction" (or "tx"), "receipt" and
+// "state".
+func ParseTrieValueType(valueType string) (TrieValueType, error) {
+	var names []string
+	for _, registered := range ValueTypes() {
+		verifier, _ := registered.verifier()
+		if verifier.hasName(valueType) {
+			return registered, nil
+		}
+		names = append(names, verifier.Name)
+	}
+	return VALUE_TYPE_TRANSACTION, fmt.Errorf("unknown value type '%s' (%s)", valueType, strings.Join(names, ", "))
 }
 
 func (t TestimoniumSubmitBlock) String() string {
@@ -118,7 +221,7 @@ func (event TestimoniumPoWValidationResult) String() string {
 }
 
 func (result VerificationResult) String() string {
-	return fmt.Sprintf("VerificationResult: { returnCode: %d }", result.returnCode)
+	return fmt.Sprintf("VerificationResult: { returnCode: %d }", result.ReturnCode)
 }
 
 func (event TestimoniumWithdrawStake) String() string {
@@ -139,90 +242,148 @@ func CreateChainConfig(connectionType string, connectionUrl string, connectionPo
 	return chainConfig
 }
 
-func NewClient(privateKey string, chainsConfig map[string]interface{}) *Client {
+// NewClient creates a client of the configured chains. The chains are connected on first use, which prints the
+// problems found as warnings, or with Connect, which returns them in a ClientReport instead. It fails if an option,
+// the private key or the chain ids of the config are illegal, and with ErrUnknownRelayInstance if no chain config names
+// the instance set with WithRelayInstance.
+func NewClient(privateKey string, chainsConfig map[string]interface{}, opts ...ClientOption) (*Client, error) {
 	client := new(Client)
 	client.chains = make(map[uint8]*Chain)
 
-	for k, v := range chainsConfig {
-		chainId, err := strconv.ParseInt(k, 10, 8)
-		if err != nil {
-			log.Fatal(err)
+	for _, opt := range opts {
+		if err := opt(client); err != nil {
+			return nil, err
 		}
+	}
 
-		chainConfig := v.(map[string]interface{})
-
-		// create client connection
-		var ethClient *ethclient.Client
-		fullUrl, err := createConnectionUrl(chainConfig)
+	// get public address, the private key is only required to send transactions (see ChainRole)
+	if privateKey != "" {
+		privateKeyBytes, err := hexutil.Decode(privateKey)
 		if err != nil {
-			fmt.Printf("WARNING: Could not read url specified for chain %d (%s)\n", chainId, err)
-			continue
+			return nil, fmt.Errorf("could not decode private key, is it a correct hex string (0x...)? %w", err)
 		}
-
-		ethClient, err = ethclient.Dial(fullUrl)
+		ecdsaPrivateKey, err := crypto.ToECDSA(privateKeyBytes)
 		if err != nil {
-			fmt.Printf("WARNING: Cannot connect to chain %d (%s): %s\n", chainId, fullUrl, err)
-			continue // --> even if we cannot connect to this chain, we still try to connect to the other ones
+			return nil, err
+		}
+		client.privateKey = ecdsaPrivateKey
+		publicKey := ecdsaPrivateKey.Public()
+		publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, errors.New("error casting public key to ECDSA")
 		}
 
-		chain := new(Chain)
-		chain.client = ethClient
-		chain.fullUrl = fullUrl
+		client.account = crypto.PubkeyToAddress(*publicKeyECDSA)
+	}
+	client.defaultAccount, client.defaultPrivateKey = client.account, client.privateKey
+	if client.reserveNonces && client.privateKey != nil {
+		client.accountOperator = &operator{
+			account:    client.account,
+			privateKey: client.privateKey,
+			spent:      make(map[uint8][]*spending),
+			nonces:     make(map[uint8]nonceReservation),
+		}
+	}
 
-		// create testimonium contract instance
-		var testimoniumContract *Testimonium
-		addressHex := chainConfig["ethrelayaddress"]
-		if addressHex != nil {
-			ethrelayAddress := common.HexToAddress(addressHex.(string))
-			testimoniumContract, err = NewTestimonium(ethrelayAddress, ethClient)
-			if err != nil {
-				fmt.Printf("WARNING: No Testimonium contract deployed at address %s on chain %d (%s)\n", addressHex, chainId, fullUrl)
-			} else {
-				chain.testimoniumContract = testimoniumContract
-				chain.testimoniumContractAddress = ethrelayAddress
+	client.dialer = &chainDialer{configs: make(map[uint8]map[string]interface{}), dialing: make(map[uint8]chan struct{}),
+		problems: make(map[uint8][]ChainProblem)}
+	for k, v := range chainsConfig {
+		chainId, err := strconv.ParseInt(k, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("illegal chain id %s: %w", k, err)
+		}
+		client.dialer.configs[uint8(chainId)] = v.(map[string]interface{})
+	}
+	if client.relayInstance != "" && !client.hasRelayInstance(client.relayInstance) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRelayInstance, client.relayInstance)
+	}
+	return client, nil
+}
+
+func (c Client) dial(fullUrl string, provider *ProviderConfig, connection *ConnectionConfig, retry RetryPolicies) (*rpc.Client, error) {
+	isHttp := strings.HasPrefix(fullUrl, "http://") || strings.HasPrefix(fullUrl, "https://")
+
+	// transport reaching the node, with the headers and TLS options of the connection config
+	base := http.DefaultTransport
+	if connection != nil && !c.replay {
+		if !isHttp {
+			c.progressf("WARNING: The headers and TLS options of %s are ignored, they are only supported for http connections\n", fullUrl)
+		} else {
+			var err error
+			if base, err = connection.newTransport(); err != nil {
+				return nil, err
 			}
 		}
+	}
 
-		// create ethash contract instance
-		var ethashContract *ethash.Ethash
-		addressHex = chainConfig["ethashaddress"]
-		if addressHex != nil {
-			ethashAddress := common.HexToAddress(addressHex.(string))
-			ethashContract, err = ethash.NewEthash(ethashAddress, ethClient)
-			if err != nil {
-				fmt.Printf("WARNING: No Ethash contract deployed at address %s on chain %d (%s)\n", addressHex, chainId, fullUrl)
+	// a replayed recording does not reach the provider, so no quota is used
+	if provider != nil && !c.replay {
+		if !isHttp {
+			c.progressf("WARNING: The provider config of %s is ignored, it is only supported for http connections\n", fullUrl)
+		} else {
+			var transport http.RoundTripper
+			if recorder, ok := c.transport.(*recordingTransport); ok {
+				// the exchanges are recorded without the API keys
+				transport = recorder.through(newProviderTransport(*provider, base, c.progressf))
+			} else if c.transport != nil {
+				transport = newProviderTransport(*provider, c.transport, c.progressf)
 			} else {
-				chain.ethashContract = ethashContract
-				chain.ethashContractAddress = ethashAddress
+				transport = newProviderTransport(*provider, base, c.progressf)
 			}
+			return c.dialHTTP(fullUrl, transport, retry)
 		}
+	}
 
-		client.chains[uint8(chainId)] = chain
+	if c.transport == nil {
+		if isHttp {
+			return c.dialHTTP(fullUrl, base, retry)
+		}
+		return dialRetrying(fullUrl, retry)
 	}
 
-	// get public address
-	privateKeyBytes, err := hexutil.Decode(privateKey)
-	if err != nil {
-		fmt.Println("Could not decode private key. Is it a correct hex string (0x...)?")
-		os.Exit(1)
+	// when replaying, no connection is established, so websocket urls can be served by the transport as well
+	if c.replay || isHttp {
+		if recorder, ok := c.transport.(*recordingTransport); ok && connection != nil {
+			return c.dialHTTP(fullUrl, recorder.through(base), retry)
+		}
+		return c.dialHTTP(fullUrl, c.transport, retry)
 	}
-	ecdsaPrivateKey, err := crypto.ToECDSA(privateKeyBytes)
-	if err != nil {
-		log.Fatal(err)
+
+	c.progressf("WARNING: Exchanges with %s are not recorded, recording is only supported for http connections\n", fullUrl)
+	return dialRetrying(fullUrl, retry)
+}
+
+func (c Client) dialHTTP(fullUrl string, transport http.RoundTripper, retry RetryPolicies) (*rpc.Client, error) {
+	if c.tracer != nil {
+		transport = c.tracer.rpcTransport(transport)
 	}
-	client.privateKey = ecdsaPrivateKey
-	publicKey := ecdsaPrivateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatal("error casting public key to ECDSA")
+	// replayed exchanges do not fail transiently
+	if !c.replay {
+		transport = &retryTransport{policy: retry.Of(RETRY_RPC), transport: transport}
 	}
+	return rpc.DialHTTPWithClient(fullUrl, &http.Client{Transport: transport})
+}
 
-	client.account = crypto.PubkeyToAddress(*publicKeyECDSA)
-
-	return client
+// dialRetrying establishes a websocket or IPC connection, the dial retry policy applies
+func dialRetrying(fullUrl string, retry RetryPolicies) (*rpc.Client, error) {
+	var rpcClient *rpc.Client
+	err := retry.Of(RETRY_DIAL).Do(context.Background(), func() error {
+		var err error
+		rpcClient, err = rpc.Dial(fullUrl)
+		return err
+	})
+	return rpcClient, err
 }
 
 func createConnectionUrl(chainConfig map[string]interface{}) (string, error) {
+	// the current layout contains the scheme and the port in the url (see "config migrate")
+	if url, ok := chainConfig["url"].(string); ok && strings.Contains(url, "://") {
+		if chainConfig["type"] != nil || chainConfig["port"] != nil {
+			return "", fmt.Errorf("type or port specified for url %s containing a scheme", url)
+		}
+		return url, nil
+	}
+
 	fullUrl := ""
 	if chainConfig["type"] != nil {
 		fullUrl += chainConfig["type"].(string) + "://"
@@ -251,29 +412,74 @@ func createConnectionUrl(chainConfig map[string]interface{}) (string, error) {
 	return fullUrl, nil
 }
 
+// Chains connects to all configured chains (see Connect) and returns the ids of the connected ones.
 func (c Client) Chains() []uint8 {
-	keys := make([]uint8, len(c.chains))
-
-	i := 0
-	for k := range c.chains {
-		keys[i] = k
-		i++
-	}
-	return keys
+	c.connectAll()
+	return c.connectedChains()
 }
 
 func (c Client) Account() string {
 	return c.account.Hex()
 }
 
+// ChainBalance is the balance of the current account on a single chain. If the balance could not be queried,
+// Balance is nil and Err contains the reason.
+type ChainBalance struct {
+	Chain   uint8
+	Balance *big.Int
+	Err     error
+}
+
+const (
+	// maximum number of chains that are queried at the same time
+	balanceQueryParallelism = 4
+	// maximum time a single chain is given to answer a balance query
+	balanceQueryTimeout = 10 * time.Second
+)
+
+// Balances queries the balance of the current account on all chains transactions can be sent to (all chains except
+// source chains) concurrently. The result contains an entry for each of these chains (ordered by chain ID), chains that
+// could not be queried in time are reported with an error.
+func (c Client) Balances() []ChainBalance {
+	chains := c.DestinationChains()
+
+	balances := make([]ChainBalance, len(chains))
+	semaphore := make(chan struct{}, balanceQueryParallelism)
+	var wg sync.WaitGroup
+
+	for i, chainId := range chains {
+		wg.Add(1)
+		go func(i int, chainId uint8) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			ctx, cancel := context.WithTimeout(c.context(), balanceQueryTimeout)
+			defer cancel()
+
+			balance, err := c.chain(chainId).client.BalanceAt(ctx, c.account, nil)
+			balances[i] = ChainBalance{Chain: chainId, Balance: balance, Err: err}
+		}(i, chainId)
+	}
+	wg.Wait()
+
+	return balances
+}
+
+// TotalBalance returns the sum of the balances of the current account on all chains. If some chains could not be
+// queried, the sum of the remaining chains is returned together with an error listing the failed chains.
 func (c Client) TotalBalance() (*big.Int, error) {
 	var totalBalance = new(big.Int)
-	for k, _ := range c.chains {
-		balance, err := c.Balance(k)
-		if err != nil {
-			return nil, err
+	var failures []string
+	for _, balance := range c.Balances() {
+		if balance.Err != nil {
+			failures = append(failures, fmt.Sprintf("chain %d: %s", balance.Chain, balance.Err))
+			continue
 		}
-		totalBalance.Add(totalBalance, balance)
+		totalBalance.Add(totalBalance, balance.Balance)
+	}
+	if len(failures) > 0 {
+		return totalBalance, fmt.Errorf("could not query balance of %s", strings.Join(failures, ", "))
 	}
 	return totalBalance, nil
 }
@@ -281,12 +487,11 @@ func (c Client) TotalBalance() (*big.Int, error) {
 func (c Client) Balance(chainId uint8) (*big.Int, error) {
 	var totalBalance = new(big.Int);
 
-	_, exists := c.chains[chainId]
-	if !exists {
-		return nil, fmt.Errorf("chain %s does not exist", chainId)
+	if err := c.checkChain(chainId); err != nil {
+		return nil, err
 	}
 
-	balance, err := c.chains[chainId].client.BalanceAt(context.Background(), c.account, nil)
+	balance, err := c.chain(chainId).client.BalanceAt(c.context(), c.account, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -297,11 +502,11 @@ func (c Client) Balance(chainId uint8) (*big.Int, error) {
 }
 
 func (c Client) GetStake(chainId uint8) (*big.Int, error) {
-	_, exists := c.chains[chainId]
-	if !exists {
-		return nil, fmt.Errorf("chain %s does not exist", chainId)
+	c = c.as(OPERATOR_SUBMITTER)
+	if err := c.checkTestimonium(chainId); err != nil {
+		return nil, err
 	}
-	stake, err := c.chains[chainId].testimoniumContract.GetStake(
+	stake, err := c.chain(chainId).testimoniumContract.GetStake(
 		&bind.CallOpts{
 			From: c.account,
 		})
@@ -311,122 +516,248 @@ func (c Client) GetStake(chainId uint8) (*big.Int, error) {
 	return stake, nil
 }
 
-func (c Client) DepositStake(chainId uint8, amountInWei *big.Int) error {
-	_, exists := c.chains[chainId]
-	if !exists {
-		return fmt.Errorf("chain %s does not exist", chainId)
+func (c Client) DepositStake(chainId uint8, amountInWei *big.Int) (*TxResult, error) {
+	result, err := c.depositStake(chainId, amountInWei)
+	c.publishTxEvent(AnalyticsEvent{Type: ANALYTICS_EVENT_STAKE_DEPOSIT, Chain: chainId, Account: c.as(OPERATOR_SUBMITTER).account,
+		AmountInWei: amountInWei}, result, err)
+	return result, err
+}
+
+func (c Client) depositStake(chainId uint8, amountInWei *big.Int) (*TxResult, error) {
+	c = c.as(OPERATOR_SUBMITTER)
+	if err := c.checkTestimonium(chainId); err != nil {
+		return nil, err
 	}
 
-	auth := prepareTransaction(c.account, c.privateKey, c.chains[chainId], amountInWei)
+	auth, err := prepareTransaction(c.context(), c.account, c.privateKey, c.chain(chainId), amountInWei)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err := c.chains[chainId].testimoniumContract.DepositStake(auth, amountInWei)
+	tx, err := c.chain(chainId).testimoniumContract.DepositStake(auth, amountInWei)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// fmt.Printf("Tx submitted: %s\n", tx.Hash().Hex())
+	// the receipt is not awaited
+	return newTxResult(tx, nil), nil
+}
 
-	return nil
+func (c Client) WithdrawStake(chainId uint8, amountInWei *big.Int) (*TxResult, error) {
+	result, err := c.withdrawStake(chainId, amountInWei)
+	c.publishTxEvent(AnalyticsEvent{Type: ANALYTICS_EVENT_STAKE_WITHDRAWAL, Chain: chainId, Account: c.as(OPERATOR_SUBMITTER).account,
+		AmountInWei: amountInWei}, result, err)
+	return result, err
 }
 
-func (c Client) WithdrawStake(chainId uint8, amountInWei *big.Int) error {
-	_, exists := c.chains[chainId]
-	if !exists {
-		return fmt.Errorf("chain %s does not exist", chainId)
+func (c Client) withdrawStake(chainId uint8, amountInWei *big.Int) (*TxResult, error) {
+	c = c.as(OPERATOR_SUBMITTER)
+	if err := c.checkTestimonium(chainId); err != nil {
+		return nil, err
 	}
 
-	auth := prepareTransaction(c.account, c.privateKey, c.chains[chainId], big.NewInt(0))
-
-	tx, err := c.chains[chainId].testimoniumContract.WithdrawStake(auth, amountInWei)
+	auth, err := prepareTransaction(c.context(), c.account, c.privateKey, c.chain(chainId), big.NewInt(0))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// fmt.Printf("Tx submitted: %s\n", tx.Hash().Hex())
+	tx, err := c.chain(chainId).testimoniumContract.WithdrawStake(auth, amountInWei)
+	if err != nil {
+		return nil, err
+	}
 
-	receipt, err := awaitTxReceipt(c.chains[chainId].client, tx.Hash())
+	receipt, err := awaitTxReceipt(c.context(), c.chain(chainId), tx.Hash())
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if receipt.Status == 0 {
 		// Transaction failed
-		reason := getFailureReason(c.chains[chainId].client, c.account, tx, receipt.BlockNumber)
-		return fmt.Errorf("Tx failed: %s\n", reason)
+		reason := getFailureReason(c.chain(chainId).client, c.account, tx, receipt.BlockNumber)
+		return nil, fmt.Errorf("Tx failed: %s\n", reason)
 	}
 
 	// Transaction is successful
-	eventIterator, err := c.chains[chainId].testimoniumContract.TestimoniumFilterer.FilterWithdrawStake(&bind.FilterOpts{
+	eventIterator, err := c.chain(chainId).testimoniumContract.TestimoniumFilterer.FilterWithdrawStake(&bind.FilterOpts{
 		Start:   receipt.BlockNumber.Uint64(),
 		End:     nil,
 		Context: nil,
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if eventIterator.Next() {
-		// fmt.Printf("Tx successful: %s\n", eventIterator.Event.String())
-
+		result := newTxResult(tx, receipt)
+		result.Events = append(result.Events, eventIterator.Event.String())
 		if eventIterator.Event.WithdrawnStake.Cmp(amountInWei) != 0 {
-			return errors.New("withdraw not successful, reason: more than 'amount' stake is locked in contract")
+			// the transaction was sent anyway, so its result is returned as well
+			return result, ErrStakeLocked
 		}
-
-		return nil
+		return result, nil
 	}
 
-	return errors.New("uncaught error")
+	return nil, errors.New("uncaught error")
 }
 
 func (c Client) BlockHeaderExists(blockHash [32]byte, chain uint8) (bool, error) {
-	_, exists := c.chains[chain]
-	if !exists {
-		return false, fmt.Errorf("chain %s does not exist", chain)
+	if err := c.checkTestimonium(chain); err != nil {
+		return false, err
 	}
 
-	return c.chains[chain].testimoniumContract.IsHeaderStored(nil, blockHash)
+	return c.chain(chain).testimoniumContract.IsHeaderStored(c.callOpts(), blockHash)
 }
 
 func (c Client) GetLongestChainEndpoint(chain uint8) ([32]byte, error) {
-	_, exists := c.chains[chain]
-	if !exists {
-		fmt.Errorf("chain %s does not exist", chain)
+	if err := c.checkTestimonium(chain); err != nil {
+		return [32]byte{}, err
 	}
 
-	return c.chains[chain].testimoniumContract.GetLongestChainEndpoint(nil)
+	return c.chain(chain).testimoniumContract.GetLongestChainEndpoint(c.callOpts())
 }
 
 func (c Client) GetBlockHeader(blockHash [32]byte, chain uint8) (Header, error) {
-	return c.chains[chain].testimoniumContract.GetHeader(nil, blockHash)
+	if err := c.checkTestimonium(chain); err != nil {
+		return Header{}, err
+	}
+	return c.chain(chain).testimoniumContract.GetHeader(c.callOpts(), blockHash)
+}
+
+// GetFullBlockHeader returns all fields of a header stored in the Testimonium contract. The contract only stores the
+// hash, number and total difficulty, the other fields are decoded from the submitted RLP header, which is taken from
+// the event index if the client has one, otherwise the SubmitBlock events are scanned.
+func (c Client) GetFullBlockHeader(blockHash [32]byte, chain uint8) (FullHeader, error) {
+	storedHeader, err := c.GetBlockHeader(blockHash, chain)
+	if err != nil {
+		return FullHeader{}, err
+	}
+	if storedHeader.Hash != blockHash {
+		return FullHeader{}, fmt.Errorf("block %s is not stored in the contract", common.Bytes2Hex(blockHash[:]))
+	}
+
+	rlpHeader, err := c.submittedRlpHeader(blockHash, chain)
+	if err != nil {
+		return FullHeader{}, fmt.Errorf("failed to retrieve the submitted header: %s", err)
+	}
+	header, err := decodeHeaderFromRLP(rlpHeader)
+	if err != nil {
+		return FullHeader{}, fmt.Errorf("failed to decode the submitted header: %s", err)
+	}
+	if header.Hash() != blockHash || header.Number.Cmp(storedHeader.BlockNumber) != 0 {
+		return FullHeader{}, fmt.Errorf("submitted header %s does not match the stored header %s", header.Hash().Hex(), common.Bytes2Hex(blockHash[:]))
+	}
+
+	fullHeader, err := NewFullHeader(header)
+	if err != nil {
+		return FullHeader{}, err
+	}
+	fullHeader.TotalDifficulty = storedHeader.TotalDifficulty
+	return fullHeader, nil
 }
 
 func (c Client) GetOriginalBlockHeader(blockHash [32]byte, chain uint8) (*types.Block, error) {
-	return c.chains[chain].client.BlockByHash(context.Background(), common.BytesToHash(blockHash[:]))
+	if err := c.checkChain(chain); err != nil {
+		return nil, err
+	}
+	return c.BlockByHash(common.BytesToHash(blockHash[:]), chain)
 }
 
-func (c Client) SubmitHeader(header *types.Header, chain uint8) (error) {
-	if _, exists := c.chains[chain]; !exists {
-		log.Fatalf("Chain '%d' does not exist", chain)
+func (c Client) SubmitHeader(header *types.Header, chain uint8) (*TxResult, error) {
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, err
 	}
 
-	fmt.Printf("Submitting block: \nNo: %s\nHash: %s\n", header.Number.String(), header.Hash().String())
+	c.progressf("Submitting block: \nNo: %s\nHash: %s\n", header.Number.String(), header.Hash().String())
 
 	rlpHeader, err := encodeHeaderToRLP(header)
 	if err != nil {
-		log.Fatal("Failed to encode header to RLP: " + err.Error())
+		return nil, fmt.Errorf("failed to encode header to RLP: %s", err)
 	}
 
 	return c.SubmitRLPHeader(rlpHeader, chain)
 }
 
-func (c Client) SubmitHeaderLive(destinationChain uint8, sourceChain uint8, lockTime time.Duration) {
+// SubmitHeaderWithAncestors submits the header to the destination chain. If ancestors of the header are not yet stored
+// in the Testimonium contract, they are fetched from the source chain and submitted first (oldest first).
+// At most maxAncestors missing ancestors are submitted, if more are missing an error is returned and nothing is submitted.
+// The results of all sent transactions are returned, the header's result last.
+func (c Client) SubmitHeaderWithAncestors(header *types.Header, destinationChain uint8, sourceChain uint8, maxAncestors int) ([]*TxResult, error) {
+	if err := c.checkTestimonium(destinationChain); err != nil {
+		return nil, err
+	}
+	if err := c.checkChain(sourceChain); err != nil {
+		return nil, err
+	}
+
+	var missing []*types.Header
+	parentHash := header.ParentHash
+	for {
+		isParentStored, err := c.chain(destinationChain).testimoniumContract.IsHeaderStored(c.callOpts(), parentHash)
+		if err != nil {
+			return nil, err
+		}
+		if isParentStored {
+			break
+		}
+		if len(missing) >= maxAncestors {
+			return nil, fmt.Errorf("%w: more than %d ancestors of block %s are missing", ErrParentNotStored, maxAncestors, header.Hash().String())
+		}
+
+		parent, err := c.HeaderByHash(parentHash, sourceChain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve ancestor %s from source chain: %s", parentHash.String(), err)
+		}
+		missing = append(missing, parent)
+		parentHash = parent.ParentHash
+	}
+
+	if err := c.ValidateHeader(header, sourceChain); err != nil {
+		return nil, err
+	}
+
+	// submit the missing ancestors starting with the oldest one
+	var results []*TxResult
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := c.ValidateHeader(missing[i], sourceChain); err != nil {
+			return nil, err
+		}
+		result, err := c.SubmitHeader(missing[i], destinationChain)
+		if errors.Is(err, ErrHeaderAlreadyStored) {
+			// stored in the meantime, e.g., by another relayer
+			continue
+		}
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+		c.archiveBody(missing[i], sourceChain)
+	}
+
+	result, err := c.SubmitHeader(header, destinationChain)
+	if err != nil {
+		return results, err
+	}
+	c.archiveBody(header, sourceChain)
+	return append(results, result), nil
+}
+
+// SubmitHeaderLive submits all blocks of the source chain that are newer than the most recent block stored in the
+// Testimonium contract and afterwards continuously submits new blocks selected by the relay policy of the client
+// (see WithRelayPolicy). It only returns in case of an error.
+func (c Client) SubmitHeaderLive(destinationChain uint8, sourceChain uint8, lockTime time.Duration) error {
 	// Check preconditions
-	if _, exists := c.chains[destinationChain]; !exists {
-		log.Fatalf("Chain '%d' does not exist", destinationChain)
+	if err := c.checkTestimonium(destinationChain); err != nil {
+		return err
+	}
+	if err := c.checkDecommissioned(destinationChain); err != nil {
+		return err
 	}
 
-	if _, exists := c.chains[sourceChain]; !exists {
-		log.Fatalf("Chain '%d' does not exist", sourceChain)
+	if err := c.checkChain(sourceChain); err != nil {
+		return err
+	}
+	for _, chain := range []uint8{sourceChain, destinationChain} {
+		c.progressf("Node capabilities of chain %d: %s\n", chain, c.chain(chain).capabilities)
 	}
 
 	/*
@@ -463,17 +794,17 @@ func (c Client) SubmitHeaderLive(destinationChain uint8, sourceChain uint8, lock
 	// if in the backwards search more than log2(n) blocks are stored, a binary search is always faster, so maybe
 	// implement a binary search as default here
 
-	genesis, err := c.chains[destinationChain].testimoniumContract.GetGenesisBlockHash(nil)
+	genesis, err := c.chain(destinationChain).testimoniumContract.GetGenesisBlockHash(c.callOpts())
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	fmt.Printf("Getting sure ETH Relay genesis block 0x%s from destination chain %d really exists on source chain %d\n", common.Bytes2Hex(genesis[:]), sourceChain, destinationChain)
+	c.progressf("Getting sure ETH Relay genesis block 0x%s from destination chain %d really exists on source chain %d\n", common.Bytes2Hex(genesis[:]), sourceChain, destinationChain)
 
 	// returns an error if genesis was not found
-	_, err = c.chains[sourceChain].client.HeaderByHash(context.Background(), genesis)
+	_, err = c.chain(sourceChain).client.HeaderByHash(c.context(), genesis)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// at the beginning this is nil - which returns the most recent block
@@ -487,14 +818,14 @@ func (c Client) SubmitHeaderLive(destinationChain uint8, sourceChain uint8, lock
 		// get newest, longest header from source chain
 		header, err = c.HeaderByNumber(blockNumber, sourceChain)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
-		fmt.Printf("\nSearching for block No. %s from source chain %d on destination chain %d", header.Number.String(), sourceChain, destinationChain)
+		c.progressf("\nSearching for block No. %s from source chain %d on destination chain %d", header.Number.String(), sourceChain, destinationChain)
 
-		isHeaderStored, err := c.chains[destinationChain].testimoniumContract.IsHeaderStored(nil, header.Hash())
+		isHeaderStored, err := c.chain(destinationChain).testimoniumContract.IsHeaderStored(c.callOpts(), header.Hash())
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
 		if isHeaderStored {
@@ -506,21 +837,25 @@ func (c Client) SubmitHeaderLive(destinationChain uint8, sourceChain uint8, lock
 		blockNumber.Sub(blockNumber, one)
 	}
 
-	fmt.Printf("\n\nlatest block No. submitted to destination chain: %s\n\n", header.Number.String())
+	c.progressf("\n\nlatest block No. submitted to destination chain: %s\n\n", header.Number.String())
+
+	// the stake queue below waits for lock periods with the local clock
+	c.warnClockSkew(sourceChain, destinationChain)
+	lastClockCheck := time.Now()
 
-	requiredStake, err := c.chains[destinationChain].testimoniumContract.GetRequiredStakePerBlock(nil)
+	requiredStake, err := c.chain(destinationChain).requiredStakePerBlock()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	stake, err := c.GetStake(destinationChain)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// check if there is enough stake
 	if stake.Cmp(requiredStake) < 0 {
-		log.Fatal("not enough stake deposited")
+		return errors.New("not enough stake deposited")
 	}
 
 	// has to be bigger than one as we checked above
@@ -529,47 +864,66 @@ func (c Client) SubmitHeaderLive(destinationChain uint8, sourceChain uint8, lock
 	maxBlocksWithStake.Div(stake, requiredStake)
 
 	// calculate max. block submissions with stake
-	var queue []time.Time
+	// the queue contains the times the stake of the submitted headers is unlocked, starting with the headers submitted
+	// before (e.g., before a restart) according to the lock calendar
+	queue := c.initialStakeQueue(destinationChain, lockTime)
+
+	policy := c.relayPolicy
+	if policy == nil {
+		policy = RelayEveryBlock()
+	}
+	pause := c.newGasPause()
 
 	// blockNumber was updated, so the destination chain is a few blocks behind source chain - updating now
 	if blockNumber != nil {
 		// submit all blocks to the most recent one
 		for {
-			if len(queue) >= int(maxBlocksWithStake.Uint64()) {
-				timeUntilNextBlockIsUnlocked := queue[0].Add(lockTime)
-				waitingTime := timeUntilNextBlockIsUnlocked.Sub(time.Now())
-
-				if waitingTime > 0 {
-					fmt.Printf("all stake is locked, waiting for %fs to continue\n", waitingTime.Seconds())
-					time.Sleep(waitingTime)
-				}
-
-				queue = queue[1:]
-			}
+			queue = c.awaitFreeStake(queue, int(maxBlocksWithStake.Uint64()))
 
 			// increase by one as we only want blocks that are new
 			blockNumber.Add(blockNumber, one)
 
 			header, err := c.HeaderByNumber(blockNumber, sourceChain)
 			if err != nil {
-				log.Fatal(err)
+				return err
 			}
 
-			fmt.Println("Stake queue-length: ", len(queue), "\n")
+			c.progressf("Stake queue-length: %d\n\n", len(queue))
 
-			// TODO: a check for enough free/unlocked stake is required here, though a time based workaround is already implemented
-			err = c.SubmitHeader(header, destinationChain)
+			relay, err := policy.ShouldRelay(c, header, sourceChain)
+			if err != nil {
+				return err
+			}
+			relayed, err := c.gateGasCeiling(pause, header, relay, destinationChain)
 			if err != nil {
-				log.Fatal(err)
+				return err
 			}
 
-			// add now + 1m for latency and whatever
-			queue = append(queue, time.Now().Add(time.Second))
+			if relayed != nil {
+				// an invalid header of the source chain cannot be skipped here as all following blocks depend on it
+				// TODO: a check for enough free/unlocked stake is required here, though a time based workaround is already implemented
+				results, err := c.relayHeader(relayed, destinationChain, sourceChain, int(maxBlocksWithStake.Uint64())-len(queue)-1)
+				if errors.Is(err, ErrLostRace) {
+					c.progressf("Lost submission race: %s\n", err)
+				} else if errors.Is(err, ErrHeaderAlreadyStored) {
+					// e.g., after a restart or if another relayer was faster, no stake is locked for this block
+					c.progressf("Block %s already stored, skipping\n", relayed.Hash().String())
+				} else if err != nil {
+					return err
+				}
+				for range results {
+					// add now + 1m for latency and whatever
+					queue = append(queue, time.Now().Add(time.Second+lockTime))
+				}
+				c.liveMonitor.headerProcessed(header.Number.Uint64(), len(results), queue)
+			} else {
+				c.liveMonitor.headerProcessed(header.Number.Uint64(), 0, queue)
+			}
 
 			// get newest, longest header from source chain
 			header, err = c.HeaderByNumber(nil, sourceChain)
 			if err != nil {
-				log.Fatal(err)
+				return err
 			}
 
 			// we caught up all the blocks... continue
@@ -579,88 +933,173 @@ func (c Client) SubmitHeaderLive(destinationChain uint8, sourceChain uint8, lock
 		}
 	}
 
-	fmt.Printf("\nstarting live mode...\n\n")
+	c.progressf("\nstarting live mode...\n\n")
 
 	headers := make(chan *types.Header)
 
-	sub, err := c.chains[sourceChain].client.SubscribeNewHead(context.Background(), headers)
+	// heads are polled if the node does not support subscriptions
+	sub, err := c.chain(sourceChain).subscribeNewHeads(c.context(), headers)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	for {
+		var header *types.Header
+		withheld := false
 		select {
 		case err := <-sub.Err():
-			log.Fatal(err)
-		case header := <-headers:
-			if len(queue) >= int(maxBlocksWithStake.Uint64()) {
-				timeUntilNextBlockIsUnlocked := queue[0].Add(lockTime)
-				waitingTime := timeUntilNextBlockIsUnlocked.Sub(time.Now())
-
-				if waitingTime > 0 {
-					fmt.Printf("all stake is locked, waiting for %fs to continue\n", waitingTime.Seconds())
-					time.Sleep(waitingTime)
-				}
+			return err
+		case header = <-headers:
+		case <-pause.recheck():
+			// the withheld header is relayed as soon as the gas price falls, not only with the next block
+			header, withheld = pause.withheldHeader(), true
+		}
 
-				queue = queue[1:]
-			}
+		if time.Since(lastClockCheck) >= CLOCK_CHECK_INTERVAL {
+			c.warnClockSkew(sourceChain, destinationChain)
+			lastClockCheck = time.Now()
+		}
+
+		queue = c.awaitFreeStake(queue, int(maxBlocksWithStake.Uint64()))
 
-			fmt.Println("Stake queue-length: ", len(queue), "\n")
+		c.progressf("Stake queue-length: %d\n\n", len(queue))
 
-			err = c.SubmitHeader(header, destinationChain)
+		// the policy already selected the withheld header
+		relay := withheld
+		if !withheld {
+			relay, err = policy.ShouldRelay(c, header, sourceChain)
 			if err != nil {
-				log.Fatal(err)
+				return err
 			}
+		}
+		relayed, err := c.gateGasCeiling(pause, header, relay, destinationChain)
+		if err != nil {
+			return err
+		}
+		if relayed == nil {
+			c.liveMonitor.headerProcessed(header.Number.Uint64(), 0, queue)
+			continue
+		}
+		header = relayed
 
-			queue = append(queue, time.Now().Add(time.Second))
+		results, err := c.relayHeader(header, destinationChain, sourceChain, int(maxBlocksWithStake.Uint64())-len(queue)-1)
+		for range results {
+			queue = append(queue, time.Now().Add(time.Second+lockTime))
+		}
+		c.liveMonitor.headerProcessed(header.Number.Uint64(), len(results), queue)
+		if errors.Is(err, ErrInvalidHeader) {
+			c.progressf("Block %s failed validation, skipping: %s\n", header.Hash().String(), err)
+			continue
+		}
+		if errors.Is(err, ErrHeaderDisagreement) {
+			c.progressf("Block %s not confirmed by all providers, skipping: %s\n", header.Hash().String(), err)
+			continue
+		}
+		if errors.Is(err, ErrLostRace) {
+			// another relayer was faster, retrying would revert again
+			c.progressf("Lost submission race: %s\n", err)
+			continue
+		}
+		if errors.Is(err, ErrHeaderAlreadyStored) {
+			c.progressf("Block %s already stored, skipping\n", header.Hash().String())
+			continue
+		}
+		if err != nil {
+			return err
 		}
 	}
 }
 
-func (c Client) SubmitRLPHeader(rlpHeader []byte, chain uint8) (error) {
+func (c Client) SubmitRLPHeader(rlpHeader []byte, chain uint8) (*TxResult, error) {
+	c, span := c.startSpan("submit header", chain)
+	span.SetAttribute("ethrelay.block_hash", crypto.Keccak256Hash(rlpHeader).Hex())
+	result, err := c.submitRLPHeader(rlpHeader, chain)
+	span.setTxResult(result)
+	blockHash := crypto.Keccak256Hash(rlpHeader)
+	c.publishTxEvent(AnalyticsEvent{Type: ANALYTICS_EVENT_SUBMISSION, Chain: chain, Account: c.as(OPERATOR_SUBMITTER).account,
+		BlockHash: &blockHash}, result, err)
+	return result, span.End(err)
+}
+
+func (c Client) submitRLPHeader(rlpHeader []byte, chain uint8) (*TxResult, error) {
+	c = c.as(OPERATOR_SUBMITTER)
 	// Check preconditions
-	if _, exists := c.chains[chain]; !exists {
-		log.Fatalf("Chain '%d' does not exist", chain)
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, err
+	}
+	if err := c.checkDecommissioned(chain); err != nil {
+		return nil, err
 	}
 
-	// for getting the max. actual gas limit, that's only a workaround for the indeterministic
-	// "now" value in the contract method cleanSubmitList's isUnlocked call as we don't know
-	// the exact timestamp and can't estimate gas precisely
-	lastBlock, err := c.chains[chain].client.BlockByNumber(context.Background(), nil)
+	// the hash of the RLP encoded header is the block hash, if it is already stored the contract
+	// would revert the submission anyway, so we do not waste gas on sending the transaction
+	blockHash := crypto.Keccak256Hash(rlpHeader)
+	isHeaderStored, err := c.chain(chain).testimoniumContract.IsHeaderStored(c.callOpts(), blockHash)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	if isHeaderStored {
+		return nil, ErrHeaderAlreadyStored
 	}
 
-	// Submit Transfer Transaction
-	auth := prepareTransaction(c.account, c.privateKey, c.chains[chain], big.NewInt(0))
-	auth.GasLimit = lastBlock.GasLimit()
-	tx, err := c.chains[chain].testimoniumContract.SubmitBlock(auth, rlpHeader)
+	// the contract only accepts headers that extend an already stored header
+	header, err := decodeHeaderFromRLP(rlpHeader)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	isParentStored, err := c.chain(chain).testimoniumContract.IsHeaderStored(c.callOpts(), header.ParentHash)
+	if err != nil {
+		return nil, err
+	}
+	if !isParentStored {
+		return nil, fmt.Errorf("%w: %s", ErrParentNotStored, header.ParentHash.String())
 	}
 
-	// fmt.Printf("Tx submitted: %s\n", tx.Hash().Hex())
+	// Submit Transfer Transaction
+	auth, err := prepareTransaction(c.context(), c.account, c.privateKey, c.chain(chain), big.NewInt(0))
+	if err != nil {
+		return nil, err
+	}
+	if _, configured := c.chain(chain).gasLimits.gasLimitConfig(GAS_OP_SUBMIT); !configured {
+		// for getting the max. actual gas limit, that's only a workaround for the indeterministic
+		// "now" value in the contract method cleanSubmitList's isUnlocked call as we don't know
+		// the exact timestamp and can't estimate gas precisely
+		lastBlock, err := c.chain(chain).client.BlockByNumber(c.context(), nil)
+		if err != nil {
+			return nil, err
+		}
+		auth.GasLimit = lastBlock.GasLimit()
+	}
+	tx, err := c.chain(chain).testimoniumContract.SubmitBlock(auth, rlpHeader)
+	if err != nil {
+		return nil, err
+	}
 
-	receipt, err := awaitTxReceipt(c.chains[chain].client, tx.Hash())
+	receipt, err := awaitTxReceipt(c.context(), c.chain(chain), tx.Hash())
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	if receipt.Status == 0 {
-		// Transaction failed
-		reason := getFailureReason(c.chains[chain].client, c.account, tx, receipt.BlockNumber)
-		// fmt.Printf("Tx failed: %s\n", reason)
-		return errors.New(reason)
+		// Transaction failed, if another relayer submitted the header first it is not a failure of the submission
+		isHeaderStored, err := c.chain(chain).testimoniumContract.IsHeaderStored(&bind.CallOpts{BlockNumber: receipt.BlockNumber}, blockHash)
+		if err == nil && isHeaderStored {
+			c.liveMonitor.lostRace(receipt.GasUsed)
+			return nil, &LostRaceError{BlockHash: blockHash, Tx: newTxResult(tx, receipt)}
+		}
+
+		reason := getFailureReason(c.chain(chain).client, c.account, tx, receipt.BlockNumber)
+		return nil, errors.New(reason)
 	}
 
 	// Transaction is successful
-	eventIterator, err := c.chains[chain].testimoniumContract.TestimoniumFilterer.FilterSubmitBlock(&bind.FilterOpts{
+	eventIterator, err := c.chain(chain).testimoniumContract.TestimoniumFilterer.FilterSubmitBlock(&bind.FilterOpts{
 		Start:   receipt.BlockNumber.Uint64(),
 		End:     nil,
 		Context: nil,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// TODO: is this really the next event on the same chain? what if a transaction is included into one block,
@@ -668,41 +1107,59 @@ func (c Client) SubmitRLPHeader(rlpHeader []byte, chain uint8) (error) {
 	//  to other nodes within a usage - this may also be the case on every other transaction call
 	//  workaround: check that the transaction from eventIterator's event is the same as the submitted transaction above
 	if eventIterator.Next() {
-		// fmt.Printf("Tx successful: %s\n", eventIterator.Event.String())
-
 		// TODO: this is only 1 special hash value emitted by the contract for too small stake and not a read error code
 		if eventIterator.Event.BlockHash == [32] byte { 0 } {
-			return errors.New("block was not submitted, reason: too small stake deposited")
+			return nil, errors.New("block was not submitted, reason: too small stake deposited")
 		}
 
-		return nil
+		result := newTxResult(tx, receipt)
+		result.Events = append(result.Events, eventIterator.Event.String())
+		return result, nil
 	}
 
-	return errors.New("uncaught error")
+	return nil, errors.New("uncaught error")
 }
 
 func (c Client) BlockByHash(blockHash common.Hash, chain uint8) (*types.Block, error) {
-	if _, exists := c.chains[chain]; !exists {
-		log.Fatalf("Chain '%d' does not exist", chain)
+	if err := c.checkChain(chain); err != nil {
+		return nil, err
 	}
 
-	return c.chains[chain].client.BlockByHash(context.Background(), blockHash)
+	var block *types.Block
+	err := c.withBlockSource(chain, "block "+blockHash.Hex(), func(source BlockSource) error {
+		var err error
+		block, err = source.BlockByHash(c.context(), blockHash)
+		return err
+	})
+	return block, err
 }
 
 func (c Client) BlockByNumber(blockNumber uint64, chain uint8) (*types.Block, error) {
-	if _, exists := c.chains[chain]; !exists {
-		log.Fatalf("Chain '%d' does not exist", chain)
+	if err := c.checkChain(chain); err != nil {
+		return nil, err
 	}
 
-	return c.chains[chain].client.BlockByNumber(context.Background(), new(big.Int).SetUint64(blockNumber))
+	var block *types.Block
+	err := c.withBlockSource(chain, fmt.Sprintf("block %d", blockNumber), func(source BlockSource) error {
+		var err error
+		block, err = source.BlockByNumber(c.context(), new(big.Int).SetUint64(blockNumber))
+		return err
+	})
+	return block, err
 }
 
 func (c Client) HeaderByNumber(blockNumber *big.Int, chain uint8) (*types.Header, error) {
-	if _, exists := c.chains[chain]; !exists {
-		log.Fatalf("Chain '%d' does not exist", chain)
+	if err := c.checkChain(chain); err != nil {
+		return nil, err
 	}
 
-	return c.chains[chain].client.HeaderByNumber(context.Background(), blockNumber)
+	var header *types.Header
+	err := c.withBlockSource(chain, fmt.Sprintf("header %s", toBlockNumArg(blockNumber)), func(source BlockSource) error {
+		var err error
+		header, err = source.HeaderByNumber(c.context(), blockNumber)
+		return err
+	})
+	return header, err
 }
 
 type TotalDifficulty struct {
@@ -718,19 +1175,24 @@ func toBlockNumArg(number *big.Int) string {
 }
 
 func (c Client) TotalDifficulty(blockNumber *big.Int, chain uint8) (*big.Int, error) {
-	if _, exists := c.chains[chain]; !exists {
-		log.Fatalf("Chain '%d' does not exist", chain)
+	if err := c.checkChain(chain); err != nil {
+		return nil, err
 	}
 
-	client, err := rpc.Dial(c.chains[chain].fullUrl)
+	var totalDifficulty *TotalDifficulty
+	err := c.withArchiveFallback(chain, fmt.Sprintf("block %s", toBlockNumArg(blockNumber)), func(_ *ethclient.Client, rpcClient *rpc.Client) error {
+		err := rpcClient.CallContext(c.context(), &totalDifficulty, "eth_getBlockByNumber", toBlockNumArg(blockNumber), false)
+		if err == nil && totalDifficulty == nil {
+			return ethereum.NotFound
+		}
+		return err
+	})
 	if err != nil {
-		log.Fatal("Failed to connect to chain", err)
+		return big.NewInt(0), err
 	}
-
-	var totalDifficulty *TotalDifficulty
-	err = client.CallContext(context.Background(), &totalDifficulty, "eth_getBlockByNumber", toBlockNumArg(blockNumber), false)
-	if err == nil && totalDifficulty == nil {
-		return big.NewInt(0), ethereum.NotFound
+	if totalDifficulty.TotalDifficulty == "" {
+		// the node does not provide the total difficulty (e.g., after the merge), it is computed from the headers
+		return c.computeTotalDifficulty(blockNumber, chain)
 	}
 
 	diff, err := hexutil.DecodeBig(totalDifficulty.TotalDifficulty)
@@ -742,37 +1204,58 @@ func (c Client) TotalDifficulty(blockNumber *big.Int, chain uint8) (*big.Int, er
 }
 
 func (c Client) HeaderByHash(blockHash common.Hash, chain uint8) (*types.Header, error) {
-	if _, exists := c.chains[chain]; !exists {
-		log.Fatalf("Chain '%d' does not exist", chain)
+	if err := c.checkChain(chain); err != nil {
+		return nil, err
 	}
 
-	return c.chains[chain].client.HeaderByHash(context.Background(), blockHash)
+	return c.headerByHash(blockHash, chain)
 }
 
 func (c Client) Transaction(txHash common.Hash, chain uint8) (*types.Transaction, bool, error) {
-	if _, exists := c.chains[chain]; !exists {
-		log.Fatalf("Chain '%d' does not exist", chain)
+	if err := c.checkChain(chain); err != nil {
+		return nil, false, err
 	}
 
-	return c.chains[chain].client.TransactionByHash(context.Background(), txHash)
+	var tx *types.Transaction
+	var isPending bool
+	err := c.withArchiveFallback(chain, "transaction "+txHash.Hex(), func(client *ethclient.Client, _ *rpc.Client) error {
+		var err error
+		tx, isPending, err = client.TransactionByHash(c.context(), txHash)
+		return err
+	})
+	return tx, isPending, err
 }
 
 func (c Client) TransactionReceipt(txHash common.Hash, chain uint8) (*types.Receipt, error) {
-	if _, exists := c.chains[chain]; !exists {
-		log.Fatalf("Chain '%d' does not exist", chain)
+	if err := c.checkChain(chain); err != nil {
+		return nil, err
 	}
 
-	return c.chains[chain].client.TransactionReceipt(context.Background(), txHash)
+	return c.transactionReceipt(txHash, chain)
 }
 
-func (c Client) RandomizeHeader(header *types.Header, chain uint8) *types.Header {
-	temp := header.TxHash
-
-	header.TxHash = header.ReceiptHash
-	header.ReceiptHash = header.Root
-	header.Root = temp
+// transactionReceipt returns the receipt of the transaction from the node or the archive node of the chain
+func (c Client) transactionReceipt(txHash common.Hash, chain uint8) (*types.Receipt, error) {
+	var receipt *types.Receipt
+	err := c.withArchiveFallback(chain, "receipt of transaction "+txHash.Hex(), func(client *ethclient.Client, _ *rpc.Client) error {
+		var err error
+		receipt, err = client.TransactionReceipt(c.context(), txHash)
+		return err
+	})
+	// receipts of relayed blocks are also available from the body archive if the providers pruned them
+	if errors.Is(err, ErrBlockDataUnavailable) && c.chain(chain).bodyArchive != nil {
+		if archived, archiveErr := c.chain(chain).bodyArchive.TransactionReceipt(txHash); archiveErr == nil {
+			return archived, nil
+		}
+	}
+	return receipt, err
+}
 
-	return header
+// RandomizeHeader returns a copy of the header with swapped roots.
+//
+// Deprecated: use MUTATE_SWAP_ROOTS or another HeaderMutator.
+func (c Client) RandomizeHeader(header *types.Header, chain uint8) *types.Header {
+	return MUTATE_SWAP_ROOTS.Mutate(header)
 }
 
 func getRlpHeaderByTestimoniumSubmitEvent(chain *Chain, blockHash [32]byte) ([]byte, error) {
@@ -804,383 +1287,408 @@ func getRlpHeaderByTestimoniumSubmitEvent(chain *Chain, blockHash [32]byte) ([]b
 				return nil, fmt.Errorf("transaction where block was submitted is currently pending...")
 			}
 
-			// get raw abi-encoded bytes of transaction data
-			txData := tx.Data()
-
-			// parse method-id, the first 4 bytes are always the first 4 bytes of the encoded message signature
-			methodId := txData[0:4]
-			methodInputs := txData[4:]
-
-			// load contract ABI
-			testimoniumAbi, err := abi.JSON(strings.NewReader(TestimoniumABI))
-			if err != nil {
-				return nil, err
-			}
-
-			// recover method from signature and ABI
-			method, err := testimoniumAbi.MethodById(methodId)
-			if err != nil {
-				return nil, err
-			}
-
-			type FunctionInputs struct {
-				RlpHeader []byte
-			}
-			var parameter FunctionInputs
-
-			// unpack method inputs
-			err = method.Inputs.Unpack(&parameter, methodInputs)
-			if err != nil {
-				return nil, err
-			}
-
-			return parameter.RlpHeader, nil
+			return chain.rlpHeaderFromSubmitTx(tx)
 		}
 	}
 
 	return nil, fmt.Errorf("no submit event for block '%s' found", common.Bytes2Hex(blockHash[:]))
 }
 
-func (c Client) DisputeBlock(blockHash [32]byte, chain uint8) {
-	fmt.Println("Disputing block ...")
+// rlpHeaderFromSubmitTx extracts the RLP encoded header from the input of a transaction calling submitBlock
+func (chain *Chain) rlpHeaderFromSubmitTx(tx *types.Transaction) ([]byte, error) {
+	// get raw abi-encoded bytes of transaction data
+	txData := tx.Data()
+	if len(txData) < 4 {
+		return nil, fmt.Errorf("transaction %s is not a contract call", tx.Hash().String())
+	}
+
+	// parse method-id, the first 4 bytes are always the first 4 bytes of the encoded message signature
+	methodId := txData[0:4]
+	methodInputs := txData[4:]
 
-	rlpEncodedBlockHeader, err := getRlpHeaderByTestimoniumSubmitEvent(c.chains[chain], blockHash)
+	// load contract ABI
+	testimoniumAbi, err := chain.testimoniumAbi()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	// decode block header from rlp encoded block header
-	blockHeader, err := decodeHeaderFromRLP(rlpEncodedBlockHeader)
+	// recover method from signature and ABI
+	method, err := testimoniumAbi.MethodById(methodId)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+
+	type FunctionInputs struct {
+		RlpHeader []byte
 	}
+	var parameter FunctionInputs
 
-	// take the encoded block header and encode it without the nonce and the mixed hash
-	blockHeaderWithoutNonce, err := encodeHeaderWithoutNonceToRLP(blockHeader)
+	// unpack method inputs
+	err = method.Inputs.Unpack(&parameter, methodInputs)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	// create a hash to get the block hash without nonce needed for the ethash metadata construction
-	blockHeaderHashWithoutNonce := crypto.Keccak256(blockHeaderWithoutNonce)
+	return parameter.RlpHeader, nil
+}
 
-	// keccak256 returns a dynamic byte array, but we need a 32 byte fixed size byte array for the ethash block meta data
-	var blockHeaderHashWithoutNonceLength32 [32]byte
-	copy(blockHeaderHashWithoutNonceLength32[:], blockHeaderHashWithoutNonce)
+func (c Client) DisputeBlock(blockHash [32]byte, chain uint8) (*TxResult, error) {
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, err
+	}
 
-	// get DAG and compute dataSetLookup and witnessForLookup
-	blockMetaData := ethash.NewBlockMetaData(blockHeader.Number.Uint64(), blockHeader.Nonce.Uint64(), blockHeaderHashWithoutNonceLength32)
-	dataSetLookUp := blockMetaData.DAGElementArray()
-	witnessForLookup := blockMetaData.DAGProofArray()
+	c.progressf("Disputing block ...\n")
 
-	// the last thing needed for calling dispute is the parent rlp encoded block header
-	rlpEncodedParentBlockHeader, err := getRlpHeaderByTestimoniumSubmitEvent(c.chains[chain], blockHeader.ParentHash)
+	witnesses, err := c.GenerateDisputeWitnesses([]common.Hash{blockHash}, chain)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	auth := prepareTransaction(c.account, c.privateKey, c.chains[chain], big.NewInt(0))
+	return c.DisputeBlockWithWitness(witnesses[0], chain)
+}
+
+// DisputeBlockWithWitness disputes a submitted block header with a witness generated by GenerateDisputeWitnesses.
+func (c Client) DisputeBlockWithWitness(witness DisputeWitness, chain uint8) (*TxResult, error) {
+	c, span := c.startSpan("dispute block", chain)
+	span.SetAttribute("ethrelay.block_hash", crypto.Keccak256Hash(witness.RlpHeader).Hex())
+	evidence := c.newDisputeEvidence(witness, chain)
+	result, err := c.disputeBlockWithWitness(witness, chain, evidence)
+	if evidence.DisputeTx != (common.Hash{}) {
+		c.archiveDispute(evidence, result, err)
+	}
+	span.setTxResult(result)
+	blockHash := crypto.Keccak256Hash(witness.RlpHeader)
+	c.publishTxEvent(AnalyticsEvent{Type: ANALYTICS_EVENT_DISPUTE, Chain: chain, Account: c.as(OPERATOR_DISPUTER).account,
+		BlockHash: &blockHash}, result, err)
+	return result, span.End(err)
+}
+
+func (c Client) disputeBlockWithWitness(witness DisputeWitness, chain uint8, evidence *DisputeEvidence) (*TxResult, error) {
+	c = c.as(OPERATOR_DISPUTER)
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, err
+	}
 
-	tx, err := c.chains[chain].testimoniumContract.DisputeBlockHeader(auth, rlpEncodedBlockHeader, rlpEncodedParentBlockHeader, dataSetLookUp, witnessForLookup)
+	auth, err := prepareTransaction(c.context(), c.account, c.privateKey, c.chain(chain), big.NewInt(0))
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	fmt.Printf("Tx submitted: %s\n", tx.Hash().Hex())
+	// disputes in the public mempool can be front-run by the submitter of the block
+	receiptTimeout := TX_RECEIPT_TIMEOUT
+	if c.chain(chain).privateRelay != nil {
+		auth.Context = withPrivateSubmission(auth.Context)
+		receiptTimeout = PRIVATE_TX_RECEIPT_TIMEOUT
+	}
+	if !c.noAccessLists {
+		// the Ethash lookups read many storage slots
+		auth.Context = withAccessList(auth.Context)
+	}
 
-	receipt, err := awaitTxReceipt(c.chains[chain].client, tx.Hash())
+	tx, err := c.chain(chain).testimoniumContract.DisputeBlockHeader(auth, witness.RlpHeader, witness.RlpParentHeader, witness.DataSetLookup, witness.WitnessForLookup)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+	evidence.DisputeTx = tx.Hash()
+
+	c.progressf("Tx submitted: %s\n", tx.Hash().Hex())
+
+	receipt, err := awaitTxReceiptWithin(c.context(), c.chain(chain), tx.Hash(), receiptTimeout)
+	if err != nil {
+		return nil, err
+	}
+	result := newTxResult(tx, receipt)
+	evidence.Result = result
 
 	if receipt.Status == 0 {
 		// Transaction failed
-		reason := getFailureReason(c.chains[chain].client, c.account, tx, receipt.BlockNumber)
-		fmt.Printf("Tx failed: %s\n", reason)
-		return
+		reason := getFailureReason(c.chain(chain).client, c.account, tx, receipt.BlockNumber)
+		return nil, fmt.Errorf("tx failed: %s", reason)
 	}
 
 	// get RemoveBranch event
-	eventIteratorRemoveBranch, err := c.chains[chain].testimoniumContract.TestimoniumFilterer.FilterRemoveBranch(&bind.FilterOpts{
+	eventIteratorRemoveBranch, err := c.chain(chain).testimoniumContract.TestimoniumFilterer.FilterRemoveBranch(&bind.FilterOpts{
 		Start:   receipt.BlockNumber.Uint64(),
 		End:     nil,
 		Context: nil,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	if eventIteratorRemoveBranch.Next() {
-		fmt.Printf("Tx successful: %s\n", eventIteratorRemoveBranch.Event.String())
+		result.Events = append(result.Events, eventIteratorRemoveBranch.Event.String())
+		evidence.Removed = true
 	}
 
 	// get PoW Verification event
-	eventIteratorPoWResult, err := c.chains[chain].testimoniumContract.TestimoniumFilterer.FilterPoWValidationResult(&bind.FilterOpts{
+	eventIteratorPoWResult, err := c.chain(chain).testimoniumContract.TestimoniumFilterer.FilterPoWValidationResult(&bind.FilterOpts{
 		Start:   receipt.BlockNumber.Uint64(),
 		End:     nil,
 		Context: nil,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	if eventIteratorPoWResult.Next() {
-		fmt.Printf("Tx successful: %s\n", eventIteratorPoWResult.Event.String())
+		result.Events = append(result.Events, eventIteratorPoWResult.Event.String())
+		returnCode := eventIteratorPoWResult.Event.ReturnCode.Uint64()
+		evidence.ReturnCode, evidence.ErrorInfo = &returnCode, eventIteratorPoWResult.Event.ErrorInfo
 	}
+	return result, nil
 }
 
 func (c Client) GetRequiredVerificationFee(chain uint8) (*big.Int, error) {
-	return c.chains[chain].testimoniumContract.GetRequiredVerificationFee(nil)
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, err
+	}
+	return c.chain(chain).requiredVerificationFee()
 }
 
 func (c Client) GenerateMerkleProofForTx(txHash [32]byte, chain uint8) ([]byte, []byte, []byte, []byte, error) {
-	if _, exists := c.chains[chain]; !exists {
-		log.Fatalf("Chain '%d' does not exist", chain)
-	}
-
-	txReceipt, err := c.chains[chain].client.TransactionReceipt(context.Background(), txHash)
+	proof, header, err := c.BuildTxProof(txHash, chain)
 	if err != nil {
 		return []byte{}, []byte{}, []byte{}, []byte{}, err
 	}
 
-	block, err := c.chains[chain].client.BlockByHash(context.Background(), txReceipt.BlockHash)
+	return encodeProof(header, proof)
+}
+
+func (c Client) GenerateMerkleProofForReceipt(txHash [32]byte, chain uint8) ([]byte, []byte, []byte, []byte, error) {
+	proof, header, err := c.BuildReceiptProof(txHash, chain)
 	if err != nil {
 		return []byte{}, []byte{}, []byte{}, []byte{}, err
 	}
 
-	// create transactions trie
-	buffer := new(bytes.Buffer)
-	merkleTrie := new(trie.Trie)
-	txList := block.Transactions()
-	for i := 0; i < txList.Len(); i++ {
-		buffer.Reset()
-		rlp.Encode(buffer, uint(i))
-		merkleTrie.Update(buffer.Bytes(), txList.GetRlp(i))
+	return encodeProof(header, proof)
+}
+
+// BuildTxProof builds the Merkle Patricia proof of the transaction with the specified hash against the transactions
+// root of the header of its block, which is returned as well.
+func (c Client) BuildTxProof(txHash common.Hash, chain uint8) (proofs.Proof, *types.Header, error) {
+	c, span := c.startSpan("generate transaction proof", chain)
+	span.SetAttribute("ethrelay.tx_hash", txHash.Hex())
+	proof, header, err := c.buildTxProof(txHash, chain)
+	return proof, header, span.End(err)
+}
+
+func (c Client) buildTxProof(txHash common.Hash, chain uint8) (proofs.Proof, *types.Header, error) {
+	if err := c.checkChain(chain); err != nil {
+		return proofs.Proof{}, nil, err
 	}
 
-	// create Merkle proof
-	rlpEncodedTx := txList.GetRlp(int(txReceipt.TransactionIndex))
-
-	buffer.Reset()
-	rlp.Encode(buffer, txReceipt.TransactionIndex)
-	path := make([]byte, len(buffer.Bytes()))
-	copy(path, buffer.Bytes())
-
-	merkleIterator := merkleTrie.NodeIterator(nil)
-	var proofNodes [][]byte
-	for merkleIterator.Next(true) {
-		if merkleIterator.Leaf() && bytes.Equal(merkleIterator.LeafKey(), path) {
-			// leaf node representing tx has been found --> create Merkle proof
-			proofNodes = merkleIterator.LeafProof()
-			break
+	txReceipt, err := c.transactionReceipt(txHash, chain)
+	if err != nil {
+		return proofs.Proof{}, nil, err
+	}
+
+	var header *types.Header
+	var txs types.Transactions
+	if c.chain(chain).lightProofs {
+		if header, err = c.headerByHash(txReceipt.BlockHash, chain); err != nil {
+			return proofs.Proof{}, nil, err
+		}
+		if txs, err = c.lightBlockTransactions(txReceipt.BlockHash, chain); err != nil {
+			return proofs.Proof{}, nil, err
 		}
+	} else {
+		block, err := c.BlockByHash(txReceipt.BlockHash, chain)
+		if err != nil {
+			return proofs.Proof{}, nil, err
+		}
+		header, txs = block.Header(), block.Transactions()
 	}
 
-	buffer.Reset()
-	rlp.Encode(buffer, proofNodes)
-	rlpEncodedProofNodes := make([]byte, len(buffer.Bytes()))
-	copy(rlpEncodedProofNodes, buffer.Bytes())
+	// create Merkle proof
+	proof, err := proofs.BuildTxProof(txs, txReceipt.TransactionIndex)
+	if err != nil {
+		return proofs.Proof{}, nil, err
+	}
+	if err := c.checkProofRoot(proof, header.TxHash, "transactions", header, txReceipt.BlockHash, nil, chain); err != nil {
+		return proofs.Proof{}, nil, err
+	}
 
-	buffer.Reset()
-	rlp.Encode(buffer, block.Header())
-	rlpEncodedHeader := make([]byte, len(buffer.Bytes()))
-	copy(rlpEncodedHeader, buffer.Bytes())
+	return proof, header, nil
+}
 
-	return rlpEncodedHeader, rlpEncodedTx, path, rlpEncodedProofNodes, nil
+// BuildReceiptProof builds the Merkle Patricia proof of the receipt of the transaction with the specified hash against
+// the receipts root of the header of its block, which is returned as well.
+func (c Client) BuildReceiptProof(txHash common.Hash, chain uint8) (proofs.Proof, *types.Header, error) {
+	c, span := c.startSpan("generate receipt proof", chain)
+	span.SetAttribute("ethrelay.tx_hash", txHash.Hex())
+	proof, header, err := c.buildReceiptProof(txHash, chain)
+	return proof, header, span.End(err)
 }
 
-func (c Client) GenerateMerkleProofForReceipt(txHash [32]byte, chain uint8) ([]byte, []byte, []byte, []byte, error) {
-	if _, exists := c.chains[chain]; !exists {
-		log.Fatalf("Chain '%d' does not exist", chain)
+func (c Client) buildReceiptProof(txHash common.Hash, chain uint8) (proofs.Proof, *types.Header, error) {
+	if err := c.checkChain(chain); err != nil {
+		return proofs.Proof{}, nil, err
 	}
 
-	txReceipt, err := c.chains[chain].client.TransactionReceipt(context.Background(), txHash)
+	txReceipt, err := c.transactionReceipt(txHash, chain)
 	if err != nil {
-		return []byte{}, []byte{}, []byte{}, []byte{}, err
+		return proofs.Proof{}, nil, err
 	}
 
-	block, err := c.chains[chain].client.BlockByHash(context.Background(), txReceipt.BlockHash)
+	header, err := c.headerByHash(txReceipt.BlockHash, chain)
 	if err != nil {
-		return []byte{}, []byte{}, []byte{}, []byte{}, err
+		return proofs.Proof{}, nil, err
 	}
 
-	var path []byte
-	var rlpEncodedReceipt []byte
-
-	// create receipts trie
-	buffer := new(bytes.Buffer)
-	merkleTrie := new(trie.Trie)
-	for i := 0; i < block.Transactions().Len(); i++ {
-		tx := block.Body().Transactions[i]
-
-		receipt, err := c.chains[chain].client.TransactionReceipt(context.Background(), tx.Hash())
-		if err != nil {
-			return []byte{}, []byte{}, []byte{}, []byte{}, err
-		}
-
-		buffer.Reset()
-		receipt.EncodeRLP(buffer)
-		encodedReceipt := make([]byte, len(buffer.Bytes()))
-		copy(encodedReceipt, buffer.Bytes())
-
-		buffer.Reset()
-		rlp.Encode(buffer, uint(i))
-
-		if txReceipt.TxHash == receipt.TxHash {
-			path = make([]byte, len(buffer.Bytes()))
-			copy(path, buffer.Bytes())
-
-			rlpEncodedReceipt = make([]byte, len(encodedReceipt))
-			copy(rlpEncodedReceipt, encodedReceipt)
-		}
-
-		merkleTrie.Update(buffer.Bytes(), encodedReceipt)
+	// collect all receipts of the block to create the receipts trie
+	var receipts types.Receipts
+	if c.chain(chain).lightProofs {
+		receipts, err = c.lightBlockReceipts(txReceipt.BlockHash, chain)
+	} else {
+		receipts, err = c.blockReceipts(txReceipt.BlockHash, chain)
+	}
+	if err != nil {
+		return proofs.Proof{}, nil, err
 	}
 
 	// create Merkle proof
-
-	merkleIterator := merkleTrie.NodeIterator(nil)
-	var proofNodes [][]byte
-	for merkleIterator.Next(true) {
-		if merkleIterator.Leaf() && bytes.Equal(merkleIterator.LeafKey(), path) {
-			// leaf node representing tx has been found --> create Merkle proof
-			proofNodes = merkleIterator.LeafProof()
-			break
-		}
+	proof, err := proofs.BuildReceiptProof(receipts, txReceipt.TransactionIndex)
+	if err != nil {
+		return proofs.Proof{}, nil, err
+	}
+	if err := c.checkProofRoot(proof, header.ReceiptHash, "receipts", header, txReceipt.BlockHash, receipts, chain); err != nil {
+		return proofs.Proof{}, nil, err
 	}
 
-	buffer.Reset()
-	rlp.Encode(buffer, proofNodes)
-	rlpEncodedProofNodes := make([]byte, len(buffer.Bytes()))
-	copy(rlpEncodedProofNodes, buffer.Bytes())
+	return proof, header, nil
+}
 
-	buffer.Reset()
-	rlp.Encode(buffer, block.Header())
-	rlpEncodedHeader := make([]byte, len(buffer.Bytes()))
-	copy(rlpEncodedHeader, buffer.Bytes())
+// encodeProof returns the RLP encoded header, value, path and proof nodes as expected by the verify functions of the contract
+func encodeProof(header *types.Header, proof proofs.Proof) ([]byte, []byte, []byte, []byte, error) {
+	rlpEncodedProofNodes, err := proof.EncodedNodes()
+	if err != nil {
+		return []byte{}, []byte{}, []byte{}, []byte{}, err
+	}
 
-	return rlpEncodedHeader, rlpEncodedReceipt, path, rlpEncodedProofNodes, nil
+	rlpEncodedHeader, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return []byte{}, []byte{}, []byte{}, []byte{}, err
+	}
+
+	return rlpEncodedHeader, proof.Value, proof.Path, rlpEncodedProofNodes, nil
 }
 
+// VerifyMerkleProof verifies the value against the stored header with the Merkle proof (path and proof nodes). The
+// verification is checked with CheckVerification before it is sent, the options (e.g., OnBranch) apply to the check.
 func (c Client) VerifyMerkleProof(feeInWei *big.Int, rlpHeader []byte, trieValueType TrieValueType, rlpEncodedValue []byte, path []byte,
-	rlpEncodedProofNodes []byte, noOfConfirmations uint8, chain uint8) {
-	if _, exists := c.chains[chain]; !exists {
-		log.Fatalf("Chain '%d' does not exist", chain)
-	}
+	rlpEncodedProofNodes []byte, noOfConfirmations uint8, chain uint8, opts ...VerifyOption) (*TxResult, error) {
+	c, span := c.startSpan("verify proof", chain)
+	span.SetAttribute("ethrelay.value_type", int(trieValueType))
+	span.SetAttribute("ethrelay.block_hash", crypto.Keccak256Hash(rlpHeader).Hex())
+	result, err := c.verifyMerkleProof(feeInWei, rlpHeader, trieValueType, rlpEncodedValue, path, rlpEncodedProofNodes,
+		noOfConfirmations, chain, opts)
+	span.setTxResult(result)
+	blockHash := crypto.Keccak256Hash(rlpHeader)
+	c.publishTxEvent(AnalyticsEvent{Type: ANALYTICS_EVENT_VERIFICATION, Chain: chain, Account: c.as(OPERATOR_VERIFIER).account,
+		BlockHash: &blockHash, AmountInWei: feeInWei, ValueType: trieValueType.String()}, result, err)
+	return result, span.End(err)
+}
 
-	var tx *types.Transaction
-	var err error
-	auth := prepareTransaction(c.account, c.privateKey, c.chains[chain], feeInWei)
+func (c Client) verifyMerkleProof(feeInWei *big.Int, rlpHeader []byte, trieValueType TrieValueType, rlpEncodedValue []byte, path []byte,
+	rlpEncodedProofNodes []byte, noOfConfirmations uint8, chain uint8, opts []VerifyOption) (*TxResult, error) {
+	c = c.as(OPERATOR_VERIFIER)
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, err
+	}
 
-	switch trieValueType {
-		case VALUE_TYPE_TRANSACTION:
-			tx, err = c.chains[chain].testimoniumContract.VerifyTransaction(auth, feeInWei, rlpHeader,
-				noOfConfirmations, rlpEncodedValue, path, rlpEncodedProofNodes)
-		case VALUE_TYPE_RECEIPT:
-			tx, err = c.chains[chain].testimoniumContract.VerifyReceipt(auth, feeInWei, rlpHeader, noOfConfirmations,
-				rlpEncodedValue, path, rlpEncodedProofNodes)
-		case VALUE_TYPE_STATE:
-			tx, err = c.chains[chain].testimoniumContract.VerifyState(auth, feeInWei, rlpHeader, noOfConfirmations,
-				rlpEncodedValue, path, rlpEncodedProofNodes)
-		default:
-			log.Fatal("Unexpected trie value type: ", trieValueType)
+	// the contract would only report a failed verification after the gas is spent
+	if err := c.CheckVerification(feeInWei, rlpHeader, noOfConfirmations, chain, opts...); err != nil {
+		if errors.Is(err, ErrWrongVerificationFee) {
+			// the fee changed, it is read again for the next verification
+			c.chain(chain).viewCache.invalidate()
+		}
+		return nil, err
+	}
+	// the bundled contract rejects valid proofs through extension nodes after the fee is paid
+	if c.chain(chain).testimoniumABI == "" {
+		if err := checkProofForContract(path, rlpEncodedProofNodes); err != nil {
+			return nil, err
+		}
 	}
 
+	value, err := c.verificationValue(feeInWei, 1, chain)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
-	fmt.Printf("Tx submitted: %s\n", tx.Hash().Hex())
-
-	receipt, err := awaitTxReceipt(c.chains[chain].client, tx.Hash())
+	auth, err := prepareTransaction(c.context(), c.account, c.privateKey, c.chain(chain), value)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	if receipt.Status == 0 {
-		// Transaction failed
-		reason := getFailureReason(c.chains[chain].client, c.account, tx, receipt.BlockNumber)
-		fmt.Printf("Tx failed: %s\n", reason)
-		return
+	tx, err := c.sendVerification(auth, feeInWei, rlpHeader, trieValueType, rlpEncodedValue, path, rlpEncodedProofNodes,
+		noOfConfirmations, chain)
+	if err != nil {
+		return nil, err
 	}
 
-	var verificationResult *VerificationResult
+	c.progressf("Tx submitted: %s\n", tx.Hash().Hex())
 
-	switch trieValueType {
-		case VALUE_TYPE_TRANSACTION:
-			verificationResult, err = c.getVerifyTransactionEvent(chain, receipt)
-		case VALUE_TYPE_RECEIPT:
-			verificationResult, err = c.getVerifyReceiptEvent(chain, receipt)
-		case VALUE_TYPE_STATE:
-			verificationResult, err = c.getVerifyStateEvent(chain, receipt)
-	}
+	return c.awaitVerification(tx, trieValueType, chain)
+}
 
-	if err != nil {
-		log.Fatal(err)
+// sendVerification sends the verification of the value with the prepared options without waiting for its receipt
+func (c Client) sendVerification(auth *bind.TransactOpts, feeInWei *big.Int, rlpHeader []byte, trieValueType TrieValueType,
+	rlpEncodedValue []byte, path []byte, rlpEncodedProofNodes []byte, noOfConfirmations uint8, chain uint8) (*types.Transaction, error) {
+	if !c.noAccessLists {
+		auth.Context = withAccessList(auth.Context)
 	}
 
-	fmt.Printf("Tx successful: %s\n", verificationResult.String())
-}
-
-func (c Client) getVerifyTransactionEvent(chain uint8, receipt *types.Receipt) (*VerificationResult, error) {
-	eventIterator, err := c.chains[chain].testimoniumContract.TestimoniumFilterer.FilterVerifyTransaction(
-		&bind.FilterOpts{
-			Start:   receipt.BlockNumber.Uint64(),
-			End:     nil,
-			Context: nil,
-		})
+	verifier, err := trieValueType.verifier()
 	if err != nil {
 		return nil, err
 	}
-	if eventIterator.Next() {
-		return &VerificationResult{
-			returnCode: eventIterator.Event.Result,
-		}, nil
-	}
-	return nil, fmt.Errorf("no event found")
+	return verifier.SendVerification(c.chain(chain).testimoniumContract, auth, feeInWei, rlpHeader, noOfConfirmations,
+		rlpEncodedValue, path, rlpEncodedProofNodes)
 }
 
-func (c Client) getVerifyReceiptEvent(chain uint8, receipt *types.Receipt) (*VerificationResult, error) {
-	eventIterator, err := c.chains[chain].testimoniumContract.TestimoniumFilterer.FilterVerifyReceipt(
-		&bind.FilterOpts{
-			Start:   receipt.BlockNumber.Uint64(),
-			End:     nil,
-			Context: nil,
-		})
+// awaitVerification waits for the receipt of the sent verification and returns its result
+func (c Client) awaitVerification(tx *types.Transaction, trieValueType TrieValueType, chain uint8) (*TxResult, error) {
+	receipt, err := awaitTxReceipt(c.context(), c.chain(chain), tx.Hash())
 	if err != nil {
 		return nil, err
 	}
-	if eventIterator.Next() {
-		return &VerificationResult{
-			returnCode: eventIterator.Event.Result,
-		}, nil
+
+	if receipt.Status == 0 {
+		// Transaction failed
+		reason := getFailureReason(c.chain(chain).client, c.account, tx, receipt.BlockNumber)
+		return nil, fmt.Errorf("tx failed: %s", reason)
 	}
-	return nil, fmt.Errorf("no event found")
-}
 
-func (c Client) getVerifyStateEvent(chain uint8, receipt *types.Receipt) (*VerificationResult, error) {
-	eventIterator, err := c.chains[chain].testimoniumContract.TestimoniumFilterer.FilterVerifyState(
-		&bind.FilterOpts{
-			Start:   receipt.BlockNumber.Uint64(),
-			End:     nil,
-			Context: nil,
-		})
+	verifier, err := trieValueType.verifier()
 	if err != nil {
 		return nil, err
 	}
-	if eventIterator.Next() {
-		return &VerificationResult{
-			returnCode: eventIterator.Event.Result,
-		}, nil
+	verificationResult, err := verifier.parseVerificationResult(c.chain(chain).testimoniumContract,
+		c.chain(chain).testimoniumContractAddress, receipt)
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("no event found")
+
+	result := newTxResult(tx, receipt)
+	result.Verification = verificationResult
+	return result, nil
 }
 
-func (c Client) SetEpochData(epochData typedefs.EpochData, chain uint8) {
-	if _, exists := c.chains[chain]; !exists {
-		log.Fatalf("Chain '%d' does not exist", chain)
+func (c Client) SetEpochData(epochData typedefs.EpochData, chain uint8) ([]*TxResult, error) {
+	c, span := c.startSpan("set epoch data", chain)
+	span.SetAttribute("ethrelay.epoch", epochData.Epoch.String())
+	results, err := c.setEpochData(epochData, chain)
+	span.SetAttribute("ethrelay.transactions", len(results))
+	return results, span.End(err)
+}
+
+func (c Client) setEpochData(epochData typedefs.EpochData, chain uint8) ([]*TxResult, error) {
+	if err := c.checkEthash(chain); err != nil {
+		return nil, err
 	}
 
+	results := []*TxResult{}
 	nodes := []*big.Int{}
 	start := big.NewInt(0)
 	//fmt.Printf("No meaningful nodes: %d\n", len(epochData.MerkleNodes))
@@ -1188,7 +1696,7 @@ func (c Client) SetEpochData(epochData typedefs.EpochData, chain uint8) {
 		nodes = append(nodes, n)
 		if len(nodes) == 40 || k == len(epochData.MerkleNodes)-1 {
 			mnlen := big.NewInt(int64(len(nodes)))
-			fmt.Printf("Going to do tx\n")
+			c.progressf("Going to do tx\n")
 
 			if k < 440 && epochData.Epoch.Uint64() == 128 {
 				start.Add(start, mnlen)
@@ -1196,117 +1704,140 @@ func (c Client) SetEpochData(epochData typedefs.EpochData, chain uint8) {
 				continue
 			}
 
-			auth := prepareTransaction(c.account, c.privateKey, c.chains[chain], big.NewInt(0))
+			auth, err := prepareTransaction(c.context(), c.account, c.privateKey, c.chain(chain), big.NewInt(0))
+			if err != nil {
+				return results, err
+			}
 
-			tx, err := c.chains[chain].ethashContract.SetEpochData(auth, epochData.Epoch, epochData.FullSizeIn128Resolution,
+			tx, err := c.chain(chain).ethashContract.SetEpochData(auth, epochData.Epoch, epochData.FullSizeIn128Resolution,
 				epochData.BranchDepth, nodes, start, mnlen)
 			if err != nil {
-				log.Fatal(err)
+				return results, err
 			}
-			fmt.Printf("Tx submitted: %s\n", tx.Hash().Hex())
+			c.progressf("Tx submitted: %s\n", tx.Hash().Hex())
 
-			receipt, err := awaitTxReceipt(c.chains[chain].client, tx.Hash())
+			receipt, err := awaitTxReceipt(c.context(), c.chain(chain), tx.Hash())
 			if err != nil {
-				log.Fatal(err)
+				return results, err
 			}
 			if receipt.Status == 0 {
 				// Transaction failed
-				reason := getFailureReason(c.chains[chain].client, c.account, tx, receipt.BlockNumber)
-				fmt.Printf("Tx failed: %s\n", reason)
-				return
+				reason := getFailureReason(c.chain(chain).client, c.account, tx, receipt.BlockNumber)
+				return results, fmt.Errorf("tx failed: %s", reason)
 			}
 
+			results = append(results, newTxResult(tx, receipt))
 			start.Add(start, mnlen)
 			nodes = []*big.Int{}
 		}
 	}
+	return results, nil
 }
 
-func (c Client) DeployTestimonium(destinationChain uint8, sourceChain uint8, genesisBlockNumber uint64) (common.Address) {
-	if _, exists := c.chains[destinationChain]; !exists {
-		log.Fatalf("DestinationChain chain '%d' does not exist", destinationChain)
+func (c Client) DeployTestimonium(destinationChain uint8, sourceChain uint8, genesisBlockNumber uint64) (common.Address, error) {
+	if err := c.checkEthash(destinationChain); err != nil {
+		return common.Address{}, err
 	}
-	if _, exists := c.chains[sourceChain]; !exists {
-		log.Fatalf("Source chain '%d' does not exist", sourceChain)
+	if err := c.checkChain(sourceChain); err != nil {
+		return common.Address{}, err
 	}
 
 	header, err := c.HeaderByNumber(new(big.Int).SetUint64(genesisBlockNumber), sourceChain)
 	if err != nil {
-		log.Fatal("Failed to retrieve header from source chain: " + err.Error())
+		return common.Address{}, fmt.Errorf("failed to retrieve header from source chain: %s", err)
 	}
 
 	totalDifficulty, err := c.TotalDifficulty(new(big.Int).SetUint64(genesisBlockNumber), sourceChain)
 	if err != nil {
-		log.Fatalf("Failed to retrieve total difficulty of block %d: %s", genesisBlockNumber, err)
+		return common.Address{}, fmt.Errorf("failed to retrieve total difficulty of block %d: %s", genesisBlockNumber, err)
 	}
 
+	return c.deployTestimonium(destinationChain, &Genesis{
+		SourceChain:     sourceChain,
+		BlockNumber:     genesisBlockNumber,
+		BlockHash:       header.Hash(),
+		TotalDifficulty: totalDifficulty,
+	}, header)
+}
+
+// deployTestimonium deploys the Testimonium contract starting at the genesis block
+func (c Client) deployTestimonium(destinationChain uint8, genesis *Genesis, header *types.Header) (common.Address, error) {
+	totalDifficulty := genesis.TotalDifficulty
 	rlpHeader, err := encodeHeaderToRLP(header)
 	if err != nil {
-		log.Fatal("Failed to encode header to RLP: " + err.Error())
+		return common.Address{}, fmt.Errorf("failed to encode header to RLP: %s", err)
 	}
 
-	auth := prepareTransaction(c.account, c.privateKey, c.chains[destinationChain], big.NewInt(0))
+	auth, err := prepareTransaction(c.context(), c.account, c.privateKey, c.chain(destinationChain), big.NewInt(0))
+	if err != nil {
+		return common.Address{}, err
+	}
 
-	addr, tx, _, err := DeployTestimonium(auth, c.chains[destinationChain].client, rlpHeader, totalDifficulty, c.chains[destinationChain].ethashContractAddress)
+	addr, tx, _, err := DeployTestimonium(auth, txLogBackend{c.chain(destinationChain).client, c.chain(destinationChain)}, rlpHeader, totalDifficulty, c.chain(destinationChain).ethashContractAddress)
 	if err != nil {
-		log.Fatal(err)
+		return common.Address{}, err
 	}
-	fmt.Printf("Tx submitted: %s\n", tx.Hash().Hex())
+	c.progressf("Tx submitted: %s\n", tx.Hash().Hex())
 
-	receipt, err := awaitTxReceipt(c.chains[destinationChain].client, tx.Hash())
+	receipt, err := awaitTxReceipt(c.context(), c.chain(destinationChain), tx.Hash())
 	if err != nil {
-		log.Fatal(err)
+		return common.Address{}, err
 	}
 	if receipt.Status == 0 {
 		// Transaction failed
-		reason := getFailureReason(c.chains[destinationChain].client, c.account, tx, receipt.BlockNumber)
-		fmt.Printf("Tx failed: %s\n", reason)
-		return common.Address{}
+		reason := getFailureReason(c.chain(destinationChain).client, c.account, tx, receipt.BlockNumber)
+		return common.Address{}, fmt.Errorf("tx failed: %s", reason)
 	}
 
-	fmt.Println("Contract has been deployed at address: ", addr.String())
-	return addr
+	c.recordDeployment(destinationChain, REGISTRY_ETHRELAY, addr, receipt, nil, genesis)
+	return addr, nil
 }
 
-func (c Client) DeployEthash(destinationChain uint8) (common.Address) {
-	if _, exists := c.chains[destinationChain]; !exists {
-		log.Fatalf("DestinationChain chain '%d' does not exist", destinationChain)
+func (c Client) DeployEthash(destinationChain uint8) (common.Address, error) {
+	if err := c.checkChain(destinationChain); err != nil {
+		return common.Address{}, err
 	}
 
-	auth := prepareTransaction(c.account, c.privateKey, c.chains[destinationChain], big.NewInt(0))
+	auth, err := prepareTransaction(c.context(), c.account, c.privateKey, c.chain(destinationChain), big.NewInt(0))
+	if err != nil {
+		return common.Address{}, err
+	}
 
-	addr, tx, _, err := ethash.DeployEthash(auth, c.chains[destinationChain].client)
+	addr, tx, _, err := ethash.DeployEthash(auth, txLogBackend{c.chain(destinationChain).client, c.chain(destinationChain)})
 	if err != nil {
-		log.Fatal(err)
+		return common.Address{}, err
 	}
 
-	fmt.Printf("Tx submitted: %s\n", tx.Hash().Hex())
+	c.progressf("Tx submitted: %s\n", tx.Hash().Hex())
 
-	receipt, err := awaitTxReceipt(c.chains[destinationChain].client, tx.Hash())
+	receipt, err := awaitTxReceipt(c.context(), c.chain(destinationChain), tx.Hash())
 	if err != nil {
-		log.Fatal(err)
+		return common.Address{}, err
 	}
 
 	if receipt.Status == 0 {
 		// Transaction failed
-		reason := getFailureReason(c.chains[destinationChain].client, c.account, tx, receipt.BlockNumber)
-		fmt.Printf("Tx failed: %s\n", reason)
-		return common.Address{}
+		reason := getFailureReason(c.chain(destinationChain).client, c.account, tx, receipt.BlockNumber)
+		return common.Address{}, fmt.Errorf("tx failed: %s", reason)
 	}
 
-	fmt.Println("Contract has been deployed at address: ", addr.String())
-
-	return addr
+	c.recordDeployment(destinationChain, REGISTRY_ETHASH, addr, receipt, nil, nil)
+	return addr, nil
 }
 
 func getFailureReason(client *ethclient.Client, from common.Address, tx *types.Transaction, blockNumber *big.Int) string {
 	code, err := client.CallContract(context.Background(), createCallMsgFromTransaction(from, tx), blockNumber)
 
 	if err != nil {
-		log.Fatal(err)
+		return err.Error()
 	}
 
-	return fmt.Sprintf(string(code[67:]))
+	// the revert reason is ABI encoded as string following the function selector of Error(string)
+	if len(code) < 68 {
+		return "unknown reason"
+	}
+
+	return string(code[67:])
 }
 
 func createCallMsgFromTransaction(from common.Address, tx *types.Transaction) ethereum.CallMsg {
@@ -1320,29 +1851,9 @@ func createCallMsgFromTransaction(from common.Address, tx *types.Transaction) et
 	}
 }
 
+// encodeHeaderToRLP encodes the header in the format expected by the Testimonium contract
 func encodeHeaderToRLP(header *types.Header) ([]byte, error) {
-	buffer := new(bytes.Buffer)
-
-	err := rlp.Encode(buffer, []interface{}{
-		header.ParentHash,
-		header.UncleHash,
-		header.Coinbase,
-		header.Root,
-		header.TxHash,
-		header.ReceiptHash,
-		header.Bloom,
-		header.Difficulty,
-		header.Number,
-		header.GasLimit,
-		header.GasUsed,
-		header.Time,
-		header.Extra,
-		header.MixDigest,
-		header.Nonce,
-	})
-
-	// be careful when passing byte-array as buffer, the pointer can change if the buffer is used again
-	return buffer.Bytes(), err
+	return EthashHeaderCodec.EncodeHeader(&ExtendedHeader{Header: header})
 }
 
 func decodeHeaderFromRLP(bytes []byte) (*types.Header, error) {
@@ -1353,7 +1864,9 @@ func decodeHeaderFromRLP(bytes []byte) (*types.Header, error) {
 	return header, err
 }
 
-func encodeHeaderWithoutNonceToRLP(header *types.Header) ([]byte, error) {
+// EncodeHeaderWithoutNonce returns the RLP encoding of the header without the fields MixDigest and Nonce, whose hash is
+// the input of the Ethash proof of work (see HeaderHashWithoutNonce).
+func EncodeHeaderWithoutNonce(header *types.Header) ([]byte, error) {
 	buffer := new(bytes.Buffer)
 
 	err := rlp.Encode(buffer, []interface{}{
@@ -1375,18 +1888,37 @@ func encodeHeaderWithoutNonceToRLP(header *types.Header) ([]byte, error) {
 	return buffer.Bytes(), err
 }
 
-func prepareTransaction(from common.Address, privateKey *ecdsa.PrivateKey, chain *Chain, valueInWei *big.Int) *bind.TransactOpts {
-	nonce, err := chain.client.PendingNonceAt(context.Background(), from)
+func prepareTransaction(ctx context.Context, from common.Address, privateKey *ecdsa.PrivateKey, chain *Chain, valueInWei *big.Int) (*bind.TransactOpts, error) {
+	if privateKey == nil {
+		return nil, ErrNoAccount
+	}
+	if chain.role == ROLE_SOURCE {
+		return nil, fmt.Errorf("%w: chain %d", ErrSourceChain, chain.id)
+	}
+
+	// never send transactions to a chain other than the configured one
+	if chain.idMismatch != nil {
+		return nil, chain.idMismatch
+	}
+
+	nonce, err := chain.client.PendingNonceAt(ctx, from)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	if op, exists := chain.operators[from]; exists {
+		nonce = op.reserveNonce(chain.id, nonce)
 	}
 
-	gasPrice, err := chain.client.SuggestGasPrice(context.Background())
+	gasPrice, err := chain.client.SuggestGasPrice(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	auth := bind.NewKeyedTransactor(privateKey)
+	if chain.signingChainId != nil {
+		auth.Signer = keyedSigner(privateKey, chain.signingChainId)
+	}
+	auth.Context = ctx
 	auth.From = from
 	auth.Nonce = big.NewInt(int64(nonce))
 	auth.Value = valueInWei // in wei
@@ -1394,52 +1926,79 @@ func prepareTransaction(from common.Address, privateKey *ecdsa.PrivateKey, chain
 
 	// one could also set the gas limit, however it seems that the right gas limit is only estimated
 	// if the gas limit is not set specifically
-	return auth
+	if err := modifyTransactOpts(chain, auth); err != nil {
+		return nil, err
+	}
+	return auth, nil
 }
 
-func awaitTxReceipt(client *ethclient.Client, txHash common.Hash) (*types.Receipt, error) {
-	const TimeoutLength = 2
-	receipts := make(chan *types.Receipt)
+// TX_RECEIPT_TIMEOUT is the time awaitTxReceipt waits for the receipt of a sent transaction.
+const TX_RECEIPT_TIMEOUT = 2 * time.Minute
 
-	go func(chan *types.Receipt) {
-		for ; ; {
-			receipt, _ := client.TransactionReceipt(context.Background(), txHash)
+// awaitTxReceipt waits for the receipt until the timeout expires or the context (usually the client's) is canceled
+func awaitTxReceipt(ctx context.Context, chain *Chain, txHash common.Hash) (*types.Receipt, error) {
+	return awaitTxReceiptWithin(ctx, chain, txHash, TX_RECEIPT_TIMEOUT)
+}
 
-			if receipt != nil {
-				receipts <- receipt
-			}
+func awaitTxReceiptWithin(ctx context.Context, chain *Chain, txHash common.Hash, timeout time.Duration) (*types.Receipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	receipt, err := awaitTxReceiptContext(ctx, chain, txHash)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("timeout: did not receive receipt after %s: %w", timeout, err)
+	}
+	return receipt, err
+}
+
+// awaitTxReceiptContext waits until the transaction is mined or the context is done. The receipt is requested whenever
+// the chain announces a new block, if the node does not support subscriptions it is polled. No goroutines are started,
+// so nothing keeps running after the function returned.
+func awaitTxReceiptContext(ctx context.Context, chain *Chain, txHash common.Hash) (*types.Receipt, error) {
+	// relayed transactions are executed by a transaction of the relayer
+	txHash, err := chain.relayedTxHash(ctx, chain.substitutedTxHash(txHash))
+	if err != nil {
+		return nil, err
+	}
+
+	// the receipt is polled with the delays of the receipt retry policy, with a subscription polling is only a
+	// fallback in case a notification is missed
+	policy := chain.retryPolicy(RETRY_RECEIPT)
+	heads := make(chan *types.Header, 1)
+	var subErr <-chan error
+	subscribed := false
+	if chain.capabilities.Subscriptions {
+		if sub, err := chain.client.SubscribeNewHead(ctx, heads); err == nil {
+			defer sub.Unsubscribe()
+			subErr = sub.Err()
+			subscribed = true
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		// errors other than a missing receipt (e.g., a failed request) are retried with the next block
+		receipt, _ := chain.client.TransactionReceipt(ctx, txHash)
+		if receipt != nil {
+			return receipt, nil
+		}
+
+		delay := policy.Delay(attempt)
+		if subscribed && policy.MaxDelay > 0 {
+			delay = policy.MaxDelay
 		}
-	}(receipts)
-
-	select {
-	case receipt := <-receipts:
-		return receipt, nil
-	case <-time.After(TimeoutLength * time.Minute):
-		return nil, fmt.Errorf("timeout: did not receive receipt after %d minutes", TimeoutLength)
-	}
-
-	//query := ethereum.FilterQuery{
-	//	Addresses: []common.Address{chain.testimoniumContractAddress},
-	//}
-	//
-	//logs := make(chan types.Log)
-	//
-	//sub, err := chain.client.SubscribeFilterLogs(context.Background(), query, logs)
-	//if err != nil {
-	//	log.Fatal(err)
-	//}
-	//
-	//for {
-	//	select {
-	//	case err := <-sub.Err():
-	//		return nil, err
-	//	case vLog := <-logs:
-	//		// if the transaction hash of the event does not equal the passed
-	//		// transaction hash we continue listening
-	//		if vLog.TxHash.Hex() != txHash.Hex() {
-	//			break
-	//		}
-	//		return parseEvent(vLog)
-	//	}
-	//}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-heads:
+		case <-subErr:
+			// the subscription failed, fall back to polling
+			subErr = nil
+			subscribed = false
+			attempt = 0
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
 }
@@ -0,0 +1,181 @@
+// This file contains the check of the local clock against the timestamps of the chains. The contract measures lock
+// periods (the time stake stays locked and a header can be disputed) with the timestamps of the destination chain's
+// blocks, while the live mode waits for them with the local clock, so a skewed clock or a stale node lets the client
+// misjudge when stake is unlocked and until when headers can be disputed.
+
+package testimonium
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// CLOCK_SKEW_TOLERANCE is the default difference between the local clock and the timestamps of the chains that is
+// tolerated in addition to the block interval of the chains.
+const CLOCK_SKEW_TOLERANCE = 30 * time.Second
+
+// CLOCK_CHECK_INTERVAL is the interval the live mode checks the clocks in.
+const CLOCK_CHECK_INTERVAL = 10 * time.Minute
+
+// blocks the block interval of a chain is averaged over
+const clockIntervalBlocks = 10
+
+// ChainClock is the head of a chain compared with the local clock.
+type ChainClock struct {
+	Chain         uint8         `json:"chain"`
+	HeadNumber    uint64        `json:"headNumber"`
+	HeadTime      time.Time     `json:"headTime"`
+	HeadAge       time.Duration `json:"headAge"`       // local time minus the head's timestamp, negative if it is in the future
+	BlockInterval time.Duration `json:"blockInterval"` // average interval of the latest blocks
+}
+
+// ClockCheck compares the local clock with the heads of a source and a destination chain.
+type ClockCheck struct {
+	LocalTime   time.Time  `json:"localTime"`
+	Source      ChainClock `json:"source"`
+	Destination ChainClock `json:"destination"`
+	// timestamp of the source chain's head minus the timestamp of the destination chain's head
+	ChainSkew  time.Duration `json:"chainSkew"`
+	LockPeriod time.Duration `json:"lockPeriod"` // lock period of the ETH Relay contract, 0 if it cannot be read
+	Tolerance  time.Duration `json:"tolerance"`
+	Warnings   []string      `json:"warnings,omitempty"`
+}
+
+// Skewed reports whether the check found a skew beyond the tolerance.
+func (check ClockCheck) Skewed() bool {
+	return len(check.Warnings) > 0
+}
+
+// CheckClocks compares the local clock with the timestamps of the heads of the source and the destination chain. A
+// warning is returned in the check if a head is ahead of the local clock or older than its block interval by more
+// than the tolerance (CLOCK_SKEW_TOLERANCE if 0), or if the chains' timestamps differ by more than that. Skews of the
+// destination chain are related to the lock period of the ETH Relay contract if the contract exposes it.
+func (c Client) CheckClocks(sourceChain uint8, destinationChain uint8, tolerance time.Duration) (ClockCheck, error) {
+	if err := c.checkChain(sourceChain); err != nil {
+		return ClockCheck{}, err
+	}
+	if err := c.checkChain(destinationChain); err != nil {
+		return ClockCheck{}, err
+	}
+	if tolerance <= 0 {
+		tolerance = CLOCK_SKEW_TOLERANCE
+	}
+
+	check := ClockCheck{Tolerance: tolerance}
+	var err error
+	if check.Source, err = c.chainClock(sourceChain); err != nil {
+		return ClockCheck{}, err
+	}
+	if check.Destination, err = c.chainClock(destinationChain); err != nil {
+		return ClockCheck{}, err
+	}
+	check.LocalTime = time.Now().UTC()
+	check.ChainSkew = check.Source.HeadTime.Sub(check.Destination.HeadTime)
+	if c.chain(destinationChain).testimoniumContract != nil {
+		if lockPeriod, err := c.LockPeriod(destinationChain); err == nil {
+			check.LockPeriod = time.Duration(lockPeriod.Int64()) * time.Second
+		}
+	}
+
+	for _, chain := range []ChainClock{check.Source, check.Destination} {
+		if chain.HeadAge < -tolerance {
+			check.Warnings = append(check.Warnings, fmt.Sprintf("head of chain %d is %s ahead of the local clock, "+
+				"the local clock is behind or the chain's timestamps are skewed", chain.Chain, (-chain.HeadAge).Round(time.Second)))
+		} else if chain.HeadAge > chain.BlockInterval+tolerance {
+			check.Warnings = append(check.Warnings, fmt.Sprintf("head of chain %d is %s old (block interval %s), "+
+				"the node is stale or the local clock is ahead", chain.Chain, chain.HeadAge.Round(time.Second), chain.BlockInterval.Round(time.Second)))
+		}
+	}
+
+	maxInterval := check.Source.BlockInterval
+	if check.Destination.BlockInterval > maxInterval {
+		maxInterval = check.Destination.BlockInterval
+	}
+	if absDuration(check.ChainSkew) > maxInterval+tolerance {
+		check.Warnings = append(check.Warnings, fmt.Sprintf("timestamps of chain %d and chain %d differ by %s",
+			sourceChain, destinationChain, check.ChainSkew.Round(time.Second)))
+	}
+
+	// the offset between the local clock and the destination chain is what the waiting for lock periods is off by
+	offset := check.Destination.HeadAge
+	if offset > 0 {
+		offset -= check.Destination.BlockInterval
+		if offset < 0 {
+			offset = 0
+		}
+	}
+	if check.LockPeriod > 0 && absDuration(offset) > tolerance {
+		direction := "before"
+		if offset < 0 {
+			direction = "after"
+		}
+		check.Warnings = append(check.Warnings, fmt.Sprintf("lock periods measured with the local clock end about %s %s "+
+			"the contract's (%.1f%% of the lock period of %s), stake and dispute windows may be misjudged",
+			absDuration(offset).Round(time.Second), direction, 100*float64(absDuration(offset))/float64(check.LockPeriod), check.LockPeriod))
+	}
+	return check, nil
+}
+
+// chainClock reads the head of the chain and the average interval of its latest blocks
+func (c Client) chainClock(chain uint8) (ChainClock, error) {
+	ctx, cancel := context.WithTimeout(c.context(), 30*time.Second)
+	defer cancel()
+
+	client := c.chain(chain).client
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return ChainClock{}, fmt.Errorf("failed to read the head of chain %d: %s", chain, err)
+	}
+	headTime := time.Unix(int64(head.Time), 0).UTC()
+	clock := ChainClock{
+		Chain:      chain,
+		HeadNumber: head.Number.Uint64(),
+		HeadTime:   headTime,
+		HeadAge:    time.Since(headTime),
+	}
+
+	if head.Number.Uint64() >= clockIntervalBlocks {
+		var earlier *types.Header
+		earlier, err = client.HeaderByNumber(ctx, new(big.Int).Sub(head.Number, big.NewInt(clockIntervalBlocks)))
+		if err != nil {
+			return ChainClock{}, fmt.Errorf("failed to read block %d of chain %d: %s", head.Number.Uint64()-clockIntervalBlocks, chain, err)
+		}
+		clock.BlockInterval = time.Duration(head.Time-earlier.Time) * time.Second / clockIntervalBlocks
+	}
+	return clock, nil
+}
+
+// warnClockSkew prints the warnings of a clock check, failures of the check are printed as warnings as well
+func (c Client) warnClockSkew(sourceChain uint8, destinationChain uint8) {
+	check, err := c.CheckClocks(sourceChain, destinationChain, 0)
+	if err != nil {
+		c.progressf("WARNING: Cannot check the clocks: %s\n", err)
+		return
+	}
+	for _, warning := range check.Warnings {
+		c.progressf("WARNING: Clock skew: %s\n", warning)
+	}
+	c.liveMonitor.clockChecked(check)
+}
+
+// clockChecked records the warnings of the last clock check, the monitor may be nil
+func (m *LiveMonitor) clockChecked(check ClockCheck) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.clockWarnings = check.Warnings
+	m.lastClockCheck = check.LocalTime
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
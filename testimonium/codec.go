@@ -0,0 +1,248 @@
+// This file contains the chain-specific RLP encodings of block headers. The encoding of a chain's headers is
+// selected with the "headercodec" entry of the chain config.
+
+package testimonium
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrHeaderCodecMismatch is returned if a header encoded with the codec of a chain does not hash to the block hash.
+var ErrHeaderCodecMismatch = errors.New("encoded header does not match block hash")
+
+// ExtendedHeader is a header including the fields added by forks after the Ethash era, which are not part of
+// types.Header. Fields that do not exist on a chain are nil.
+type ExtendedHeader struct {
+	*types.Header
+	BaseFee          *big.Int     // EIP-1559 (London)
+	WithdrawalsHash  *common.Hash // EIP-4895 (Shanghai)
+	BlobGasUsed      *uint64      // EIP-4844 (Cancun)
+	ExcessBlobGas    *uint64      // EIP-4844 (Cancun)
+	ParentBeaconRoot *common.Hash // EIP-4788 (Cancun)
+	RequestsHash     *common.Hash // EIP-7685 (Prague)
+}
+
+func (h *ExtendedHeader) UnmarshalJSON(input []byte) error {
+	h.Header = new(types.Header)
+	if err := json.Unmarshal(input, h.Header); err != nil {
+		return err
+	}
+
+	var extension struct {
+		BaseFee          *hexutil.Big    `json:"baseFeePerGas"`
+		WithdrawalsHash  *common.Hash    `json:"withdrawalsRoot"`
+		BlobGasUsed      *hexutil.Uint64 `json:"blobGasUsed"`
+		ExcessBlobGas    *hexutil.Uint64 `json:"excessBlobGas"`
+		ParentBeaconRoot *common.Hash    `json:"parentBeaconBlockRoot"`
+		RequestsHash     *common.Hash    `json:"requestsHash"`
+	}
+	if err := json.Unmarshal(input, &extension); err != nil {
+		return err
+	}
+	if extension.BaseFee != nil {
+		h.BaseFee = extension.BaseFee.ToInt()
+	}
+	h.WithdrawalsHash = extension.WithdrawalsHash
+	if extension.BlobGasUsed != nil {
+		blobGasUsed := uint64(*extension.BlobGasUsed)
+		h.BlobGasUsed = &blobGasUsed
+	}
+	if extension.ExcessBlobGas != nil {
+		excessBlobGas := uint64(*extension.ExcessBlobGas)
+		h.ExcessBlobGas = &excessBlobGas
+	}
+	h.ParentBeaconRoot = extension.ParentBeaconRoot
+	h.RequestsHash = extension.RequestsHash
+	return nil
+}
+
+// extensionFields returns the fields following the Ethash fields in the order of the forks introducing them. As each
+// fork appends fields, the returned fields end at the first field that is not set.
+func (h *ExtendedHeader) extensionFields() ([]interface{}, error) {
+	var fields []interface{}
+	present := []bool{
+		h.BaseFee != nil,
+		h.WithdrawalsHash != nil,
+		h.BlobGasUsed != nil,
+		h.ExcessBlobGas != nil,
+		h.ParentBeaconRoot != nil,
+		h.RequestsHash != nil,
+	}
+	for i, isPresent := range present {
+		if !isPresent {
+			for _, later := range present[i+1:] {
+				if later {
+					return nil, fmt.Errorf("header contains fields of later forks without field %d of the earlier forks", i)
+				}
+			}
+			break
+		}
+		switch i {
+		case 0:
+			fields = append(fields, h.BaseFee)
+		case 1:
+			fields = append(fields, *h.WithdrawalsHash)
+		case 2:
+			fields = append(fields, *h.BlobGasUsed)
+		case 3:
+			fields = append(fields, *h.ExcessBlobGas)
+		case 4:
+			fields = append(fields, *h.ParentBeaconRoot)
+		case 5:
+			fields = append(fields, *h.RequestsHash)
+		}
+	}
+	return fields, nil
+}
+
+// HeaderCodec encodes the headers of a chain the way the chain hashes them.
+type HeaderCodec interface {
+	Name() string
+	// EncodeHeader returns the RLP encoding of the header, its Keccak-256 hash is the block hash.
+	EncodeHeader(header *ExtendedHeader) ([]byte, error)
+}
+
+// forkHeaderCodec encodes the Ethash fields followed by the fields of later forks, which is the encoding of all
+// Ethereum-like chains. The chains differ in the forks they activated.
+type forkHeaderCodec struct {
+	name     string
+	required int // number of fields of later forks that have to be present
+	allowed  int // maximum number of fields of later forks
+}
+
+func (codec forkHeaderCodec) Name() string {
+	return codec.name
+}
+
+func (codec forkHeaderCodec) EncodeHeader(header *ExtendedHeader) ([]byte, error) {
+	extension, err := header.extensionFields()
+	if err != nil {
+		return nil, err
+	}
+	if len(extension) < codec.required {
+		return nil, fmt.Errorf("%s headers require %d fields after the nonce, the header contains %d", codec.name, codec.required, len(extension))
+	}
+	if len(extension) > codec.allowed {
+		return nil, fmt.Errorf("%s headers have at most %d fields after the nonce, the header contains %d", codec.name, codec.allowed, len(extension))
+	}
+
+	fields := []interface{}{
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra,
+		header.MixDigest,
+		header.Nonce,
+	}
+	return rlp.EncodeToBytes(append(fields, extension...))
+}
+
+var (
+	// EthashHeaderCodec encodes proof-of-work headers before London, the only headers the Testimonium contract accepts.
+	EthashHeaderCodec HeaderCodec = forkHeaderCodec{name: "ethash", required: 0, allowed: 0}
+	// CliqueHeaderCodec encodes proof-of-authority headers, the signature is part of the extra data.
+	CliqueHeaderCodec HeaderCodec = forkHeaderCodec{name: "clique", required: 0, allowed: 1}
+	// EIP1559HeaderCodec encodes headers after London containing the base fee.
+	EIP1559HeaderCodec HeaderCodec = forkHeaderCodec{name: "eip1559", required: 1, allowed: 1}
+	// PoSHeaderCodec encodes proof-of-stake headers from Shanghai on.
+	PoSHeaderCodec HeaderCodec = forkHeaderCodec{name: "pos", required: 2, allowed: 6}
+	// ArbitrumHeaderCodec encodes Arbitrum Nitro headers, which use the London layout.
+	ArbitrumHeaderCodec HeaderCodec = forkHeaderCodec{name: "arbitrum", required: 1, allowed: 1}
+	// OptimismHeaderCodec encodes OP Stack (Bedrock) headers, which follow the L1 forks.
+	OptimismHeaderCodec HeaderCodec = forkHeaderCodec{name: "optimism", required: 1, allowed: 6}
+)
+
+var headerCodecs = map[string]HeaderCodec{}
+
+func init() {
+	for _, codec := range []HeaderCodec{EthashHeaderCodec, CliqueHeaderCodec, EIP1559HeaderCodec, PoSHeaderCodec,
+		ArbitrumHeaderCodec, OptimismHeaderCodec} {
+		RegisterHeaderCodec(codec)
+	}
+}
+
+// RegisterHeaderCodec makes the codec selectable by its name in the chain config. Register codecs before creating
+// the client.
+func RegisterHeaderCodec(codec HeaderCodec) {
+	headerCodecs[strings.ToLower(codec.Name())] = codec
+}
+
+// HeaderCodecByName returns the registered codec with the name.
+func HeaderCodecByName(name string) (HeaderCodec, error) {
+	codec, exists := headerCodecs[strings.ToLower(name)]
+	if !exists {
+		return nil, fmt.Errorf("unknown header codec '%s'", name)
+	}
+	return codec, nil
+}
+
+// headerCodecFromConfig returns the codec configured for the chain, the Ethash codec if none is configured
+func headerCodecFromConfig(chainConfig map[string]interface{}) (HeaderCodec, error) {
+	name, ok := chainConfig["headercodec"].(string)
+	if !ok || name == "" {
+		return EthashHeaderCodec, nil
+	}
+	return HeaderCodecByName(name)
+}
+
+// HeaderCodec returns the codec of the chain's headers.
+func (c Client) HeaderCodec(chain uint8) (HeaderCodec, error) {
+	if err := c.checkChain(chain); err != nil {
+		return nil, err
+	}
+	return c.chain(chain).codec, nil
+}
+
+// RLPHeaderByHash returns the RLP encoded header of the block with the specified hash, encoded with the codec of the
+// chain. ErrHeaderCodecMismatch is returned if the encoding does not hash to the block hash, e.g., because the
+// configured codec does not fit the chain.
+func (c Client) RLPHeaderByHash(blockHash common.Hash, chain uint8) ([]byte, error) {
+	if err := c.checkChain(chain); err != nil {
+		return nil, err
+	}
+
+	var header *ExtendedHeader
+	err := c.withArchiveFallback(chain, "header "+blockHash.Hex(), func(_ *ethclient.Client, rpcClient *rpc.Client) error {
+		if err := rpcClient.CallContext(c.context(), &header, "eth_getBlockByHash", blockHash, false); err != nil {
+			return err
+		}
+		if header == nil {
+			return fmt.Errorf("block %s %w", blockHash.Hex(), ethereum.NotFound)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rlpHeader, err := c.chain(chain).codec.EncodeHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	if crypto.Keccak256Hash(rlpHeader) != blockHash {
+		return nil, fmt.Errorf("%w: %s (codec %s)", ErrHeaderCodecMismatch, blockHash.Hex(), c.chain(chain).codec.Name())
+	}
+	return rlpHeader, nil
+}
@@ -0,0 +1,329 @@
+// This file contains the conformance suite of the proof generation. Proofs of transactions, receipts and accounts are
+// built locally for generated tries, mutated in the ways proofs break (tampered values and nodes, wrong paths, missing
+// or superfluous nodes) and verified both with the pure-Go verification (proofs.VerifyProof) and by the bundled ETH
+// Relay contract on a simulated backend. Both have to accept and reject the same proofs, so changes to the proof
+// generation cannot silently diverge from the verification of the contract. The defects of the bundled contract's
+// verification (see contractproof.go) fail the suite as well, the checks name the defect causing the difference.
+
+package testimonium
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"math/rand"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+	"github.com/pantos-io/go-ethrelay/proofs"
+)
+
+// CONFORMANCE_VECTORS is the default number of generated tries of the conformance suite
+const CONFORMANCE_VECTORS = 8
+
+// gas limit of the verifications on the simulated backend
+const conformanceGasLimit = 8000000
+
+// sizes of the tries that are always checked: a single value (the root is the leaf), values with and without nodes
+// embedded in their parents (smaller than 32 bytes) and the boundary of the one and two byte RLP encoded indices
+var conformanceTrieSizes = []int{1, 2, 17, 130}
+
+// ConformanceCheck is the outcome of verifying a proof with the pure-Go verification and with the contract.
+type ConformanceCheck struct {
+	Name             string `json:"name"` // value type, trie and mutation of the proof
+	LocalAccepted    bool   `json:"localAccepted"`
+	ContractAccepted bool   `json:"contractAccepted"`
+	// PredictedAccepted is the outcome of the model of the bundled contract's verification (verifyProofLikeContract)
+	PredictedAccepted bool  `json:"predictedAccepted"`
+	ReturnCode        uint8 `json:"returnCode"` // result of the verification event of the contract, 0 if accepted
+	// Passed is set if both accepted or both rejected the proof
+	Passed bool `json:"passed"`
+	// ContractDefect is the defect of the bundled contract that explains a failed check, empty if it is unexplained
+	ContractDefect string `json:"contractDefect,omitempty"`
+	Detail         string `json:"detail,omitempty"`
+}
+
+// proofMutation changes a proof in a way the verification has to detect, or not (e.g., the valid proof)
+type proofMutation struct {
+	name   string
+	mutate func(proof proofs.Proof, other []byte) proofs.Proof // other is the path of another value of the trie
+}
+
+var proofMutations = []proofMutation{
+	{"valid", func(proof proofs.Proof, other []byte) proofs.Proof { return proof }},
+	{"tampered value", func(proof proofs.Proof, other []byte) proofs.Proof {
+		proof.Value = flipLastByte(proof.Value)
+		return proof
+	}},
+	{"path of another value", func(proof proofs.Proof, other []byte) proofs.Proof {
+		proof.Path = other
+		return proof
+	}},
+	{"tampered leaf node", func(proof proofs.Proof, other []byte) proofs.Proof {
+		proof.Nodes = replaceNode(proof.Nodes, len(proof.Nodes)-1, flipLastByte(proof.Nodes[len(proof.Nodes)-1]))
+		return proof
+	}},
+	{"tampered root node", func(proof proofs.Proof, other []byte) proofs.Proof {
+		proof.Nodes = replaceNode(proof.Nodes, 0, flipLastByte(proof.Nodes[0]))
+		return proof
+	}},
+	{"missing leaf node", func(proof proofs.Proof, other []byte) proofs.Proof {
+		proof.Nodes = proof.Nodes[:len(proof.Nodes)-1]
+		return proof
+	}},
+	{"superfluous node", func(proof proofs.Proof, other []byte) proofs.Proof {
+		proof.Nodes = append(append([][]byte(nil), proof.Nodes...), proof.Nodes[0])
+		return proof
+	}},
+}
+
+// conformanceVector is a generated block whose transactions, receipts and accounts are proven
+type conformanceVector struct {
+	name     string
+	header   *types.Header
+	txs      types.Transactions
+	receipts types.Receipts
+	accounts []common.Address
+	state    *trie.Trie
+}
+
+// RunConformanceSuite generates the tries of the specified number of random blocks (in addition to the tries of fixed
+// sizes) from the seed and checks the proofs of their first, last and, if contained, 128th values. The contracts are
+// deployed on a simulated backend, no chain is connected.
+func RunConformanceSuite(seed int64, vectors int) ([]ConformanceCheck, error) {
+	random := rand.New(rand.NewSource(seed))
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	auth := bind.NewKeyedTransactor(key)
+	funds := new(big.Int).Exp(big.NewInt(10), big.NewInt(30), nil)
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{auth.From: {Balance: funds}}, 10*conformanceGasLimit)
+	ethashAddress, _, _, err := ethash.DeployEthash(auth, backend)
+	if err != nil {
+		return nil, fmt.Errorf("cannot deploy the Ethash contract: %s", err)
+	}
+	backend.Commit()
+
+	sizes := append([]int(nil), conformanceTrieSizes...)
+	for i := 0; i < vectors; i++ {
+		sizes = append(sizes, 1+random.Intn(300))
+	}
+	var checks []ConformanceCheck
+	for i, size := range sizes {
+		vector, err := newConformanceVector(random, key, uint64(i), size)
+		if err != nil {
+			return checks, err
+		}
+		vectorChecks, err := vector.check(auth, backend, ethashAddress)
+		if err != nil {
+			return checks, fmt.Errorf("%s: %s", vector.name, err)
+		}
+		checks = append(checks, vectorChecks...)
+	}
+	return checks, nil
+}
+
+// newConformanceVector generates a block with the number of transactions (and receipts and accounts)
+func newConformanceVector(random *rand.Rand, key *ecdsa.PrivateKey, number uint64, size int) (*conformanceVector, error) {
+	vector := &conformanceVector{name: fmt.Sprintf("trie of %d values", size)}
+	signer := types.HomesteadSigner{}
+	stateTrie, err := trie.New(common.Hash{}, trie.NewDatabase(memorydb.New()))
+	if err != nil {
+		return nil, err
+	}
+	vector.state = stateTrie
+
+	for i := 0; i < size; i++ {
+		var recipient common.Address
+		random.Read(recipient[:])
+		// small payloads keep some trie nodes below 32 bytes, so they are embedded in their parents
+		data := make([]byte, random.Intn(3)*random.Intn(100))
+		random.Read(data)
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), recipient, big.NewInt(random.Int63()), 21000+uint64(len(data))*68,
+			big.NewInt(1+random.Int63n(1e11)), data), signer, key)
+		if err != nil {
+			return nil, err
+		}
+		vector.txs = append(vector.txs, tx)
+
+		receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: uint64(i+1) * 21000}
+		for j := random.Intn(3); j > 0; j-- {
+			receipt.Logs = append(receipt.Logs, &types.Log{Address: recipient, Topics: []common.Hash{crypto.Keccak256Hash(data)}, Data: data})
+		}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		vector.receipts = append(vector.receipts, receipt)
+
+		account, err := rlp.EncodeToBytes(state.Account{Nonce: random.Uint64(), Balance: big.NewInt(random.Int63()),
+			Root: types.EmptyRootHash, CodeHash: crypto.Keccak256(nil)})
+		if err != nil {
+			return nil, err
+		}
+		vector.accounts = append(vector.accounts, recipient)
+		vector.state.Update(crypto.Keccak256(recipient.Bytes()), account)
+	}
+
+	vector.header = &types.Header{
+		ParentHash:  crypto.Keccak256Hash(new(big.Int).SetUint64(number).Bytes()),
+		UncleHash:   types.EmptyUncleHash,
+		Root:        vector.state.Hash(),
+		TxHash:      types.DeriveSha(vector.txs),
+		ReceiptHash: types.DeriveSha(vector.receipts),
+		Difficulty:  big.NewInt(131072),
+		Number:      new(big.Int).SetUint64(1000 + number),
+		GasLimit:    conformanceGasLimit,
+		Time:        1438269988 + number,
+		Extra:       []byte{},
+	}
+	return vector, nil
+}
+
+// check deploys an ETH Relay contract with the header of the vector as genesis and verifies the mutated proofs of the
+// vector's values
+func (vector *conformanceVector) check(auth *bind.TransactOpts, backend *backends.SimulatedBackend, ethashAddress common.Address) ([]ConformanceCheck, error) {
+	rlpHeader, err := rlp.EncodeToBytes(vector.header)
+	if err != nil {
+		return nil, err
+	}
+	_, _, contract, err := DeployTestimonium(auth, backend, rlpHeader, big.NewInt(1), ethashAddress)
+	if err != nil {
+		return nil, fmt.Errorf("cannot deploy the ETH Relay contract: %s", err)
+	}
+	backend.Commit()
+	fee, err := contract.GetRequiredVerificationFee(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := []int{0, len(vector.txs) - 1}
+	if len(vector.txs) > 128 {
+		indexes = append(indexes, 128)
+	}
+	var checks []ConformanceCheck
+	for _, index := range indexes {
+		other := (index + 1) % len(vector.txs)
+		for _, valueType := range []TrieValueType{VALUE_TYPE_TRANSACTION, VALUE_TYPE_RECEIPT, VALUE_TYPE_STATE} {
+			proof, otherPath, err := vector.proof(valueType, index, other)
+			if err != nil {
+				return checks, err
+			}
+			for _, mutation := range proofMutations {
+				if len(proof.Nodes) == 1 && mutation.name == "missing leaf node" || index == other && mutation.name == "path of another value" {
+					// the mutation would leave no proof or not change it
+					continue
+				}
+				mutated := mutation.mutate(proof, otherPath)
+				check, err := verifyConformance(auth, backend, contract, fee, rlpHeader, valueType, mutated)
+				if err != nil {
+					return checks, err
+				}
+				check.Name = fmt.Sprintf("%s %d of %s, %s", valueType, index, vector.name, mutation.name)
+				checks = append(checks, check)
+			}
+		}
+	}
+	return checks, nil
+}
+
+// proof builds the proof of the value of the type at the index and returns the path of the other value
+func (vector *conformanceVector) proof(valueType TrieValueType, index int, other int) (proofs.Proof, []byte, error) {
+	switch valueType {
+	case VALUE_TYPE_TRANSACTION:
+		proof, err := proofs.BuildTxProof(vector.txs, uint(index))
+		otherPath, _ := rlp.EncodeToBytes(uint(other))
+		return proof, otherPath, err
+	case VALUE_TYPE_RECEIPT:
+		proof, err := proofs.BuildReceiptProof(vector.receipts, uint(index))
+		otherPath, _ := rlp.EncodeToBytes(uint(other))
+		return proof, otherPath, err
+	default:
+		address := vector.accounts[index]
+		var nodes [][]byte
+		iterator := vector.state.NodeIterator(nil)
+		for iterator.Next(true) {
+			if iterator.Leaf() && bytes.Equal(iterator.LeafKey(), crypto.Keccak256(address.Bytes())) {
+				nodes = iterator.LeafProof()
+				break
+			}
+		}
+		proof, err := proofs.BuildAccountProof(vector.header.Root, address, nodes)
+		return proof, crypto.Keccak256(vector.accounts[other].Bytes()), err
+	}
+}
+
+// verifyConformance verifies the proof with the pure-Go verification and with the contract
+func verifyConformance(auth *bind.TransactOpts, backend *backends.SimulatedBackend, contract *Testimonium, fee *big.Int,
+	rlpHeader []byte, valueType TrieValueType, proof proofs.Proof) (ConformanceCheck, error) {
+	var check ConformanceCheck
+	localErr := proofs.VerifyProof(proof)
+	check.LocalAccepted = localErr == nil
+	predicted := verifyProofLikeContract(proof)
+	check.PredictedAccepted = predicted.accepted
+
+	nodes, err := proof.EncodedNodes()
+	if err != nil {
+		return check, err
+	}
+	verifier, err := valueType.verifier()
+	if err != nil {
+		return check, err
+	}
+	opts := *auth
+	opts.Value, opts.GasLimit = fee, conformanceGasLimit
+	tx, err := verifier.SendVerification(contract, &opts, fee, rlpHeader, 0, proof.Value, proof.Path, nodes)
+	if err != nil {
+		return check, err
+	}
+	backend.Commit()
+	receipt, err := backend.TransactionReceipt(context.Background(), tx.Hash())
+	if err != nil {
+		return check, err
+	}
+
+	if receipt.Status == types.ReceiptStatusFailed {
+		check.Detail = "the verification reverted"
+	} else {
+		for _, log := range receipt.Logs {
+			if len(log.Topics) == 0 || log.Topics[0] != verifier.EventId {
+				continue
+			}
+			result, err := verifier.ParseResult(contract, *log)
+			if err != nil {
+				return check, err
+			}
+			check.ReturnCode = result.ReturnCode
+			check.ContractAccepted = result.ReturnCode == 0
+		}
+	}
+	check.Passed = check.LocalAccepted == check.ContractAccepted
+	if !check.Passed && check.ContractAccepted == check.PredictedAccepted {
+		check.ContractDefect = predicted.defect
+	}
+	if !check.Passed && localErr != nil && check.Detail == "" {
+		check.Detail = fmt.Sprintf("rejected locally: %s", localErr)
+	}
+	return check, nil
+}
+
+func flipLastByte(data []byte) []byte {
+	flipped := append([]byte(nil), data...)
+	flipped[len(flipped)-1] ^= 0x01
+	return flipped
+}
+
+func replaceNode(nodes [][]byte, index int, node []byte) [][]byte {
+	replaced := append([][]byte(nil), nodes...)
+	replaced[index] = node
+	return replaced
+}
@@ -0,0 +1,106 @@
+package testimonium
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/pantos-io/go-ethrelay/proofs"
+)
+
+// TestConformanceSuite runs the conformance suite on the simulated backend. Every outcome of the contract has to match
+// the model of its verification and every difference from the pure-Go verification has to be one of its known
+// defects, so new differences (of the proof generation or the contract) fail.
+func TestConformanceSuite(t *testing.T) {
+	checks, err := RunConformanceSuite(1, CONFORMANCE_VECTORS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) == 0 {
+		t.Fatal("no checks")
+	}
+
+	defects := make(map[string]int)
+	for _, check := range checks {
+		if check.ContractAccepted != check.PredictedAccepted {
+			t.Errorf("%s: contract accepted: %t, predicted: %t (return code %d) %s", check.Name, check.ContractAccepted,
+				check.PredictedAccepted, check.ReturnCode, check.Detail)
+		}
+		expected := strings.HasSuffix(check.Name, ", valid") || strings.HasSuffix(check.Name, ", superfluous node")
+		if check.LocalAccepted != expected {
+			t.Errorf("%s: locally accepted: %t, expected: %t", check.Name, check.LocalAccepted, expected)
+		}
+		if !check.Passed {
+			if check.ContractDefect == "" {
+				t.Errorf("%s: unexplained difference (contract accepted: %t)", check.Name, check.ContractAccepted)
+			}
+			defects[check.ContractDefect]++
+		}
+	}
+	for _, defect := range []string{CONTRACT_DEFECT_EXTENSION_NODE, CONTRACT_DEFECT_TRUNCATED} {
+		if defects[defect] == 0 {
+			t.Errorf("defect not observed, is the model outdated? %s", defect)
+		}
+	}
+}
+
+// testRecipient of the transactions of the tries in the tests
+var testRecipient = common.HexToAddress("0x3535353535353535353535353535353535353535")
+
+func TestCheckProofForContract(t *testing.T) {
+	var txs types.Transactions
+	for i := 0; i < 130; i++ {
+		txs = append(txs, types.NewTransaction(uint64(i), testRecipient, nil, 21000, nil, nil))
+	}
+
+	tests := []struct {
+		index    uint
+		rejected bool
+	}{
+		{0, false},
+		{127, false},
+		// the keys 0x8180 to 0x818f share the extension node of 0x81
+		{128, true},
+		{129, true},
+	}
+	for _, test := range tests {
+		proof, err := proofs.BuildTxProof(txs, test.index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes, err := proof.EncodedNodes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = checkProofForContract(proof.Path, nodes)
+		if rejected := errors.Is(err, ErrProofRejectedByContract); rejected != test.rejected {
+			t.Errorf("tx %d: rejected: %t, expected: %t (%v)", test.index, rejected, test.rejected, err)
+		}
+	}
+}
+
+func TestVerifyProofLikeContractAcceptsTruncatedProofs(t *testing.T) {
+	txs := types.Transactions{
+		types.NewTransaction(0, testRecipient, nil, 21000, nil, nil),
+		types.NewTransaction(1, testRecipient, nil, 21000, nil, nil),
+	}
+	proof, err := proofs.BuildTxProof(txs, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verification := verifyProofLikeContract(proof); !verification.accepted || verification.defect != "" {
+		t.Fatalf("valid proof: %+v", verification)
+	}
+
+	proof.Nodes = proof.Nodes[:len(proof.Nodes)-1]
+	proof.Value, _ = rlp.EncodeToBytes("any value")
+	if verification := verifyProofLikeContract(proof); !verification.accepted || verification.defect != CONTRACT_DEFECT_TRUNCATED {
+		t.Fatalf("truncated proof: %+v", verification)
+	}
+	if proofs.VerifyProof(proof) == nil {
+		t.Fatal("truncated proof accepted locally")
+	}
+}
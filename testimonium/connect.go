@@ -0,0 +1,409 @@
+// This file contains the connections to the configured chains. A chain is dialed on first use, so a command only
+// connects to the chains it touches; long-running processes connect to all chains upfront (see Connect).
+
+package testimonium
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+)
+
+// chainDialer holds the configs of the chains not yet dialed, it is shared by the copies of a client. The mutex is
+// only held to update the dialer, chains are dialed without it, so a slow endpoint does not block the other chains.
+type chainDialer struct {
+	mutex       sync.Mutex
+	chainsMutex sync.RWMutex // guards the chains of the client, which are added while other goroutines use them
+	configs     map[uint8]map[string]interface{}
+	dialing     map[uint8]chan struct{}  // closed once the chain is dialed, including the ones that could not be connected
+	problems    map[uint8][]ChainProblem // found while connecting to the chains
+}
+
+// ChainProblem is a problem found while connecting to a chain, e.g., a contract that could not be bound or a chain id
+// that differs from the configured one. The chain is still used unless it is unusable.
+type ChainProblem struct {
+	Chain    uint8  `json:"chain"`
+	Message  string `json:"message"`
+	Unusable bool   `json:"unusable,omitempty"` // the chain is not connected
+}
+
+func (p ChainProblem) String() string {
+	return p.Message
+}
+
+// ClientReport lists the problems found while connecting to the chains (see Connect), so callers can decide whether
+// to proceed.
+type ClientReport struct {
+	Problems []ChainProblem `json:"problems"`
+}
+
+// UnusableChains returns the chains that are not connected, ordered by chain id.
+func (r ClientReport) UnusableChains() []uint8 {
+	var chains []uint8
+	for _, problem := range r.Problems {
+		if problem.Unusable {
+			chains = append(chains, problem.Chain)
+		}
+	}
+	return chains
+}
+
+// ChainProblems returns the problems of the chain.
+func (r ClientReport) ChainProblems(chain uint8) []ChainProblem {
+	var problems []ChainProblem
+	for _, problem := range r.Problems {
+		if problem.Chain == chain {
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+// Err returns an error wrapping ErrUnknownChain if a chain is not connected.
+func (r ClientReport) Err() error {
+	if unusable := r.UnusableChains(); len(unusable) > 0 {
+		return fmt.Errorf("%w: cannot connect to chains %v", ErrUnknownChain, unusable)
+	}
+	return nil
+}
+
+// chainProblems collects the problems of the chain a copy of the client connects to
+type chainProblems struct {
+	chain    uint8
+	problems []ChainProblem
+}
+
+// Connect dials the chains unless they are already connected, all configured chains if no chain is specified, and
+// returns the problems found while connecting to them. Chains are otherwise dialed on first use and their problems are
+// printed as warnings. Long-running processes should connect upfront, so connection problems are reported at startup
+// and no chains are dialed while requests are served concurrently.
+func (c Client) Connect(chains ...uint8) ClientReport {
+	if len(chains) == 0 {
+		chains = c.ConfiguredChains()
+	}
+	var report ClientReport
+	for _, chain := range chains {
+		c.connectChain(chain, false)
+		report.Problems = append(report.Problems, c.Report().ChainProblems(chain)...)
+	}
+	return report
+}
+
+// Report returns the problems found while connecting to the chains connected so far.
+func (c Client) Report() ClientReport {
+	var report ClientReport
+	if c.dialer == nil {
+		return report
+	}
+	c.dialer.mutex.Lock()
+	defer c.dialer.mutex.Unlock()
+	ids := make([]int, 0, len(c.dialer.problems))
+	for id := range c.dialer.problems {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		report.Problems = append(report.Problems, c.dialer.problems[uint8(id)]...)
+	}
+	return report
+}
+
+// ConfiguredChains returns the ids of all configured chains ordered by id, whether they are connected or not.
+func (c Client) ConfiguredChains() []uint8 {
+	var chains []uint8
+	if c.dialer != nil {
+		for id := range c.dialer.configs {
+			chains = append(chains, id)
+		}
+	}
+	sort.Slice(chains, func(i, j int) bool { return chains[i] < chains[j] })
+	return chains
+}
+
+// connectAll dials all configured chains, their problems are printed as warnings
+func (c Client) connectAll() {
+	for _, chain := range c.ConfiguredChains() {
+		c.connectChain(chain, true)
+	}
+}
+
+// connectChain dials the chain if it was not dialed before and reports whether it is connected. The problems found
+// while dialing are printed as warnings if warn is set. Concurrent callers wait until the first one dialed the chain.
+func (c Client) connectChain(chainId uint8, warn bool) bool {
+	if connected := c.chainConnected(chainId); connected || c.dialer == nil {
+		return connected
+	}
+	c.dialer.mutex.Lock()
+	dialing, dialed := c.dialer.dialing[chainId]
+	if !dialed {
+		dialing = make(chan struct{})
+		c.dialer.dialing[chainId] = dialing
+	}
+	chainConfig, configured := c.dialer.configs[chainId]
+	c.dialer.mutex.Unlock()
+	if dialed {
+		<-dialing
+		return c.chainConnected(chainId)
+	}
+	defer close(dialing)
+
+	c.connecting = &chainProblems{chain: chainId}
+	var chain *Chain
+	if !configured {
+		c.problemf("Chain %d is not configured", chainId)
+	} else if chain = c.dialChain(chainId, chainConfig); chain != nil {
+		c.warnReplayHazards(chain)
+		c.dialer.chainsMutex.Lock()
+		c.chains[chainId] = chain
+		c.dialer.chainsMutex.Unlock()
+		// reconciling the transaction log uses the chain, so it is opened once the chain is connected
+		c.openTxLog(chain)
+	}
+	if chain == nil {
+		// the last problem is the reason the chain cannot be used
+		c.connecting.problems[len(c.connecting.problems)-1].Unusable = true
+	}
+
+	problems := c.connecting.problems
+	c.dialer.mutex.Lock()
+	c.dialer.problems[chainId] = problems
+	c.dialer.mutex.Unlock()
+	if warn {
+		for _, problem := range problems {
+			c.progressf("WARNING: %s\n", problem)
+		}
+	}
+	return chain != nil
+}
+
+// chain returns the connected chain, nil if it is not connected. Chains are added while other goroutines use the
+// client, so the chains of the client are only read with chain and eachChain.
+func (c Client) chain(chainId uint8) *Chain {
+	if c.dialer != nil {
+		c.dialer.chainsMutex.RLock()
+		defer c.dialer.chainsMutex.RUnlock()
+	}
+	return c.chains[chainId]
+}
+
+// eachChain calls fn with the chains connected so far ordered by id
+func (c Client) eachChain(fn func(chain *Chain)) {
+	for _, id := range c.connectedChains() {
+		fn(c.chain(id))
+	}
+}
+
+// chainConnected reports whether the chain is connected
+func (c Client) chainConnected(chainId uint8) bool {
+	return c.chain(chainId) != nil
+}
+
+// problemf records a problem of the chain being connected, it is printed as a warning if no chain is being connected
+func (c Client) problemf(format string, args ...interface{}) {
+	if c.connecting == nil {
+		c.progressf("WARNING: "+format+"\n", args...)
+		return
+	}
+	c.connecting.problems = append(c.connecting.problems, ChainProblem{Chain: c.connecting.chain, Message: fmt.Sprintf(format, args...)})
+}
+
+// connectedChains returns the ids of the chains connected so far ordered by id
+func (c Client) connectedChains() []uint8 {
+	if c.dialer != nil {
+		c.dialer.chainsMutex.RLock()
+		defer c.dialer.chainsMutex.RUnlock()
+	}
+	chains := make([]uint8, 0, len(c.chains))
+	for id := range c.chains {
+		chains = append(chains, id)
+	}
+	sort.Slice(chains, func(i, j int) bool { return chains[i] < chains[j] })
+	return chains
+}
+
+// dialChain connects to the chain and binds its contracts, nil is returned if the chain cannot be used at all
+func (c Client) dialChain(chainId uint8, chainConfig map[string]interface{}) *Chain {
+	// create client connection
+	var ethClient *ethclient.Client
+	fullUrl, err := createConnectionUrl(chainConfig)
+	if err != nil {
+		c.problemf("Could not read url specified for chain %d (%s)", chainId, err)
+		return nil
+	}
+
+	provider, err := providerConfigFromChainConfig(chainConfig)
+	if err != nil {
+		c.problemf("Could not read provider config of chain %d (%s)", chainId, err)
+		return nil
+	}
+
+	connection, err := connectionConfigFromChainConfig(chainConfig)
+	if err != nil {
+		c.problemf("Could not read connection config of chain %d (%s)", chainId, err)
+		return nil
+	}
+
+	role, err := chainRoleFromConfig(chainConfig)
+	if err != nil {
+		c.problemf("Could not read role of chain %d (%s)", chainId, err)
+		return nil
+	}
+	if override, exists := c.chainRoles[chainId]; exists {
+		role = override
+	}
+
+	retryPolicies, err := c.retryPoliciesFromConfig(chainConfig)
+	if err != nil {
+		c.problemf("Could not read retry policies of chain %d, using the defaults (%s)", chainId, err)
+		retryPolicies, _ = c.retryPoliciesFromConfig(nil)
+	}
+
+	rpcClient, err := c.dial(fullUrl, provider, connection, retryPolicies)
+	if err != nil {
+		c.problemf("Cannot connect to chain %d (%s): %s", chainId, fullUrl, err)
+		return nil
+	}
+	ethClient = ethclient.NewClient(rpcClient)
+
+	chain := new(Chain)
+	chain.id = chainId
+	chain.client = ethClient
+	chain.rpcClient = rpcClient
+	chain.fullUrl = fullUrl
+	chain.role = role
+	// shared with the copies of the chain using other ETH Relay instances
+	chain.relayedTasks, chain.substitutedTxs, chain.noAccessLists = new(sync.Map), new(sync.Map), new(int32)
+	chain.retryPolicies = retryPolicies
+	chain.viewCache, err = c.viewCacheFromConfig(chainConfig)
+	if err != nil {
+		c.problemf("%s for chain %d, using the default TTL", err, chainId)
+	}
+	chain.relayer = c.relayers[chainId]
+	chain.privateRelay = c.privateRelays[chainId]
+	chain.transactOptsModifiers = c.transactOptsModifiers
+	chain.operators = c.operatorsByAccount()
+	backend := relayBackend{Client: ethClient, chain: chain, privateKey: c.privateKey, progressf: c.progressf}
+
+	if err := c.verifyChainIds(chain, chainConfig); err != nil {
+		c.problemf("No transactions will be sent to chain %d: %s", chainId, err)
+		chain.idMismatch = err
+	}
+	c.resolveSigningChainId(chain, chainConfig)
+
+	// create testimonium contract instance
+	var testimoniumContract *Testimonium
+	addressHex := chainConfig["ethrelayaddress"]
+	if addressHex != nil {
+		ethrelayAddress := common.HexToAddress(addressHex.(string))
+		testimoniumContract, err = c.bindTestimoniumContract(chain, chainConfig, ethrelayAddress, backend)
+		if err != nil {
+			c.problemf("No Testimonium contract deployed at address %s on chain %d (%s): %s", addressHex, chainId, fullUrl, err)
+		} else {
+			chain.testimoniumContract = testimoniumContract
+			chain.testimoniumContractAddress = ethrelayAddress
+		}
+	}
+	c.bindRelayInstances(chain, chainConfig, backend)
+
+	// create ethash contract instance
+	var ethashContract *ethash.Ethash
+	addressHex = chainConfig["ethashaddress"]
+	if addressHex != nil {
+		ethashAddress := common.HexToAddress(addressHex.(string))
+		ethashContract, err = ethash.NewEthash(ethashAddress, backend)
+		if err != nil {
+			c.problemf("No Ethash contract deployed at address %s on chain %d (%s)", addressHex, chainId, fullUrl)
+		} else {
+			chain.ethashContract = ethashContract
+			chain.ethashContractAddress = ethashAddress
+		}
+	}
+
+	chain.crossCheckSources, err = c.dialCrossCheckSources(chainConfig, retryPolicies)
+	if err != nil {
+		c.problemf("No headers of chain %d will be relayed: %s", chainId, err)
+		chain.crossCheckErr = err
+	}
+
+	chain.blockSource, err = blockSourceFromConfig(chainConfig, rpcClient)
+	if err != nil {
+		c.problemf("Using the JSON-RPC API for the blocks of chain %d: %s", chainId, err)
+		chain.blockSource = NewRpcBlockSource(rpcClient)
+	}
+	if source, exists := c.blockSources[chainId]; exists {
+		chain.blockSource = source
+	}
+	c.probeChainCapabilities(chain)
+
+	// blocks archived by relaying them are read from the archive first (see WithBodyArchive)
+	if c.bodyArchiveDir != "" {
+		chain.bodyArchive = OpenBodyArchive(c.bodyArchiveDir, chainId)
+		chain.blockSource = archivedBlockSource{archive: chain.bodyArchive, next: chain.blockSource}
+		chain.bodyRetention, err = bodyRetentionFromConfig(chainConfig)
+		if err != nil {
+			c.problemf("%s for chain %d, relayed blocks are not archived", err, chainId)
+		}
+		if retention, exists := c.bodyRetentions[chainId]; exists {
+			chain.bodyRetention = &retention
+		}
+	}
+
+	chain.lightProofs, err = lightProofsFromConfig(chainConfig)
+	if err != nil {
+		c.problemf("%s for chain %d, proofs are built from full blocks", err, chainId)
+	}
+	chain.lightProofs = chain.lightProofs || c.lightProofChains[chainId]
+
+	chain.gasLimits, err = gasLimitsFromConfig(chainConfig)
+	if err != nil {
+		c.problemf("%s for chain %d, gas limits are estimated", err, chainId)
+	}
+	if limits, exists := c.gasLimits[chainId]; exists {
+		chain.gasLimits = limits
+	}
+
+	chain.archiveClient, chain.archiveRpcClient, err = c.dialArchive(chainConfig, retryPolicies)
+	if err != nil {
+		c.problemf("Data of old blocks of chain %d may not be available: %s", chainId, err)
+	}
+
+	tdCheckpoints, err := tdCheckpointsFromConfig(chainConfig)
+	if err != nil {
+		c.problemf("Could not read total difficulty checkpoints of chain %d (%s)", chainId, err)
+	}
+	chain.tdCache = newTdCache(tdCheckpoints)
+
+	chain.codec, err = headerCodecFromConfig(chainConfig)
+	if err != nil {
+		c.problemf("%s for chain %d, using the Ethash header encoding", err, chainId)
+		chain.codec = EthashHeaderCodec
+	}
+
+	// read calls are aggregated with the Multicall3 contract if it is deployed at this address
+	chain.multicallAddress = common.HexToAddress(MULTICALL3_ADDRESS)
+	if addressHex := chainConfig["multicalladdress"]; addressHex != nil {
+		chain.multicallAddress = common.HexToAddress(addressHex.(string))
+	}
+
+	// verification fees are paid in this ERC-20 token instead of ether (see FeeToken)
+	if addressHex := chainConfig["feetoken"]; addressHex != nil {
+		chain.feeToken = common.HexToAddress(addressHex.(string))
+	}
+
+	// deterministic deployments are sent to the CREATE2 deployer at this address
+	chain.create2Deployer = common.HexToAddress(CREATE2_DEPLOYER_ADDRESS)
+	if addressHex := chainConfig["create2deployer"]; addressHex != nil {
+		chain.create2Deployer = common.HexToAddress(addressHex.(string))
+	}
+
+	chain.deploymentRegistry, err = deploymentRegistryFromConfig(chainConfig)
+	if err != nil {
+		c.problemf("Deployments on chain %d are not announced: %s", chainId, err)
+	}
+
+	return chain
+}
@@ -0,0 +1,110 @@
+package testimonium
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newFakeNode starts a JSON-RPC node of chain 1 answering the requests sent while a chain is connected, every account
+// has the code and calls return 32 zero bytes. Requests are answered once release is closed, nil answers them right
+// away.
+func newFakeNode(t *testing.T, code string, release <-chan struct{}, requested chan<- struct{}) string {
+	var once sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requested != nil {
+			once.Do(func() { close(requested) })
+		}
+		if release != nil {
+			<-release
+		}
+		var request struct {
+			Id     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		response := map[string]interface{}{"jsonrpc": "2.0", "id": request.Id}
+		switch request.Method {
+		case "eth_chainId":
+			response["result"] = "0x1"
+		case "net_version":
+			response["result"] = "1"
+		case "eth_getCode":
+			response["result"] = code
+		case "eth_call":
+			response["result"] = "0x" + strings.Repeat("00", 32)
+		default:
+			response["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// TestConnectChainConcurrently connects a chain while another chain's node does not answer, the chains of the client
+// are read meanwhile (run with -race)
+func TestConnectChainConcurrently(t *testing.T) {
+	release, requested := make(chan struct{}), make(chan struct{})
+	chainsConfig := map[string]interface{}{
+		"0": map[string]interface{}{"url": newFakeNode(t, "0x", release, requested)},
+		"1": map[string]interface{}{"url": newFakeNode(t, "0x", nil, nil)},
+	}
+	client, err := NewClient("", chainsConfig, WithoutCapabilityProbing(), WithProgressOutput(ioutil.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slow := make(chan bool)
+	go func() { slow <- client.connectChain(0, false) }()
+	<-requested
+	stop := make(chan struct{})
+	reading := make(chan struct{})
+	go func() {
+		defer close(reading)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				client.chain(0)
+				client.eachChain(func(chain *Chain) {})
+			}
+		}
+	}()
+
+	connected := make(chan bool)
+	go func() { connected <- client.connectChain(1, false) }()
+	select {
+	case ok := <-connected:
+		if !ok {
+			t.Errorf("chain 1 not connected: %v", client.Report().ChainProblems(1))
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("chain 1 waits for the node of chain 0")
+	}
+	if client.chain(0) != nil {
+		t.Error("chain 0 connected before its node answered")
+	}
+
+	// concurrent callers wait until the chain is dialed
+	waiting := make(chan bool)
+	go func() { waiting <- client.connectChain(0, false) }()
+	close(release)
+	if !<-slow || !<-waiting {
+		t.Errorf("chain 0 not connected: %v", client.Report().ChainProblems(0))
+	}
+	close(stop)
+	<-reading
+	if chains := client.connectedChains(); len(chains) != 2 {
+		t.Errorf("connected chains %v", chains)
+	}
+}
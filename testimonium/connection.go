@@ -0,0 +1,153 @@
+// This file contains the HTTP options of the connections to the nodes of a chain, i.e., custom headers, basic auth and
+// TLS client certificates and CA bundles. They are required if the nodes sit behind gateways that authenticate clients.
+
+package testimonium
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// ConnectionConfig is read from the "headers", "basicauth" and "tls" entries of a chain config, e.g.,
+//
+//	headers:
+//	    authorization: Bearer <token>
+//	basicauth:
+//	    username: <user>
+//	    password: <password>
+//	tls:
+//	    cacert: /etc/ethrelay/gateway-ca.pem
+//	    cert: /etc/ethrelay/client.pem
+//	    key: /etc/ethrelay/client-key.pem
+//	    servername: node.internal
+//	    insecureskipverify: false
+type ConnectionConfig struct {
+	Headers            map[string]string // added to every request
+	Username           string            // basic auth is used if set
+	Password           string
+	CACert             string // PEM file with the certificates trusted in addition to the system ones
+	Cert               string // PEM file with the client certificate
+	Key                string // PEM file with the key of the client certificate
+	ServerName         string // name the server certificate is verified against instead of the host of the URL
+	InsecureSkipVerify bool   // the server certificate is not verified (only for testing)
+}
+
+// connectionConfigFromChainConfig reads the connection entries of a chain config, nil is returned if there are none
+func connectionConfigFromChainConfig(chainConfig map[string]interface{}) (*ConnectionConfig, error) {
+	if chainConfig["headers"] == nil && chainConfig["basicauth"] == nil && chainConfig["tls"] == nil {
+		return nil, nil
+	}
+	config := &ConnectionConfig{Headers: make(map[string]string)}
+
+	if entry := chainConfig["headers"]; entry != nil {
+		headers, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("headers is not a map of header names to values")
+		}
+		for name, value := range headers {
+			config.Headers[http.CanonicalHeaderKey(name)] = fmt.Sprint(value)
+		}
+	}
+
+	if entry := chainConfig["basicauth"]; entry != nil {
+		basicAuth, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("basicauth has to contain username and password")
+		}
+		config.Username, _ = basicAuth["username"].(string)
+		config.Password = fmt.Sprint(basicAuth["password"])
+		if config.Username == "" || basicAuth["password"] == nil {
+			return nil, fmt.Errorf("basicauth has to contain username and password")
+		}
+	}
+
+	if entry := chainConfig["tls"]; entry != nil {
+		tlsConfig, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("tls is not a map of options")
+		}
+		config.CACert, _ = tlsConfig["cacert"].(string)
+		config.Cert, _ = tlsConfig["cert"].(string)
+		config.Key, _ = tlsConfig["key"].(string)
+		config.ServerName, _ = tlsConfig["servername"].(string)
+		if (config.Cert == "") != (config.Key == "") {
+			return nil, fmt.Errorf("tls cert and key have to be specified together")
+		}
+		if skip, exists := tlsConfig["insecureskipverify"]; exists {
+			config.InsecureSkipVerify, ok = skip.(bool)
+			if !ok {
+				return nil, fmt.Errorf("illegal insecureskipverify: %v", skip)
+			}
+		}
+	}
+	return config, nil
+}
+
+// newTransport creates a transport connecting to the node with the TLS options and adding the headers to every request
+func (config ConnectionConfig) newTransport() (http.RoundTripper, error) {
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	header := make(http.Header)
+	for name, value := range config.Headers {
+		header.Set(name, value)
+	}
+	return &headerTransport{header: header, username: config.Username, password: config.Password, transport: transport}, nil
+}
+
+func (config ConnectionConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         config.ServerName,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	if config.CACert != "" {
+		pem, err := ioutil.ReadFile(config.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA bundle: %s", err)
+		}
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil || rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", config.CACert)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if config.Cert != "" {
+		certificate, err := tls.LoadX509KeyPair(config.Cert, config.Key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+// headerTransport adds the configured headers and basic auth credentials to the requests
+type headerTransport struct {
+	header    http.Header
+	username  string
+	password  string
+	transport http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, values := range t.header {
+		req.Header[name] = values
+	}
+	if t.username != "" {
+		req.SetBasicAuth(t.username, t.password)
+	}
+	return t.transport.RoundTrip(req)
+}
@@ -0,0 +1,166 @@
+// This file contains a model of the Merkle Patricia proof verification of the bundled ETH Relay contract. The
+// contract's verification (derived from PeaceRelay's MerklePatriciaProof) differs from the verification of Ethereum
+// clients in two ways:
+//
+//   - after consuming the path of an extension node, it compares the path of the node with the path again, starting
+//     behind it. Valid proofs through extension nodes are rejected unless the path repeats, e.g., the proofs of the
+//     transactions 128 to 143 of blocks with more than 128 transactions.
+//   - if the proof nodes end before the value is reached, the proof is accepted whatever the value.
+//
+// The client uses the model to refuse verifications the contract would reject before their fee is paid, and the
+// conformance suite uses it to tell the defects of the contract from differences of the proof generation.
+
+package testimonium
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/pantos-io/go-ethrelay/proofs"
+)
+
+// ErrProofRejectedByContract is returned if a valid proof is not sent because the bundled contract would reject it.
+var ErrProofRejectedByContract = errors.New("proof rejected by the contract")
+
+// defects of the bundled contract's proof verification
+const (
+	CONTRACT_DEFECT_EXTENSION_NODE = "the contract rejects valid proofs through extension nodes, it compares the path of the node twice"
+	CONTRACT_DEFECT_TRUNCATED      = "the contract accepts proofs ending before the value is reached, whatever the value"
+)
+
+// contractProofVerification is the outcome of the bundled contract's verification of a proof
+type contractProofVerification struct {
+	accepted bool
+	defect   string // the defect of the contract deciding the outcome, empty if it verified like Ethereum clients
+}
+
+// verifyProofLikeContract verifies the proof like the bundled contract does, reverting verifications are rejected
+func verifyProofLikeContract(proof proofs.Proof) contractProofVerification {
+	path := bytesToNibbles(proof.Path)
+	if len(path) == 0 {
+		return contractProofVerification{}
+	}
+	nodeKey := proof.Root
+	pathPtr := 0
+	for _, node := range proof.Nodes {
+		if pathPtr > len(path) || crypto.Keccak256Hash(node) != nodeKey {
+			return contractProofVerification{}
+		}
+		items, err := contractNodeItems(node)
+		if err != nil {
+			return contractProofVerification{}
+		}
+
+		switch len(items) {
+		case 17:
+			if pathPtr == len(path) {
+				return contractProofVerification{accepted: bytes.Equal(items[16], proof.Value)}
+			}
+			if path[pathPtr] > 16 {
+				return contractProofVerification{}
+			}
+			nodeKey = contractNodeKey(items[path[pathPtr]])
+			pathPtr++
+		case 2:
+			partialPath := hexPrefixToNibbles(items[0])
+			traversed, ok := contractNibblesToTraverse(partialPath, path, pathPtr)
+			if !ok {
+				return contractProofVerification{}
+			}
+			pathPtr += traversed
+			if pathPtr == len(path) {
+				return contractProofVerification{accepted: bytes.Equal(items[1], proof.Value)}
+			}
+			// the path of the node is compared again, behind the part of the path it consumed
+			if again, ok := contractNibblesToTraverse(partialPath, path, pathPtr); !ok || again == 0 {
+				verification := contractProofVerification{}
+				if traversed > 0 {
+					verification.defect = CONTRACT_DEFECT_EXTENSION_NODE
+				}
+				return verification
+			}
+			nodeKey = contractNodeKey(items[1])
+		default:
+			return contractProofVerification{}
+		}
+	}
+	return contractProofVerification{accepted: true, defect: CONTRACT_DEFECT_TRUNCATED}
+}
+
+// checkProofForContract returns ErrProofRejectedByContract if the bundled contract would reject the proof because of
+// one of its defects. The root is taken from the first node, the contract checks it against the stored header.
+func checkProofForContract(path []byte, rlpEncodedProofNodes []byte) error {
+	var nodes [][]byte
+	if err := rlp.DecodeBytes(rlpEncodedProofNodes, &nodes); err != nil || len(nodes) == 0 {
+		// the contract rejects the proof anyway
+		return nil
+	}
+	proof := proofs.Proof{Root: crypto.Keccak256Hash(nodes[0]), Path: path, Nodes: nodes}
+	if verification := verifyProofLikeContract(proof); verification.defect == CONTRACT_DEFECT_EXTENSION_NODE {
+		return fmt.Errorf("%w: %s", ErrProofRejectedByContract, verification.defect)
+	}
+	return nil
+}
+
+// contractNodeItems returns the payloads of the items of a trie node, embedded nodes are returned encoded
+func contractNodeItems(node []byte) ([][]byte, error) {
+	content, _, err := rlp.SplitList(node)
+	if err != nil {
+		return nil, err
+	}
+	var items [][]byte
+	for len(content) > 0 {
+		kind, payload, rest, err := rlp.Split(content)
+		if err != nil {
+			return nil, err
+		}
+		if kind == rlp.List {
+			payload = content[:len(content)-len(rest)]
+		}
+		items = append(items, payload)
+		content = rest
+	}
+	return items, nil
+}
+
+// contractNodeKey returns the key of the child node like the contract reads it (the payload as 32 bytes word), only
+// hashes of children lead to the next proof node
+func contractNodeKey(item []byte) common.Hash {
+	return common.BytesToHash(item)
+}
+
+// contractNibblesToTraverse returns the length of the partial path if the path continues with it at the pointer and
+// zero otherwise, not ok if the contract reverts reading behind the path
+func contractNibblesToTraverse(partialPath []byte, path []byte, pathPtr int) (int, bool) {
+	if pathPtr+len(partialPath) > len(path) {
+		return 0, len(partialPath) == 0
+	}
+	if bytes.Equal(partialPath, path[pathPtr:pathPtr+len(partialPath)]) {
+		return len(partialPath), true
+	}
+	return 0, true
+}
+
+func bytesToNibbles(data []byte) []byte {
+	nibbles := make([]byte, 0, 2*len(data))
+	for _, b := range data {
+		nibbles = append(nibbles, b>>4, b&0x0f)
+	}
+	return nibbles
+}
+
+// hexPrefixToNibbles decodes the hex prefix encoded path of a leaf or extension node
+func hexPrefixToNibbles(encoded []byte) []byte {
+	nibbles := bytesToNibbles(encoded)
+	if len(nibbles) == 0 {
+		return nibbles
+	}
+	if nibbles[0]&1 == 1 {
+		return nibbles[1:]
+	}
+	return nibbles[2:]
+}
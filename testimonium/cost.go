@@ -0,0 +1,86 @@
+// This file contains the estimation of the cost of header submissions.
+
+package testimonium
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// SubmitCost is the estimated cost of submitting a header to the Testimonium contract.
+type SubmitCost struct {
+	Gas       uint64   `json:"gas"`
+	GasPrice  *big.Int `json:"gasPrice"`
+	Fee       *big.Int `json:"fee"`       // gas * gas price in wei
+	StakeLock *big.Int `json:"stakeLock"` // stake in wei that is locked for the submitted header until the lock period ends
+}
+
+// EstimateSubmitCost estimates the gas, the transaction fee at the currently suggested gas price and the stake that
+// is locked for submitting the header to the specified chain, without sending a transaction. The estimation fails if
+// the submission would fail, e.g., because the header is already stored or its parent is not.
+// The gas depends on the number of expired submissions the contract cleans up, so the actual gas may differ slightly.
+func (c Client) EstimateSubmitCost(header *types.Header, chain uint8) (SubmitCost, error) {
+	c = c.as(OPERATOR_SUBMITTER)
+	if err := c.checkTestimonium(chain); err != nil {
+		return SubmitCost{}, err
+	}
+
+	isHeaderStored, err := c.chain(chain).testimoniumContract.IsHeaderStored(c.callOpts(), header.Hash())
+	if err != nil {
+		return SubmitCost{}, err
+	}
+	if isHeaderStored {
+		return SubmitCost{}, ErrHeaderAlreadyStored
+	}
+	isParentStored, err := c.chain(chain).testimoniumContract.IsHeaderStored(c.callOpts(), header.ParentHash)
+	if err != nil {
+		return SubmitCost{}, err
+	}
+	if !isParentStored {
+		return SubmitCost{}, fmt.Errorf("%w: %s", ErrParentNotStored, header.ParentHash.String())
+	}
+
+	rlpHeader, err := encodeHeaderToRLP(header)
+	if err != nil {
+		return SubmitCost{}, fmt.Errorf("failed to encode header to RLP: %s", err)
+	}
+
+	testimoniumAbi, err := c.chain(chain).testimoniumAbi()
+	if err != nil {
+		return SubmitCost{}, err
+	}
+	data, err := testimoniumAbi.Pack("submitBlock", rlpHeader)
+	if err != nil {
+		return SubmitCost{}, err
+	}
+
+	contractAddress := c.chain(chain).testimoniumContractAddress
+	gas, err := c.chain(chain).client.EstimateGas(c.context(), ethereum.CallMsg{
+		From: c.account,
+		To:   &contractAddress,
+		Data: data,
+	})
+	if err != nil {
+		return SubmitCost{}, fmt.Errorf("failed to estimate gas: %s", err)
+	}
+
+	gasPrice, err := c.chain(chain).client.SuggestGasPrice(c.context())
+	if err != nil {
+		return SubmitCost{}, err
+	}
+
+	stakeLock, err := c.chain(chain).requiredStakePerBlock()
+	if err != nil {
+		return SubmitCost{}, err
+	}
+
+	return SubmitCost{
+		Gas:       gas,
+		GasPrice:  gasPrice,
+		Fee:       new(big.Int).Mul(new(big.Int).SetUint64(gas), gasPrice),
+		StakeLock: stakeLock,
+	}, nil
+}
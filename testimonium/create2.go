@@ -0,0 +1,198 @@
+// This file contains the deployment of the Ethash and Testimonium contracts with CREATE2, so the contracts land at
+// the same, predictable addresses on every chain they are deployed to with the same salt.
+
+package testimonium
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+)
+
+// CREATE2_DEPLOYER_ADDRESS is the address of the deterministic deployment proxy, which is deployed at this address on
+// most chains. It deploys the init code following the 32 byte salt in the call data with CREATE2.
+const CREATE2_DEPLOYER_ADDRESS = "0x4e59b44847b379578588920cA78FbF26c0B4956C"
+
+// ParseSalt returns the salt for a CREATE2 deployment. A 32 byte hex string is used as is, any other string is hashed,
+// so salts like "production" can be used.
+func ParseSalt(salt string) common.Hash {
+	if strings.HasPrefix(salt, "0x") && len(salt) == 2+2*common.HashLength {
+		if bytes := common.FromHex(salt); len(bytes) == common.HashLength {
+			return common.BytesToHash(bytes)
+		}
+	}
+	return crypto.Keccak256Hash([]byte(salt))
+}
+
+// Create2Address returns the address the init code is deployed at by the CREATE2 deployer of the chain.
+func (c Client) Create2Address(chain uint8, salt common.Hash, initCode []byte) (common.Address, error) {
+	if err := c.checkChain(chain); err != nil {
+		return common.Address{}, err
+	}
+	return crypto.CreateAddress2(c.chain(chain).create2Deployer, salt, crypto.Keccak256(initCode)), nil
+}
+
+// DeployEthashCreate2 deploys the Ethash contract with the CREATE2 deployer. The contract is not deployed again if it
+// already exists at the resulting address.
+func (c Client) DeployEthashCreate2(destinationChain uint8, salt common.Hash) (common.Address, error) {
+	if err := c.checkChain(destinationChain); err != nil {
+		return common.Address{}, err
+	}
+
+	initCode, err := contractInitCode(ethash.EthashABI, ethash.EthashBin)
+	if err != nil {
+		return common.Address{}, err
+	}
+	addr, receipt, err := c.deployCreate2(destinationChain, salt, initCode)
+	if err != nil {
+		return common.Address{}, err
+	}
+	c.recordDeployment(destinationChain, REGISTRY_ETHASH, addr, receipt, &salt, nil)
+	return addr, nil
+}
+
+// DeployTestimoniumCreate2 deploys the Testimonium contract with the CREATE2 deployer. As the genesis block and the
+// Ethash contract address are part of the init code, the address only stays the same for the same genesis block and
+// an Ethash contract deployed with CREATE2 as well. The contract is not deployed again if it already exists at the
+// resulting address.
+func (c Client) DeployTestimoniumCreate2(destinationChain uint8, sourceChain uint8, genesisBlockNumber uint64, salt common.Hash) (common.Address, error) {
+	if err := c.checkEthash(destinationChain); err != nil {
+		return common.Address{}, err
+	}
+	if err := c.checkChain(sourceChain); err != nil {
+		return common.Address{}, err
+	}
+
+	header, err := c.HeaderByNumber(new(big.Int).SetUint64(genesisBlockNumber), sourceChain)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to retrieve header from source chain: %s", err)
+	}
+
+	totalDifficulty, err := c.TotalDifficulty(new(big.Int).SetUint64(genesisBlockNumber), sourceChain)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to retrieve total difficulty of block %d: %s", genesisBlockNumber, err)
+	}
+
+	return c.deployTestimoniumCreate2(destinationChain, &Genesis{
+		SourceChain:     sourceChain,
+		BlockNumber:     genesisBlockNumber,
+		BlockHash:       header.Hash(),
+		TotalDifficulty: totalDifficulty,
+	}, header, salt)
+}
+
+// deployTestimoniumCreate2 deploys the Testimonium contract starting at the genesis block with the CREATE2 deployer
+func (c Client) deployTestimoniumCreate2(destinationChain uint8, genesis *Genesis, header *types.Header, salt common.Hash) (common.Address, error) {
+	rlpHeader, err := encodeHeaderToRLP(header)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to encode header to RLP: %s", err)
+	}
+
+	initCode, err := contractInitCode(TestimoniumABI, TestimoniumBin, rlpHeader, genesis.TotalDifficulty, c.chain(destinationChain).ethashContractAddress)
+	if err != nil {
+		return common.Address{}, err
+	}
+	addr, receipt, err := c.deployCreate2(destinationChain, salt, initCode)
+	if err != nil {
+		return common.Address{}, err
+	}
+	c.recordDeployment(destinationChain, REGISTRY_ETHRELAY, addr, receipt, &salt, genesis)
+	return addr, nil
+}
+
+// contractInitCode returns the creation code of the contract followed by the ABI encoded constructor arguments
+func contractInitCode(contractAbi string, bin string, args ...interface{}) ([]byte, error) {
+	parsed, err := abi.JSON(strings.NewReader(contractAbi))
+	if err != nil {
+		return nil, err
+	}
+	encodedArgs, err := parsed.Pack("", args...)
+	if err != nil {
+		return nil, err
+	}
+	return append(common.FromHex(bin), encodedArgs...), nil
+}
+
+// deployCreate2 returns the address of the contract and the receipt of its deployment, which is nil if the contract
+// already existed
+func (c Client) deployCreate2(chain uint8, salt common.Hash, initCode []byte) (common.Address, *types.Receipt, error) {
+	client := c.chain(chain).client
+	deployer := c.chain(chain).create2Deployer
+
+	addr, err := c.Create2Address(chain, salt, initCode)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+
+	code, err := client.CodeAt(c.context(), addr, nil)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	if len(code) > 0 {
+		c.progressf("Contract already deployed at %s\n", addr.Hex())
+		return addr, nil, nil
+	}
+
+	deployerCode, err := client.CodeAt(c.context(), deployer, nil)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	if len(deployerCode) == 0 {
+		return common.Address{}, nil, fmt.Errorf("no CREATE2 deployer deployed at address %s on chain %d (set 'create2deployer' in the chain config)", deployer.Hex(), chain)
+	}
+
+	auth, err := prepareTransaction(c.context(), c.account, c.privateKey, c.chain(chain), big.NewInt(0))
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+
+	data := append(salt.Bytes(), initCode...)
+	gasLimit := auth.GasLimit // may be set by a transact opts modifier
+	if gasLimit == 0 {
+		gasLimit, err = client.EstimateGas(c.context(), ethereum.CallMsg{
+			From:     c.account,
+			To:       &deployer,
+			GasPrice: auth.GasPrice,
+			Data:     data,
+		})
+		if err != nil {
+			return common.Address{}, nil, fmt.Errorf("failed to estimate gas of the deployment: %s", err)
+		}
+	}
+
+	rawTx := types.NewTransaction(auth.Nonce.Uint64(), deployer, auth.Value, gasLimit, auth.GasPrice, data)
+	tx, err := auth.Signer(types.HomesteadSigner{}, auth.From, rawTx)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	if err := c.chain(chain).logBroadcastTx(c.context(), client, tx); err != nil {
+		return common.Address{}, nil, err
+	}
+	c.progressf("Tx submitted: %s\n", tx.Hash().Hex())
+
+	receipt, err := awaitTxReceipt(c.context(), c.chain(chain), tx.Hash())
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	if receipt.Status == 0 {
+		// Transaction failed
+		reason := getFailureReason(client, c.account, tx, receipt.BlockNumber)
+		return common.Address{}, nil, fmt.Errorf("tx failed: %s", reason)
+	}
+
+	code, err = client.CodeAt(c.context(), addr, nil)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	if len(code) == 0 {
+		return common.Address{}, nil, fmt.Errorf("deployer did not create a contract at the expected address %s", addr.Hex())
+	}
+	return addr, receipt, nil
+}
@@ -0,0 +1,108 @@
+// This file contains the cross-checking of source chain headers with independent RPC providers. A compromised or buggy
+// provider could otherwise make the relayer submit a header that is not part of the source chain and lose its stake.
+
+package testimonium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrHeaderDisagreement is returned if the cross-check providers of a source chain do not agree on a header.
+var ErrHeaderDisagreement = errors.New("providers disagree on header")
+
+// CROSS_CHECK_ATTEMPTS is the number of times a header is compared with the cross-check providers before it is
+// rejected. Providers that lag behind usually catch up within a few blocks.
+const CROSS_CHECK_ATTEMPTS = 5
+
+// CROSS_CHECK_DELAY is the time waited between two comparisons of a header.
+const CROSS_CHECK_DELAY = 10 * time.Second
+
+// crossCheckSource is an additional provider of a source chain
+type crossCheckSource struct {
+	url    string
+	client *ethclient.Client
+	rpc    *rpc.Client
+}
+
+// dialCrossCheckSources connects to the providers of the "crosscheck" entry of a chain config, a list of full URLs:
+//
+//	crosscheck:
+//	    - https://mainnet.infura.io/v3/<key>
+//	    - https://eth-mainnet.g.alchemy.com/v2/<key>
+func (c Client) dialCrossCheckSources(chainConfig map[string]interface{}, retry RetryPolicies) ([]crossCheckSource, error) {
+	urls, ok := chainConfig["crosscheck"].([]interface{})
+	if chainConfig["crosscheck"] != nil && !ok {
+		return nil, fmt.Errorf("crosscheck is not a list of URLs")
+	}
+
+	var sources []crossCheckSource
+	for _, entry := range urls {
+		url, ok := entry.(string)
+		if !ok {
+			return nil, fmt.Errorf("illegal crosscheck URL %v", entry)
+		}
+		rpcClient, err := c.dial(url, nil, nil, retry)
+		if err != nil {
+			return nil, fmt.Errorf("cannot connect to crosscheck provider %s: %s", url, err)
+		}
+		sources = append(sources, crossCheckSource{url: url, client: ethclient.NewClient(rpcClient), rpc: rpcClient})
+	}
+	return sources, nil
+}
+
+// CrossCheckHeader compares the header with the headers at the same height returned by the cross-check providers of
+// the source chain. Disagreements are reported and the comparison is repeated CROSS_CHECK_ATTEMPTS times, after that
+// an error wrapping ErrHeaderDisagreement is returned. Without cross-check providers, nil is returned.
+func (c Client) CrossCheckHeader(header *types.Header, sourceChain uint8) error {
+	if err := c.checkChain(sourceChain); err != nil {
+		return err
+	}
+	if err := c.chain(sourceChain).crossCheckErr; err != nil {
+		return fmt.Errorf("%w: %s", ErrHeaderDisagreement, err)
+	}
+	sources := c.chain(sourceChain).crossCheckSources
+	if len(sources) == 0 {
+		return nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		disagreements := headerDisagreements(header, sources)
+		if len(disagreements) == 0 {
+			return nil
+		}
+		for _, disagreement := range disagreements {
+			c.progressf("WARNING: Block %s: %s\n", header.Number, disagreement)
+		}
+		if attempt == CROSS_CHECK_ATTEMPTS {
+			return fmt.Errorf("%w: block %s (%s): %s", ErrHeaderDisagreement, header.Number, header.Hash().Hex(), strings.Join(disagreements, "; "))
+		}
+		c.progressf("Delaying block %s for %s until all providers agree\n", header.Number, CROSS_CHECK_DELAY)
+		time.Sleep(CROSS_CHECK_DELAY)
+	}
+}
+
+// headerDisagreements returns a description of every provider that does not return the header at its height
+func headerDisagreements(header *types.Header, sources []crossCheckSource) []string {
+	var disagreements []string
+	for _, source := range sources {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		other, err := source.client.HeaderByNumber(ctx, header.Number)
+		cancel()
+		if err != nil {
+			disagreements = append(disagreements, fmt.Sprintf("%s cannot provide the block: %s", source.url, err))
+			continue
+		}
+		if other.Hash() != header.Hash() {
+			disagreements = append(disagreements, fmt.Sprintf("%s has hash %s instead of %s", source.url, other.Hash().Hex(), header.Hash().Hex()))
+		}
+	}
+	return disagreements
+}
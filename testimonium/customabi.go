@@ -0,0 +1,194 @@
+// This file contains the support for variants of the ETH Relay contract (e.g., research forks) whose ABI differs from
+// the bundled one. The contract is bound at runtime to the ABI of the variant, so the typed bindings keep working as
+// long as the functions the client uses exist with compatible arguments, and additional functions can be called
+// through the generic bound contract.
+
+package testimonium
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// WithTestimoniumABI binds the ETH Relay contract of the chain to the ABI (JSON) instead of the bundled one. It
+// overrides the "ethrelayabi" entry of the chain config.
+func WithTestimoniumABI(chain uint8, abiJSON string) ClientOption {
+	return func(client *Client) error {
+		if _, err := ParseContractABI([]byte(abiJSON)); err != nil {
+			return fmt.Errorf("illegal ETH Relay ABI of chain %d: %s", chain, err)
+		}
+		if client.testimoniumABIs == nil {
+			client.testimoniumABIs = make(map[uint8]string)
+		}
+		client.testimoniumABIs[chain] = abiJSON
+		return nil
+	}
+}
+
+// ParseContractABI parses an ABI, either a plain JSON ABI or a build artifact (e.g., of Truffle or Hardhat) containing
+// the ABI in its "abi" entry.
+func ParseContractABI(data []byte) (abi.ABI, error) {
+	var artifact struct {
+		Abi json.RawMessage `json:"abi"`
+	}
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "{") {
+		if err := json.Unmarshal(data, &artifact); err != nil {
+			return abi.ABI{}, err
+		}
+		if len(artifact.Abi) == 0 {
+			return abi.ABI{}, fmt.Errorf("artifact contains no abi entry")
+		}
+		trimmed = string(artifact.Abi)
+	}
+	return abi.JSON(strings.NewReader(trimmed))
+}
+
+// testimoniumABIFromConfig returns the ABI JSON of the file named by the "ethrelayabi" entry of the chain config, an
+// empty string if there is no entry
+func testimoniumABIFromConfig(chainConfig map[string]interface{}) (string, error) {
+	if chainConfig["ethrelayabi"] == nil {
+		return "", nil
+	}
+	path, ok := chainConfig["ethrelayabi"].(string)
+	if !ok || path == "" {
+		return "", fmt.Errorf("illegal ABI file %v", chainConfig["ethrelayabi"])
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := ParseContractABI(data); err != nil {
+		return "", fmt.Errorf("illegal ABI file %s: %s", path, err)
+	}
+	return string(data), nil
+}
+
+// bindTestimoniumContract binds the ETH Relay contract of the chain to the bundled ABI or to the ABI of the variant
+// configured for the chain. Functions and events of the bundled ABI the variant lacks or changed are reported, as calling
+// them fails.
+func (c *Client) bindTestimoniumContract(chain *Chain, chainConfig map[string]interface{}, address common.Address, backend bind.ContractBackend) (*Testimonium, error) {
+	abiJSON, err := testimoniumABIFromConfig(chainConfig)
+	if err != nil {
+		return nil, err
+	}
+	if override, exists := c.testimoniumABIs[chain.id]; exists {
+		abiJSON = override
+	}
+	if abiJSON == "" {
+		return NewTestimonium(address, backend)
+	}
+
+	variant, err := ParseContractABI([]byte(abiJSON))
+	if err != nil {
+		return nil, err
+	}
+	differences, err := TestimoniumABIDifferences(variant)
+	if err != nil {
+		return nil, err
+	}
+	if len(differences) > 0 {
+		c.problemf("The ETH Relay ABI of chain %d differs from the bundled one: %s", chain.id, strings.Join(differences, ", "))
+	}
+	chain.testimoniumABI = abiJSON
+	return bindTestimoniumVariant(address, variant, backend), nil
+}
+
+// bindTestimoniumVariant binds the contract with the ABI of a variant, the typed bindings pack their arguments and
+// unpack their results with it
+func bindTestimoniumVariant(address common.Address, contractAbi abi.ABI, backend bind.ContractBackend) *Testimonium {
+	contract := bind.NewBoundContract(address, contractAbi, backend, backend, backend)
+	return &Testimonium{TestimoniumCaller: TestimoniumCaller{contract: contract}, TestimoniumTransactor: TestimoniumTransactor{contract: contract}, TestimoniumFilterer: TestimoniumFilterer{contract: contract}}
+}
+
+// TestimoniumABIDifferences compares the ABI of a contract variant with the bundled ABI and describes the functions
+// and events the client uses that are missing or have other parameter types. Additional functions and events of the
+// variant are not reported.
+func TestimoniumABIDifferences(variant abi.ABI) ([]string, error) {
+	bundled, err := abi.JSON(strings.NewReader(TestimoniumABI))
+	if err != nil {
+		return nil, err
+	}
+
+	var differences []string
+	for name, method := range bundled.Methods {
+		variantMethod, exists := variant.Methods[name]
+		switch {
+		case !exists:
+			differences = append(differences, fmt.Sprintf("function %s is missing", name))
+		case variantMethod.Sig() != method.Sig():
+			differences = append(differences, fmt.Sprintf("function %s has parameters %s instead of %s", name, variantMethod.Sig(), method.Sig()))
+		case !sameTypes(variantMethod.Outputs, method.Outputs):
+			differences = append(differences, fmt.Sprintf("function %s has other return values", name))
+		}
+	}
+	for name, event := range bundled.Events {
+		variantEvent, exists := variant.Events[name]
+		switch {
+		case !exists:
+			differences = append(differences, fmt.Sprintf("event %s is missing", name))
+		case variantEvent.ID() != event.ID():
+			differences = append(differences, fmt.Sprintf("event %s has other parameters", name))
+		}
+	}
+	sort.Strings(differences)
+	return differences, nil
+}
+
+func sameTypes(a abi.Arguments, b abi.Arguments) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Type.String() != b[i].Type.String() {
+			return false
+		}
+	}
+	return true
+}
+
+// testimoniumAbi returns the ABI the ETH Relay contract of the chain is bound to
+func (chain *Chain) testimoniumAbi() (abi.ABI, error) {
+	if chain.testimoniumABI != "" {
+		return ParseContractABI([]byte(chain.testimoniumABI))
+	}
+	return abi.JSON(strings.NewReader(TestimoniumABI))
+}
+
+// TestimoniumBoundContract returns the generic binding of the chain's ETH Relay contract, e.g., to call functions of a
+// contract variant that have no typed binding. Transactions sent with it are signed by the account of the client if
+// the transact options are created with TransactOpts.
+func (c Client) TestimoniumBoundContract(chain uint8) (*bind.BoundContract, error) {
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, err
+	}
+	return c.chain(chain).testimoniumContract.TestimoniumCaller.contract, nil
+}
+
+// TestimoniumABI returns the ABI (JSON) the ETH Relay contract of the chain is bound to.
+func (c Client) TestimoniumABI(chain uint8) (string, error) {
+	if err := c.checkChain(chain); err != nil {
+		return "", err
+	}
+	if c.chain(chain).testimoniumABI != "" {
+		return c.chain(chain).testimoniumABI, nil
+	}
+	return TestimoniumABI, nil
+}
+
+// TransactOpts returns the options of a transaction of the account to the chain, with the current nonce and gas price
+// and the transact options modifiers applied.
+func (c Client) TransactOpts(chain uint8, valueInWei *big.Int) (*bind.TransactOpts, error) {
+	if err := c.checkChain(chain); err != nil {
+		return nil, err
+	}
+	return prepareTransaction(c.context(), c.account, c.privateKey, c.chain(chain), valueInWei)
+}
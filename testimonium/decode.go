@@ -0,0 +1,102 @@
+// This file contains the decoding and inspection of RLP encoded block headers, e.g., for debugging failed disputes.
+
+package testimonium
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// number of header fields expected by the Testimonium contract (up to and including the nonce)
+const contractHeaderFieldCount = 15
+
+// DecodedHeader is an RLP encoded header together with the values derived from it.
+type DecodedHeader struct {
+	Header           *types.Header
+	Hash             common.Hash // hash of the complete encoding
+	HashWithoutNonce common.Hash
+	FieldCount       int
+	Fork             string   // Ethereum main net fork the block number belongs to
+	Warnings         []string // properties the deployed Testimonium contract cannot handle
+}
+
+// DecodeRLPHeader decodes an RLP encoded header. Headers with more fields than the Testimonium contract expects
+// (e.g., headers of later forks) are decoded as far as possible and a warning is added.
+func DecodeRLPHeader(rlpHeader []byte) (*DecodedHeader, error) {
+	var fields []rlp.RawValue
+	if err := rlp.DecodeBytes(rlpHeader, &fields); err != nil {
+		return nil, fmt.Errorf("not an RLP encoded list: %s", err)
+	}
+	if len(fields) < contractHeaderFieldCount {
+		return nil, fmt.Errorf("header contains %d fields, at least %d are expected", len(fields), contractHeaderFieldCount)
+	}
+
+	// decode the fields known to this client, additional fields are ignored
+	knownFields, err := rlp.EncodeToBytes(fields[:contractHeaderFieldCount])
+	if err != nil {
+		return nil, err
+	}
+	header, err := decodeHeaderFromRLP(knownFields)
+	if err != nil {
+		return nil, err
+	}
+
+	hashWithoutNonce, err := HeaderHashWithoutNonce(header)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := &DecodedHeader{
+		Header:           header,
+		Hash:             crypto.Keccak256Hash(rlpHeader),
+		HashWithoutNonce: hashWithoutNonce,
+		FieldCount:       len(fields),
+		Fork:             MainnetFork(header.Number),
+	}
+
+	if len(fields) > contractHeaderFieldCount {
+		decoded.Warnings = append(decoded.Warnings, fmt.Sprintf("header contains %d fields, the contract expects %d", len(fields), contractHeaderFieldCount))
+	}
+	if uint64(len(header.Extra)) > params.MaximumExtraDataSize {
+		decoded.Warnings = append(decoded.Warnings, fmt.Sprintf("extra data contains %d bytes, at most %d are allowed", len(header.Extra), params.MaximumExtraDataSize))
+	}
+	if header.Difficulty.Sign() == 0 {
+		decoded.Warnings = append(decoded.Warnings, "difficulty is zero, the block is not sealed with Ethash")
+	}
+	if !header.Number.IsUint64() || header.Difficulty.BitLen() > 256 {
+		decoded.Warnings = append(decoded.Warnings, "block number or difficulty exceeds the range supported by the contract")
+	}
+
+	return decoded, nil
+}
+
+// MainnetFork returns the name of the Ethereum main net fork that is active at the specified block number.
+func MainnetFork(blockNumber *big.Int) string {
+	config := params.MainnetChainConfig
+	forks := []struct {
+		name  string
+		block *big.Int
+	}{
+		{"Muir Glacier", config.MuirGlacierBlock},
+		{"Istanbul", config.IstanbulBlock},
+		{"Petersburg", config.PetersburgBlock},
+		{"Byzantium", config.ByzantiumBlock},
+		{"Spurious Dragon", config.EIP158Block},
+		{"Tangerine Whistle", config.EIP150Block},
+		{"DAO", config.DAOForkBlock},
+		{"Homestead", config.HomesteadBlock},
+	}
+
+	for _, fork := range forks {
+		if fork.block != nil && blockNumber.Cmp(fork.block) >= 0 {
+			return fork.name
+		}
+	}
+	return "Frontier"
+}
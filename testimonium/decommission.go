@@ -0,0 +1,298 @@
+// This file contains the decommissioning of a relayer: its header submissions are stopped, the stake is withdrawn
+// once the lock periods of all submitted headers passed and a final accounting of its relay activity is reported.
+
+package testimonium
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// DECOMMISSION_POLL_INTERVAL is the default interval the lock periods are checked in while waiting.
+	DECOMMISSION_POLL_INTERVAL = time.Minute
+	// DECOMMISSION_UNLOCK_MARGIN is waited in addition to the lock period, as the contract compares the lock period
+	// with the timestamp of the withdrawal's block.
+	DECOMMISSION_UNLOCK_MARGIN = 30 * time.Second
+)
+
+var (
+	// ErrDecommissioned is returned if headers are submitted by an account that is being decommissioned on the chain.
+	ErrDecommissioned = errors.New("the account is decommissioned")
+	// ErrUnknownLockPeriod is returned if the lock period is neither configured nor readable from the contract.
+	ErrUnknownLockPeriod = errors.New("lock period unknown")
+	// ErrStakeLocked is returned by WithdrawStake if less than the requested stake was withdrawn as the rest is locked
+	// by submitted headers.
+	ErrStakeLocked = errors.New("withdraw not successful, reason: more than 'amount' stake is locked in contract")
+)
+
+// DecommissionMarker is stored in the data directory while an account is decommissioned on a chain. Clients using
+// the data directory do not submit headers of the account to the chain.
+type DecommissionMarker struct {
+	Chain   uint8          `json:"chain"`
+	Account common.Address `json:"account"`
+	Started time.Time      `json:"started"`
+}
+
+// DecommissionConfig configures Decommission.
+type DecommissionConfig struct {
+	LockPeriod   time.Duration // lock period of submitted headers, read from the contract if 0
+	PollInterval time.Duration // DECOMMISSION_POLL_INTERVAL if 0
+	NoWait       bool          // fail instead of waiting if headers are still locked
+}
+
+// DecommissionReport is the final accounting of an account on a chain.
+type DecommissionReport struct {
+	Chain          uint8          `json:"chain"`
+	Account        common.Address `json:"account"`
+	Started        time.Time      `json:"started"`
+	Completed      time.Time      `json:"completed"`
+	LockPeriod     time.Duration  `json:"lockPeriod"`
+	LastSubmission *time.Time     `json:"lastSubmission,omitempty"` // nil if the account never submitted a header
+	UnlockedAt     *time.Time     `json:"unlockedAt,omitempty"`     // end of the lock period of the last submission
+	StakeBefore    *big.Int       `json:"stakeBefore"`
+	StakeWithdrawn *big.Int       `json:"stakeWithdrawn"`
+	StakeRemaining *big.Int       `json:"stakeRemaining"` // stake that could not be withdrawn, e.g., of disputed headers
+	Withdrawals    []*TxResult    `json:"withdrawals,omitempty"`
+	Balance        *big.Int       `json:"balance"` // balance of the account after the withdrawal
+	// relay activity of the account over the whole event index
+	Activity *DayStats `json:"activity"`
+	Profit   *big.Int  `json:"profit"` // fees earned in ether minus the gas spent, including the withdrawals
+}
+
+// DecommissionPath returns the path of the decommission marker of the chain in the data directory.
+func DecommissionPath(dataDir string, chain uint8) string {
+	return filepath.Join(dataDir, fmt.Sprintf("decommission-%d.json", chain))
+}
+
+// ReadDecommissionMarker returns the decommission marker of the chain, nil if the chain is not decommissioned.
+func ReadDecommissionMarker(dataDir string, chain uint8) (*DecommissionMarker, error) {
+	data, err := ioutil.ReadFile(DecommissionPath(dataDir, chain))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	marker := &DecommissionMarker{}
+	if err := json.Unmarshal(data, marker); err != nil {
+		return nil, fmt.Errorf("corrupt decommission marker %s: %s", DecommissionPath(dataDir, chain), err)
+	}
+	return marker, nil
+}
+
+// CancelDecommission removes the decommission marker of the chain, so headers are submitted again.
+func CancelDecommission(dataDir string, chain uint8) error {
+	if err := os.Remove(DecommissionPath(dataDir, chain)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// checkDecommissioned returns ErrDecommissioned if the account is decommissioned on the chain according to the data
+// directory of the event index
+func (c Client) checkDecommissioned(chain uint8) error {
+	c = c.as(OPERATOR_SUBMITTER)
+	if c.indexDir == "" {
+		return nil
+	}
+	marker, err := ReadDecommissionMarker(c.indexDir, chain)
+	if err != nil {
+		return err
+	}
+	if marker != nil && marker.Account == c.account {
+		return fmt.Errorf("%w: chain %d since %s (see %s)", ErrDecommissioned, chain,
+			marker.Started.Format(time.RFC3339), DecommissionPath(c.indexDir, chain))
+	}
+	return nil
+}
+
+// Decommission stops the header submissions of the account on the chain by writing the decommission marker to the data
+// directory, which makes clients using the directory (e.g., the live mode) fail with ErrDecommissioned. It then waits
+// until the lock period of the last header of the account that is still locked (see LockCalendar) passed on the
+// chain, withdraws the whole stake and
+// reports the relay activity of the account from the event index. The marker is kept, so the account does not
+// submit headers again until CancelDecommission is called.
+func (c Client) Decommission(dataDir string, chain uint8, config DecommissionConfig) (*DecommissionReport, error) {
+	c = c.as(OPERATOR_SUBMITTER)
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, err
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = DECOMMISSION_POLL_INTERVAL
+	}
+
+	marker, err := ReadDecommissionMarker(dataDir, chain)
+	if err != nil {
+		return nil, err
+	}
+	if marker == nil || marker.Account != c.account {
+		marker = &DecommissionMarker{Chain: chain, Account: c.account, Started: time.Now().UTC()}
+		if err := writeDecommissionMarker(dataDir, marker); err != nil {
+			return nil, err
+		}
+	}
+	c.progressf("Header submissions of %s to chain %d stopped (%s)\n", c.account.Hex(), chain, DecommissionPath(dataDir, chain))
+
+	report := &DecommissionReport{Chain: chain, Account: c.account, Started: marker.Started, LockPeriod: config.LockPeriod}
+	if report.LockPeriod == 0 {
+		lockPeriod, err := c.LockPeriod(chain)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownLockPeriod, err)
+		}
+		report.LockPeriod = time.Duration(lockPeriod.Int64()) * time.Second
+	}
+
+	index, _, err := c.UpdateEventIndex(dataDir, chain)
+	if err != nil {
+		return nil, err
+	}
+	lastSubmission, err := c.lastSubmission(index, chain)
+	if err != nil {
+		return nil, err
+	}
+	if lastSubmission != nil {
+		// headers removed by a dispute do not lock stake, so the last locked header of the calendar is waited for
+		calendar, err := c.lockCalendar(index, chain, report.LockPeriod)
+		if err != nil {
+			return nil, err
+		}
+		unlockedAt := lastSubmission.Add(report.LockPeriod + DECOMMISSION_UNLOCK_MARGIN)
+		report.LastSubmission, report.UnlockedAt = lastSubmission, &unlockedAt
+		if lastUnlock := calendar.LastUnlock(); lastUnlock != nil {
+			unlockedAt = lastUnlock.Add(DECOMMISSION_UNLOCK_MARGIN)
+			if err := c.awaitChainTime(chain, unlockedAt, config); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if report.StakeBefore, err = c.GetStake(chain); err != nil {
+		return nil, err
+	}
+	report.Withdrawals, err = c.withdrawAllStake(chain, config)
+	if err != nil {
+		return nil, err
+	}
+	if report.StakeRemaining, err = c.GetStake(chain); err != nil {
+		return nil, err
+	}
+	report.StakeWithdrawn = new(big.Int).Sub(report.StakeBefore, report.StakeRemaining)
+	if report.Balance, err = c.Balance(chain); err != nil {
+		return nil, err
+	}
+
+	// the withdrawals are not indexed, as they emit no indexed events
+	report.Activity = ComputeRelayStats(index, c.account).Total
+	gasSpent := new(big.Int).Set(report.Activity.GasSpent)
+	for _, withdrawal := range report.Withdrawals {
+		gasSpent.Add(gasSpent, c.txGasCost(chain, withdrawal))
+	}
+	report.Activity.GasSpent = gasSpent
+	report.Profit = new(big.Int).Sub(report.Activity.FeesEarned, gasSpent)
+	report.Completed = time.Now().UTC()
+	return report, nil
+}
+
+func writeDecommissionMarker(dataDir string, marker *DecommissionMarker) error {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(marker, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(DecommissionPath(dataDir, marker.Chain), data, 0644)
+}
+
+// lastSubmission returns the time of the latest header submission of the account in the index, nil if there is none
+func (c Client) lastSubmission(index *EventIndex, chain uint8) (*time.Time, error) {
+	c = c.as(OPERATOR_SUBMITTER)
+	var last *SubmitRecord
+	for _, record := range index.Records {
+		if record.Submitter == c.account && (last == nil || record.SubmitBlockNumber > last.SubmitBlockNumber) {
+			last = record
+		}
+	}
+	if last == nil {
+		return nil, nil
+	}
+	timestamp := last.Timestamp
+	if timestamp == 0 {
+		// indexed without timestamps
+		header, err := c.HeaderByNumber(new(big.Int).SetUint64(last.SubmitBlockNumber), chain)
+		if err != nil {
+			return nil, err
+		}
+		timestamp = header.Time
+	}
+	submitted := time.Unix(int64(timestamp), 0).UTC()
+	return &submitted, nil
+}
+
+// awaitChainTime waits until the latest block of the chain is not older than the time
+func (c Client) awaitChainTime(chain uint8, t time.Time, config DecommissionConfig) error {
+	for {
+		head, err := c.HeaderByNumber(nil, chain)
+		if err != nil {
+			return err
+		}
+		remaining := t.Sub(time.Unix(int64(head.Time), 0))
+		if remaining <= 0 {
+			return nil
+		}
+		if config.NoWait {
+			return fmt.Errorf("the stake is locked until %s (%s)", t.Format(time.RFC3339), remaining.Round(time.Second))
+		}
+		c.progressf("Waiting for the lock period of the last submitted header, %s remaining\n", remaining.Round(time.Second))
+		if remaining > config.PollInterval {
+			remaining = config.PollInterval
+		}
+		time.Sleep(remaining)
+	}
+}
+
+// withdrawAllStake withdraws the stake of the account until it is zero. Stake still locked by headers (e.g., of a
+// pending dispute) is retried after the poll interval unless config.NoWait is set.
+func (c Client) withdrawAllStake(chain uint8, config DecommissionConfig) ([]*TxResult, error) {
+	var withdrawals []*TxResult
+	for {
+		stake, err := c.GetStake(chain)
+		if err != nil {
+			return withdrawals, err
+		}
+		if stake.Sign() == 0 {
+			return withdrawals, nil
+		}
+		c.progressf("Withdrawing stake of %s wei\n", stake)
+		result, err := c.WithdrawStake(chain, stake)
+		if result != nil {
+			withdrawals = append(withdrawals, result)
+		}
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, ErrStakeLocked) || config.NoWait {
+			return withdrawals, err
+		}
+		c.progressf("Stake still locked, retrying in %s\n", config.PollInterval)
+		time.Sleep(config.PollInterval)
+	}
+}
+
+// txGasCost returns the gas costs of the transaction in wei, zero if they are unknown
+func (c Client) txGasCost(chain uint8, result *TxResult) *big.Int {
+	tx, _, err := c.Transaction(result.TxHash, chain)
+	if err != nil || result.GasUsed == 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(result.GasUsed))
+}
@@ -0,0 +1,114 @@
+// This file contains the metadata deployments are tagged with (e.g., who deployed a contract, in which version and for
+// which purpose), so environments shared by several teams can track who deployed which relay instance. The metadata is
+// recorded in the registry of the data directory and, if a deployment registry contract is configured for the chain,
+// announced to that contract.
+
+package testimonium
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DEPLOYMENT_CLIENT identifies the client in the metadata of its deployments (key METADATA_CLIENT)
+const DEPLOYMENT_CLIENT = "go-ethrelay"
+
+// well-known keys of the deployment metadata, any other keys can be used as well
+const (
+	METADATA_DEPLOYER = "deployer" // person or team responsible for the deployment
+	METADATA_VERSION  = "version"  // release of the relay setup, independent of the contract version
+	METADATA_PURPOSE  = "purpose"
+	METADATA_CLIENT   = "client" // set to DEPLOYMENT_CLIENT unless specified
+)
+
+// DeploymentRegistryABI is the interface of the deployment registry contracts deployments are announced to. The
+// contract is not part of ETH Relay; any contract implementing register (e.g., emitting an event with the arguments)
+// can be used. The metadata is passed as JSON object.
+const DeploymentRegistryABI = `[{"type":"function","name":"register","stateMutability":"nonpayable","inputs":[{"name":"deployment","type":"address"},{"name":"contractName","type":"string"},{"name":"metadata","type":"string"}],"outputs":[]}]`
+
+// WithDeploymentMetadata tags the contracts deployed by the client with the metadata, see METADATA_DEPLOYER,
+// METADATA_VERSION and METADATA_PURPOSE. Options passed several times are merged, later values win.
+func WithDeploymentMetadata(metadata map[string]string) ClientOption {
+	return func(client *Client) error {
+		if client.deploymentMetadata == nil {
+			client.deploymentMetadata = make(map[string]string)
+		}
+		for key, value := range metadata {
+			if key == "" {
+				return fmt.Errorf("deployment metadata without key (value %q)", value)
+			}
+			client.deploymentMetadata[key] = value
+		}
+		return nil
+	}
+}
+
+// metadataOfDeployment returns the metadata the client tags its deployments with, including the client identification
+func (c Client) metadataOfDeployment() map[string]string {
+	metadata := map[string]string{METADATA_CLIENT: DEPLOYMENT_CLIENT}
+	for key, value := range c.deploymentMetadata {
+		metadata[key] = value
+	}
+	return metadata
+}
+
+// deploymentRegistryFromConfig returns the address of the deployment registry contract of the "deploymentregistry"
+// entry of a chain config, nil if deployments are not announced on the chain
+func deploymentRegistryFromConfig(chainConfig map[string]interface{}) (*common.Address, error) {
+	entry := chainConfig["deploymentregistry"]
+	if entry == nil {
+		return nil, nil
+	}
+	addressHex := fmt.Sprint(entry)
+	if !common.IsHexAddress(addressHex) {
+		return nil, fmt.Errorf("illegal deploymentregistry entry %v", entry)
+	}
+	address := common.HexToAddress(addressHex)
+	return &address, nil
+}
+
+// announceDeployment registers the deployment with the deployment registry contract of the chain and returns the hash
+// of the registration, nil if no registry is configured
+func (c Client) announceDeployment(chain uint8, contract string, addr common.Address, metadata map[string]string) (*common.Hash, error) {
+	registry := c.chain(chain).deploymentRegistry
+	if registry == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := abi.JSON(strings.NewReader(DeploymentRegistryABI))
+	if err != nil {
+		return nil, err
+	}
+	client := c.chain(chain).client
+	bound := bind.NewBoundContract(*registry, parsed, client, txLogBackend{client, c.chain(chain)}, client)
+
+	auth, err := prepareTransaction(c.context(), c.account, c.privateKey, c.chain(chain), big.NewInt(0))
+	if err != nil {
+		return nil, err
+	}
+	tx, err := bound.Transact(auth, "register", addr, contract, string(encoded))
+	if err != nil {
+		return nil, err
+	}
+	c.progressf("Registration of the deployment submitted: %s\n", tx.Hash().Hex())
+
+	receipt, err := awaitTxReceipt(c.context(), c.chain(chain), tx.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status == 0 {
+		reason := getFailureReason(client, c.account, tx, receipt.BlockNumber)
+		return nil, fmt.Errorf("tx failed: %s", reason)
+	}
+	hash := tx.Hash()
+	return &hash, nil
+}
@@ -0,0 +1,297 @@
+// This file contains the fallback for the total difficulty of blocks. Since the merge, many providers no longer return
+// the totalDifficulty field of blocks, which is required to deploy the ETH Relay contract. The total difficulty is then
+// computed from a checkpoint with a known total difficulty by adding (or subtracting) the difficulties of the headers in
+// between. The total difficulty only includes the difficulties of the blocks of the chain (not of their uncles), so the
+// headers are followed along their parent hashes.
+
+package testimonium
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// MAX_TOTAL_DIFFICULTY_HEADERS is the maximum number of headers fetched to compute a total difficulty. Configure a
+// checkpoint close to the blocks if more headers would be needed.
+const MAX_TOTAL_DIFFICULTY_HEADERS = 100000
+
+// TOTAL_DIFFICULTY_CACHE_SIZE is the number of computed total difficulties cached per chain.
+const TOTAL_DIFFICULTY_CACHE_SIZE = 1024
+
+// TdCheckpoint is a block with a known total difficulty.
+type TdCheckpoint struct {
+	Number          uint64
+	Hash            common.Hash
+	TotalDifficulty *big.Int
+	// set if the difficulty of all later blocks is zero (the last proof-of-work block), the total difficulty of later
+	// blocks equals the one of the checkpoint
+	Final bool
+}
+
+// checkpoints of all chains, they are only used if the node has a block with the hash at the number
+var knownTdCheckpoints = []TdCheckpoint{
+	{
+		// last proof-of-work block of the main net
+		Number:          15537393,
+		Hash:            common.HexToHash("0x55b11b918355b1ef9c5db810302ebad0bf2544255b530cdce90674d5887bb286"),
+		TotalDifficulty: bigFromString("58750003716598352816469"),
+		Final:           true,
+	},
+}
+
+func bigFromString(value string) *big.Int {
+	result, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		panic("illegal number " + value)
+	}
+	return result
+}
+
+// tdCache contains the verified checkpoints and the computed total difficulties of a chain
+type tdCache struct {
+	mutex       sync.Mutex
+	checkpoints []TdCheckpoint               // configured and known checkpoints
+	verified    map[common.Hash]TdCheckpoint // set once the checkpoints are verified
+	computed    map[common.Hash]TdCheckpoint
+}
+
+func newTdCache(checkpoints []TdCheckpoint) *tdCache {
+	return &tdCache{
+		checkpoints: append(checkpoints, knownTdCheckpoints...),
+		computed:    make(map[common.Hash]TdCheckpoint),
+	}
+}
+
+func (cache *tdCache) lookup(blockHash common.Hash) (*big.Int, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if checkpoint, exists := cache.verified[blockHash]; exists {
+		return checkpoint.TotalDifficulty, true
+	}
+	if checkpoint, exists := cache.computed[blockHash]; exists {
+		return checkpoint.TotalDifficulty, true
+	}
+	return nil, false
+}
+
+func (cache *tdCache) add(header *types.Header, totalDifficulty *big.Int) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if len(cache.computed) >= TOTAL_DIFFICULTY_CACHE_SIZE {
+		// evict the lowest block, total difficulties are usually computed for increasing blocks
+		var lowest TdCheckpoint
+		first := true
+		for _, checkpoint := range cache.computed {
+			if first || checkpoint.Number < lowest.Number {
+				lowest, first = checkpoint, false
+			}
+		}
+		delete(cache.computed, lowest.Hash)
+	}
+	cache.computed[header.Hash()] = TdCheckpoint{
+		Number:          header.Number.Uint64(),
+		Hash:            header.Hash(),
+		TotalDifficulty: totalDifficulty,
+	}
+}
+
+// tdCheckpointsFromConfig reads the "tdcheckpoints" entry of a chain config, e.g.,
+//
+//	tdcheckpoints:
+//	    - block: 15537393
+//	      hash: "0x55b11b918355b1ef9c5db810302ebad0bf2544255b530cdce90674d5887bb286"
+//	      totaldifficulty: "58750003716598352816469"
+//	      final: true
+func tdCheckpointsFromConfig(chainConfig map[string]interface{}) ([]TdCheckpoint, error) {
+	entries, ok := chainConfig["tdcheckpoints"].([]interface{})
+	if chainConfig["tdcheckpoints"] != nil && !ok {
+		return nil, fmt.Errorf("tdcheckpoints is not a list of checkpoints")
+	}
+
+	var checkpoints []TdCheckpoint
+	for i, entry := range entries {
+		values := make(map[string]interface{})
+		switch entry := entry.(type) {
+		case map[string]interface{}:
+			values = entry
+		case map[interface{}]interface{}:
+			for key, value := range entry {
+				values[fmt.Sprint(key)] = value
+			}
+		default:
+			return nil, fmt.Errorf("illegal tdcheckpoint %v", entry)
+		}
+
+		number, err := configuredId(values, "block")
+		if err != nil || number == nil {
+			return nil, fmt.Errorf("tdcheckpoint %d: illegal block %v", i+1, values["block"])
+		}
+		hash, ok := values["hash"].(string)
+		if !ok || len(common.FromHex(hash)) != common.HashLength {
+			return nil, fmt.Errorf("tdcheckpoint %d: illegal hash %v", i+1, values["hash"])
+		}
+		totalDifficulty, err := configuredId(values, "totaldifficulty")
+		if err != nil || totalDifficulty == nil {
+			return nil, fmt.Errorf("tdcheckpoint %d: illegal totaldifficulty %v", i+1, values["totaldifficulty"])
+		}
+		final, _ := values["final"].(bool)
+
+		checkpoints = append(checkpoints, TdCheckpoint{
+			Number:          number.Uint64(),
+			Hash:            common.HexToHash(hash),
+			TotalDifficulty: totalDifficulty,
+			Final:           final,
+		})
+	}
+	return checkpoints, nil
+}
+
+// verifiedTdCheckpoints returns the checkpoints the node of the chain agrees with and the computed total
+// difficulties. Checkpoints are verified once, the genesis block is a checkpoint of every chain (its total difficulty
+// is its difficulty).
+func (c Client) verifiedTdCheckpoints(chain uint8) ([]TdCheckpoint, error) {
+	cache := c.chain(chain).tdCache
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.verified == nil {
+		genesis, err := c.HeaderByNumber(big.NewInt(0), chain)
+		if err != nil {
+			return nil, err
+		}
+		verified := map[common.Hash]TdCheckpoint{
+			genesis.Hash(): {Hash: genesis.Hash(), TotalDifficulty: genesis.Difficulty},
+		}
+		for _, checkpoint := range cache.checkpoints {
+			header, err := c.HeaderByNumber(new(big.Int).SetUint64(checkpoint.Number), chain)
+			if err != nil {
+				if isPruned(err) {
+					continue // the chain is shorter than the checkpoint
+				}
+				return nil, err
+			}
+			if header.Hash() == checkpoint.Hash {
+				verified[checkpoint.Hash] = checkpoint
+			}
+		}
+		cache.verified = verified
+	}
+
+	checkpoints := make([]TdCheckpoint, 0, len(cache.verified)+len(cache.computed))
+	for _, checkpoint := range cache.verified {
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	for _, checkpoint := range cache.computed {
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints, nil
+}
+
+// computeTotalDifficulty computes the total difficulty of the block from the nearest checkpoint of the chain
+func (c Client) computeTotalDifficulty(blockNumber *big.Int, chain uint8) (*big.Int, error) {
+	header, err := c.HeaderByNumber(blockNumber, chain)
+	if err != nil {
+		return nil, err
+	}
+	cache := c.chain(chain).tdCache
+	if totalDifficulty, exists := cache.lookup(header.Hash()); exists {
+		return new(big.Int).Set(totalDifficulty), nil
+	}
+
+	checkpoints, err := c.verifiedTdCheckpoints(chain)
+	if err != nil {
+		return nil, err
+	}
+	number := header.Number.Uint64()
+	var below, above *TdCheckpoint
+	for i := range checkpoints {
+		checkpoint := &checkpoints[i]
+		if checkpoint.Number <= number && (below == nil || checkpoint.Number > below.Number) {
+			below = checkpoint
+		}
+		if checkpoint.Number > number && (above == nil || checkpoint.Number < above.Number) {
+			above = checkpoint
+		}
+	}
+
+	var totalDifficulty *big.Int
+	switch {
+	case below != nil && below.Final && header.Difficulty.Sign() == 0:
+		totalDifficulty = new(big.Int).Set(below.TotalDifficulty)
+	case below != nil && (above == nil || number-below.Number <= above.Number-number):
+		totalDifficulty, err = c.totalDifficultyFromAncestor(header, *below, chain)
+	case above != nil:
+		totalDifficulty, err = c.totalDifficultyFromDescendant(header, *above, chain)
+	default:
+		err = fmt.Errorf("no total difficulty checkpoint for block %d of chain %d", number, chain)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cache.add(header, totalDifficulty)
+	return new(big.Int).Set(totalDifficulty), nil
+}
+
+// totalDifficultyFromAncestor adds the difficulties of the header and its ancestors down to the checkpoint
+func (c Client) totalDifficultyFromAncestor(header *types.Header, checkpoint TdCheckpoint, chain uint8) (*big.Int, error) {
+	if err := c.checkTdDistance(header.Number.Uint64()-checkpoint.Number, checkpoint, chain); err != nil {
+		return nil, err
+	}
+	sum := new(big.Int)
+	for header.Number.Uint64() > checkpoint.Number {
+		sum.Add(sum, header.Difficulty)
+		parent, err := c.headerByHash(header.ParentHash, chain)
+		if err != nil {
+			return nil, err
+		}
+		header = parent
+		if remaining := header.Number.Uint64() - checkpoint.Number; remaining > 0 && remaining%1000 == 0 {
+			c.progressf("Computing total difficulty: %d headers to checkpoint %d\n", remaining, checkpoint.Number)
+		}
+	}
+	if header.Hash() != checkpoint.Hash {
+		return nil, fmt.Errorf("block %d is not a descendant of the total difficulty checkpoint %s", header.Number, checkpoint.Hash.Hex())
+	}
+	return sum.Add(sum, checkpoint.TotalDifficulty), nil
+}
+
+// totalDifficultyFromDescendant subtracts the difficulties of the checkpoint and its ancestors down to the header
+func (c Client) totalDifficultyFromDescendant(header *types.Header, checkpoint TdCheckpoint, chain uint8) (*big.Int, error) {
+	if err := c.checkTdDistance(checkpoint.Number-header.Number.Uint64(), checkpoint, chain); err != nil {
+		return nil, err
+	}
+	descendant, err := c.headerByHash(checkpoint.Hash, chain)
+	if err != nil {
+		return nil, err
+	}
+	totalDifficulty := new(big.Int).Set(checkpoint.TotalDifficulty)
+	for descendant.Number.Cmp(header.Number) > 0 {
+		totalDifficulty.Sub(totalDifficulty, descendant.Difficulty)
+		descendant, err = c.headerByHash(descendant.ParentHash, chain)
+		if err != nil {
+			return nil, err
+		}
+		if remaining := descendant.Number.Uint64() - header.Number.Uint64(); remaining > 0 && remaining%1000 == 0 {
+			c.progressf("Computing total difficulty: %d headers to block %d\n", remaining, header.Number)
+		}
+	}
+	if descendant.Hash() != header.Hash() {
+		return nil, fmt.Errorf("block %d is not an ancestor of the total difficulty checkpoint %s", header.Number, checkpoint.Hash.Hex())
+	}
+	return totalDifficulty, nil
+}
+
+func (c Client) checkTdDistance(distance uint64, checkpoint TdCheckpoint, chain uint8) error {
+	if distance > MAX_TOTAL_DIFFICULTY_HEADERS {
+		return fmt.Errorf("the total difficulty checkpoint %d of chain %d is %d blocks away (maximum %d), configure a closer checkpoint (tdcheckpoints)",
+			checkpoint.Number, chain, distance, MAX_TOTAL_DIFFICULTY_HEADERS)
+	}
+	if distance > 1 {
+		c.progressf("Node of chain %d does not provide the total difficulty, computing it from %d headers\n", chain, distance)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// This file contains the prediction of the outcome of a dispute without sending a transaction.
+
+package testimonium
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+)
+
+// return codes of the PoW validation of the Ethash contract, emitted by the PoWValidationResult event
+const (
+	POW_VALID                 uint64 = 0 // the dispute fails
+	POW_EPOCH_DATA_NOT_SET    uint64 = 1 // errorInfo contains the epoch
+	POW_DATASET_PROOF_INVALID uint64 = 2 // errorInfo contains the index of the invalid dataset lookup
+	POW_DIFFICULTY_NOT_MET    uint64 = 3 // errorInfo contains the proof-of-work result
+)
+
+// DisputePrediction is the locally computed outcome of disputing a block header.
+type DisputePrediction struct {
+	BlockHash    common.Hash
+	BlockNumber  uint64
+	ReturnCode   uint64   // predicted return code of the PoWValidationResult event
+	ErrorInfo    *big.Int // predicted error info of the PoWValidationResult event
+	PoW          PoWVerification
+	MixDigestErr error // the mix digest is not checked by the contract, a mismatch is reported for information only
+}
+
+// Succeeds returns whether the PoW validation of the contract is predicted to fail, i.e., the dispute removes the
+// header (and its branch) from the contract.
+func (p DisputePrediction) Succeeds() bool {
+	return p.ReturnCode != POW_VALID
+}
+
+func (p DisputePrediction) String() string {
+	return fmt.Sprintf("DisputePrediction: { block: %s, returnCode: %d, errorInfo: %s, succeeds: %t }",
+		p.BlockHash.String(), p.ReturnCode, p.ErrorInfo.String(), p.Succeeds())
+}
+
+// PredictDispute computes the outcome of disputing the block header with the specified hash without sending a
+// transaction. The header is read from the transaction that submitted it, its proof-of-work is computed locally
+// and the epoch data is checked with a call to the Ethash contract.
+func (c Client) PredictDispute(blockHash common.Hash, chain uint8) (DisputePrediction, error) {
+	if err := c.checkTestimonium(chain); err != nil {
+		return DisputePrediction{}, err
+	}
+	if err := c.checkEthash(chain); err != nil {
+		return DisputePrediction{}, err
+	}
+
+	rlpHeader, err := c.submittedRlpHeader(blockHash, chain)
+	if err != nil {
+		return DisputePrediction{}, err
+	}
+
+	header, err := decodeHeaderFromRLP(rlpHeader)
+	if err != nil {
+		return DisputePrediction{}, err
+	}
+
+	prediction := DisputePrediction{
+		BlockHash:   header.Hash(),
+		BlockNumber: header.Number.Uint64(),
+		ReturnCode:  POW_VALID,
+		ErrorInfo:   big.NewInt(0),
+	}
+
+	epoch := header.Number.Uint64() / 30000
+	isEpochDataSet, err := c.chain(chain).ethashContract.IsEpochDataSet(c.callOpts(), new(big.Int).SetUint64(epoch))
+	if err != nil {
+		return DisputePrediction{}, err
+	}
+	if !isEpochDataSet {
+		prediction.ReturnCode = POW_EPOCH_DATA_NOT_SET
+		prediction.ErrorInfo = new(big.Int).SetUint64(epoch)
+		return prediction, nil
+	}
+
+	// the dataset proofs are generated by the client itself and are therefore always valid,
+	// so only the difficulty check can fail
+	prediction.PoW, err = VerifyPoW(header)
+	if prediction.PoW.Target == nil {
+		return DisputePrediction{}, err
+	}
+	if prediction.PoW.Result.Cmp(prediction.PoW.Target) > 0 {
+		prediction.ReturnCode = POW_DIFFICULTY_NOT_MET
+		prediction.ErrorInfo = prediction.PoW.Result
+	}
+	if prediction.PoW.ComputedMixDigest != prediction.PoW.MixDigest {
+		prediction.MixDigestErr = fmt.Errorf("%w: computed %s, header contains %s", ethash.ErrInvalidMixDigest,
+			prediction.PoW.ComputedMixDigest.String(), prediction.PoW.MixDigest.String())
+	}
+
+	return prediction, nil
+}
@@ -0,0 +1,287 @@
+// This file contains the economics simulator of the live mode. It replays the submissions and verifications recorded in
+// the event index with the relay policies "all" and "every:N" and estimates for each policy the verification fees the
+// account would have earned and the gas its submissions would have cost.
+
+package testimonium
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// ECONOMICS_DEFAULT_DELAY is the time between the mining of a block and its submission by the account if the index
+	// contains no submissions of the account to measure it.
+	ECONOMICS_DEFAULT_DELAY = 30 * time.Second
+	// ECONOMICS_DEFAULT_SUBMIT_GAS is the gas of a submission if the index contains no submission with gas costs.
+	ECONOMICS_DEFAULT_SUBMIT_GAS = 300000
+)
+
+// ErrNoEconomicsData is returned if the index contains no submitted blocks or no gas prices in the simulated window.
+var ErrNoEconomicsData = errors.New("not enough data in the event index")
+
+// EconomicsConfig configures the simulation. Zero values are derived from the index.
+type EconomicsConfig struct {
+	Account   common.Address
+	Intervals []uint64      // relay every N-th block, 1 for every block
+	Window    time.Duration // simulated time before the latest indexed block, the whole index if 0
+	// time between the mining of a block and its submission by the account, by default the median of its indexed
+	// submissions or ECONOMICS_DEFAULT_DELAY
+	Delay     time.Duration
+	GasPrice  *big.Int // gas price of the submissions, by default the price paid by the indexed transactions at the time
+	SubmitGas uint64   // gas of a submission, by default the average of the indexed submissions
+}
+
+// PolicyEconomics is the estimated outcome of a relay policy over the simulated window.
+type PolicyEconomics struct {
+	Interval            uint64        `json:"interval"`
+	Policy              string        `json:"policy"` // the policy of 'submit block --live --policy'
+	HeadersSubmitted    int           `json:"headersSubmitted"`
+	VerificationsServed int           `json:"verificationsServed"`
+	FeesEarned          *big.Int      `json:"feesEarned"` // in wei
+	GasCost             *big.Int      `json:"gasCost"`    // in wei
+	Profit              *big.Int      `json:"profit"`     // fees earned minus gas cost, in wei
+	AverageLatency      time.Duration `json:"averageLatency"`
+}
+
+// EconomicsSimulation is the result of simulating the relay policies. Recommended is the most profitable policy,
+// empty if no policy is profitable.
+type EconomicsSimulation struct {
+	Chain         uint8             `json:"chain"`
+	Account       common.Address    `json:"account"`
+	From          time.Time         `json:"from"` // mining time of the first simulated block
+	To            time.Time         `json:"to"`
+	FirstBlock    uint64            `json:"firstBlock"`
+	LastBlock     uint64            `json:"lastBlock"`
+	Verifications int               `json:"verifications"` // verifications of blocks of the window paid in ether
+	TokenFees     int               `json:"tokenFees"`     // verifications paid in an ERC-20 token, not included
+	Delay         time.Duration     `json:"delay"`
+	SubmitGas     uint64            `json:"submitGas"`
+	Policies      []PolicyEconomics `json:"policies"`
+	Recommended   string            `json:"recommended"`
+}
+
+// simBlock is a block of the source chain in the simulated window
+type simBlock struct {
+	mined      uint64     // timestamp of the block
+	competitor uint64     // earliest submission by another account, 0 if none
+	fees       []*big.Int // fees of the verifications of values in the block
+}
+
+// gasPriceSample is the gas price paid by an indexed transaction
+type gasPriceSample struct {
+	timestamp uint64
+	gasPrice  *big.Int
+}
+
+// SimulateEconomics replays the window of the index with each interval of the config. A block is submitted by the
+// account if no other account submitted it before the account would have, i.e., before the delay after the mining of
+// the next block the policy relays (all headers are submitted as the contract only accepts a header extending a stored
+// one). The account earns the fees of the verifications of the blocks it submits and pays the gas price recorded at
+// the time of the submission. The stake is not a cost, as it is returned after the lock period.
+func SimulateEconomics(index *EventIndex, config EconomicsConfig) (*EconomicsSimulation, error) {
+	blocks := make(map[uint64]*simBlock)
+	blocksByHash := make(map[common.Hash]uint64)
+	var gasPrices []gasPriceSample
+	var ownDelays []time.Duration
+	var submitGas, submissions uint64
+
+	addGasPrice := func(cost txCost) {
+		if cost.Timestamp > 0 && cost.GasPrice != nil {
+			gasPrices = append(gasPrices, gasPriceSample{cost.Timestamp, cost.GasPrice})
+		}
+	}
+	for _, record := range index.Records {
+		header, err := decodeHeaderFromRLP(record.RlpHeader)
+		if err != nil {
+			continue
+		}
+		number := header.Number.Uint64()
+		block := blocks[number]
+		if block == nil {
+			block = &simBlock{mined: header.Time}
+			blocks[number] = block
+		}
+		if header.Time < block.mined {
+			// the earliest block of several branches
+			block.mined = header.Time
+		}
+		blocksByHash[record.BlockHash] = number
+
+		addGasPrice(record.txCost)
+		if record.GasUsed > 0 {
+			submitGas += record.GasUsed
+			submissions++
+		}
+		if record.Submitter == config.Account {
+			if record.Timestamp >= header.Time {
+				ownDelays = append(ownDelays, time.Duration(record.Timestamp-header.Time)*time.Second)
+			}
+		} else if record.Timestamp > 0 && (block.competitor == 0 || record.Timestamp < block.competitor) {
+			block.competitor = record.Timestamp
+		}
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("%w: no submitted blocks", ErrNoEconomicsData)
+	}
+
+	numbers := make([]uint64, 0, len(blocks))
+	for number := range blocks {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	last := numbers[len(numbers)-1]
+	first := numbers[0]
+	if window := uint64(config.Window / time.Second); window > 0 && window < blocks[last].mined {
+		start := blocks[last].mined - window
+		for _, number := range numbers {
+			if blocks[number].mined >= start {
+				first = number
+				break
+			}
+		}
+	}
+
+	simulation := &EconomicsSimulation{
+		Chain:      index.Chain,
+		Account:    config.Account,
+		From:       time.Unix(int64(blocks[first].mined), 0),
+		To:         time.Unix(int64(blocks[last].mined), 0),
+		FirstBlock: first,
+		LastBlock:  last,
+		Delay:      config.Delay,
+		SubmitGas:  config.SubmitGas,
+	}
+	if simulation.Delay == 0 {
+		simulation.Delay = ECONOMICS_DEFAULT_DELAY
+		if len(ownDelays) > 0 {
+			sort.Slice(ownDelays, func(i, j int) bool { return ownDelays[i] < ownDelays[j] })
+			simulation.Delay = ownDelays[len(ownDelays)/2]
+		}
+	}
+	if simulation.SubmitGas == 0 {
+		simulation.SubmitGas = ECONOMICS_DEFAULT_SUBMIT_GAS
+		if submissions > 0 {
+			simulation.SubmitGas = submitGas / submissions
+		}
+	}
+
+	for _, record := range index.Verifications {
+		addGasPrice(record.txCost)
+		number, exists := blocksByHash[record.BlockHash]
+		if !exists || number < first || record.Fee == nil {
+			continue
+		}
+		if record.FeeToken != nil {
+			simulation.TokenFees++
+			continue
+		}
+		blocks[number].fees = append(blocks[number].fees, record.Fee)
+		simulation.Verifications++
+	}
+	for _, record := range index.Disputes {
+		addGasPrice(record.txCost)
+	}
+	sort.Slice(gasPrices, func(i, j int) bool { return gasPrices[i].timestamp < gasPrices[j].timestamp })
+	if config.GasPrice == nil && len(gasPrices) == 0 {
+		return nil, fmt.Errorf("%w: no gas prices (the index was built without gas costs)", ErrNoEconomicsData)
+	}
+	gasPriceAt := func(timestamp uint64) *big.Int {
+		if config.GasPrice != nil {
+			return config.GasPrice
+		}
+		i := sort.Search(len(gasPrices), func(i int) bool { return gasPrices[i].timestamp > timestamp })
+		if i == 0 {
+			return gasPrices[0].gasPrice
+		}
+		return gasPrices[i-1].gasPrice
+	}
+
+	// blocks nobody submitted are not indexed, their mining time is interpolated
+	blockTime := float64(0)
+	if last > numbers[0] {
+		blockTime = float64(blocks[last].mined-blocks[numbers[0]].mined) / float64(last-numbers[0])
+	}
+	minedAt := func(number uint64) uint64 {
+		if block, exists := blocks[number]; exists {
+			return block.mined
+		}
+		i := sort.Search(len(numbers), func(i int) bool { return numbers[i] > number })
+		if i == 0 {
+			return blocks[numbers[0]].mined
+		}
+		previous := numbers[i-1]
+		return blocks[previous].mined + uint64(float64(number-previous)*blockTime)
+	}
+
+	delay := uint64(simulation.Delay / time.Second)
+	for _, interval := range config.Intervals {
+		if interval == 0 {
+			continue
+		}
+		policy := PolicyEconomics{
+			Interval:   interval,
+			Policy:     "all",
+			FeesEarned: new(big.Int),
+			GasCost:    new(big.Int),
+		}
+		if interval > 1 {
+			policy.Policy = fmt.Sprintf("every:%d", interval)
+		}
+		var latency time.Duration
+		for number := first; number <= last; number++ {
+			relayed := (number + interval - 1) / interval * interval
+			submitted := minedAt(relayed) + delay
+			block := blocks[number]
+			if block != nil && block.competitor > 0 && block.competitor <= submitted {
+				continue
+			}
+			policy.HeadersSubmitted++
+			gasCost := new(big.Int).Mul(new(big.Int).SetUint64(simulation.SubmitGas), gasPriceAt(submitted))
+			policy.GasCost.Add(policy.GasCost, gasCost)
+			latency += time.Duration(submitted-minedAt(number)) * time.Second
+			if block != nil {
+				for _, fee := range block.fees {
+					policy.FeesEarned.Add(policy.FeesEarned, fee)
+					policy.VerificationsServed++
+				}
+			}
+		}
+		policy.Profit = new(big.Int).Sub(policy.FeesEarned, policy.GasCost)
+		if policy.HeadersSubmitted > 0 {
+			policy.AverageLatency = latency / time.Duration(policy.HeadersSubmitted)
+		}
+		simulation.Policies = append(simulation.Policies, policy)
+	}
+
+	// the policy with the lowest latency is preferred among equally profitable ones
+	var best *PolicyEconomics
+	for i := range simulation.Policies {
+		policy := &simulation.Policies[i]
+		if policy.Profit.Sign() <= 0 {
+			continue
+		}
+		if best == nil || policy.Profit.Cmp(best.Profit) > 0 ||
+			(policy.Profit.Cmp(best.Profit) == 0 && policy.AverageLatency < best.AverageLatency) {
+			best = policy
+		}
+	}
+	if best != nil {
+		simulation.Recommended = best.Policy
+	}
+	return simulation, nil
+}
+
+// SimulateEconomics updates the event index of the chain in the data directory and simulates the relay policies on it.
+func (c Client) SimulateEconomics(dataDir string, chain uint8, config EconomicsConfig) (*EconomicsSimulation, error) {
+	index, _, err := c.UpdateEventIndex(dataDir, chain)
+	if err != nil {
+		return nil, err
+	}
+	return SimulateEconomics(index, config)
+}
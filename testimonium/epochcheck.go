@@ -0,0 +1,262 @@
+// This file contains the verification of the epoch data stored in the Ethash contract. The contract offers no getter
+// for the Merkle nodes of an epoch, so they are read back from the calldata of the setEpochData transactions that set
+// them and compared with the epoch data computed locally. Wrong nodes, whether corrupted or set maliciously, make
+// disputes of illegitimate headers fail, as the contract rejects the witnesses of the correct DAG.
+
+package testimonium
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+	"github.com/pantos-io/go-ethrelay/typedefs"
+)
+
+// EpochDataTx is a setEpochData transaction of an epoch.
+type EpochDataTx struct {
+	TxHash      common.Hash    `json:"txHash"`
+	BlockNumber uint64         `json:"blockNumber"`
+	Sender      common.Address `json:"sender"`
+	Start       uint64         `json:"start"`    // index of the first Merkle node set by the transaction
+	NumElems    uint64         `json:"numElems"` // number of Merkle nodes set by the transaction
+	// error code of the SetEpochData event, the nodes of the transaction are ignored if it is not 0
+	Error uint64 `json:"error"`
+}
+
+// EpochDataMismatch is a value stored in the Ethash contract that differs from the locally computed epoch data.
+// Field is "fullSizeIn128Resolution", "branchDepth" or "merkleNodes", Index is the index of the Merkle node.
+type EpochDataMismatch struct {
+	Field    string      `json:"field"`
+	Index    uint64      `json:"index,omitempty"`
+	Stored   *big.Int    `json:"stored"`
+	Expected *big.Int    `json:"expected"`
+	TxHash   common.Hash `json:"txHash"` // the transaction that set the value
+}
+
+// EpochNodeRange is an inclusive range of Merkle node indexes.
+type EpochNodeRange struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+// EpochDataCheck is the result of CheckEpochData.
+type EpochDataCheck struct {
+	Chain         uint8          `json:"chain"`
+	EthashAddress common.Address `json:"ethashAddress"`
+	Epoch         uint64         `json:"epoch"`
+	Set           bool           `json:"set"` // isEpochDataSet of the contract
+	Transactions  []EpochDataTx  `json:"transactions"`
+	ExpectedNodes uint64         `json:"expectedNodes"`
+	StoredNodes   uint64         `json:"storedNodes"` // nodes set by the transactions, each index counted once
+	// nodes not set by any transaction
+	Missing    []EpochNodeRange    `json:"missing,omitempty"`
+	Mismatches []EpochDataMismatch `json:"mismatches,omitempty"`
+	// transactions emitting SetEpochData whose calldata is not a setEpochData call, e.g., calls through another
+	// contract, their nodes cannot be checked
+	Undecodable []common.Hash `json:"undecodable,omitempty"`
+}
+
+// Valid returns true if all nodes of the epoch were set with the expected values and the contract considers the epoch
+// data set.
+func (check EpochDataCheck) Valid() bool {
+	return check.Set && len(check.Missing) == 0 && len(check.Mismatches) == 0 && len(check.Undecodable) == 0
+}
+
+func (check EpochDataCheck) String() string {
+	if check.Valid() {
+		return fmt.Sprintf("epoch data of epoch %d is correct (%d nodes)", check.Epoch, check.StoredNodes)
+	}
+	var problems []string
+	if !check.Set {
+		problems = append(problems, "not set")
+	}
+	if len(check.Mismatches) > 0 {
+		problems = append(problems, fmt.Sprintf("%d wrong values", len(check.Mismatches)))
+	}
+	if len(check.Missing) > 0 {
+		problems = append(problems, fmt.Sprintf("%d of %d nodes missing", check.ExpectedNodes-check.StoredNodes, check.ExpectedNodes))
+	}
+	if len(check.Undecodable) > 0 {
+		problems = append(problems, fmt.Sprintf("%d undecodable transactions", len(check.Undecodable)))
+	}
+	return fmt.Sprintf("epoch data of epoch %d is INVALID: %s", check.Epoch, strings.Join(problems, ", "))
+}
+
+// CheckEpochData compares the epoch data stored in the Ethash contract of the chain with the expected epoch data,
+// e.g., computed by ethash.GenerateEpochData. The stored values are read from the setEpochData transactions emitting
+// SetEpochData events from fromBlock on (the deployment block of the Ethash contract is sufficient); if a node was set
+// several times, the last value counts.
+func (c Client) CheckEpochData(expected typedefs.EpochData, chain uint8, fromBlock uint64) (*EpochDataCheck, error) {
+	if err := c.checkEthash(chain); err != nil {
+		return nil, err
+	}
+	ethashAbi, err := abi.JSON(strings.NewReader(ethash.EthashABI))
+	if err != nil {
+		return nil, err
+	}
+
+	check := &EpochDataCheck{
+		Chain:         chain,
+		EthashAddress: c.chain(chain).ethashContractAddress,
+		Epoch:         expected.Epoch.Uint64(),
+		ExpectedNodes: uint64(len(expected.MerkleNodes)),
+	}
+	if check.Set, err = c.chain(chain).ethashContract.IsEpochDataSet(c.callOpts(), expected.Epoch); err != nil {
+		return nil, err
+	}
+
+	logs, err := c.epochDataLogs(ethashAbi, chain, fromBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	// the last value set for each node and parameter
+	type storedValue struct {
+		value  *big.Int
+		txHash common.Hash
+	}
+	nodes := make(map[uint64]storedValue)
+	var fullSize, branchDepth *storedValue
+	for _, vLog := range logs {
+		event := struct {
+			Error     *big.Int
+			ErrorInfo *big.Int
+		}{}
+		if err := ethashAbi.Unpack(&event, "SetEpochData", vLog.Data); err != nil {
+			return nil, err
+		}
+		tx, _, err := c.chain(chain).client.TransactionByHash(c.context(), vLog.TxHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve tx %s: %s", vLog.TxHash.Hex(), err)
+		}
+		args, err := decodeSetEpochData(ethashAbi, tx)
+		if err != nil {
+			check.Undecodable = append(check.Undecodable, vLog.TxHash)
+			continue
+		}
+		if args.Epoch.Cmp(expected.Epoch) != 0 {
+			continue
+		}
+
+		check.Transactions = append(check.Transactions, EpochDataTx{
+			TxHash:      vLog.TxHash,
+			BlockNumber: vLog.BlockNumber,
+			Sender:      common.BytesToAddress(vLog.Topics[1].Bytes()),
+			Start:       args.Start.Uint64(),
+			NumElems:    args.NumElems.Uint64(),
+			Error:       event.Error.Uint64(),
+		})
+		if event.Error.Sign() != 0 {
+			continue
+		}
+		fullSize = &storedValue{args.FullSizeIn128Resultion, vLog.TxHash}
+		branchDepth = &storedValue{args.BranchDepth, vLog.TxHash}
+		for i, node := range args.MerkleNodes {
+			if uint64(i) < args.NumElems.Uint64() {
+				nodes[args.Start.Uint64()+uint64(i)] = storedValue{node, vLog.TxHash}
+			}
+		}
+	}
+
+	if fullSize != nil && fullSize.value.Cmp(expected.FullSizeIn128Resolution) != 0 {
+		check.Mismatches = append(check.Mismatches, EpochDataMismatch{Field: "fullSizeIn128Resolution",
+			Stored: fullSize.value, Expected: expected.FullSizeIn128Resolution, TxHash: fullSize.txHash})
+	}
+	if branchDepth != nil && branchDepth.value.Cmp(expected.BranchDepth) != 0 {
+		check.Mismatches = append(check.Mismatches, EpochDataMismatch{Field: "branchDepth",
+			Stored: branchDepth.value, Expected: expected.BranchDepth, TxHash: branchDepth.txHash})
+	}
+	for i, node := range expected.MerkleNodes {
+		index := uint64(i)
+		stored, exists := nodes[index]
+		if !exists {
+			if n := len(check.Missing); n > 0 && check.Missing[n-1].To == index-1 {
+				check.Missing[n-1].To = index
+			} else {
+				check.Missing = append(check.Missing, EpochNodeRange{From: index, To: index})
+			}
+			continue
+		}
+		check.StoredNodes++
+		if stored.value.Cmp(node) != 0 {
+			check.Mismatches = append(check.Mismatches, EpochDataMismatch{Field: "merkleNodes", Index: index,
+				Stored: stored.value, Expected: node, TxHash: stored.txHash})
+		}
+	}
+	// nodes beyond the expected ones are never read, but show that the data was not computed for this DAG
+	var surplus []uint64
+	for index := range nodes {
+		if index >= check.ExpectedNodes {
+			surplus = append(surplus, index)
+		}
+	}
+	sort.Slice(surplus, func(i, j int) bool { return surplus[i] < surplus[j] })
+	for _, index := range surplus {
+		check.Mismatches = append(check.Mismatches, EpochDataMismatch{Field: "merkleNodes", Index: index,
+			Stored: nodes[index].value, Expected: new(big.Int), TxHash: nodes[index].txHash})
+	}
+	return check, nil
+}
+
+// setEpochDataArgs are the arguments of a setEpochData call
+type setEpochDataArgs struct {
+	Epoch                  *big.Int
+	FullSizeIn128Resultion *big.Int
+	BranchDepth            *big.Int
+	MerkleNodes            []*big.Int
+	Start                  *big.Int
+	NumElems               *big.Int
+}
+
+func decodeSetEpochData(ethashAbi abi.ABI, tx *types.Transaction) (*setEpochDataArgs, error) {
+	method := ethashAbi.Methods["setEpochData"]
+	data := tx.Data()
+	if len(data) < 4 || !bytes.Equal(data[:4], method.ID()) {
+		return nil, fmt.Errorf("tx %s does not call setEpochData", tx.Hash().Hex())
+	}
+	args := &setEpochDataArgs{}
+	if err := method.Inputs.Unpack(args, data[4:]); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// epochDataLogs returns the SetEpochData events of the Ethash contract of the chain from fromBlock on, in the order
+// they were emitted
+func (c Client) epochDataLogs(ethashAbi abi.ABI, chain uint8, fromBlock uint64) ([]types.Log, error) {
+	header, err := c.chain(chain).client.HeaderByNumber(c.context(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var logs []types.Log
+	for start := fromBlock; start <= header.Number.Uint64(); start += eventScanBatchSize {
+		end := start + eventScanBatchSize - 1
+		if end > header.Number.Uint64() {
+			end = header.Number.Uint64()
+		}
+		c.progressf("Scanning blocks %d to %d for epoch data ...\n", start, end)
+		batch, err := c.chain(chain).filterLogs(c.context(), ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: []common.Address{c.chain(chain).ethashContractAddress},
+			Topics:    [][]common.Hash{{ethashAbi.Events["SetEpochData"].ID()}},
+		})
+		if err != nil {
+			return logs, err
+		}
+		for _, vLog := range batch {
+			if !vLog.Removed && len(vLog.Topics) == 2 {
+				logs = append(logs, vLog)
+			}
+		}
+	}
+	return logs, nil
+}
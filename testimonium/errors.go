@@ -0,0 +1,48 @@
+// This file contains the errors returned if the client is used with chains or contracts that are not configured.
+
+package testimonium
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnknownChain is returned if a chain id is not contained in the chains config or the chain is not connected.
+	ErrUnknownChain = errors.New("chain does not exist")
+	// ErrNoTestimoniumContract is returned if no ETH Relay contract is configured for a chain.
+	ErrNoTestimoniumContract = errors.New("no ETH Relay contract configured")
+	// ErrNoEthashContract is returned if no Ethash contract is configured for a chain.
+	ErrNoEthashContract = errors.New("no Ethash contract configured")
+)
+
+// checkChain dials the chain on first use, it returns ErrUnknownChain if the chain is not configured or cannot be
+// connected
+func (c Client) checkChain(chain uint8) error {
+	if !c.connectChain(chain, true) {
+		return fmt.Errorf("%w: %d", ErrUnknownChain, chain)
+	}
+	return nil
+}
+
+// checkTestimonium additionally returns ErrNoTestimoniumContract if the chain has no ETH Relay contract
+func (c Client) checkTestimonium(chain uint8) error {
+	if err := c.checkChain(chain); err != nil {
+		return err
+	}
+	if c.chain(chain).testimoniumContract == nil {
+		return fmt.Errorf("%w: chain %d", ErrNoTestimoniumContract, chain)
+	}
+	return nil
+}
+
+// checkEthash additionally returns ErrNoEthashContract if the chain has no Ethash contract
+func (c Client) checkEthash(chain uint8) error {
+	if err := c.checkChain(chain); err != nil {
+		return err
+	}
+	if c.chain(chain).ethashContract == nil {
+		return fmt.Errorf("%w: chain %d", ErrNoEthashContract, chain)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+// This file contains functions to retrieve and decode the events emitted by the Testimonium contract.
+
+package testimonium
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// number of blocks that are queried with a single eth_getLogs request
+const eventScanBatchSize = 5000
+
+// RelayEvent is a decoded event emitted by the Testimonium contract.
+type RelayEvent struct {
+	Name        string            `json:"event"`
+	BlockNumber uint64            `json:"blockNumber"`
+	BlockHash   common.Hash       `json:"blockHash"`
+	TxHash      common.Hash       `json:"txHash"`
+	LogIndex    uint              `json:"logIndex"`
+	Fields      map[string]string `json:"fields"`
+}
+
+func (event RelayEvent) String() string {
+	fields := make([]string, 0, len(event.Fields))
+	for k, v := range event.Fields {
+		fields = append(fields, fmt.Sprintf("%s: %s", k, v))
+	}
+	sort.Strings(fields)
+	return fmt.Sprintf("%sEvent: { %s }", event.Name, strings.Join(fields, ", "))
+}
+
+// Events returns all events emitted by the Testimonium contract on the specified chain between fromBlock and
+// toBlock (inclusive). If toBlock is nil, events up to the most recent block are returned.
+func (c Client) Events(chain uint8, fromBlock uint64, toBlock *big.Int) ([]RelayEvent, error) {
+	return c.EventsContext(c.context(), chain, fromBlock, toBlock)
+}
+
+// EventsContext is like Events, but stops scanning when the context is done. The events of the blocks scanned so far
+// are returned with a *ScanInterruptedError, whose cursor is the fromBlock to resume the scan with.
+func (c Client) EventsContext(ctx context.Context, chain uint8, fromBlock uint64, toBlock *big.Int) ([]RelayEvent, error) {
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, err
+	}
+
+	testimoniumAbi, err := c.chain(chain).testimoniumAbi()
+	if err != nil {
+		return nil, err
+	}
+
+	lastBlock := toBlock
+	if lastBlock == nil {
+		header, err := c.chain(chain).client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		lastBlock = header.Number
+	}
+
+	var events []RelayEvent
+	for start := fromBlock; start <= lastBlock.Uint64(); start += eventScanBatchSize {
+		if err := scanInterrupted(ctx, chain, start, lastBlock.Uint64()); err != nil {
+			return events, err
+		}
+		end := start + eventScanBatchSize - 1
+		if end > lastBlock.Uint64() {
+			end = lastBlock.Uint64()
+		}
+
+		logs, err := c.chain(chain).filterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: []common.Address{c.chain(chain).testimoniumContractAddress},
+		})
+		if interrupted := scanInterrupted(ctx, chain, start, lastBlock.Uint64()); interrupted != nil {
+			return events, interrupted
+		}
+		if err != nil {
+			return events, err
+		}
+
+		for _, vLog := range logs {
+			event, err := decodeEvent(testimoniumAbi, vLog)
+			if err != nil {
+				return events, err
+			}
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// filterLogs requests the logs matching the query, failed requests are repeated with the scan retry policy
+func (chain *Chain) filterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	err := chain.retryPolicy(RETRY_SCAN).Do(ctx, func() error {
+		var err error
+		logs, err = chain.client.FilterLogs(ctx, query)
+		return err
+	})
+	return logs, err
+}
+
+func decodeEvent(contractAbi abi.ABI, vLog types.Log) (RelayEvent, error) {
+	if len(vLog.Topics) == 0 {
+		return RelayEvent{}, fmt.Errorf("anonymous event in tx %s cannot be decoded", vLog.TxHash.String())
+	}
+
+	eventAbi, err := contractAbi.EventByID(vLog.Topics[0])
+	if err != nil {
+		return RelayEvent{}, err
+	}
+
+	// all parameters of the Testimonium events are non-indexed and can be read from the data field
+	values := make(map[string]interface{})
+	if err := contractAbi.UnpackIntoMap(values, eventAbi.Name, vLog.Data); err != nil {
+		return RelayEvent{}, err
+	}
+
+	fields := make(map[string]string, len(values))
+	for k, v := range values {
+		fields[k] = formatEventValue(v)
+	}
+
+	return RelayEvent{
+		Name:        eventAbi.Name,
+		BlockNumber: vLog.BlockNumber,
+		BlockHash:   vLog.BlockHash,
+		TxHash:      vLog.TxHash,
+		LogIndex:    vLog.Index,
+		Fields:      fields,
+	}, nil
+}
+
+func formatEventValue(value interface{}) string {
+	switch v := value.(type) {
+	case [32]byte:
+		return common.BytesToHash(v[:]).String()
+	case common.Address:
+		return v.String()
+	case *big.Int:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,145 @@
+// This file contains the archive of dispute evidence. Every dispute sent by the client is recorded with everything
+// needed to audit or publish it later: both RLP headers, the DAG lookups and their Merkle proofs, the transactions and
+// the events the dispute emitted.
+
+package testimonium
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// DisputeEvidence is the archived evidence of a dispute.
+type DisputeEvidence struct {
+	Chain           uint8          `json:"chain"`
+	ContractAddress common.Address `json:"contractAddress"`
+	Disputer        common.Address `json:"disputer"`
+	Time            time.Time      `json:"time"` // when the dispute was sent
+	BlockHash       common.Hash    `json:"blockHash"`
+	BlockNumber     uint64         `json:"blockNumber"`
+	RlpHeader       hexutil.Bytes  `json:"rlpHeader"`
+	RlpParentHeader hexutil.Bytes  `json:"rlpParentHeader"`
+	// the submission of the disputed header, zero if it is not contained in the event index
+	SubmitTx         common.Hash     `json:"submitTx,omitempty"`
+	Submitter        *common.Address `json:"submitter,omitempty"`
+	DataSetLookup    []*big.Int      `json:"dataSetLookup"`    // DAG elements accessed by the proof-of-work computation
+	WitnessForLookup []*big.Int      `json:"witnessForLookup"` // Merkle proofs of the DAG elements
+	DisputeTx        common.Hash     `json:"disputeTx,omitempty"`
+	Private          bool            `json:"private"` // sent through a private relay
+	Result           *TxResult       `json:"result,omitempty"`
+	// return code and error info of the PoWValidationResult event, nil if the event was not emitted
+	ReturnCode *uint64  `json:"returnCode,omitempty"`
+	ErrorInfo  *big.Int `json:"errorInfo,omitempty"`
+	Removed    bool     `json:"removed"`         // the RemoveBranch event was emitted
+	Error      string   `json:"error,omitempty"` // the error of the dispute, if it failed
+}
+
+// WithDisputeArchive archives the evidence of every dispute as JSON file in the directory, named after the chain, the
+// disputed block and the time of the dispute.
+func WithDisputeArchive(dir string) ClientOption {
+	return func(client *Client) error {
+		client.disputeArchiveDir = dir
+		return nil
+	}
+}
+
+// DisputeEvidencePath returns the path of the evidence file of a dispute in the archive directory.
+func DisputeEvidencePath(dir string, evidence *DisputeEvidence) string {
+	return filepath.Join(dir, fmt.Sprintf("dispute-%d-%s-%s.json", evidence.Chain, evidence.BlockHash.Hex(),
+		evidence.Time.UTC().Format("20060102T150405Z")))
+}
+
+// ReadDisputeArchive returns the evidence of all archived disputes in the directory, oldest first.
+func ReadDisputeArchive(dir string) ([]*DisputeEvidence, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var archive []*DisputeEvidence
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), "dispute-") || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		evidence := &DisputeEvidence{}
+		if err := json.Unmarshal(data, evidence); err != nil {
+			return nil, fmt.Errorf("corrupt dispute evidence %s: %s", file.Name(), err)
+		}
+		archive = append(archive, evidence)
+	}
+	sort.Slice(archive, func(i, j int) bool { return archive[i].Time.Before(archive[j].Time) })
+	return archive, nil
+}
+
+// newDisputeEvidence collects the evidence known before the dispute is sent
+func (c Client) newDisputeEvidence(witness DisputeWitness, chain uint8) *DisputeEvidence {
+	c = c.as(OPERATOR_DISPUTER)
+	evidence := &DisputeEvidence{
+		Chain:            chain,
+		Disputer:         c.account,
+		Time:             time.Now().UTC(),
+		BlockHash:        witness.BlockHash,
+		BlockNumber:      witness.BlockNumber,
+		RlpHeader:        witness.RlpHeader,
+		RlpParentHeader:  witness.RlpParentHeader,
+		DataSetLookup:    witness.DataSetLookup,
+		WitnessForLookup: witness.WitnessForLookup,
+	}
+	if c.chain(chain) == nil {
+		return evidence
+	}
+	evidence.ContractAddress = c.chain(chain).testimoniumContractAddress
+	evidence.Private = c.chain(chain).privateRelay != nil
+	if c.indexDir != "" {
+		if index, err := OpenEventIndex(c.indexDir, chain, evidence.ContractAddress); err == nil {
+			if record, exists := index.Lookup(witness.BlockHash); exists {
+				submitter := record.Submitter
+				evidence.SubmitTx, evidence.Submitter = record.TxHash, &submitter
+			}
+		}
+	}
+	return evidence
+}
+
+// archiveDispute writes the evidence to the archive directory, failures only cause a warning as the dispute was sent
+// anyway
+func (c Client) archiveDispute(evidence *DisputeEvidence, result *TxResult, err error) {
+	if c.disputeArchiveDir == "" {
+		return
+	}
+	if result != nil {
+		evidence.Result = result
+	}
+	if err != nil {
+		evidence.Error = err.Error()
+	}
+
+	path := DisputeEvidencePath(c.disputeArchiveDir, evidence)
+	data, err := json.MarshalIndent(evidence, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(c.disputeArchiveDir, 0755); err == nil {
+			err = ioutil.WriteFile(path, data, 0644)
+		}
+	}
+	if err != nil {
+		c.progressf("WARNING: Cannot archive the evidence of the dispute of block %s: %s\n", evidence.BlockHash.Hex(), err)
+		return
+	}
+	c.progressf("Dispute evidence archived in %s\n", path)
+}
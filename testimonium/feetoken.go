@@ -0,0 +1,265 @@
+// This file contains the support for verification fees paid in an ERC-20 token instead of ether. Future versions of the
+// ETH Relay contract may collect the fee with transferFrom, the verifier then approves the contract to spend the fee
+// and sends the verification without value. The token is the "feetoken" entry of the chain config or is read from the
+// contract if it exposes getVerificationFeeToken.
+
+package testimonium
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrInsufficientFeeTokens is returned if the account holds fewer fee tokens than the verifications require.
+var ErrInsufficientFeeTokens = errors.New("insufficient fee tokens")
+
+// FEE_TOKEN_APPROVAL_VERIFICATIONS is the number of verifications the contract is approved to collect the fee for
+// when the allowance of the account does not cover the next verification, so not every verification needs an approval.
+const FEE_TOKEN_APPROVAL_VERIFICATIONS = 10
+
+const erc20ABI = `[
+	{"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"stateMutability":"view","type":"function"},
+	{"inputs":[{"name":"account","type":"address"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},
+	{"inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],"name":"allowance","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},
+	{"inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}],"name":"approve","outputs":[{"name":"","type":"bool"}],"stateMutability":"nonpayable","type":"function"}
+]`
+
+const testimoniumFeeTokenABI = `[
+	{"inputs":[],"name":"getVerificationFeeToken","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"}
+]`
+
+// erc20TransferEventId is the topic of the Transfer event of ERC-20 tokens
+var erc20TransferEventId = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// VerificationFee is the fee of a verification, in wei or in the smallest unit of the fee token.
+type VerificationFee struct {
+	Amount   *big.Int       `json:"amount"`
+	Token    common.Address `json:"token"` // zero address if the fee is paid in ether
+	Symbol   string         `json:"symbol"`
+	Decimals uint8          `json:"decimals"`
+}
+
+// IsToken reports whether the fee is paid in an ERC-20 token.
+func (fee VerificationFee) IsToken() bool {
+	return fee.Token != (common.Address{})
+}
+
+// String formats the amount in whole units of ether or of the token, e.g., "0.5 ETH".
+func (fee VerificationFee) String() string {
+	return FormatTokenAmount(fee.Amount, fee.Decimals) + " " + fee.Symbol
+}
+
+// FormatTokenAmount formats an amount of the smallest unit of a token in whole units without trailing zeros.
+func FormatTokenAmount(amount *big.Int, decimals uint8) string {
+	if amount == nil {
+		return "0"
+	}
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	whole, fraction := new(big.Int).QuoRem(new(big.Int).Abs(amount), unit, new(big.Int))
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+	}
+	if fraction.Sign() == 0 {
+		return sign + whole.String()
+	}
+	digits := fmt.Sprintf("%0*s", int(decimals), fraction.String())
+	return sign + whole.String() + "." + strings.TrimRight(digits, "0")
+}
+
+// FeeToken returns the ERC-20 token the verification fee of the ETH Relay contract on the chain is paid in, the zero
+// address if it is paid in ether.
+func (c Client) FeeToken(chain uint8) (common.Address, error) {
+	if err := c.checkTestimonium(chain); err != nil {
+		return common.Address{}, err
+	}
+	if c.chain(chain).feeToken != (common.Address{}) {
+		return c.chain(chain).feeToken, nil
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(testimoniumFeeTokenABI))
+	if err != nil {
+		return common.Address{}, err
+	}
+	address := c.chain(chain).testimoniumContractAddress
+	code, err := c.chain(chain).client.CodeAt(c.context(), address, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if !codeHasSelector(code, parsed.Methods["getVerificationFeeToken"].ID()) {
+		return common.Address{}, nil
+	}
+	client := c.chain(chain).client
+	var token common.Address
+	err = bind.NewBoundContract(address, parsed, client, client, client).Call(nil, &token, "getVerificationFeeToken")
+	return token, err
+}
+
+// VerificationFee returns the fee required by the ETH Relay contract on the chain together with the token it is paid
+// in.
+func (c Client) VerificationFee(chain uint8) (VerificationFee, error) {
+	token, err := c.FeeToken(chain)
+	if err != nil {
+		return VerificationFee{}, err
+	}
+	amount, err := c.chain(chain).requiredVerificationFee()
+	if err != nil {
+		return VerificationFee{}, err
+	}
+	fee := VerificationFee{Amount: amount, Token: token, Symbol: "ETH", Decimals: 18}
+	if !fee.IsToken() {
+		return fee, nil
+	}
+
+	contract, err := c.feeTokenContract(chain, token)
+	if err != nil {
+		return VerificationFee{}, err
+	}
+	if err := contract.Call(nil, &fee.Decimals, "decimals"); err != nil {
+		return VerificationFee{}, fmt.Errorf("fee token %s: %s", token.Hex(), err)
+	}
+	// the symbol is optional in ERC-20
+	if err := contract.Call(nil, &fee.Symbol, "symbol"); err != nil || fee.Symbol == "" {
+		fee.Symbol = token.Hex()
+	}
+	return fee, nil
+}
+
+// FeeTokenBalance returns the fee tokens held by the account on the chain.
+func (c Client) FeeTokenBalance(chain uint8) (*big.Int, error) {
+	c = c.as(OPERATOR_VERIFIER)
+	contract, err := c.requireFeeTokenContract(chain)
+	if err != nil {
+		return nil, err
+	}
+	balance := new(big.Int)
+	err = contract.Call(nil, &balance, "balanceOf", c.account)
+	return balance, err
+}
+
+// FeeTokenAllowance returns the fee tokens the ETH Relay contract on the chain may collect from the account.
+func (c Client) FeeTokenAllowance(chain uint8) (*big.Int, error) {
+	c = c.as(OPERATOR_VERIFIER)
+	contract, err := c.requireFeeTokenContract(chain)
+	if err != nil {
+		return nil, err
+	}
+	allowance := new(big.Int)
+	err = contract.Call(nil, &allowance, "allowance", c.account, c.chain(chain).testimoniumContractAddress)
+	return allowance, err
+}
+
+// ApproveFeeToken allows the ETH Relay contract on the chain to collect amount fee tokens from the account. The
+// approval replaces the current allowance, an amount of zero revokes it.
+func (c Client) ApproveFeeToken(chain uint8, amount *big.Int) (*TxResult, error) {
+	c = c.as(OPERATOR_VERIFIER)
+	contract, err := c.requireFeeTokenContract(chain)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := prepareTransaction(c.context(), c.account, c.privateKey, c.chain(chain), big.NewInt(0))
+	if err != nil {
+		return nil, err
+	}
+	tx, err := contract.Transact(auth, "approve", c.chain(chain).testimoniumContractAddress, amount)
+	if err != nil {
+		return nil, err
+	}
+	c.progressf("Tx submitted: %s (approval of %s fee tokens)\n", tx.Hash().Hex(), amount)
+
+	receipt, err := awaitTxReceipt(c.context(), c.chain(chain), tx.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status == 0 {
+		reason := getFailureReason(c.chain(chain).client, c.account, tx, receipt.BlockNumber)
+		return nil, fmt.Errorf("tx failed: %s", reason)
+	}
+	return newTxResult(tx, receipt), nil
+}
+
+// verificationValue returns the value of verification transactions paying the fee. If the fee is paid in a token, the
+// value is zero and the contract is approved to collect the fee of the verifications unless the allowance covers them.
+func (c Client) verificationValue(feeInWei *big.Int, verifications int, chain uint8) (*big.Int, error) {
+	token, err := c.FeeToken(chain)
+	if err != nil {
+		return nil, err
+	}
+	if token == (common.Address{}) || feeInWei == nil || feeInWei.Sign() == 0 {
+		return feeInWei, nil
+	}
+
+	required := new(big.Int).Mul(feeInWei, big.NewInt(int64(verifications)))
+	balance, err := c.FeeTokenBalance(chain)
+	if err != nil {
+		return nil, err
+	}
+	if balance.Cmp(required) < 0 {
+		return nil, fmt.Errorf("%w: %s of %s (token %s)", ErrInsufficientFeeTokens, balance, required, token.Hex())
+	}
+	allowance, err := c.FeeTokenAllowance(chain)
+	if err != nil {
+		return nil, err
+	}
+	if allowance.Cmp(required) >= 0 {
+		return big.NewInt(0), nil
+	}
+
+	approval := new(big.Int).Mul(feeInWei, big.NewInt(FEE_TOKEN_APPROVAL_VERIFICATIONS))
+	if approval.Cmp(required) < 0 {
+		approval = required
+	}
+	if approval.Cmp(balance) > 0 {
+		approval = balance
+	}
+	c.progressf("Approving the ETH Relay contract on chain %d to collect %s fee tokens\n", chain, approval)
+	if _, err := c.ApproveFeeToken(chain, approval); err != nil {
+		return nil, fmt.Errorf("approval of the fee token: %s", err)
+	}
+	return big.NewInt(0), nil
+}
+
+// requireFeeTokenContract returns the binding of the chain's fee token, it fails if the fee is paid in ether
+func (c Client) requireFeeTokenContract(chain uint8) (*bind.BoundContract, error) {
+	token, err := c.FeeToken(chain)
+	if err != nil {
+		return nil, err
+	}
+	if token == (common.Address{}) {
+		return nil, fmt.Errorf("the verification fee on chain %d is paid in ether", chain)
+	}
+	return c.feeTokenContract(chain, token)
+}
+
+func (c Client) feeTokenContract(chain uint8, token common.Address) (*bind.BoundContract, error) {
+	parsed, err := abi.JSON(strings.NewReader(erc20ABI))
+	if err != nil {
+		return nil, err
+	}
+	client := c.chain(chain).client
+	return bind.NewBoundContract(token, parsed, client, client, client), nil
+}
+
+// feeTokenTransfers returns the fee tokens transferred by the sender in the transaction of the receipt
+func feeTokenTransfers(receipt *types.Receipt, token common.Address, sender common.Address) *big.Int {
+	amount := new(big.Int)
+	for _, vLog := range receipt.Logs {
+		if vLog.Address != token || len(vLog.Topics) != 3 || vLog.Topics[0] != erc20TransferEventId {
+			continue
+		}
+		if common.BytesToAddress(vLog.Topics[1].Bytes()) != sender || len(vLog.Data) < 32 {
+			continue
+		}
+		amount.Add(amount, new(big.Int).SetBytes(vLog.Data[:32]))
+	}
+	return amount
+}
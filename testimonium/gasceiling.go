@@ -0,0 +1,177 @@
+// This file contains the gas price ceiling of the live mode. While the gas price of the destination chain exceeds the
+// ceiling, submissions are paused instead of paying the fees of a price spike. The headers that would have been relayed
+// in the meantime are withheld and submitted as soon as the gas price falls again.
+
+package testimonium
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// GAS_CEILING_CHECK_INTERVAL is the default interval the gas price is checked at while submissions are paused.
+const GAS_CEILING_CHECK_INTERVAL = 30 * time.Second
+
+// GasCeiling configures when the live mode pauses and resumes submissions. Different prices for pausing and resuming
+// keep a gas price hovering around the ceiling from pausing and resuming with every block.
+type GasCeiling struct {
+	MaxGasPrice    *big.Int      // submissions are paused above this gas price
+	ResumeGasPrice *big.Int      // paused submissions resume at or below this gas price, MaxGasPrice if nil
+	CheckInterval  time.Duration // the gas price is also checked at this interval while paused, not only with new blocks
+}
+
+// WithGasCeiling pauses the submissions of SubmitHeaderLive while the gas price of the destination chain exceeds the
+// ceiling. As the missing ancestors of a relayed header are submitted along with it, the deposited stake has to suffice
+// for the headers withheld during a pause.
+func WithGasCeiling(ceiling GasCeiling) ClientOption {
+	return func(client *Client) error {
+		if ceiling.ResumeGasPrice == nil || ceiling.ResumeGasPrice.Cmp(ceiling.MaxGasPrice) > 0 {
+			ceiling.ResumeGasPrice = ceiling.MaxGasPrice
+		}
+		if ceiling.CheckInterval <= 0 {
+			ceiling.CheckInterval = GAS_CEILING_CHECK_INTERVAL
+		}
+		client.gasCeiling = &ceiling
+		return nil
+	}
+}
+
+// gasPause is the state of the gas price ceiling in a run of the live mode, it is nil without a ceiling
+type gasPause struct {
+	ceiling  GasCeiling
+	paused   bool
+	since    time.Time
+	withheld *types.Header // most recent header the relay policy selected while paused
+}
+
+func (c Client) newGasPause() *gasPause {
+	if c.gasCeiling == nil {
+		return nil
+	}
+	return &gasPause{ceiling: *c.gasCeiling}
+}
+
+// recheck returns a channel delivering the time the gas price is checked again, nil (blocking forever) unless a paused
+// run withholds a header
+func (pause *gasPause) recheck() <-chan time.Time {
+	if pause == nil || !pause.paused || pause.withheld == nil {
+		return nil
+	}
+	return time.After(pause.ceiling.CheckInterval)
+}
+
+// withheldHeader returns the header withheld during the pause, nil if there is none
+func (pause *gasPause) withheldHeader() *types.Header {
+	if pause == nil {
+		return nil
+	}
+	return pause.withheld
+}
+
+// gateGasCeiling decides which header is relayed after the relay policy decided about the header: the header itself,
+// the header withheld during a pause that ended, or none. Headers selected by the policy while paused are withheld.
+func (c Client) gateGasCeiling(pause *gasPause, header *types.Header, relay bool, destinationChain uint8) (*types.Header, error) {
+	if pause == nil {
+		if relay {
+			return header, nil
+		}
+		return nil, nil
+	}
+	if !relay && pause.withheld == nil {
+		return nil, nil
+	}
+
+	paused, err := c.updateGasPause(pause, destinationChain)
+	if err != nil {
+		return nil, err
+	}
+	if paused {
+		if relay && (pause.withheld == nil || header.Number.Cmp(pause.withheld.Number) > 0) {
+			pause.withheld = header
+			c.liveMonitor.headerWithheld()
+			c.progressf("Withholding block %s until the gas price falls\n", header.Number)
+		}
+		return nil, nil
+	}
+
+	// the header extends the withheld header, so the withheld one is submitted along with it
+	withheld := pause.withheld
+	pause.withheld = nil
+	if relay {
+		return header, nil
+	}
+	return withheld, nil
+}
+
+// updateGasPause compares the gas price of the destination chain with the ceiling and returns whether submissions are
+// paused
+func (c Client) updateGasPause(pause *gasPause, destinationChain uint8) (bool, error) {
+	gasPrice, err := c.chain(destinationChain).client.SuggestGasPrice(c.context())
+	if err != nil {
+		return pause.paused, err
+	}
+
+	switch {
+	case !pause.paused && gasPrice.Cmp(pause.ceiling.MaxGasPrice) > 0:
+		pause.paused = true
+		pause.since = time.Now()
+		c.liveMonitor.gasPaused(gasPrice)
+		c.progressf("Pausing submissions to chain %d: gas price %s gwei above ceiling of %s gwei\n", destinationChain,
+			weiToGwei(gasPrice), weiToGwei(pause.ceiling.MaxGasPrice))
+	case pause.paused && gasPrice.Cmp(pause.ceiling.ResumeGasPrice) <= 0:
+		pause.paused = false
+		duration := time.Since(pause.since).Round(time.Second)
+		c.liveMonitor.gasResumed(gasPrice, duration)
+		c.progressf("Resuming submissions to chain %d after %s: gas price %s gwei\n", destinationChain, duration, weiToGwei(gasPrice))
+	default:
+		c.liveMonitor.gasPriceChecked(gasPrice)
+	}
+	return pause.paused, nil
+}
+
+// gasPaused records the start of a pause in the accounting of the live mode, the monitor may be nil
+func (m *LiveMonitor) gasPaused(gasPrice *big.Int) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.gasPrice = gasPrice
+	m.gasPausedSince = time.Now()
+	m.gasPauses++
+}
+
+// gasResumed records the end of a pause, the monitor may be nil
+func (m *LiveMonitor) gasResumed(gasPrice *big.Int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.gasPrice = gasPrice
+	m.gasPausedSince = time.Time{}
+	m.gasPausedTotal += duration
+	m.withheldHeaders = 0
+}
+
+// gasPriceChecked records the gas price of a check that did not change the state, the monitor may be nil
+func (m *LiveMonitor) gasPriceChecked(gasPrice *big.Int) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.gasPrice = gasPrice
+}
+
+// headerWithheld records a header selected by the relay policy during a pause, the monitor may be nil
+func (m *LiveMonitor) headerWithheld() {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.withheldHeaders++
+}
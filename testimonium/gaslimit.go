@@ -0,0 +1,194 @@
+// This file contains the gas limits of the transactions sent by the client. By default, the bindings estimate the gas
+// limit of every transaction. The estimates of some providers fail or are too low for very large transactions (e.g.,
+// disputes with their DAG witnesses), so the gas limit can be fixed, the estimate multiplied or a fallback used per
+// operation.
+
+package testimonium
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+)
+
+// operations of the "gaslimits" entry of a chain config, GAS_OP_DEFAULT applies to all transactions of operations
+// without an entry
+const (
+	GAS_OP_SUBMIT         = "submit"
+	GAS_OP_DISPUTE        = "dispute"
+	GAS_OP_VERIFY         = "verify"
+	GAS_OP_SET_EPOCH_DATA = "setepochdata"
+	GAS_OP_DEFAULT        = "default"
+)
+
+// contract methods of the operations
+var gasOperations = map[string]string{
+	"submitBlock":        GAS_OP_SUBMIT,
+	"submitBlockBatch":   GAS_OP_SUBMIT,
+	"disputeBlockHeader": GAS_OP_DISPUTE,
+	"verifyTransaction":  GAS_OP_VERIFY,
+	"verifyReceipt":      GAS_OP_VERIFY,
+	"verifyState":        GAS_OP_VERIFY,
+	"setEpochData":       GAS_OP_SET_EPOCH_DATA,
+}
+
+// GasLimitConfig configures the gas limit of the transactions of an operation.
+type GasLimitConfig struct {
+	Limit      uint64  // used without estimation if set
+	Multiplier float64 // applied to the estimate (capped at the block gas limit), 1 if 0
+	Fallback   uint64  // used if the estimation fails, the estimation error is returned if 0
+}
+
+// GasLimits are the gas limit configs of a chain by operation (e.g., GAS_OP_DISPUTE).
+type GasLimits map[string]GasLimitConfig
+
+// WithGasLimits sets the gas limits of the transactions sent to the chain, overriding the "gaslimits" entry of the
+// chain config.
+func WithGasLimits(chain uint8, limits GasLimits) ClientOption {
+	return func(client *Client) error {
+		for operation := range limits {
+			if err := checkGasOperation(operation); err != nil {
+				return err
+			}
+		}
+		if client.gasLimits == nil {
+			client.gasLimits = make(map[uint8]GasLimits)
+		}
+		client.gasLimits[chain] = limits
+		return nil
+	}
+}
+
+func checkGasOperation(operation string) error {
+	switch operation {
+	case GAS_OP_SUBMIT, GAS_OP_DISPUTE, GAS_OP_VERIFY, GAS_OP_SET_EPOCH_DATA, GAS_OP_DEFAULT:
+		return nil
+	default:
+		return fmt.Errorf("unknown gas limit operation '%s' (%s)", operation, strings.Join([]string{GAS_OP_SUBMIT,
+			GAS_OP_DISPUTE, GAS_OP_VERIFY, GAS_OP_SET_EPOCH_DATA, GAS_OP_DEFAULT}, ", "))
+	}
+}
+
+// gasLimitsFromConfig reads the "gaslimits" entry of a chain config, e.g.,
+//
+//	gaslimits:
+//	    submit:
+//	        limit: 300000
+//	    dispute:
+//	        multiplier: 1.5
+//	        fallback: 8000000
+//
+// Nil is returned if there is no entry.
+func gasLimitsFromConfig(chainConfig map[string]interface{}) (GasLimits, error) {
+	entry, ok := chainConfig["gaslimits"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	limits := make(GasLimits)
+	for operation, value := range entry {
+		if err := checkGasOperation(operation); err != nil {
+			return nil, err
+		}
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("illegal gas limit config of operation %s: %v", operation, value)
+		}
+
+		var config GasLimitConfig
+		var err error
+		if limit, exists := fields["limit"]; exists {
+			if config.Limit, err = strconv.ParseUint(fmt.Sprint(limit), 10, 64); err != nil {
+				return nil, fmt.Errorf("illegal gas limit of operation %s: %v", operation, limit)
+			}
+		}
+		if fallback, exists := fields["fallback"]; exists {
+			if config.Fallback, err = strconv.ParseUint(fmt.Sprint(fallback), 10, 64); err != nil {
+				return nil, fmt.Errorf("illegal fallback gas limit of operation %s: %v", operation, fallback)
+			}
+		}
+		if multiplier, exists := fields["multiplier"]; exists {
+			config.Multiplier, err = strconv.ParseFloat(fmt.Sprint(multiplier), 64)
+			if err != nil || config.Multiplier < 1 {
+				return nil, fmt.Errorf("illegal gas multiplier of operation %s: %v (has to be at least 1)", operation, multiplier)
+			}
+		}
+		limits[operation] = config
+	}
+	return limits, nil
+}
+
+// gasLimitConfig returns the config of the operation, the default config if the operation has none
+func (limits GasLimits) gasLimitConfig(operation string) (GasLimitConfig, bool) {
+	if config, exists := limits[operation]; exists {
+		return config, true
+	}
+	config, exists := limits[GAS_OP_DEFAULT]
+	return config, exists
+}
+
+// gasOperation returns the operation of the call to one of the chain's contracts, GAS_OP_DEFAULT if the call belongs
+// to no operation
+func (chain *Chain) gasOperation(msg ethereum.CallMsg) string {
+	if msg.To == nil || len(msg.Data) < 4 {
+		return GAS_OP_DEFAULT
+	}
+
+	var contractAbi abi.ABI
+	var err error
+	switch *msg.To {
+	case chain.testimoniumContractAddress:
+		contractAbi, err = chain.testimoniumAbi()
+	case chain.ethashContractAddress:
+		contractAbi, err = abi.JSON(strings.NewReader(ethash.EthashABI))
+	default:
+		return GAS_OP_DEFAULT
+	}
+	if err != nil {
+		return GAS_OP_DEFAULT
+	}
+	method, err := contractAbi.MethodById(msg.Data[:4])
+	if err != nil {
+		return GAS_OP_DEFAULT
+	}
+	if operation, exists := gasOperations[method.Name]; exists {
+		return operation
+	}
+	return GAS_OP_DEFAULT
+}
+
+// EstimateGas is called by the bindings for transactions without gas limit. It applies the gas limit config of the
+// transaction's operation to the estimate of the node.
+func (b relayBackend) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	operation := b.chain.gasOperation(msg)
+	config, exists := b.chain.gasLimits.gasLimitConfig(operation)
+	if !exists {
+		return b.Client.EstimateGas(ctx, msg)
+	}
+	if config.Limit != 0 {
+		return config.Limit, nil
+	}
+
+	estimate, err := b.Client.EstimateGas(ctx, msg)
+	if err != nil {
+		if config.Fallback == 0 {
+			return 0, err
+		}
+		b.progressf("WARNING: Gas estimation of %s transaction failed (%s), using the gas limit %d\n", operation, err, config.Fallback)
+		return config.Fallback, nil
+	}
+	if config.Multiplier <= 1 {
+		return estimate, nil
+	}
+
+	limit := uint64(float64(estimate) * config.Multiplier)
+	if head, err := b.Client.HeaderByNumber(ctx, nil); err == nil && limit > head.GasLimit {
+		limit = head.GasLimit
+	}
+	return limit, nil
+}
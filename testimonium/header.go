@@ -0,0 +1,158 @@
+// This file contains FullHeader, the header of a relayed block with all fields, and its conversions from and to
+// types.Header and the RLP encoding submitted to the Testimonium contract.
+
+package testimonium
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// FullHeader contains all fields of an Ethash block header, the hashes derived from it and the total difficulty
+// stored in the contract.
+type FullHeader struct {
+	Hash                      [32]byte
+	TotalDifficulty           *big.Int // as stored in the contract, nil if the header is not taken from the contract
+	Parent                    [32]byte
+	UncleHash                 [32]byte
+	Coinbase                  common.Address
+	StateRoot                 [32]byte
+	TransactionsRoot          [32]byte
+	ReceiptsRoot              [32]byte
+	LogsBloom                 types.Bloom
+	Difficulty                *big.Int
+	BlockNumber               *big.Int
+	GasLimit                  uint64
+	GasUsed                   uint64
+	Timestamp                 uint64
+	ExtraData                 []byte
+	MixHash                   [32]byte
+	Nonce                     uint64
+	RlpHeaderHashWithoutNonce [32]byte
+}
+
+// NewFullHeader returns the full header of the header. The total difficulty is not known and remains nil, like the
+// difficulty and block number if the header lacks them.
+func NewFullHeader(header *types.Header) (FullHeader, error) {
+	hashWithoutNonce, err := HeaderHashWithoutNonce(header)
+	if err != nil {
+		return FullHeader{}, err
+	}
+
+	fullHeader := FullHeader{
+		Hash:                      header.Hash(),
+		Parent:                    header.ParentHash,
+		UncleHash:                 header.UncleHash,
+		Coinbase:                  header.Coinbase,
+		StateRoot:                 header.Root,
+		TransactionsRoot:          header.TxHash,
+		ReceiptsRoot:              header.ReceiptHash,
+		LogsBloom:                 header.Bloom,
+		GasLimit:                  header.GasLimit,
+		GasUsed:                   header.GasUsed,
+		Timestamp:                 header.Time,
+		ExtraData:                 common.CopyBytes(header.Extra),
+		MixHash:                   header.MixDigest,
+		Nonce:                     header.Nonce.Uint64(),
+		RlpHeaderHashWithoutNonce: hashWithoutNonce,
+	}
+	if header.Difficulty != nil {
+		fullHeader.Difficulty = new(big.Int).Set(header.Difficulty)
+	}
+	if header.Number != nil {
+		fullHeader.BlockNumber = new(big.Int).Set(header.Number)
+	}
+	return fullHeader, nil
+}
+
+// DecodeFullHeader decodes an RLP encoded header as submitted to the contract.
+func DecodeFullHeader(rlpHeader []byte) (FullHeader, error) {
+	header, err := decodeHeaderFromRLP(rlpHeader)
+	if err != nil {
+		return FullHeader{}, err
+	}
+	return NewFullHeader(header)
+}
+
+// ToHeader returns the header with the fields of the full header. The derived hashes are not copied, they are
+// recomputed from the fields by types.Header.
+func (header FullHeader) ToHeader() *types.Header {
+	h := &types.Header{
+		ParentHash:  header.Parent,
+		UncleHash:   header.UncleHash,
+		Coinbase:    header.Coinbase,
+		Root:        header.StateRoot,
+		TxHash:      header.TransactionsRoot,
+		ReceiptHash: header.ReceiptsRoot,
+		Bloom:       header.LogsBloom,
+		GasLimit:    header.GasLimit,
+		GasUsed:     header.GasUsed,
+		Time:        header.Timestamp,
+		Extra:       common.CopyBytes(header.ExtraData),
+		MixDigest:   header.MixHash,
+		Nonce:       types.EncodeNonce(header.Nonce),
+	}
+	if header.Difficulty != nil {
+		h.Difficulty = new(big.Int).Set(header.Difficulty)
+	}
+	if header.BlockNumber != nil {
+		h.Number = new(big.Int).Set(header.BlockNumber)
+	}
+	return h
+}
+
+// RLP returns the RLP encoding of the header as submitted to the contract, i.e., with EthashHeaderCodec regardless
+// of the codec configured for the chain (see Encode).
+func (header FullHeader) RLP() ([]byte, error) {
+	return header.Encode(EthashHeaderCodec)
+}
+
+// Encode returns the RLP encoding of the header with the codec, e.g., the codec of the chain (Client.HeaderCodec).
+// The full header only contains the Ethash fields, codecs requiring the fields of later forks return an error.
+func (header FullHeader) Encode(codec HeaderCodec) ([]byte, error) {
+	if header.Difficulty == nil || header.BlockNumber == nil {
+		return nil, fmt.Errorf("header without difficulty or block number cannot be encoded")
+	}
+	return codec.EncodeHeader(&ExtendedHeader{Header: header.ToHeader()})
+}
+
+func (header FullHeader) String() string {
+	return fmt.Sprintf(`BlockHeader: {
+Hash: %s,
+Parent: %s,
+UncleHash: %s,
+Coinbase: %s,
+StateRoot: %s,
+TransactionsRoot: %s,
+ReceiptsRoot: %s,
+Difficulty: %s,
+BlockNumber: %s,
+GasLimit: %d,
+GasUsed: %d,
+Timestamp: %d,
+ExtraData: %s,
+MixHash: %s,
+Nonce: %d,
+RlpHeaderHashWithoutNonce: %s,
+TotalDifficulty: %s }`,
+		common.Bytes2Hex(header.Hash[:]),
+		common.Bytes2Hex(header.Parent[:]),
+		common.Bytes2Hex(header.UncleHash[:]),
+		header.Coinbase.Hex(),
+		common.Bytes2Hex(header.StateRoot[:]),
+		common.Bytes2Hex(header.TransactionsRoot[:]),
+		common.Bytes2Hex(header.ReceiptsRoot[:]),
+		header.Difficulty.String(),
+		header.BlockNumber.String(),
+		header.GasLimit,
+		header.GasUsed,
+		header.Timestamp,
+		common.Bytes2Hex(header.ExtraData),
+		common.Bytes2Hex(header.MixHash[:]),
+		header.Nonce,
+		common.Bytes2Hex(header.RlpHeaderHashWithoutNonce[:]),
+		header.TotalDifficulty.String())
+}
@@ -0,0 +1,115 @@
+package testimonium
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func testHeaders(t *testing.T) map[string]*types.Header {
+	genesis := core.DefaultGenesisBlock().ToBlock(nil).Header()
+	if genesis.Hash() != params.MainnetGenesisHash {
+		t.Fatalf("genesis hash: got %s, want %s", genesis.Hash().Hex(), params.MainnetGenesisHash.Hex())
+	}
+	return map[string]*types.Header{
+		"mainnet genesis": genesis,
+		"all fields set": {
+			ParentHash:  common.HexToHash("0x01"),
+			UncleHash:   types.EmptyUncleHash,
+			Coinbase:    common.HexToAddress("0x5df9b87991262f6ba471f09758cde1c0fc1de734"),
+			Root:        common.HexToHash("0x02"),
+			TxHash:      common.HexToHash("0x03"),
+			ReceiptHash: common.HexToHash("0x04"),
+			Bloom:       types.BytesToBloom([]byte{0x05}),
+			Difficulty:  new(big.Int).Lsh(big.NewInt(1), 70),
+			Number:      big.NewInt(9000000),
+			GasLimit:    10000000,
+			GasUsed:     9999999,
+			Time:        1574706444,
+			Extra:       []byte("extra data"),
+			MixDigest:   common.HexToHash("0x06"),
+			Nonce:       types.EncodeNonce(0xffffffffffffffff),
+		},
+	}
+}
+
+func TestFullHeaderRoundTrip(t *testing.T) {
+	for name, header := range testHeaders(t) {
+		t.Run(name, func(t *testing.T) {
+			fullHeader, err := NewFullHeader(header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fullHeader.Hash != header.Hash() {
+				t.Errorf("hash: got %x, want %s", fullHeader.Hash, header.Hash().Hex())
+			}
+			if converted := fullHeader.ToHeader(); !reflect.DeepEqual(converted, header) {
+				t.Errorf("converted header differs:\ngot:  %+v\nwant: %+v", converted, header)
+			}
+
+			encoded, err := fullHeader.RLP()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, _ := rlp.EncodeToBytes(header)
+			if !bytes.Equal(encoded, expected) {
+				t.Errorf("encoding differs:\ngot:  %x\nwant: %x", encoded, expected)
+			}
+			decoded, err := DecodeFullHeader(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, fullHeader) {
+				t.Errorf("decoded header differs:\ngot:  %+v\nwant: %+v", decoded, fullHeader)
+			}
+		})
+	}
+}
+
+func TestFullHeaderEncode(t *testing.T) {
+	fullHeader, err := NewFullHeader(testHeaders(t)["all fields set"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ethash, err := fullHeader.RLP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// clique headers without extension fields are encoded like Ethash headers
+	clique, err := fullHeader.Encode(CliqueHeaderCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(clique, ethash) {
+		t.Errorf("clique encoding differs from the ethash encoding")
+	}
+	if _, err := fullHeader.Encode(PoSHeaderCodec); err == nil {
+		t.Error("header without the fields of later forks encoded with the proof-of-stake codec")
+	}
+}
+
+func TestNewFullHeaderWithoutDifficultyAndNumber(t *testing.T) {
+	header := testHeaders(t)["all fields set"]
+	header.Difficulty, header.Number = nil, nil
+
+	fullHeader, err := NewFullHeader(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fullHeader.Difficulty != nil || fullHeader.BlockNumber != nil {
+		t.Errorf("difficulty and block number set: %s, %s", fullHeader.Difficulty, fullHeader.BlockNumber)
+	}
+	if converted := fullHeader.ToHeader(); converted.Difficulty != nil || converted.Number != nil {
+		t.Errorf("converted header with difficulty and block number")
+	}
+	if _, err := fullHeader.RLP(); err == nil {
+		t.Error("header without difficulty and block number encoded")
+	}
+}
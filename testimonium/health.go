@@ -0,0 +1,240 @@
+// This file contains the liveness and readiness checks of the live mode (daemon), served as /healthz and /readyz so
+// orchestrators (e.g., Kubernetes) can restart a stalled relay or withhold traffic while its chains are unusable.
+
+package testimonium
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LiveMonitor observes the progress of SubmitHeaderLive. Pass it to the client with WithLiveMonitor.
+type LiveMonitor struct {
+	mutex           sync.Mutex
+	started         time.Time
+	lastHeader      time.Time // time the last header of the source chain was processed
+	lastBlockNumber uint64
+	relayedHeaders  int
+	stakeQueue      int       // headers whose stake is still locked
+	nextUnlock      time.Time // the stake of the next locked header is freed then, zero if none is locked
+	lostRaces       int       // submissions reverted because another relayer was faster
+	gasLostInRaces  uint64
+	gasPrice        *big.Int  // gas price of the destination chain at the last check of the gas ceiling
+	gasPausedSince  time.Time // zero unless submissions are paused by the gas ceiling
+	gasPauses       int
+	gasPausedTotal  time.Duration // duration of the ended pauses
+	withheldHeaders int           // headers selected by the relay policy during the current pause
+	lastClockCheck  time.Time     // time of the last clock check (see CheckClocks)
+	clockWarnings   []string
+}
+
+// LiveSnapshot is the state of the live mode at a point in time.
+type LiveSnapshot struct {
+	Started         time.Time `json:"started"`
+	LastHeader      time.Time `json:"lastHeader"`
+	LastBlockNumber uint64    `json:"lastBlockNumber"`
+	RelayedHeaders  int       `json:"relayedHeaders"`
+	StakeQueue      int       `json:"stakeQueue"`
+	NextUnlock      time.Time `json:"nextUnlock"` // zero if no stake is locked
+	LostRaces       int       `json:"lostRaces"`
+	GasLostInRaces  uint64    `json:"gasLostInRaces"`
+	// state of the gas ceiling (see WithGasCeiling)
+	GasPrice        *big.Int      `json:"gasPrice,omitempty"`
+	GasPaused       bool          `json:"gasPaused"`
+	GasPausedSince  time.Time     `json:"gasPausedSince"`
+	GasPauses       int           `json:"gasPauses"`
+	GasPausedTotal  time.Duration `json:"gasPausedTotal"` // including the current pause
+	WithheldHeaders int           `json:"withheldHeaders"`
+	// warnings of the last check of the local clock against the chains (see CheckClocks)
+	ClockChecked  time.Time `json:"clockChecked"`
+	ClockWarnings []string  `json:"clockWarnings,omitempty"`
+}
+
+func NewLiveMonitor() *LiveMonitor {
+	return &LiveMonitor{started: time.Now()}
+}
+
+// WithLiveMonitor reports the progress of SubmitHeaderLive to the monitor.
+func WithLiveMonitor(monitor *LiveMonitor) ClientOption {
+	return func(client *Client) error {
+		client.liveMonitor = monitor
+		return nil
+	}
+}
+
+// Snapshot returns the current state of the live mode.
+func (m *LiveMonitor) Snapshot() LiveSnapshot {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	snapshot := LiveSnapshot{
+		Started:         m.started,
+		LastHeader:      m.lastHeader,
+		LastBlockNumber: m.lastBlockNumber,
+		RelayedHeaders:  m.relayedHeaders,
+		StakeQueue:      m.stakeQueue,
+		NextUnlock:      m.nextUnlock,
+		LostRaces:       m.lostRaces,
+		GasLostInRaces:  m.gasLostInRaces,
+		GasPrice:        m.gasPrice,
+		GasPaused:       !m.gasPausedSince.IsZero(),
+		GasPausedSince:  m.gasPausedSince,
+		GasPauses:       m.gasPauses,
+		GasPausedTotal:  m.gasPausedTotal,
+		WithheldHeaders: m.withheldHeaders,
+		ClockChecked:    m.lastClockCheck,
+		ClockWarnings:   m.clockWarnings,
+	}
+	if snapshot.GasPaused {
+		snapshot.GasPausedTotal += time.Since(m.gasPausedSince).Round(time.Second)
+	}
+	return snapshot
+}
+
+// headerProcessed records that a header of the source chain was handled, stakeQueue contains the times the locked
+// headers are unlocked. The monitor may be nil.
+func (m *LiveMonitor) headerProcessed(blockNumber uint64, relayedHeaders int, stakeQueue []time.Time) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.lastHeader = time.Now()
+	m.lastBlockNumber = blockNumber
+	m.relayedHeaders += relayedHeaders
+	m.stakeQueue = len(stakeQueue)
+	m.nextUnlock = time.Time{}
+	if len(stakeQueue) > 0 {
+		m.nextUnlock = stakeQueue[0]
+	}
+}
+
+// RelayPair is a source chain whose headers are relayed to a destination chain.
+type RelayPair struct {
+	SourceChain      uint8 `json:"sourceChain"`
+	DestinationChain uint8 `json:"destinationChain"`
+}
+
+// ReadinessProblems returns the reasons the chains of the pair cannot be used for relaying, nil if they can. The
+// source chain has to be in sync and, unless its node is known not to support them (new heads are polled then), support
+// subscriptions, the destination chain additionally has to accept
+// transactions (i.e., it is not a source chain and a private key is configured) and contain the ETH Relay contract.
+func (c Client) ReadinessProblems(pair RelayPair) []string {
+	var problems []string
+
+	source, err := c.Probe(pair.SourceChain)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	for _, probeErr := range source.Errors {
+		problems = append(problems, fmt.Sprintf("source chain %d: %s", pair.SourceChain, probeErr))
+	}
+	if source.Syncing {
+		problems = append(problems, fmt.Sprintf("source chain %d: node is syncing", pair.SourceChain))
+	}
+	if !source.Subscriptions && source.Capabilities.Subscriptions {
+		problems = append(problems, fmt.Sprintf("source chain %d: subscriptions to the node fail", pair.SourceChain))
+	}
+
+	destination, err := c.Probe(pair.DestinationChain)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	for _, probeErr := range destination.Errors {
+		problems = append(problems, fmt.Sprintf("destination chain %d: %s", pair.DestinationChain, probeErr))
+	}
+	if destination.Syncing {
+		problems = append(problems, fmt.Sprintf("destination chain %d: node is syncing", pair.DestinationChain))
+	}
+	if destination.Role == ROLE_SOURCE {
+		problems = append(problems, fmt.Sprintf("destination chain %d: configured as source chain", pair.DestinationChain))
+	}
+	if !c.HasAccount() {
+		problems = append(problems, fmt.Sprintf("destination chain %d: %s", pair.DestinationChain, ErrNoAccount))
+	}
+	if !destination.TransactionsUsable {
+		problems = append(problems, fmt.Sprintf("destination chain %d: chain ids do not match the config", pair.DestinationChain))
+	}
+	if !destination.EthrelayReachable {
+		problems = append(problems, fmt.Sprintf("destination chain %d: ETH Relay contract not reachable", pair.DestinationChain))
+	}
+	return problems
+}
+
+// HealthHandler serves /healthz and /readyz. /healthz fails if the live mode has not processed a header for longer
+// than the stall timeout (the process should be restarted), /readyz fails if a chain of a relay pair is unusable.
+type HealthHandler struct {
+	client       Client
+	pairs        []RelayPair
+	monitor      *LiveMonitor
+	stallTimeout time.Duration
+	mux          *http.ServeMux
+}
+
+// NewHealthHandler returns the handler checking the relay pairs. Without a monitor, /healthz does not check for stalls.
+func NewHealthHandler(client Client, monitor *LiveMonitor, stallTimeout time.Duration, pairs ...RelayPair) *HealthHandler {
+	if monitor == nil {
+		monitor = NewLiveMonitor()
+		stallTimeout = 0
+	}
+	handler := &HealthHandler{
+		client:       client,
+		pairs:        pairs,
+		monitor:      monitor,
+		stallTimeout: stallTimeout,
+		mux:          http.NewServeMux(),
+	}
+	handler.mux.HandleFunc("/healthz", handler.serveLiveness)
+	handler.mux.HandleFunc("/readyz", handler.serveReadiness)
+	return handler
+}
+
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+type healthResponse struct {
+	Status   string        `json:"status"`
+	Problems []string      `json:"problems,omitempty"`
+	Live     *LiveSnapshot `json:"live,omitempty"`
+}
+
+func (h *HealthHandler) serveLiveness(w http.ResponseWriter, r *http.Request) {
+	snapshot := h.monitor.Snapshot()
+
+	// before the first header the start time is used, so the catch-up of the live mode is not mistaken for a stall
+	lastProgress := snapshot.LastHeader
+	if lastProgress.IsZero() {
+		lastProgress = snapshot.Started
+	}
+
+	var problems []string
+	if h.stallTimeout > 0 && time.Since(lastProgress) > h.stallTimeout {
+		problems = append(problems, fmt.Sprintf("no header processed for %s", time.Since(lastProgress).Round(time.Second)))
+	}
+	writeHealthResponse(w, problems, &snapshot)
+}
+
+func (h *HealthHandler) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	var problems []string
+	for _, pair := range h.pairs {
+		problems = append(problems, h.client.ReadinessProblems(pair)...)
+	}
+	writeHealthResponse(w, problems, nil)
+}
+
+func writeHealthResponse(w http.ResponseWriter, problems []string, snapshot *LiveSnapshot) {
+	response := healthResponse{Status: "ok", Problems: problems, Live: snapshot}
+	status := http.StatusOK
+	if len(problems) > 0 {
+		response.Status = "unavailable"
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,478 @@
+// This file contains a local index of the block headers submitted to the Testimonium contract. The index is built
+// once from the SubmitBlock events of the destination chain and updated incrementally afterwards, so looking up the
+// RLP header of a submitted block (e.g., for disputes) does not require scanning all events again. The verifications,
+// disputes and stake withdrawals of the contract are indexed as well, e.g., for the relay statistics.
+
+package testimonium
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// signatures of the events emitted by the Testimonium contract
+var (
+	submitBlockEventId       = crypto.Keccak256Hash([]byte("SubmitBlock(bytes32)"))
+	disputeBlockEventId      = crypto.Keccak256Hash([]byte("DisputeBlock(uint256)"))
+	verifyTransactionEventId = crypto.Keccak256Hash([]byte("VerifyTransaction(uint8)"))
+	verifyReceiptEventId     = crypto.Keccak256Hash([]byte("VerifyReceipt(uint8)"))
+	verifyStateEventId       = crypto.Keccak256Hash([]byte("VerifyState(uint8)"))
+	withdrawStakeEventId     = crypto.Keccak256Hash([]byte("WithdrawStake(address,uint256)"))
+)
+
+// EVENT_INDEX_VERSION is the version of the indexed data, indexes of older versions are rebuilt on the next update.
+const EVENT_INDEX_VERSION = 3
+
+// SubmitRecord contains everything known about the submission of a single block header.
+type SubmitRecord struct {
+	BlockHash         common.Hash    `json:"blockHash"`
+	TxHash            common.Hash    `json:"txHash"`
+	Submitter         common.Address `json:"submitter"`
+	SubmitBlockNumber uint64         `json:"submitBlockNumber"` // block of the destination chain containing the tx
+	RlpHeader         hexutil.Bytes  `json:"rlpHeader"`
+	txCost
+}
+
+// txCost contains the time and the gas costs of an indexed transaction
+type txCost struct {
+	Timestamp uint64   `json:"timestamp,omitempty"` // of the block of the destination chain containing the tx
+	GasUsed   uint64   `json:"gasUsed,omitempty"`
+	GasPrice  *big.Int `json:"gasPrice,omitempty"`
+}
+
+// VerificationRecord contains a verification of a transaction, receipt or state of a submitted block.
+type VerificationRecord struct {
+	TxHash      common.Hash     `json:"txHash"`
+	Verifier    common.Address  `json:"verifier"`
+	ValueType   TrieValueType   `json:"type"`
+	BlockHash   common.Hash     `json:"blockHash"` // the block containing the verified value
+	Result      uint8           `json:"result"`
+	Fee         *big.Int        `json:"fee"`
+	FeeToken    *common.Address `json:"feeToken,omitempty"` // ERC-20 token the fee was paid in, nil if paid in ether
+	BlockNumber uint64          `json:"blockNumber"`        // block of the destination chain containing the tx
+	txCost
+}
+
+// DisputeRecord contains a dispute of a submitted block.
+type DisputeRecord struct {
+	TxHash      common.Hash    `json:"txHash"`
+	Disputer    common.Address `json:"disputer"`
+	BlockHash   common.Hash    `json:"blockHash"`   // the disputed block
+	ReturnCode  uint64         `json:"returnCode"`  // POW_VALID if the dispute failed
+	BlockNumber uint64         `json:"blockNumber"` // block of the destination chain containing the tx
+	txCost
+}
+
+// Succeeded reports whether the disputed block was removed.
+func (record DisputeRecord) Succeeded() bool {
+	return record.ReturnCode != POW_VALID
+}
+
+// WithdrawalRecord contains a withdrawal of stake.
+type WithdrawalRecord struct {
+	TxHash      common.Hash    `json:"txHash"`
+	Client      common.Address `json:"client"`
+	Amount      *big.Int       `json:"amount"`      // the withdrawn stake, less than requested if the rest is locked
+	BlockNumber uint64         `json:"blockNumber"` // block of the destination chain containing the tx
+	txCost
+}
+
+// EventIndex maps the hashes of submitted blocks to their submit records. It belongs to a single Testimonium contract
+// and is stored as JSON file in the data directory.
+type EventIndex struct {
+	Version          int                           `json:"version"`
+	Chain            uint8                         `json:"chain"`
+	Contract         common.Address                `json:"contract"`
+	LastScannedBlock uint64                        `json:"lastScannedBlock"`
+	Records          map[common.Hash]*SubmitRecord `json:"records"`
+	Verifications    []*VerificationRecord         `json:"verifications,omitempty"`
+	Disputes         []*DisputeRecord              `json:"disputes,omitempty"`
+	Withdrawals      []*WithdrawalRecord           `json:"withdrawals,omitempty"`
+
+	path string
+}
+
+// EventIndexPath returns the path of the index file for the contract in the data directory.
+func EventIndexPath(dataDir string, chain uint8, contract common.Address) string {
+	return filepath.Join(dataDir, fmt.Sprintf("index-%d-%s.json", chain, contract.Hex()))
+}
+
+// OpenEventIndex reads the index of the contract from the data directory. If the index does not exist yet, an empty
+// index is returned, which is created on the first call of Save.
+func OpenEventIndex(dataDir string, chain uint8, contract common.Address) (*EventIndex, error) {
+	index := &EventIndex{
+		Chain:    chain,
+		Contract: contract,
+		Records:  make(map[common.Hash]*SubmitRecord),
+		path:     EventIndexPath(dataDir, chain, contract),
+	}
+
+	data, err := ioutil.ReadFile(index.path)
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("corrupt index %s: %s", index.path, err)
+	}
+	if index.Records == nil {
+		index.Records = make(map[common.Hash]*SubmitRecord)
+	}
+	return index, nil
+}
+
+// Save writes the index atomically to its file.
+func (index *EventIndex) Save() error {
+	if err := os.MkdirAll(filepath.Dir(index.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	temp := index.path + ".tmp"
+	if err := ioutil.WriteFile(temp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, index.path)
+}
+
+// Lookup returns the submit record of the block with the specified hash.
+func (index *EventIndex) Lookup(blockHash common.Hash) (*SubmitRecord, bool) {
+	record, exists := index.Records[blockHash]
+	return record, exists
+}
+
+// SortedRecords returns all records ordered by the block in which they were submitted.
+func (index *EventIndex) SortedRecords() []*SubmitRecord {
+	records := make([]*SubmitRecord, 0, len(index.Records))
+	for _, record := range index.Records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].SubmitBlockNumber < records[j].SubmitBlockNumber
+	})
+	return records
+}
+
+// WithEventIndex lets the client look up submitted headers in the indexes stored in the data directory before
+// falling back to scanning the events of the contract.
+func WithEventIndex(dataDir string) ClientOption {
+	return func(client *Client) error {
+		client.indexDir = dataDir
+		return nil
+	}
+}
+
+// UpdateEventIndex scans the SubmitBlock, Verify, DisputeBlock and WithdrawStake events of the Testimonium contract on the specified
+// chain that were emitted after the last scan and adds them to the index of the data directory. The index is saved after every scanned batch
+// of blocks, so an interrupted update continues where it stopped. It returns the updated index and the number of
+// added records.
+func (c Client) UpdateEventIndex(dataDir string, chain uint8) (*EventIndex, int, error) {
+	return c.UpdateEventIndexContext(c.context(), dataDir, chain)
+}
+
+// UpdateEventIndexContext is like UpdateEventIndex, but stops scanning when the context is done. The index is saved
+// up to the last completely scanned batch and returned with a *ScanInterruptedError, whose cursor is the block the
+// next update continues at.
+func (c Client) UpdateEventIndexContext(ctx context.Context, dataDir string, chain uint8) (*EventIndex, int, error) {
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, 0, err
+	}
+
+	index, err := OpenEventIndex(dataDir, chain, c.chain(chain).testimoniumContractAddress)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	latest, err := c.chain(chain).client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return index, 0, err
+	}
+
+	if index.Version < EVENT_INDEX_VERSION {
+		// the events indexed since the last version are missing, the index is rebuilt
+		index.LastScannedBlock = 0
+		index.Verifications = nil
+		index.Disputes = nil
+		index.Withdrawals = nil
+		index.Version = EVENT_INDEX_VERSION
+	}
+
+	// fees of verifications are transfers of the fee token if the fee is not paid in ether
+	feeToken, err := c.FeeToken(chain)
+	if err != nil {
+		return index, 0, err
+	}
+
+	start := uint64(0)
+	if index.LastScannedBlock > 0 {
+		start = index.LastScannedBlock + 1
+	}
+
+	added := 0
+	for ; start <= latest.Number.Uint64(); start += eventScanBatchSize {
+		if err := scanInterrupted(ctx, chain, start, latest.Number.Uint64()); err != nil {
+			return index, added, err
+		}
+		end := start + eventScanBatchSize - 1
+		if end > latest.Number.Uint64() {
+			end = latest.Number.Uint64()
+		}
+
+		logs, err := c.chain(chain).filterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: []common.Address{index.Contract},
+			Topics:    [][]common.Hash{append([]common.Hash{submitBlockEventId, disputeBlockEventId, withdrawStakeEventId}, verificationEventIds()...)},
+		})
+		if interrupted := scanInterrupted(ctx, chain, start, latest.Number.Uint64()); interrupted != nil {
+			return index, added, interrupted
+		}
+		if err != nil {
+			return index, added, err
+		}
+
+		costs := make(map[common.Hash]txCost)
+		for _, vLog := range logs {
+			if len(vLog.Topics) == 0 {
+				continue
+			}
+			switch vLog.Topics[0] {
+			case submitBlockEventId:
+				record, err := c.submitRecord(vLog, chain)
+				if err != nil {
+					return index, added, err
+				}
+				if record.txCost, err = c.indexedTxCost(vLog, chain, costs); err != nil {
+					return index, added, err
+				}
+				index.Records[record.BlockHash] = record
+			case disputeBlockEventId:
+				record, err := c.disputeRecord(vLog, chain)
+				if err != nil {
+					return index, added, err
+				}
+				if record.txCost, err = c.indexedTxCost(vLog, chain, costs); err != nil {
+					return index, added, err
+				}
+				index.Disputes = append(index.Disputes, record)
+			case withdrawStakeEventId:
+				record, err := withdrawalRecord(vLog)
+				if err != nil {
+					return index, added, err
+				}
+				if record.txCost, err = c.indexedTxCost(vLog, chain, costs); err != nil {
+					return index, added, err
+				}
+				index.Withdrawals = append(index.Withdrawals, record)
+			default:
+				record, err := c.verificationRecord(vLog, chain, feeToken)
+				if err != nil {
+					return index, added, err
+				}
+				if record.txCost, err = c.indexedTxCost(vLog, chain, costs); err != nil {
+					return index, added, err
+				}
+				index.Verifications = append(index.Verifications, record)
+			}
+			added++
+		}
+
+		index.LastScannedBlock = end
+		if err := index.Save(); err != nil {
+			return index, added, err
+		}
+	}
+
+	return index, added, nil
+}
+
+func (c Client) submitRecord(vLog types.Log, chain uint8) (*SubmitRecord, error) {
+	tx, _, err := c.chain(chain).client.TransactionByHash(c.context(), vLog.TxHash)
+	if err != nil {
+		return nil, err
+	}
+
+	rlpHeader, err := c.chain(chain).rlpHeaderFromSubmitTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	submitter, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SubmitRecord{
+		BlockHash:         common.BytesToHash(vLog.Data),
+		TxHash:            vLog.TxHash,
+		Submitter:         submitter,
+		SubmitBlockNumber: vLog.BlockNumber,
+		RlpHeader:         rlpHeader,
+	}, nil
+}
+
+func (c Client) verificationRecord(vLog types.Log, chain uint8, feeToken common.Address) (*VerificationRecord, error) {
+	tx, _, err := c.chain(chain).client.TransactionByHash(c.context(), vLog.TxHash)
+	if err != nil {
+		return nil, err
+	}
+	rlpHeader, err := c.chain(chain).txInputArgument(tx, "rlpHeader")
+	if err != nil {
+		return nil, err
+	}
+
+	record := &VerificationRecord{
+		TxHash:      vLog.TxHash,
+		Verifier:    txSender(tx),
+		ValueType:   VALUE_TYPE_TRANSACTION,
+		BlockHash:   crypto.Keccak256Hash(rlpHeader.([]byte)),
+		Fee:         tx.Value(),
+		BlockNumber: vLog.BlockNumber,
+	}
+	if feeToken != (common.Address{}) {
+		receipt, err := c.chain(chain).client.TransactionReceipt(c.context(), vLog.TxHash)
+		if err != nil {
+			return nil, err
+		}
+		record.Fee = feeTokenTransfers(receipt, feeToken, record.Verifier)
+		record.FeeToken = &feeToken
+	}
+	if valueType, exists := valueTypeOfEvent(vLog.Topics[0]); exists {
+		record.ValueType = valueType
+	}
+	if len(vLog.Data) >= 32 {
+		record.Result = vLog.Data[31]
+	}
+	return record, nil
+}
+
+func (c Client) disputeRecord(vLog types.Log, chain uint8) (*DisputeRecord, error) {
+	tx, _, err := c.chain(chain).client.TransactionByHash(c.context(), vLog.TxHash)
+	if err != nil {
+		return nil, err
+	}
+	rlpHeader, err := c.chain(chain).txInputArgument(tx, "rlpHeader")
+	if err != nil {
+		return nil, err
+	}
+
+	record := &DisputeRecord{
+		TxHash:      vLog.TxHash,
+		Disputer:    txSender(tx),
+		BlockHash:   crypto.Keccak256Hash(rlpHeader.([]byte)),
+		BlockNumber: vLog.BlockNumber,
+	}
+	if len(vLog.Data) >= 32 {
+		record.ReturnCode = new(big.Int).SetBytes(vLog.Data[:32]).Uint64()
+	}
+	return record, nil
+}
+
+func withdrawalRecord(vLog types.Log) (*WithdrawalRecord, error) {
+	if len(vLog.Data) < 64 {
+		return nil, fmt.Errorf("illegal WithdrawStake event in transaction %s", vLog.TxHash.Hex())
+	}
+	return &WithdrawalRecord{
+		TxHash:      vLog.TxHash,
+		Client:      common.BytesToAddress(vLog.Data[:32]),
+		Amount:      new(big.Int).SetBytes(vLog.Data[32:64]),
+		BlockNumber: vLog.BlockNumber,
+	}, nil
+}
+
+// indexedTxCost returns the time and the gas costs of the transaction emitting the event, costs contains the costs
+// of the transactions of the current scan
+func (c Client) indexedTxCost(vLog types.Log, chain uint8, costs map[common.Hash]txCost) (txCost, error) {
+	if cost, exists := costs[vLog.TxHash]; exists {
+		return cost, nil
+	}
+
+	client := c.chain(chain).client
+	tx, _, err := client.TransactionByHash(c.context(), vLog.TxHash)
+	if err != nil {
+		return txCost{}, err
+	}
+	receipt, err := client.TransactionReceipt(c.context(), vLog.TxHash)
+	if err != nil {
+		return txCost{}, err
+	}
+	header, err := client.HeaderByHash(c.context(), vLog.BlockHash)
+	if err != nil {
+		return txCost{}, err
+	}
+
+	cost := txCost{Timestamp: header.Time, GasUsed: receipt.GasUsed, GasPrice: tx.GasPrice()}
+	costs[vLog.TxHash] = cost
+	return cost, nil
+}
+
+// txInputArgument returns the argument with the name of the Testimonium function called by the transaction
+func (chain *Chain) txInputArgument(tx *types.Transaction, name string) (interface{}, error) {
+	if len(tx.Data()) < 4 {
+		return nil, fmt.Errorf("transaction %s is not a contract call", tx.Hash().Hex())
+	}
+	testimoniumAbi, err := chain.testimoniumAbi()
+	if err != nil {
+		return nil, err
+	}
+	method, err := testimoniumAbi.MethodById(tx.Data()[:4])
+	if err != nil {
+		return nil, err
+	}
+	values, err := method.Inputs.UnpackValues(tx.Data()[4:])
+	if err != nil {
+		return nil, err
+	}
+	for i, argument := range method.Inputs {
+		if argument.Name == name {
+			return values[i], nil
+		}
+	}
+	return nil, fmt.Errorf("function %s of transaction %s has no argument %s", method.Name, tx.Hash().Hex(), name)
+}
+
+// submittedRlpHeader returns the RLP encoded header of a submitted block, from the index if available
+func (c Client) submittedRlpHeader(blockHash common.Hash, chain uint8) ([]byte, error) {
+	if c.indexDir != "" {
+		index, err := OpenEventIndex(c.indexDir, chain, c.chain(chain).testimoniumContractAddress)
+		if err != nil {
+			return nil, err
+		}
+		if record, exists := index.Lookup(blockHash); exists {
+			return record.RlpHeader, nil
+		}
+	}
+
+	return getRlpHeaderByTestimoniumSubmitEvent(c.chain(chain), blockHash)
+}
+
+// EventIndex returns the index of the Testimonium contract on the specified chain stored in the data directory.
+func (c Client) EventIndex(dataDir string, chain uint8) (*EventIndex, error) {
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, err
+	}
+	return OpenEventIndex(dataDir, chain, c.chain(chain).testimoniumContractAddress)
+}
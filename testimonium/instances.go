@@ -0,0 +1,153 @@
+// This file contains the named ETH Relay instances of a chain. Some deployments run a separate ETH Relay contract per
+// source chain on the same destination chain; besides the default contract ("ethrelayaddress"), a chain config can
+// name further contracts in its "ethrelays" entry, and the client uses one of them instead of the default contract
+// on every chain it is configured for.
+
+package testimonium
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrUnknownRelayInstance is returned if no chain config names an ETH Relay instance with the requested name.
+var ErrUnknownRelayInstance = errors.New("unknown ETH Relay instance")
+
+// relayInstance is a named ETH Relay contract of a chain
+type relayInstance struct {
+	address  common.Address
+	contract *Testimonium
+}
+
+// WithRelayInstance uses the ETH Relay contract with the specified name of the "ethrelays" entry instead of the default
+// contract on every chain whose config names it, the other chains use their default contract.
+func WithRelayInstance(name string) ClientOption {
+	return func(client *Client) error {
+		client.relayInstance = name
+		return nil
+	}
+}
+
+// relayInstancesFromConfig returns the addresses of the named ETH Relay instances of the "ethrelays" entry of a chain
+// config, e.g.,
+//
+//	ethrelays:
+//	    mainnet: 0xabc123...
+//	    classic: 0xdef456...
+func relayInstancesFromConfig(chainConfig map[string]interface{}) (map[string]common.Address, error) {
+	entry := chainConfig["ethrelays"]
+	if entry == nil {
+		return nil, nil
+	}
+	entries, ok := entry.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("illegal ethrelays entry %v", entry)
+	}
+	addresses := make(map[string]common.Address, len(entries))
+	for name, value := range entries {
+		addressHex := fmt.Sprint(value)
+		if !common.IsHexAddress(addressHex) {
+			return nil, fmt.Errorf("illegal address %s of ETH Relay instance %s", addressHex, name)
+		}
+		addresses[name] = common.HexToAddress(addressHex)
+	}
+	return addresses, nil
+}
+
+// bindRelayInstances binds the named ETH Relay instances of the chain being connected like its default contract and
+// selects the instance of the client if the chain names it
+func (c Client) bindRelayInstances(chain *Chain, chainConfig map[string]interface{}, backend bind.ContractBackend) {
+	addresses, err := relayInstancesFromConfig(chainConfig)
+	if err != nil {
+		c.problemf("No ETH Relay instances of chain %d are used: %s", chain.id, err)
+		return
+	}
+	chain.instances = make(map[string]relayInstance, len(addresses))
+	for name, address := range addresses {
+		contract, err := c.bindTestimoniumContract(chain, chainConfig, address, backend)
+		if err != nil {
+			c.problemf("No Testimonium contract deployed at address %s (instance %s) on chain %d (%s): %s", address.Hex(), name, chain.id, chain.fullUrl, err)
+			continue
+		}
+		chain.instances[name] = relayInstance{address: address, contract: contract}
+	}
+	if c.relayInstance != "" {
+		if _, exists := addresses[c.relayInstance]; exists {
+			// an instance that cannot be bound must not silently fall back to the default contract
+			chain.testimoniumContract, chain.testimoniumContractAddress = nil, common.Address{}
+			chain.selectInstance(c.relayInstance)
+		}
+	}
+}
+
+// selectInstance makes the named instance the ETH Relay contract of the chain, it reports whether the chain has it
+func (chain *Chain) selectInstance(name string) bool {
+	instance, exists := chain.instances[name]
+	if !exists {
+		return false
+	}
+	chain.instance = name
+	chain.testimoniumContract = instance.contract
+	chain.testimoniumContractAddress = instance.address
+	// the parameters of the instance may differ from the ones of the default contract
+	chain.viewCache = chain.viewCache.fresh()
+	return true
+}
+
+// hasRelayInstance reports whether a chain config names the instance
+func (c Client) hasRelayInstance(name string) bool {
+	for _, chainConfig := range c.dialer.configs {
+		if addresses, err := relayInstancesFromConfig(chainConfig); err == nil {
+			if _, exists := addresses[name]; exists {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Instance returns a copy of the client using the ETH Relay contract with the specified name instead of the default
+// contract on every chain whose config names it (ErrUnknownRelayInstance if none does), the other chains keep their
+// contract. All configured chains are connected first, the copy shares their connections, nonces and transaction logs
+// with the client.
+func (c Client) Instance(name string) (Client, error) {
+	if c.dialer == nil || !c.hasRelayInstance(name) {
+		return c, fmt.Errorf("%w: %s", ErrUnknownRelayInstance, name)
+	}
+	c.connectAll()
+	chains := make(map[uint8]*Chain)
+	c.eachChain(func(chain *Chain) {
+		chains[chain.id] = chain
+		if _, exists := chain.instances[name]; exists {
+			instance := *chain
+			instance.selectInstance(name)
+			chains[chain.id] = &instance
+		}
+	})
+	c.chains = chains
+	c.relayInstance = name
+	return c, nil
+}
+
+// RelayInstances returns the addresses of the named ETH Relay instances the chain was connected with by name.
+func (c Client) RelayInstances(chain uint8) (map[string]common.Address, error) {
+	if err := c.checkChain(chain); err != nil {
+		return nil, err
+	}
+	addresses := make(map[string]common.Address, len(c.chain(chain).instances))
+	for name, instance := range c.chain(chain).instances {
+		addresses[name] = instance.address
+	}
+	return addresses, nil
+}
+
+// RelayInstance returns the name of the ETH Relay instance the client uses on the chain, empty for the default contract.
+func (c Client) RelayInstance(chain uint8) (string, error) {
+	if err := c.checkChain(chain); err != nil {
+		return "", err
+	}
+	return c.chain(chain).instance, nil
+}
@@ -0,0 +1,21 @@
+package testimonium
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewClientRelayInstance(t *testing.T) {
+	chainsConfig := map[string]interface{}{
+		"1": map[string]interface{}{
+			"url":       "http://127.0.0.1:1",
+			"ethrelays": map[string]interface{}{"mainnet": "0x00000000000000000000000000000000000000aa"},
+		},
+	}
+	if _, err := NewClient("", chainsConfig, WithRelayInstance("mainnet")); err != nil {
+		t.Errorf("configured instance rejected: %s", err)
+	}
+	if _, err := NewClient("", chainsConfig, WithRelayInstance("classic")); !errors.Is(err, ErrUnknownRelayInstance) {
+		t.Errorf("unknown instance: %v instead of ErrUnknownRelayInstance", err)
+	}
+}
@@ -0,0 +1,31 @@
+// This file contains the decryption of keystore files (e.g., created by geth or Clef), so the account of the client does
+// not have to be stored as plain private key in the config file.
+
+package testimonium
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// KEYSTORE_PASSWORD_ENV is the environment variable the password of the keystore file configured with the "keystore"
+// entry is read from. The CLI asks for the password if it is not set.
+const KEYSTORE_PASSWORD_ENV = "ETHRELAY_KEYSTORE_PASSWORD"
+
+// PrivateKeyFromKeystore decrypts the keystore file with the password and returns the hex encoded (0x...) private key,
+// as expected by NewClient.
+func PrivateKeyFromKeystore(path string, password string) (string, error) {
+	keyJson, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	key, err := keystore.DecryptKey(keyJson, password)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt keystore %s: %s", path, err)
+	}
+	return hexutil.Encode(crypto.FromECDSA(key.PrivateKey)), nil
+}
@@ -0,0 +1,111 @@
+// This file contains the latency breakdown of verification jobs: the time from the inclusion of a transaction in the
+// source chain to its verification on the destination chain, split into the phases the relay adds, so integrators
+// can reason about the delay their users experience.
+
+package testimonium
+
+import (
+	"bytes"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// LATENCY_SCAN_BLOCKS is the number of recent blocks of the destination chain searched for the submission of a header
+// that is not in the event index.
+const LATENCY_SCAN_BLOCKS = 10000
+
+// VerificationLatency is the latency of a verification. Times of the chains are block timestamps, the phases of the
+// client are measured with the local clock. RelayLag is zero if the submission of the header was not found.
+type VerificationLatency struct {
+	Mined     time.Time `json:"mined"`     // block containing the transaction on the source chain
+	Confirmed time.Time `json:"confirmed"` // last confirmation block on the source chain
+	Stored    time.Time `json:"stored"`    // block storing the last confirmation block (zero if not found)
+	Sent      time.Time `json:"sent"`      // the verification was sent
+	Verified  time.Time `json:"verified"`  // block containing the verification on the destination chain
+	// phases
+	ConfirmationWait time.Duration `json:"confirmationWait"` // Confirmed - Mined
+	RelayLag         time.Duration `json:"relayLag"`         // Stored - Confirmed
+	ProofGeneration  time.Duration `json:"proofGeneration"`
+	TxInclusion      time.Duration `json:"txInclusion"` // Verified - Sent
+	// Other is the time not covered by the phases, e.g., until the verification was started
+	Other time.Duration `json:"other"`
+	Total time.Duration `json:"total"` // Verified - Mined
+}
+
+// newVerificationLatency computes the phases of the latency from its points in time
+func newVerificationLatency(mined, confirmed, stored, sent, verified time.Time, proofGeneration time.Duration) *VerificationLatency {
+	latency := &VerificationLatency{
+		Mined:            mined,
+		Confirmed:        confirmed,
+		Stored:           stored,
+		Sent:             sent,
+		Verified:         verified,
+		ConfirmationWait: nonNegative(confirmed.Sub(mined)),
+		ProofGeneration:  proofGeneration,
+		TxInclusion:      nonNegative(verified.Sub(sent)),
+		Total:            nonNegative(verified.Sub(mined)),
+	}
+	if !stored.IsZero() {
+		latency.RelayLag = nonNegative(stored.Sub(confirmed))
+	}
+	latency.Other = nonNegative(latency.Total - latency.ConfirmationWait - latency.RelayLag - latency.ProofGeneration - latency.TxInclusion)
+	return latency
+}
+
+// nonNegative clamps differences of block timestamps (seconds) and the local clock at zero
+func nonNegative(duration time.Duration) time.Duration {
+	if duration < 0 {
+		return 0
+	}
+	return duration
+}
+
+// blockTime returns the timestamp of the block with the specified number
+func (c Client) blockTime(blockNumber uint64, chain uint8) (time.Time, error) {
+	header, err := c.HeaderByNumber(new(big.Int).SetUint64(blockNumber), chain)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(header.Time), 0), nil
+}
+
+// headerStoredTime returns the timestamp of the block of the destination chain the header was submitted in, from
+// the event index if available or from the SubmitBlock events of the last LATENCY_SCAN_BLOCKS blocks. It returns
+// the zero time if the submission was not found.
+func (c Client) headerStoredTime(blockHash common.Hash, chain uint8) (time.Time, error) {
+	if c.indexDir != "" {
+		index, err := OpenEventIndex(c.indexDir, chain, c.chain(chain).testimoniumContractAddress)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if record, exists := index.Lookup(blockHash); exists {
+			if record.Timestamp > 0 {
+				return time.Unix(int64(record.Timestamp), 0), nil
+			}
+			return c.blockTime(record.SubmitBlockNumber, chain)
+		}
+	}
+
+	latest, err := c.chain(chain).client.HeaderByNumber(c.context(), nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	start := uint64(0)
+	if latest.Number.Uint64() > LATENCY_SCAN_BLOCKS {
+		start = latest.Number.Uint64() - LATENCY_SCAN_BLOCKS
+	}
+	events, err := c.chain(chain).testimoniumContract.FilterSubmitBlock(&bind.FilterOpts{Start: start})
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer events.Close()
+	for events.Next() {
+		if bytes.Equal(events.Event.Raw.Data, blockHash[:]) {
+			return c.blockTime(events.Event.Raw.BlockNumber, chain)
+		}
+	}
+	return time.Time{}, events.Error()
+}
@@ -0,0 +1,172 @@
+// This file contains the generation of proofs without block bodies, for light clients and bandwidth-constrained
+// providers that do not serve (or charge heavily for) full blocks. The tries are built from the transactions and
+// receipts fetched one by one by hash, account proofs are taken from eth_getProof.
+
+package testimonium
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pantos-io/go-ethrelay/proofs"
+)
+
+// LIGHT_PROOF_CONCURRENCY is the number of transactions or receipts fetched concurrently in the light proof mode.
+const LIGHT_PROOF_CONCURRENCY = 8
+
+// WithLightProofs builds the proofs of the chain without downloading block bodies (see the "lightproofs" entry of the
+// chain config): the transaction hashes of a block are read from its header request, the transactions and receipts
+// are fetched one by one with eth_getTransactionByHash and eth_getTransactionReceipt.
+func WithLightProofs(chain uint8) ClientOption {
+	return func(client *Client) error {
+		if client.lightProofChains == nil {
+			client.lightProofChains = make(map[uint8]bool)
+		}
+		client.lightProofChains[chain] = true
+		return nil
+	}
+}
+
+// lightProofsFromConfig reads the "lightproofs" entry of a chain config
+func lightProofsFromConfig(chainConfig map[string]interface{}) (bool, error) {
+	switch lightProofs := chainConfig["lightproofs"].(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return lightProofs, nil
+	default:
+		return false, fmt.Errorf("illegal lightproofs entry %v (has to be true or false)", lightProofs)
+	}
+}
+
+// blockTxHashes returns the hashes of the transactions of the block without their bodies
+func (c Client) blockTxHashes(blockHash common.Hash, chain uint8) ([]common.Hash, error) {
+	var block *struct {
+		Transactions []common.Hash `json:"transactions"`
+	}
+	err := c.withArchiveFallback(chain, "block "+blockHash.Hex(), func(_ *ethclient.Client, rpcClient *rpc.Client) error {
+		var raw json.RawMessage
+		if err := rpcClient.CallContext(c.context(), &raw, "eth_getBlockByHash", blockHash, false); err != nil {
+			return err
+		}
+		if len(raw) == 0 || string(raw) == "null" {
+			return fmt.Errorf("block %s %w", blockHash.Hex(), ethereum.NotFound)
+		}
+		return json.Unmarshal(raw, &block)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return block.Transactions, nil
+}
+
+// fetchConcurrently calls fetch for every index below n with at most LIGHT_PROOF_CONCURRENCY concurrent calls and
+// returns the first error
+func fetchConcurrently(n int, fetch func(i int) error) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	slots := make(chan struct{}, LIGHT_PROOF_CONCURRENCY)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := fetch(i); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// lightBlockTransactions returns the transactions of the block in their order, fetched one by one
+func (c Client) lightBlockTransactions(blockHash common.Hash, chain uint8) (types.Transactions, error) {
+	hashes, err := c.blockTxHashes(blockHash, chain)
+	if err != nil {
+		return nil, err
+	}
+	txs := make(types.Transactions, len(hashes))
+	err = fetchConcurrently(len(hashes), func(i int) error {
+		tx, _, err := c.Transaction(hashes[i], chain)
+		txs[i] = tx
+		return err
+	})
+	return txs, err
+}
+
+// lightBlockReceipts returns the receipts of the block in the order of the transactions, fetched one by one
+func (c Client) lightBlockReceipts(blockHash common.Hash, chain uint8) (types.Receipts, error) {
+	hashes, err := c.blockTxHashes(blockHash, chain)
+	if err != nil {
+		return nil, err
+	}
+	receipts := make(types.Receipts, len(hashes))
+	err = fetchConcurrently(len(hashes), func(i int) error {
+		receipt, err := c.transactionReceipt(hashes[i], chain)
+		receipts[i] = receipt
+		return err
+	})
+	return receipts, err
+}
+
+// accountProofResult is the result of eth_getProof, the storage proofs are not requested
+type accountProofResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+}
+
+// BuildAccountProof builds the Merkle Patricia proof of the account against the state root of the header of the block
+// with the specified hash, which is returned as well. The proof nodes are requested with eth_getProof, so neither the
+// block body nor the state is downloaded.
+func (c Client) BuildAccountProof(address common.Address, blockHash common.Hash, chain uint8) (proofs.Proof, *types.Header, error) {
+	c, span := c.startSpan("generate account proof", chain)
+	span.SetAttribute("ethrelay.account", address.Hex())
+	proof, header, err := c.buildAccountProof(address, blockHash, chain)
+	return proof, header, span.End(err)
+}
+
+func (c Client) buildAccountProof(address common.Address, blockHash common.Hash, chain uint8) (proofs.Proof, *types.Header, error) {
+	if err := c.checkChain(chain); err != nil {
+		return proofs.Proof{}, nil, err
+	}
+
+	header, err := c.headerByHash(blockHash, chain)
+	if err != nil {
+		return proofs.Proof{}, nil, err
+	}
+
+	var result *accountProofResult
+	err = c.withStateNode(chain, header.Number.Uint64(), fmt.Sprintf("state of block %s", blockHash.Hex()), func(_ *ethclient.Client, rpcClient *rpc.Client) error {
+		// the proof is requested by number, as nodes before EIP-1898 do not accept block hashes
+		return rpcClient.CallContext(c.context(), &result, "eth_getProof", address, []string{},
+			hexutil.EncodeBig(new(big.Int).Set(header.Number)))
+	})
+	if err != nil {
+		return proofs.Proof{}, nil, err
+	}
+	if result == nil {
+		return proofs.Proof{}, nil, fmt.Errorf("no proof of account %s in block %s", address.Hex(), blockHash.Hex())
+	}
+
+	nodes := make([][]byte, len(result.AccountProof))
+	for i, node := range result.AccountProof {
+		nodes[i] = node
+	}
+	// a proof of another block (e.g., after a reorganisation) does not lead to the state root of the header
+	proof, err := proofs.BuildAccountProof(header.Root, address, nodes)
+	if err != nil {
+		return proofs.Proof{}, nil, fmt.Errorf("invalid proof of account %s in block %s: %s", address.Hex(), blockHash.Hex(), err)
+	}
+	return proof, header, nil
+}
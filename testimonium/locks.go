@@ -0,0 +1,199 @@
+// This file contains the lock calendar of the headers submitted by the account: when the lock period of each header
+// passes, so it becomes incontestable and its stake is freed. The calendar is derived from the event index and used by
+// the status, the stake management of the live mode and the decommissioning.
+
+package testimonium
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// LockedHeader is a header submitted by the account whose lock period did not pass yet, so it can still be disputed
+// and its stake is locked.
+type LockedHeader struct {
+	BlockHash     common.Hash `json:"blockHash"`
+	BlockNumber   uint64      `json:"blockNumber"`
+	SubmitTxHash  common.Hash `json:"submitTxHash"`
+	Submitted     time.Time   `json:"submitted"`     // time of the block containing the submission
+	Incontestable time.Time   `json:"incontestable"` // end of the lock period, the stake of the header is freed then
+}
+
+// LockCalendar contains the headers of the account on a chain that are still locked, ordered by the end of their
+// lock periods. Headers removed by a dispute are not contained, their stake is not locked but lost.
+type LockCalendar struct {
+	Chain          uint8          `json:"chain"`
+	Account        common.Address `json:"account"`
+	LockPeriod     time.Duration  `json:"lockPeriod"`
+	ChainTime      time.Time      `json:"chainTime"`      // time of the latest block, lock periods are measured with it
+	StakePerHeader *big.Int       `json:"stakePerHeader"` // required stake per block of the contract
+	Headers        []LockedHeader `json:"headers"`
+}
+
+// LockedStake returns the stake locked by the headers of the calendar.
+func (calendar *LockCalendar) LockedStake() *big.Int {
+	return new(big.Int).Mul(calendar.StakePerHeader, big.NewInt(int64(len(calendar.Headers))))
+}
+
+// NextUnlock returns the time the stake of the next header is freed, nil if no header is locked.
+func (calendar *LockCalendar) NextUnlock() *time.Time {
+	if len(calendar.Headers) == 0 {
+		return nil
+	}
+	return &calendar.Headers[0].Incontestable
+}
+
+// LastUnlock returns the time the stake of all headers is freed, nil if no header is locked.
+func (calendar *LockCalendar) LastUnlock() *time.Time {
+	if len(calendar.Headers) == 0 {
+		return nil
+	}
+	return &calendar.Headers[len(calendar.Headers)-1].Incontestable
+}
+
+// FreedUntil returns the stake freed from the time of the calendar until the specified chain time.
+func (calendar *LockCalendar) FreedUntil(t time.Time) *big.Int {
+	freed := 0
+	for _, header := range calendar.Headers {
+		if header.Incontestable.After(t) {
+			break
+		}
+		freed++
+	}
+	return new(big.Int).Mul(calendar.StakePerHeader, big.NewInt(int64(freed)))
+}
+
+// localUnlocks returns the times the headers are unlocked according to the local clock
+func (calendar *LockCalendar) localUnlocks() []time.Time {
+	now := time.Now()
+	unlocks := make([]time.Time, len(calendar.Headers))
+	for i, header := range calendar.Headers {
+		unlocks[i] = now.Add(header.Incontestable.Sub(calendar.ChainTime))
+	}
+	return unlocks
+}
+
+// LockCalendar updates the event index of the chain in the data directory and returns the headers of the account
+// that are still locked. The lock period of the contract is read from it if lockPeriod is 0 (ErrUnknownLockPeriod if
+// it does not expose it, like the bundled contract).
+func (c Client) LockCalendar(dataDir string, chain uint8, lockPeriod time.Duration) (*LockCalendar, error) {
+	c = c.as(OPERATOR_SUBMITTER)
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, err
+	}
+	if lockPeriod == 0 {
+		contractLockPeriod, err := c.LockPeriod(chain)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownLockPeriod, err)
+		}
+		lockPeriod = time.Duration(contractLockPeriod.Int64()) * time.Second
+	}
+	index, _, err := c.UpdateEventIndex(dataDir, chain)
+	if err != nil {
+		return nil, err
+	}
+	return c.lockCalendar(index, chain, lockPeriod)
+}
+
+// lockCalendar returns the headers of the account in the index whose lock period did not pass on the chain
+func (c Client) lockCalendar(index *EventIndex, chain uint8, lockPeriod time.Duration) (*LockCalendar, error) {
+	c = c.as(OPERATOR_SUBMITTER)
+	head, err := c.HeaderByNumber(nil, chain)
+	if err != nil {
+		return nil, err
+	}
+	stakePerHeader, err := c.chain(chain).requiredStakePerBlock()
+	if err != nil {
+		return nil, err
+	}
+	calendar := &LockCalendar{
+		Chain:          chain,
+		Account:        c.account,
+		LockPeriod:     lockPeriod,
+		ChainTime:      time.Unix(int64(head.Time), 0).UTC(),
+		StakePerHeader: stakePerHeader,
+		Headers:        []LockedHeader{},
+	}
+
+	// the latest submissions first, the older ones are unlocked once the first unlocked one is found
+	records := index.SortedRecords()
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		if record.Submitter != c.account {
+			continue
+		}
+		submitted := time.Unix(int64(record.Timestamp), 0).UTC()
+		if record.Timestamp == 0 {
+			// indexed without timestamps
+			if submitted, err = c.blockTime(record.SubmitBlockNumber, chain); err != nil {
+				return nil, err
+			}
+			submitted = submitted.UTC()
+		}
+		incontestable := submitted.Add(lockPeriod)
+		if !incontestable.After(calendar.ChainTime) {
+			break
+		}
+		stored, err := c.chain(chain).testimoniumContract.IsHeaderStored(c.callOpts(), record.BlockHash)
+		if err != nil {
+			return nil, err
+		}
+		if !stored {
+			continue
+		}
+		header, err := decodeHeaderFromRLP(record.RlpHeader)
+		if err != nil {
+			return nil, fmt.Errorf("illegal header %s submitted in transaction %s: %s", record.BlockHash.Hex(), record.TxHash.Hex(), err)
+		}
+		calendar.Headers = append(calendar.Headers, LockedHeader{
+			BlockHash:     record.BlockHash,
+			BlockNumber:   header.Number.Uint64(),
+			SubmitTxHash:  record.TxHash,
+			Submitted:     submitted,
+			Incontestable: incontestable,
+		})
+	}
+	sort.SliceStable(calendar.Headers, func(i, j int) bool {
+		return calendar.Headers[i].Incontestable.Before(calendar.Headers[j].Incontestable)
+	})
+	return calendar, nil
+}
+
+// initialStakeQueue returns the times the stake of the headers the account submitted to the chain before is unlocked
+// (local clock) after the lock period, so the live mode does not count on stake that is still locked. It is empty
+// without event index.
+func (c Client) initialStakeQueue(chain uint8, lockPeriod time.Duration) []time.Time {
+	if c.indexDir == "" {
+		return nil
+	}
+	calendar, err := c.LockCalendar(c.indexDir, chain, lockPeriod)
+	if err != nil {
+		c.progressf("WARNING: Could not read the lock calendar of chain %d, assuming no stake is locked: %s\n", chain, err)
+		return nil
+	}
+	if next := calendar.NextUnlock(); next != nil {
+		c.progressf("%d headers submitted before are locked (%s wei), the next one is unlocked at %s\n",
+			len(calendar.Headers), calendar.LockedStake(), next.Format(time.RFC3339))
+	}
+	return calendar.localUnlocks()
+}
+
+// awaitFreeStake drops the unlocked headers from the queue of unlock times and, if the stake of capacity headers is
+// locked, waits until the next one is unlocked
+func (c Client) awaitFreeStake(queue []time.Time, capacity int) []time.Time {
+	for len(queue) > 0 && !queue[0].After(time.Now()) {
+		queue = queue[1:]
+	}
+	for len(queue) > 0 && len(queue) >= capacity {
+		if waitingTime := time.Until(queue[0]); waitingTime > 0 {
+			c.progressf("all stake is locked, waiting for %fs to continue\n", waitingTime.Seconds())
+			time.Sleep(waitingTime)
+		}
+		queue = queue[1:]
+	}
+	return queue
+}
@@ -0,0 +1,131 @@
+// This file contains the mutations of block headers used to submit illegitimate headers on purpose, e.g., to test
+// disputes. Every mutation is labeled with the return code of the PoWValidationResult event a dispute of the mutated
+// header is expected to emit.
+
+package testimonium
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// HeaderMutator changes a block header so that it is no longer the header of the source chain. ReturnCode is the
+// return code expected from a dispute of the mutated header (see POW_VALID), assuming the epoch data of the block is
+// set in the Ethash contract.
+type HeaderMutator struct {
+	Name        string
+	Description string
+	ReturnCode  uint64
+	mutate      func(header *types.Header)
+}
+
+// Mutate returns a mutated copy of the header, the header itself is not changed.
+func (m HeaderMutator) Mutate(header *types.Header) *types.Header {
+	mutated := types.CopyHeader(header)
+	m.mutate(mutated)
+	return mutated
+}
+
+func (m HeaderMutator) String() string {
+	return fmt.Sprintf("%s (%s, expected dispute return code %d)", m.Name, m.Description, m.ReturnCode)
+}
+
+// The header fields except the mix digest and the nonce are the input of the proof-of-work, so changing any of them
+// invalidates the nonce of the header.
+var (
+	MUTATE_STATE_ROOT = HeaderMutator{
+		Name:        "state-root",
+		Description: "wrong state root",
+		ReturnCode:  POW_DIFFICULTY_NOT_MET,
+		mutate: func(header *types.Header) {
+			header.Root = crypto.Keccak256Hash(header.Root.Bytes())
+		},
+	}
+	MUTATE_RECEIPTS_ROOT = HeaderMutator{
+		Name:        "receipts-root",
+		Description: "wrong receipts root",
+		ReturnCode:  POW_DIFFICULTY_NOT_MET,
+		mutate: func(header *types.Header) {
+			header.ReceiptHash = crypto.Keccak256Hash(header.ReceiptHash.Bytes())
+		},
+	}
+	MUTATE_TIMESTAMP = HeaderMutator{
+		Name:        "timestamp",
+		Description: "timestamp one second later",
+		ReturnCode:  POW_DIFFICULTY_NOT_MET,
+		mutate: func(header *types.Header) {
+			// later, so the header is still accepted after its parent
+			header.Time++
+		},
+	}
+	MUTATE_NONCE = HeaderMutator{
+		Name:        "nonce",
+		Description: "invalid proof-of-work nonce",
+		ReturnCode:  POW_DIFFICULTY_NOT_MET,
+		mutate: func(header *types.Header) {
+			header.Nonce = types.EncodeNonce(header.Nonce.Uint64() + 1)
+		},
+	}
+	MUTATE_DIFFICULTY = HeaderMutator{
+		Name:        "difficulty",
+		Description: "doubled difficulty, the branch gains total difficulty",
+		ReturnCode:  POW_DIFFICULTY_NOT_MET,
+		mutate: func(header *types.Header) {
+			header.Difficulty = new(big.Int).Lsh(header.Difficulty, 1)
+		},
+	}
+	// MUTATE_SWAP_ROOTS is the mutation of the former RandomizeHeader.
+	MUTATE_SWAP_ROOTS = HeaderMutator{
+		Name:        "swap-roots",
+		Description: "transactions, receipts and state roots swapped",
+		ReturnCode:  POW_DIFFICULTY_NOT_MET,
+		mutate: func(header *types.Header) {
+			header.TxHash, header.ReceiptHash, header.Root = header.ReceiptHash, header.Root, header.TxHash
+		},
+	}
+)
+
+// HeaderMutators returns all mutators ordered by name.
+func HeaderMutators() []HeaderMutator {
+	mutators := []HeaderMutator{MUTATE_STATE_ROOT, MUTATE_RECEIPTS_ROOT, MUTATE_TIMESTAMP, MUTATE_NONCE, MUTATE_DIFFICULTY,
+		MUTATE_SWAP_ROOTS}
+	sort.Slice(mutators, func(i, j int) bool { return mutators[i].Name < mutators[j].Name })
+	return mutators
+}
+
+// ParseHeaderMutators parses a comma-separated list of mutator names.
+func ParseHeaderMutators(names string) ([]HeaderMutator, error) {
+	var mutators []HeaderMutator
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, mutator := range HeaderMutators() {
+			if mutator.Name == name {
+				mutators = append(mutators, mutator)
+				found = true
+			}
+		}
+		if !found {
+			var known []string
+			for _, mutator := range HeaderMutators() {
+				known = append(known, mutator.Name)
+			}
+			return nil, fmt.Errorf("unknown header mutation '%s' (%s)", name, strings.Join(known, ", "))
+		}
+	}
+	return mutators, nil
+}
+
+// MutateHeader applies the mutators to a copy of the header one after the other.
+func MutateHeader(header *types.Header, mutators ...HeaderMutator) *types.Header {
+	mutated := types.CopyHeader(header)
+	for _, mutator := range mutators {
+		mutator.mutate(mutated)
+	}
+	return mutated
+}
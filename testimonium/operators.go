@@ -0,0 +1,299 @@
+// This file contains the operator roles of a relay daemon. The submit loop, the dispute watcher and the verification
+// service can sign with keys of their own instead of the account of the client, each with its own nonces and spending
+// limit, so a compromised key exposes only the funds and the duties of its role. Stake belongs to the submitter, as the
+// contract locks it per sender of the submissions.
+
+package testimonium
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrSpendingLimitExceeded is returned if a transaction would exceed the spending limit of its operator.
+var ErrSpendingLimitExceeded = errors.New("spending limit exceeded")
+
+// OperatorRole is a duty of a relay daemon that can be assigned a key of its own.
+type OperatorRole string
+
+const (
+	// submits block headers and owns the stake
+	OPERATOR_SUBMITTER OperatorRole = "submitter"
+	// disputes illegitimate block headers
+	OPERATOR_DISPUTER OperatorRole = "disputer"
+	// verifies transactions, receipts and states and pays the verification fees
+	OPERATOR_VERIFIER OperatorRole = "verifier"
+)
+
+// ParseOperatorRole parses the role names "submitter", "disputer" and "verifier".
+func ParseOperatorRole(role string) (OperatorRole, error) {
+	switch OperatorRole(strings.ToLower(role)) {
+	case OPERATOR_SUBMITTER:
+		return OPERATOR_SUBMITTER, nil
+	case OPERATOR_DISPUTER:
+		return OPERATOR_DISPUTER, nil
+	case OPERATOR_VERIFIER:
+		return OPERATOR_VERIFIER, nil
+	default:
+		return "", fmt.Errorf("unknown operator role '%s' (submitter, disputer, verifier)", role)
+	}
+}
+
+// SPENDING_LIMIT_PERIOD is the default period of operator spending limits.
+const SPENDING_LIMIT_PERIOD = 24 * time.Hour
+
+// NONCE_RESERVATION_TIMEOUT is the time a nonce handed out to an operator is reserved. Nonces of transactions that are
+// never sent (e.g., since the gas estimation failed) are handed out again afterwards.
+const NONCE_RESERVATION_TIMEOUT = time.Minute
+
+// OperatorConfig configures the key of an operator role.
+type OperatorConfig struct {
+	PrivateKey string // hex encoded (0x...)
+	// the operator spends at most this amount (in wei) per period and chain, gas limit times gas price plus value of
+	// every sent transaction, unlimited if nil
+	SpendingLimit  *big.Int
+	SpendingPeriod time.Duration // SPENDING_LIMIT_PERIOD if 0
+}
+
+// OperatorConfigFromConfig reads an entry of the "operators" section of the config file, e.g.,
+//
+//	operators:
+//	    disputer:
+//	        privatekey: 0x...
+//	        spendinglimit: "2000000000000000000"
+//	        spendingperiod: 24h
+func OperatorConfigFromConfig(operatorConfig map[string]interface{}) (OperatorConfig, error) {
+	var config OperatorConfig
+	config.PrivateKey, _ = operatorConfig["privatekey"].(string)
+	if config.PrivateKey == "" {
+		return config, fmt.Errorf("no private key configured")
+	}
+	if limit, exists := operatorConfig["spendinglimit"]; exists {
+		var ok bool
+		if config.SpendingLimit, ok = new(big.Int).SetString(fmt.Sprint(limit), 0); !ok || config.SpendingLimit.Sign() < 0 {
+			return config, fmt.Errorf("illegal spending limit %v (has to be an amount in wei)", limit)
+		}
+	}
+	if period, exists := operatorConfig["spendingperiod"]; exists {
+		var err error
+		if config.SpendingPeriod, err = time.ParseDuration(fmt.Sprint(period)); err != nil || config.SpendingPeriod <= 0 {
+			return config, fmt.Errorf("illegal spending period %v", period)
+		}
+	}
+	return config, nil
+}
+
+// WithOperator signs the transactions of the role with a key of its own instead of the private key of the client.
+func WithOperator(role OperatorRole, config OperatorConfig) ClientOption {
+	return func(client *Client) error {
+		privateKeyBytes, err := hexutil.Decode(config.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("could not decode the private key of the %s: %s", role, err)
+		}
+		privateKey, err := crypto.ToECDSA(privateKeyBytes)
+		if err != nil {
+			return fmt.Errorf("invalid private key of the %s: %s", role, err)
+		}
+		if config.SpendingLimit != nil && config.SpendingPeriod <= 0 {
+			config.SpendingPeriod = SPENDING_LIMIT_PERIOD
+		}
+
+		if client.operators == nil {
+			client.operators = make(map[OperatorRole]*operator)
+		}
+		client.operators[role] = &operator{
+			role:       role,
+			account:    crypto.PubkeyToAddress(privateKey.PublicKey),
+			privateKey: privateKey,
+			limit:      config.SpendingLimit,
+			period:     config.SpendingPeriod,
+			spent:      make(map[uint8][]*spending),
+			nonces:     make(map[uint8]nonceReservation),
+		}
+		return nil
+	}
+}
+
+// WithNonceReservation reserves the nonces of the client's own account like those of operators, so transactions sent
+// back to back (e.g., the operations of a batch) get consecutive nonces even if the node does not count the previous
+// ones as pending yet.
+func WithNonceReservation() ClientOption {
+	return func(client *Client) error {
+		client.reserveNonces = true
+		return nil
+	}
+}
+
+// operator is the key of a role with its spending and reserved nonces
+type operator struct {
+	role       OperatorRole
+	account    common.Address
+	privateKey *ecdsa.PrivateKey
+	limit      *big.Int // nil if unlimited
+	period     time.Duration
+	mutex      sync.Mutex
+	spent      map[uint8][]*spending      // transactions sent within the period by chain
+	nonces     map[uint8]nonceReservation // next nonce by chain
+}
+
+type spending struct {
+	time   time.Time
+	amount *big.Int
+}
+
+type nonceReservation struct {
+	next     uint64
+	reserved time.Time
+}
+
+// Operators returns the accounts of the operator roles with keys of their own.
+func (c Client) Operators() map[OperatorRole]common.Address {
+	accounts := make(map[OperatorRole]common.Address)
+	for role, op := range c.operators {
+		accounts[role] = op.account
+	}
+	return accounts
+}
+
+// as returns a copy of the client that signs with the key of the role, the client's own key if the role has none
+func (c Client) as(role OperatorRole) Client {
+	op, exists := c.operators[role]
+	if !exists {
+		c.account, c.privateKey = c.defaultAccount, c.defaultPrivateKey
+		return c
+	}
+	c.account, c.privateKey = op.account, op.privateKey
+	return c
+}
+
+// operatorsByAccount returns the operators by their accounts, including the client's own account if its nonces are
+// reserved
+func (c Client) operatorsByAccount() map[common.Address]*operator {
+	operators := make(map[common.Address]*operator)
+	if c.accountOperator != nil {
+		operators[c.accountOperator.account] = c.accountOperator
+	}
+	for _, op := range c.operators {
+		operators[op.account] = op
+	}
+	return operators
+}
+
+// reserveNonce returns the nonce of the operator's next transaction on the chain. Concurrent transactions of the
+// operator (e.g., of several verification workers) get consecutive nonces, even if the node does not count the
+// previous ones as pending yet.
+func (op *operator) reserveNonce(chain uint8, pendingNonce uint64) uint64 {
+	op.mutex.Lock()
+	defer op.mutex.Unlock()
+
+	nonce := pendingNonce
+	if reservation, exists := op.nonces[chain]; exists && reservation.next > nonce &&
+		time.Since(reservation.reserved) < NONCE_RESERVATION_TIMEOUT {
+		nonce = reservation.next
+	}
+	op.nonces[chain] = nonceReservation{next: nonce + 1, reserved: time.Now()}
+	return nonce
+}
+
+// releaseNonce hands out the nonce of a transaction that could not be sent again, if no later nonce was reserved
+func (op *operator) releaseNonce(chain uint8, nonce uint64) {
+	op.mutex.Lock()
+	defer op.mutex.Unlock()
+
+	if reservation, exists := op.nonces[chain]; exists && reservation.next == nonce+1 {
+		op.nonces[chain] = nonceReservation{next: nonce, reserved: reservation.reserved}
+	}
+}
+
+// charge records the maximum cost of the transaction, ErrSpendingLimitExceeded is returned if it exceeds the spending
+// limit of the operator. The returned record is nil without a limit.
+func (op *operator) charge(chain uint8, tx *types.Transaction) (*spending, error) {
+	if op.limit == nil {
+		return nil, nil
+	}
+	op.mutex.Lock()
+	defer op.mutex.Unlock()
+
+	now := time.Now()
+	var recent []*spending
+	total := tx.Cost()
+	for _, spent := range op.spent[chain] {
+		if now.Sub(spent.time) < op.period {
+			recent = append(recent, spent)
+			total.Add(total, spent.amount)
+		}
+	}
+	op.spent[chain] = recent
+	if total.Cmp(op.limit) > 0 {
+		return nil, fmt.Errorf("%w: the %s (%s) would spend %s wei within %s on chain %d (limit %s wei)",
+			ErrSpendingLimitExceeded, op.role, op.account.Hex(), total, op.period, chain, op.limit)
+	}
+	record := &spending{time: now, amount: tx.Cost()}
+	op.spent[chain] = append(recent, record)
+	return record, nil
+}
+
+// refund removes the record of a transaction that could not be sent
+func (op *operator) refund(chain uint8, record *spending) {
+	if record == nil {
+		return
+	}
+	op.mutex.Lock()
+	defer op.mutex.Unlock()
+
+	spent := op.spent[chain]
+	for i := range spent {
+		if spent[i] == record {
+			op.spent[chain] = append(spent[:i:i], spent[i+1:]...)
+			return
+		}
+	}
+}
+
+// sendAsOperator charges the sender of the transaction, if it is an operator, and sends the transaction. The nonce
+// and the cost of a transaction that cannot be sent are released.
+func (chain *Chain) sendAsOperator(tx *types.Transaction, send func() error) error {
+	op, exists := chain.operators[txSender(tx)]
+	if !exists {
+		return send()
+	}
+	record, err := op.charge(chain.id, tx)
+	if err != nil {
+		op.releaseNonce(chain.id, tx.Nonce())
+		return err
+	}
+	if err := send(); err != nil {
+		op.refund(chain.id, record)
+		op.releaseNonce(chain.id, tx.Nonce())
+		return err
+	}
+	return nil
+}
+
+// releaseOperatorNonce releases the nonce of a transaction of an operator that is not sent
+func (chain *Chain) releaseOperatorNonce(tx *types.Transaction) {
+	if op, exists := chain.operators[txSender(tx)]; exists {
+		op.releaseNonce(chain.id, tx.Nonce())
+	}
+}
+
+// signingKey returns the private key of the account, nil if it is neither the client's nor an operator's account
+func (b relayBackend) signingKey(account common.Address) *ecdsa.PrivateKey {
+	if op, exists := b.chain.operators[account]; exists {
+		return op.privateKey
+	}
+	if b.privateKey != nil && crypto.PubkeyToAddress(b.privateKey.PublicKey) == account {
+		return b.privateKey
+	}
+	return nil
+}
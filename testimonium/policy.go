@@ -0,0 +1,180 @@
+// This file contains the relay policies deciding which blocks of the source chain are submitted in live mode.
+
+package testimonium
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// RelayPolicy decides which new blocks of the source chain are relayed by SubmitHeaderLive. As the contract only
+// accepts headers extending a stored header, the missing ancestors of a relayed header are submitted along with it,
+// i.e., a policy determines when headers are submitted rather than which headers end up in the contract.
+type RelayPolicy interface {
+	ShouldRelay(client Client, header *types.Header, sourceChain uint8) (bool, error)
+}
+
+// RelayPolicyFunc is an adapter to use ordinary functions as relay policies.
+type RelayPolicyFunc func(client Client, header *types.Header, sourceChain uint8) (bool, error)
+
+func (f RelayPolicyFunc) ShouldRelay(client Client, header *types.Header, sourceChain uint8) (bool, error) {
+	return f(client, header, sourceChain)
+}
+
+// WithRelayPolicy sets the policy of SubmitHeaderLive, by default every block is relayed.
+func WithRelayPolicy(policy RelayPolicy) ClientOption {
+	return func(client *Client) error {
+		client.relayPolicy = policy
+		return nil
+	}
+}
+
+// RelayEveryBlock relays every block as soon as it is received.
+func RelayEveryBlock() RelayPolicy {
+	return RelayPolicyFunc(func(client Client, header *types.Header, sourceChain uint8) (bool, error) {
+		return true, nil
+	})
+}
+
+// RelayEveryNthBlock relays every block whose number is a multiple of n, together with the n-1 blocks before it.
+// The deposited stake has to suffice for n blocks.
+func RelayEveryNthBlock(n uint64) RelayPolicy {
+	return RelayPolicyFunc(func(client Client, header *types.Header, sourceChain uint8) (bool, error) {
+		return n <= 1 || header.Number.Uint64()%n == 0, nil
+	})
+}
+
+// RelayBlocksWithTxsTo relays the blocks containing a transaction to one of the addresses.
+func RelayBlocksWithTxsTo(addresses ...common.Address) RelayPolicy {
+	watched := make(map[common.Address]bool, len(addresses))
+	for _, address := range addresses {
+		watched[address] = true
+	}
+
+	return RelayPolicyFunc(func(client Client, header *types.Header, sourceChain uint8) (bool, error) {
+		if header.TxHash == types.EmptyRootHash {
+			return false, nil
+		}
+		block, err := client.chain(sourceChain).client.BlockByHash(context.Background(), header.Hash())
+		if err != nil {
+			return false, err
+		}
+		for _, tx := range block.Transactions() {
+			if tx.To() != nil && watched[*tx.To()] {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+// OnDemandPolicy relays blocks only if they were requested, e.g., because a verification needs them.
+type OnDemandPolicy struct {
+	mutex     sync.Mutex
+	requested map[uint64]bool
+}
+
+func NewOnDemandPolicy() *OnDemandPolicy {
+	return &OnDemandPolicy{requested: make(map[uint64]bool)}
+}
+
+// Request relays the block with the specified number (if it is not yet stored) together with the first block of the
+// source chain that reaches this number.
+func (p *OnDemandPolicy) Request(blockNumber uint64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.requested[blockNumber] = true
+}
+
+func (p *OnDemandPolicy) ShouldRelay(client Client, header *types.Header, sourceChain uint8) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	relay := false
+	for blockNumber := range p.requested {
+		if blockNumber <= header.Number.Uint64() {
+			delete(p.requested, blockNumber)
+			relay = true
+		}
+	}
+	return relay, nil
+}
+
+// ParseRelayPolicy parses the policy names "all", "every:N", "to:ADDRESS,ADDRESS,..." and
+// "adaptive:low=GWEI,max=GWEI,interval=N,maxskip=N,samples=N" (gas prices of the destination chain). The on-demand
+// policy has to be created with NewOnDemandPolicy as it needs requests from the application.
+func ParseRelayPolicy(policy string, destinationChain uint8) (RelayPolicy, error) {
+	name, argument := policy, ""
+	if i := strings.Index(policy, ":"); i >= 0 {
+		name, argument = policy[:i], policy[i+1:]
+	}
+
+	switch strings.ToLower(name) {
+	case "all":
+		return RelayEveryBlock(), nil
+	case "every":
+		n, err := strconv.ParseUint(argument, 10, 64)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("illegal block interval '%s'", argument)
+		}
+		return RelayEveryNthBlock(n), nil
+	case "to":
+		var addresses []common.Address
+		for _, address := range strings.Split(argument, ",") {
+			if !common.IsHexAddress(address) {
+				return nil, fmt.Errorf("illegal address '%s'", address)
+			}
+			addresses = append(addresses, common.HexToAddress(address))
+		}
+		return RelayBlocksWithTxsTo(addresses...), nil
+	case "adaptive":
+		config, err := parseAdaptiveConfig(argument, destinationChain)
+		if err != nil {
+			return nil, err
+		}
+		return NewAdaptivePolicy(config), nil
+	default:
+		return nil, fmt.Errorf("unknown relay policy '%s' (all, every:N, to:ADDRESS,..., adaptive:OPTIONS)", policy)
+	}
+}
+
+// relayHeader submits the header, if blocks before it were skipped by the relay policy they are submitted first
+func (c Client) relayHeader(header *types.Header, destinationChain uint8, sourceChain uint8, maxAncestors int) ([]*TxResult, error) {
+	// each header of the live mode is traced separately, so the traces of the daemon remain bounded
+	_, span := c.tracer.StartRoot(c.context(), "relay header")
+	if span != nil {
+		c.span = span
+	}
+	span.SetAttribute("ethrelay.chain", destinationChain)
+	span.SetAttribute("ethrelay.source_chain", sourceChain)
+	span.SetAttribute("ethrelay.block_number", header.Number.Uint64())
+	results, err := c.relayHeaderUntraced(header, destinationChain, sourceChain, maxAncestors)
+	span.SetAttribute("ethrelay.submitted_headers", len(results))
+	return results, span.End(err)
+}
+
+func (c Client) relayHeaderUntraced(header *types.Header, destinationChain uint8, sourceChain uint8, maxAncestors int) ([]*TxResult, error) {
+	isParentStored, err := c.chain(destinationChain).testimoniumContract.IsHeaderStored(c.callOpts(), header.ParentHash)
+	if err != nil {
+		return nil, err
+	}
+	if !isParentStored {
+		return c.SubmitHeaderWithAncestors(header, destinationChain, sourceChain, maxAncestors)
+	}
+
+	if err := c.ValidateHeader(header, sourceChain); err != nil {
+		return nil, err
+	}
+	result, err := c.SubmitHeader(header, destinationChain)
+	if err != nil {
+		return nil, err
+	}
+	c.archiveBody(header, sourceChain)
+	return []*TxResult{result}, nil
+}
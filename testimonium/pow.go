@@ -0,0 +1,67 @@
+// This file contains the local verification of the proof-of-work of block headers using the ethash package.
+
+package testimonium
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+)
+
+// PoWVerification contains the values computed during the local proof-of-work verification of a header.
+type PoWVerification struct {
+	BlockNumber       uint64
+	Epoch             uint64
+	HashWithoutNonce  common.Hash
+	Nonce             uint64
+	MixDigest         common.Hash // mix digest contained in the header
+	ComputedMixDigest common.Hash
+	Result            *big.Int // proof-of-work result, has to be less or equal to the target
+	Target            *big.Int
+}
+
+// VerifyPoW verifies the proof-of-work of the header locally without any on-chain interaction.
+// The returned verification is filled even if the proof-of-work is invalid, in which case the error wraps
+// ethash.ErrInvalidMixDigest or ethash.ErrInvalidPoW.
+func VerifyPoW(header *types.Header) (PoWVerification, error) {
+	if header.Difficulty.Sign() <= 0 {
+		return PoWVerification{}, fmt.Errorf("%w: difficulty of block %s is not positive", ethash.ErrInvalidPoW, header.Number)
+	}
+
+	hashWithoutNonce, err := HeaderHashWithoutNonce(header)
+	if err != nil {
+		return PoWVerification{}, err
+	}
+
+	verification := PoWVerification{
+		BlockNumber:      header.Number.Uint64(),
+		Epoch:            header.Number.Uint64() / 30000,
+		HashWithoutNonce: hashWithoutNonce,
+		Nonce:            header.Nonce.Uint64(),
+		MixDigest:        header.MixDigest,
+		Target:           ethash.Target(header.Difficulty),
+	}
+	verification.ComputedMixDigest, verification.Result = ethash.ComputeSeal(verification.BlockNumber, hashWithoutNonce, verification.Nonce)
+
+	if verification.ComputedMixDigest != verification.MixDigest {
+		return verification, fmt.Errorf("%w: computed %s, header contains %s", ethash.ErrInvalidMixDigest, verification.ComputedMixDigest.String(), verification.MixDigest.String())
+	}
+	if verification.Result.Cmp(verification.Target) > 0 {
+		return verification, fmt.Errorf("%w: result %s exceeds target %s", ethash.ErrInvalidPoW, verification.Result, verification.Target)
+	}
+
+	return verification, nil
+}
+
+// VerifyPoWOfBlock fetches the header with the specified number from the chain and verifies its proof-of-work locally
+// (see VerifyPoW).
+func (c Client) VerifyPoWOfBlock(blockNumber *big.Int, chain uint8) (PoWVerification, error) {
+	header, err := c.HeaderByNumber(blockNumber, chain)
+	if err != nil {
+		return PoWVerification{}, err
+	}
+	return VerifyPoW(header)
+}
@@ -0,0 +1,234 @@
+// This file contains the checks of verification parameters before a verification is sent. The contract only reports a
+// failed verification after the gas is spent, the checks return descriptive errors instead.
+
+package testimonium
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// ErrWrongVerificationFee is returned if the fee of a verification differs from the fee required by the contract.
+	ErrWrongVerificationFee = errors.New("wrong verification fee")
+	// ErrHeaderNotStored is returned if the header a verification refers to is not stored in the contract.
+	ErrHeaderNotStored = errors.New("header not stored")
+	// ErrNotOnLongestBranch is returned if the header a verification refers to is not part of the longest branch.
+	ErrNotOnLongestBranch = errors.New("header not part of the longest branch")
+	// ErrNotEnoughConfirmations is returned if the longest branch has fewer headers on top of the verified header than
+	// the requested confirmations.
+	ErrNotEnoughConfirmations = errors.New("not enough confirmations")
+	// ErrPinnedBranchReplaced is returned if the branch a verification is pinned to (see OnBranch) is not part of the
+	// longest branch, e.g., since another fork overtook it.
+	ErrPinnedBranchReplaced = errors.New("pinned branch not part of the longest branch")
+)
+
+// PREFLIGHT_MAX_BRANCH_DEPTH is the maximum number of headers walked back from the longest chain endpoint to check that
+// a header is part of the longest branch. Older headers are not checked.
+const PREFLIGHT_MAX_BRANCH_DEPTH = 1024
+
+// VerifyOption configures a single verification (see VerifyMerkleProof).
+type VerifyOption func(options *verifyOptions)
+
+type verifyOptions struct {
+	branchEndpoint *common.Hash // the verification is pinned to the branch ending at this header if set
+}
+
+// OnBranch pins a verification to the branch ending at the stored header with the endpoint hash. The contract verifies
+// against its longest branch, so while several forks are stored, the verification is only sent if the endpoint is
+// part of the longest branch and the verified header is part of the branch of the endpoint with its confirmations
+// counted up to the endpoint. Otherwise it fails with ErrPinnedBranchReplaced or ErrNotOnLongestBranch instead of
+// depending on whichever fork is the longest at the time. The contract may still switch to another fork before the
+// verification is included.
+func OnBranch(endpoint common.Hash) VerifyOption {
+	return func(options *verifyOptions) {
+		options.branchEndpoint = &endpoint
+	}
+}
+
+func newVerifyOptions(opts []VerifyOption) verifyOptions {
+	var options verifyOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// CheckVerification checks that a verification with the specified fee, header and confirmations would be accepted by
+// the Testimonium contract on the chain: the fee equals the required fee, the header is stored and part of the longest
+// branch and there are at least noOfConfirmations headers on top of it. With OnBranch, the header has to be part of
+// the pinned branch instead, which has to be part of the longest branch.
+func (c Client) CheckVerification(feeInWei *big.Int, rlpHeader []byte, noOfConfirmations uint8, chain uint8, opts ...VerifyOption) error {
+	if err := c.checkTestimonium(chain); err != nil {
+		return err
+	}
+	contract := c.chain(chain).testimoniumContract
+	options := newVerifyOptions(opts)
+
+	// read without the view cache, the fee may have changed since it was read for the verification
+	requiredFee, err := contract.GetRequiredVerificationFee(nil)
+	if err != nil {
+		return err
+	}
+	if feeInWei == nil || feeInWei.Cmp(requiredFee) != 0 {
+		return fmt.Errorf("%w: %s wei instead of the required %s wei", ErrWrongVerificationFee, feeInWei, requiredFee)
+	}
+
+	blockHash := crypto.Keccak256Hash(rlpHeader)
+	isStored, err := contract.IsHeaderStored(nil, blockHash)
+	if err != nil {
+		return err
+	}
+	if !isStored {
+		return fmt.Errorf("%w: %s", ErrHeaderNotStored, blockHash.Hex())
+	}
+	header, err := contract.GetHeader(nil, blockHash)
+	if err != nil {
+		return err
+	}
+
+	endpoint, err := contract.GetLongestChainEndpoint(nil)
+	if err != nil {
+		return err
+	}
+	endpointHeader, err := contract.GetHeader(nil, endpoint)
+	if err != nil {
+		return err
+	}
+
+	blockNumber := header.BlockNumber.Uint64()
+	endpointNumber := endpointHeader.BlockNumber.Uint64()
+	branch := "longest"
+	if options.branchEndpoint != nil {
+		// the confirmations are counted up to the pinned endpoint, which the longest branch contains
+		if endpointNumber, err = c.checkPinnedBranch(*options.branchEndpoint, endpoint, endpointNumber, chain); err != nil {
+			return err
+		}
+		endpoint, branch = *options.branchEndpoint, "pinned"
+	}
+	if endpointNumber < blockNumber {
+		return fmt.Errorf("%w: block %d is beyond the %s chain endpoint %d", ErrNotOnLongestBranch, blockNumber, branch, endpointNumber)
+	}
+	if confirmations := endpointNumber - blockNumber; confirmations < uint64(noOfConfirmations) {
+		return fmt.Errorf("%w: block %d has %d of %d confirmations", ErrNotEnoughConfirmations, blockNumber, confirmations, noOfConfirmations)
+	}
+
+	if endpointNumber-blockNumber > PREFLIGHT_MAX_BRANCH_DEPTH {
+		if options.branchEndpoint != nil {
+			return fmt.Errorf("%w: block %d is more than %d blocks behind the pinned endpoint, its branch cannot be checked",
+				ErrNotOnLongestBranch, blockNumber, PREFLIGHT_MAX_BRANCH_DEPTH)
+		}
+		c.progressf("WARNING: Block %d is more than %d blocks behind the longest chain endpoint, not checking its branch\n", blockNumber, PREFLIGHT_MAX_BRANCH_DEPTH)
+		return nil
+	}
+	ancestor, err := c.storedAncestor(endpoint, endpointNumber-blockNumber, chain)
+	if err != nil {
+		return fmt.Errorf("failed to check the branch of block %d: %s", blockNumber, err)
+	}
+	if ancestor != blockHash {
+		return fmt.Errorf("%w: block %d of the %s branch is %s, not %s", ErrNotOnLongestBranch, blockNumber, branch, ancestor.Hex(), blockHash.Hex())
+	}
+	return nil
+}
+
+// checkPinnedBranch checks that the pinned endpoint is stored and part of the branch of the longest chain endpoint and
+// returns its block number
+func (c Client) checkPinnedBranch(pinned common.Hash, longestEndpoint common.Hash, longestNumber uint64, chain uint8) (uint64, error) {
+	contract := c.chain(chain).testimoniumContract
+	isStored, err := contract.IsHeaderStored(nil, pinned)
+	if err != nil {
+		return 0, err
+	}
+	if !isStored {
+		return 0, fmt.Errorf("%w: pinned endpoint %s", ErrHeaderNotStored, pinned.Hex())
+	}
+	pinnedHeader, err := contract.GetHeader(nil, pinned)
+	if err != nil {
+		return 0, err
+	}
+
+	pinnedNumber := pinnedHeader.BlockNumber.Uint64()
+	if pinnedNumber > longestNumber {
+		return 0, fmt.Errorf("%w: endpoint %s (block %d) is beyond the longest chain endpoint %d", ErrPinnedBranchReplaced,
+			pinned.Hex(), pinnedNumber, longestNumber)
+	}
+	if longestNumber-pinnedNumber > PREFLIGHT_MAX_BRANCH_DEPTH {
+		return 0, fmt.Errorf("%w: endpoint %s is more than %d blocks behind the longest chain endpoint, its branch cannot be checked",
+			ErrPinnedBranchReplaced, pinned.Hex(), PREFLIGHT_MAX_BRANCH_DEPTH)
+	}
+	ancestor, err := c.storedAncestor(longestEndpoint, longestNumber-pinnedNumber, chain)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check the branch of the pinned endpoint %s: %s", pinned.Hex(), err)
+	}
+	if ancestor != pinned {
+		return 0, fmt.Errorf("%w: block %d of the longest branch is %s, not %s", ErrPinnedBranchReplaced, pinnedNumber,
+			ancestor.Hex(), pinned.Hex())
+	}
+	return pinnedNumber, nil
+}
+
+// storedAncestor returns the hash of the stored ancestor that is depth blocks behind the specified block. The parent
+// hashes are taken from the submitted headers, which are looked up in the event index or the SubmitBlock events.
+func (c Client) storedAncestor(blockHash common.Hash, depth uint64, chain uint8) (common.Hash, error) {
+	if depth == 0 {
+		return blockHash, nil
+	}
+
+	var index *EventIndex
+	if c.indexDir != "" {
+		var err error
+		index, err = OpenEventIndex(c.indexDir, chain, c.chain(chain).testimoniumContractAddress)
+		if err != nil {
+			return common.Hash{}, err
+		}
+	}
+
+	// the submit transactions of headers missing in the index are found by scanning the events once
+	var submitTxs map[common.Hash]common.Hash
+	rlpHeader := func(hash common.Hash) ([]byte, error) {
+		if index != nil {
+			if record, exists := index.Lookup(hash); exists {
+				return record.RlpHeader, nil
+			}
+		}
+		if submitTxs == nil {
+			eventIterator, err := c.chain(chain).testimoniumContract.FilterSubmitBlock(nil)
+			if err != nil {
+				return nil, err
+			}
+			submitTxs = make(map[common.Hash]common.Hash)
+			for eventIterator.Next() {
+				submitTxs[eventIterator.Event.BlockHash] = eventIterator.Event.Raw.TxHash
+			}
+			if err := eventIterator.Error(); err != nil {
+				return nil, err
+			}
+		}
+		txHash, exists := submitTxs[hash]
+		if !exists {
+			return nil, fmt.Errorf("no SubmitBlock event of block %s", hash.Hex())
+		}
+		tx, _, err := c.chain(chain).client.TransactionByHash(c.context(), txHash)
+		if err != nil {
+			return nil, err
+		}
+		return c.chain(chain).rlpHeaderFromSubmitTx(tx)
+	}
+
+	for ; depth > 0; depth-- {
+		encoded, err := rlpHeader(blockHash)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		header, err := decodeHeaderFromRLP(encoded)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		blockHash = header.ParentHash
+	}
+	return blockHash, nil
+}
@@ -0,0 +1,221 @@
+// This file contains the submission of dispute transactions to private relays. Disputes sent to the public mempool can
+// be front-run by the submitter of the disputed header, e.g., by withdrawing the stake before the dispute is mined.
+
+package testimonium
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// FLASHBOTS_RELAY_URL is the default endpoint of the Flashbots relay (eth_sendPrivateTransaction).
+const FLASHBOTS_RELAY_URL = "https://relay.flashbots.net"
+
+// FLASHBOTS_PROTECT_URL is the default endpoint of Flashbots Protect, whose transactions take part in MEV-Share.
+const FLASHBOTS_PROTECT_URL = "https://rpc.flashbots.net/fast"
+
+// PRIVATE_TX_MAX_BLOCKS is the default number of blocks a private transaction may be included in.
+const PRIVATE_TX_MAX_BLOCKS = 25
+
+// PRIVATE_TX_RECEIPT_TIMEOUT is the time the receipt of a private transaction is awaited. Private transactions are
+// only included by participating builders and may take longer than public ones.
+const PRIVATE_TX_RECEIPT_TIMEOUT = 10 * time.Minute
+
+// PrivateRelay sends signed transactions to block builders without publishing them in the public mempool.
+type PrivateRelay interface {
+	// SendPrivateTransaction sends the RLP encoded signed transaction, which is dropped if it is not included up to
+	// the block with the specified number.
+	SendPrivateTransaction(ctx context.Context, rawTx []byte, maxBlockNumber uint64) error
+}
+
+// WithPrivateRelay sends the dispute transactions of the account on the specified chain to the private relay.
+func WithPrivateRelay(chain uint8, relay PrivateRelay) ClientOption {
+	return func(client *Client) error {
+		if client.privateRelays == nil {
+			client.privateRelays = make(map[uint8]PrivateRelay)
+		}
+		client.privateRelays[chain] = relay
+		return nil
+	}
+}
+
+// NewPrivateRelayFromConfig creates the private relay specified by the "privaterelay" entry of a chain config, e.g.,
+//
+//	privaterelay:
+//	    type: flashbots        # eth_sendPrivateTransaction, or 'protect' for eth_sendRawTransaction to a private RPC
+//	    url: https://relay.flashbots.net
+//	    signingkey: 0x...      # key authenticating the requests (flashbots only), a random key if not set
+func NewPrivateRelayFromConfig(relayConfig map[string]interface{}) (PrivateRelay, error) {
+	relayType, _ := relayConfig["type"].(string)
+	url, _ := relayConfig["url"].(string)
+
+	switch strings.ToLower(relayType) {
+	case "flashbots":
+		if url == "" {
+			url = FLASHBOTS_RELAY_URL
+		}
+		var signingKey *ecdsa.PrivateKey
+		var err error
+		if keyHex, _ := relayConfig["signingkey"].(string); keyHex != "" {
+			signingKey, err = crypto.HexToECDSA(strings.TrimPrefix(keyHex, "0x"))
+		} else {
+			signingKey, err = crypto.GenerateKey()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("illegal signing key of the private relay: %s", err)
+		}
+		return NewFlashbotsRelay(url, signingKey), nil
+	case "protect":
+		if url == "" {
+			url = FLASHBOTS_PROTECT_URL
+		}
+		return NewProtectRelay(url), nil
+	default:
+		return nil, fmt.Errorf("unknown private relay type '%s' (flashbots, protect)", relayType)
+	}
+}
+
+// FlashbotsRelay sends transactions with eth_sendPrivateTransaction, the requests are signed with the signing key as
+// required by the Flashbots relay.
+type FlashbotsRelay struct {
+	url        string
+	signingKey *ecdsa.PrivateKey
+	httpClient *http.Client
+}
+
+func NewFlashbotsRelay(url string, signingKey *ecdsa.PrivateKey) *FlashbotsRelay {
+	return &FlashbotsRelay{url: url, signingKey: signingKey, httpClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (r *FlashbotsRelay) SendPrivateTransaction(ctx context.Context, rawTx []byte, maxBlockNumber uint64) error {
+	params := map[string]interface{}{
+		"tx":             hexutil.Encode(rawTx),
+		"maxBlockNumber": hexutil.EncodeUint64(maxBlockNumber),
+		"preferences":    map[string]interface{}{"fast": true},
+	}
+	body, err := json.Marshal(privateRpcRequest("eth_sendPrivateTransaction", params))
+	if err != nil {
+		return err
+	}
+
+	// the signature is the personal signature of the hex encoded hash of the body
+	hash := crypto.Keccak256Hash(body).Hex()
+	signature, err := crypto.Sign(accountsTextHash([]byte(hash)), r.signingKey)
+	if err != nil {
+		return err
+	}
+	header := crypto.PubkeyToAddress(r.signingKey.PublicKey).Hex() + ":" + hexutil.Encode(signature)
+
+	return postPrivateRpc(ctx, r.httpClient, r.url, body, map[string]string{"X-Flashbots-Signature": header})
+}
+
+// ProtectRelay sends transactions with eth_sendRawTransaction to a private RPC endpoint (e.g., Flashbots Protect, whose
+// URL parameters select the MEV-Share hints), which does not forward them to the public mempool.
+type ProtectRelay struct {
+	url        string
+	httpClient *http.Client
+}
+
+func NewProtectRelay(url string) *ProtectRelay {
+	return &ProtectRelay{url: url, httpClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (r *ProtectRelay) SendPrivateTransaction(ctx context.Context, rawTx []byte, maxBlockNumber uint64) error {
+	body, err := json.Marshal(privateRpcRequest("eth_sendRawTransaction", hexutil.Encode(rawTx)))
+	if err != nil {
+		return err
+	}
+	return postPrivateRpc(ctx, r.httpClient, r.url, body, nil)
+}
+
+func privateRpcRequest(method string, param interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  []interface{}{param},
+	}
+}
+
+func postPrivateRpc(ctx context.Context, httpClient *http.Client, url string, body []byte, headers map[string]string) error {
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("private relay %s: %s: %s", url, response.Status, strings.TrimSpace(string(responseBody)))
+	}
+
+	var rpcResponse struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(responseBody, &rpcResponse); err != nil {
+		return fmt.Errorf("private relay %s: malformed response: %s", url, err)
+	}
+	if rpcResponse.Error != nil {
+		return fmt.Errorf("private relay %s: %s", url, rpcResponse.Error.Message)
+	}
+	return nil
+}
+
+// accountsTextHash is the hash signed by personal_sign (EIP-191)
+func accountsTextHash(data []byte) []byte {
+	return crypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(data), data)))
+}
+
+type privateSubmissionKey struct{}
+
+// withPrivateSubmission marks the transactions sent with the context to be sent to the private relay of the chain
+func withPrivateSubmission(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, privateSubmissionKey{}, true)
+}
+
+func isPrivateSubmission(ctx context.Context) bool {
+	private, _ := ctx.Value(privateSubmissionKey{}).(bool)
+	return private
+}
+
+// sendPrivateTransaction sends the signed transaction to the private relay of the chain
+func (b relayBackend) sendPrivateTransaction(ctx context.Context, rawTx []byte) error {
+	head, err := b.Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	maxBlockNumber := head.Number.Uint64() + PRIVATE_TX_MAX_BLOCKS
+	if err := b.chain.privateRelay.SendPrivateTransaction(ctx, rawTx, maxBlockNumber); err != nil {
+		return fmt.Errorf("failed to send transaction to the private relay: %w", err)
+	}
+	b.progressf("Tx sent to the private relay, valid up to block %d\n", maxBlockNumber)
+	return nil
+}
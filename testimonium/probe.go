@@ -0,0 +1,129 @@
+// This file contains the health probe of a chain, which checks everything the client needs from a chain's node and
+// contracts in a single call.
+
+package testimonium
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ChainProbe is the result of probing a chain. Checks that failed are listed in Errors, the other fields of a failed
+// check keep their zero values.
+type ChainProbe struct {
+	Chain              uint8         `json:"chain"`
+	Latency            time.Duration `json:"latency"` // of a eth_blockNumber request
+	Syncing            bool          `json:"syncing"`
+	CurrentBlock       uint64        `json:"currentBlock"`
+	HighestBlock       uint64        `json:"highestBlock"` // only set while the node is syncing
+	HeadAge            time.Duration `json:"headAge"`      // time since the latest block was mined
+	EthrelayReachable  bool          `json:"ethrelayReachable"`
+	EthashReachable    bool          `json:"ethashReachable"`
+	Role               ChainRole     `json:"role"`
+	Balance            *big.Int      `json:"balance"`            // not queried for source chains
+	Nonce              uint64        `json:"nonce"`              // pending nonce of the account
+	Subscriptions      bool          `json:"subscriptions"`      // whether new heads can be subscribed to (live mode)
+	Capabilities       Capabilities  `json:"capabilities"`       // probed when the chain was connected
+	TransactionsUsable bool          `json:"transactionsUsable"` // false if the chain ids of the node do not match the config
+	Errors             []string      `json:"errors,omitempty"`
+}
+
+// Healthy reports whether the node is reachable, in sync, matches the configured chain ids (unless it is a source
+// chain) and all checks succeeded.
+func (probe ChainProbe) Healthy() bool {
+	return len(probe.Errors) == 0 && !probe.Syncing && (probe.TransactionsUsable || probe.Role == ROLE_SOURCE)
+}
+
+// Probe checks the connection to the chain's node, its sync status, the age of its latest block, the configured
+// contracts, the balance and nonce of the account and whether the node supports subscriptions. The probe contains the
+// capabilities of the node probed when the chain was connected as well. Only an unknown chain is returned as error,
+// the failures of single checks are contained in the probe.
+func (c Client) Probe(chain uint8) (ChainProbe, error) {
+	if err := c.checkChain(chain); err != nil {
+		return ChainProbe{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(c.context(), 30*time.Second)
+	defer cancel()
+
+	client := c.chain(chain).client
+	probe := ChainProbe{Chain: chain, Role: c.chain(chain).role, TransactionsUsable: c.chain(chain).idMismatch == nil,
+		Capabilities: c.chain(chain).capabilities}
+	fail := func(check string, err error) {
+		probe.Errors = append(probe.Errors, fmt.Sprintf("%s: %s", check, err))
+	}
+
+	start := time.Now()
+	var blockNumber string
+	if err := c.chain(chain).rpcClient.CallContext(ctx, &blockNumber, "eth_blockNumber"); err != nil {
+		// the node is not reachable, the other checks would fail as well
+		fail("rpc", err)
+		return probe, nil
+	}
+	probe.Latency = time.Since(start)
+
+	progress, err := client.SyncProgress(ctx)
+	if err != nil {
+		fail("sync status", err)
+	} else if progress != nil {
+		probe.Syncing = true
+		probe.CurrentBlock = progress.CurrentBlock
+		probe.HighestBlock = progress.HighestBlock
+	}
+
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		fail("latest block", err)
+	} else {
+		if !probe.Syncing {
+			probe.CurrentBlock = head.Number.Uint64()
+		}
+		probe.HeadAge = time.Since(time.Unix(int64(head.Time), 0)).Round(time.Second)
+	}
+
+	if c.chain(chain).testimoniumContract != nil {
+		code, err := client.CodeAt(ctx, c.chain(chain).testimoniumContractAddress, nil)
+		if err != nil {
+			fail("ETH Relay contract", err)
+		} else if len(code) == 0 {
+			fail("ETH Relay contract", fmt.Errorf("no code at %s", c.chain(chain).testimoniumContractAddress.Hex()))
+		} else {
+			probe.EthrelayReachable = true
+		}
+	}
+	if c.chain(chain).ethashContract != nil {
+		code, err := client.CodeAt(ctx, c.chain(chain).ethashContractAddress, nil)
+		if err != nil {
+			fail("Ethash contract", err)
+		} else if len(code) == 0 {
+			fail("Ethash contract", fmt.Errorf("no code at %s", c.chain(chain).ethashContractAddress.Hex()))
+		} else {
+			probe.EthashReachable = true
+		}
+	}
+
+	// the account is only used on chains transactions are sent to
+	if probe.Role != ROLE_SOURCE && c.HasAccount() {
+		probe.Balance, err = client.BalanceAt(ctx, c.account, nil)
+		if err != nil {
+			fail("balance", err)
+		}
+		probe.Nonce, err = client.PendingNonceAt(ctx, c.account)
+		if err != nil {
+			fail("nonce", err)
+		}
+	}
+
+	// subscriptions are only supported by websocket and IPC connections
+	heads := make(chan *types.Header)
+	if sub, err := client.SubscribeNewHead(ctx, heads); err == nil {
+		probe.Subscriptions = true
+		sub.Unsubscribe()
+	}
+
+	return probe, nil
+}
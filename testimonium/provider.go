@@ -0,0 +1,211 @@
+// This file contains the handling of hosted node providers (e.g., Infura, Alchemy) that require API keys and limit the
+// number of requests per day. Requests are slowed down when the daily quota is nearly used up and paused when it is
+// exhausted, instead of failing with HTTP 429 errors in the middle of an operation.
+
+package testimonium
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExhausted is returned if the provider keeps rejecting requests with HTTP 429 with all configured API keys.
+var ErrQuotaExhausted = errors.New("provider quota exhausted")
+
+// API_KEY_PLACEHOLDER is replaced with the current API key in the URL of a chain, e.g., mainnet.infura.io/v3/{apikey}.
+const API_KEY_PLACEHOLDER = "{apikey}"
+
+// number of rejected rounds over all API keys before ErrQuotaExhausted is returned
+const maxRateLimitedRounds = 5
+
+// ProviderConfig is the "provider" entry of a chain config, e.g.,
+//
+//	provider:
+//	    apikeys: [<key1>, <key2>]
+//	    apikeyheader: X-Api-Key
+//	    dailyquota: 100000
+//	    slowdownat: 0.8
+type ProviderConfig struct {
+	ApiKeys      []string // used in turn, the next key is used if the quota of a key is exhausted
+	ApiKeyHeader string   // HTTP header the API key is sent in, if empty the key has to be part of the URL
+	DailyQuota   int      // requests per day and API key (UTC), unlimited if zero
+	SlowDownAt   float64  // fraction of the daily quota from which requests are spread over the rest of the day
+}
+
+// providerConfigFromChainConfig reads the provider entry of a chain config, nil is returned if there is none
+func providerConfigFromChainConfig(chainConfig map[string]interface{}) (*ProviderConfig, error) {
+	entry, ok := chainConfig["provider"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	config := &ProviderConfig{SlowDownAt: 0.8}
+	switch keys := entry["apikeys"].(type) {
+	case []interface{}:
+		for _, key := range keys {
+			config.ApiKeys = append(config.ApiKeys, fmt.Sprint(key))
+		}
+	case string:
+		config.ApiKeys = strings.Split(keys, ",")
+	}
+	if key, ok := entry["apikey"].(string); ok {
+		config.ApiKeys = append(config.ApiKeys, key)
+	}
+	config.ApiKeyHeader, _ = entry["apikeyheader"].(string)
+
+	if quota, exists := entry["dailyquota"]; exists {
+		dailyQuota, err := strconv.Atoi(fmt.Sprint(quota))
+		if err != nil {
+			return nil, fmt.Errorf("illegal daily quota: %v", quota)
+		}
+		config.DailyQuota = dailyQuota
+	}
+	if slowDownAt, exists := entry["slowdownat"]; exists {
+		fraction, err := strconv.ParseFloat(fmt.Sprint(slowDownAt), 64)
+		if err != nil || fraction <= 0 || fraction > 1 {
+			return nil, fmt.Errorf("illegal slowdownat: %v (has to be in (0, 1])", slowDownAt)
+		}
+		config.SlowDownAt = fraction
+	}
+	return config, nil
+}
+
+// providerTransport adds the API keys to the requests and enforces the daily quota
+type providerTransport struct {
+	config    ProviderConfig
+	transport http.RoundTripper
+	progressf func(format string, args ...interface{})
+
+	mutex        sync.Mutex
+	day          int64       // day (UTC) the usage is counted for
+	used         []int       // requests sent with each key on this day
+	limitedUntil []time.Time // keys rejected with HTTP 429 are not used until then
+	current      int         // index of the key in use
+}
+
+func newProviderTransport(config ProviderConfig, transport http.RoundTripper, progressf func(format string, args ...interface{})) *providerTransport {
+	if len(config.ApiKeys) == 0 {
+		config.ApiKeys = []string{""}
+	}
+	return &providerTransport{
+		config:       config,
+		transport:    transport,
+		progressf:    progressf,
+		used:         make([]int, len(config.ApiKeys)),
+		limitedUntil: make([]time.Time, len(config.ApiKeys)),
+	}
+}
+
+func (t *providerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	for rateLimited := 0; rateLimited < maxRateLimitedRounds*len(t.config.ApiKeys); rateLimited++ {
+		key := t.acquire()
+
+		keyedReq := req.Clone(req.Context())
+		keyedReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if t.config.ApiKeyHeader != "" {
+			keyedReq.Header.Set(t.config.ApiKeyHeader, t.config.ApiKeys[key])
+		} else if strings.Contains(keyedReq.URL.Path, API_KEY_PLACEHOLDER) {
+			keyedReq.URL.Path = strings.Replace(keyedReq.URL.Path, API_KEY_PLACEHOLDER, t.config.ApiKeys[key], -1)
+			keyedReq.URL.RawPath = ""
+		}
+
+		resp, err := t.transport.RoundTrip(keyedReq)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+		resp.Body.Close()
+
+		t.limit(key, retryAfter(resp, 30*time.Second))
+	}
+	return nil, fmt.Errorf("%w: all %d API keys were rejected with HTTP 429", ErrQuotaExhausted, len(t.config.ApiKeys))
+}
+
+// acquire returns the key used for the next request. If the quota is nearly exhausted, it slows down by waiting
+// before it returns, if it is exhausted or all keys are rate limited, it waits until a key can be used again.
+func (t *providerTransport) acquire() int {
+	for {
+		t.mutex.Lock()
+		now := time.Now()
+		if day := now.Unix() / 86400; day != t.day {
+			t.day = day
+			t.used = make([]int, len(t.config.ApiKeys))
+		}
+		endOfDay := time.Unix((t.day+1)*86400, 0)
+
+		// rotate to the next usable key
+		usable := func(key int) bool {
+			return (t.config.DailyQuota <= 0 || t.used[key] < t.config.DailyQuota) && !t.limitedUntil[key].After(now)
+		}
+		for i := 0; i < len(t.used) && !usable(t.current); i++ {
+			t.current = (t.current + 1) % len(t.used)
+		}
+
+		if !usable(t.current) {
+			// wait until the first key is usable again
+			resume := endOfDay
+			for key := range t.used {
+				if (t.config.DailyQuota <= 0 || t.used[key] < t.config.DailyQuota) && t.limitedUntil[key].Before(resume) {
+					resume = t.limitedUntil[key]
+				}
+			}
+			t.mutex.Unlock()
+
+			if resume == endOfDay {
+				t.progressf("WARNING: Daily provider quota exhausted, pausing until %s\n", endOfDay.UTC().Format(time.RFC3339))
+			} else {
+				t.progressf("WARNING: Provider rate limit reached with all API keys, waiting %s\n", time.Until(resume).Round(time.Second))
+			}
+			time.Sleep(time.Until(resume))
+			continue
+		}
+
+		var wait time.Duration
+		if t.config.DailyQuota > 0 {
+			used, remaining := 0, 0
+			for _, n := range t.used {
+				used += n
+				if n < t.config.DailyQuota {
+					remaining += t.config.DailyQuota - n
+				}
+			}
+			if float64(used) >= t.config.SlowDownAt*float64(t.config.DailyQuota*len(t.used)) {
+				// spread the remaining requests over the rest of the day
+				wait = time.Until(endOfDay) / time.Duration(remaining)
+			}
+		}
+
+		key := t.current
+		t.used[key]++
+		t.mutex.Unlock()
+
+		time.Sleep(wait)
+		return key
+	}
+}
+
+func (t *providerTransport) limit(key int, duration time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.limitedUntil[key] = time.Now().Add(duration)
+	t.current = (key + 1) % len(t.used)
+}
+
+// retryAfter returns the waiting time requested by the Retry-After header of the response
+func retryAfter(resp *http.Response, defaultWait time.Duration) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultWait
+}
@@ -0,0 +1,312 @@
+// This file contains the job queue of submissions and verifications. Jobs are pushed to a queue and processed by
+// workers (see ProcessJobs). The queue is in-memory within one process, or backed by Redis or NATS JetStream so that
+// several workers on different hosts share the work, and the jobs of a crashed worker are handed to the others.
+// Jobs are delivered at least once: a job whose worker crashed after sending its transaction is processed again.
+
+package testimonium
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// QUEUE_POLL_INTERVAL is the time a worker waits for a job before it checks whether it is stopped.
+	QUEUE_POLL_INTERVAL = 5 * time.Second
+	// QUEUE_LEASE is the time a job stays with a worker that stopped responding until it is handed to another worker.
+	// Workers extend the lease of the processed job every QUEUE_LEASE / 3.
+	QUEUE_LEASE = time.Minute
+	// QUEUE_MAX_ATTEMPTS is the number of times a failing job is processed until it is moved to the failed jobs.
+	QUEUE_MAX_ATTEMPTS = 3
+)
+
+// job kinds
+const (
+	JOB_SUBMIT = "submit" // submit a block header of the source chain, including its missing ancestors
+	JOB_VERIFY = "verify" // verify a transaction or receipt (see VerifyWithBackfill and VerifyAfterRelay)
+)
+
+// QueuedJob is a job of the queue. Kind, the chains and the fields of the kind are set by the caller, Id and Attempts
+// are set by the queue.
+type QueuedJob struct {
+	Id               string `json:"id"`
+	Kind             string `json:"kind"`
+	SourceChain      uint8  `json:"sourceChain"`
+	DestinationChain uint8  `json:"destinationChain"`
+	Attempts         int    `json:"attempts"`        // failed attempts so far
+	Enqueued         int64  `json:"enqueued"`        // unix time
+	Error            string `json:"error,omitempty"` // reason of failed jobs
+
+	// JOB_SUBMIT
+	BlockNumber uint64 `json:"blockNumber,omitempty"`
+	// JOB_VERIFY
+	TxHash        common.Hash   `json:"txHash,omitempty"`
+	ValueType     TrieValueType `json:"valueType,omitempty"`
+	Confirmations uint8         `json:"confirmations,omitempty"`
+	Backfill      bool          `json:"backfill,omitempty"` // submit the missing headers instead of waiting for them
+	Wait          time.Duration `json:"wait,omitempty"`     // without Backfill, maximum time to wait for the headers
+	Callback      string        `json:"callback,omitempty"` // webhook notified of the result (see VerificationWebhook)
+
+	// JOB_SUBMIT and JOB_VERIFY with Backfill
+	MaxHeaders int `json:"maxHeaders,omitempty"`
+
+	receipt interface{} // backend specific handle of the delivery
+}
+
+// JobQueue is a queue of jobs shared by workers. A received job is leased to the worker until it is acknowledged,
+// retried or failed, or until its lease expires (the worker crashed), then it is delivered again.
+type JobQueue interface {
+	// Push appends the job to the queue and returns its id.
+	Push(job QueuedJob) (string, error)
+	// Receive waits at most timeout for a job, it returns nil if there is none.
+	Receive(timeout time.Duration) (*QueuedJob, error)
+	// Extend extends the lease of the received job.
+	Extend(job *QueuedJob) error
+	// Ack removes the completed job.
+	Ack(job *QueuedJob) error
+	// Retry returns the failed job to the end of the queue with one more attempt.
+	Retry(job *QueuedJob) error
+	// Fail moves the job to the failed jobs, it is not delivered again.
+	Fail(job *QueuedJob, reason error) error
+	Close() error
+}
+
+// OpenJobQueue opens the queue at the URL:
+//
+//	memory://                                                     in-memory queue of this process
+//	redis://[:password@]host:port[/db][?key=ethrelay:jobs]       Redis lists (rediss:// for TLS)
+//	nats://[user:password@]host:port[?stream=..&subject=..&consumer=..]  NATS JetStream (tls:// for TLS)
+//
+// worker identifies the worker in the queue (e.g., the Redis list of its leased jobs), a random id is used if empty.
+func OpenJobQueue(queueUrl string, worker string) (JobQueue, error) {
+	parsed, err := url.Parse(queueUrl)
+	if err != nil {
+		return nil, fmt.Errorf("illegal queue URL %s: %s", queueUrl, err)
+	}
+	if worker == "" {
+		worker = randomJobId()
+	}
+	switch parsed.Scheme {
+	case "memory":
+		return NewMemoryJobQueue(), nil
+	case "redis", "rediss":
+		return openRedisJobQueue(parsed, worker)
+	case "nats", "tls":
+		return openNatsJobQueue(parsed, worker)
+	default:
+		return nil, fmt.Errorf("unsupported queue %s (memory, redis, rediss, nats or tls)", queueUrl)
+	}
+}
+
+func randomJobId() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// newJobPayload assigns an id to a new job and encodes it
+func newJobPayload(job QueuedJob) (string, []byte, error) {
+	if job.Kind != JOB_SUBMIT && job.Kind != JOB_VERIFY {
+		return "", nil, fmt.Errorf("illegal job kind '%s'", job.Kind)
+	}
+	if job.Id == "" {
+		job.Id = randomJobId()
+	}
+	if job.Enqueued == 0 {
+		job.Enqueued = time.Now().Unix()
+	}
+	payload, err := json.Marshal(job)
+	return job.Id, payload, err
+}
+
+// MemoryJobQueue is a JobQueue within one process, e.g., to feed the workers of an application. Leases do not expire,
+// as the jobs are lost with the process anyway. Failed jobs are kept in Failed.
+type MemoryJobQueue struct {
+	mutex  sync.Mutex
+	jobs   []QueuedJob
+	signal chan struct{} // receives a value when a job is pushed
+	Failed []QueuedJob
+}
+
+func NewMemoryJobQueue() *MemoryJobQueue {
+	return &MemoryJobQueue{signal: make(chan struct{}, 1)}
+}
+
+func (q *MemoryJobQueue) Push(job QueuedJob) (string, error) {
+	id, payload, err := newJobPayload(job)
+	if err != nil {
+		return "", err
+	}
+	var pushed QueuedJob
+	if err := json.Unmarshal(payload, &pushed); err != nil {
+		return "", err
+	}
+
+	q.mutex.Lock()
+	q.jobs = append(q.jobs, pushed)
+	q.mutex.Unlock()
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+	return id, nil
+}
+
+func (q *MemoryJobQueue) Receive(timeout time.Duration) (*QueuedJob, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		q.mutex.Lock()
+		if len(q.jobs) > 0 {
+			job := q.jobs[0]
+			q.jobs = q.jobs[1:]
+			q.mutex.Unlock()
+			return &job, nil
+		}
+		q.mutex.Unlock()
+
+		select {
+		case <-q.signal:
+		case <-deadline.C:
+			return nil, nil
+		}
+	}
+}
+
+func (q *MemoryJobQueue) Extend(job *QueuedJob) error {
+	return nil
+}
+
+func (q *MemoryJobQueue) Ack(job *QueuedJob) error {
+	return nil
+}
+
+func (q *MemoryJobQueue) Retry(job *QueuedJob) error {
+	retried := *job
+	retried.Attempts++
+	q.mutex.Lock()
+	q.jobs = append(q.jobs, retried)
+	q.mutex.Unlock()
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (q *MemoryJobQueue) Fail(job *QueuedJob, reason error) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	failed := *job
+	failed.Error = reason.Error()
+	q.Failed = append(q.Failed, failed)
+	return nil
+}
+
+func (q *MemoryJobQueue) Close() error {
+	return nil
+}
+
+// ProcessJobs processes the jobs of the queue one after the other until the context is cancelled. Failing jobs are
+// retried up to QUEUE_MAX_ATTEMPTS times. The webhooks of verification jobs are notified of the final outcome, their
+// payloads are signed with callbackSecret. Every processed job is passed to report (which may be nil) with the error
+// of the attempt. Only errors of the queue itself stop the processing.
+func (c Client) ProcessJobs(ctx context.Context, queue JobQueue, callbackSecret []byte, report func(job QueuedJob, err error)) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		job, err := queue.Receive(QUEUE_POLL_INTERVAL)
+		if err != nil {
+			return err
+		}
+		if job == nil {
+			continue
+		}
+
+		c.progressf("Processing job %s (%s, attempt %d)\n", job.Id, job.Kind, job.Attempts+1)
+		verification, jobErr := c.processJob(queue, job)
+
+		final := jobErr == nil || job.Attempts+1 >= QUEUE_MAX_ATTEMPTS
+		switch {
+		case jobErr == nil:
+			err = queue.Ack(job)
+		case !final:
+			c.progressf("Job %s failed, retrying: %s\n", job.Id, jobErr)
+			err = queue.Retry(job)
+		default:
+			c.progressf("Job %s failed %d times: %s\n", job.Id, job.Attempts+1, jobErr)
+			err = queue.Fail(job, jobErr)
+		}
+		if err != nil {
+			return err
+		}
+
+		if final && job.Kind == JOB_VERIFY && job.Callback != "" {
+			if verification == nil {
+				verification = &VerificationJob{TxHash: job.TxHash, ValueType: job.ValueType, Confirmations: job.Confirmations,
+					Stage: BACKFILL_FAILED, Error: jobErr.Error()}
+			}
+			hook := VerificationWebhook{URL: job.Callback, Secret: callbackSecret}
+			if err := hook.Notify(NewVerificationCallback(*verification, job.SourceChain, job.DestinationChain)); err != nil {
+				c.progressf("WARNING: Result of job %s not delivered: %s\n", job.Id, err)
+			}
+		}
+		if report != nil {
+			report(*job, jobErr)
+		}
+	}
+}
+
+// processJob runs the job while extending its lease
+func (c Client) processJob(queue JobQueue, job *QueuedJob) (*VerificationJob, error) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(QUEUE_LEASE / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := queue.Extend(job); err != nil {
+					c.progressf("WARNING: Lease of job %s not extended: %s\n", job.Id, err)
+				}
+			}
+		}
+	}()
+
+	switch job.Kind {
+	case JOB_SUBMIT:
+		header, err := c.HeaderByNumber(new(big.Int).SetUint64(job.BlockNumber), job.SourceChain)
+		if err != nil {
+			return nil, err
+		}
+		_, err = c.SubmitHeaderWithAncestors(header, job.DestinationChain, job.SourceChain, job.MaxHeaders)
+		if errors.Is(err, ErrHeaderAlreadyStored) {
+			// submitted before, e.g., by a worker that crashed before acknowledging the job
+			return nil, nil
+		}
+		return nil, err
+	case JOB_VERIFY:
+		if job.Backfill {
+			return c.VerifyWithBackfill(job.TxHash, job.ValueType, job.Confirmations, job.SourceChain, job.DestinationChain,
+				job.MaxHeaders, nil)
+		}
+		return c.VerifyAfterRelay(job.TxHash, job.ValueType, job.Confirmations, job.SourceChain, job.DestinationChain,
+			job.Wait, nil)
+	default:
+		return nil, fmt.Errorf("illegal job kind '%s'", job.Kind)
+	}
+}
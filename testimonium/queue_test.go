@@ -0,0 +1,71 @@
+package testimonium
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// testJobQueue runs the jobs of a queue through the states of the JobQueue interface: the queue has to be empty, the
+// failed job is returned for the checks of the backend.
+func testJobQueue(t *testing.T, queue JobQueue) QueuedJob {
+	first, err := queue.Push(QueuedJob{Kind: JOB_SUBMIT, BlockNumber: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := queue.Push(QueuedJob{Kind: JOB_SUBMIT, BlockNumber: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := queue.Push(QueuedJob{Kind: "illegal"}); err == nil {
+		t.Fatal("job of illegal kind pushed")
+	}
+
+	job, err := queue.Receive(time.Second)
+	if err != nil || job == nil {
+		t.Fatalf("no job received: %v", err)
+	}
+	if job.Id != first || job.BlockNumber != 1 {
+		t.Fatalf("received job %s of block %d instead of the first job", job.Id, job.BlockNumber)
+	}
+	if err := queue.Extend(job); err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Retry(job); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err = queue.Receive(time.Second)
+	if err != nil || job == nil || job.BlockNumber != 2 {
+		t.Fatalf("second job not received: %+v, %v", job, err)
+	}
+	if err := queue.Ack(job); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err = queue.Receive(time.Second)
+	if err != nil || job == nil || job.Id != first || job.Attempts != 1 {
+		t.Fatalf("retried job not received with one attempt: %+v, %v", job, err)
+	}
+	if err := queue.Fail(job, fmt.Errorf("broken")); err != nil {
+		t.Fatal(err)
+	}
+
+	if job, err := queue.Receive(time.Second); job != nil || err != nil {
+		t.Fatalf("job received from the empty queue: %+v, %v", job, err)
+	}
+	return QueuedJob{Id: first, Kind: JOB_SUBMIT, BlockNumber: 1, Attempts: 1, Error: "broken"}
+}
+
+// checkFailedJob compares the stored failed job with the failed job of testJobQueue
+func checkFailedJob(t *testing.T, failed []QueuedJob, expected QueuedJob) {
+	if len(failed) != 1 || failed[0].Id != expected.Id || failed[0].Attempts != expected.Attempts ||
+		failed[0].Error != expected.Error {
+		t.Fatalf("failed jobs %+v instead of %+v", failed, expected)
+	}
+}
+
+func TestMemoryJobQueue(t *testing.T) {
+	queue := NewMemoryJobQueue()
+	defer queue.Close()
+	checkFailedJob(t, queue.Failed, testJobQueue(t, queue))
+}
@@ -0,0 +1,202 @@
+// This file contains the NATS JetStream backend of the job queue. Jobs are messages of a work queue stream, all workers
+// pull them from one durable consumer. A job that is not acknowledged within QUEUE_LEASE (the worker crashed) is
+// redelivered to another worker, workers keep the lease of a processed job with progress acknowledgements.
+
+package testimonium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATS_TIMEOUT is the timeout of connecting to NATS and of requests to JetStream.
+const NATS_TIMEOUT = 10 * time.Second
+
+type natsJobQueue struct {
+	conn     *nats.Conn
+	js       jetstream.JetStream
+	consumer jetstream.Consumer
+	stream   string
+	subject  string
+}
+
+func openNatsJobQueue(queueUrl *url.URL, worker string) (*natsJobQueue, error) {
+	query := queueUrl.Query()
+	stream, subject, consumer := query.Get("stream"), query.Get("subject"), query.Get("consumer")
+	if stream == "" {
+		stream = "ETHRELAY_JOBS"
+	}
+	if subject == "" {
+		subject = "ethrelay.jobs"
+	}
+	if consumer == "" {
+		consumer = "ethrelay-workers"
+	}
+
+	// the options of the queue are not passed to the server
+	serverUrl := *queueUrl
+	serverUrl.RawQuery = ""
+	conn, err := nats.Connect(serverUrl.String(), nats.Name("go-ethrelay"), nats.Timeout(NATS_TIMEOUT))
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	q := &natsJobQueue{conn: conn, js: js, stream: stream, subject: subject}
+	if err := q.ensureStream(consumer); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+// failedSubject is the subject of the failed jobs, which are stored in the stream of the jobs
+func (q *natsJobQueue) failedSubject() string {
+	return q.subject + ".failed"
+}
+
+// ensureStream creates the stream of the jobs and the consumer of the workers unless they exist
+func (q *natsJobQueue) ensureStream(consumer string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), NATS_TIMEOUT)
+	defer cancel()
+
+	_, err := q.js.Stream(ctx, q.stream)
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		_, err = q.js.CreateStream(ctx, jetstream.StreamConfig{
+			Name:      q.stream,
+			Subjects:  []string{q.subject, q.failedSubject()},
+			Retention: jetstream.WorkQueuePolicy,
+			Storage:   jetstream.FileStorage,
+		})
+	}
+	if errors.Is(err, nats.ErrNoResponders) {
+		// nobody answers the JetStream API of the server
+		err = jetstream.ErrJetStreamNotEnabled
+	}
+	if err != nil {
+		return fmt.Errorf("stream %s: %w", q.stream, err)
+	}
+
+	// creating an existing consumer with the same configuration succeeds
+	q.consumer, err = q.js.CreateOrUpdateConsumer(ctx, q.stream, jetstream.ConsumerConfig{
+		Durable:       consumer,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       QUEUE_LEASE,
+		FilterSubject: q.subject,
+	})
+	if err != nil {
+		return fmt.Errorf("consumer %s: %w", consumer, err)
+	}
+	return nil
+}
+
+func (q *natsJobQueue) Push(job QueuedJob) (string, error) {
+	id, payload, err := newJobPayload(job)
+	if err != nil {
+		return "", err
+	}
+	return id, q.publish(q.subject, payload)
+}
+
+// publish publishes the job to the subject and waits until the stream stored it
+func (q *natsJobQueue) publish(subject string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), NATS_TIMEOUT)
+	defer cancel()
+	if _, err := q.js.Publish(ctx, subject, payload); err != nil {
+		return fmt.Errorf("subject %s: %w", subject, err)
+	}
+	return nil
+}
+
+func (q *natsJobQueue) Receive(timeout time.Duration) (*QueuedJob, error) {
+	batch, err := q.consumer.Fetch(1, jetstream.FetchMaxWait(timeout))
+	if err != nil {
+		return nil, err
+	}
+	msg, received := <-batch.Messages()
+	if !received {
+		// no job within the timeout
+		return nil, batch.Error()
+	}
+
+	job := new(QueuedJob)
+	if err := json.Unmarshal(msg.Data(), job); err != nil {
+		// an illegal message is not delivered again
+		msg.Term()
+		return nil, nil
+	}
+	job.receipt = msg
+	return job, nil
+}
+
+// message returns the message of a received job
+func (q *natsJobQueue) message(job *QueuedJob) (jetstream.Msg, error) {
+	msg, ok := job.receipt.(jetstream.Msg)
+	if !ok {
+		return nil, fmt.Errorf("job %s was not received", job.Id)
+	}
+	return msg, nil
+}
+
+func (q *natsJobQueue) Extend(job *QueuedJob) error {
+	msg, err := q.message(job)
+	if err != nil {
+		return err
+	}
+	return msg.InProgress()
+}
+
+func (q *natsJobQueue) Ack(job *QueuedJob) error {
+	msg, err := q.message(job)
+	if err != nil {
+		return err
+	}
+	return msg.Ack()
+}
+
+func (q *natsJobQueue) Retry(job *QueuedJob) error {
+	retried := *job
+	retried.Attempts++
+	payload, err := json.Marshal(retried)
+	if err != nil {
+		return err
+	}
+	if err := q.publish(q.subject, payload); err != nil {
+		return err
+	}
+	return q.Ack(job)
+}
+
+// Fail terminates the delivery of the job, failed jobs are stored with the subject "<subject>.failed". Streams created
+// by older versions do not include this subject, the job is delivered again then and an error is returned.
+func (q *natsJobQueue) Fail(job *QueuedJob, reason error) error {
+	msg, err := q.message(job)
+	if err != nil {
+		return err
+	}
+	failed := *job
+	failed.Error = reason.Error()
+	payload, err := json.Marshal(failed)
+	if err != nil {
+		return err
+	}
+	if err := q.publish(q.failedSubject(), payload); err != nil {
+		return err
+	}
+	return msg.Term()
+}
+
+func (q *natsJobQueue) Close() error {
+	q.conn.Close()
+	return nil
+}
@@ -0,0 +1,168 @@
+package testimonium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// newTestNats starts a NATS server with JetStream (unless disabled) storing the streams in a temporary directory
+func newTestNats(t *testing.T, user string, password string, jetStream bool) *server.Server {
+	nats, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: jetStream,
+		StoreDir:  t.TempDir(),
+		Username:  user,
+		Password:  password,
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go nats.Start()
+	if !nats.ReadyForConnections(10 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+	t.Cleanup(nats.Shutdown)
+	return nats
+}
+
+// testNatsUrl returns the URL of the server with the credentials (e.g., "user:password@")
+func testNatsUrl(nats *server.Server, credentials string) string {
+	return strings.Replace(nats.ClientURL(), "nats://", "nats://"+credentials, 1)
+}
+
+func openTestNatsQueue(t *testing.T, queueUrl string) *natsJobQueue {
+	queue, err := OpenJobQueue(queueUrl, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return queue.(*natsJobQueue)
+}
+
+// testJetStream returns the stream of the server to inspect it
+func testJetStream(t *testing.T, queueUrl string, name string) jetstream.Stream {
+	conn, err := nats.Connect(queueUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(conn.Close)
+	js, err := jetstream.New(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := js.Stream(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stream
+}
+
+// storedJobs returns the number of messages of the stream
+func storedJobs(t *testing.T, stream jetstream.Stream) uint64 {
+	info, err := stream.Info(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.State.Msgs
+}
+
+func TestNatsJobQueue(t *testing.T) {
+	server := newTestNats(t, "relayer", "secret", true)
+	queueUrl := testNatsUrl(server, "relayer:secret@")
+	queue := openTestNatsQueue(t, queueUrl)
+	defer queue.Close()
+	// the stream of another worker is reused
+	openTestNatsQueue(t, queueUrl).Close()
+
+	stream := testJetStream(t, queueUrl, "ETHRELAY_JOBS")
+	config := stream.CachedInfo().Config
+	if strings.Join(config.Subjects, " ") != "ethrelay.jobs ethrelay.jobs.failed" || config.Retention != jetstream.WorkQueuePolicy {
+		t.Fatalf("stream configured with %+v", config)
+	}
+
+	expected := testJobQueue(t, queue)
+	msg, err := stream.GetLastMsgForSubject(context.Background(), "ethrelay.jobs.failed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failed QueuedJob
+	if err := json.Unmarshal(msg.Data, &failed); err != nil {
+		t.Fatal(err)
+	}
+	checkFailedJob(t, []QueuedJob{failed}, expected)
+	if jobs := storedJobs(t, stream); jobs != 1 {
+		t.Fatalf("%d jobs left in the stream besides the failed job", jobs-1)
+	}
+}
+
+func TestNatsJobQueueFailWithoutFailedSubject(t *testing.T) {
+	server := newTestNats(t, "", "", true)
+	queueUrl := testNatsUrl(server, "")
+	// the stream of an older version
+	conn, err := nats.Connect(queueUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	js, _ := jetstream.New(conn)
+	stream, err := js.CreateStream(context.Background(), jetstream.StreamConfig{Name: "JOBS", Subjects: []string{"jobs"},
+		Retention: jetstream.WorkQueuePolicy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue := openTestNatsQueue(t, queueUrl+"?stream=JOBS&subject=jobs&consumer=workers")
+	defer queue.Close()
+
+	if _, err := queue.Push(QueuedJob{Kind: JOB_SUBMIT, BlockNumber: 1}); err != nil {
+		t.Fatal(err)
+	}
+	job, err := queue.Receive(time.Second)
+	if err != nil || job == nil {
+		t.Fatalf("job not received: %v", err)
+	}
+	if err := queue.Fail(job, errors.New("broken")); err == nil || !strings.Contains(err.Error(), "jobs.failed") {
+		t.Fatalf("job failed without storing it: %v", err)
+	}
+	if jobs := storedJobs(t, stream); jobs != 1 {
+		t.Fatalf("job removed from the queue")
+	}
+}
+
+func TestNatsJobQueueIllegalMessage(t *testing.T) {
+	server := newTestNats(t, "", "", true)
+	queueUrl := testNatsUrl(server, "")
+	queue := openTestNatsQueue(t, queueUrl)
+	defer queue.Close()
+	if _, err := queue.js.Publish(context.Background(), "ethrelay.jobs", []byte("not a job")); err != nil {
+		t.Fatal(err)
+	}
+
+	if job, err := queue.Receive(time.Second); job != nil || err != nil {
+		t.Fatalf("illegal message received: %+v, %v", job, err)
+	}
+	if job, err := queue.Receive(time.Second); job != nil || err != nil {
+		t.Fatalf("illegal message delivered again: %+v, %v", job, err)
+	}
+}
+
+func TestNatsJobQueueServerErrors(t *testing.T) {
+	server := newTestNats(t, "relayer", "secret", true)
+	if _, err := OpenJobQueue(testNatsUrl(server, "relayer:wrong@"), ""); !errors.Is(err, nats.ErrAuthorization) {
+		t.Errorf("wrong password accepted: %v", err)
+	}
+
+	server = newTestNats(t, "", "", false)
+	if _, err := OpenJobQueue(testNatsUrl(server, ""), ""); !errors.Is(err, jetstream.ErrJetStreamNotEnabled) {
+		t.Errorf("server without JetStream accepted: %v", err)
+	}
+}
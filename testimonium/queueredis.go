@@ -0,0 +1,204 @@
+// This file contains the Redis backend of the job queue. Jobs are JSON entries of a Redis list. A worker moves a job
+// atomically to its own list of leased jobs (BRPOPLPUSH) and removes it from there when the job ends. Workers refresh
+// a heartbeat key while they are alive, the leased jobs of a worker whose heartbeat expired (it crashed) are moved back
+// to the queue by the other workers.
+
+package testimonium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// REDIS_TIMEOUT is the timeout of connecting to Redis and of commands (in addition to the time they block).
+const REDIS_TIMEOUT = 10 * time.Second
+
+type redisJobQueue struct {
+	client       *redis.Client
+	key          string
+	worker       string
+	registered   bool // the worker received jobs and has a heartbeat
+	lastRecovery time.Time
+}
+
+func openRedisJobQueue(queueUrl *url.URL, worker string) (*redisJobQueue, error) {
+	// the key is an option of the queue, the other options are passed to the client (e.g., ?dial_timeout=3s)
+	query := queueUrl.Query()
+	key := query.Get("key")
+	if key == "" {
+		key = "ethrelay:jobs"
+	}
+	query.Del("key")
+	clientUrl := *queueUrl
+	clientUrl.RawQuery = query.Encode()
+
+	options, err := redis.ParseURL(clientUrl.String())
+	if err != nil {
+		return nil, err
+	}
+	options.DialTimeout = REDIS_TIMEOUT
+	options.ReadTimeout = REDIS_TIMEOUT
+	options.WriteTimeout = REDIS_TIMEOUT
+	client := redis.NewClient(options)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &redisJobQueue{client: client, key: key, worker: worker}, nil
+}
+
+func (q *redisJobQueue) leasedKey(worker string) string {
+	return q.key + ":leased:" + worker
+}
+
+func (q *redisJobQueue) heartbeatKey(worker string) string {
+	return q.key + ":heartbeat:" + worker
+}
+
+func (q *redisJobQueue) Push(job QueuedJob) (string, error) {
+	id, payload, err := newJobPayload(job)
+	if err != nil {
+		return "", err
+	}
+	return id, q.client.LPush(context.Background(), q.key, payload).Err()
+}
+
+func (q *redisJobQueue) Receive(timeout time.Duration) (*QueuedJob, error) {
+	ctx := context.Background()
+	if !q.registered {
+		// jobs leased by a previous run of this worker are not processed anymore
+		if err := q.requeueLeased(q.worker); err != nil {
+			return nil, err
+		}
+		q.registered = true
+	}
+	if err := q.heartbeat(); err != nil {
+		return nil, err
+	}
+	if time.Since(q.lastRecovery) > QUEUE_LEASE {
+		if err := q.recoverCrashedWorkers(); err != nil {
+			return nil, err
+		}
+		q.lastRecovery = time.Now()
+	}
+
+	// Redis blocks for whole seconds
+	if timeout < time.Second {
+		timeout = time.Second
+	}
+	payload, err := q.client.BRPopLPush(ctx, q.key, q.leasedKey(q.worker), timeout.Truncate(time.Second)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	job := new(QueuedJob)
+	if err := json.Unmarshal([]byte(payload), job); err != nil {
+		// an illegal entry would block the queue, it is moved to the failed jobs as is
+		q.client.LPush(ctx, q.key+":failed", payload)
+		q.client.LRem(ctx, q.leasedKey(q.worker), 1, payload)
+		return nil, nil
+	}
+	job.receipt = payload
+	return job, nil
+}
+
+// heartbeat marks the worker as alive for QUEUE_LEASE
+func (q *redisJobQueue) heartbeat() error {
+	ctx := context.Background()
+	if err := q.client.Set(ctx, q.heartbeatKey(q.worker), time.Now().Unix(), QUEUE_LEASE).Err(); err != nil {
+		return err
+	}
+	return q.client.SAdd(ctx, q.key+":workers", q.worker).Err()
+}
+
+// recoverCrashedWorkers moves the leased jobs of workers without heartbeat back to the queue
+func (q *redisJobQueue) recoverCrashedWorkers() error {
+	ctx := context.Background()
+	workers, err := q.client.SMembers(ctx, q.key+":workers").Result()
+	if err != nil {
+		return err
+	}
+	for _, worker := range workers {
+		if worker == "" || worker == q.worker {
+			continue
+		}
+		alive, err := q.client.Exists(ctx, q.heartbeatKey(worker)).Result()
+		if err != nil {
+			return err
+		}
+		if alive != 0 {
+			continue
+		}
+		if err := q.requeueLeased(worker); err != nil {
+			return err
+		}
+		if err := q.client.SRem(ctx, q.key+":workers", worker).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requeueLeased moves the jobs leased by the worker back to the queue
+func (q *redisJobQueue) requeueLeased(worker string) error {
+	for {
+		err := q.client.RPopLPush(context.Background(), q.leasedKey(worker), q.key).Err()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (q *redisJobQueue) Extend(job *QueuedJob) error {
+	return q.heartbeat()
+}
+
+func (q *redisJobQueue) Ack(job *QueuedJob) error {
+	payload, _ := job.receipt.(string)
+	return q.client.LRem(context.Background(), q.leasedKey(q.worker), 1, payload).Err()
+}
+
+func (q *redisJobQueue) Retry(job *QueuedJob) error {
+	retried := *job
+	retried.Attempts++
+	return q.replace(job, q.key, retried)
+}
+
+func (q *redisJobQueue) Fail(job *QueuedJob, reason error) error {
+	failed := *job
+	failed.Error = reason.Error()
+	return q.replace(job, q.key+":failed", failed)
+}
+
+// replace pushes the new entry to the list before the job is removed from the leased jobs, so it is not lost if the
+// worker crashes in between
+func (q *redisJobQueue) replace(job *QueuedJob, list string, entry QueuedJob) error {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := q.client.LPush(context.Background(), list, payload).Err(); err != nil {
+		return err
+	}
+	return q.Ack(job)
+}
+
+func (q *redisJobQueue) Close() error {
+	if q.registered {
+		ctx := context.Background()
+		q.requeueLeased(q.worker)
+		q.client.Del(ctx, q.heartbeatKey(q.worker))
+		q.client.SRem(ctx, q.key+":workers", q.worker)
+	}
+	return q.client.Close()
+}
@@ -0,0 +1,109 @@
+package testimonium
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func openTestRedisQueue(t *testing.T, queueUrl string, worker string) *redisJobQueue {
+	queue, err := OpenJobQueue(queueUrl, worker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return queue.(*redisJobQueue)
+}
+
+// redisList returns the entries of the list, a missing list is empty
+func redisList(server *miniredis.Miniredis, db int, key string) []string {
+	entries, _ := server.DB(db).List(key)
+	return entries
+}
+
+func TestRedisJobQueue(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireUserAuth("relayer", "secret")
+	queue := openTestRedisQueue(t, "redis://relayer:secret@"+server.Addr()+"/2?key=test", "worker-1")
+	defer queue.Close()
+
+	expected := testJobQueue(t, queue)
+	var failed []QueuedJob
+	for _, entry := range redisList(server, 2, "test:failed") {
+		var job QueuedJob
+		if err := json.Unmarshal([]byte(entry), &job); err != nil {
+			t.Fatal(err)
+		}
+		failed = append(failed, job)
+	}
+	checkFailedJob(t, failed, expected)
+	if leased := redisList(server, 2, "test:leased:worker-1"); len(leased) != 0 {
+		t.Fatalf("%d jobs leased after all jobs ended", len(leased))
+	}
+	if workers, _ := server.DB(2).Members("test:workers"); len(workers) != 1 || workers[0] != "worker-1" {
+		t.Errorf("workers %v", workers)
+	}
+}
+
+func TestRedisJobQueueRecoversLeasedJobs(t *testing.T) {
+	server := miniredis.RunT(t)
+	queueUrl := "redis://" + server.Addr()
+	crashed := openTestRedisQueue(t, queueUrl, "worker-1")
+	id, err := crashed.Push(QueuedJob{Kind: JOB_SUBMIT, BlockNumber: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job, err := crashed.Receive(time.Second); job == nil || err != nil {
+		t.Fatalf("job not received: %v", err)
+	}
+	// the worker crashes and its heartbeat expires
+	crashed.client.Close()
+	server.FastForward(QUEUE_LEASE)
+
+	other := openTestRedisQueue(t, queueUrl, "worker-2")
+	job, err := other.Receive(time.Second)
+	if err != nil || job == nil || job.Id != id {
+		t.Fatalf("job of the crashed worker not received by the other worker: %+v, %v", job, err)
+	}
+	if member, _ := server.IsMember("ethrelay:jobs:workers", "worker-1"); member {
+		t.Error("crashed worker not removed")
+	}
+
+	// a restarted worker takes its leased jobs back
+	other.client.Close()
+	restarted := openTestRedisQueue(t, queueUrl, "worker-2")
+	defer restarted.Close()
+	job, err = restarted.Receive(time.Second)
+	if err != nil || job == nil || job.Id != id {
+		t.Fatalf("job leased before the restart not received: %+v, %v", job, err)
+	}
+}
+
+func TestRedisJobQueueIllegalEntry(t *testing.T) {
+	server := miniredis.RunT(t)
+	queue := openTestRedisQueue(t, "redis://"+server.Addr(), "worker-1")
+	defer queue.Close()
+	server.Lpush("ethrelay:jobs", "not a job")
+
+	if job, err := queue.Receive(time.Second); job != nil || err != nil {
+		t.Fatalf("illegal entry received: %+v, %v", job, err)
+	}
+	if failed := redisList(server, 0, "ethrelay:jobs:failed"); len(failed) != 1 || failed[0] != "not a job" {
+		t.Fatalf("illegal entry not moved to the failed jobs: %v", failed)
+	}
+	if leased := redisList(server, 0, "ethrelay:jobs:leased:worker-1"); len(leased) != 0 {
+		t.Fatalf("illegal entry still leased")
+	}
+}
+
+func TestRedisJobQueueAuthentication(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+	if _, err := OpenJobQueue("redis://:wrong@"+server.Addr(), ""); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Fatalf("wrong password accepted: %v", err)
+	}
+	queue := openTestRedisQueue(t, "redis://:secret@"+server.Addr(), "")
+	queue.Close()
+}
@@ -0,0 +1,42 @@
+// This file contains the handling of submissions that lose the race against another relayer submitting the same
+// header a moment earlier. Such a revert is expected when several relayers serve the same contract and is not a
+// failure of the submission.
+
+package testimonium
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrLostRace is returned if a submission reverted because another relayer submitted the header first.
+var ErrLostRace = errors.New("submission race lost")
+
+// LostRaceError is returned if a submission reverted because the header was stored by another transaction in the
+// meantime. It matches both ErrLostRace and ErrHeaderAlreadyStored with errors.Is.
+type LostRaceError struct {
+	BlockHash common.Hash
+	Tx        *TxResult // the reverted submission, its gas is lost
+}
+
+func (e *LostRaceError) Error() string {
+	return fmt.Sprintf("%s: block %s was submitted by another relayer first (tx %s reverted, %d gas used)",
+		ErrLostRace, e.BlockHash.Hex(), e.Tx.TxHash.Hex(), e.Tx.GasUsed)
+}
+
+func (e *LostRaceError) Is(target error) bool {
+	return target == ErrLostRace || target == ErrHeaderAlreadyStored
+}
+
+// lostRace records a lost race in the accounting of the live mode, the monitor may be nil
+func (m *LiveMonitor) lostRace(gasUsed uint64) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.lostRaces++
+	m.gasLostInRaces += gasUsed
+}
@@ -0,0 +1,138 @@
+// This file contains the retrieval of all receipts of a block and the validation of locally built trie roots against
+// the roots contained in the block header.
+
+package testimonium
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pantos-io/go-ethrelay/proofs"
+)
+
+// ErrRootMismatch is returned if the root of a locally built trie differs from the root in the block header. A proof
+// of such a trie would be rejected by the contract.
+var ErrRootMismatch = errors.New("trie root does not match block header")
+
+// blockReceipts returns the receipts of all transactions of the block in the order of the transactions. The receipts
+// are fetched from the block source of the chain (see BlockSource), if it does not provide them they are fetched from
+// the archive node of the chain.
+func (c Client) blockReceipts(blockHash common.Hash, chain uint8) (types.Receipts, error) {
+	var receipts types.Receipts
+	err := c.withBlockSource(chain, "receipts of block "+blockHash.Hex(), func(source BlockSource) error {
+		var err error
+		receipts, err = source.BlockReceipts(c.context(), blockHash)
+		return err
+	})
+	return receipts, err
+}
+
+// RootMismatchError is returned if a locally built trie root differs from the root in the block header. It contains
+// the likely causes found by inspecting the data the node returned for the block.
+type RootMismatchError struct {
+	Trie      string // "transactions" or "receipts"
+	BlockHash common.Hash
+	Computed  common.Hash
+	Expected  common.Hash
+	Causes    []string
+}
+
+func (e *RootMismatchError) Error() string {
+	message := fmt.Sprintf("%s: computed %s root %s of block %s, header contains %s", ErrRootMismatch, e.Trie,
+		e.Computed.Hex(), e.BlockHash.Hex(), e.Expected.Hex())
+	for _, cause := range e.Causes {
+		message += "\n  likely cause: " + cause
+	}
+	return message
+}
+
+func (e *RootMismatchError) Unwrap() error {
+	return ErrRootMismatch
+}
+
+// checkProofRoot returns a RootMismatchError if the proof was not built for the expected root of the header. The
+// receipts are only used for the diagnostics of receipt proofs.
+func (c Client) checkProofRoot(proof proofs.Proof, expectedRoot common.Hash, trie string, header *types.Header,
+	blockHash common.Hash, receipts types.Receipts, chain uint8) error {
+	if proof.Root == expectedRoot {
+		return nil
+	}
+
+	mismatch := &RootMismatchError{
+		Trie:      trie,
+		BlockHash: blockHash,
+		Computed:  proof.Root,
+		Expected:  expectedRoot,
+	}
+	mismatch.Causes = c.diagnoseRootMismatch(header, blockHash, receipts, chain)
+	return mismatch
+}
+
+// diagnoseRootMismatch inspects the block for known reasons of wrongly built tries
+func (c Client) diagnoseRootMismatch(header *types.Header, blockHash common.Hash, receipts types.Receipts, chain uint8) []string {
+	var causes []string
+
+	if header.Hash() != blockHash {
+		causes = append(causes, fmt.Sprintf("the header returned by the node hashes to %s, i.e., it contains fields "+
+			"of a later fork (e.g., the base fee of EIP-1559) this client cannot encode", header.Hash().Hex()))
+	}
+
+	canonical, err := c.chain(chain).client.HeaderByNumber(c.context(), header.Number)
+	if err == nil && canonical.Hash() != blockHash && canonical.Hash() != header.Hash() {
+		causes = append(causes, fmt.Sprintf("block %s is not part of the node's canonical chain (block %s has number %s), "+
+			"the chain was reorganised or the provider returned non-canonical data", blockHash.Hex(), canonical.Hash().Hex(), header.Number.String()))
+	}
+
+	type inspectedTx struct {
+		Hash common.Hash     `json:"hash"`
+		Type *hexutil.Uint64 `json:"type"`
+	}
+	var block struct {
+		Transactions []inspectedTx `json:"transactions"`
+	}
+	if c.chain(chain).lightProofs {
+		// without the block body, the types of the transactions are unknown
+		hashes, err := c.blockTxHashes(blockHash, chain)
+		if err != nil {
+			return append(causes, fmt.Sprintf("the block could not be inspected: %s", err))
+		}
+		for _, hash := range hashes {
+			block.Transactions = append(block.Transactions, inspectedTx{Hash: hash})
+		}
+	} else if err := c.chain(chain).rpcClient.CallContext(c.context(), &block, "eth_getBlockByHash", blockHash, true); err != nil {
+		return append(causes, fmt.Sprintf("the block could not be inspected: %s", err))
+	}
+
+	typedTxs := 0
+	for _, tx := range block.Transactions {
+		if tx.Type != nil && *tx.Type != 0 {
+			typedTxs++
+		}
+	}
+	if typedTxs > 0 {
+		causes = append(causes, fmt.Sprintf("the block contains %d typed transactions (EIP-2718) whose encoding this "+
+			"client does not support", typedTxs))
+	}
+
+	if receipts != nil {
+		if len(receipts) != len(block.Transactions) {
+			causes = append(causes, fmt.Sprintf("the node returned %d receipts for %d transactions", len(receipts), len(block.Transactions)))
+		} else {
+			for i, receipt := range receipts {
+				if receipt.TxHash != block.Transactions[i].Hash {
+					causes = append(causes, fmt.Sprintf("receipt %d belongs to transaction %s instead of %s, the node "+
+						"returned the receipts in the wrong order", i, receipt.TxHash.Hex(), block.Transactions[i].Hash.Hex()))
+					break
+				}
+			}
+		}
+	}
+
+	if len(causes) == 0 {
+		causes = append(causes, "no known cause found, the provider may return inconsistent data, try another node")
+	}
+	return causes
+}
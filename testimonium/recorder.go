@@ -0,0 +1,262 @@
+// This file contains an HTTP transport that records all JSON-RPC requests and responses exchanged with the chains
+// to a file and a transport that replays such a recording without connecting to any chain. Recordings of failing
+// operations can be attached to bug reports and replayed offline against the client logic.
+
+package testimonium
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// RPCExchange is a single recorded JSON-RPC request together with its response.
+type RPCExchange struct {
+	Url      string          `json:"url"`
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+type recordingTransport struct {
+	transport http.RoundTripper
+	file      *os.File
+	mutex     *sync.Mutex
+}
+
+// newRecordingTransport creates a transport appending every exchange to the file at the specified path.
+// Exchanges are written immediately, so the recording is complete even if the process exits unexpectedly.
+func newRecordingTransport(path string) (*recordingTransport, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingTransport{
+		transport: http.DefaultTransport,
+		file:      file,
+		mutex:     new(sync.Mutex),
+	}, nil
+}
+
+// through returns a transport recording the exchanges sent with the specified transport to the same file
+func (t *recordingTransport) through(transport http.RoundTripper) *recordingTransport {
+	return &recordingTransport{
+		transport: transport,
+		file:      t.file,
+		mutex:     t.mutex,
+	}
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := readBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	line, err := json.Marshal(RPCExchange{
+		Url:      req.URL.String(),
+		Request:  compactJson(reqBody),
+		Response: compactJson(respBody),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if _, err := t.file.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type replayingTransport struct {
+	responses map[string][]json.RawMessage
+	mutex     sync.Mutex
+}
+
+// newReplayingTransport creates a transport answering requests with the responses recorded in the file at the
+// specified path. Requests are matched by URL, method and parameters, identical requests are answered in the
+// order they were recorded.
+func newReplayingTransport(path string) (*replayingTransport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	t := &replayingTransport{responses: make(map[string][]json.RawMessage)}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var exchange RPCExchange
+		if err := json.Unmarshal(scanner.Bytes(), &exchange); err != nil {
+			return nil, fmt.Errorf("malformed recording %s: %s", path, err)
+		}
+		key, err := exchangeKey(exchange.Url, exchange.Request)
+		if err != nil {
+			return nil, err
+		}
+		t.responses[key] = append(t.responses[key], exchange.Response)
+	}
+
+	return t, scanner.Err()
+}
+
+func (t *replayingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := exchangeKey(req.URL.String(), reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	responses := t.responses[key]
+	if len(responses) == 0 {
+		t.mutex.Unlock()
+		return nil, fmt.Errorf("no recorded response for request %s", reqBody)
+	}
+	response := responses[0]
+	t.responses[key] = responses[1:]
+	t.mutex.Unlock()
+
+	// the ids of the recorded responses have to match the ids of the current requests
+	response, err = copyIds(reqBody, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(response)),
+		ContentLength: int64(len(response)),
+		Request:       req,
+	}, nil
+}
+
+func readBody(body io.ReadCloser) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+func compactJson(data []byte) json.RawMessage {
+	buffer := new(bytes.Buffer)
+	if err := json.Compact(buffer, data); err != nil {
+		// not JSON (e.g., an error page of the provider), store it as JSON string
+		quoted, _ := json.Marshal(string(data))
+		return quoted
+	}
+	return buffer.Bytes()
+}
+
+// exchangeKey identifies a request independent of its JSON-RPC id(s)
+func exchangeKey(url string, request []byte) (string, error) {
+	var message interface{}
+	if err := json.Unmarshal(request, &message); err != nil {
+		return "", err
+	}
+
+	switch m := message.(type) {
+	case map[string]interface{}:
+		delete(m, "id")
+	case []interface{}:
+		for _, element := range m {
+			if batchElement, ok := element.(map[string]interface{}); ok {
+				delete(batchElement, "id")
+			}
+		}
+	}
+
+	normalized, err := json.Marshal(message)
+	if err != nil {
+		return "", err
+	}
+	return url + " " + string(normalized), nil
+}
+
+// WithRPCRecording records all JSON-RPC exchanges of HTTP connections to the file at the specified path.
+func WithRPCRecording(path string) ClientOption {
+	return func(client *Client) error {
+		transport, err := newRecordingTransport(path)
+		if err != nil {
+			return err
+		}
+		client.transport = transport
+		return nil
+	}
+}
+
+// WithRPCReplay answers all JSON-RPC requests with the responses recorded in the file at the specified path instead
+// of connecting to the configured chains.
+func WithRPCReplay(path string) ClientOption {
+	return func(client *Client) error {
+		transport, err := newReplayingTransport(path)
+		if err != nil {
+			return err
+		}
+		client.transport = transport
+		client.replay = true
+		return nil
+	}
+}
+
+func copyIds(request []byte, response []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(request)) > 0 && bytes.TrimSpace(request)[0] == '[' {
+		var requests []map[string]json.RawMessage
+		var responses []map[string]json.RawMessage
+		if err := json.Unmarshal(request, &requests); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(response, &responses); err != nil {
+			return nil, err
+		}
+		for i := range responses {
+			if i < len(requests) {
+				responses[i]["id"] = requests[i]["id"]
+			}
+		}
+		return json.Marshal(responses)
+	}
+
+	var requestMessage map[string]json.RawMessage
+	var responseMessage map[string]json.RawMessage
+	if err := json.Unmarshal(request, &requestMessage); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(response, &responseMessage); err != nil {
+		return nil, err
+	}
+	responseMessage["id"] = requestMessage["id"]
+	return json.Marshal(responseMessage)
+}
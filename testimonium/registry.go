@@ -0,0 +1,192 @@
+// This file contains the registry of the contracts deployed on a chain. It records how and when the contracts were
+// deployed (and the genesis block of the ETH Relay contract), so redeployments, which abandon the stored headers and
+// stakes of the previous contract, can be refused unless they are intended.
+
+package testimonium
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+)
+
+// names of the contracts in the registry
+const (
+	REGISTRY_ETHASH   = "ethash"
+	REGISTRY_ETHRELAY = "ethrelay"
+)
+
+// Deployment describes a deployed contract.
+type Deployment struct {
+	Address     common.Address `json:"address"`
+	TxHash      common.Hash    `json:"txHash"` // zero if an existing CREATE2 deployment was reused
+	BlockNumber uint64         `json:"blockNumber"`
+	Deployer    common.Address `json:"deployer"`
+	Salt        *common.Hash   `json:"salt,omitempty"` // set if the contract was deployed with CREATE2
+	// Version identifies the compiled contract, it is the hash of the creation code of the bindings
+	Version    common.Hash `json:"version"`
+	DeployedAt time.Time   `json:"deployedAt"`
+	// Metadata the deployment was tagged with (see WithDeploymentMetadata), e.g., who deployed it for which purpose
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// RegistrationTxHash is the transaction announcing the deployment to the deployment registry contract of the
+	// chain, nil if none is configured
+	RegistrationTxHash *common.Hash `json:"registrationTxHash,omitempty"`
+}
+
+// Genesis describes the genesis block of an ETH Relay contract.
+type Genesis struct {
+	SourceChain     uint8       `json:"sourceChain"`
+	BlockNumber     uint64      `json:"blockNumber"`
+	BlockHash       common.Hash `json:"blockHash"`
+	TotalDifficulty *big.Int    `json:"totalDifficulty"`
+	// trusted signers of the checkpoint the contract was deployed with, empty if the genesis block was read from the
+	// source chain
+	CheckpointSigners []common.Address `json:"checkpointSigners,omitempty"`
+}
+
+// ChainRegistry contains the contracts deployed on a single chain. It is stored as JSON file in the data directory.
+type ChainRegistry struct {
+	Chain     uint8                  `json:"chain"`
+	ChainId   *big.Int               `json:"chainId,omitempty"` // reported by the node at the last deployment
+	Contracts map[string]*Deployment `json:"contracts"`
+	Genesis   *Genesis               `json:"genesis,omitempty"` // genesis block of the ETH Relay contract
+
+	path string
+}
+
+// RegistryPath returns the path of the registry file of the chain in the data directory.
+func RegistryPath(dataDir string, chain uint8) string {
+	return filepath.Join(dataDir, fmt.Sprintf("registry-%d.json", chain))
+}
+
+// OpenChainRegistry reads the registry of the chain from the data directory. If the registry does not exist yet, an
+// empty registry is returned, which is created on the first call of Save.
+func OpenChainRegistry(dataDir string, chain uint8) (*ChainRegistry, error) {
+	registry := &ChainRegistry{
+		Chain:     chain,
+		Contracts: make(map[string]*Deployment),
+		path:      RegistryPath(dataDir, chain),
+	}
+
+	data, err := ioutil.ReadFile(registry.path)
+	if os.IsNotExist(err) {
+		return registry, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, registry); err != nil {
+		return nil, fmt.Errorf("corrupt registry %s: %s", registry.path, err)
+	}
+	if registry.Contracts == nil {
+		registry.Contracts = make(map[string]*Deployment)
+	}
+	return registry, nil
+}
+
+// Deployment returns the recorded deployment of the contract (REGISTRY_ETHASH or REGISTRY_ETHRELAY).
+func (registry *ChainRegistry) Deployment(contract string) (*Deployment, bool) {
+	deployment, exists := registry.Contracts[contract]
+	return deployment, exists
+}
+
+// Save writes the registry atomically to its file.
+func (registry *ChainRegistry) Save() error {
+	if err := os.MkdirAll(filepath.Dir(registry.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(registry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temp := registry.path + ".tmp"
+	if err := ioutil.WriteFile(temp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, registry.path)
+}
+
+// WithRegistry records the contracts deployed by the client in the registries of the data directory.
+func WithRegistry(dataDir string) ClientOption {
+	return func(client *Client) error {
+		client.registryDir = dataDir
+		return nil
+	}
+}
+
+// recordDeployment announces a new deployment of the contract to the deployment registry contract of the chain (if
+// configured) and adds it to the registry of the chain. The receipt is nil if an existing deployment was reused.
+// Failures only cause a warning, as the contract is deployed anyway.
+func (c Client) recordDeployment(chain uint8, contract string, addr common.Address, receipt *types.Receipt, salt *common.Hash, genesis *Genesis) {
+	metadata := c.metadataOfDeployment()
+	var registrationTx *common.Hash
+	if receipt != nil {
+		var err error
+		if registrationTx, err = c.announceDeployment(chain, contract, addr, metadata); err != nil {
+			c.progressf("WARNING: Deployment of %s at %s not announced to the deployment registry: %s\n", contract, addr.Hex(), err)
+		}
+	}
+	if c.registryDir == "" {
+		return
+	}
+
+	registry, err := OpenChainRegistry(c.registryDir, chain)
+	if err != nil {
+		c.progressf("WARNING: Deployment of %s at %s not recorded: %s\n", contract, addr.Hex(), err)
+		return
+	}
+	if chainId, err := c.chain(chain).client.ChainID(c.context()); err == nil {
+		registry.ChainId = chainId
+	}
+
+	deployment := &Deployment{
+		Address:            addr,
+		Deployer:           c.account,
+		Salt:               salt,
+		Version:            ContractVersion(contract),
+		DeployedAt:         time.Now().UTC(),
+		Metadata:           metadata,
+		RegistrationTxHash: registrationTx,
+	}
+	if previous, exists := registry.Contracts[contract]; exists && receipt == nil && previous.Address == addr {
+		// the reused deployment keeps the metadata it was deployed with
+		deployment.Metadata, deployment.RegistrationTxHash = previous.Metadata, previous.RegistrationTxHash
+	}
+	if receipt != nil {
+		deployment.TxHash = receipt.TxHash
+		deployment.BlockNumber = receipt.BlockNumber.Uint64()
+	}
+	registry.Contracts[contract] = deployment
+	if contract == REGISTRY_ETHRELAY {
+		registry.Genesis = genesis
+	}
+
+	if err := registry.Save(); err != nil {
+		c.progressf("WARNING: Deployment of %s at %s not recorded: %s\n", contract, addr.Hex(), err)
+	}
+}
+
+// ContractVersion returns the version of the contract (REGISTRY_ETHASH or REGISTRY_ETHRELAY) deployed by the client,
+// which is the hash of the creation code of its bindings.
+func ContractVersion(contract string) common.Hash {
+	switch contract {
+	case REGISTRY_ETHASH:
+		return crypto.Keccak256Hash(common.FromHex(ethash.EthashBin))
+	case REGISTRY_ETHRELAY:
+		return crypto.Keccak256Hash(common.FromHex(TestimoniumBin))
+	default:
+		return common.Hash{}
+	}
+}
@@ -0,0 +1,258 @@
+// This file contains the delegation of state-changing calls to relayer services, so the account does not need to
+// hold native tokens on the destination chain to pay for gas.
+
+package testimonium
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ErrRelayFailed is returned if a relayer did not execute a call.
+var ErrRelayFailed = errors.New("relayed call failed")
+
+// Relayer executes contract calls on behalf of the account, e.g., a relayer-as-a-service endpoint that pays the gas.
+type Relayer interface {
+	// Relay submits the call and returns an identifier of the relay task.
+	Relay(ctx context.Context, chainId *big.Int, to common.Address, data []byte, value *big.Int) (string, error)
+	// TransactionHash returns the hash of the transaction executing the task, or false if it is not yet known.
+	TransactionHash(ctx context.Context, taskId string) (common.Hash, bool, error)
+}
+
+// WithRelayer routes all state-changing contract calls on the specified chain through the relayer instead of sending
+// transactions signed by the account. Contract deployments are always sent directly.
+func WithRelayer(chain uint8, relayer Relayer) ClientOption {
+	return func(client *Client) error {
+		if client.relayers == nil {
+			client.relayers = make(map[uint8]Relayer)
+		}
+		client.relayers[chain] = relayer
+		return nil
+	}
+}
+
+// NewRelayerFromConfig creates the relayer specified by the "relayer" entry of a chain config, e.g.,
+//
+//	relayer:
+//	    type: gelato
+//	    url: https://relay.gelato.digital
+//	    apikey: ...
+func NewRelayerFromConfig(relayerConfig map[string]interface{}) (Relayer, error) {
+	relayerType, _ := relayerConfig["type"].(string)
+	url, _ := relayerConfig["url"].(string)
+	apiKey, _ := relayerConfig["apikey"].(string)
+
+	switch strings.ToLower(relayerType) {
+	case "gelato":
+		if url == "" {
+			url = GELATO_RELAY_URL
+		}
+		return NewGelatoRelayer(url, apiKey), nil
+	default:
+		return nil, fmt.Errorf("unknown relayer type '%s'", relayerType)
+	}
+}
+
+// relayBackend sends the transactions of the contract bindings to the relayer of the chain if one is configured
+type relayBackend struct {
+	*ethclient.Client
+	chain      *Chain
+	privateKey *ecdsa.PrivateKey // transactions with access lists are signed with the key
+	progressf  func(format string, args ...interface{})
+}
+
+func (b relayBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	private := b.chain.privateRelay != nil && isPrivateSubmission(ctx)
+	if b.chain.relayer == nil || private {
+		return b.chain.sendAsOperator(tx, func() error {
+			return b.sendDirectly(ctx, tx, private)
+		})
+	}
+	// the relayer sends a transaction of its own, the nonce of the account is not used
+	b.chain.releaseOperatorNonce(tx)
+	if tx.To() == nil {
+		return fmt.Errorf("%w: contract creations cannot be relayed", ErrRelayFailed)
+	}
+
+	chainId, err := b.Client.ChainID(ctx)
+	if err != nil {
+		return err
+	}
+
+	taskId, err := b.chain.relayer.Relay(ctx, chainId, *tx.To(), tx.Data(), tx.Value())
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrRelayFailed, err)
+	}
+	b.progressf("Call relayed as task %s\n", taskId)
+
+	// the transaction itself is never sent, its hash identifies the relay task
+	b.chain.relayedTasks.Store(tx.Hash(), taskId)
+	return nil
+}
+
+// sendDirectly sends the transaction signed by the account itself, publicly or to the private relay of the chain
+func (b relayBackend) sendDirectly(ctx context.Context, tx *types.Transaction, private bool) error {
+	if isAccessListRequested(ctx) {
+		if rawTx, hash, ok := b.signWithAccessList(ctx, tx); ok {
+			err := b.chain.logBroadcast(rawTx, tx, hash, txSender(tx), private, func() error {
+				if private {
+					return b.sendPrivateTransaction(ctx, rawTx)
+				}
+				return b.sendRawTransaction(ctx, rawTx)
+			})
+			if err != nil {
+				return err
+			}
+			// the receipt of the transaction created by the bindings is looked up by its hash
+			b.chain.substitutedTxs.Store(tx.Hash(), hash)
+			b.progressf("Tx sent as EIP-2930 transaction %s\n", hash.Hex())
+			return nil
+		}
+	}
+	if private {
+		rawTx, err := rlp.EncodeToBytes(tx)
+		if err != nil {
+			return err
+		}
+		return b.chain.logBroadcast(rawTx, tx, tx.Hash(), txSender(tx), true, func() error {
+			return b.sendPrivateTransaction(ctx, rawTx)
+		})
+	}
+	return b.chain.logBroadcastTx(ctx, b.Client, tx)
+}
+
+// relayedTxHash waits until the relayer executed the task belonging to the transaction
+func (chain *Chain) relayedTxHash(ctx context.Context, txHash common.Hash) (common.Hash, error) {
+	taskId, relayed := chain.relayedTasks.Load(txHash)
+	if !relayed {
+		return txHash, nil
+	}
+
+	for {
+		hash, executed, err := chain.relayer.TransactionHash(ctx, taskId.(string))
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if executed {
+			return hash, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return common.Hash{}, fmt.Errorf("relay task %s was not executed: %w", taskId, ctx.Err())
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// GELATO_RELAY_URL is the default endpoint of the Gelato relay.
+const GELATO_RELAY_URL = "https://relay.gelato.digital"
+
+// GelatoRelayer relays calls as sponsored calls of the Gelato relay, the gas is paid from the balance of the sponsor's
+// API key. The contract sees the Gelato relay as sender, calls that send value are not supported.
+type GelatoRelayer struct {
+	url        string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewGelatoRelayer creates a relayer for the Gelato relay at the specified URL.
+func NewGelatoRelayer(url string, apiKey string) *GelatoRelayer {
+	return &GelatoRelayer{
+		url:        strings.TrimSuffix(url, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (r *GelatoRelayer) Relay(ctx context.Context, chainId *big.Int, to common.Address, data []byte, value *big.Int) (string, error) {
+	if value != nil && value.Sign() > 0 {
+		return "", fmt.Errorf("sponsored calls cannot send value (%s wei)", value.String())
+	}
+
+	request, err := json.Marshal(map[string]interface{}{
+		"chainId":       chainId.String(),
+		"target":        to.Hex(),
+		"data":          hexutil.Encode(data),
+		"sponsorApiKey": r.apiKey,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		TaskId  string `json:"taskId"`
+		Message string `json:"message"`
+	}
+	if err := r.do(ctx, http.MethodPost, "/relays/v2/sponsored-call", request, &response); err != nil {
+		return "", err
+	}
+	if response.TaskId == "" {
+		return "", fmt.Errorf("no task created: %s", response.Message)
+	}
+	return response.TaskId, nil
+}
+
+func (r *GelatoRelayer) TransactionHash(ctx context.Context, taskId string) (common.Hash, bool, error) {
+	var response struct {
+		Task struct {
+			TaskState        string `json:"taskState"`
+			TransactionHash  string `json:"transactionHash"`
+			LastCheckMessage string `json:"lastCheckMessage"`
+		} `json:"task"`
+	}
+	if err := r.do(ctx, http.MethodGet, "/tasks/status/"+taskId, nil, &response); err != nil {
+		return common.Hash{}, false, err
+	}
+
+	switch response.Task.TaskState {
+	case "Cancelled":
+		return common.Hash{}, false, fmt.Errorf("%w: task %s cancelled: %s", ErrRelayFailed, taskId, response.Task.LastCheckMessage)
+	case "ExecSuccess", "ExecReverted":
+		// reverted calls have a transaction as well, the receipt contains the failure
+		return common.HexToHash(response.Task.TransactionHash), true, nil
+	default:
+		return common.Hash{}, false, nil
+	}
+}
+
+func (r *GelatoRelayer) do(ctx context.Context, method string, path string, body []byte, response interface{}) error {
+	request, err := http.NewRequest(method, r.url+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	httpResponse, err := r.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+
+	data, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return err
+	}
+	if httpResponse.StatusCode >= 300 {
+		return fmt.Errorf("relayer returned %s: %s", httpResponse.Status, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, response)
+}
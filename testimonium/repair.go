@@ -0,0 +1,107 @@
+// This file contains the repair of the nonce state of the accounts on a chain. After a crash or after transactions were
+// sent through other wallets, the transaction log may hold in-flight transactions that will never be mined and the
+// reserved nonces may be ahead of the chain, so later transactions wait for nonces that are never used.
+
+package testimonium
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrTxCleared is recorded for transactions of the transaction log that were cleared by RepairAccount, since the node
+// does not know them and their nonce is still unused.
+var ErrTxCleared = errors.New("cleared by account repair: unknown to the node")
+
+// AccountNonces are the nonces of an account on a chain when it was repaired.
+type AccountNonces struct {
+	Account      common.Address `json:"account"`
+	Role         OperatorRole   `json:"role,omitempty"` // empty for the client's own account
+	Nonce        uint64         `json:"nonce"`          // nonce of the next transaction according to the latest block
+	PendingNonce uint64         `json:"pendingNonce"`   // nonce of the next transaction including the pending transactions
+	// ReservedNonce is the next nonce the client had reserved for the account, nil if none was reserved. The
+	// reservation is dropped, so the next transaction uses the pending nonce of the node.
+	ReservedNonce *uint64 `json:"reservedNonce,omitempty"`
+}
+
+// AccountRepair is the outcome of repairing the nonce state of the accounts on a chain.
+type AccountRepair struct {
+	Chain    uint8                 `json:"chain"`
+	Accounts []AccountNonces       `json:"accounts"`
+	Txs      []TxLogReconciliation `json:"txs"` // the unresolved transactions of the transaction log
+}
+
+// Cleared returns the transactions of the transaction log cleared by the repair.
+func (repair *AccountRepair) Cleared() []TxLogReconciliation {
+	var cleared []TxLogReconciliation
+	for _, tx := range repair.Txs {
+		if tx.State == TXLOG_FAILED {
+			cleared = append(cleared, tx)
+		}
+	}
+	return cleared
+}
+
+// RepairAccount reconciles the nonce state of the client's accounts (its own and the operators') on the chain with the
+// chain's confirmed and pending nonces: the unresolved transactions of the transaction log are resolved like when the
+// client starts, but transactions the node does not know are cleared instead of broadcast again, and the reserved
+// nonces are dropped. Use WithoutTxLogReconciliation, so the transactions are not broadcast again when the chain is
+// connected.
+func (c Client) RepairAccount(chain uint8) (*AccountRepair, error) {
+	if err := c.checkChain(chain); err != nil {
+		return nil, err
+	}
+	source := c.chain(chain)
+	repair := &AccountRepair{Chain: chain, Accounts: []AccountNonces{}, Txs: []TxLogReconciliation{}}
+
+	if source.txLog != nil {
+		for _, entry := range source.txLog.Pending() {
+			reconciliation, err := c.reconcileTx(entry, chain, false)
+			if err != nil {
+				return repair, fmt.Errorf("transaction %s: %s", entry.Hash.Hex(), err)
+			}
+			repair.Txs = append(repair.Txs, reconciliation)
+		}
+	}
+
+	accounts := make(map[common.Address]OperatorRole)
+	if c.defaultPrivateKey != nil {
+		accounts[c.defaultAccount] = ""
+	}
+	for role, op := range c.operators {
+		accounts[op.account] = role
+	}
+	for account, role := range accounts {
+		nonces := AccountNonces{Account: account, Role: role}
+		var err error
+		if nonces.Nonce, err = source.client.NonceAt(c.context(), account, nil); err != nil {
+			return repair, err
+		}
+		if nonces.PendingNonce, err = source.client.PendingNonceAt(c.context(), account); err != nil {
+			return repair, err
+		}
+		if op, exists := source.operators[account]; exists {
+			nonces.ReservedNonce = op.resetNonce(chain)
+		}
+		repair.Accounts = append(repair.Accounts, nonces)
+	}
+	sort.Slice(repair.Accounts, func(i, j int) bool { return repair.Accounts[i].Role < repair.Accounts[j].Role })
+	return repair, nil
+}
+
+// resetNonce drops the nonce reservation of the operator on the chain and returns the next reserved nonce, nil if
+// none was reserved
+func (op *operator) resetNonce(chain uint8) *uint64 {
+	op.mutex.Lock()
+	defer op.mutex.Unlock()
+
+	reservation, exists := op.nonces[chain]
+	if !exists {
+		return nil
+	}
+	delete(op.nonces, chain)
+	return &reservation.next
+}
@@ -0,0 +1,263 @@
+// This file contains the replay protection of the transactions sent by the account. Transactions are signed according
+// to EIP-155 with the chain id of the destination chain, which may be any uint256, so a transaction is only valid on the
+// chain it was sent to. The replay audit reports configurations under which a transaction sent to one chain could still
+// be included on another chain, e.g., since the chain id is unknown or shared by several chains.
+
+package testimonium
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// sources of the chain id transactions are signed with
+const (
+	CHAIN_ID_CONFIG   = "config"   // the "chainid" entry of the chain config
+	CHAIN_ID_NODE     = "node"     // eth_chainId of the node
+	CHAIN_ID_NONE     = "none"     // the chain id is unknown, transactions are not replay protected
+	CHAIN_ID_DISABLED = "disabled" // EIP-155 is disabled by the "eip155" entry of the chain config
+)
+
+// MAX_CHAIN_ID is the largest chain id, chain ids are uint256 values.
+var MAX_CHAIN_ID = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// resolveSigningChainId determines the chain id transactions to the chain are signed with: the configured chain id
+// (which verifyChainIds compares with the node's), otherwise the one reported by the node. Old development chains
+// that do not accept EIP-155 transactions can opt out with "eip155: false".
+func (c Client) resolveSigningChainId(chain *Chain, chainConfig map[string]interface{}) {
+	if enabled, ok := chainConfig["eip155"].(bool); ok && !enabled {
+		chain.chainIdSource = CHAIN_ID_DISABLED
+		return
+	}
+
+	// chain ids in the config are verified with the configuration, an invalid one is reported there
+	configured, err := configuredId(chainConfig, "chainid")
+	if err == nil && configured != nil {
+		chain.signingChainId, chain.chainIdSource = configured, CHAIN_ID_CONFIG
+		return
+	}
+
+	chain.chainIdSource = CHAIN_ID_NONE
+	// no transactions are sent to source chains and replayed sessions, their chain id is only needed by the audit
+	if chain.role == ROLE_SOURCE || c.replay {
+		return
+	}
+	nodeChainId, err := chain.client.ChainID(c.context())
+	if err != nil {
+		c.problemf("Transactions to chain %d are not replay protected, its chain id is unknown: %s", chain.id, err)
+		return
+	}
+	chain.signingChainId, chain.chainIdSource = nodeChainId, CHAIN_ID_NODE
+}
+
+// keyedSigner returns a signer function of the bindings signing transactions with the key according to EIP-155
+func keyedSigner(key *ecdsa.PrivateKey, chainId *big.Int) bind.SignerFn {
+	signer := types.NewEIP155Signer(chainId)
+	keyAddress := crypto.PubkeyToAddress(key.PublicKey)
+	return func(_ types.Signer, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		if address != keyAddress {
+			return nil, errors.New("not authorized to sign this account")
+		}
+		signature, err := crypto.Sign(signer.Hash(tx).Bytes(), key)
+		if err != nil {
+			return nil, err
+		}
+		return tx.WithSignature(signer, signature)
+	}
+}
+
+// ChainReplayState is the state of a chain relevant for the replay of the account's transactions.
+type ChainReplayState struct {
+	Chain uint8     `json:"chain"`
+	Url   string    `json:"url"`
+	Role  ChainRole `json:"role"`
+	// chain id transactions are signed with, nil if they are not replay protected
+	ChainId       *big.Int        `json:"chainId,omitempty"`
+	ChainIdSource string          `json:"chainIdSource"`
+	NodeChainId   *big.Int        `json:"nodeChainId,omitempty"`
+	GenesisHash   common.Hash     `json:"genesisHash"`
+	Nonce         uint64          `json:"nonce"`        // transactions of the account included in the chain
+	PendingNonce  uint64          `json:"pendingNonce"` // including the transactions in the mempool of the node
+	Ethrelay      *common.Address `json:"ethrelayAddress,omitempty"`
+	Ethash        *common.Address `json:"ethashAddress,omitempty"`
+	Errors        []string        `json:"errors,omitempty"`
+}
+
+// sends reports whether the account sends transactions to the chain
+func (state ChainReplayState) sends() bool {
+	return state.Role != ROLE_SOURCE
+}
+
+// replay hazard severities
+const (
+	// transactions sent by the account can be included on another chain now
+	REPLAY_DANGER = "danger"
+	// transactions can be included on another chain under some circumstances, e.g., once the nonces align
+	REPLAY_WARNING = "warning"
+)
+
+// ReplayHazard is a way transactions of the account could be included on another chain than the one they were sent to.
+type ReplayHazard struct {
+	Severity    string  `json:"severity"`
+	Chains      []uint8 `json:"chains"`
+	Description string  `json:"description"`
+}
+
+// ReplayAudit is the result of AuditReplayProtection.
+type ReplayAudit struct {
+	Account common.Address     `json:"account"`
+	Chains  []ChainReplayState `json:"chains"`
+	Hazards []ReplayHazard     `json:"hazards"`
+}
+
+// AuditReplayProtection checks the chain ids, contract addresses and nonces of the account on all configured chains for
+// replay hazards: transactions sent without replay protection, chain ids shared by different chains and contracts at
+// the same address on chains the transactions could be replayed on.
+func (c Client) AuditReplayProtection() (*ReplayAudit, error) {
+	if c.privateKey == nil {
+		return nil, ErrNoAccount
+	}
+	audit := &ReplayAudit{Account: c.account}
+
+	// replay hazards exist between all configured chains, not only the ones used so far
+	c.connectAll()
+	for _, id := range c.connectedChains() {
+		audit.Chains = append(audit.Chains, c.chainReplayState(id))
+	}
+
+	for i, a := range audit.Chains {
+		for _, b := range audit.Chains[i+1:] {
+			audit.Hazards = append(audit.Hazards, replayHazards(a, b)...)
+			audit.Hazards = append(audit.Hazards, replayHazards(b, a)...)
+		}
+		if a.sends() && a.NodeChainId != nil && a.ChainId != nil && a.NodeChainId.Cmp(a.ChainId) != 0 {
+			audit.Hazards = append(audit.Hazards, ReplayHazard{
+				Severity: REPLAY_WARNING,
+				Chains:   []uint8{a.Chain},
+				Description: fmt.Sprintf("chain %d: the node reports chain id %s, transactions are signed with %s (no transactions are sent)",
+					a.Chain, a.NodeChainId, a.ChainId),
+			})
+		}
+	}
+	return audit, nil
+}
+
+func (c Client) chainReplayState(id uint8) ChainReplayState {
+	chain := c.chain(id)
+	ctx := c.context()
+	state := ChainReplayState{
+		Chain:         id,
+		Url:           chain.fullUrl,
+		Role:          chain.role,
+		ChainId:       chain.signingChainId,
+		ChainIdSource: chain.chainIdSource,
+	}
+	if chain.testimoniumContract != nil {
+		address := chain.testimoniumContractAddress
+		state.Ethrelay = &address
+	}
+	if chain.ethashContract != nil {
+		address := chain.ethashContractAddress
+		state.Ethash = &address
+	}
+
+	var err error
+	if state.NodeChainId, err = chain.client.ChainID(ctx); err != nil {
+		state.Errors = append(state.Errors, fmt.Sprintf("chain id: %s", err))
+	}
+	if genesis, err := chain.client.HeaderByNumber(ctx, big.NewInt(0)); err != nil {
+		state.Errors = append(state.Errors, fmt.Sprintf("genesis block: %s", err))
+	} else {
+		state.GenesisHash = genesis.Hash()
+	}
+	if state.Nonce, err = chain.client.NonceAt(ctx, c.account, nil); err != nil {
+		state.Errors = append(state.Errors, fmt.Sprintf("nonce: %s", err))
+	}
+	if state.PendingNonce, err = chain.client.PendingNonceAt(ctx, c.account); err != nil {
+		state.Errors = append(state.Errors, fmt.Sprintf("pending nonce: %s", err))
+	}
+	return state
+}
+
+// replayHazards returns the ways transactions sent to chain a could be included on chain b
+func replayHazards(a ChainReplayState, b ChainReplayState) []ReplayHazard {
+	if !a.sends() {
+		return nil
+	}
+
+	var reason string
+	switch {
+	case a.ChainId == nil:
+		reason = fmt.Sprintf("transactions to chain %d are not replay protected (chain id %s)", a.Chain, a.ChainIdSource)
+	case b.NodeChainId != nil && a.ChainId.Cmp(b.NodeChainId) == 0:
+		if a.GenesisHash != (common.Hash{}) && a.GenesisHash == b.GenesisHash {
+			// both entries are the same network, the transactions are not replayed but may conflict by their nonces
+			if b.sends() && a.Chain < b.Chain {
+				return []ReplayHazard{{
+					Severity: REPLAY_WARNING,
+					Chains:   []uint8{a.Chain, b.Chain},
+					Description: fmt.Sprintf("chains %d and %d are the same network (chain id %s), transactions sent to both use the same nonces",
+						a.Chain, b.Chain, a.ChainId),
+				}}
+			}
+			return nil
+		}
+		reason = fmt.Sprintf("chain %d has the same chain id %s as chain %d", b.Chain, a.ChainId, a.Chain)
+	default:
+		return nil
+	}
+
+	// a transaction with nonce n is valid on chain b while the account's nonce on chain b is n
+	severity := REPLAY_WARNING
+	nonces := fmt.Sprintf("transactions with nonces from %d on are valid on chain %d once its nonce reaches them", b.Nonce, b.Chain)
+	if b.Nonce < a.PendingNonce {
+		severity = REPLAY_DANGER
+		nonces = fmt.Sprintf("the sent transactions with nonces %d to %d are valid on chain %d now", b.Nonce, a.PendingNonce-1, b.Chain)
+	}
+
+	var contracts []string
+	if a.Ethrelay != nil && b.Ethrelay != nil && *a.Ethrelay == *b.Ethrelay {
+		contracts = append(contracts, "ETH Relay")
+	}
+	if a.Ethash != nil && b.Ethash != nil && *a.Ethash == *b.Ethash {
+		contracts = append(contracts, "Ethash")
+	}
+	description := fmt.Sprintf("%s: %s", reason, nonces)
+	if len(contracts) > 0 {
+		description += fmt.Sprintf(", and the %s contract has the same address on both chains", strings.Join(contracts, " and "))
+	}
+	return []ReplayHazard{{Severity: severity, Chains: []uint8{a.Chain, b.Chain}, Description: description}}
+}
+
+// warnReplayHazards warns of replay hazards of a newly connected chain that are known without querying the chains:
+// transactions sent without replay protection and chain ids shared with other connected chains transactions are sent
+// to. 'account audit' also checks the nonces and genesis blocks.
+func (c Client) warnReplayHazards(chain *Chain) {
+	if c.privateKey == nil || c.replay || chain.role == ROLE_SOURCE {
+		return
+	}
+	if chain.signingChainId == nil {
+		c.problemf("Transactions to chain %d are not replay protected (chain id %s), they are also valid on other chains with the same nonce of the account",
+			chain.id, chain.chainIdSource)
+		return
+	}
+	var shared []uint8
+	for _, id := range c.connectedChains() {
+		other := c.chain(id)
+		if id != chain.id && other.role != ROLE_SOURCE && other.signingChainId != nil && other.signingChainId.Cmp(chain.signingChainId) == 0 {
+			shared = append(shared, id)
+		}
+	}
+	if len(shared) > 0 {
+		c.problemf("Chain %d shares the chain id %s with chains %v, transactions sent to one of them may be replayed on the others (see 'account audit')",
+			chain.id, chain.signingChainId, shared)
+	}
+}
@@ -0,0 +1,50 @@
+// This file contains the results returned by state-changing calls and the output of progress messages. The client
+// does not print results itself, so callers can render them in any format.
+
+package testimonium
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TxResult is the outcome of a transaction sent by the client.
+type TxResult struct {
+	TxHash       common.Hash         `json:"txHash"`
+	BlockNumber  uint64              `json:"blockNumber,omitempty"` // zero if the receipt was not awaited
+	GasUsed      uint64              `json:"gasUsed,omitempty"`
+	Events       []string            `json:"events,omitempty"` // events emitted by the contract
+	Verification *VerificationResult `json:"verification,omitempty"`
+}
+
+func newTxResult(tx *types.Transaction, receipt *types.Receipt) *TxResult {
+	result := &TxResult{TxHash: tx.Hash()}
+	if receipt != nil {
+		// relayed transactions are executed by another transaction
+		result.TxHash = receipt.TxHash
+		result.BlockNumber = receipt.BlockNumber.Uint64()
+		result.GasUsed = receipt.GasUsed
+	}
+	return result
+}
+
+// WithProgressOutput writes the progress messages of long running operations (e.g., submitted transactions) and
+// warnings to w instead of stdout. Use ioutil.Discard to suppress them.
+func WithProgressOutput(w io.Writer) ClientOption {
+	return func(client *Client) error {
+		client.progress = w
+		return nil
+	}
+}
+
+func (c Client) progressf(format string, args ...interface{}) {
+	if c.progress == nil {
+		fmt.Fprintf(os.Stdout, format, args...)
+		return
+	}
+	fmt.Fprintf(c.progress, format, args...)
+}
@@ -0,0 +1,288 @@
+// This file contains the retry policies shared by all operations that are repeated after transient failures: RPC
+// calls, awaiting receipts, event scans and dialing connections. Delays grow exponentially and are randomised
+// (jitter), so relayers failing at the same time do not hit a recovering node at the same time again.
+
+package testimonium
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryOperation is the kind of operation a retry policy applies to.
+type RetryOperation string
+
+const (
+	RETRY_RPC     RetryOperation = "rpc"     // requests to nodes over http, transactions are never sent twice
+	RETRY_RECEIPT RetryOperation = "receipt" // polling for the receipt of a sent transaction
+	RETRY_SCAN    RetryOperation = "scan"    // eth_getLogs requests of event scans
+	RETRY_DIAL    RetryOperation = "dial"    // establishing websocket and IPC connections
+)
+
+// RetryPolicy determines how often and after which delays a failed operation is repeated. The delay after the n-th
+// failed attempt is InitialDelay * Multiplier^(n-1), at most MaxDelay, reduced by a random fraction of up to Jitter.
+type RetryPolicy struct {
+	Attempts     int           // attempts in total including the first one, unlimited if zero (e.g., until a timeout)
+	InitialDelay time.Duration // delay after the first failed attempt
+	MaxDelay     time.Duration // unlimited if zero
+	Multiplier   float64       // growth of the delay per attempt, constant delays if at most 1
+	Jitter       float64       // fraction of the delay that is randomised, in [0, 1]
+}
+
+// DefaultRetryPolicies are the policies of the operations unless a client option or the "retry" entry of the chain
+// config overrides them.
+var DefaultRetryPolicies = RetryPolicies{
+	RETRY_RPC:     {Attempts: 4, InitialDelay: 500 * time.Millisecond, MaxDelay: 8 * time.Second, Multiplier: 2, Jitter: 0.5},
+	RETRY_RECEIPT: {InitialDelay: time.Second, MaxDelay: 15 * time.Second, Multiplier: 1.5, Jitter: 0.2},
+	RETRY_SCAN:    {Attempts: 5, InitialDelay: 2 * time.Second, MaxDelay: 30 * time.Second, Multiplier: 2, Jitter: 0.5},
+	RETRY_DIAL:    {Attempts: 3, InitialDelay: time.Second, MaxDelay: 10 * time.Second, Multiplier: 2, Jitter: 0.5},
+}
+
+// RetryPolicies are the retry policies by operation.
+type RetryPolicies map[RetryOperation]RetryPolicy
+
+// Of returns the policy of the operation, the default policy if none is set.
+func (policies RetryPolicies) Of(operation RetryOperation) RetryPolicy {
+	if policy, exists := policies[operation]; exists {
+		return policy
+	}
+	return DefaultRetryPolicies[operation]
+}
+
+var (
+	jitterMutex  sync.Mutex
+	jitterSource = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Delay returns the delay after the failed attempt (counted from 1).
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := float64(p.InitialDelay)
+	if p.Multiplier > 1 && attempt > 1 {
+		delay *= math.Pow(p.Multiplier, float64(attempt-1))
+	}
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		jitterMutex.Lock()
+		delay -= delay * math.Min(p.Jitter, 1) * jitterSource.Float64()
+		jitterMutex.Unlock()
+	}
+	return time.Duration(delay)
+}
+
+// Do calls operation until it succeeds, returns an error marked with Permanent, the attempts are used up or the
+// context is done. The last error is returned.
+func (p RetryPolicy) Do(ctx context.Context, operation func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := operation()
+		if err == nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if !p.wait(ctx, attempt) {
+			return err
+		}
+	}
+}
+
+// wait waits for the delay after the failed attempt, it returns false without waiting if no attempt is left and false
+// if the context is done meanwhile
+func (p RetryPolicy) wait(ctx context.Context, attempt int) bool {
+	if p.Attempts > 0 && attempt >= p.Attempts {
+		return false
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timer := time.NewTimer(p.Delay(attempt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// permanentError stops the retries of RetryPolicy.Do
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Permanent marks an error returned to RetryPolicy.Do as not worth retrying, Do returns the error without the mark.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// WithRetryPolicy sets the retry policy of the operation for all chains. The "retry" entry of a chain config overrides
+// it for that chain.
+func WithRetryPolicy(operation RetryOperation, policy RetryPolicy) ClientOption {
+	return func(client *Client) error {
+		if _, exists := DefaultRetryPolicies[operation]; !exists {
+			return fmt.Errorf("unknown retry operation %s", operation)
+		}
+		if client.retryPolicies == nil {
+			client.retryPolicies = make(RetryPolicies)
+		}
+		client.retryPolicies[operation] = policy
+		return nil
+	}
+}
+
+// retryPoliciesFromConfig returns the retry policies of the client overridden by the "retry" entry of a chain
+// config, fields that are not specified keep their value, e.g.,
+//
+//	retry:
+//	    rpc:
+//	        attempts: 6
+//	        maxdelay: 20s
+//	    receipt:
+//	        initialdelay: 2s
+//	        multiplier: 1
+//	        jitter: 0
+func (c Client) retryPoliciesFromConfig(chainConfig map[string]interface{}) (RetryPolicies, error) {
+	policies := make(RetryPolicies)
+	for operation := range DefaultRetryPolicies {
+		policies[operation] = c.retryPolicies.Of(operation)
+	}
+	entry, ok := chainConfig["retry"].(map[string]interface{})
+	if !ok {
+		if chainConfig["retry"] != nil {
+			return policies, fmt.Errorf("illegal retry entry %v", chainConfig["retry"])
+		}
+		return policies, nil
+	}
+
+	for name, value := range entry {
+		operation := RetryOperation(name)
+		policy, exists := policies[operation]
+		if !exists {
+			return policies, fmt.Errorf("unknown retry operation %s (rpc, receipt, scan or dial)", name)
+		}
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return policies, fmt.Errorf("illegal retry policy of %s: %v", name, value)
+		}
+		for field, fieldValue := range fields {
+			var err error
+			switch field {
+			case "attempts":
+				policy.Attempts, err = strconv.Atoi(fmt.Sprint(fieldValue))
+			case "initialdelay":
+				policy.InitialDelay, err = time.ParseDuration(fmt.Sprint(fieldValue))
+			case "maxdelay":
+				policy.MaxDelay, err = time.ParseDuration(fmt.Sprint(fieldValue))
+			case "multiplier":
+				policy.Multiplier, err = strconv.ParseFloat(fmt.Sprint(fieldValue), 64)
+			case "jitter":
+				policy.Jitter, err = strconv.ParseFloat(fmt.Sprint(fieldValue), 64)
+				if err == nil && (policy.Jitter < 0 || policy.Jitter > 1) {
+					err = errors.New("has to be in [0, 1]")
+				}
+			default:
+				err = errors.New("unknown field")
+			}
+			if err != nil {
+				return policies, fmt.Errorf("illegal %s of the %s retry policy: %v (%s)", field, name, fieldValue, err)
+			}
+		}
+		policies[operation] = policy
+	}
+	return policies, nil
+}
+
+// retryPolicy returns the retry policy of the operation on the chain
+func (chain *Chain) retryPolicy(operation RetryOperation) RetryPolicy {
+	return chain.retryPolicies.Of(operation)
+}
+
+// retryTransport repeats http requests to nodes that failed because the node was unreachable or temporarily
+// unavailable. Requests sending transactions are not repeated, the node may have accepted the transaction.
+type retryTransport struct {
+	policy    RetryPolicy
+	transport http.RoundTripper
+}
+
+// methods whose requests are never repeated
+var unrepeatableMethods = map[string]bool{"eth_sendRawTransaction": true, "eth_sendTransaction": true}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := t.policy
+	if !repeatableRequest(body) {
+		policy.Attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		attemptReq := req.Clone(req.Context())
+		attemptReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+		resp, err := t.transport.RoundTrip(attemptReq)
+		if !retryableResponse(req.Context(), resp, err) || !policy.wait(req.Context(), attempt) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+	}
+}
+
+// repeatableRequest returns whether the JSON-RPC request (or batch of requests) may be sent again
+func repeatableRequest(body []byte) bool {
+	var calls []struct {
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(body, &calls); err != nil {
+		var call struct {
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal(body, &call); err != nil {
+			return false
+		}
+		calls = append(calls, call)
+	}
+	for _, call := range calls {
+		if unrepeatableMethods[call.Method] {
+			return false
+		}
+	}
+	return true
+}
+
+// retryableResponse returns whether the request failed transiently: the node was unreachable, overloaded or a gateway
+// in front of it failed
+func retryableResponse(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		// the provider transport already waited for the rate limits of all API keys
+		return ctx.Err() == nil && !errors.Is(err, ErrQuotaExhausted)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,120 @@
+// This file contains the roles of the configured chains. Source chains are only read from (headers, transactions,
+// receipts), so they need neither a funded account nor contract addresses. Transactions are only sent to destination
+// chains, which require the private key.
+
+package testimonium
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNoAccount is returned if a transaction should be sent, but no private key is configured.
+	ErrNoAccount = errors.New("no account configured")
+	// ErrSourceChain is returned if a transaction should be sent to a chain with the source role.
+	ErrSourceChain = errors.New("no transactions are sent to source chains")
+)
+
+// ChainRole determines how a chain is used by the client.
+type ChainRole int
+
+const (
+	// the chain is read from and transactions are sent to it (default)
+	ROLE_ANY ChainRole = 0
+	// the chain is only read from, e.g., the chain whose headers are relayed
+	ROLE_SOURCE ChainRole = 1
+	// transactions are sent to the chain, e.g., the chain the ETH Relay contract is deployed on
+	ROLE_DESTINATION ChainRole = 2
+)
+
+func (role ChainRole) String() string {
+	switch role {
+	case ROLE_ANY:
+		return "any"
+	case ROLE_SOURCE:
+		return "source"
+	case ROLE_DESTINATION:
+		return "destination"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(role))
+	}
+}
+
+// MarshalText encodes the role by its name, e.g., in JSON results.
+func (role ChainRole) MarshalText() ([]byte, error) {
+	return []byte(role.String()), nil
+}
+
+// ParseChainRole parses the role names "any", "source" and "destination".
+func ParseChainRole(role string) (ChainRole, error) {
+	switch strings.ToLower(role) {
+	case "any", "":
+		return ROLE_ANY, nil
+	case "source":
+		return ROLE_SOURCE, nil
+	case "destination":
+		return ROLE_DESTINATION, nil
+	default:
+		return ROLE_ANY, fmt.Errorf("unknown chain role '%s' (any, source, destination)", role)
+	}
+}
+
+// chainRoleFromConfig reads the "role" entry of a chain config
+func chainRoleFromConfig(chainConfig map[string]interface{}) (ChainRole, error) {
+	if chainConfig["role"] == nil {
+		return ROLE_ANY, nil
+	}
+	role, ok := chainConfig["role"].(string)
+	if !ok {
+		return ROLE_ANY, fmt.Errorf("illegal chain role %v", chainConfig["role"])
+	}
+	return ParseChainRole(role)
+}
+
+// WithChainRole sets the role of the chain, overriding the "role" entry of its config.
+func WithChainRole(chain uint8, role ChainRole) ClientOption {
+	return func(client *Client) error {
+		if client.chainRoles == nil {
+			client.chainRoles = make(map[uint8]ChainRole)
+		}
+		client.chainRoles[chain] = role
+		return nil
+	}
+}
+
+// ChainRole returns the role of the chain.
+func (c Client) ChainRole(chain uint8) (ChainRole, error) {
+	if err := c.checkChain(chain); err != nil {
+		return ROLE_ANY, err
+	}
+	return c.chain(chain).role, nil
+}
+
+// SourceChains returns the chains that can be read from (all chains except destination chains), ordered by chain id.
+func (c Client) SourceChains() []uint8 {
+	return c.chainsWithout(ROLE_DESTINATION)
+}
+
+// DestinationChains returns the chains transactions can be sent to (all chains except source chains), ordered by
+// chain id.
+func (c Client) DestinationChains() []uint8 {
+	return c.chainsWithout(ROLE_SOURCE)
+}
+
+func (c Client) chainsWithout(role ChainRole) []uint8 {
+	c.connectAll()
+	var chains []uint8
+	c.eachChain(func(chain *Chain) {
+		if chain.role != role {
+			chains = append(chains, chain.id)
+		}
+	})
+	return chains
+}
+
+// HasAccount reports whether a private key is configured, which is required to send transactions.
+func (c Client) HasAccount() bool {
+	return c.privateKey != nil
+}
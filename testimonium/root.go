@@ -0,0 +1,141 @@
+// This file contains the verification of Merkle Patricia proofs against trusted roots that are anchored in the
+// contract by other mechanisms than submitted block headers.
+
+package testimonium
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/proofs"
+)
+
+// RootVerifierABI is the interface a contract has to implement to support the verification against trusted roots.
+const RootVerifierABI = `[
+{"type":"function","name":"isRootTrusted","stateMutability":"view","inputs":[{"name":"root","type":"bytes32"}],"outputs":[{"name":"","type":"bool"}]},
+{"type":"function","name":"verifyAgainstRoot","stateMutability":"payable","inputs":[{"name":"feeInWei","type":"uint256"},{"name":"root","type":"bytes32"},{"name":"valueType","type":"uint8"},{"name":"rlpEncodedValue","type":"bytes"},{"name":"path","type":"bytes"},{"name":"rlpEncodedNodes","type":"bytes"}],"outputs":[{"name":"","type":"uint8"}]},
+{"type":"event","name":"VerifyAgainstRoot","anonymous":false,"inputs":[{"name":"root","type":"bytes32","indexed":false},{"name":"result","type":"uint8","indexed":false}]}
+]`
+
+var (
+	// ErrRootVerificationNotSupported is returned if the deployed contract does not expose the functions of
+	// RootVerifierABI.
+	ErrRootVerificationNotSupported = errors.New("contract does not support verification against trusted roots")
+	// ErrUntrustedRoot is returned if the root is not stored as trusted root in the contract.
+	ErrUntrustedRoot = errors.New("root is not trusted by the contract")
+)
+
+// VerifyAgainstRoot verifies the proof on the specified chain against a trusted root stored in the contract instead
+// of the root of a submitted block header, where the contract supports it (ErrRootVerificationNotSupported otherwise).
+// The proof is checked locally first, the proof's root has to be the specified root. Proofs can be built with
+// BuildTxProof, BuildReceiptProof or the proofs package.
+func (c Client) VerifyAgainstRoot(feeInWei *big.Int, root common.Hash, trieValueType TrieValueType, proof proofs.Proof, chain uint8) (*TxResult, error) {
+	c, span := c.startSpan("verify proof against root", chain)
+	span.SetAttribute("ethrelay.value_type", int(trieValueType))
+	span.SetAttribute("ethrelay.root", root.Hex())
+	result, err := c.verifyAgainstRoot(feeInWei, root, trieValueType, proof, chain)
+	span.setTxResult(result)
+	c.publishTxEvent(AnalyticsEvent{Type: ANALYTICS_EVENT_VERIFICATION, Chain: chain, Account: c.as(OPERATOR_VERIFIER).account,
+		AmountInWei: feeInWei, ValueType: trieValueType.String()}, result, err)
+	return result, span.End(err)
+}
+
+func (c Client) verifyAgainstRoot(feeInWei *big.Int, root common.Hash, trieValueType TrieValueType, proof proofs.Proof, chain uint8) (*TxResult, error) {
+	c = c.as(OPERATOR_VERIFIER)
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, err
+	}
+
+	if proof.Root != root {
+		return nil, fmt.Errorf("proof is built for root %s, not for %s", proof.Root.String(), root.String())
+	}
+	if err := proofs.VerifyProof(proof); err != nil {
+		return nil, fmt.Errorf("invalid proof: %s", err)
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(RootVerifierABI))
+	if err != nil {
+		return nil, err
+	}
+	destination := c.chain(chain)
+	code, err := destination.client.CodeAt(c.context(), destination.testimoniumContractAddress, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"isRootTrusted", "verifyAgainstRoot"} {
+		if !codeHasSelector(code, parsed.Methods[name].ID()) {
+			return nil, fmt.Errorf("%w: no function %s in the contract at %s", ErrRootVerificationNotSupported, name,
+				destination.testimoniumContractAddress.Hex())
+		}
+	}
+	client := destination.client
+	contract := bind.NewBoundContract(destination.testimoniumContractAddress, parsed, client, relayBackend{Client: client,
+		chain: destination, privateKey: c.privateKey, progressf: c.progressf}, client)
+
+	trusted := new(bool)
+	if err := contract.Call(&bind.CallOpts{From: c.account, Context: c.context()}, trusted, "isRootTrusted", root); err != nil {
+		return nil, err
+	}
+	if !*trusted {
+		return nil, fmt.Errorf("%w: %s", ErrUntrustedRoot, root.String())
+	}
+
+	rlpEncodedProofNodes, err := proof.EncodedNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := c.verificationValue(feeInWei, 1, chain)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := prepareTransaction(c.context(), c.account, c.privateKey, destination, value)
+	if err != nil {
+		return nil, err
+	}
+	if !c.noAccessLists {
+		auth.Context = withAccessList(auth.Context)
+	}
+
+	tx, err := contract.Transact(auth, "verifyAgainstRoot", feeInWei, root, uint8(trieValueType), proof.Value, proof.Path, rlpEncodedProofNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	c.progressf("Tx submitted: %s\n", tx.Hash().Hex())
+
+	receipt, err := awaitTxReceipt(c.context(), destination, tx.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	if receipt.Status == 0 {
+		// Transaction failed
+		reason := getFailureReason(client, c.account, tx, receipt.BlockNumber)
+		return nil, fmt.Errorf("tx failed: %s", reason)
+	}
+
+	event := parsed.Events["VerifyAgainstRoot"]
+	for _, vLog := range receipt.Logs {
+		if len(vLog.Topics) == 0 || vLog.Topics[0] != event.ID() {
+			continue
+		}
+		var emitted struct {
+			Root   [32]byte
+			Result uint8
+		}
+		if err := contract.UnpackLog(&emitted, "VerifyAgainstRoot", *vLog); err != nil {
+			return nil, err
+		}
+		result := newTxResult(tx, receipt)
+		result.Verification = &VerificationResult{ReturnCode: emitted.Result}
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("no event found")
+}
@@ -0,0 +1,66 @@
+package testimonium
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pantos-io/go-ethrelay/proofs"
+)
+
+func TestVerifyAgainstRoot(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(RootVerifierABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// function dispatcher comparing the call data with the selectors of the root verifier
+	dispatcher := []byte{0x60, 0x80}
+	for _, name := range []string{"isRootTrusted", "verifyAgainstRoot"} {
+		dispatcher = append(append(dispatcher, 0x63), parsed.Methods[name].ID()...)
+	}
+
+	receipt := types.NewReceipt(nil, false, 21000)
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	proof, err := proofs.BuildReceiptProof(types.Receipts{receipt}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		code []byte
+		root common.Hash
+		err  error
+	}{
+		{"not supported", []byte{0x60, 0x80}, proof.Root, ErrRootVerificationNotSupported},
+		{"isRootTrusted only", dispatcher[:7], proof.Root, ErrRootVerificationNotSupported},
+		{"untrusted root", dispatcher, proof.Root, ErrUntrustedRoot},
+		{"other root", dispatcher, common.HexToHash("0x01"), nil},
+	}
+	for _, test := range tests {
+		chainsConfig := map[string]interface{}{
+			"1": map[string]interface{}{
+				"url":             newFakeNode(t, hexutil.Encode(test.code), nil, nil),
+				"ethrelayaddress": "0x00000000000000000000000000000000000000aa",
+			},
+		}
+		client, err := NewClient("", chainsConfig, WithoutCapabilityProbing(), WithProgressOutput(ioutil.Discard))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.VerifyAgainstRoot(big.NewInt(0), test.root, VALUE_TYPE_RECEIPT, proof, 1)
+		if test.err == nil {
+			if err == nil || errors.Is(err, ErrRootVerificationNotSupported) || errors.Is(err, ErrUntrustedRoot) {
+				t.Errorf("%s: %v instead of a root mismatch", test.name, err)
+			}
+		} else if !errors.Is(err, test.err) {
+			t.Errorf("%s: %v instead of %v", test.name, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// This file contains the cancellation of long event scans. A canceled scan (e.g., interrupted with Ctrl-C or timed
+// out) returns what it has scanned so far together with a cursor to resume the scan at, instead of discarding the work.
+
+package testimonium
+
+import (
+	"context"
+	"fmt"
+)
+
+// ScanCursor is the position a canceled event scan continues at.
+type ScanCursor struct {
+	Chain     uint8  `json:"chain"`
+	NextBlock uint64 `json:"nextBlock"` // first block that was not scanned completely
+	LastBlock uint64 `json:"lastBlock"` // last block of the scanned range
+}
+
+// ScanInterruptedError is returned by event scans whose context was canceled. The results of the blocks before the
+// cursor are returned with the error.
+type ScanInterruptedError struct {
+	Cursor ScanCursor
+	Err    error // error of the context, e.g., context.Canceled or context.DeadlineExceeded
+}
+
+func (e *ScanInterruptedError) Error() string {
+	return fmt.Sprintf("scan of chain %d interrupted at block %d of %d: %s", e.Cursor.Chain, e.Cursor.NextBlock,
+		e.Cursor.LastBlock, e.Err)
+}
+
+func (e *ScanInterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// scanInterrupted returns a ScanInterruptedError with the cursor if the context is done, nil otherwise
+func scanInterrupted(ctx context.Context, chain uint8, nextBlock uint64, lastBlock uint64) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	return &ScanInterruptedError{
+		Cursor: ScanCursor{Chain: chain, NextBlock: nextBlock, LastBlock: lastBlock},
+		Err:    ctx.Err(),
+	}
+}
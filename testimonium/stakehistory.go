@@ -0,0 +1,318 @@
+// This file contains the stake history of an account: every change of its stake in the ETH Relay contract with its
+// cause (deposit, withdrawal, dispute), so the stake at any time in the past can be queried, e.g., for accounting or
+// the post-mortem of a dispute. The contract emits no event for deposits, so the history is reconstructed from the
+// stake at past blocks (which requires the state of these blocks, e.g., of an archive node) and the indexed events.
+
+package testimonium
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// StakeChangeKind is the cause of a change of the stake of an account.
+type StakeChangeKind string
+
+const (
+	STAKE_DEPOSIT    StakeChangeKind = "deposit"
+	STAKE_WITHDRAWAL StakeChangeKind = "withdrawal"
+	// headers of the account were removed by a dispute
+	STAKE_SLASHED StakeChangeKind = "slashed"
+	// the account removed headers with a dispute
+	STAKE_DISPUTE StakeChangeKind = "dispute"
+	// a decrease without indexed event, e.g., by a contract variant
+	STAKE_OTHER StakeChangeKind = "other"
+)
+
+// StakeChange is a change of the stake of an account in a block of the destination chain. Changes within the same block
+// are combined, the kind is the one of the first indexed event of the block (withdrawal, slashing, dispute).
+type StakeChange struct {
+	BlockNumber uint64          `json:"blockNumber"`
+	Time        time.Time       `json:"time"` // of the block
+	Kind        StakeChangeKind `json:"kind"`
+	Amount      *big.Int        `json:"amount"`             // negative if the stake decreased
+	Balance     *big.Int        `json:"balance"`            // the stake after the block
+	TxHashes    []common.Hash   `json:"txHashes,omitempty"` // the indexed transactions of the change, none for deposits
+}
+
+// StakeHistory contains the changes of the stake of an account on a chain up to the last block of the event index.
+type StakeHistory struct {
+	Chain     uint8          `json:"chain"`
+	Account   common.Address `json:"account"`
+	LastBlock uint64         `json:"lastBlock"` // the history is complete up to this block
+	Changes   []StakeChange  `json:"changes"`   // oldest first
+}
+
+// Balance returns the stake of the account after the last block of the history.
+func (history *StakeHistory) Balance() *big.Int {
+	return history.BalanceAtBlock(history.LastBlock)
+}
+
+// BalanceAtBlock returns the stake of the account after the block of the destination chain.
+func (history *StakeHistory) BalanceAtBlock(blockNumber uint64) *big.Int {
+	balance := new(big.Int)
+	for _, change := range history.Changes {
+		if change.BlockNumber > blockNumber {
+			break
+		}
+		balance = change.Balance
+	}
+	return new(big.Int).Set(balance)
+}
+
+// BalanceAt returns the stake of the account at the time, i.e., after the last block mined until then.
+func (history *StakeHistory) BalanceAt(t time.Time) *big.Int {
+	balance := new(big.Int)
+	for _, change := range history.Changes {
+		if change.Time.After(t) {
+			break
+		}
+		balance = change.Balance
+	}
+	return new(big.Int).Set(balance)
+}
+
+// Totals returns the sum of the changes per kind, e.g., the stake deposited or lost by disputes in total.
+func (history *StakeHistory) Totals() map[StakeChangeKind]*big.Int {
+	totals := make(map[StakeChangeKind]*big.Int)
+	for _, change := range history.Changes {
+		if totals[change.Kind] == nil {
+			totals[change.Kind] = new(big.Int)
+		}
+		totals[change.Kind].Add(totals[change.Kind], change.Amount)
+	}
+	return totals
+}
+
+// StakeHistory updates the event index of the chain in the data directory and reconstructs the changes of the stake of
+// the account up to the last indexed block. The stake is read at the blocks of the account's indexed withdrawals and
+// disputes and, between them, bisected to find the blocks of its deposits. The state of old blocks is read from the
+// archive node of the chain if the node has pruned it (ErrBlockDataUnavailable if neither provides it).
+func (c Client) StakeHistory(dataDir string, chain uint8, account common.Address) (*StakeHistory, error) {
+	index, _, err := c.UpdateEventIndex(dataDir, chain)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := c.newStakeReader(chain, account)
+	if err != nil {
+		return nil, err
+	}
+	history := &StakeHistory{Chain: chain, Account: account, LastBlock: index.LastScannedBlock, Changes: []StakeChange{}}
+
+	causes := stakeChangeCauses(index, account)
+	probes := []uint64{0, index.LastScannedBlock}
+	for blockNumber := range causes {
+		if blockNumber > 0 && blockNumber <= index.LastScannedBlock {
+			probes = append(probes, blockNumber-1, blockNumber)
+		}
+	}
+	sort.Slice(probes, func(i, j int) bool { return probes[i] < probes[j] })
+
+	// only deposits increase the stake between the probed blocks, so stake changes cannot cancel each other out there
+	var changed []uint64
+	for i := 1; i < len(probes); i++ {
+		if probes[i] == probes[i-1] {
+			continue
+		}
+		blocks, err := reader.changes(probes[i-1], probes[i])
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, blocks...)
+	}
+
+	for _, blockNumber := range changed {
+		before, err := reader.stakeAt(blockNumber - 1)
+		if err != nil {
+			return nil, err
+		}
+		after, err := reader.stakeAt(blockNumber)
+		if err != nil {
+			return nil, err
+		}
+		blockTime, err := c.blockTime(blockNumber, chain)
+		if err != nil {
+			return nil, err
+		}
+		change := StakeChange{
+			BlockNumber: blockNumber,
+			Time:        blockTime.UTC(),
+			Kind:        STAKE_DEPOSIT,
+			Amount:      new(big.Int).Sub(after, before),
+			Balance:     after,
+		}
+		if cause, exists := causes[blockNumber]; exists {
+			change.Kind, change.TxHashes = cause.kind, cause.txHashes
+		} else if change.Amount.Sign() < 0 {
+			change.Kind = STAKE_OTHER
+		}
+		history.Changes = append(history.Changes, change)
+	}
+	return history, nil
+}
+
+// stakeChangeCause is the indexed cause of a change of the stake in a block
+type stakeChangeCause struct {
+	kind     StakeChangeKind
+	txHashes []common.Hash
+}
+
+// stakeChangeCauses returns the blocks of the indexed events changing the stake of the account with their cause
+func stakeChangeCauses(index *EventIndex, account common.Address) map[uint64]*stakeChangeCause {
+	causes := make(map[uint64]*stakeChangeCause)
+	add := func(blockNumber uint64, kind StakeChangeKind, txHash common.Hash) {
+		if causes[blockNumber] == nil {
+			causes[blockNumber] = &stakeChangeCause{kind: kind}
+		}
+		causes[blockNumber].txHashes = append(causes[blockNumber].txHashes, txHash)
+	}
+
+	for _, record := range index.Withdrawals {
+		if record.Client == account {
+			add(record.BlockNumber, STAKE_WITHDRAWAL, record.TxHash)
+		}
+	}
+	children := submittedChildren(index)
+	for _, record := range index.Disputes {
+		if !record.Succeeded() {
+			continue
+		}
+		for _, removed := range removedBranch(index, children, record) {
+			if removed.Submitter == account {
+				add(record.BlockNumber, STAKE_SLASHED, record.TxHash)
+				break
+			}
+		}
+	}
+	for _, record := range index.Disputes {
+		if record.Succeeded() && record.Disputer == account {
+			add(record.BlockNumber, STAKE_DISPUTE, record.TxHash)
+		}
+	}
+	return causes
+}
+
+// submittedChildren maps the hashes of blocks to the indexed submissions of their children
+func submittedChildren(index *EventIndex) map[common.Hash][]*SubmitRecord {
+	children := make(map[common.Hash][]*SubmitRecord)
+	for _, record := range index.Records {
+		if header, err := decodeHeaderFromRLP(record.RlpHeader); err == nil {
+			children[header.ParentHash] = append(children[header.ParentHash], record)
+		}
+	}
+	return children
+}
+
+// removedBranch returns the indexed submissions removed by the dispute: the disputed block and the blocks submitted
+// on top of it until the dispute
+func removedBranch(index *EventIndex, children map[common.Hash][]*SubmitRecord, dispute *DisputeRecord) []*SubmitRecord {
+	disputed, exists := index.Records[dispute.BlockHash]
+	if !exists || disputed.SubmitBlockNumber > dispute.BlockNumber {
+		return nil
+	}
+	removed := []*SubmitRecord{disputed}
+	for i := 0; i < len(removed); i++ {
+		for _, child := range children[removed[i].BlockHash] {
+			if child.SubmitBlockNumber <= dispute.BlockNumber {
+				removed = append(removed, child)
+			}
+		}
+	}
+	return removed
+}
+
+// stakeReader reads the stake of an account at past blocks of a chain
+type stakeReader struct {
+	client   Client
+	chain    uint8
+	account  common.Address
+	contract common.Address
+	call     []byte
+	stakes   map[uint64]*big.Int
+}
+
+func (c Client) newStakeReader(chain uint8, account common.Address) (*stakeReader, error) {
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, err
+	}
+	testimoniumAbi, err := c.chain(chain).testimoniumAbi()
+	if err != nil {
+		return nil, err
+	}
+	call, err := testimoniumAbi.Pack("getStake")
+	if err != nil {
+		return nil, err
+	}
+	return &stakeReader{
+		client:   c,
+		chain:    chain,
+		account:  account,
+		contract: c.chain(chain).testimoniumContractAddress,
+		call:     call,
+		stakes:   make(map[uint64]*big.Int),
+	}, nil
+}
+
+// stakeAt returns the stake of the account after the block, zero before the contract was deployed
+func (reader *stakeReader) stakeAt(blockNumber uint64) (*big.Int, error) {
+	if stake, exists := reader.stakes[blockNumber]; exists {
+		return stake, nil
+	}
+	var output []byte
+	what := fmt.Sprintf("stake of %s at block %d", reader.account.Hex(), blockNumber)
+	err := reader.client.withArchiveFallback(reader.chain, what, func(client *ethclient.Client, _ *rpc.Client) error {
+		var err error
+		output, err = client.CallContract(context.Background(), ethereum.CallMsg{
+			From: reader.account,
+			To:   &reader.contract,
+			Data: reader.call,
+		}, new(big.Int).SetUint64(blockNumber))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	stake := new(big.Int)
+	if len(output) >= 32 {
+		stake.SetBytes(output[:32])
+	}
+	reader.stakes[blockNumber] = stake
+	return stake, nil
+}
+
+// changes bisects the blocks after from until to and returns the blocks changing the stake. Changes that cancel each
+// other out are not found.
+func (reader *stakeReader) changes(from uint64, to uint64) ([]uint64, error) {
+	before, err := reader.stakeAt(from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := reader.stakeAt(to)
+	if err != nil {
+		return nil, err
+	}
+	if before.Cmp(after) == 0 {
+		return nil, nil
+	}
+	if to == from+1 {
+		return []uint64{to}, nil
+	}
+	middle := from + (to-from)/2
+	changes, err := reader.changes(from, middle)
+	if err != nil {
+		return nil, err
+	}
+	later, err := reader.changes(middle, to)
+	if err != nil {
+		return nil, err
+	}
+	return append(changes, later...), nil
+}
@@ -0,0 +1,176 @@
+// This file contains the statistics of a relayer, aggregated per day from the event index of a chain: the headers it
+// submitted, the disputes it won or lost, the verifications of its headers and the gas it spent.
+
+package testimonium
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RelayStats contains the statistics of an account on a chain.
+type RelayStats struct {
+	Chain   uint8          `json:"chain"`
+	Account common.Address `json:"account"`
+	Days    []*DayStats    `json:"days"` // days with activity, oldest first
+	Total   *DayStats      `json:"total"`
+}
+
+// DayStats contains the statistics of a single day (UTC), the day of the total statistics is empty.
+type DayStats struct {
+	Day              string `json:"day,omitempty"` // e.g., 2006-01-02
+	HeadersSubmitted int    `json:"headersSubmitted"`
+	// own disputes that removed the disputed block and failed disputes of own blocks
+	DisputesWon int `json:"disputesWon"`
+	// own disputes that failed and disputes that removed own blocks
+	DisputesLost int `json:"disputesLost"`
+	// verifications of values in blocks submitted by the account and the fees paid for them
+	VerificationsServed int      `json:"verificationsServed"`
+	FeesEarned          *big.Int `json:"feesEarned"`
+	// fees paid in ERC-20 tokens (in the smallest unit) per token, they are not included in FeesEarned
+	TokenFeesEarned map[common.Address]*big.Int `json:"tokenFeesEarned,omitempty"`
+	// gas costs (in wei) of the submissions, disputes and verifications sent by the account
+	GasSpent *big.Int `json:"gasSpent"`
+	// average time between the mining of a submitted block on the source chain and its submission
+	AverageLatency time.Duration `json:"averageLatency"`
+
+	latencySum   time.Duration
+	latencyCount int
+}
+
+func newDayStats(day string) *DayStats {
+	return &DayStats{Day: day, FeesEarned: new(big.Int), GasSpent: new(big.Int)}
+}
+
+// statsDay returns the UTC day of the timestamp, the day of records indexed without timestamp is unknown
+func statsDay(timestamp uint64) string {
+	if timestamp == 0 {
+		return "unknown"
+	}
+	return time.Unix(int64(timestamp), 0).UTC().Format("2006-01-02")
+}
+
+// ComputeRelayStats aggregates the records of the event index sent by or concerning the account per day. Gas costs of
+// transactions emitting several events (e.g., batch submissions) are counted once.
+func ComputeRelayStats(index *EventIndex, account common.Address) *RelayStats {
+	stats := &RelayStats{Chain: index.Chain, Account: account, Total: newDayStats("")}
+	days := make(map[string]*DayStats)
+	day := func(timestamp uint64) *DayStats {
+		name := statsDay(timestamp)
+		if days[name] == nil {
+			days[name] = newDayStats(name)
+		}
+		return days[name]
+	}
+	paidTxs := make(map[common.Hash]bool)
+	spend := func(txHash common.Hash, cost txCost) {
+		if paidTxs[txHash] || cost.GasPrice == nil {
+			return
+		}
+		paidTxs[txHash] = true
+		gas := new(big.Int).Mul(new(big.Int).SetUint64(cost.GasUsed), cost.GasPrice)
+		day(cost.Timestamp).GasSpent.Add(day(cost.Timestamp).GasSpent, gas)
+	}
+
+	for _, record := range index.Records {
+		if record.Submitter != account {
+			continue
+		}
+		entry := day(record.Timestamp)
+		entry.HeadersSubmitted++
+		spend(record.TxHash, record.txCost)
+		if header, err := decodeHeaderFromRLP(record.RlpHeader); err == nil && record.Timestamp >= header.Time {
+			entry.latencySum += time.Duration(record.Timestamp-header.Time) * time.Second
+			entry.latencyCount++
+		}
+	}
+
+	for _, record := range index.Disputes {
+		own := record.Disputer == account
+		submitted, exists := index.Records[record.BlockHash]
+		ownBlock := exists && submitted.Submitter == account
+		if !own && !ownBlock {
+			continue
+		}
+		if own == record.Succeeded() {
+			day(record.Timestamp).DisputesWon++
+		} else {
+			day(record.Timestamp).DisputesLost++
+		}
+		if own {
+			spend(record.TxHash, record.txCost)
+		}
+	}
+
+	for _, record := range index.Verifications {
+		if record.Verifier == account {
+			spend(record.TxHash, record.txCost)
+		}
+		if submitted, exists := index.Records[record.BlockHash]; exists && submitted.Submitter == account {
+			entry := day(record.Timestamp)
+			entry.VerificationsServed++
+			if record.Fee != nil && record.FeeToken != nil {
+				entry.addFee(*record.FeeToken, record.Fee)
+			} else if record.Fee != nil {
+				entry.addFee(common.Address{}, record.Fee)
+			}
+		}
+	}
+
+	for _, entry := range days {
+		stats.Days = append(stats.Days, entry)
+		stats.Total.add(entry)
+		entry.average()
+	}
+	stats.Total.average()
+	sort.Slice(stats.Days, func(i, j int) bool { return stats.Days[i].Day < stats.Days[j].Day })
+	return stats
+}
+
+func (stats *DayStats) add(other *DayStats) {
+	stats.HeadersSubmitted += other.HeadersSubmitted
+	stats.DisputesWon += other.DisputesWon
+	stats.DisputesLost += other.DisputesLost
+	stats.VerificationsServed += other.VerificationsServed
+	stats.FeesEarned.Add(stats.FeesEarned, other.FeesEarned)
+	for token, fees := range other.TokenFeesEarned {
+		stats.addFee(token, fees)
+	}
+	stats.GasSpent.Add(stats.GasSpent, other.GasSpent)
+	stats.latencySum += other.latencySum
+	stats.latencyCount += other.latencyCount
+}
+
+// addFee adds a fee paid in ether (zero token) or in the token
+func (stats *DayStats) addFee(token common.Address, fee *big.Int) {
+	if token == (common.Address{}) {
+		stats.FeesEarned.Add(stats.FeesEarned, fee)
+		return
+	}
+	if stats.TokenFeesEarned == nil {
+		stats.TokenFeesEarned = make(map[common.Address]*big.Int)
+	}
+	if stats.TokenFeesEarned[token] == nil {
+		stats.TokenFeesEarned[token] = new(big.Int)
+	}
+	stats.TokenFeesEarned[token].Add(stats.TokenFeesEarned[token], fee)
+}
+
+func (stats *DayStats) average() {
+	if stats.latencyCount > 0 {
+		stats.AverageLatency = (stats.latencySum / time.Duration(stats.latencyCount)).Round(time.Second)
+	}
+}
+
+// RelayStats updates the event index of the chain in the data directory and computes the statistics of the account
+// from it.
+func (c Client) RelayStats(dataDir string, chain uint8, account common.Address) (*RelayStats, error) {
+	index, _, err := c.UpdateEventIndex(dataDir, chain)
+	if err != nil {
+		return nil, err
+	}
+	return ComputeRelayStats(index, account), nil
+}
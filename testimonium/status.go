@@ -0,0 +1,240 @@
+// This file contains the status of the relay on a chain. The view calls needed for the status are aggregated into a
+// single Multicall3 request if a Multicall3 contract is deployed on the chain.
+
+package testimonium
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MULTICALL3_ADDRESS is the address the Multicall3 contract is deployed at on most chains.
+const MULTICALL3_ADDRESS = "0xcA11bde05977b3631167028862bE2a173976CA11"
+
+const multicall3ABI = `[
+{"type":"function","name":"aggregate3","stateMutability":"payable","inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}]}],"outputs":[{"name":"returnData","type":"tuple[]","components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}]}]},
+{"type":"function","name":"getEthBalance","stateMutability":"view","inputs":[{"name":"addr","type":"address"}],"outputs":[{"name":"balance","type":"uint256"}]}
+]`
+
+// ChainStatus contains the state of the ETH Relay contract on a chain as seen by the current account.
+type ChainStatus struct {
+	Chain                 uint8                `json:"chain"`
+	Balance               *big.Int             `json:"balance"`
+	Stake                 *big.Int             `json:"stake"`
+	RequiredStakePerBlock *big.Int             `json:"requiredStakePerBlock"`
+	VerificationFee       *big.Int             `json:"verificationFee"`
+	LongestChainEndpoint  common.Hash          `json:"longestChainEndpoint"`
+	GenesisBlockHash      common.Hash          `json:"genesisBlockHash"`
+	HeadersStored         map[common.Hash]bool `json:"headersStored,omitempty"` // whether the requested headers are stored in the contract
+	Multicall             bool                 `json:"multicall"`               // whether the view calls were aggregated with Multicall3
+}
+
+type multicall3Call struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type multicall3Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// viewCall is a single view call of the status, unpack stores the decoded return value
+type viewCall struct {
+	target   common.Address
+	contract abi.ABI
+	method   string
+	args     []interface{}
+	unpack   func(out []interface{}) error
+}
+
+// Status returns the status of the ETH Relay contract on the specified chain including whether the headers with the
+// specified hashes are stored. If a Multicall3 contract is deployed on the chain (config key "multicalladdress",
+// default MULTICALL3_ADDRESS), the view calls are sent as a single request, otherwise they are sent one by one.
+// The stake is always read with a separate call, as the contract returns the stake of the caller.
+func (c Client) Status(chain uint8, blockHashes ...common.Hash) (ChainStatus, error) {
+	if err := c.checkTestimonium(chain); err != nil {
+		return ChainStatus{}, err
+	}
+
+	testimoniumAbi, err := c.chain(chain).testimoniumAbi()
+	if err != nil {
+		return ChainStatus{}, err
+	}
+	multicallAbi, err := abi.JSON(strings.NewReader(multicall3ABI))
+	if err != nil {
+		return ChainStatus{}, err
+	}
+
+	status := ChainStatus{
+		Chain:         chain,
+		HeadersStored: make(map[common.Hash]bool),
+	}
+	contract := c.chain(chain).testimoniumContractAddress
+
+	calls := []viewCall{
+		{contract, testimoniumAbi, "getRequiredStakePerBlock", nil, func(out []interface{}) error {
+			return unpackBigInt(out, &status.RequiredStakePerBlock)
+		}},
+		{contract, testimoniumAbi, "getRequiredVerificationFee", nil, func(out []interface{}) error {
+			return unpackBigInt(out, &status.VerificationFee)
+		}},
+		{contract, testimoniumAbi, "getLongestChainEndpoint", nil, func(out []interface{}) error {
+			return unpackHash(out, &status.LongestChainEndpoint)
+		}},
+		{contract, testimoniumAbi, "getGenesisBlockHash", nil, func(out []interface{}) error {
+			return unpackHash(out, &status.GenesisBlockHash)
+		}},
+	}
+	for _, blockHash := range blockHashes {
+		blockHash := blockHash
+		calls = append(calls, viewCall{contract, testimoniumAbi, "isHeaderStored", []interface{}{blockHash}, func(out []interface{}) error {
+			stored, ok := out[0].(bool)
+			if !ok {
+				return fmt.Errorf("unexpected return value %v", out[0])
+			}
+			status.HeadersStored[blockHash] = stored
+			return nil
+		}})
+	}
+
+	multicallAddress := c.chain(chain).multicallAddress
+	code, err := c.chain(chain).client.CodeAt(c.context(), multicallAddress, nil)
+	if err != nil {
+		return ChainStatus{}, err
+	}
+
+	if len(code) > 0 {
+		// the balance is read by the multicall contract as well
+		calls = append(calls, viewCall{multicallAddress, multicallAbi, "getEthBalance", []interface{}{c.account}, func(out []interface{}) error {
+			return unpackBigInt(out, &status.Balance)
+		}})
+		if err := c.aggregate(chain, multicallAbi, calls); err != nil {
+			return ChainStatus{}, err
+		}
+		status.Multicall = true
+	} else {
+		for _, call := range calls {
+			if err := c.callView(chain, call); err != nil {
+				return ChainStatus{}, err
+			}
+		}
+		status.Balance, err = c.chain(chain).client.BalanceAt(c.context(), c.account, nil)
+		if err != nil {
+			return ChainStatus{}, err
+		}
+	}
+
+	status.Stake, err = c.GetStake(chain)
+	if err != nil {
+		return ChainStatus{}, err
+	}
+
+	return status, nil
+}
+
+// aggregate sends all calls with a single aggregate3 call of the multicall contract
+func (c Client) aggregate(chain uint8, multicallAbi abi.ABI, calls []viewCall) error {
+	requests := make([]multicall3Call, len(calls))
+	for i, call := range calls {
+		callData, err := call.contract.Pack(call.method, call.args...)
+		if err != nil {
+			return err
+		}
+		requests[i] = multicall3Call{Target: call.target, AllowFailure: true, CallData: callData}
+	}
+
+	input, err := multicallAbi.Pack("aggregate3", requests)
+	if err != nil {
+		return err
+	}
+
+	multicallAddress := c.chain(chain).multicallAddress
+	output, err := c.chain(chain).client.CallContract(c.context(), ethereum.CallMsg{
+		From: c.account,
+		To:   &multicallAddress,
+		Data: input,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	var results []multicall3Result
+	if err := multicallAbi.Unpack(&results, "aggregate3", output); err != nil {
+		return err
+	}
+	if len(results) != len(calls) {
+		return fmt.Errorf("multicall returned %d results for %d calls", len(results), len(calls))
+	}
+
+	for i, call := range calls {
+		if !results[i].Success {
+			return fmt.Errorf("call of %s failed", call.method)
+		}
+		out, err := call.contract.Methods[call.method].Outputs.UnpackValues(results[i].ReturnData)
+		if err != nil {
+			return fmt.Errorf("%s: %s", call.method, err)
+		}
+		if err := call.unpack(out); err != nil {
+			return fmt.Errorf("%s: %s", call.method, err)
+		}
+	}
+
+	return nil
+}
+
+// callView sends a single call without the multicall contract
+func (c Client) callView(chain uint8, call viewCall) error {
+	input, err := call.contract.Pack(call.method, call.args...)
+	if err != nil {
+		return err
+	}
+
+	output, err := c.chain(chain).client.CallContract(c.context(), ethereum.CallMsg{
+		From: c.account,
+		To:   &call.target,
+		Data: input,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %s", call.method, err)
+	}
+
+	out, err := call.contract.Methods[call.method].Outputs.UnpackValues(output)
+	if err != nil {
+		return fmt.Errorf("%s: %s", call.method, err)
+	}
+	if err := call.unpack(out); err != nil {
+		return fmt.Errorf("%s: %s", call.method, err)
+	}
+	return nil
+}
+
+func unpackBigInt(out []interface{}, value **big.Int) error {
+	if len(out) == 0 {
+		return fmt.Errorf("no return value")
+	}
+	v, ok := out[0].(*big.Int)
+	if !ok {
+		return fmt.Errorf("unexpected return value %v", out[0])
+	}
+	*value = v
+	return nil
+}
+
+func unpackHash(out []interface{}, value *common.Hash) error {
+	if len(out) == 0 {
+		return fmt.Errorf("no return value")
+	}
+	v, ok := out[0].([32]byte)
+	if !ok {
+		return fmt.Errorf("unexpected return value %v", out[0])
+	}
+	*value = v
+	return nil
+}
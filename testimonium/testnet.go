@@ -0,0 +1,151 @@
+// This file contains ready-made configurations of public test networks, so the relay can be evaluated without setting
+// up local chains.
+
+package testimonium
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TestnetChain is a public chain of a testnet profile.
+type TestnetChain struct {
+	Name            string
+	ChainId         uint64
+	NetworkId       uint64
+	Type            string // connection type (https or wss)
+	Url             string // public endpoint without scheme
+	Faucets         []string
+	EthashAddress   string // known public deployment, empty if there is none
+	EthrelayAddress string // known public deployment, empty if there is none
+}
+
+// TestnetProfile pairs a source chain (chain 0) with a verifying chain (chain 1).
+type TestnetProfile struct {
+	Name        string
+	Description string
+	Source      TestnetChain
+	Verifying   TestnetChain
+	Hints       []string
+	// block of the source chain suggested as genesis block when deploying the ETH Relay contract, 0 if there is none
+	SuggestedGenesis uint64
+}
+
+var mainnetSource = TestnetChain{
+	Name:      "Ethereum main net",
+	ChainId:   1,
+	NetworkId: 1,
+	Type:      "wss",
+	Url:       "ethereum-rpc.publicnode.com",
+}
+
+// main net block before the merge suggested as genesis block, so some confirmation blocks follow it
+const lastProofOfWorkGenesis = 15537000
+
+// headers of proof-of-stake chains are not accepted by the contract, only main net blocks before the merge can be relayed
+var proofOfWorkHint = "Only Ethash blocks can be relayed: choose a genesis block before the merge (block 15537394) " +
+	"when deploying the ETH Relay contract, e.g., 'deploy ethrelay --genesis " + strconv.Itoa(lastProofOfWorkGenesis) + "'."
+
+var testnetProfiles = map[string]TestnetProfile{
+	"mainnet-sepolia": {
+		Name:        "mainnet-sepolia",
+		Description: "relays main net blocks before the merge to Sepolia",
+		Source:      mainnetSource,
+		Verifying: TestnetChain{
+			Name:      "Sepolia",
+			ChainId:   11155111,
+			NetworkId: 11155111,
+			Type:      "https",
+			Url:       "ethereum-sepolia-rpc.publicnode.com",
+			Faucets: []string{
+				"https://sepolia-faucet.pk910.de",
+				"https://www.alchemy.com/faucets/ethereum-sepolia",
+				"https://cloud.google.com/application/web3/faucet/ethereum/sepolia",
+			},
+		},
+		Hints:            []string{proofOfWorkHint},
+		SuggestedGenesis: lastProofOfWorkGenesis,
+	},
+	"mainnet-holesky": {
+		Name:        "mainnet-holesky",
+		Description: "relays main net blocks before the merge to Holesky",
+		Source:      mainnetSource,
+		Verifying: TestnetChain{
+			Name:      "Holesky",
+			ChainId:   17000,
+			NetworkId: 17000,
+			Type:      "https",
+			Url:       "ethereum-holesky-rpc.publicnode.com",
+			Faucets: []string{
+				"https://holesky-faucet.pk910.de",
+				"https://cloud.google.com/application/web3/faucet/ethereum/holesky",
+			},
+		},
+		Hints:            []string{proofOfWorkHint},
+		SuggestedGenesis: lastProofOfWorkGenesis,
+	},
+	"mainnet-goerli": {
+		Name:        "mainnet-goerli",
+		Description: "relays main net blocks before the merge to Görli (deprecated)",
+		Source:      mainnetSource,
+		Verifying: TestnetChain{
+			Name:      "Görli",
+			ChainId:   5,
+			NetworkId: 5,
+			Type:      "https",
+			Url:       "rpc.ankr.com/eth_goerli",
+			Faucets:   []string{"https://goerlifaucet.com"},
+		},
+		Hints: []string{
+			proofOfWorkHint,
+			"Görli is deprecated, most faucets and public endpoints have been shut down. Prefer mainnet-sepolia.",
+		},
+		SuggestedGenesis: lastProofOfWorkGenesis,
+	},
+}
+
+// TestnetProfileByName returns the testnet profile with the name.
+func TestnetProfileByName(name string) (TestnetProfile, error) {
+	profile, exists := testnetProfiles[strings.ToLower(name)]
+	if !exists {
+		return TestnetProfile{}, fmt.Errorf("unknown testnet '%s' (%s)", name, strings.Join(TestnetProfileNames(), ", "))
+	}
+	return profile, nil
+}
+
+// TestnetProfileNames returns the names of all testnet profiles.
+func TestnetProfileNames() []string {
+	var names []string
+	for name := range testnetProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ChainsConfig returns the chains config of the profile, including the known contract deployments.
+func (profile TestnetProfile) ChainsConfig() map[uint8]interface{} {
+	source := profile.Source.chainConfig()
+	source["role"] = ROLE_SOURCE.String()
+	verifying := profile.Verifying.chainConfig()
+	verifying["role"] = ROLE_DESTINATION.String()
+	return map[uint8]interface{}{
+		0: source,
+		1: verifying,
+	}
+}
+
+func (chain TestnetChain) chainConfig() map[string]interface{} {
+	chainConfig := CreateChainConfig(chain.Type, chain.Url, 0)
+	chainConfig["chainid"] = chain.ChainId
+	chainConfig["networkid"] = chain.NetworkId
+	if chain.EthashAddress != "" {
+		chainConfig["ethashaddress"] = chain.EthashAddress
+	}
+	if chain.EthrelayAddress != "" {
+		chainConfig["ethrelayaddress"] = chain.EthrelayAddress
+	}
+	return chainConfig
+}
@@ -0,0 +1,312 @@
+// This file contains the tracing of submissions, proof generations and verifications. Spans are recorded with the
+// OpenTelemetry SDK and exported to a collector with OTLP/HTTP, so operators can see where the time of a cross-chain
+// workflow is spent. JSON-RPC requests sent over HTTP become child spans of the operation they belong to.
+
+package testimonium
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TRACE_EXPORT_INTERVAL = 2 * time.Second
+	TRACE_EXPORT_TIMEOUT  = 10 * time.Second
+	TRACE_MAX_QUEUED      = 4096 // finished spans exceeding this number are dropped until the next export
+)
+
+const (
+	traceServiceName = "go-ethrelay"
+	traceScopeName   = "github.com/pantos-io/go-ethrelay/testimonium"
+	traceTracesPath  = "/v1/traces"
+)
+
+// Tracer records spans of an OpenTelemetry tracer provider. The parent of a span is carried explicitly by the context
+// it is started with, RPC requests become children of the span of their request context. Concurrent operations
+// therefore never get each other's spans as parents. A nil tracer records nothing.
+type Tracer struct {
+	tracer   trace.Tracer
+	provider *sdktrace.TracerProvider // the provider created by NewTracer, nil if the application owns it
+}
+
+// Span is a timed operation of a trace. A nil span records nothing.
+type Span struct {
+	span trace.Span
+}
+
+// NewTracer creates a tracer exporting to the OTLP/HTTP endpoint (e.g., http://localhost:4318, the path /v1/traces
+// is appended unless the endpoint already ends with it). The tracer has to be shut down to export the last spans.
+func NewTracer(endpoint string, service string) (*Tracer, error) {
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		return nil, fmt.Errorf("illegal OTLP endpoint '%s' (expected http:// or https:// url)", endpoint)
+	}
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, traceTracesPath) {
+		url += traceTracesPath
+	}
+	if service == "" {
+		service = traceServiceName
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(url),
+		otlptracehttp.WithTimeout(TRACE_EXPORT_TIMEOUT))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter,
+			sdktrace.WithBatchTimeout(TRACE_EXPORT_INTERVAL),
+			sdktrace.WithExportTimeout(TRACE_EXPORT_TIMEOUT),
+			sdktrace.WithMaxQueueSize(TRACE_MAX_QUEUED)),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))))
+	tracer := TracerFromProvider(provider)
+	tracer.provider = provider
+	return tracer, nil
+}
+
+// TracerFromProvider creates a tracer recording the spans with the tracer provider of the application, e.g., the
+// global provider of otel.GetTracerProvider(). Shutting the tracer down does not shut the provider down.
+func TracerFromProvider(provider trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: provider.Tracer(traceScopeName)}
+}
+
+// WithTracer records spans of submissions, proof generations and verifications with the tracer.
+func WithTracer(tracer *Tracer) ClientOption {
+	return func(client *Client) error {
+		client.tracer = tracer
+		return nil
+	}
+}
+
+// WithTraceContext makes the operations of the client children of the span of the context (see ContextWithSpan),
+// e.g., of the span of the command or request using the client. Only the span is taken from the context.
+func WithTraceContext(ctx context.Context) ClientOption {
+	return func(client *Client) error {
+		client.span = SpanFromContext(ctx)
+		return nil
+	}
+}
+
+// ContextWithSpan returns a copy of the context carrying the span, operations started with it become its children.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if span == nil {
+		return ctx
+	}
+	return trace.ContextWithSpan(ctx, span.span)
+}
+
+// SpanFromContext returns the span carried by the context, nil if there is none.
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span := trace.SpanFromContext(ctx)
+	if !span.SpanContext().IsValid() {
+		return nil
+	}
+	return &Span{span: span}
+}
+
+// Start starts an operation, which becomes a child of the span of the context (a new trace if the context carries
+// none). It returns a copy of the context carrying the new span, which is passed to the child operations.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	return t.start(ctx, name)
+}
+
+// StartRoot starts an operation in a new trace regardless of the span of the context, e.g., for each header of the
+// live mode, so the traces of a daemon remain bounded.
+func (t *Tracer) StartRoot(ctx context.Context, name string) (context.Context, *Span) {
+	return t.start(ctx, name, trace.WithNewRoot())
+}
+
+func (t *Tracer) start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := t.tracer.Start(ctx, name, options...)
+	return ctx, &Span{span: span}
+}
+
+// Shutdown exports the remaining finished spans and stops the export of a tracer created with NewTracer.
+func (t *Tracer) Shutdown() error {
+	if t == nil || t.provider == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), TRACE_EXPORT_TIMEOUT)
+	defer cancel()
+	if err := t.provider.ForceFlush(ctx); err != nil {
+		t.provider.Shutdown(ctx)
+		return fmt.Errorf("failed to export spans: %s", err)
+	}
+	return t.provider.Shutdown(ctx)
+}
+
+// SetAttribute adds an attribute (string, bool, integer or float) to the span.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.span.SetAttributes(traceAttribute(key, value))
+}
+
+// End ends the span, a non-nil error marks it as failed. It returns the error, so it can be used in return statements.
+func (s *Span) End(err error) error {
+	if s == nil {
+		return err
+	}
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	} else {
+		s.span.SetStatus(codes.Ok, "")
+	}
+	s.span.End()
+	return err
+}
+
+func traceAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case uint8:
+		return attribute.Int(key, int(v))
+	case uint64:
+		return attribute.Int64(key, int64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
+
+// tracingTransport records each JSON-RPC request as a client span of the span of its request context and passes the
+// W3C trace context on, so traced nodes (or proxies) can join the trace
+type tracingTransport struct {
+	tracer    *Tracer
+	transport http.RoundTripper
+}
+
+// rpcTransport returns a transport tracing the requests sent with the transport
+func (t *Tracer) rpcTransport(transport http.RoundTripper) http.RoundTripper {
+	return &tracingTransport{tracer: t, transport: transport}
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	method := rpcMethods(reqBody)
+	ctx, span := t.tracer.tracer.Start(req.Context(), "rpc "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "jsonrpc"),
+			attribute.String("rpc.method", method),
+			attribute.String("server.address", req.URL.Host)))
+	traced := &Span{span: span}
+
+	// the request must not be modified by a transport
+	req = req.Clone(ctx)
+	req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		traced.End(err)
+		return nil, err
+	}
+	traced.SetAttribute("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode/100 != 2 {
+		traced.End(fmt.Errorf("%s", resp.Status))
+	} else {
+		traced.End(nil)
+	}
+	return resp, nil
+}
+
+// rpcMethods returns the method of a JSON-RPC request or the methods of a batch request
+func rpcMethods(body []byte) string {
+	var request struct {
+		Method string `json:"method"`
+	}
+	if json.Unmarshal(body, &request) == nil {
+		return request.Method
+	}
+
+	var batch []struct {
+		Method string `json:"method"`
+	}
+	if json.Unmarshal(body, &batch) != nil {
+		return "unknown"
+	}
+	var methods []string
+	for _, r := range batch {
+		methods = append(methods, r.Method)
+	}
+	return "batch(" + strings.Join(methods, ",") + ")"
+}
+
+// startSpan starts an operation of the client's tracer as child of the client's current operation. It returns a copy
+// of the client whose operations and RPC requests become children of the new span, which is nil if the client is not
+// traced.
+func (c Client) startSpan(name string, chain uint8) (Client, *Span) {
+	_, span := c.tracer.Start(c.context(), name)
+	if span != nil {
+		c.span = span
+	}
+	span.SetAttribute("ethrelay.chain", chain)
+	return c, span
+}
+
+// context returns the context of the client's RPC requests, it is derived from the context set with WithContext and
+// carries the span of the client's current operation
+func (c Client) context() context.Context {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return ContextWithSpan(ctx, c.span)
+}
+
+// callOpts returns the options of contract calls, so they are traced as part of the client's current operation
+func (c Client) callOpts() *bind.CallOpts {
+	return &bind.CallOpts{Context: c.context()}
+}
+
+// setTxResult adds the transaction of the result to the span, the result may be nil
+func (s *Span) setTxResult(result *TxResult) {
+	if result == nil {
+		return
+	}
+	s.SetAttribute("ethrelay.tx_hash", result.TxHash.Hex())
+	if result.BlockNumber != 0 {
+		s.SetAttribute("ethrelay.gas_used", result.GasUsed)
+	}
+}
@@ -0,0 +1,173 @@
+package testimonium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestTracer returns a tracer recording the spans in memory
+func newTestTracer() (*Tracer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	return TracerFromProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))), recorder
+}
+
+func spanContext(span *Span) trace.SpanContext {
+	return span.span.SpanContext()
+}
+
+func parentContext(span *Span) trace.SpanContext {
+	return span.span.(sdktrace.ReadOnlySpan).Parent()
+}
+
+func TestTracerParentsOfConcurrentSpans(t *testing.T) {
+	tracer, _ := newTestTracer()
+	rootCtx, root := tracer.Start(context.Background(), "root")
+
+	const workers = 16
+	parents := make([]*Span, workers)
+	children := make([]*Span, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, parent := tracer.Start(rootCtx, "worker")
+			_, child := tracer.Start(ctx, "operation")
+			parents[i], children[i] = parent, child
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < workers; i++ {
+		if parentContext(parents[i]).SpanID() != spanContext(root).SpanID() ||
+			spanContext(parents[i]).TraceID() != spanContext(root).TraceID() {
+			t.Errorf("worker %d: span is not a child of the root span", i)
+		}
+		if parentContext(children[i]).SpanID() != spanContext(parents[i]).SpanID() ||
+			spanContext(children[i]).TraceID() != spanContext(root).TraceID() {
+			t.Errorf("worker %d: operation is not a child of its worker span", i)
+		}
+	}
+}
+
+func TestTracerStartRoot(t *testing.T) {
+	tracer, _ := newTestTracer()
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	rootCtx, root := tracer.StartRoot(ctx, "root")
+	if parentContext(root).IsValid() || spanContext(root).TraceID() == spanContext(parent).TraceID() {
+		t.Errorf("root span belongs to the trace of the parent")
+	}
+	if !spanContext(SpanFromContext(rootCtx)).Equal(spanContext(root)) {
+		t.Errorf("context does not carry the root span")
+	}
+}
+
+func TestClientStartSpan(t *testing.T) {
+	tracer, _ := newTestTracer()
+	ctx, command := tracer.Start(context.Background(), "command")
+	client := Client{tracer: tracer, span: SpanFromContext(ctx)}
+
+	traced, span := client.startSpan("operation", 1)
+	if parentContext(span).SpanID() != spanContext(command).SpanID() {
+		t.Errorf("operation is not a child of the command")
+	}
+	if !spanContext(SpanFromContext(traced.context())).Equal(spanContext(span)) ||
+		!spanContext(SpanFromContext(client.context())).Equal(spanContext(command)) {
+		t.Errorf("contexts of the clients carry the wrong spans")
+	}
+}
+
+func TestSpanEnd(t *testing.T) {
+	tracer, recorder := newTestTracer()
+	_, span := tracer.Start(context.Background(), "operation")
+	span.SetAttribute("ethrelay.chain", uint8(1))
+	failure := errors.New("failure")
+	if err := span.End(failure); err != failure {
+		t.Errorf("End returned %v instead of the error", err)
+	}
+
+	ended := recorder.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("%d spans ended", len(ended))
+	}
+	if status := ended[0].Status(); status.Code != codes.Error || status.Description != "failure" {
+		t.Errorf("status %v", status)
+	}
+	if attributes := ended[0].Attributes(); len(attributes) != 1 || attributes[0].Value.AsInt64() != 1 {
+		t.Errorf("attributes %v", attributes)
+	}
+}
+
+func TestRPCTransport(t *testing.T) {
+	var traceParent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParent = r.Header.Get("traceparent")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer server.Close()
+
+	tracer, recorder := newTestTracer()
+	ctx, operation := tracer.Start(context.Background(), "operation")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL,
+		strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId"}`))
+	resp, err := (&http.Client{Transport: tracer.rpcTransport(http.DefaultTransport)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	ended := recorder.Ended()
+	if len(ended) != 1 || ended[0].Name() != "rpc eth_chainId" {
+		t.Fatalf("ended spans %v", ended)
+	}
+	rpc := ended[0]
+	if rpc.Parent().SpanID() != spanContext(operation).SpanID() || rpc.SpanKind() != trace.SpanKindClient {
+		t.Errorf("request is not a client span of the operation")
+	}
+	if !strings.Contains(traceParent, rpc.SpanContext().SpanID().String()) {
+		t.Errorf("trace context %q does not carry the span of the request", traceParent)
+	}
+}
+
+func TestTracerExport(t *testing.T) {
+	exported := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case exported <- r.URL.Path:
+		default:
+		}
+	}))
+	defer server.Close()
+
+	tracer, err := NewTracer(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := tracer.Start(context.Background(), "operation")
+	span.End(nil)
+	if err := tracer.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case path := <-exported:
+		if path != "/v1/traces" {
+			t.Errorf("spans exported to %s", path)
+		}
+	default:
+		t.Error("no spans exported")
+	}
+
+	if _, err := NewTracer("localhost:4318", ""); err == nil {
+		t.Error("endpoint without scheme accepted")
+	}
+}
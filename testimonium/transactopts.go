@@ -0,0 +1,36 @@
+// This file contains the hook to adjust the options of each transaction before it is sent, so integrators can use
+// advanced setups (e.g., gas tips, access lists, an external nonce source, private mempools or bundles) without
+// changing how transactions are prepared.
+
+package testimonium
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// TransactOptsModifier adjusts the options of a transaction to the specified chain after the nonce and gas price have
+// been set and before the transaction is signed and sent, e.g., to raise the gas price, set a gas limit, take the
+// nonce from an external source or sign with a remote signer. A returned error aborts the transaction.
+type TransactOptsModifier func(chain uint8, opts *bind.TransactOpts) error
+
+// WithTransactOptsModifier adds a modifier applied to the options of every transaction sent by the account, including
+// contract deployments. Modifiers are applied in the order they were added. They also run for calls routed through a
+// relayer, but only the value of such calls reaches the relayer.
+func WithTransactOptsModifier(modifier TransactOptsModifier) ClientOption {
+	return func(client *Client) error {
+		client.transactOptsModifiers = append(client.transactOptsModifiers, modifier)
+		return nil
+	}
+}
+
+// modifyTransactOpts applies the modifiers of the chain to the options
+func modifyTransactOpts(chain *Chain, opts *bind.TransactOpts) error {
+	for _, modifier := range chain.transactOptsModifiers {
+		if err := modifier(chain.id, opts); err != nil {
+			return fmt.Errorf("transaction to chain %d aborted by modifier: %w", chain.id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+// This file contains the fork tree of the headers stored in the Testimonium contract: all submitted headers with their
+// parents, submitters and disputes, so operators can see the branch structure the contract maintains.
+
+package testimonium
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TreeNode is a submitted header of the fork tree. Headers removed by a dispute (including the descendants of
+// disputed headers) are kept in the tree, but are no longer stored.
+type TreeNode struct {
+	BlockHash    common.Hash     `json:"blockHash"`
+	ParentHash   common.Hash     `json:"parentHash"`
+	BlockNumber  uint64          `json:"blockNumber"`
+	Submitter    *common.Address `json:"submitter,omitempty"` // nil for the genesis block
+	SubmitTxHash *common.Hash    `json:"submitTxHash,omitempty"`
+	Submitted    *time.Time      `json:"submitted,omitempty"` // time of the block containing the submission
+	Stored       bool            `json:"stored"`
+	Disputed     bool            `json:"disputed"` // a dispute of the header was sent, whether it succeeded or not
+	// Confirmed is set if the lock period of the header elapsed, so it can no longer be disputed. It is nil if the lock
+	// period or the time of the submission is unknown.
+	Confirmed    *bool         `json:"confirmed,omitempty"`
+	LongestChain bool          `json:"longestChain"`
+	Children     []common.Hash `json:"children,omitempty"` // the child on the longest chain first
+}
+
+// BranchTree is the fork tree of the headers submitted to the Testimonium contract on a chain.
+type BranchTree struct {
+	Chain      uint8          `json:"chain"`
+	Contract   common.Address `json:"contract"`
+	Genesis    common.Hash    `json:"genesis"`
+	Endpoint   common.Hash    `json:"endpoint"`             // endpoint of the longest chain
+	LockPeriod time.Duration  `json:"lockPeriod,omitempty"` // 0 if the contract does not expose it
+	Nodes      []*TreeNode    `json:"nodes"`                // ordered by block number and hash
+
+	nodes map[common.Hash]*TreeNode
+}
+
+// Node returns the node of the header with the specified hash.
+func (tree *BranchTree) Node(blockHash common.Hash) (*TreeNode, bool) {
+	node, exists := tree.nodes[blockHash]
+	return node, exists
+}
+
+// Roots returns the nodes whose parent is not part of the tree, usually only the genesis block. Submissions of headers
+// whose parent is unknown show up as further roots.
+func (tree *BranchTree) Roots() []*TreeNode {
+	var roots []*TreeNode
+	for _, node := range tree.Nodes {
+		if _, exists := tree.nodes[node.ParentHash]; !exists {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+// Forks returns the number of headers with more than one child.
+func (tree *BranchTree) Forks() int {
+	forks := 0
+	for _, node := range tree.Nodes {
+		if len(node.Children) > 1 {
+			forks++
+		}
+	}
+	return forks
+}
+
+// BranchTree updates the event index of the chain in the data directory and builds the fork tree of all headers
+// submitted to the Testimonium contract from it. Whether a header is still stored is read from the contract, headers
+// are confirmed once the lock period of the contract elapsed since their submission (measured with the time of the
+// latest block of the chain).
+func (c Client) BranchTree(dataDir string, chain uint8) (*BranchTree, error) {
+	index, _, err := c.UpdateEventIndex(dataDir, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	contract := c.chain(chain).testimoniumContract
+	genesis, err := contract.GetGenesisBlockHash(nil)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := contract.GetLongestChainEndpoint(nil)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := c.chain(chain).client.HeaderByNumber(c.context(), nil)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Unix(int64(latest.Time), 0)
+
+	tree := &BranchTree{
+		Chain:    chain,
+		Contract: index.Contract,
+		Genesis:  genesis,
+		Endpoint: endpoint,
+		Nodes:    []*TreeNode{},
+		nodes:    make(map[common.Hash]*TreeNode),
+	}
+	// contracts without the admin interface do not expose the lock period, confirmations are unknown then
+	if lockPeriod, err := c.LockPeriod(chain); err == nil && lockPeriod.Sign() > 0 {
+		tree.LockPeriod = time.Duration(lockPeriod.Int64()) * time.Second
+	}
+
+	storedGenesis, err := contract.GetHeader(nil, genesis)
+	if err != nil {
+		return nil, err
+	}
+	confirmed := true
+	tree.add(&TreeNode{
+		BlockHash:   genesis,
+		BlockNumber: storedGenesis.BlockNumber.Uint64(),
+		Stored:      true,
+		Confirmed:   &confirmed,
+	})
+
+	for _, record := range index.SortedRecords() {
+		header, err := decodeHeaderFromRLP(record.RlpHeader)
+		if err != nil {
+			return nil, fmt.Errorf("illegal header %s submitted in transaction %s: %s", record.BlockHash.Hex(), record.TxHash.Hex(), err)
+		}
+		stored, err := contract.IsHeaderStored(nil, record.BlockHash)
+		if err != nil {
+			return nil, err
+		}
+		submitter, txHash := record.Submitter, record.TxHash
+		node := &TreeNode{
+			BlockHash:    record.BlockHash,
+			ParentHash:   header.ParentHash,
+			BlockNumber:  header.Number.Uint64(),
+			Submitter:    &submitter,
+			SubmitTxHash: &txHash,
+			Stored:       stored,
+		}
+		submitted := time.Unix(int64(record.Timestamp), 0)
+		if record.Timestamp == 0 {
+			if submitted, err = c.blockTime(record.SubmitBlockNumber, chain); err != nil {
+				return nil, err
+			}
+		}
+		node.Submitted = &submitted
+		if tree.LockPeriod > 0 {
+			confirmed := stored && !submitted.Add(tree.LockPeriod).After(now)
+			node.Confirmed = &confirmed
+		}
+		tree.add(node)
+	}
+
+	for _, dispute := range index.Disputes {
+		if node, exists := tree.nodes[dispute.BlockHash]; exists {
+			node.Disputed = true
+		}
+	}
+	for blockHash := common.Hash(endpoint); ; {
+		node, exists := tree.nodes[blockHash]
+		if !exists || node.LongestChain {
+			break
+		}
+		node.LongestChain = true
+		blockHash = node.ParentHash
+	}
+
+	sort.Slice(tree.Nodes, func(i, j int) bool {
+		if tree.Nodes[i].BlockNumber != tree.Nodes[j].BlockNumber {
+			return tree.Nodes[i].BlockNumber < tree.Nodes[j].BlockNumber
+		}
+		return tree.Nodes[i].BlockHash.Hex() < tree.Nodes[j].BlockHash.Hex()
+	})
+	for _, node := range tree.Nodes {
+		if parent, exists := tree.nodes[node.ParentHash]; exists && node.BlockHash != genesis {
+			parent.Children = append(parent.Children, node.BlockHash)
+		}
+	}
+	for _, node := range tree.Nodes {
+		sort.SliceStable(node.Children, func(i, j int) bool {
+			return tree.nodes[node.Children[i]].LongestChain && !tree.nodes[node.Children[j]].LongestChain
+		})
+	}
+	return tree, nil
+}
+
+func (tree *BranchTree) add(node *TreeNode) {
+	tree.nodes[node.BlockHash] = node
+	tree.Nodes = append(tree.Nodes, node)
+}
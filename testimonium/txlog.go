@@ -0,0 +1,443 @@
+// This file contains the write-ahead log of the transactions sent by the account. Every signed transaction is
+// persisted before it is broadcast, so a transaction lost in a crash between signing and mining can be broadcast again
+// on the next start. Otherwise, the nonce of the lost transaction would block all later transactions of the account
+// (a "nonce gap") until a transaction with the same nonce is sent.
+
+package testimonium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+)
+
+// TxLogState is the state of a transaction in the transaction log.
+type TxLogState string
+
+const (
+	// the transaction is signed, but may not have been broadcast
+	TXLOG_SIGNED TxLogState = "signed"
+	// the transaction was accepted by the node
+	TXLOG_SENT TxLogState = "sent"
+	// the transaction is included in a block
+	TXLOG_MINED TxLogState = "mined"
+	// another transaction with the same nonce is included in a block
+	TXLOG_REPLACED TxLogState = "replaced"
+	// the node rejected the transaction, its nonce was not used
+	TXLOG_FAILED TxLogState = "failed"
+)
+
+// resolved reports whether the transaction needs no further attention
+func (state TxLogState) resolved() bool {
+	return state == TXLOG_MINED || state == TXLOG_REPLACED || state == TXLOG_FAILED
+}
+
+// TxLogEntry is a transaction of the transaction log.
+type TxLogEntry struct {
+	Hash    common.Hash     `json:"hash"`
+	From    common.Address  `json:"from"`
+	Nonce   uint64          `json:"nonce"`
+	To      *common.Address `json:"to,omitempty"` // nil for contract creations
+	Intent  string          `json:"intent"`       // the called contract function, e.g., "submitBlock"
+	Private bool            `json:"private,omitempty"`
+	RawTx   hexutil.Bytes   `json:"rawTx"`
+	State   TxLogState      `json:"state"`
+	Error   string          `json:"error,omitempty"`
+	Time    time.Time       `json:"time"`
+}
+
+// TxLog is the write-ahead log of the transactions sent to a chain. It is stored as JSON lines in the data directory,
+// every state change of a transaction is appended as new line.
+type TxLog struct {
+	mutex   sync.Mutex
+	path    string
+	entries map[common.Hash]*TxLogEntry
+}
+
+// TxLogPath returns the path of the transaction log of the chain in the data directory.
+func TxLogPath(dataDir string, chain uint8) string {
+	return filepath.Join(dataDir, fmt.Sprintf("txlog-%d.jsonl", chain))
+}
+
+// OpenTxLog reads the transaction log of the chain from the data directory. Resolved transactions are dropped from
+// the file, so it only grows with the transactions sent since the last start.
+func OpenTxLog(dataDir string, chain uint8) (*TxLog, error) {
+	txLog := &TxLog{path: TxLogPath(dataDir, chain), entries: make(map[common.Hash]*TxLogEntry)}
+
+	data, err := ioutil.ReadFile(txLog.path)
+	if os.IsNotExist(err) {
+		return txLog, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		var entry TxLogEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			if i == len(lines)-1 {
+				// the last line is incomplete if the process crashed while writing it, the transaction was not sent
+				break
+			}
+			return nil, fmt.Errorf("corrupt transaction log %s (line %d): %s", txLog.path, i+1, err)
+		}
+		txLog.entries[entry.Hash] = &entry
+	}
+
+	for hash, entry := range txLog.entries {
+		if entry.State.resolved() {
+			delete(txLog.entries, hash)
+		}
+	}
+	return txLog, txLog.compact()
+}
+
+// compact rewrites the file with the current state of the transactions
+func (txLog *TxLog) compact() error {
+	var data []byte
+	for _, entry := range txLog.Entries() {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	temp := txLog.path + ".tmp"
+	if err := ioutil.WriteFile(temp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, txLog.path)
+}
+
+// append records the state of the transaction, the file is synced before returning
+func (txLog *TxLog) append(entry TxLogEntry) error {
+	txLog.mutex.Lock()
+	defer txLog.mutex.Unlock()
+
+	entry.Time = time.Now().UTC()
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(txLog.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(txLog.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	txLog.entries[entry.Hash] = &entry
+	return nil
+}
+
+// setState records the new state of a logged transaction
+func (txLog *TxLog) setState(hash common.Hash, state TxLogState, cause error) error {
+	txLog.mutex.Lock()
+	entry, exists := txLog.entries[hash]
+	txLog.mutex.Unlock()
+	if !exists {
+		return fmt.Errorf("transaction %s is not logged", hash.Hex())
+	}
+	updated := *entry
+	updated.State = state
+	updated.Error = ""
+	if cause != nil {
+		updated.Error = cause.Error()
+	}
+	return txLog.append(updated)
+}
+
+// Entries returns the transactions of the log ordered by nonce.
+func (txLog *TxLog) Entries() []TxLogEntry {
+	txLog.mutex.Lock()
+	defer txLog.mutex.Unlock()
+	entries := make([]TxLogEntry, 0, len(txLog.entries))
+	for _, entry := range txLog.entries {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Nonce != entries[j].Nonce {
+			return entries[i].Nonce < entries[j].Nonce
+		}
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries
+}
+
+// Pending returns the transactions that are neither mined nor replaced nor rejected, ordered by nonce.
+func (txLog *TxLog) Pending() []TxLogEntry {
+	var pending []TxLogEntry
+	for _, entry := range txLog.Entries() {
+		if !entry.State.resolved() {
+			pending = append(pending, entry)
+		}
+	}
+	return pending
+}
+
+// WithTxLog records the transactions sent by the account in the transaction logs of the data directory. Transactions
+// left unresolved by a previous run are reconciled with the chains when the client is created.
+func WithTxLog(dataDir string) ClientOption {
+	return func(client *Client) error {
+		client.txLogDir = dataDir
+		return nil
+	}
+}
+
+// WithoutTxLogReconciliation opens the transaction logs without reconciling them when the chains are connected, so
+// lost transactions are not broadcast again, e.g., before the log is repaired with RepairAccount.
+func WithoutTxLogReconciliation() ClientOption {
+	return func(client *Client) error {
+		client.noTxLogReconciliation = true
+		return nil
+	}
+}
+
+// logBroadcast persists the signed transaction before it is broadcast with send. Without a transaction log, the
+// transaction is just sent. If the transaction cannot be persisted, it is not sent.
+func (chain *Chain) logBroadcast(rawTx []byte, tx *types.Transaction, hash common.Hash, from common.Address, private bool, send func() error) error {
+	if chain.txLog == nil {
+		return send()
+	}
+	entry := TxLogEntry{
+		Hash:    hash,
+		From:    from,
+		Nonce:   tx.Nonce(),
+		To:      tx.To(),
+		Intent:  chain.txIntent(tx),
+		Private: private,
+		RawTx:   rawTx,
+		State:   TXLOG_SIGNED,
+	}
+	if err := chain.txLog.append(entry); err != nil {
+		return fmt.Errorf("transaction not sent, it cannot be written to the transaction log: %s", err)
+	}
+
+	sendErr := send()
+	state := TXLOG_SENT
+	if sendErr != nil {
+		state = TXLOG_FAILED
+	}
+	// the outcome is determined by the reconciliation of the next start if it cannot be recorded
+	chain.txLog.setState(hash, state, sendErr)
+	return sendErr
+}
+
+// logBroadcastTx persists and sends a transaction signed by the bindings (legacy transaction)
+func (chain *Chain) logBroadcastTx(ctx context.Context, client *ethclient.Client, tx *types.Transaction) error {
+	if chain.txLog == nil {
+		return client.SendTransaction(ctx, tx)
+	}
+	rawTx, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return err
+	}
+	return chain.logBroadcast(rawTx, tx, tx.Hash(), txSender(tx), false, func() error {
+		return client.SendTransaction(ctx, tx)
+	})
+}
+
+// txLogBackend logs the transactions of the contract bindings that are not sent through the relayBackend, e.g.,
+// contract creations
+type txLogBackend struct {
+	*ethclient.Client
+	chain *Chain
+}
+
+func (b txLogBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	return b.chain.logBroadcastTx(ctx, b.Client, tx)
+}
+
+// txSender returns the signer of the transaction, the zero address if it cannot be recovered
+func txSender(tx *types.Transaction) common.Address {
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	from, _ := types.Sender(signer, tx)
+	return from
+}
+
+// txIntent describes the purpose of the transaction by the called contract function
+func (chain *Chain) txIntent(tx *types.Transaction) string {
+	if tx.To() == nil {
+		return "contract creation"
+	}
+	if len(tx.Data()) < 4 {
+		return "transfer"
+	}
+	var contractAbi abi.ABI
+	var err error
+	switch *tx.To() {
+	case chain.testimoniumContractAddress:
+		contractAbi, err = chain.testimoniumAbi()
+	case chain.ethashContractAddress:
+		contractAbi, err = abi.JSON(strings.NewReader(ethash.EthashABI))
+	case chain.create2Deployer:
+		return "create2 deployment"
+	}
+	if err == nil {
+		if method := methodName(contractAbi, tx.Data()); method != "" {
+			return method
+		}
+	}
+	return fmt.Sprintf("call %s", hexutil.Encode(tx.Data()[:4]))
+}
+
+// TxLogEntries returns the transactions in the transaction log of the chain ordered by nonce: the unresolved
+// transactions of previous runs (after their reconciliation) and the transactions sent since the client was created.
+func (c Client) TxLogEntries(chain uint8) ([]TxLogEntry, error) {
+	if err := c.checkChain(chain); err != nil {
+		return nil, err
+	}
+	if c.chain(chain).txLog == nil {
+		return nil, fmt.Errorf("no transaction log for chain %d", chain)
+	}
+	return c.chain(chain).txLog.Entries(), nil
+}
+
+// TxLogReconciliation is the outcome of reconciling a logged transaction with the chain.
+type TxLogReconciliation struct {
+	TxLogEntry
+	PreviousState TxLogState `json:"previousState"`
+	Rebroadcast   bool       `json:"rebroadcast"`
+}
+
+// ReconcileTxLog compares the unresolved transactions of the chain's transaction log with the chain. Transactions that
+// are mined or whose nonce was used by another transaction are resolved. Transactions unknown to the node whose nonce
+// is still unused are broadcast again (private transactions are not, they are never sent to the public mempool).
+func (c Client) ReconcileTxLog(chain uint8) ([]TxLogReconciliation, error) {
+	if err := c.checkChain(chain); err != nil {
+		return nil, err
+	}
+	txLog := c.chain(chain).txLog
+	if txLog == nil {
+		return nil, fmt.Errorf("no transaction log for chain %d", chain)
+	}
+
+	var reconciliations []TxLogReconciliation
+	for _, entry := range txLog.Pending() {
+		reconciliation, err := c.reconcileTx(entry, chain, true)
+		if err != nil {
+			return reconciliations, fmt.Errorf("transaction %s: %s", entry.Hash.Hex(), err)
+		}
+		reconciliations = append(reconciliations, reconciliation)
+	}
+	return reconciliations, nil
+}
+
+// reconcileTx resolves the logged transaction according to the chain. A transaction unknown to the node whose nonce is
+// still unused is broadcast again if rebroadcast is set, otherwise it is cleared (resolved as failed).
+func (c Client) reconcileTx(entry TxLogEntry, chain uint8, rebroadcast bool) (TxLogReconciliation, error) {
+	ctx := c.context()
+	source := c.chain(chain)
+	reconciliation := TxLogReconciliation{TxLogEntry: entry, PreviousState: entry.State}
+	resolve := func(state TxLogState, cause error) (TxLogReconciliation, error) {
+		reconciliation.State = state
+		if cause != nil {
+			reconciliation.Error = cause.Error()
+		}
+		return reconciliation, source.txLog.setState(entry.Hash, state, cause)
+	}
+
+	receipt, err := source.client.TransactionReceipt(ctx, entry.Hash)
+	if err == nil && receipt != nil {
+		return resolve(TXLOG_MINED, nil)
+	}
+	if err != nil && !errors.Is(err, ethereum.NotFound) {
+		return reconciliation, err
+	}
+
+	confirmedNonce, err := source.client.NonceAt(ctx, entry.From, nil)
+	if err != nil {
+		return reconciliation, err
+	}
+	if entry.Nonce < confirmedNonce {
+		return resolve(TXLOG_REPLACED, nil)
+	}
+
+	if _, _, err := source.client.TransactionByHash(ctx, entry.Hash); err == nil {
+		if entry.State != TXLOG_SENT {
+			return resolve(TXLOG_SENT, nil)
+		}
+		return reconciliation, nil // still pending in the mempool
+	} else if !errors.Is(err, ethereum.NotFound) {
+		return reconciliation, err
+	}
+
+	if entry.Private {
+		// the private relay drops transactions not included within PRIVATE_TX_MAX_BLOCKS
+		return resolve(TXLOG_FAILED, fmt.Errorf("private transaction not included"))
+	}
+	if !rebroadcast {
+		return resolve(TXLOG_FAILED, ErrTxCleared)
+	}
+
+	c.progressf("Broadcasting transaction %s (%s, nonce %d) lost before it was mined\n", entry.Hash.Hex(), entry.Intent, entry.Nonce)
+	reconciliation.Rebroadcast = true
+	if err := source.rpcClient.CallContext(ctx, nil, "eth_sendRawTransaction", hexutil.Encode(entry.RawTx)); err != nil {
+		message := strings.ToLower(err.Error())
+		switch {
+		case strings.Contains(message, "already known"):
+			return resolve(TXLOG_SENT, nil)
+		case strings.Contains(message, "nonce too low"):
+			return resolve(TXLOG_REPLACED, nil)
+		default:
+			return resolve(TXLOG_FAILED, err)
+		}
+	}
+	return resolve(TXLOG_SENT, nil)
+}
+
+// openTxLog opens the transaction log of a newly connected chain transactions are sent to and reconciles it (unless
+// disabled with WithoutTxLogReconciliation)
+func (c Client) openTxLog(chain *Chain) {
+	// replayed sessions send no transactions
+	if c.txLogDir == "" || c.privateKey == nil || c.replay || chain.role == ROLE_SOURCE {
+		return
+	}
+	txLog, err := OpenTxLog(c.txLogDir, chain.id)
+	if err != nil {
+		c.problemf("Transactions sent to chain %d are not logged: %s", chain.id, err)
+		return
+	}
+	chain.txLog = txLog
+	if len(txLog.Pending()) == 0 || c.noTxLogReconciliation {
+		return
+	}
+	c.progressf("Reconciling %d unresolved transactions of chain %d ...\n", len(txLog.Pending()), chain.id)
+	if _, err := c.ReconcileTxLog(chain.id); err != nil {
+		c.problemf("Cannot reconcile the transaction log of chain %d: %s", chain.id, err)
+	}
+}
@@ -0,0 +1,101 @@
+// This file contains the verification of a transaction together with its receipt, so applications that depend on both
+// the inclusion of a transaction and its success (status, gas used) run a single verification job.
+
+package testimonium
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// TransactionMeta is the outcome of a relayed transaction. Status and CumulativeGasUsed are part of the verified receipt,
+// GasUsed is derived by the node of the source chain.
+type TransactionMeta struct {
+	Status            uint64 `json:"status"` // 1 = success
+	CumulativeGasUsed uint64 `json:"cumulativeGasUsed"`
+	GasUsed           uint64 `json:"gasUsed"`
+}
+
+// Succeeded returns whether the transaction was executed successfully.
+func (m TransactionMeta) Succeeded() bool {
+	return m.Status == types.ReceiptStatusSuccessful
+}
+
+// MetaVerificationJob tracks the verification of a transaction and its receipt. Both are verified against the same
+// block, the submitted headers are recorded in the transaction job.
+type MetaVerificationJob struct {
+	Transaction *VerificationJob `json:"transaction"`
+	Receipt     *VerificationJob `json:"receipt"`
+	Meta        *TransactionMeta `json:"meta,omitempty"` // set once both verifications are sent
+}
+
+// Verified returns whether the contract accepted both the transaction and its receipt.
+func (j *MetaVerificationJob) Verified() bool {
+	for _, job := range []*VerificationJob{j.Transaction, j.Receipt} {
+		if job == nil || job.Verification == nil || job.Verification.Verification == nil ||
+			job.Verification.Verification.ReturnCode != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyTransactionWithMeta verifies the transaction with the specified hash and its receipt on the destination chain
+// in one job, so the caller learns both whether the transaction is included and whether it succeeded. The headers up
+// to the last confirmation block are submitted first (at most maxHeaders) if maxHeaders is positive (see
+// VerifyWithBackfill), otherwise it waits up to timeout until they are relayed by others (see VerifyAfterRelay). The
+// job is passed to track whenever its stage changes, track may be nil. The options apply to both verifications.
+func (c Client) VerifyTransactionWithMeta(txHash common.Hash, noOfConfirmations uint8, sourceChain uint8,
+	destinationChain uint8, maxHeaders int, timeout time.Duration, track func(job MetaVerificationJob),
+	opts ...VerifyOption) (*MetaVerificationJob, error) {
+	ensureHeaders := c.awaitRelayedHeaders(destinationChain, timeout)
+	if maxHeaders > 0 {
+		ensureHeaders = c.backfillHeaders(sourceChain, destinationChain, maxHeaders)
+	}
+
+	metaJob := &MetaVerificationJob{}
+	var trackJob func(job VerificationJob)
+	if track != nil {
+		trackJob = func(job VerificationJob) {
+			// both jobs pass the stages together, the transaction job is passed first
+			switch job.ValueType {
+			case VALUE_TYPE_TRANSACTION:
+				metaJob.Transaction = &job
+			case VALUE_TYPE_RECEIPT:
+				metaJob.Receipt = &job
+				if job.Stage == BACKFILL_DONE {
+					metaJob.Meta, _ = transactionMeta(&job)
+				}
+				track(*metaJob)
+			}
+		}
+	}
+	jobs, err := c.runVerificationJobs(txHash, []TrieValueType{VALUE_TYPE_TRANSACTION, VALUE_TYPE_RECEIPT},
+		noOfConfirmations, sourceChain, destinationChain, trackJob, opts, ensureHeaders)
+	if len(jobs) == 0 {
+		return nil, err
+	}
+	metaJob.Transaction, metaJob.Receipt = jobs[0], jobs[1]
+	if err != nil {
+		return metaJob, err
+	}
+	metaJob.Meta, err = transactionMeta(metaJob.Receipt)
+	return metaJob, err
+}
+
+// transactionMeta decodes the outcome of the transaction from the verified receipt
+func transactionMeta(job *VerificationJob) (*TransactionMeta, error) {
+	var receipt types.Receipt
+	if err := rlp.DecodeBytes(job.value, &receipt); err != nil {
+		return nil, fmt.Errorf("illegal receipt of transaction %s: %s", job.TxHash.Hex(), err)
+	}
+	meta := &TransactionMeta{Status: receipt.Status, CumulativeGasUsed: receipt.CumulativeGasUsed}
+	if job.receipt != nil {
+		meta.GasUsed = job.receipt.GasUsed
+	}
+	return meta, nil
+}
@@ -0,0 +1,142 @@
+// This file contains the inspection of the account's transactions that are not yet included in a block.
+
+package testimonium
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+)
+
+// PoolTransaction is a transaction of the current account waiting in the transaction pool of a node.
+type PoolTransaction struct {
+	Hash     common.Hash
+	Nonce    uint64
+	GasPrice *big.Int
+	Gas      uint64
+	To       *common.Address
+	Value    *big.Int
+	Method   string // name of the called ETH Relay or Ethash method, empty if unknown
+}
+
+// AccountTxPool contains the nonces and the pooled transactions of the current account on a single chain.
+// Pending transactions are executable, queued transactions wait for a missing nonce.
+type AccountTxPool struct {
+	Nonce        uint64 // nonce of the next transaction according to the latest block
+	PendingNonce uint64 // nonce of the next transaction including the pending transactions
+	Pending      []PoolTransaction
+	Queued       []PoolTransaction
+}
+
+type rpcPoolTransaction struct {
+	Hash     common.Hash     `json:"hash"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Gas      hexutil.Uint64  `json:"gas"`
+	To       *common.Address `json:"to"`
+	Value    *hexutil.Big    `json:"value"`
+	Input    hexutil.Bytes   `json:"input"`
+}
+
+// AccountTxPool returns the pending and queued transactions of the current account on the specified chain.
+// The transactions are read with txpool_content, which has to be enabled on the node (e.g., geth's txpool API).
+func (c Client) AccountTxPool(chain uint8) (AccountTxPool, error) {
+	if err := c.checkChain(chain); err != nil {
+		return AccountTxPool{}, err
+	}
+	if !c.chain(chain).capabilities.TxPool {
+		return AccountTxPool{}, fmt.Errorf("the node of chain %d does not support the txpool API", chain)
+	}
+
+	var pool AccountTxPool
+	var err error
+
+	pool.Nonce, err = c.chain(chain).client.NonceAt(c.context(), c.account, nil)
+	if err != nil {
+		return pool, err
+	}
+	pool.PendingNonce, err = c.chain(chain).client.PendingNonceAt(c.context(), c.account)
+	if err != nil {
+		return pool, err
+	}
+
+	var content map[string]map[string]map[string]*rpcPoolTransaction
+	if err := c.chain(chain).rpcClient.CallContext(c.context(), &content, "txpool_content"); err != nil {
+		return pool, fmt.Errorf("failed to read transaction pool (is the txpool API enabled on the node?): %s", err)
+	}
+
+	pool.Pending, err = c.accountPoolTransactions(content["pending"], c.chain(chain))
+	if err != nil {
+		return pool, err
+	}
+	pool.Queued, err = c.accountPoolTransactions(content["queued"], c.chain(chain))
+	if err != nil {
+		return pool, err
+	}
+
+	return pool, nil
+}
+
+func (c Client) accountPoolTransactions(txsByAccount map[string]map[string]*rpcPoolTransaction, chain *Chain) ([]PoolTransaction, error) {
+	testimoniumAbi, err := chain.testimoniumAbi()
+	if err != nil {
+		return nil, err
+	}
+	ethashAbi, err := abi.JSON(strings.NewReader(ethash.EthashABI))
+	if err != nil {
+		return nil, err
+	}
+
+	var txs []PoolTransaction
+	for account, txsByNonce := range txsByAccount {
+		if common.HexToAddress(account) != c.account {
+			continue
+		}
+
+		for _, tx := range txsByNonce {
+			poolTx := PoolTransaction{
+				Hash:  tx.Hash,
+				Nonce: uint64(tx.Nonce),
+				Gas:   uint64(tx.Gas),
+				To:    tx.To,
+			}
+			if tx.GasPrice != nil {
+				poolTx.GasPrice = tx.GasPrice.ToInt()
+			}
+			if tx.Value != nil {
+				poolTx.Value = tx.Value.ToInt()
+			}
+
+			if tx.To != nil && len(tx.Input) >= 4 {
+				switch *tx.To {
+				case chain.testimoniumContractAddress:
+					poolTx.Method = methodName(testimoniumAbi, tx.Input)
+				case chain.ethashContractAddress:
+					poolTx.Method = methodName(ethashAbi, tx.Input)
+				}
+			}
+
+			txs = append(txs, poolTx)
+		}
+	}
+
+	sort.Slice(txs, func(i, j int) bool {
+		return txs[i].Nonce < txs[j].Nonce
+	})
+
+	return txs, nil
+}
+
+func methodName(contractAbi abi.ABI, input []byte) string {
+	method, err := contractAbi.MethodById(input[:4])
+	if err != nil {
+		return ""
+	}
+	return method.Name
+}
@@ -0,0 +1,169 @@
+// This file contains the client-side validation of block headers before they are submitted to the Testimonium contract.
+// Honest relayers should never submit a header that can later be disputed successfully, as they would lose their stake.
+
+package testimonium
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethashconsensus "github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ErrInvalidHeader is returned if a header does not pass the client-side validation.
+var ErrInvalidHeader = errors.New("invalid header")
+
+// ValidationLevel determines the checks performed on a header before it is submitted.
+type ValidationLevel int
+
+const (
+	// no checks are performed
+	VALIDATION_NONE ValidationLevel = 0
+	// parent linkage, timestamp, gas limit, gas used, extra data and difficulty lower bound
+	VALIDATION_BASIC ValidationLevel = 1
+	// basic checks, exact difficulty (Ethereum main net rules) and proof-of-work via local Ethash
+	VALIDATION_STRICT ValidationLevel = 2
+)
+
+// maximum time a header's timestamp may lie in the future
+const allowedFutureBlockTime = 15 * time.Second
+
+func (level ValidationLevel) String() string {
+	switch level {
+	case VALIDATION_NONE:
+		return "none"
+	case VALIDATION_BASIC:
+		return "basic"
+	case VALIDATION_STRICT:
+		return "strict"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(level))
+	}
+}
+
+// ParseValidationLevel parses the level names "none", "basic" and "strict".
+func ParseValidationLevel(level string) (ValidationLevel, error) {
+	switch strings.ToLower(level) {
+	case "none":
+		return VALIDATION_NONE, nil
+	case "basic":
+		return VALIDATION_BASIC, nil
+	case "strict":
+		return VALIDATION_STRICT, nil
+	default:
+		return VALIDATION_NONE, fmt.Errorf("unknown validation level '%s' (none, basic, strict)", level)
+	}
+}
+
+// WithHeaderValidation validates every header fetched from a source chain with the specified level before it is
+// submitted by SubmitHeaderWithAncestors and SubmitHeaderLive.
+func WithHeaderValidation(level ValidationLevel) ClientOption {
+	return func(client *Client) error {
+		client.validationLevel = level
+		return nil
+	}
+}
+
+// ValidateHeader fetches the parent of the header from the source chain and validates the header against it with the
+// validation level of the client. Afterwards, the header is cross-checked with the providers configured for the source
+// chain (see CrossCheckHeader).
+func (c Client) ValidateHeader(header *types.Header, sourceChain uint8) error {
+	if err := c.validateHeader(header, sourceChain, c.validationLevel); err != nil {
+		return err
+	}
+	return c.CrossCheckHeader(header, sourceChain)
+}
+
+func (c Client) validateHeader(header *types.Header, sourceChain uint8, level ValidationLevel) error {
+	if level == VALIDATION_NONE {
+		return nil
+	}
+
+	parent, err := c.HeaderByHash(header.ParentHash, sourceChain)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve parent %s for validation: %s", header.ParentHash.String(), err)
+	}
+
+	return ValidateHeaderAgainstParent(header, parent, level)
+}
+
+// ValidateHeaderAgainstParent validates the header against its parent with the specified level.
+// The returned error wraps ErrInvalidHeader.
+func ValidateHeaderAgainstParent(header *types.Header, parent *types.Header, level ValidationLevel) error {
+	if level == VALIDATION_NONE {
+		return nil
+	}
+
+	if header.ParentHash != parent.Hash() {
+		return fmt.Errorf("%w: parent hash %s does not match parent %s", ErrInvalidHeader, header.ParentHash.String(), parent.Hash().String())
+	}
+	if header.Number.Cmp(new(big.Int).Add(parent.Number, big.NewInt(1))) != 0 {
+		return fmt.Errorf("%w: block number %s does not follow parent number %s", ErrInvalidHeader, header.Number, parent.Number)
+	}
+	if header.Time <= parent.Time {
+		return fmt.Errorf("%w: timestamp %d is not greater than parent timestamp %d", ErrInvalidHeader, header.Time, parent.Time)
+	}
+	if header.Time > uint64(time.Now().Add(allowedFutureBlockTime).Unix()) {
+		return fmt.Errorf("%w: timestamp %d lies in the future", ErrInvalidHeader, header.Time)
+	}
+	if uint64(len(header.Extra)) > params.MaximumExtraDataSize {
+		return fmt.Errorf("%w: extra data too long (%d > %d)", ErrInvalidHeader, len(header.Extra), params.MaximumExtraDataSize)
+	}
+	if header.GasUsed > header.GasLimit {
+		return fmt.Errorf("%w: gas used %d exceeds gas limit %d", ErrInvalidHeader, header.GasUsed, header.GasLimit)
+	}
+
+	// the gas limit may only change by parent.GasLimit / 1024 per block
+	diff := int64(parent.GasLimit) - int64(header.GasLimit)
+	if diff < 0 {
+		diff *= -1
+	}
+	limit := parent.GasLimit / params.GasLimitBoundDivisor
+	if uint64(diff) >= limit || header.GasLimit < params.MinGasLimit {
+		return fmt.Errorf("%w: gas limit %d differs too much from parent gas limit %d", ErrInvalidHeader, header.GasLimit, parent.GasLimit)
+	}
+
+	// the difficulty can decrease by at most 99 * parent.Difficulty / 2048 per block, it can only be increased further
+	// by the difficulty bomb, so there is no upper bound independent of the chain's fork configuration
+	minDifficulty := new(big.Int).Div(parent.Difficulty, params.DifficultyBoundDivisor)
+	minDifficulty.Mul(minDifficulty, big.NewInt(99))
+	minDifficulty.Sub(parent.Difficulty, minDifficulty)
+	if minDifficulty.Cmp(params.MinimumDifficulty) < 0 {
+		minDifficulty = params.MinimumDifficulty
+	}
+	if header.Difficulty.Cmp(minDifficulty) < 0 {
+		return fmt.Errorf("%w: difficulty %s is below the minimum of %s", ErrInvalidHeader, header.Difficulty, minDifficulty)
+	}
+
+	if level < VALIDATION_STRICT {
+		return nil
+	}
+
+	expectedDifficulty := ethashconsensus.CalcDifficulty(params.MainnetChainConfig, header.Time, parent)
+	if header.Difficulty.Cmp(expectedDifficulty) != 0 {
+		return fmt.Errorf("%w: difficulty %s does not match expected difficulty %s", ErrInvalidHeader, header.Difficulty, expectedDifficulty)
+	}
+
+	if _, err := VerifyPoW(header); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidHeader, err)
+	}
+
+	return nil
+}
+
+// HeaderHashWithoutNonce returns the keccak256 hash of the header encoded without MixDigest and Nonce (also called seal
+// hash), which is mixed with the nonce by Ethash and stored by the Ethash contract for disputes.
+func HeaderHashWithoutNonce(header *types.Header) (common.Hash, error) {
+	rlpHeaderWithoutNonce, err := EncodeHeaderWithoutNonce(header)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(rlpHeaderWithoutNonce), nil
+}
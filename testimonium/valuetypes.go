@@ -0,0 +1,201 @@
+// This file contains the registry of the value types the ETH Relay contract verifies. A value type bundles building
+// its Merkle proof, sending its verification and parsing the verification event, so further proof types (e.g., storage
+// slots, logs or uncles of contract variants) are added by registering them instead of editing every verification step.
+
+package testimonium
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pantos-io/go-ethrelay/proofs"
+)
+
+// ValueVerifier implements the verification of a value type.
+type ValueVerifier struct {
+	Name    string   // e.g., "receipt", also accepted by ParseTrieValueType
+	Aliases []string // further names accepted by ParseTrieValueType, e.g., "tx"
+	// EventId is the topic of the event the contract emits with the result of a verification
+	EventId common.Hash
+	// BuildProof builds the proof of the value of a transaction on the source chain, nil if values of the type are not
+	// identified by a transaction (e.g., state)
+	BuildProof func(c Client, txHash common.Hash, chain uint8) (proofs.Proof, *types.Header, error)
+	// SendVerification sends the verification of the value to the contract
+	SendVerification func(contract *Testimonium, auth *bind.TransactOpts, feeInWei *big.Int, rlpHeader []byte,
+		noOfConfirmations uint8, rlpEncodedValue []byte, path []byte, rlpEncodedProofNodes []byte) (*types.Transaction, error)
+	// ParseResult parses the verification event, the result is read from the last byte of its data if not set
+	ParseResult func(contract *Testimonium, log types.Log) (*VerificationResult, error)
+}
+
+var (
+	valueVerifiersMutex sync.RWMutex
+	valueVerifiers      = map[TrieValueType]ValueVerifier{
+		VALUE_TYPE_TRANSACTION: {
+			Name:       "transaction",
+			Aliases:    []string{"tx"},
+			EventId:    verifyTransactionEventId,
+			BuildProof: Client.BuildTxProof,
+			SendVerification: func(contract *Testimonium, auth *bind.TransactOpts, feeInWei *big.Int, rlpHeader []byte,
+				noOfConfirmations uint8, rlpEncodedValue []byte, path []byte, rlpEncodedProofNodes []byte) (*types.Transaction, error) {
+				return contract.VerifyTransaction(auth, feeInWei, rlpHeader, noOfConfirmations, rlpEncodedValue, path, rlpEncodedProofNodes)
+			},
+			ParseResult: func(contract *Testimonium, log types.Log) (*VerificationResult, error) {
+				event, err := contract.ParseVerifyTransaction(log)
+				if err != nil {
+					return nil, err
+				}
+				return &VerificationResult{ReturnCode: event.Result}, nil
+			},
+		},
+		VALUE_TYPE_RECEIPT: {
+			Name:       "receipt",
+			EventId:    verifyReceiptEventId,
+			BuildProof: Client.BuildReceiptProof,
+			SendVerification: func(contract *Testimonium, auth *bind.TransactOpts, feeInWei *big.Int, rlpHeader []byte,
+				noOfConfirmations uint8, rlpEncodedValue []byte, path []byte, rlpEncodedProofNodes []byte) (*types.Transaction, error) {
+				return contract.VerifyReceipt(auth, feeInWei, rlpHeader, noOfConfirmations, rlpEncodedValue, path, rlpEncodedProofNodes)
+			},
+			ParseResult: func(contract *Testimonium, log types.Log) (*VerificationResult, error) {
+				event, err := contract.ParseVerifyReceipt(log)
+				if err != nil {
+					return nil, err
+				}
+				return &VerificationResult{ReturnCode: event.Result}, nil
+			},
+		},
+		VALUE_TYPE_STATE: {
+			Name:    "state",
+			EventId: verifyStateEventId,
+			SendVerification: func(contract *Testimonium, auth *bind.TransactOpts, feeInWei *big.Int, rlpHeader []byte,
+				noOfConfirmations uint8, rlpEncodedValue []byte, path []byte, rlpEncodedProofNodes []byte) (*types.Transaction, error) {
+				return contract.VerifyState(auth, feeInWei, rlpHeader, noOfConfirmations, rlpEncodedValue, path, rlpEncodedProofNodes)
+			},
+			ParseResult: func(contract *Testimonium, log types.Log) (*VerificationResult, error) {
+				event, err := contract.ParseVerifyState(log)
+				if err != nil {
+					return nil, err
+				}
+				return &VerificationResult{ReturnCode: event.Result}, nil
+			},
+		},
+	}
+)
+
+// RegisterValueType registers the verifier of a value type, e.g., of an ETH Relay contract variant (see
+// WithTestimoniumABI) verifying further proof types. Value types and their names can only be registered once.
+func RegisterValueType(valueType TrieValueType, verifier ValueVerifier) error {
+	if verifier.Name == "" || verifier.SendVerification == nil {
+		return fmt.Errorf("value type %d: a name and the verification have to be specified", int(valueType))
+	}
+	valueVerifiersMutex.Lock()
+	defer valueVerifiersMutex.Unlock()
+	if registered, exists := valueVerifiers[valueType]; exists {
+		return fmt.Errorf("value type %d is already registered as %s", int(valueType), registered.Name)
+	}
+	// registered types are named by their verifiers, their String method would lock the registry again
+	for _, registered := range valueVerifiers {
+		for _, name := range append([]string{verifier.Name}, verifier.Aliases...) {
+			if registered.hasName(name) {
+				return fmt.Errorf("value type name %s is already registered for %s values", name, registered.Name)
+			}
+		}
+		if registered.EventId == verifier.EventId {
+			return fmt.Errorf("verification event %s is already registered for %s values", verifier.EventId.Hex(), registered.Name)
+		}
+	}
+	valueVerifiers[valueType] = verifier
+	return nil
+}
+
+// ValueTypes returns the registered value types ordered by their number.
+func ValueTypes() []TrieValueType {
+	valueVerifiersMutex.RLock()
+	defer valueVerifiersMutex.RUnlock()
+	valueTypes := make([]TrieValueType, 0, len(valueVerifiers))
+	for valueType := range valueVerifiers {
+		valueTypes = append(valueTypes, valueType)
+	}
+	sort.Slice(valueTypes, func(i, j int) bool { return valueTypes[i] < valueTypes[j] })
+	return valueTypes
+}
+
+// verifier returns the registered verifier of the value type
+func (t TrieValueType) verifier() (ValueVerifier, error) {
+	valueVerifiersMutex.RLock()
+	defer valueVerifiersMutex.RUnlock()
+	verifier, exists := valueVerifiers[t]
+	if !exists {
+		return ValueVerifier{}, fmt.Errorf("unexpected trie value type: %d", int(t))
+	}
+	return verifier, nil
+}
+
+func (v ValueVerifier) hasName(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if strings.ToLower(v.Name) == name {
+		return true
+	}
+	for _, alias := range v.Aliases {
+		if strings.ToLower(alias) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// valueTypeOfEvent returns the value type whose verification emits the event
+func valueTypeOfEvent(eventId common.Hash) (TrieValueType, bool) {
+	valueVerifiersMutex.RLock()
+	defer valueVerifiersMutex.RUnlock()
+	for valueType, verifier := range valueVerifiers {
+		if verifier.EventId == eventId {
+			return valueType, true
+		}
+	}
+	return 0, false
+}
+
+// verificationEventIds returns the topics of the verification events of all value types
+func verificationEventIds() []common.Hash {
+	var eventIds []common.Hash
+	for _, valueType := range ValueTypes() {
+		verifier, _ := valueType.verifier()
+		eventIds = append(eventIds, verifier.EventId)
+	}
+	return eventIds
+}
+
+// buildValueProof builds the proof of the value of the transaction on the source chain
+func (c Client) buildValueProof(txHash common.Hash, valueType TrieValueType, sourceChain uint8) (proofs.Proof, *types.Header, error) {
+	verifier, err := valueType.verifier()
+	if err != nil {
+		return proofs.Proof{}, nil, err
+	}
+	if verifier.BuildProof == nil {
+		return proofs.Proof{}, nil, fmt.Errorf("proofs of %s values cannot be built from a transaction", valueType)
+	}
+	return verifier.BuildProof(c, txHash, sourceChain)
+}
+
+// parseVerificationResult returns the result of the verification event in the receipt of the verification
+func (v ValueVerifier) parseVerificationResult(contract *Testimonium, contractAddress common.Address, receipt *types.Receipt) (*VerificationResult, error) {
+	for _, vLog := range receipt.Logs {
+		if vLog.Address != contractAddress || len(vLog.Topics) == 0 || vLog.Topics[0] != v.EventId {
+			continue
+		}
+		if v.ParseResult != nil {
+			return v.ParseResult(contract, *vLog)
+		}
+		if len(vLog.Data) < 32 {
+			return nil, fmt.Errorf("illegal %s verification event", v.Name)
+		}
+		return &VerificationResult{ReturnCode: vLog.Data[31]}, nil
+	}
+	return nil, fmt.Errorf("no event found")
+}
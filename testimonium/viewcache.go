@@ -0,0 +1,138 @@
+// This file contains the cache of view calls reading static parameters of the Testimonium contract (required
+// verification fee, required stake per block, lock period). The daemon reads them for every relayed header and
+// verification, although they only change with admin transactions. Results are read at and keyed by the block hash of
+// the latest block; after the TTL they are only read again if a new block was mined. The cache is opt-in: a fee read
+// before a transaction is prepared may be stale for up to the TTL.
+
+package testimonium
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// viewCacheEntry is the result of a view call read at a block
+type viewCacheEntry struct {
+	value     *big.Int
+	blockHash common.Hash
+	checked   time.Time // time the block was the latest block
+}
+
+// viewCache contains the results of the view calls of a chain by method, nil if results are not cached
+type viewCache struct {
+	ttl     time.Duration
+	mutex   sync.Mutex
+	entries map[string]viewCacheEntry
+}
+
+func newViewCache(ttl time.Duration) *viewCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &viewCache{ttl: ttl, entries: make(map[string]viewCacheEntry)}
+}
+
+// invalidate drops all results, e.g., after an admin transaction changed a parameter
+func (cache *viewCache) invalidate() {
+	if cache == nil {
+		return
+	}
+	cache.mutex.Lock()
+	cache.entries = make(map[string]viewCacheEntry)
+	cache.mutex.Unlock()
+}
+
+// fresh returns an empty cache with the same TTL, nil if results are not cached
+func (cache *viewCache) fresh() *viewCache {
+	if cache == nil {
+		return nil
+	}
+	return newViewCache(cache.ttl)
+}
+
+// WithViewCacheTTL enables the cache of view calls of static contract parameters and sets the time their results are
+// used before checking whether a new block was mined, 0 disables the cache. The "viewcachettl" entry of a chain config overrides it for that
+// chain. Recorded sessions are replayed without the cache.
+func WithViewCacheTTL(ttl time.Duration) ClientOption {
+	return func(client *Client) error {
+		if ttl < 0 {
+			return fmt.Errorf("illegal view cache TTL %s", ttl)
+		}
+		client.viewCacheTTL = &ttl
+		return nil
+	}
+}
+
+// viewCacheFromConfig returns the view cache of a chain with the TTL of the "viewcachettl" entry of its config, e.g.,
+//
+//	viewcachettl: 30s
+//
+// The TTL of the client is used if the entry is missing, without either (or with 0) results are not cached.
+func (c Client) viewCacheFromConfig(chainConfig map[string]interface{}) (*viewCache, error) {
+	var ttl time.Duration
+	if c.viewCacheTTL != nil {
+		ttl = *c.viewCacheTTL
+	}
+	if c.replay {
+		return nil, nil
+	}
+	if entry := chainConfig["viewcachettl"]; entry != nil {
+		configured, err := time.ParseDuration(fmt.Sprint(entry))
+		if err != nil || configured < 0 {
+			return newViewCache(ttl), fmt.Errorf("illegal viewcachettl entry %v", entry)
+		}
+		ttl = configured
+	}
+	return newViewCache(ttl), nil
+}
+
+// cachedView returns the result of the view call from the cache of the chain. If the result is older than the TTL, the
+// latest block is read: the result is still used if no new block was mined, otherwise call is repeated at the latest
+// block. Without cache, call is made at the latest block right away.
+func (chain *Chain) cachedView(method string, call func(opts *bind.CallOpts) (*big.Int, error)) (*big.Int, error) {
+	cache := chain.viewCache
+	if cache == nil {
+		return call(nil)
+	}
+
+	cache.mutex.Lock()
+	entry, exists := cache.entries[method]
+	cache.mutex.Unlock()
+	if exists && time.Since(entry.checked) < cache.ttl {
+		return new(big.Int).Set(entry.value), nil
+	}
+
+	head, err := chain.client.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if !exists || entry.blockHash != head.Hash() {
+		value, err := call(&bind.CallOpts{BlockNumber: head.Number})
+		if err != nil {
+			return nil, err
+		}
+		entry = viewCacheEntry{value: new(big.Int).Set(value), blockHash: head.Hash()}
+	}
+	entry.checked = time.Now()
+
+	cache.mutex.Lock()
+	cache.entries[method] = entry
+	cache.mutex.Unlock()
+	return new(big.Int).Set(entry.value), nil
+}
+
+// requiredVerificationFee returns the verification fee required by the Testimonium contract of the chain
+func (chain *Chain) requiredVerificationFee() (*big.Int, error) {
+	return chain.cachedView("getRequiredVerificationFee", chain.testimoniumContract.GetRequiredVerificationFee)
+}
+
+// requiredStakePerBlock returns the stake locked per submitted header by the Testimonium contract of the chain
+func (chain *Chain) requiredStakePerBlock() (*big.Int, error) {
+	return chain.cachedView("getRequiredStakePerBlock", chain.testimoniumContract.GetRequiredStakePerBlock)
+}
@@ -0,0 +1,186 @@
+// This file contains the webhook callbacks of verification jobs. When the verification of a job completes (or the job
+// fails), a JSON payload signed with HMAC-SHA256 is posted to the callback URL, so applications can start verifications
+// and continue once the result is on chain instead of waiting for the job.
+
+package testimonium
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// WEBHOOK_SIGNATURE_HEADER contains the signature of the payload, "sha256=" followed by the hex encoded HMAC.
+	WEBHOOK_SIGNATURE_HEADER = "X-Ethrelay-Signature"
+	// WEBHOOK_TIMESTAMP_HEADER contains the unix time the payload was signed at, it is part of the signed message.
+	WEBHOOK_TIMESTAMP_HEADER = "X-Ethrelay-Timestamp"
+	// WEBHOOK_ATTEMPTS is the number of times a callback is posted until the receiver accepts it.
+	WEBHOOK_ATTEMPTS = 5
+)
+
+// webhook events
+const (
+	WEBHOOK_VERIFICATION_COMPLETED = "verification.completed"
+	WEBHOOK_VERIFICATION_FAILED    = "verification.failed"
+)
+
+// VerificationWebhook posts the result of a verification job to a URL.
+type VerificationWebhook struct {
+	URL    string
+	Secret []byte       // key of the HMAC signing the payloads, payloads are not signed if empty
+	Client *http.Client // http.DefaultClient with a timeout of 10s if nil
+	Retry  *RetryPolicy // failed deliveries are retried WEBHOOK_ATTEMPTS times with exponential backoff if nil
+}
+
+// VerificationCallback is the payload posted to the webhook when a verification job ends.
+type VerificationCallback struct {
+	Event            string        `json:"event"` // WEBHOOK_VERIFICATION_COMPLETED or WEBHOOK_VERIFICATION_FAILED
+	SourceChain      uint8         `json:"sourceChain"`
+	DestinationChain uint8         `json:"destinationChain"`
+	TxHash           common.Hash   `json:"txHash"` // verified transaction (or transaction of the verified receipt)
+	ValueType        TrieValueType `json:"valueType"`
+	BlockHash        common.Hash   `json:"blockHash"`
+	BlockNumber      uint64        `json:"blockNumber"`
+	Confirmations    uint8         `json:"confirmations"`
+	// result of the verification transaction, unset if the job failed before the verification was sent
+	ReturnCode         *uint8               `json:"returnCode,omitempty"`
+	VerificationTxHash *common.Hash         `json:"verificationTxHash,omitempty"`
+	VerificationBlock  uint64               `json:"verificationBlock,omitempty"`
+	Latency            *VerificationLatency `json:"latency,omitempty"`
+	Error              string               `json:"error,omitempty"`
+	Timestamp          int64                `json:"timestamp"`
+}
+
+// NewVerificationCallback returns the payload of the job, which has to be done or failed.
+func NewVerificationCallback(job VerificationJob, sourceChain uint8, destinationChain uint8) VerificationCallback {
+	callback := VerificationCallback{
+		Event:            WEBHOOK_VERIFICATION_COMPLETED,
+		SourceChain:      sourceChain,
+		DestinationChain: destinationChain,
+		TxHash:           job.TxHash,
+		ValueType:        job.ValueType,
+		BlockHash:        job.BlockHash,
+		BlockNumber:      job.BlockNumber,
+		Confirmations:    job.Confirmations,
+		Latency:          job.Latency,
+		Error:            job.Error,
+		Timestamp:        time.Now().Unix(),
+	}
+	if job.Stage == BACKFILL_FAILED {
+		callback.Event = WEBHOOK_VERIFICATION_FAILED
+	}
+	if job.Verification != nil {
+		txHash := job.Verification.TxHash
+		callback.VerificationTxHash = &txHash
+		callback.VerificationBlock = job.Verification.BlockNumber
+		if job.Verification.Verification != nil {
+			returnCode := job.Verification.Verification.ReturnCode
+			callback.ReturnCode = &returnCode
+		}
+	}
+	return callback
+}
+
+// Notify posts the payload to the webhook. Failed deliveries (network errors and non-2xx responses) are retried with
+// the retry policy of the webhook.
+func (hook VerificationWebhook) Notify(callback VerificationCallback) error {
+	body, err := json.Marshal(callback)
+	if err != nil {
+		return err
+	}
+	client := hook.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	policy := RetryPolicy{Attempts: WEBHOOK_ATTEMPTS, InitialDelay: time.Second, Multiplier: 2, Jitter: 0.2}
+	if hook.Retry != nil {
+		policy = *hook.Retry
+	}
+	err = policy.Do(context.Background(), func() error {
+		return hook.post(client, body)
+	})
+	if err != nil {
+		return fmt.Errorf("webhook %s: %s", hook.URL, err)
+	}
+	return nil
+}
+
+func (hook VerificationWebhook) post(client *http.Client, body []byte) error {
+	request, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if len(hook.Secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		request.Header.Set(WEBHOOK_TIMESTAMP_HEADER, timestamp)
+		request.Header.Set(WEBHOOK_SIGNATURE_HEADER, "sha256="+webhookSignature(hook.Secret, timestamp, body))
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("receiver responded %s", response.Status)
+	}
+	return nil
+}
+
+// webhookSignature returns the hex encoded HMAC-SHA256 of "<timestamp>.<body>"
+func webhookSignature(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks the signature of a received payload, e.g., in the handler of the callback URL.
+// Payloads signed longer than maxAge ago are rejected, so recorded payloads cannot be replayed.
+func VerifyWebhookSignature(secret []byte, header http.Header, body []byte, maxAge time.Duration) error {
+	timestamp := header.Get(WEBHOOK_TIMESTAMP_HEADER)
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing or invalid %s header", WEBHOOK_TIMESTAMP_HEADER)
+	}
+	if age := time.Since(time.Unix(signedAt, 0)); age > maxAge || age < -maxAge {
+		return fmt.Errorf("payload signed %s ago", age.Round(time.Second))
+	}
+
+	signature := strings.TrimPrefix(header.Get(WEBHOOK_SIGNATURE_HEADER), "sha256=")
+	expected := webhookSignature(secret, timestamp, body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// TrackWithWebhook returns a tracking function of verification jobs (e.g., for VerifyWithBackfill) that notifies the
+// webhook when a job is done or failed, and passes every stage to track, which may be nil. Delivery errors are
+// reported as progress messages, they do not fail the job.
+func (c Client) TrackWithWebhook(hook VerificationWebhook, sourceChain uint8, destinationChain uint8, track func(job VerificationJob)) func(job VerificationJob) {
+	return func(job VerificationJob) {
+		if track != nil {
+			track(job)
+		}
+		if job.Stage != BACKFILL_DONE && job.Stage != BACKFILL_FAILED {
+			return
+		}
+		if err := hook.Notify(NewVerificationCallback(job, sourceChain, destinationChain)); err != nil {
+			c.progressf("WARNING: Result of the verification of %s not delivered: %s\n", job.TxHash.Hex(), err)
+		}
+	}
+}
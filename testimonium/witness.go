@@ -0,0 +1,101 @@
+// This file contains the generation of the witnesses needed to dispute submitted block headers.
+
+package testimonium
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pantos-io/go-ethrelay/ethereum/ethash"
+)
+
+// DisputeWitness contains the parameters of the disputeBlockHeader call of the Testimonium contract.
+type DisputeWitness struct {
+	BlockHash        common.Hash
+	BlockNumber      uint64
+	RlpHeader        []byte
+	RlpParentHeader  []byte
+	DataSetLookup    []*big.Int // DAG elements accessed by the proof-of-work computation
+	WitnessForLookup []*big.Int // Merkle proofs of the DAG elements
+}
+
+// WithWitnessProvider sets the provider of the DAG elements and proofs of disputes, e.g., a cache shared by several
+// relayer instances. Without a provider, they are computed one by one, streaming the DAGs from disk.
+func WithWitnessProvider(provider ethash.WitnessProvider) ClientOption {
+	return func(client *Client) error {
+		client.witnessProvider = provider
+		return nil
+	}
+}
+
+// GenerateDisputeWitnesses generates the witnesses for disputing the submitted block headers with the specified
+// hashes. The DAG elements and proofs of all blocks are requested from the witness provider of the client at once,
+// so it can build the DAG trees concurrently. The witnesses are returned in the order of the block hashes.
+func (c Client) GenerateDisputeWitnesses(blockHashes []common.Hash, chain uint8) ([]DisputeWitness, error) {
+	c, span := c.startSpan("generate dispute witnesses", chain)
+	span.SetAttribute("ethrelay.blocks", len(blockHashes))
+	witnesses, err := c.generateDisputeWitnesses(blockHashes, chain)
+	return witnesses, span.End(err)
+}
+
+func (c Client) generateDisputeWitnesses(blockHashes []common.Hash, chain uint8) ([]DisputeWitness, error) {
+	if err := c.checkTestimonium(chain); err != nil {
+		return nil, err
+	}
+
+	witnesses := make([]DisputeWitness, len(blockHashes))
+	requests := make([]ethash.WitnessRequest, len(blockHashes))
+
+	for i, blockHash := range blockHashes {
+		rlpHeader, err := c.submittedRlpHeader(blockHash, chain)
+		if err != nil {
+			return nil, fmt.Errorf("block %s: %s", blockHash.String(), err)
+		}
+
+		header, err := decodeHeaderFromRLP(rlpHeader)
+		if err != nil {
+			return nil, fmt.Errorf("block %s: %s", blockHash.String(), err)
+		}
+
+		// the parent rlp encoded block header is needed for calling dispute as well
+		rlpParentHeader, err := c.submittedRlpHeader(header.ParentHash, chain)
+		if err != nil {
+			return nil, fmt.Errorf("parent of block %s: %s", blockHash.String(), err)
+		}
+
+		hashWithoutNonce, err := HeaderHashWithoutNonce(header)
+		if err != nil {
+			return nil, err
+		}
+
+		witnesses[i] = DisputeWitness{
+			BlockHash:       blockHash,
+			BlockNumber:     header.Number.Uint64(),
+			RlpHeader:       rlpHeader,
+			RlpParentHeader: rlpParentHeader,
+		}
+		requests[i] = ethash.WitnessRequest{
+			BlockNumber: header.Number.Uint64(),
+			Nonce:       header.Nonce.Uint64(),
+			HashNoNonce: hashWithoutNonce,
+		}
+	}
+
+	provider := c.witnessProvider
+	if provider == nil {
+		// the DAG is streamed from disk unless the limits of the shared cache were set
+		provider = ethash.NewComputedWitnessProvider(ethash.SharedDAGCache(), 1)
+	}
+	powWitnesses, err := provider.Witnesses(requests)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, powWitness := range powWitnesses {
+		witnesses[i].DataSetLookup = powWitness.DataSetLookup
+		witnesses[i].WitnessForLookup = powWitness.WitnessForLookup
+	}
+
+	return witnesses, nil
+}